@@ -41,7 +41,10 @@ if (-not (Test-Path "cmd\gots")) {
     exit 1
 }
 
-go build -o "$BUILD_DIR\gots.exe" -ldflags "-s -w -X main.Version=$Version" .\cmd\gots
+$Commit = (git rev-parse HEAD 2>$null)
+$BuildDate = (Get-Date).ToUniversalTime().ToString("yyyy-MM-ddTHH:mm:ssZ")
+
+go build -o "$BUILD_DIR\gots.exe" -ldflags "-s -w -X main.version=$Version -X main.commit=$Commit -X main.buildDate=$BuildDate" .\cmd\gots
 
 if ($LASTEXITCODE -ne 0) {
     Write-Host "ERROR: Go build failed" -ForegroundColor Red
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gots-runtime/internal/config"
+	"gots-runtime/internal/transpiler"
+
+	"github.com/spf13/cobra"
+)
+
+// newCacheCmd creates the cache command and its subcommands
+func newCacheCmd() *cobra.Command {
+	cacheCmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Manage the transpile cache",
+		Long:  "Manage the on-disk cache of transpiled files kept in " + transpiler.CacheDir + "\nunder the project root.",
+	}
+
+	cleanCmd := &cobra.Command{
+		Use:   "clean [dir]",
+		Short: "Remove the transpile cache",
+		Long:  "Remove the transpile cache of the project containing dir, or of the\ncurrent directory.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  cleanCache,
+	}
+	cacheCmd.AddCommand(cleanCmd)
+
+	return cacheCmd
+}
+
+func cleanCache(cmd *cobra.Command, args []string) error {
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+	root, err := projectRoot(dir)
+	if err != nil {
+		return err
+	}
+
+	cache := transpiler.NewDiskCache(filepath.Join(root, transpiler.CacheDir))
+	if err := cache.Clean(); err != nil {
+		return err
+	}
+	fmt.Printf("Removed %s\n", cache.Dir())
+	return nil
+}
+
+// projectRoot returns the directory of the gots.json nearest to dir, or dir
+// itself if there is none
+func projectRoot(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	if configPath, err := config.FindConfig(abs); err == nil {
+		return filepath.Dir(configPath), nil
+	}
+	return abs, nil
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gots-runtime/internal/runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// tscDiagnostic matches an error reported by tsc --pretty false, e.g.
+// "src/main.ts(3,7): error TS2322: Type 'string' is not assignable..."
+var tscDiagnostic = regexp.MustCompile(`^(.+)\((\d+),(\d+)\): error (TS\d+): (.*)$`)
+
+// checkDiagnostic is a problem found by gots check
+type checkDiagnostic struct {
+	File    string
+	Line    int
+	Column  int
+	Message string
+}
+
+// String formats the diagnostic as "file:line:col: error: message"
+func (d checkDiagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: error: %s", d.File, d.Line, d.Column, d.Message)
+}
+
+// newCheckCmd creates the check command
+func newCheckCmd() *cobra.Command {
+	var noTSC bool
+	checkCmd := &cobra.Command{
+		Use:   "check [file...]",
+		Short: "Type-check TypeScript files without running them",
+		Long:  "Type-check the given files, or the project containing the current\ndirectory, without running them. The TypeScript compiler (tsc) from the\nproject's node_modules or the PATH does the checking; without it, only\nsyntax errors and unresolved relative imports are reported.\n\nErrors are printed as file:line:col and make the command exit with\nstatus 1.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return checkFiles(args, noTSC)
+		},
+	}
+	checkCmd.Flags().BoolVar(&noTSC, "no-tsc", false, "Only check syntax and imports, even if tsc is available")
+	return checkCmd
+}
+
+func checkFiles(args []string, noTSC bool) error {
+	root, err := projectRoot(".")
+	if err != nil {
+		return err
+	}
+	for _, file := range args {
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("file not found: %s", file)
+		}
+	}
+
+	var diagnostics []checkDiagnostic
+	if tsc := findTSC(root); tsc != "" && !noTSC {
+		diagnostics, err = runTSC(tsc, root, args)
+	} else {
+		if !noTSC {
+			fmt.Fprintln(os.Stderr, "Warning: tsc not found; checking syntax and imports only (install typescript to check types)")
+		}
+		files := args
+		if len(files) == 0 {
+			if files, err = checkSources(root); err != nil {
+				return err
+			}
+		}
+		diagnostics, err = checkSyntax(files)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, d := range diagnostics {
+		fmt.Println(d)
+	}
+	if len(diagnostics) > 0 {
+		files := make(map[string]bool)
+		for _, d := range diagnostics {
+			files[d.File] = true
+		}
+		fmt.Printf("\nFound %d error(s) in %d file(s).\n", len(diagnostics), len(files))
+		os.Exit(1)
+	}
+	fmt.Println("No errors found.")
+	return nil
+}
+
+// findTSC returns the path of the project's tsc, or of the one on the PATH,
+// or "" if there is none
+func findTSC(root string) string {
+	local := filepath.Join(root, "node_modules", ".bin", "tsc")
+	if info, err := os.Stat(local); err == nil && !info.IsDir() {
+		return local
+	}
+	if path, err := exec.LookPath("tsc"); err == nil {
+		return path
+	}
+	return ""
+}
+
+// runTSC type-checks files with tsc, or the project at root when no files
+// are given: with its tsconfig.json if it has one, otherwise its sources
+func runTSC(tsc, root string, files []string) ([]checkDiagnostic, error) {
+	args := []string{"--noEmit", "--pretty", "false"}
+	switch {
+	case len(files) > 0:
+		args = append(args, files...)
+	case fileExists(filepath.Join(root, "tsconfig.json")):
+		args = append(args, "-p", root)
+	default:
+		sources, err := checkSources(root)
+		if err != nil {
+			return nil, err
+		}
+		if len(sources) == 0 {
+			return nil, nil
+		}
+		args = append(args, "--allowJs", "--checkJs", "false")
+		args = append(args, sources...)
+	}
+
+	output, err := exec.Command(tsc, args...).CombinedOutput()
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return nil, fmt.Errorf("failed to run tsc: %w", err)
+	}
+
+	var diagnostics []checkDiagnostic
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		match := tscDiagnostic.FindStringSubmatch(line)
+		if match == nil {
+			// Continuation lines elaborate on the previous error
+			if len(diagnostics) > 0 && strings.TrimSpace(line) != "" {
+				diagnostics[len(diagnostics)-1].Message += "\n" + line
+			}
+			continue
+		}
+		d := checkDiagnostic{File: match[1], Message: match[4] + ": " + match[5]}
+		fmt.Sscan(match[2], &d.Line)
+		fmt.Sscan(match[3], &d.Column)
+		diagnostics = append(diagnostics, d)
+	}
+
+	// A failure without diagnostics, e.g. a broken tsconfig.json
+	if err != nil && len(diagnostics) == 0 {
+		return nil, fmt.Errorf("tsc failed: %s", strings.TrimSpace(string(output)))
+	}
+	return diagnostics, nil
+}
+
+// checkSyntax transpiles and compiles files as gots run does, and checks
+// that their relative imports resolve
+func checkSyntax(files []string) ([]checkDiagnostic, error) {
+	rt, err := runtime.New(findStdlibPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create runtime: %w", err)
+	}
+	defer rt.Shutdown()
+
+	var diagnostics []checkDiagnostic
+	for _, file := range files {
+		if err := applyCompilerOptions(rt, file); err != nil {
+			return nil, err
+		}
+		err := rt.CheckFile(file)
+		var synErr *runtime.SyntaxError
+		switch {
+		case errors.As(err, &synErr):
+			diagnostics = append(diagnostics, checkDiagnostic{File: file, Line: synErr.Line, Column: synErr.Column, Message: synErr.Message})
+		case err != nil:
+			diagnostics = append(diagnostics, checkDiagnostic{File: file, Line: 1, Column: 1, Message: err.Error()})
+		}
+
+		unresolved, err := checkImports(file)
+		if err != nil {
+			return nil, err
+		}
+		diagnostics = append(diagnostics, unresolved...)
+	}
+	return diagnostics, nil
+}
+
+// checkImports reports the relative imports of file that do not resolve to
+// a file
+func checkImports(file string) ([]checkDiagnostic, error) {
+	source, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", file, err)
+	}
+
+	var diagnostics []checkDiagnostic
+	for _, match := range importPattern.FindAllSubmatchIndex(source, -1) {
+		var spec string
+		var start int
+		for i := 2; i < len(match); i += 2 {
+			if match[i] >= 0 {
+				spec, start = string(source[match[i]:match[i+1]]), match[i]
+			}
+		}
+		if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+			continue
+		}
+		if resolveCheckImport(filepath.Dir(file), spec) {
+			continue
+		}
+		before := source[:start]
+		line := bytes.Count(before, []byte("\n")) + 1
+		column := len([]rune(string(before[bytes.LastIndexByte(before, '\n')+1:]))) + 1
+		diagnostics = append(diagnostics, checkDiagnostic{
+			File:    file,
+			Line:    line,
+			Column:  column,
+			Message: fmt.Sprintf("cannot find module %q", spec),
+		})
+	}
+	return diagnostics, nil
+}
+
+// resolveCheckImport reports whether a relative import from dir resolves
+// to a file, trying the source extensions and index files. An import of a
+// .js file also resolves to the TypeScript file it is compiled from.
+func resolveCheckImport(dir, spec string) bool {
+	base := filepath.Join(dir, filepath.FromSlash(spec))
+	candidates := []string{base}
+	if strings.HasSuffix(base, ".js") {
+		trimmed := strings.TrimSuffix(base, ".js")
+		candidates = append(candidates, trimmed+".ts", trimmed+".tsx")
+	}
+	for _, ext := range graphExtensions {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range graphExtensions {
+		candidates = append(candidates, filepath.Join(base, "index"+ext))
+	}
+	for _, candidate := range candidates {
+		if fileExists(candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkSources returns the source files of the project at root, relative
+// to the current directory when they are under it
+func checkSources(root string) ([]string, error) {
+	wd, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	err = filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if p != root && (name == "node_modules" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isGraphSource(p) {
+			return nil
+		}
+		if rel, err := filepath.Rel(wd, p); err == nil && !strings.HasPrefix(rel, "..") {
+			p = rel
+		}
+		files = append(files, p)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", root, err)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// fileExists reports whether path is an existing file
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
+}
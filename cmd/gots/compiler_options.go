@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"gots-runtime/internal/config"
+	"gots-runtime/internal/runtime"
+	"gots-runtime/internal/transpiler"
+)
+
+// applyCompilerOptions configures the transpiler from the tsconfig.json or
+// gots.json compilerOptions nearest to the entry file, if any
+func applyCompilerOptions(rt *runtime.Runtime, entryFile string) error {
+	dir, err := filepath.Abs(filepath.Dir(entryFile))
+	if err != nil {
+		return err
+	}
+
+	opts, configPath, err := config.FindCompilerOptions(dir)
+	if err != nil {
+		return fmt.Errorf("failed to load compiler options: %w", err)
+	}
+	if opts == nil {
+		return nil
+	}
+
+	err = rt.SetCompilerOptions(transpiler.Options{
+		Target: opts.Target,
+		Module: opts.Module,
+		JSX:    opts.JSX,
+
+		JSXFactory:  opts.JSXFactory,
+		JSXFragment: opts.JSXFragmentFactory,
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %w", configPath, err)
+	}
+	return nil
+}
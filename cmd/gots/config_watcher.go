@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gots-runtime/internal/config"
+)
+
+// watchProjectConfig starts watching the gots.json nearest to the entry
+// file, so that the program sees changes through runtime.onConfigChange.
+// It returns nil when there is no configuration to watch.
+func watchProjectConfig(entryFile string) *config.Watcher {
+	configPath, err := config.FindConfig(filepath.Dir(entryFile))
+	if err != nil {
+		return nil
+	}
+	watcher, err := config.NewWatcher(configPath)
+	if err != nil {
+		return nil
+	}
+	if err := watcher.Start(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	return watcher
+}
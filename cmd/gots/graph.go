@@ -0,0 +1,272 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gots-runtime/internal/config"
+	"gots-runtime/internal/runtime"
+
+	"github.com/spf13/cobra"
+)
+
+// graphExtensions are the source file extensions included in the graph, in
+// the order they are tried when resolving an import
+var graphExtensions = []string{".ts", ".tsx", ".js", ".jsx"}
+
+// importPattern matches static imports, re-exports, dynamic imports and
+// require calls
+var importPattern = regexp.MustCompile(`(?:import|export)\s[^'";]*?from\s*['"]([^'"]+)['"]|import\s*\(?\s*['"]([^'"]+)['"]|require\s*\(\s*['"]([^'"]+)['"]\s*\)`)
+
+func exportGraph(cmd *cobra.Command, args []string) error {
+	if graphFormat != "dot" && graphFormat != "svg" {
+		return fmt.Errorf("unsupported format %q: use dot or svg", graphFormat)
+	}
+
+	dir := "."
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	// Module IDs and domain paths are relative to the project root
+	root := dir
+	var domains []config.DomainConfig
+	if configPath, err := config.FindConfig(dir); err == nil {
+		cfg, err := config.LoadConfig(configPath)
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+		root = filepath.Dir(configPath)
+		domains = cfg.Domains
+	}
+
+	graph, err := buildModuleGraph(root, dir)
+	if err != nil {
+		return err
+	}
+	enforcer := buildDomainEnforcer(graph, domains)
+
+	var dot string
+	if graphDomains {
+		dot = buildDomainGraph(graph, enforcer, domains).ToDOT()
+	} else {
+		var violations []runtime.BoundaryViolation
+		for _, v := range runtime.NewDDDValidator(enforcer).ValidateAllDependencies() {
+			// Modules outside every domain are not subject to boundaries
+			if v.FromDomain != "" && v.ToDomain != "" {
+				violations = append(violations, v)
+			}
+		}
+		dot = graph.ToDOT(violations...)
+	}
+
+	output := []byte(dot)
+	if graphFormat == "svg" {
+		if output, err = renderSVG(output); err != nil {
+			return err
+		}
+	}
+
+	if graphOutput == "" {
+		_, err = os.Stdout.Write(output)
+		return err
+	}
+	if err := os.WriteFile(graphOutput, output, 0644); err != nil {
+		return fmt.Errorf("failed to write graph: %w", err)
+	}
+	return nil
+}
+
+// buildModuleGraph scans the source files under dir and adds a node for
+// each file and an edge for each relative import. Node IDs are slash
+// separated paths relative to root.
+func buildModuleGraph(root, dir string) (*runtime.ServiceGraph, error) {
+	files := make(map[string]string) // module ID -> file path
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			name := info.Name()
+			if p != dir && (name == "node_modules" || strings.HasPrefix(name, ".")) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isGraphSource(p) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = p
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan %s: %w", dir, err)
+	}
+
+	graph := runtime.NewServiceGraph()
+	for id := range files {
+		graph.AddNode(&runtime.ServiceNode{ID: id, ModuleID: id})
+	}
+
+	for id, p := range files {
+		source, err := os.ReadFile(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+		for _, match := range importPattern.FindAllSubmatch(source, -1) {
+			spec := string(bytes.Join(match[1:], nil))
+			target, ok := resolveGraphImport(id, spec, files)
+			if !ok {
+				continue
+			}
+			if err := graph.AddDependency(id, target); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return graph, nil
+}
+
+// isGraphSource reports whether a file is a source file (declaration files
+// excluded)
+func isGraphSource(p string) bool {
+	if strings.HasSuffix(p, ".d.ts") {
+		return false
+	}
+	for _, ext := range graphExtensions {
+		if strings.HasSuffix(p, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveGraphImport resolves a relative import from module fromID to a
+// scanned module ID. Bare and stdlib imports are not part of the graph.
+func resolveGraphImport(fromID, spec string, files map[string]string) (string, bool) {
+	if !strings.HasPrefix(spec, "./") && !strings.HasPrefix(spec, "../") {
+		return "", false
+	}
+
+	base := path.Join(path.Dir(fromID), spec)
+	candidates := []string{base}
+	for _, ext := range graphExtensions {
+		candidates = append(candidates, base+ext)
+	}
+	for _, ext := range graphExtensions {
+		candidates = append(candidates, base+"/index"+ext)
+	}
+	for _, candidate := range candidates {
+		if _, ok := files[candidate]; ok {
+			return candidate, true
+		}
+	}
+	return "", false
+}
+
+// buildDomainEnforcer assigns the graph's modules to the configured domains
+// and adds their boundaries
+func buildDomainEnforcer(graph *runtime.ServiceGraph, domains []config.DomainConfig) *runtime.DDDEnforcer {
+	enforcer := runtime.NewDDDEnforcer(graph)
+	nodes := graph.GetAllNodes()
+
+	for _, domain := range domains {
+		enforcer.RegisterDomain(domain.Name, domainModules(nodes, domain))
+	}
+	for _, domain := range domains {
+		for _, boundary := range domain.Allow {
+			enforcer.AddBoundary(domain.Name, boundary.Domain, boundary.Protocol, true)
+		}
+	}
+	return enforcer
+}
+
+// domainModules returns the sorted IDs of the modules matching a domain's
+// file or directory entries
+func domainModules(nodes map[string]*runtime.ServiceNode, domain config.DomainConfig) []string {
+	modules := make([]string, 0)
+	for id := range nodes {
+		for _, entry := range domain.Modules {
+			entry = path.Clean(filepath.ToSlash(entry))
+			if id == entry || strings.HasPrefix(id, entry+"/") {
+				modules = append(modules, id)
+				break
+			}
+		}
+	}
+	sort.Strings(modules)
+	return modules
+}
+
+// buildDomainGraph builds the graph of configured domains with an edge for
+// each domain that imports from another
+func buildDomainGraph(graph *runtime.ServiceGraph, enforcer *runtime.DDDEnforcer, domains []config.DomainConfig) *runtime.DomainGraph {
+	domainGraph := runtime.NewDomainGraph()
+	nodes := graph.GetAllNodes()
+
+	for _, domain := range domains {
+		boundaries := make([]runtime.DomainBoundary, 0, len(domain.Allow))
+		for _, boundary := range domain.Allow {
+			b := runtime.DomainBoundary{
+				FromDomain: domain.Name,
+				ToDomain:   boundary.Domain,
+				Protocol:   boundary.Protocol,
+			}
+			// Edges are imports, which only a boundary allowing direct calls permits
+			b.Allowed = b.AllowsProtocol(runtime.ProtocolDirect)
+			boundaries = append(boundaries, b)
+		}
+		domainGraph.AddDomain(&runtime.Domain{
+			Name:       domain.Name,
+			Modules:    domainModules(nodes, domain),
+			Boundaries: boundaries,
+		})
+	}
+
+	seen := make(map[[2]string]bool)
+	for id := range nodes {
+		fromDomain, err := enforcer.GetDomainForModule(id)
+		if err != nil {
+			continue
+		}
+		dependencies, _ := graph.GetDependencies(id)
+		for _, dep := range dependencies {
+			toDomain, err := enforcer.GetDomainForModule(dep)
+			if err != nil || toDomain == fromDomain || seen[[2]string{fromDomain, toDomain}] {
+				continue
+			}
+			seen[[2]string{fromDomain, toDomain}] = true
+			domainGraph.AddEdge(fromDomain, toDomain)
+		}
+	}
+	return domainGraph
+}
+
+// renderSVG converts DOT source to SVG with Graphviz
+func renderSVG(dot []byte) ([]byte, error) {
+	dotPath, err := exec.LookPath("dot")
+	if err != nil {
+		return nil, fmt.Errorf("svg output requires Graphviz: %w", err)
+	}
+
+	var stderr bytes.Buffer
+	render := exec.Command(dotPath, "-Tsvg")
+	render.Stdin = bytes.NewReader(dot)
+	render.Stderr = &stderr
+	svg, err := render.Output()
+	if err != nil {
+		return nil, fmt.Errorf("graphviz failed: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return svg, nil
+}
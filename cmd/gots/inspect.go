@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gots-runtime/internal/runtime"
+	"gots-runtime/pkg/debugger"
+
+	"github.com/spf13/cobra"
+)
+
+// inspectFlags are the debugger flags of gots run
+type inspectFlags struct {
+	addr    string // --inspect
+	brkAddr string // --inspect-brk
+}
+
+// registerInspectFlags adds --inspect and --inspect-brk to cmd; both take
+// an optional address
+func registerInspectFlags(cmd *cobra.Command, flags *inspectFlags) {
+	cmd.Flags().StringVar(&flags.addr, "inspect", "", "Listen for a DAP debugger client on `addr`")
+	cmd.Flags().Lookup("inspect").NoOptDefVal = debugger.DefaultInspectAddr
+	cmd.Flags().StringVar(&flags.brkAddr, "inspect-brk", "", "Like --inspect, but wait for a client and stop before the first statement")
+	cmd.Flags().Lookup("inspect-brk").NoOptDefVal = debugger.DefaultInspectAddr
+}
+
+// startInspector starts the DAP server requested by flags, or returns nil
+// if none was. With --inspect-brk it waits for a client to attach and keeps
+// the program stopped on entry until the client continues it.
+func startInspector(flags *inspectFlags, rt *runtime.Runtime, filename string) (*debugger.DAPServer, error) {
+	addr, brk := flags.addr, false
+	if flags.brkAddr != "" {
+		addr, brk = flags.brkAddr, true
+	}
+	if addr == "" {
+		return nil, nil
+	}
+
+	evaluate := func(expression string) (string, error) {
+		value, err := rt.GetVM().RunString(expression)
+		if err != nil {
+			return "", err
+		}
+		return rt.Inspect(value), nil
+	}
+	source, err := filepath.Abs(filename)
+	if err != nil {
+		source = filename
+	}
+	server, err := debugger.NewDAPServer(addr, source, evaluate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start debugger: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Debugger listening on %s\n", server.Addr())
+
+	if brk {
+		fmt.Fprintln(os.Stderr, "Waiting for the debugger to attach...")
+		ctx := context.Background()
+		if err := server.WaitForClient(ctx); err != nil {
+			server.Close()
+			return nil, err
+		}
+		if err := server.StopOnEntry(ctx); err != nil {
+			server.Close()
+			return nil, err
+		}
+	}
+	return server, nil
+}
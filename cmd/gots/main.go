@@ -1,21 +1,43 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"gots-runtime/internal/config"
+	"gots-runtime/internal/docs"
+	"gots-runtime/internal/scaffold"
 	"gots-runtime/pkg/testrunner"
 
 	"gots-runtime/internal/runtime"
+	"gots-runtime/internal/transpiler"
+	"gots-runtime/internal/tsengine"
 
+	"github.com/dop251/goja"
 	"github.com/spf13/cobra"
 )
 
 var (
-	version = "0.1.0"
+	runPermissions      permissionFlags
+	runEval             string
+	runRecord           string
+	runFakeTime         bool
+	runSeed             int64
+	runStallThreshold   time.Duration
+	runInspect          inspectFlags
+	testParallel        int
+	testUpdateSnapshots bool
+	graphFormat         string
+	graphOutput         string
+	graphDomains        bool
+	docOpen             bool
+	initTemplate        string
 )
 
 func main() {
@@ -27,28 +49,30 @@ func main() {
 	}
 
 	var runCmd = &cobra.Command{
-		Use:   "run [file]",
+		Use:   "run [file] [args...]",
 		Short: "Run a TypeScript file",
-		Long:  "Execute a TypeScript file using the GoTS runtime",
-		Args:  cobra.ExactArgs(1),
+		Long:  "Execute a TypeScript file using the GoTS runtime. Use - as the file to\nread the program from stdin, or --eval to run an inline string.\nArguments after the file are passed to the program as process.argv.\n\nFile system, network and environment access is denied unless granted\nwith the --allow-* flags or in gots.json. When running in a terminal,\nmissing permissions are requested interactively unless --no-prompt is set.",
+		Args:  runArgs,
 		RunE:  runFile,
 	}
-
-	var versionCmd = &cobra.Command{
-		Use:   "version",
-		Short: "Print version information",
-		Run: func(cmd *cobra.Command, args []string) {
-			fmt.Printf("gots version %s\n", version)
-		},
-	}
+	// Flags after the file belong to the program, not to gots
+	runCmd.Flags().SetInterspersed(false)
+	runCmd.Flags().StringVarP(&runEval, "eval", "e", "", "Evaluate an inline TypeScript string instead of a file")
+	runCmd.Flags().StringVar(&runRecord, "record", "", "Record fs and env operations to a file for gots replay")
+	runCmd.Flags().BoolVar(&runFakeTime, "fake-time", false, "Let the program freeze and advance time with runtime.clock.set and advance")
+	runCmd.Flags().Int64Var(&runSeed, "seed", 0, "Seed Math.random so that it produces the same sequence on every run")
+	runCmd.Flags().DurationVar(&runStallThreshold, "stall-threshold", runtime.DefaultStallThreshold, "Warn when a callback blocks the event loop for longer than this (0 disables)")
+	registerInspectFlags(runCmd, &runInspect)
+	registerPermissionFlags(runCmd, &runPermissions)
 
 	var initCmd = &cobra.Command{
 		Use:   "init [project-name]",
 		Short: "Initialize a new GoTS project",
-		Long:  "Create a new GoTS project with basic structure",
+		Long:  "Create a new GoTS project from a template: " + strings.Join(scaffold.Templates(), ", ") + ".",
 		Args:  cobra.MaximumNArgs(1),
 		RunE:  initProject,
 	}
+	initCmd.Flags().StringVar(&initTemplate, "template", scaffold.DefaultTemplate, "Project template: "+strings.Join(scaffold.Templates(), ", "))
 
 	var buildCmd = &cobra.Command{
 		Use:   "build [file]",
@@ -61,10 +85,12 @@ func main() {
 	var testCmd = &cobra.Command{
 		Use:   "test [pattern]",
 		Short: "Run tests",
-		Long:  "Run tests in the current project",
+		Long:  "Run tests in the current project. Each test file runs in its own\nruntime; use --parallel to run several files at once.",
 		Args:  cobra.MaximumNArgs(1),
 		RunE:  runTests,
 	}
+	testCmd.Flags().IntVar(&testParallel, "parallel", 1, "Number of test files to run concurrently")
+	testCmd.Flags().BoolVarP(&testUpdateSnapshots, "update-snapshots", "u", false, "Overwrite snapshots that do not match")
 
 	var debugCmd = &cobra.Command{
 		Use:   "debug [file]",
@@ -93,10 +119,11 @@ func main() {
 	var docCmd = &cobra.Command{
 		Use:   "doc [query]",
 		Short: "Search documentation",
-		Long:  "Search the GoTS runtime documentation",
-		Args:  cobra.MaximumNArgs(1),
+		Long:  "Search the GoTS runtime documentation offline. Without a query, lists\nthe available topics; with --open, prints the full text of a topic.",
+		Args:  cobra.ArbitraryArgs,
 		RunE:  searchDocs,
 	}
+	docCmd.Flags().BoolVar(&docOpen, "open", false, "Print the full text of the matching topic")
 
 	var lintCmd = &cobra.Command{
 		Use:   "lint [file]",
@@ -114,10 +141,22 @@ func main() {
 		RunE:  formatFiles,
 	}
 
+	var graphCmd = &cobra.Command{
+		Use:   "graph [dir]",
+		Short: "Export the module dependency graph",
+		Long:  "Export the module dependency graph of a project as Graphviz DOT or SVG.\nCycles are highlighted, as are calls that cross a domain boundary\nconfigured in the \"domains\" section of gots.json. SVG output requires\nGraphviz (dot) on the PATH.",
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  exportGraph,
+	}
+	graphCmd.Flags().StringVar(&graphFormat, "format", "dot", "Output format: dot or svg")
+	graphCmd.Flags().StringVarP(&graphOutput, "output", "o", "", "Write to a file instead of stdout")
+	graphCmd.Flags().BoolVar(&graphDomains, "domains", false, "Export the domain graph instead of the module graph")
+
 	rootCmd.AddCommand(runCmd)
-	rootCmd.AddCommand(versionCmd)
+	rootCmd.AddCommand(newVersionCmd())
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(buildCmd)
+	rootCmd.AddCommand(newCheckCmd())
 	rootCmd.AddCommand(testCmd)
 	rootCmd.AddCommand(debugCmd)
 	rootCmd.AddCommand(serveCmd)
@@ -125,72 +164,142 @@ func main() {
 	rootCmd.AddCommand(docCmd)
 	rootCmd.AddCommand(lintCmd)
 	rootCmd.AddCommand(formatCmd)
+	rootCmd.AddCommand(graphCmd)
+	rootCmd.AddCommand(newCacheCmd())
+	rootCmd.AddCommand(newReplayCmd())
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 }
-func findStdlibPath() string {
-	// Check environment variable
-	if envPath := os.Getenv("GOTS_STDLIB_PATH"); envPath != "" {
-		if _, err := os.Stat(envPath); err == nil {
-			return envPath
-		}
-	}
 
-	// Get executable directory
-	exe, err := os.Executable()
-	if err == nil {
-		exeDir := filepath.Dir(exe)
-		stdlibPath := filepath.Join(exeDir, "stdlib")
-		if _, err := os.Stat(stdlibPath); err == nil {
-			return stdlibPath
-		}
-	}
+// findStdlibPath returns the stdlib directory set in GOTS_STDLIB_PATH, or
+// "" to use the stdlib embedded in the binary
+func findStdlibPath() string {
+	return os.Getenv("GOTS_STDLIB_PATH")
+}
 
-	// Check current directory
-	if _, err := os.Stat("stdlib"); err == nil {
-		return "stdlib"
-	}
+// stdinFilename is the run argument that reads the program from stdin
+const stdinFilename = "-"
 
-	// Check parent directories (dev fallback)
-	if _, err := os.Stat("../../stdlib"); err == nil {
-		return "../../stdlib"
+// runArgs requires a file argument unless --eval is given
+func runArgs(cmd *cobra.Command, args []string) error {
+	if cmd.Flags().Changed("eval") {
+		return nil
 	}
-
-	return ""
+	return cobra.MinimumNArgs(1)(cmd, args)
 }
 
 func runFile(cmd *cobra.Command, args []string) error {
-	filename := args[0]
+	evaluating := cmd.Flags().Changed("eval")
+	filename := stdinFilename
+	scriptArgs := args
+	if !evaluating {
+		filename = args[0]
+		scriptArgs = args[1:]
+	}
+	fromStdin := filename == stdinFilename
 
 	// Check if file exists
-	if _, err := os.Stat(filename); os.IsNotExist(err) {
-		fmt.Printf("Error: File not found: %s\n", filename)
-		os.Exit(1)
+	if !fromStdin {
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			fmt.Printf("Error: File not found: %s\n", filename)
+			os.Exit(1)
+		}
 	}
 
-	// Find stdlib path
-	stdlibPath := findStdlibPath()
-	if stdlibPath == "" {
-		fmt.Println("Warning: stdlib directory not found")
-		fmt.Println("Set GOTS_STDLIB_PATH or place stdlib next to executable")
+	// Record the run for gots replay; exits save the recording first
+	exit := os.Exit
+	var rec *recording
+	if runRecord != "" {
+		if fromStdin {
+			fmt.Println("Error: --record requires a file")
+			os.Exit(1)
+		}
+		var err error
+		rec, err = startRecording(runRecord, filename, scriptArgs, runFakeTime)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		exit = rec.exit
+		rec.saveOnSignal()
 	}
 
-	// Create runtime
-	rt, err := runtime.New(stdlibPath)
+	rt, err := newScriptRuntime(filename, scriptArgs, &runPermissions, func(rt *runtime.Runtime) {
+		rt.SetClockControl(runFakeTime)
+		rt.SetStallThreshold(runStallThreshold)
+		if cmd.Flags().Changed("seed") {
+			rt.SetRandomSeed(runSeed)
+		}
+		if rec != nil {
+			rt.SetIOLog(rec.log)
+			rt.SetExitHandler(rec.exit)
+		}
+	})
 	if err != nil {
-		fmt.Printf("Error: Failed to create runtime: %v\n", err)
-		os.Exit(1)
+		fmt.Printf("Error: %v\n", err)
+		exit(1)
 	}
+	defer rt.Shutdown()
 
-	// Execute the file
-	fmt.Printf("Running: %s\n", filename)
-	result, err := rt.ExecuteFile(filename)
+	// Start the debugger for --inspect and --inspect-brk; exits end the
+	// debugging session first
+	inspector, err := startInspector(&runInspect, rt, filename)
 	if err != nil {
 		fmt.Printf("Error: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+	if inspector != nil {
+		defer inspector.Close()
+		programExit := exit
+		exit = func(code int) {
+			inspector.Exited(code)
+			inspector.Close()
+			programExit(code)
+		}
+	}
+
+	// Execute the file, or stdin relative to the working directory
+	var result goja.Value
+	if evaluating {
+		result, err = rt.ExecuteNamedString(runEval, "<eval>", true)
+	} else if fromStdin {
+		fmt.Println("Running: <stdin>")
+		code, readErr := io.ReadAll(os.Stdin)
+		if readErr != nil {
+			fmt.Printf("Error: Failed to read stdin: %v\n", readErr)
+			os.Exit(1)
+		}
+		result, err = rt.ExecuteNamedString(string(code), "<stdin>", true)
+	} else {
+		fmt.Printf("Running: %s\n", filename)
+		result, err = rt.ExecuteFile(filename)
+	}
+	var synErr *runtime.SyntaxError
+	if errors.As(err, &synErr) {
+		fmt.Print(synErr.Diagnostic())
+		exit(1)
+	}
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+		if denial, ok := tsengine.PermissionDenial(err); ok {
+			fmt.Printf("Hint: %s\n", tsengine.PermissionHint(denial))
+		}
+		exit(1)
+	}
+
+	// Wait for pending callbacks and timers
+	if err := rt.Wait(context.Background()); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		exit(1)
+	}
+	if rec != nil {
+		rec.save()
+	}
+	if inspector != nil {
+		inspector.Exited(0)
 	}
 
 	// Print result if not undefined
@@ -200,61 +309,67 @@ func runFile(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// newScriptRuntime creates a runtime for running filename, with scriptArgs
+// as process.argv and the permissions granted by perms and gots.json.
+// configure, if not nil, is called before the runtime APIs are enabled.
+func newScriptRuntime(filename string, scriptArgs []string, perms *permissionFlags, configure func(*runtime.Runtime)) (*runtime.Runtime, error) {
+	rt, err := runtime.New(findStdlibPath())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create runtime: %w", err)
+	}
+	if err := rt.StdlibError(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: GOTS_STDLIB_PATH: %v; using the stdlib built into gots\n", err)
+	}
+	if err := applyCompilerOptions(rt, filename); err != nil {
+		rt.Shutdown()
+		return nil, err
+	}
+	if root, err := projectRoot(filepath.Dir(filename)); err == nil {
+		rt.SetTranspileCacheDir(filepath.Join(root, transpiler.CacheDir))
+	}
+
+	// Register permission-checked APIs
+	permManager, err := buildPermissionManager(perms, filename)
+	if err != nil {
+		rt.Shutdown()
+		return nil, err
+	}
+	rt.SetArgv(scriptArgs)
+	if watcher := watchProjectConfig(filename); watcher != nil {
+		rt.SetConfigWatcher(watcher)
+	}
+	if configure != nil {
+		configure(rt)
+	}
+	if err := rt.EnableSecureAPIs(permManager, entryModuleID); err != nil {
+		rt.Shutdown()
+		return nil, fmt.Errorf("failed to enable runtime APIs: %w", err)
+	}
+	return rt, nil
+}
+
 func initProject(cmd *cobra.Command, args []string) error {
 	projectName := "my-gots-project"
 	if len(args) > 0 {
 		projectName = args[0]
 	}
 
-	// Create project directory
-	if err := os.MkdirAll(projectName, 0755); err != nil {
-		return fmt.Errorf("failed to create project directory: %w", err)
-	}
-
-	// Create main.ts
-	mainContent := `// Main entry point
-console.log("Hello from GoTS Runtime!");
-
-export function main(): void {
-    console.log("Main function executed");
-}
-`
-	if err := os.WriteFile(filepath.Join(projectName, "main.ts"), []byte(mainContent), 0644); err != nil {
-		return fmt.Errorf("failed to create main.ts: %w", err)
+	// Write the template's main.ts, gots.json, README and tests into the
+	// project directory
+	files, err := scaffold.Write(initTemplate, projectName, scaffold.Data{Name: filepath.Base(projectName)})
+	if err != nil {
+		return err
 	}
 
-	// Create gots.json config
-	cfg := config.GetDefaultConfig()
-	cfg.Name = projectName
-	cfg.Main = "main.ts"
-
-	configPath := filepath.Join(projectName, "gots.json")
-	if err := config.SaveConfig(cfg, configPath); err != nil {
-		return fmt.Errorf("failed to create config file: %w", err)
+	// The templates must stay in step with the configuration format
+	if _, err := config.LoadConfig(filepath.Join(projectName, "gots.json")); err != nil {
+		return fmt.Errorf("template %s has an invalid gots.json: %w", initTemplate, err)
 	}
 
-	// Create README.md
-	readmeContent := fmt.Sprintf(`# %s
-
-A GoTS Runtime project.
-
-## Running
-
-`+"```bash"+`
-gots run main.ts
-`+"```"+`
-
-## Testing
-
-`+"```bash"+`
-gots test
-`+"```"+`
-`, projectName)
-	if err := os.WriteFile(filepath.Join(projectName, "README.md"), []byte(readmeContent), 0644); err != nil {
-		return fmt.Errorf("failed to create README.md: %w", err)
+	fmt.Printf("Project '%s' initialized successfully from the %s template:\n", projectName, initTemplate)
+	for _, file := range files {
+		fmt.Printf("  %s\n", filepath.Join(projectName, file))
 	}
-
-	fmt.Printf("Project '%s' initialized successfully!\n", projectName)
 	return nil
 }
 
@@ -299,6 +414,8 @@ func runTests(cmd *cobra.Command, args []string) error {
 
 	// Create test runner
 	runner := testrunner.NewRunner(projectRoot)
+	runner.SetParallelism(testParallel)
+	runner.SetUpdateSnapshots(testUpdateSnapshots)
 
 	// Discover and run tests
 	results, err := runner.RunTests(pattern)
@@ -450,27 +567,56 @@ func getTimestamp() string {
 }
 
 func searchDocs(cmd *cobra.Command, args []string) error {
-	query := ""
-	if len(args) > 0 {
-		query = args[0]
+	index, err := docs.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load documentation: %w", err)
 	}
+	query := strings.Join(args, " ")
 
 	if query == "" {
 		fmt.Println("GoTS Runtime Documentation")
-		fmt.Println("\nCore Topics:")
-		fmt.Println("  1. Getting Started - Basic usage and setup")
-		fmt.Println("  2. TypeScript Support - Type system and strictness")
-		fmt.Println("  3. Async/Await - Event-driven programming")
-		fmt.Println("  4. Concurrency - Goroutine and worker management")
-		fmt.Println("  5. Stdlib - Standard library reference")
-		fmt.Println("  6. Framework - Web framework usage")
-		fmt.Println("  7. Security - Permissions and sandboxing")
-		fmt.Println("\nUsage: gots doc [topic]")
+		fmt.Println("\nTopics:")
+		for _, topic := range index.Topics() {
+			fmt.Printf("  %-22s %s\n", topic.Name, topic.Title)
+		}
+		fmt.Println("\nUsage: gots doc <query>, or gots doc --open <topic>")
+		return nil
+	}
+
+	// --open shows a topic by name, or the topic of the best match
+	if docOpen {
+		topic, ok := index.Topic(query)
+		if !ok {
+			if results := index.Search(query); len(results) > 0 {
+				topic, ok = index.Topic(results[0].Section.Topic)
+			}
+		}
+		if !ok {
+			return fmt.Errorf("no documentation found for %q", query)
+		}
+		fmt.Print(topic.Text())
 		return nil
 	}
 
-	fmt.Printf("Searching documentation for: %s\n", query)
-	fmt.Println("Documentation feature is available online at: https://gots.dev/docs")
+	results := index.Search(query)
+	if len(results) == 0 {
+		fmt.Printf("No documentation found for %q\n", query)
+		return nil
+	}
+
+	const maxResults = 10
+	fmt.Printf("Results for %q:\n\n", query)
+	for i, result := range results {
+		if i == maxResults {
+			fmt.Printf("... and %d more\n\n", len(results)-maxResults)
+			break
+		}
+		fmt.Printf("  %s: %s\n", result.Section.Topic, result.Section.Heading)
+		if snippet := result.Section.Snippet(query); snippet != "" {
+			fmt.Printf("      %s\n", snippet)
+		}
+	}
+	fmt.Println("\nUse gots doc --open <topic> to read a topic.")
 	return nil
 }
 
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gots-runtime/internal/config"
+	"gots-runtime/internal/security"
+
+	"github.com/spf13/cobra"
+)
+
+// entryModuleID is the module ID granted permissions for the entry file
+const entryModuleID = "main"
+
+// permissionFlags holds the --allow-* flags of the run command
+type permissionFlags struct {
+	allowRead  string
+	allowWrite string
+	allowNet   string
+	allowEnv   bool
+	allowAll   bool
+	noPrompt   bool
+	auditLog   string
+}
+
+// scopedFlagAll is the value a scoped flag takes when given without a list
+const scopedFlagAll = "*"
+
+// registerPermissionFlags adds the --allow-* flags to cmd
+func registerPermissionFlags(cmd *cobra.Command, flags *permissionFlags) {
+	cmd.Flags().StringVar(&flags.allowRead, "allow-read", "", "Allow file system reads, optionally limited to a comma-separated list of paths")
+	cmd.Flags().Lookup("allow-read").NoOptDefVal = scopedFlagAll
+	cmd.Flags().StringVar(&flags.allowWrite, "allow-write", "", "Allow file system writes, optionally limited to a comma-separated list of paths")
+	cmd.Flags().Lookup("allow-write").NoOptDefVal = scopedFlagAll
+	cmd.Flags().StringVar(&flags.allowNet, "allow-net", "", "Allow network access, optionally limited to a comma-separated list of host[:port]")
+	cmd.Flags().Lookup("allow-net").NoOptDefVal = scopedFlagAll
+	cmd.Flags().BoolVar(&flags.allowEnv, "allow-env", false, "Allow reading and writing environment variables")
+	cmd.Flags().BoolVarP(&flags.allowAll, "allow-all", "A", false, "Allow all permissions")
+	cmd.Flags().BoolVar(&flags.noPrompt, "no-prompt", false, "Deny missing permissions instead of prompting")
+	cmd.Flags().StringVar(&flags.auditLog, "audit-log", "", "Append a JSON line for every permission check to this file")
+}
+
+// buildPermissionManager creates a permission manager for the entry module
+// from gots.json and the --allow-* flags
+func buildPermissionManager(flags *permissionFlags, entryFile string) (*security.PermissionManager, error) {
+	policy := security.NewPolicy(entryModuleID)
+	pm := security.NewPermissionManager()
+
+	if err := applyConfigPermissions(pm, policy, filepath.Dir(entryFile)); err != nil {
+		return nil, err
+	}
+
+	if flags.allowAll {
+		policy.Allow(security.PermissionAll)
+	}
+	applyScopedFlag(policy, flags.allowRead, security.PermissionFSRead)
+	applyScopedFlag(policy, flags.allowWrite, security.PermissionFSWrite)
+	applyScopedFlag(policy, flags.allowNet, security.PermissionNetDial, security.PermissionNetListen)
+	if flags.allowEnv {
+		policy.Allow(security.PermissionEnvRead)
+		policy.Allow(security.PermissionEnvWrite)
+	}
+
+	pm.RegisterPolicy(entryModuleID, policy)
+
+	if !flags.noPrompt && isInteractive() {
+		pm.SetPrompter(newTerminalPrompter())
+	}
+
+	if flags.auditLog != "" {
+		// The file stays open for the lifetime of the process
+		file, err := os.OpenFile(flags.auditLog, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+		pm.AddAuditSink(security.NewJSONLinesAuditSink(file))
+	}
+
+	return pm, nil
+}
+
+// applyConfigPermissions grants the permissions configured for the entry
+// module in the nearest gots.json, if any, and enforces its quotas
+func applyConfigPermissions(pm *security.PermissionManager, policy *security.Policy, dir string) error {
+	configPath, err := config.FindConfig(dir)
+	if err != nil {
+		return nil
+	}
+
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+	printConfigWarnings(configPath, cfg)
+
+	for i := range cfg.Permissions {
+		if cfg.Permissions[i].Module != entryModuleID {
+			continue
+		}
+		cfg.Permissions[i].ApplyTo(policy)
+	}
+
+	if len(cfg.Quotas) > 0 {
+		quotas := security.NewQuotaManager()
+		for i := range cfg.Quotas {
+			if err := cfg.Quotas[i].ApplyTo(quotas); err != nil {
+				return fmt.Errorf("invalid config: %w", err)
+			}
+		}
+		pm.SetQuotaManager(quotas)
+	}
+	return nil
+}
+
+// printConfigWarnings prints the problems found in a configuration that
+// do not prevent its use
+func printConfigWarnings(configPath string, cfg *config.ProjectConfig) {
+	for _, warning := range cfg.Warnings() {
+		fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", configPath, warning)
+	}
+}
+
+// applyScopedFlag grants permissions for a flag value. An empty value grants
+// nothing, "*" grants unrestricted access and a list grants scoped access.
+func applyScopedFlag(policy *security.Policy, value string, permissions ...security.Permission) {
+	if value == "" {
+		return
+	}
+
+	scopes := splitList(value)
+	for _, perm := range permissions {
+		if value == scopedFlagAll {
+			policy.Allow(perm)
+		} else {
+			policy.AllowScoped(perm, scopes...)
+		}
+	}
+}
+
+// splitList splits a comma-separated flag value
+func splitList(value string) []string {
+	parts := strings.Split(value, ",")
+	result := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// isInteractive reports whether stdin and stderr are terminals
+func isInteractive() bool {
+	for _, f := range []*os.File{os.Stdin, os.Stderr} {
+		info, err := f.Stat()
+		if err != nil || info.Mode()&os.ModeCharDevice == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// newTerminalPrompter returns a prompter that asks on the terminal. The
+// permission manager serializes calls, so prompts never interleave.
+func newTerminalPrompter() security.Prompter {
+	reader := bufio.NewReader(os.Stdin)
+
+	return func(moduleID string, permission security.Permission, target string) bool {
+		request := string(permission)
+		if target != "" {
+			request = fmt.Sprintf("%s access to %q", permission, target)
+		}
+		fmt.Fprintf(os.Stderr, "Module %q requests %s. Allow? [y/N] ", moduleID, request)
+
+		answer, err := reader.ReadString('\n')
+		if err != nil {
+			fmt.Fprintln(os.Stderr)
+			return false
+		}
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		return answer == "y" || answer == "yes"
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+
+	"gots-runtime/internal/replay"
+	"gots-runtime/internal/runtime"
+	"gots-runtime/internal/tsengine"
+
+	"github.com/spf13/cobra"
+)
+
+// startEventType is the type of the first event of a recording, which
+// describes the recorded run
+const startEventType = "process.start"
+
+// startEvent is the data of the start event
+type startEvent struct {
+	Entry    string   `json:"entry"`
+	Args     []string `json:"args"`
+	FakeTime bool     `json:"fakeTime,omitempty"`
+}
+
+// recording records a run of gots run --record
+type recording struct {
+	engine *replay.ReplayEngine
+	log    *replay.IOLog
+	file   string
+	once   sync.Once
+}
+
+// startRecording starts recording a run of entry with args into file;
+// fakeTime is the --fake-time flag of the run
+func startRecording(file, entry string, args []string, fakeTime bool) (*recording, error) {
+	absEntry, err := filepath.Abs(entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", entry, err)
+	}
+
+	engine := replay.NewReplayEngine()
+	log := replay.NewIORecorder(engine)
+	if _, err := engine.RecordEvent(startEventType, startEvent{Entry: absEntry, Args: args, FakeTime: fakeTime}); err != nil {
+		return nil, fmt.Errorf("failed to start recording: %w", err)
+	}
+
+	return &recording{engine: engine, log: log, file: file}, nil
+}
+
+// save writes the recording to its file; only the first call has an effect
+func (r *recording) save() {
+	r.once.Do(func() {
+		r.engine.StopRecording()
+		if err := r.engine.Save(r.file); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: failed to save recording: %v\n", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Recorded %d events to %s\n", r.engine.GetEventCount(), r.file)
+	})
+}
+
+// exit saves the recording and exits with code
+func (r *recording) exit(code int) {
+	r.save()
+	os.Exit(code)
+}
+
+// saveOnSignal saves the recording when the process is interrupted, so
+// that servers can be recorded until stopped
+func (r *recording) saveOnSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		r.exit(130)
+	}()
+}
+
+// newReplayCmd creates the replay command
+func newReplayCmd() *cobra.Command {
+	var perms permissionFlags
+	cmd := &cobra.Command{
+		Use:   "replay <file>",
+		Short: "Replay a recorded run",
+		Long:  "Run the program recorded with gots run --record again, serving its fs\nand env operations, the time it reads and its random values from the recording instead of the system, so that it\nsees the same files and environment. The replay fails if the program\nperforms operations that differ from the recorded ones.",
+		Args:  cobra.ExactArgs(1),
+		// Replay failures are not usage errors
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return replayFile(args[0], &perms)
+		},
+	}
+	registerPermissionFlags(cmd, &perms)
+	return cmd
+}
+
+// replayFile replays the run recorded in file
+func replayFile(file string, perms *permissionFlags) error {
+	engine := replay.NewReplayEngine()
+	if err := engine.Load(file); err != nil {
+		return err
+	}
+	if err := engine.StartReplay(); err != nil {
+		return err
+	}
+
+	first, err := engine.NextEvent()
+	if err != nil || first.Type != startEventType {
+		return fmt.Errorf("%s is not a gots run recording", file)
+	}
+	var start startEvent
+	if err := json.Unmarshal(first.Data, &start); err != nil {
+		return fmt.Errorf("invalid start event in %s: %w", file, err)
+	}
+
+	log, err := replay.NewIOReplayer(engine)
+	if err != nil {
+		return err
+	}
+
+	rt, err := newScriptRuntime(start.Entry, start.Args, perms, func(rt *runtime.Runtime) {
+		rt.SetIOLog(log)
+		rt.SetClockControl(start.FakeTime)
+	})
+	if err != nil {
+		return err
+	}
+	defer rt.Shutdown()
+
+	fmt.Printf("Replaying: %s\n", start.Entry)
+	if _, err := rt.ExecuteFile(start.Entry); err != nil {
+		if divergence := log.Err(); divergence != nil {
+			return divergence
+		}
+		if denial, ok := tsengine.PermissionDenial(err); ok {
+			return fmt.Errorf("%w\nHint: %s", err, tsengine.PermissionHint(denial))
+		}
+		return err
+	}
+	if err := rt.Wait(context.Background()); err != nil {
+		return err
+	}
+
+	if err := log.Err(); err != nil {
+		return err
+	}
+	if remaining := log.Remaining(); remaining > 0 {
+		return fmt.Errorf("replay diverged: %d recorded events were not replayed", remaining)
+	}
+	fmt.Fprintf(os.Stderr, "Replayed %d events\n", engine.GetEventCount()-1)
+	return nil
+}
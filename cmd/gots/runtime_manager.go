@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 
 	"gots-runtime/internal/config"
+	"gots-runtime/internal/eventloop"
 	"gots-runtime/internal/observability"
 	"gots-runtime/internal/runtime"
 	"gots-runtime/internal/security"
@@ -29,13 +30,23 @@ func NewRuntimeManager(projectRoot string) (*RuntimeManager, error) {
 		if err != nil {
 			return nil, fmt.Errorf("failed to load config: %w", err)
 		}
+		printConfigWarnings(configPath, cfg)
 	} else {
 		// Use default config
 		cfg = config.GetDefaultConfig()
 	}
 	
 	// Create runtime integration
-	integration := runtime.NewRuntimeIntegration()
+	opts := runtime.RuntimeOptions{}
+	if cfg.Runtime != nil {
+		policy, err := eventloop.ParseOverflowPolicy(cfg.Runtime.QueuePolicy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid runtime config: %w", err)
+		}
+		opts.EventQueueSize = cfg.Runtime.EventQueueSize
+		opts.QueuePolicy = policy
+	}
+	integration := runtime.NewRuntimeIntegrationWithOptions(opts)
 	
 	// Initialize runtime
 	if err := integration.Initialize(); err != nil {
@@ -74,11 +85,8 @@ func NewRuntimeManager(projectRoot string) (*RuntimeManager, error) {
 // registerModules registers modules with their permissions
 func registerModules(integration *runtime.RuntimeIntegration, cfg *config.ProjectConfig) error {
 	// Register permissions from config
-	for _, permConfig := range cfg.Permissions {
-		perms := permConfig.ToSecurityPermissions()
-		if err := integration.RegisterModule(permConfig.Module, perms...); err != nil {
-			return fmt.Errorf("failed to register module %s: %w", permConfig.Module, err)
-		}
+	for i := range cfg.Permissions {
+		integration.RegisterPolicy(cfg.Permissions[i].Policy())
 	}
 	
 	// Register modules from config
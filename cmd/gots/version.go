@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	goruntime "runtime"
+	"runtime/debug"
+
+	"gots-runtime/internal/transpiler"
+
+	"github.com/spf13/cobra"
+)
+
+// Build metadata, set at build time with
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%Y-%m-%dT%H:%M:%SZ)" ./cmd/gots
+//
+// commit and buildDate default to the VCS information Go records in
+// binaries built from a checkout
+var (
+	version   = "0.1.0"
+	commit    = ""
+	buildDate = ""
+)
+
+// gojaModule is the module path of the JavaScript engine
+const gojaModule = "github.com/dop251/goja"
+
+// versionInfo is the output of gots version
+type versionInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+	GoVersion string `json:"goVersion"`
+	OS        string `json:"os"`
+	Arch      string `json:"arch"`
+	Goja      string `json:"goja"`
+	ESBuild   string `json:"esbuild"` // empty when esbuild is not installed
+}
+
+// newVersionCmd creates the version command
+func newVersionCmd() *cobra.Command {
+	var asJSON bool
+	versionCmd := &cobra.Command{
+		Use:   "version",
+		Short: "Print version information",
+		Long:  "Print the version of gots, the commit and date it was built from, the Go\ntoolchain and platform, and the versions of the goja engine and of the\nesbuild used to transpile TypeScript.",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info := buildVersionInfo()
+			if asJSON {
+				encoded, err := json.MarshalIndent(info, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(encoded))
+				return nil
+			}
+			esbuild := info.ESBuild
+			if esbuild == "" {
+				esbuild = "not found (TypeScript is stripped without it)"
+			}
+			fmt.Printf("gots version %s\n", info.Version)
+			fmt.Printf("  commit:     %s\n", info.Commit)
+			fmt.Printf("  built:      %s\n", info.BuildDate)
+			fmt.Printf("  go:         %s %s/%s\n", info.GoVersion, info.OS, info.Arch)
+			fmt.Printf("  goja:       %s\n", info.Goja)
+			fmt.Printf("  esbuild:    %s\n", esbuild)
+			return nil
+		},
+	}
+	versionCmd.Flags().BoolVar(&asJSON, "json", false, "Print the version information as JSON")
+	return versionCmd
+}
+
+// buildVersionInfo collects the version information of this binary
+func buildVersionInfo() versionInfo {
+	info := versionInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: goruntime.Version(),
+		OS:        goruntime.GOOS,
+		Arch:      goruntime.GOARCH,
+		Goja:      "unknown",
+	}
+
+	if build, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range build.Deps {
+			if dep.Path == gojaModule {
+				info.Goja = dep.Version
+				if dep.Replace != nil {
+					info.Goja = dep.Replace.Version
+				}
+			}
+		}
+		modified := false
+		for _, setting := range build.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+		if modified && commit == "" && info.Commit != "" {
+			info.Commit += "-dirty"
+		}
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.BuildDate == "" {
+		info.BuildDate = "unknown"
+	}
+
+	if v, err := transpiler.ESBuildVersion(); err == nil {
+		info.ESBuild = v
+	}
+	return info
+}
@@ -1,31 +1,45 @@
 package runtime
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
 	"regexp"
+	"sort"
 	"strings"
 	"sync"
+
+	"gots-runtime/internal/api"
+	"gots-runtime/internal/observability"
 )
 
 // App represents the runtime-aware framework application
 type App struct {
 	name            string
-	middleware      []Middleware
+	middleware      []orderedMiddleware
 	routes          map[string]Route
 	dynamicRoutes   []*DynamicRoute
 	lifecycle       *Lifecycle
 	errorHandler    ErrorHandler
 	notFoundHandler NotFoundHandler
 	panicHandler    PanicHandler
+	metrics         *MetricsData
+	devMode         bool
+	logger          *observability.Logger
+	proxies         []proxyRoute
+	shedder         LoadShedder
+	inFlight        int64 // admitted requests, reported to shedder
 	mu              sync.RWMutex
 }
 
 // DynamicRoute represents a route with dynamic parameters
 type DynamicRoute struct {
-	Method  string
-	Pattern *regexp.Regexp
-	Path    string
-	Handler Handler
+	Method   string
+	Pattern  *regexp.Regexp
+	Path     string
+	Handler  Handler
+	Priority Priority // load shedding class
 }
 
 // ErrorHandler handles errors during request processing
@@ -34,32 +48,106 @@ type ErrorHandler func(ctx *Context, err error) error
 // NotFoundHandler handles 404 not found errors
 type NotFoundHandler func(ctx *Context) error
 
-// PanicHandler handles panics in handlers
+// PanicHandler handles panics in handlers. Its result is returned by
+// App.Handle.
 type PanicHandler func(ctx *Context, r interface{}) error
 
 // Middleware is a middleware function
 type Middleware func(ctx *Context, next Next) error
 
+// Middleware phases. App middleware runs in ascending priority, so
+// middleware with a lower priority wraps middleware with a higher one;
+// middleware with equal priority runs in registration order. Use registers
+// middleware in PhaseMain.
+const (
+	PhasePre  = -100 // outermost, e.g. recovery and request IDs
+	PhaseMain = 0
+	PhasePost = 100 // innermost, just before the route
+)
+
+// ParsePhase returns the priority of a named phase: "pre", "main" or
+// "post"
+func ParsePhase(name string) (int, error) {
+	switch name {
+	case "pre":
+		return PhasePre, nil
+	case "main":
+		return PhaseMain, nil
+	case "post":
+		return PhasePost, nil
+	}
+	return 0, fmt.Errorf("unknown middleware phase: %s", name)
+}
+
+// orderedMiddleware is app middleware with its priority
+type orderedMiddleware struct {
+	middleware Middleware
+	priority   int
+}
+
 // Next is the next middleware/handler in the chain
 type Next func() error
 
 // Context represents request context
 type Context struct {
-	Request  *Request
-	Response *Response
-	App      *App
-	Data     map[string]interface{}
-	mu       sync.RWMutex
+	Request   *Request
+	Response  *Response
+	App       *App
+	Data      map[string]interface{}
+	ctx       context.Context
+	session   *Session
+	logFields map[string]interface{} // added to the entries of Logger
+	route     string                 // path pattern of the matched route
+	mu        sync.RWMutex
+}
+
+// Context returns the Go context of the request. It is cancelled when the
+// client disconnects or a TimeoutMiddleware deadline passes, so
+// long-running handlers should stop, and pass it to downstream calls.
+func (c *Context) Context() context.Context {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// Route returns the path pattern of the route that handles the request,
+// e.g. /users/:id, or "" before routing and for requests no route matches.
+// Proxied requests match their prefix followed by /*.
+func (c *Context) Route() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.route
+}
+
+// setRoute records the path pattern of the route that handles the request
+func (c *Context) setRoute(route string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.route = route
+}
+
+// SetContext replaces the Go context of the request
+func (c *Context) SetContext(ctx context.Context) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ctx = ctx
 }
 
 // Request represents an HTTP request
 type Request struct {
-	Method  string
-	Path    string
-	Headers map[string]string
-	Body    []byte
-	Query   map[string]string
-	Params  map[string]string
+	Method     string
+	Path       string
+	Headers    map[string]string
+	Body       []byte
+	Query      map[string]string
+	Params     map[string]string
+	RawQuery   string // the query string, without the "?"
+	Host       string // the Host header
+	RemoteAddr string // the client's address, e.g. "10.0.0.1:52344"
+	TLS        bool   // whether the request arrived over TLS
 }
 
 // Response represents an HTTP response
@@ -67,13 +155,43 @@ type Response struct {
 	Status  int
 	Headers map[string]string
 	Body    []byte
+	Cookies []*http.Cookie
+	Stream  *api.Stream // set by Context.Stream; Body is then ignored
+	// Deferred, when set by a handler, completes the response once Handle
+	// has returned, e.g. by waiting for an upstream server. Servers that
+	// run Handle on the event loop call it off the loop.
+	Deferred func() error
+}
+
+// Written reports whether a body or stream has been written to the
+// response
+func (r *Response) Written() bool {
+	return len(r.Body) > 0 || r.Stream != nil
+}
+
+// JSON writes value as a JSON response body with the given status
+func (c *Context) JSON(status int, value interface{}) error {
+	body, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON response: %w", err)
+	}
+
+	if c.Response.Headers == nil {
+		c.Response.Headers = make(map[string]string)
+	}
+	c.Response.Status = status
+	c.Response.Headers["Content-Type"] = "application/json"
+	c.Response.Headers["Content-Length"] = fmt.Sprintf("%d", len(body))
+	c.Response.Body = body
+	return nil
 }
 
 // Route represents a route
 type Route struct {
-	Method  string
-	Path    string
-	Handler Handler
+	Method   string
+	Path     string
+	Handler  Handler
+	Priority Priority // load shedding class
 }
 
 // Handler is a request handler
@@ -90,7 +208,7 @@ type Lifecycle struct {
 func NewApp(name string) *App {
 	return &App{
 		name:          name,
-		middleware:    make([]Middleware, 0),
+		middleware:    make([]orderedMiddleware, 0),
 		routes:        make(map[string]Route),
 		dynamicRoutes: make([]*DynamicRoute, 0),
 		lifecycle: &Lifecycle{
@@ -100,14 +218,15 @@ func NewApp(name string) *App {
 		errorHandler:    DefaultErrorHandler,
 		notFoundHandler: DefaultNotFoundHandler,
 		panicHandler:    DefaultPanicHandler,
+		metrics:         NewMetricsData(),
+		logger:          observability.NewLogger(observability.LogLevelInfo),
 	}
 }
 
-// DefaultErrorHandler provides default error handling
-func DefaultErrorHandler(ctx *Context, err error) error {
-	ctx.Response.Status = 500
-	ctx.Response.Body = []byte(fmt.Sprintf("Internal Server Error: %v", err))
-	return err
+// Metrics returns a snapshot of the request metrics collected by
+// MetricsMiddleware for this app
+func (a *App) Metrics() *MetricsData {
+	return a.metrics.Snapshot()
 }
 
 // DefaultNotFoundHandler provides default 404 handling
@@ -117,57 +236,87 @@ func DefaultNotFoundHandler(ctx *Context) error {
 	return nil
 }
 
-// DefaultPanicHandler provides default panic handling
+// DefaultPanicHandler logs the panic with its stack trace and passes the
+// resulting PanicError to the app's error handler
 func DefaultPanicHandler(ctx *Context, r interface{}) error {
-	ctx.Response.Status = 500
-	ctx.Response.Body = []byte(fmt.Sprintf("Internal Server Error: %v", r))
-	return fmt.Errorf("panic: %v", r)
+	err := recoverPanic(ctx, r)
+
+	errorHandler := ErrorHandler(DefaultErrorHandler)
+	if ctx.App != nil {
+		ctx.App.mu.RLock()
+		errorHandler = ctx.App.errorHandler
+		ctx.App.mu.RUnlock()
+	}
+	if handleErr := errorHandler(ctx, err); handleErr != nil {
+		return handleErr
+	}
+	return err
 }
 
-// Use adds middleware
+// Use adds middleware in PhaseMain
 func (a *App) Use(middleware Middleware) {
+	a.UsePriority(middleware, PhaseMain)
+}
+
+// UsePriority adds middleware with the given priority, such as PhasePre.
+// It runs after all middleware with a lower priority, and after middleware
+// with the same priority that was added earlier.
+func (a *App) UsePriority(middleware Middleware, priority int) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
-	a.middleware = append(a.middleware, middleware)
+
+	i := sort.Search(len(a.middleware), func(i int) bool {
+		return a.middleware[i].priority > priority
+	})
+	a.middleware = append(a.middleware, orderedMiddleware{})
+	copy(a.middleware[i+1:], a.middleware[i:])
+	a.middleware[i] = orderedMiddleware{middleware: middleware, priority: priority}
 }
 
 // Get registers a GET route
-func (a *App) Get(path string, handler Handler) {
-	a.registerRoute("GET", path, handler)
+func (a *App) Get(path string, handler Handler, middleware ...Middleware) {
+	a.registerRoute("GET", path, handler, middleware...)
 }
 
 // Post registers a POST route
-func (a *App) Post(path string, handler Handler) {
-	a.registerRoute("POST", path, handler)
+func (a *App) Post(path string, handler Handler, middleware ...Middleware) {
+	a.registerRoute("POST", path, handler, middleware...)
 }
 
 // Put registers a PUT route
-func (a *App) Put(path string, handler Handler) {
-	a.registerRoute("PUT", path, handler)
+func (a *App) Put(path string, handler Handler, middleware ...Middleware) {
+	a.registerRoute("PUT", path, handler, middleware...)
 }
 
 // Delete registers a DELETE route
-func (a *App) Delete(path string, handler Handler) {
-	a.registerRoute("DELETE", path, handler)
+func (a *App) Delete(path string, handler Handler, middleware ...Middleware) {
+	a.registerRoute("DELETE", path, handler, middleware...)
 }
 
 // Patch registers a PATCH route
-func (a *App) Patch(path string, handler Handler) {
-	a.registerRoute("PATCH", path, handler)
+func (a *App) Patch(path string, handler Handler, middleware ...Middleware) {
+	a.registerRoute("PATCH", path, handler, middleware...)
 }
 
 // Options registers an OPTIONS route
-func (a *App) Options(path string, handler Handler) {
-	a.registerRoute("OPTIONS", path, handler)
+func (a *App) Options(path string, handler Handler, middleware ...Middleware) {
+	a.registerRoute("OPTIONS", path, handler, middleware...)
 }
 
 // Head registers a HEAD route
-func (a *App) Head(path string, handler Handler) {
-	a.registerRoute("HEAD", path, handler)
+func (a *App) Head(path string, handler Handler, middleware ...Middleware) {
+	a.registerRoute("HEAD", path, handler, middleware...)
 }
 
-// registerRoute registers a route
-func (a *App) registerRoute(method, path string, handler Handler) {
+// registerRoute registers a route with optional route-specific middleware.
+// Paths with :param segments are matched as dynamic routes.
+func (a *App) registerRoute(method, path string, handler Handler, middleware ...Middleware) {
+	if paramPattern.MatchString(path) {
+		a.Dynamic(method, path, handler, middleware...)
+		return
+	}
+	handler = withMiddleware(handler, middleware)
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -179,8 +328,27 @@ func (a *App) registerRoute(method, path string, handler Handler) {
 	}
 }
 
+// withMiddleware wraps a route handler in route-specific middleware. The
+// middleware runs in order after the app's global middleware and before
+// the handler.
+func withMiddleware(handler Handler, middleware []Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		mw := middleware[i]
+		inner := handler
+		handler = func(ctx *Context) error {
+			return mw(ctx, func() error {
+				return inner(ctx)
+			})
+		}
+	}
+	return handler
+}
+
 // Dynamic registers a dynamic route with parameters (e.g., /users/:id/posts/:postid)
-func (a *App) Dynamic(method, path string, handler Handler) {
+// and optional route-specific middleware
+func (a *App) Dynamic(method, path string, handler Handler, middleware ...Middleware) {
+	handler = withMiddleware(handler, middleware)
+
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
@@ -199,14 +367,46 @@ func (a *App) Dynamic(method, path string, handler Handler) {
 	})
 }
 
+// paramPattern matches :param segments in route paths
+var paramPattern = regexp.MustCompile(`:([a-zA-Z_][a-zA-Z0-9_]*)`)
+
 // convertPathToPattern converts a path like /users/:id to a regex pattern
 func convertPathToPattern(path string) string {
 	pattern := regexp.QuoteMeta(path)
 	pattern = strings.ReplaceAll(pattern, "\\:", ":")
-	pattern = regexp.MustCompile(`:([a-zA-Z_][a-zA-Z0-9_]*)`).ReplaceAllString(pattern, `(?P<$1>[^/]+)`)
+	pattern = paramPattern.ReplaceAllString(pattern, `(?P<$1>[^/]+)`)
 	return "^" + pattern + "$"
 }
 
+// SetDevMode sets whether error responses include the details of internal
+// errors. It must not be enabled in production.
+func (a *App) SetDevMode(dev bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.devMode = dev
+}
+
+// DevMode reports whether the app is in dev mode
+func (a *App) DevMode() bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.devMode
+}
+
+// SetLogger sets the logger used to report panics
+func (a *App) SetLogger(logger *observability.Logger) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.logger = logger
+}
+
+// Logger returns the app's logger
+func (a *App) Logger() *observability.Logger {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.logger
+}
+
 // SetErrorHandler sets the error handler
 func (a *App) SetErrorHandler(handler ErrorHandler) {
 	a.mu.Lock()
@@ -273,20 +473,25 @@ func (a *App) Stop() error {
 }
 
 // Handle handles a request
-func (a *App) Handle(ctx *Context) error {
-	// Defer panic recovery
+func (a *App) Handle(ctx *Context) (err error) {
+	// Recover panics that escape the middleware chain
 	defer func() {
 		if r := recover(); r != nil {
 			a.mu.RLock()
 			panicHandler := a.panicHandler
 			a.mu.RUnlock()
-			_ = panicHandler(ctx, r)
+			err = panicHandler(ctx, r)
 		}
 	}()
 
 	// Build middleware chain
 	var next Next
 	next = func() error {
+		if timing := ctx.Timing(); timing != nil {
+			timing.beginHandler()
+			defer timing.endHandler()
+		}
+
 		// Find route
 		key := fmt.Sprintf("%s:%s", ctx.Request.Method, ctx.Request.Path)
 		a.mu.RLock()
@@ -294,6 +499,7 @@ func (a *App) Handle(ctx *Context) error {
 		a.mu.RUnlock()
 
 		if ok {
+			ctx.setRoute(route.Path)
 			return route.Handler(ctx)
 		}
 
@@ -310,11 +516,18 @@ func (a *App) Handle(ctx *Context) error {
 					ctx.Request.Params[key] = val
 				}
 				a.mu.RUnlock()
+				ctx.setRoute(dynRoute.Path)
 				return dynRoute.Handler(ctx)
 			}
 		}
 		a.mu.RUnlock()
 
+		// Try proxied path prefixes
+		if handler, prefix := a.proxyHandler(ctx.Request.Path); handler != nil {
+			ctx.setRoute(strings.TrimSuffix(prefix, "/") + "/*")
+			return handler(ctx)
+		}
+
 		// Not found
 		a.mu.RLock()
 		notFoundHandler := a.notFoundHandler
@@ -325,7 +538,9 @@ func (a *App) Handle(ctx *Context) error {
 	// Execute middleware in order
 	a.mu.RLock()
 	middleware := make([]Middleware, len(a.middleware))
-	copy(middleware, a.middleware)
+	for i, entry := range a.middleware {
+		middleware[i] = entry.middleware
+	}
 	errorHandler := a.errorHandler
 	a.mu.RUnlock()
 
@@ -338,7 +553,7 @@ func (a *App) Handle(ctx *Context) error {
 	}
 
 	// Execute the middleware chain
-	err := next()
+	err = next()
 
 	// Handle errors
 	if err != nil {
@@ -0,0 +1,41 @@
+package runtime
+
+import "testing"
+
+func TestRouteMethodsMatchPathParameters(t *testing.T) {
+	app := NewApp("test")
+	app.Get("/users", func(ctx *Context) error {
+		ctx.Response.Body = []byte("list")
+		return nil
+	})
+	app.Patch("/users/:id", func(ctx *Context) error {
+		ctx.Response.Body = []byte("patched " + ctx.Request.Params["id"])
+		return nil
+	})
+	app.Get("/users/:id/posts/:post", func(ctx *Context) error {
+		ctx.Response.Body = []byte(ctx.Request.Params["id"] + "/" + ctx.Request.Params["post"])
+		return nil
+	})
+
+	for _, test := range []struct {
+		method, path, body string
+	}{
+		{"GET", "/users", "list"},
+		{"PATCH", "/users/7", "patched 7"},
+		{"GET", "/users/7/posts/9", "7/9"},
+	} {
+		ctx := newTestContext(app, test.method, test.path)
+		if err := app.Handle(ctx); err != nil {
+			t.Fatalf("%s %s: %v", test.method, test.path, err)
+		}
+		if got := string(ctx.Response.Body); got != test.body {
+			t.Errorf("%s %s gave %q, want %q", test.method, test.path, got, test.body)
+		}
+	}
+
+	ctx := newTestContext(app, "GET", "/users/7")
+	_ = app.Handle(ctx)
+	if ctx.Response.Status != 404 {
+		t.Errorf("GET /users/7 gave %d, want 404", ctx.Response.Status)
+	}
+}
@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CookieOptions configures a cookie set on the response
+type CookieOptions struct {
+	Path     string // defaults to "/"
+	Domain   string
+	MaxAge   int // seconds; 0 means a session cookie
+	Expires  time.Time
+	HttpOnly bool
+	Secure   bool
+	SameSite string // "Strict", "Lax" or "None"
+}
+
+// Cookies returns the cookies sent with the request
+func (c *Context) Cookies() map[string]string {
+	cookies := make(map[string]string)
+	header := c.Request.Headers["Cookie"]
+	if header == "" {
+		return cookies
+	}
+
+	parsed, err := http.ParseCookie(header)
+	if err != nil {
+		return cookies
+	}
+	for _, cookie := range parsed {
+		if _, ok := cookies[cookie.Name]; !ok {
+			cookies[cookie.Name] = cookie.Value
+		}
+	}
+	return cookies
+}
+
+// Cookie returns the value of a request cookie
+func (c *Context) Cookie(name string) (string, bool) {
+	value, ok := c.Cookies()[name]
+	return value, ok
+}
+
+// SetCookie adds a Set-Cookie header to the response, replacing a cookie
+// previously set with the same name, path and domain
+func (c *Context) SetCookie(name, value string, opts CookieOptions) {
+	cookie := &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     opts.Path,
+		Domain:   opts.Domain,
+		MaxAge:   opts.MaxAge,
+		Expires:  opts.Expires,
+		HttpOnly: opts.HttpOnly,
+		Secure:   opts.Secure,
+		SameSite: parseSameSite(opts.SameSite),
+	}
+	if cookie.Path == "" {
+		cookie.Path = "/"
+	}
+	// Browsers reject SameSite=None cookies that are not secure
+	if cookie.SameSite == http.SameSiteNoneMode {
+		cookie.Secure = true
+	}
+
+	for i, existing := range c.Response.Cookies {
+		if existing.Name == cookie.Name && existing.Path == cookie.Path && existing.Domain == cookie.Domain {
+			c.Response.Cookies[i] = cookie
+			return
+		}
+	}
+	c.Response.Cookies = append(c.Response.Cookies, cookie)
+}
+
+// DeleteCookie expires a cookie on the client. Path and Domain must match
+// the options the cookie was set with.
+func (c *Context) DeleteCookie(name string, opts CookieOptions) {
+	opts.MaxAge = -1
+	opts.Expires = time.Unix(0, 0)
+	c.SetCookie(name, "", opts)
+}
+
+// parseSameSite converts a SameSite option to its http mode
+func parseSameSite(sameSite string) http.SameSite {
+	switch strings.ToLower(sameSite) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "lax":
+		return http.SameSiteLaxMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteDefaultMode
+	}
+}
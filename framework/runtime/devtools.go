@@ -2,7 +2,10 @@ package runtime
 
 import (
 	"fmt"
+	"html/template"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -95,16 +98,10 @@ func RequestIDMiddlewareWithHeaders(ctx *Context, next Next) error {
 	return next()
 }
 
-// StackTraceMiddleware logs stack traces on panic
+// StackTraceMiddleware logs stack traces on panic; it behaves like
+// RecoveryMiddleware
 func StackTraceMiddleware(ctx *Context, next Next) error {
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Printf("[PANIC] %v\n", r)
-			ctx.Response.Status = 500
-		}
-	}()
-
-	return next()
+	return RecoveryMiddleware(ctx, next)
 }
 
 // MockDataMiddleware provides mock data for development
@@ -114,15 +111,10 @@ func MockDataMiddleware(mockEndpoints map[string]interface{}) Middleware {
 		path := ctx.Request.Path
 
 		if mockData, ok := mockEndpoints[path]; ok {
-			ctx.Response.Status = 200
-			if ctx.Response.Headers == nil {
-				ctx.Response.Headers = make(map[string]string)
+			if err := ctx.JSON(200, mockData); err != nil {
+				return err
 			}
-			ctx.Response.Headers["Content-Type"] = "application/json"
 			ctx.Response.Headers["X-Mock"] = "true"
-
-			// In production, this would JSON-encode the mock data
-			ctx.Response.Body = []byte(fmt.Sprintf("%v", mockData))
 			return nil
 		}
 
@@ -130,10 +122,12 @@ func MockDataMiddleware(mockEndpoints map[string]interface{}) Middleware {
 	}
 }
 
-// APIDocMiddleware generates API documentation
+// APIDocMiddleware serves HTML API documentation at /api/docs and an
+// OpenAPI 3 document at /api/openapi.json
 func APIDocMiddleware(app *App) Middleware {
 	return func(ctx *Context, next Next) error {
-		if ctx.Request.Path == "/api/docs" {
+		switch ctx.Request.Path {
+		case "/api/docs":
 			ctx.Response.Status = 200
 			if ctx.Response.Headers == nil {
 				ctx.Response.Headers = make(map[string]string)
@@ -143,12 +137,88 @@ func APIDocMiddleware(app *App) Middleware {
 			docs := generateAPIDocs(app)
 			ctx.Response.Body = []byte(docs)
 			return nil
+		case "/api/openapi.json":
+			return ctx.JSON(200, generateOpenAPI(app))
 		}
 
 		return next()
 	}
 }
 
+// routeDoc describes a registered route for documentation
+type routeDoc struct {
+	Method string
+	Path   string
+	Params []string
+}
+
+// methodOrder is the order in which methods are listed in the docs
+var methodOrder = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS", "HEAD"}
+
+// collectRoutes returns the app's static and dynamic routes grouped by
+// method, sorted by path within each method
+func collectRoutes(app *App) map[string][]routeDoc {
+	app.mu.RLock()
+	defer app.mu.RUnlock()
+
+	grouped := make(map[string][]routeDoc)
+	for _, route := range app.routes {
+		grouped[route.Method] = append(grouped[route.Method], routeDoc{
+			Method: route.Method,
+			Path:   route.Path,
+		})
+	}
+	for _, route := range app.dynamicRoutes {
+		grouped[route.Method] = append(grouped[route.Method], routeDoc{
+			Method: route.Method,
+			Path:   route.Path,
+			Params: pathParams(route.Path),
+		})
+	}
+
+	for _, routes := range grouped {
+		sort.Slice(routes, func(i, j int) bool {
+			return routes[i].Path < routes[j].Path
+		})
+	}
+	return grouped
+}
+
+// sortedMethods returns the methods in grouped in documentation order
+func sortedMethods(grouped map[string][]routeDoc) []string {
+	methods := make([]string, 0, len(grouped))
+	for _, method := range methodOrder {
+		if _, ok := grouped[method]; ok {
+			methods = append(methods, method)
+		}
+	}
+
+	var others []string
+	for method := range grouped {
+		known := false
+		for _, m := range methodOrder {
+			if m == method {
+				known = true
+				break
+			}
+		}
+		if !known {
+			others = append(others, method)
+		}
+	}
+	sort.Strings(others)
+	return append(methods, others...)
+}
+
+// pathParams returns the names of the :param segments of a path
+func pathParams(path string) []string {
+	var params []string
+	for _, match := range paramPattern.FindAllStringSubmatch(path, -1) {
+		params = append(params, match[1])
+	}
+	return params
+}
+
 func generateAPIDocs(app *App) string {
 	html := `<!DOCTYPE html>
 <html>
@@ -162,21 +232,26 @@ func generateAPIDocs(app *App) string {
         .post { background: #007bff; color: white; }
         .put { background: #ffc107; color: black; }
         .delete { background: #dc3545; color: white; }
+        .param { color: #6f42c1; }
     </style>
 </head>
 <body>
     <h1>API Documentation</h1>
     <div id="routes">`
 
-	app.mu.RLock()
-	defer app.mu.RUnlock()
-
-	for _, route := range app.routes {
-		methodClass := strings.ToLower(route.Method)
+	grouped := collectRoutes(app)
+	for _, method := range sortedMethods(grouped) {
+		methodClass := strings.ToLower(method)
 		html += fmt.Sprintf(`
+    <h2>%s</h2>`, method)
+
+		for _, route := range grouped[method] {
+			path := paramPattern.ReplaceAllString(template.HTMLEscapeString(route.Path), `<span class="param">:$1</span>`)
+			html += fmt.Sprintf(`
     <div class="route">
         <span class="method %s">%s</span> %s
-    </div>`, methodClass, route.Method, route.Path)
+    </div>`, methodClass, method, path)
+		}
 	}
 
 	html += `
@@ -187,69 +262,176 @@ func generateAPIDocs(app *App) string {
 	return html
 }
 
+// generateOpenAPI builds an OpenAPI 3 document from the registered routes
+func generateOpenAPI(app *App) map[string]interface{} {
+	paths := make(map[string]interface{})
+
+	grouped := collectRoutes(app)
+	for _, method := range sortedMethods(grouped) {
+		for _, route := range grouped[method] {
+			// OpenAPI writes path parameters as {param}
+			path := paramPattern.ReplaceAllString(route.Path, "{$1}")
+			item, ok := paths[path].(map[string]interface{})
+			if !ok {
+				item = make(map[string]interface{})
+				paths[path] = item
+			}
+
+			operation := map[string]interface{}{
+				"summary": fmt.Sprintf("%s %s", method, route.Path),
+				"responses": map[string]interface{}{
+					"default": map[string]interface{}{"description": "Response"},
+				},
+			}
+			if len(route.Params) > 0 {
+				params := make([]interface{}, 0, len(route.Params))
+				for _, name := range route.Params {
+					params = append(params, map[string]interface{}{
+						"name":     name,
+						"in":       "path",
+						"required": true,
+						"schema":   map[string]interface{}{"type": "string"},
+					})
+				}
+				operation["parameters"] = params
+			}
+			item[strings.ToLower(method)] = operation
+		}
+	}
+
+	app.mu.RLock()
+	title := app.name
+	app.mu.RUnlock()
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   title,
+			"version": "1.0.0",
+		},
+		"paths": paths,
+	}
+}
+
 // HealthCheckMiddleware provides health check endpoint
 func HealthCheckMiddleware(app *App) Middleware {
 	return func(ctx *Context, next Next) error {
 		if ctx.Request.Path == "/health" {
-			ctx.Response.Status = 200
-			if ctx.Response.Headers == nil {
-				ctx.Response.Headers = make(map[string]string)
-			}
-			ctx.Response.Headers["Content-Type"] = "application/json"
-			ctx.Response.Body = []byte(`{"status": "healthy"}`)
-			return nil
+			return ctx.JSON(200, map[string]interface{}{"status": "healthy"})
 		}
 
 		if ctx.Request.Path == "/ready" {
-			ctx.Response.Status = 200
-			if ctx.Response.Headers == nil {
-				ctx.Response.Headers = make(map[string]string)
-			}
-			ctx.Response.Headers["Content-Type"] = "application/json"
-			ctx.Response.Body = []byte(`{"ready": true}`)
-			return nil
+			return ctx.JSON(200, map[string]interface{}{"ready": true})
 		}
 
 		return next()
 	}
 }
 
-// MetricsMiddleware collects request metrics
+// MetricsData holds request metrics. AverageLatency is the mean request
+// duration in milliseconds; RequestCounts and ErrorCounts are keyed by the
+// method and route pattern of finished requests, e.g. "GET /users/:id".
 type MetricsData struct {
 	TotalRequests  int64
 	TotalErrors    int64
 	AverageLatency int64
 	RequestCounts  map[string]int64
 	ErrorCounts    map[string]int64
+	totalLatency   time.Duration
+	completed      int64
+	mu             sync.RWMutex
 }
 
-var globalMetrics = &MetricsData{
-	RequestCounts: make(map[string]int64),
-	ErrorCounts:   make(map[string]int64),
+// NewMetricsData creates empty request metrics
+func NewMetricsData() *MetricsData {
+	return &MetricsData{
+		RequestCounts: make(map[string]int64),
+		ErrorCounts:   make(map[string]int64),
+	}
 }
 
-// MetricsMiddleware collects metrics
+// recordStart counts a request
+func (m *MetricsData) recordStart() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.TotalRequests++
+}
+
+// recordEnd counts a finished request for key and records its duration
+// and outcome
+func (m *MetricsData) recordEnd(key string, duration time.Duration, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.RequestCounts[key]++
+	m.completed++
+	m.totalLatency += duration
+	m.AverageLatency = int64(m.totalLatency/time.Duration(m.completed)) / int64(time.Millisecond)
+
+	if err != nil {
+		m.TotalErrors++
+		m.ErrorCounts[key]++
+	}
+}
+
+// Snapshot returns a copy of the metrics that is safe to read while
+// requests are being recorded
+func (m *MetricsData) Snapshot() *MetricsData {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snapshot := &MetricsData{
+		TotalRequests:  m.TotalRequests,
+		TotalErrors:    m.TotalErrors,
+		AverageLatency: m.AverageLatency,
+		RequestCounts:  make(map[string]int64, len(m.RequestCounts)),
+		ErrorCounts:    make(map[string]int64, len(m.ErrorCounts)),
+		totalLatency:   m.totalLatency,
+		completed:      m.completed,
+	}
+	for k, v := range m.RequestCounts {
+		snapshot.RequestCounts[k] = v
+	}
+	for k, v := range m.ErrorCounts {
+		snapshot.ErrorCounts[k] = v
+	}
+	return snapshot
+}
+
+// globalMetrics collects metrics for requests handled without an App
+var globalMetrics = NewMetricsData()
+
+// unmatchedRoute is the route of the metrics of requests no route matches
+const unmatchedRoute = "(unmatched)"
+
+// MetricsMiddleware collects metrics into the metrics of the context's app.
+// Requests are counted under their method and the pattern of the route that
+// handled them, e.g. "GET /users/:id", so that the number of keys is bounded
+// by the routes rather than the paths requested.
 func MetricsMiddleware(ctx *Context, next Next) error {
 	start := time.Now()
 
-	globalMetrics.TotalRequests++
-	key := fmt.Sprintf("%s %s", ctx.Request.Method, ctx.Request.Path)
-	globalMetrics.RequestCounts[key]++
+	metrics := globalMetrics
+	if ctx.App != nil {
+		metrics = ctx.App.metrics
+	}
 
-	err := next()
+	// The route is only known once the request is routed, so requests
+	// are counted per route when they end
+	metrics.recordStart()
 
-	duration := time.Since(start)
-	globalMetrics.AverageLatency = int64(duration / time.Millisecond)
+	err := next()
 
-	if err != nil {
-		globalMetrics.TotalErrors++
-		globalMetrics.ErrorCounts[key]++
+	route := ctx.Route()
+	if route == "" {
+		route = unmatchedRoute
 	}
+	metrics.recordEnd(fmt.Sprintf("%s %s", ctx.Request.Method, route), time.Since(start), err)
 
 	return err
 }
 
-// GetMetrics returns current metrics
+// GetMetrics returns a snapshot of the metrics collected for requests
+// handled without an App. Use App.Metrics for per-app metrics.
 func GetMetrics() *MetricsData {
-	return globalMetrics
+	return globalMetrics.Snapshot()
 }
@@ -0,0 +1,29 @@
+package runtime
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMetricsAreKeyedByRoute(t *testing.T) {
+	app := NewApp("test")
+	app.Use(MetricsMiddleware)
+	app.Get("/users/:id", func(ctx *Context) error {
+		return nil
+	})
+
+	for i := 0; i < 100; i++ {
+		_ = app.Handle(newTestContext(app, "GET", fmt.Sprintf("/users/%d", i)))
+		_ = app.Handle(newTestContext(app, "GET", fmt.Sprintf("/missing/%d", i)))
+	}
+
+	metrics := app.Metrics()
+	want := map[string]int64{"GET /users/:id": 100, "GET (unmatched)": 100}
+	if !reflect.DeepEqual(metrics.RequestCounts, want) {
+		t.Errorf("request counts are %v, want %v", metrics.RequestCounts, want)
+	}
+	if metrics.TotalRequests != 200 {
+		t.Errorf("counted %d requests, want 200", metrics.TotalRequests)
+	}
+}
@@ -0,0 +1,130 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"gots-runtime/internal/observability"
+)
+
+// HTTPError is an error that carries the HTTP response it should produce.
+// Handlers and middleware return it to send a client error, such as a 404
+// with a message; the error handler renders its status, code and message.
+type HTTPError struct {
+	Status  int
+	Code    string      // machine-readable, e.g. "not_found"; derived from Status if empty
+	Message string      // sent to the client; the status text if empty
+	Details interface{} // optional JSON-encodable details, e.g. []FieldError
+	Err     error       // underlying cause, which is never sent to the client
+}
+
+// NewHTTPError creates an HTTP error with the given status and message
+func NewHTTPError(status int, message string) *HTTPError {
+	return &HTTPError{Status: status, Message: message}
+}
+
+// Error returns the status, message and cause of the error
+func (e *HTTPError) Error() string {
+	msg := fmt.Sprintf("%d %s", e.Status, e.message())
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying cause
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// code returns the error code, deriving it from the status if unset
+func (e *HTTPError) code() string {
+	if e.Code != "" {
+		return e.Code
+	}
+	text := http.StatusText(e.Status)
+	if text == "" {
+		return "error"
+	}
+	return strings.ToLower(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// message returns the error message, defaulting to the status text
+func (e *HTTPError) message() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Status)
+}
+
+// PanicError is a panic recovered from a handler or middleware
+type PanicError struct {
+	Value interface{} // the value passed to panic
+	Stack []byte      // the stack trace of the panicking goroutine
+}
+
+// Error returns the panic value
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// defaultLogger reports panics for contexts without an App
+var defaultLogger = observability.NewLogger(observability.LogLevelInfo)
+
+// recoverPanic converts a recovered panic value into a PanicError and logs
+// it with its stack trace. It must be called from the deferred function
+// that recovered the panic for the stack to include the panicking frames.
+func recoverPanic(ctx *Context, r interface{}) *PanicError {
+	err := &PanicError{Value: r, Stack: debug.Stack()}
+
+	ctx.Logger().Error("panic handling %s %s: %v\n%s", ctx.Request.Method, ctx.Request.Path, r, err.Stack)
+
+	return err
+}
+
+// ErrorBody is the JSON body of an error response
+type ErrorBody struct {
+	Error ErrorDetail `json:"error"`
+}
+
+// ErrorDetail describes the error of an error response
+type ErrorDetail struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// DefaultErrorHandler renders errors as JSON error responses of the form
+// {"error": {"code": ..., "message": ..., "details": ...}}, where details
+// is omitted unless set. An HTTPError is rendered with
+// its status, code and message; any other error is a 500 whose message
+// includes the error text only when the app is in dev mode. A PanicError
+// is not rendered once the response has been written, so that a partial
+// response is not replaced.
+func DefaultErrorHandler(ctx *Context, err error) error {
+	var panicErr *PanicError
+	if errors.As(err, &panicErr) && ctx.Response.Written() {
+		return err
+	}
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = &HTTPError{Status: http.StatusInternalServerError, Err: err}
+		if ctx.App != nil && ctx.App.DevMode() {
+			httpErr.Message = err.Error()
+		}
+	}
+
+	body := ErrorBody{Error: ErrorDetail{
+		Code:    httpErr.code(),
+		Message: httpErr.message(),
+		Details: httpErr.Details,
+	}}
+	if renderErr := ctx.JSON(httpErr.Status, body); renderErr != nil {
+		return renderErr
+	}
+	return err
+}
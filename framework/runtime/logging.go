@@ -0,0 +1,76 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+
+	"gots-runtime/internal/observability"
+)
+
+// NewRequestID returns a random request ID, for RequestIDMiddleware
+func NewRequestID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+// Logger returns the request's logger: the app's logger, which adds the
+// request ID, method and path, and the fields added with AddLogFields, to
+// every entry, so that the entries of a request can be correlated
+func (c *Context) Logger() *observability.Logger {
+	logger := defaultLogger
+	if c.App != nil {
+		if appLogger := c.App.Logger(); appLogger != nil {
+			logger = appLogger
+		}
+	}
+
+	fields := map[string]interface{}{}
+	if c.Request != nil {
+		fields["method"] = c.Request.Method
+		fields["path"] = c.Request.Path
+	}
+	if requestID := c.requestID(); requestID != "" {
+		fields["requestId"] = requestID
+	}
+
+	c.mu.RLock()
+	for key, value := range c.logFields {
+		fields[key] = value
+	}
+	c.mu.RUnlock()
+
+	return logger.With(fields)
+}
+
+// AddLogFields adds fields to the entries logged through Logger for the
+// rest of the request, e.g. the authenticated user
+func (c *Context) AddLogFields(fields map[string]interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.logFields == nil {
+		c.logFields = make(map[string]interface{})
+	}
+	for key, value := range fields {
+		c.logFields[key] = value
+	}
+}
+
+// requestID returns the ID given to the request by RequestIDMiddleware or
+// RequestIDMiddlewareWithHeaders, or ""
+func (c *Context) requestID() string {
+	if id, ok := c.Data["requestId"].(string); ok && id != "" {
+		return id
+	}
+	if c.Request != nil {
+		if id := c.Request.Headers["X-Request-ID"]; id != "" {
+			return id
+		}
+		return c.Request.Headers["X-Request-Id"]
+	}
+	return ""
+}
@@ -1,9 +1,12 @@
 package runtime
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -73,52 +76,93 @@ func CORSMiddlewareWithOrigins(allowedOrigins []string) Middleware {
 	}
 }
 
-// RecoveryMiddleware provides panic recovery middleware
-func RecoveryMiddleware(ctx *Context, next Next) error {
+// RecoveryMiddleware recovers panics in the rest of the chain, logs them
+// with their stack trace and returns them as a PanicError, so that outer
+// middleware sees the failure and the error handler renders it
+func RecoveryMiddleware(ctx *Context, next Next) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			ctx.Response.Status = 500
-			ctx.Response.Body = []byte(fmt.Sprintf("Internal Server Error: %v", r))
+			err = recoverPanic(ctx, r)
 		}
 	}()
 
 	return next()
 }
 
-// TimeoutMiddleware provides timeout middleware
+// TimeoutMiddleware bounds the rest of the chain by timeout. The chain runs
+// with a deadline on ctx.Context(). Once the deadline passes, the chain is
+// abandoned: Abandoned reports true for its context, so the TypeScript
+// bridge stops waiting for its handlers and drops what they write, and the
+// request fails with a 504 HTTPError. Handlers must return once the context
+// is done; the middleware waits for the chain to return, so that nothing
+// writes to the response while the timeout response is rendered.
 func TimeoutMiddleware(timeout time.Duration) Middleware {
 	return func(ctx *Context, next Next) error {
-		done := make(chan error, 1)
-
-		go func() {
-			done <- next()
-		}()
-
-		select {
-		case err := <-done:
-			return err
-		case <-time.After(timeout):
-			ctx.Response.Status = 504
-			ctx.Response.Body = []byte("Request Timeout")
-			return fmt.Errorf("request timeout")
+		parent := ctx.Context()
+		flag := &abandonFlag{parent: abandonFlagOf(parent)}
+		timeoutCtx, cancel := context.WithTimeout(context.WithValue(parent, abandonedKey{}, flag), timeout)
+		defer cancel()
+		stop := context.AfterFunc(timeoutCtx, func() {
+			flag.abandoned.Store(true)
+		})
+		defer stop()
+
+		ctx.SetContext(timeoutCtx)
+		defer ctx.SetContext(parent)
+
+		err := next()
+
+		if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			return &HTTPError{Status: 504, Message: "Request Timeout", Err: context.DeadlineExceeded}
+		}
+
+		return err
+	}
+}
+
+// abandonedKey is the context key of the abandonFlag of a chain run by
+// TimeoutMiddleware
+type abandonedKey struct{}
+
+// abandonFlag is set once the request stops waiting for a chain. A chain
+// run inside another is abandoned with it.
+type abandonFlag struct {
+	abandoned atomic.Bool
+	parent    *abandonFlag
+}
+
+// abandonFlagOf returns the flag of the innermost chain goCtx belongs to,
+// or nil
+func abandonFlagOf(goCtx context.Context) *abandonFlag {
+	flag, _ := goCtx.Value(abandonedKey{}).(*abandonFlag)
+	return flag
+}
+
+// Abandoned reports whether the request stopped waiting for the handlers
+// running under goCtx, e.g. because a TimeoutMiddleware deadline passed.
+// The error response replaces what they write, so they should stop rather
+// than keep writing to ctx.Response.
+func Abandoned(goCtx context.Context) bool {
+	for flag := abandonFlagOf(goCtx); flag != nil; flag = flag.parent {
+		if flag.abandoned.Load() {
+			return true
 		}
 	}
+	return false
 }
 
-// AuthMiddleware provides authentication middleware
+// AuthMiddleware provides authentication middleware. validateToken receives
+// the raw Authorization header, including any scheme such as "Bearer ";
+// use TokenAuthMiddleware to validate just the credential.
 func AuthMiddleware(validateToken func(string) bool) Middleware {
 	return func(ctx *Context, next Next) error {
 		token := ctx.Request.Headers["Authorization"]
 		if token == "" {
-			ctx.Response.Status = 401
-			ctx.Response.Body = []byte("Unauthorized")
-			return fmt.Errorf("missing authorization token")
+			return &HTTPError{Status: 401, Err: fmt.Errorf("missing authorization token")}
 		}
 
 		if !validateToken(token) {
-			ctx.Response.Status = 401
-			ctx.Response.Body = []byte("Unauthorized")
-			return fmt.Errorf("invalid authorization token")
+			return &HTTPError{Status: 401, Err: fmt.Errorf("invalid authorization token")}
 		}
 
 		return next()
@@ -130,24 +174,18 @@ func BearerTokenMiddleware(validateToken func(string) bool) Middleware {
 	return func(ctx *Context, next Next) error {
 		authHeader := ctx.Request.Headers["Authorization"]
 		if authHeader == "" {
-			ctx.Response.Status = 401
-			ctx.Response.Body = []byte("Unauthorized: missing Authorization header")
-			return fmt.Errorf("missing authorization header")
+			return NewHTTPError(401, "missing Authorization header")
 		}
 
 		// Extract bearer token
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			ctx.Response.Status = 401
-			ctx.Response.Body = []byte("Unauthorized: invalid Authorization header format")
-			return fmt.Errorf("invalid authorization header format")
+			return NewHTTPError(401, "invalid Authorization header format")
 		}
 
 		token := parts[1]
 		if !validateToken(token) {
-			ctx.Response.Status = 401
-			ctx.Response.Body = []byte("Unauthorized: invalid token")
-			return fmt.Errorf("invalid token")
+			return NewHTTPError(401, "invalid token")
 		}
 
 		// Store token in context
@@ -160,6 +198,50 @@ func BearerTokenMiddleware(validateToken func(string) bool) Middleware {
 	}
 }
 
+// Authenticator validates a credential and returns the authenticated
+// principal, such as a user record or claims
+type Authenticator func(token string) (interface{}, error)
+
+// TokenAuthMiddleware authenticates requests using the credential of the
+// given Authorization scheme (e.g. "Bearer", "Token"). The scheme is matched
+// case-insensitively; an empty scheme passes the whole header value. On
+// success the credential is stored in ctx.Data["token"] and the principal
+// returned by authenticate in ctx.Data["principal"].
+func TokenAuthMiddleware(scheme string, authenticate Authenticator) Middleware {
+	return func(ctx *Context, next Next) error {
+		authHeader := strings.TrimSpace(ctx.Request.Headers["Authorization"])
+		if authHeader == "" {
+			return NewHTTPError(401, "missing Authorization header")
+		}
+
+		token := authHeader
+		if scheme != "" {
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], scheme) {
+				if ctx.Response.Headers == nil {
+					ctx.Response.Headers = make(map[string]string)
+				}
+				ctx.Response.Headers["WWW-Authenticate"] = scheme
+				return NewHTTPError(401, fmt.Sprintf("expected %s credentials", scheme))
+			}
+			token = strings.TrimSpace(parts[1])
+		}
+
+		principal, err := authenticate(token)
+		if err != nil {
+			return &HTTPError{Status: 401, Message: "invalid credentials", Err: err}
+		}
+
+		if ctx.Data == nil {
+			ctx.Data = make(map[string]interface{})
+		}
+		ctx.Data["token"] = token
+		ctx.Data["principal"] = principal
+
+		return next()
+	}
+}
+
 // ContentTypeMiddleware enforces content type on requests
 func ContentTypeMiddleware(requiredType string) Middleware {
 	return func(ctx *Context, next Next) error {
@@ -169,9 +251,7 @@ func ContentTypeMiddleware(requiredType string) Middleware {
 
 		contentType := ctx.Request.Headers["Content-Type"]
 		if !strings.Contains(contentType, requiredType) {
-			ctx.Response.Status = 415
-			ctx.Response.Body = []byte(fmt.Sprintf("Unsupported Media Type: expected %s", requiredType))
-			return fmt.Errorf("unsupported media type")
+			return NewHTTPError(415, fmt.Sprintf("expected %s", requiredType))
 		}
 
 		return next()
@@ -186,6 +266,10 @@ func RequestIDMiddleware(idGenerator func() string) Middleware {
 		}
 
 		requestID := ctx.Request.Headers["X-Request-ID"]
+		if requestID == "" {
+			// The canonical form net/http stores the header under
+			requestID = ctx.Request.Headers["X-Request-Id"]
+		}
 		if requestID == "" {
 			requestID = idGenerator()
 		}
@@ -220,9 +304,7 @@ func RateLimitMiddleware(maxRequests int, windowSize time.Duration) Middleware {
 
 		if len(validRequests) >= maxRequests {
 			mu.Unlock()
-			ctx.Response.Status = 429
-			ctx.Response.Body = []byte("Too Many Requests")
-			return fmt.Errorf("rate limit exceeded")
+			return NewHTTPError(429, "")
 		}
 
 		requests = append(validRequests, now)
@@ -0,0 +1,78 @@
+package runtime
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestContext creates the context of a request to path in app
+func newTestContext(app *App, method, path string) *Context {
+	return &Context{
+		Request:  &Request{Method: method, Path: path, Headers: map[string]string{}},
+		Response: &Response{Status: 200, Headers: map[string]string{}},
+		App:      app,
+		Data:     map[string]interface{}{},
+	}
+}
+
+func TestTimeoutMiddlewareReplacesLateWrites(t *testing.T) {
+	app := NewApp("test")
+	app.Use(TimeoutMiddleware(10 * time.Millisecond))
+	abandoned := make(chan bool, 1)
+	app.Get("/slow", func(ctx *Context) error {
+		// A handler that ignores the deadline and writes after it
+		time.Sleep(50 * time.Millisecond)
+		abandoned <- Abandoned(ctx.Context())
+		ctx.Response.Status = 200
+		ctx.Response.Body = []byte("late")
+		return nil
+	})
+
+	ctx := newTestContext(app, "GET", "/slow")
+	_ = app.Handle(ctx)
+	if ctx.Response.Status != 504 || string(ctx.Response.Body) == "late" {
+		t.Errorf("got %d %q, want the 504 response", ctx.Response.Status, ctx.Response.Body)
+	}
+	if !<-abandoned {
+		t.Error("handler context was not abandoned after the deadline")
+	}
+	if Abandoned(ctx.Context()) {
+		t.Error("request context is abandoned after the middleware returned")
+	}
+}
+
+func TestTimeoutMiddlewareStopsAtDeadline(t *testing.T) {
+	app := NewApp("test")
+	app.Use(TimeoutMiddleware(10 * time.Millisecond))
+	app.Get("/wait", func(ctx *Context) error {
+		<-ctx.Context().Done()
+		return ctx.Context().Err()
+	})
+
+	ctx := newTestContext(app, "GET", "/wait")
+	start := time.Now()
+	_ = app.Handle(ctx)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %v with a 10ms timeout", elapsed)
+	}
+	if ctx.Response.Status != 504 {
+		t.Errorf("got status %d, want 504", ctx.Response.Status)
+	}
+}
+
+func TestTimeoutMiddlewarePassesFastResponses(t *testing.T) {
+	app := NewApp("test")
+	app.Use(TimeoutMiddleware(time.Second))
+	app.Get("/fast", func(ctx *Context) error {
+		ctx.Response.Body = []byte("ok")
+		return nil
+	})
+
+	ctx := newTestContext(app, "GET", "/fast")
+	if err := app.Handle(ctx); err != nil {
+		t.Fatalf("Handle: %v", err)
+	}
+	if ctx.Response.Status != 200 || string(ctx.Response.Body) != "ok" {
+		t.Errorf("got %d %q, want 200 ok", ctx.Response.Status, ctx.Response.Body)
+	}
+}
@@ -0,0 +1,194 @@
+package runtime
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gots-runtime/internal/loadbalancer"
+)
+
+// hopHeaders are the hop-by-hop headers, which apply to one connection and
+// are not forwarded by proxies
+var hopHeaders = []string{
+	"Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Proxy-Connection",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+	"Upgrade",
+}
+
+// proxyChunkSize is the size of the chunks a proxied body is streamed in
+const proxyChunkSize = 32 * 1024
+
+// proxyRoute forwards the requests under a path prefix
+type proxyRoute struct {
+	prefix  string
+	handler Handler
+}
+
+// Proxy forwards the requests whose path is prefix or below it, with any
+// method, to the backends of lb, after the app's middleware and the given
+// route middleware. The full path is forwarded; routes registered for
+// paths under prefix take precedence.
+func (a *App) Proxy(prefix string, lb *loadbalancer.LoadBalancer, middleware ...Middleware) {
+	prefix = "/" + strings.Trim(prefix, "/")
+	handler := withMiddleware(ProxyHandler(lb), middleware)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.proxies = append(a.proxies, proxyRoute{prefix: prefix, handler: handler})
+	// Longer prefixes are more specific
+	sort.SliceStable(a.proxies, func(i, j int) bool {
+		return len(a.proxies[i].prefix) > len(a.proxies[j].prefix)
+	})
+}
+
+// proxyHandler returns the handler of the proxied prefix path is under, or
+// nil
+func (a *App) proxyHandler(path string) (Handler, string) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	for _, proxy := range a.proxies {
+		if proxy.prefix == "/" || path == proxy.prefix || strings.HasPrefix(path, proxy.prefix+"/") {
+			return proxy.handler, proxy.prefix
+		}
+	}
+	return nil, ""
+}
+
+// ProxyHandler returns a handler that forwards requests to the backends of
+// lb. Hop-by-hop headers are dropped in both directions and the
+// X-Forwarded-For, -Host and -Proto headers are set. The response body is
+// streamed; the request is sent by the response's Deferred function, so
+// that it does not hold up the event loop.
+func ProxyHandler(lb *loadbalancer.LoadBalancer) Handler {
+	return func(ctx *Context) error {
+		req, err := proxyRequest(ctx)
+		if err != nil {
+			return &HTTPError{Status: http.StatusBadRequest, Err: err}
+		}
+		ctx.Response.Deferred = func() error {
+			return forward(ctx, lb, req)
+		}
+		return nil
+	}
+}
+
+// proxyRequest creates the request forwarded for ctx
+func proxyRequest(ctx *Context) (*http.Request, error) {
+	target := ctx.Request.Path
+	if ctx.Request.RawQuery != "" {
+		target += "?" + ctx.Request.RawQuery
+	}
+	req, err := http.NewRequestWithContext(ctx.Context(), ctx.Request.Method, target, bytes.NewReader(ctx.Request.Body))
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range ctx.Request.Headers {
+		req.Header.Set(key, value)
+	}
+	removeHopHeaders(req.Header)
+	req.RemoteAddr = ctx.Request.RemoteAddr
+
+	clientIP := ctx.Request.RemoteAddr
+	if host, _, err := net.SplitHostPort(clientIP); err == nil {
+		clientIP = host
+	}
+	if clientIP != "" {
+		if prior := req.Header.Get("X-Forwarded-For"); prior != "" {
+			clientIP = prior + ", " + clientIP
+		}
+		req.Header.Set("X-Forwarded-For", clientIP)
+	}
+	if ctx.Request.Host != "" {
+		req.Header.Set("X-Forwarded-Host", ctx.Request.Host)
+	}
+	proto := "http"
+	if ctx.Request.TLS {
+		proto = "https"
+	}
+	req.Header.Set("X-Forwarded-Proto", proto)
+	return req, nil
+}
+
+// forward sends req to a backend of lb and streams the response into
+// ctx.Response. Failures are rendered as 502 errors by the app's error
+// handler.
+func forward(ctx *Context, lb *loadbalancer.LoadBalancer, req *http.Request) error {
+	resp, err := lb.Proxy(req)
+	if err != nil {
+		if req.Context().Err() != nil {
+			return nil // the client went away
+		}
+		ctx.Logger().Warn("proxy error: %v", err)
+		return renderError(ctx, &HTTPError{Status: http.StatusBadGateway, Err: err})
+	}
+
+	removeHopHeaders(resp.Header)
+	if ctx.Response.Headers == nil {
+		ctx.Response.Headers = make(map[string]string)
+	}
+	for key, values := range resp.Header {
+		switch key {
+		case "Set-Cookie", "Content-Length":
+			continue
+		}
+		ctx.Response.Headers[key] = strings.Join(values, ", ")
+	}
+	ctx.Response.Cookies = append(ctx.Response.Cookies, resp.Cookies()...)
+	ctx.Response.Status = resp.StatusCode
+
+	stream := ctx.Stream()
+	go func() {
+		defer resp.Body.Close()
+		defer stream.End()
+		buf := make([]byte, proxyChunkSize)
+		for {
+			n, err := resp.Body.Read(buf)
+			if n > 0 && stream.Write(buf[:n]) != nil {
+				return
+			}
+			if err != nil {
+				if err != io.EOF {
+					ctx.Logger().Warn("proxy error: reading response: %v", err)
+				}
+				return
+			}
+		}
+	}()
+	return nil
+}
+
+// removeHopHeaders deletes the hop-by-hop headers from header, including
+// those named by its Connection header
+func removeHopHeaders(header http.Header) {
+	for _, value := range header.Values("Connection") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				header.Del(name)
+			}
+		}
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+}
+
+// renderError renders err into the response with the app's error handler
+func renderError(ctx *Context, err error) error {
+	handler := DefaultErrorHandler
+	if ctx.App != nil {
+		ctx.App.mu.RLock()
+		handler = ctx.App.errorHandler
+		ctx.App.mu.RUnlock()
+	}
+	return handler(ctx, err)
+}
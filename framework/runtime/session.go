@@ -0,0 +1,296 @@
+package runtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// SessionStore persists session data by session ID
+type SessionStore interface {
+	// Load returns the data of a session, or false if it does not exist or
+	// has expired
+	Load(id string) (map[string]interface{}, bool, error)
+	// Save stores the data of a session until ttl elapses
+	Save(id string, data map[string]interface{}, ttl time.Duration) error
+	// Delete removes a session
+	Delete(id string) error
+}
+
+// MemorySessionStore is an in-memory SessionStore
+type MemorySessionStore struct {
+	sessions  map[string]*storedSession
+	lastSweep time.Time
+	mu        sync.Mutex
+}
+
+// storedSession is a session held by MemorySessionStore
+type storedSession struct {
+	data      map[string]interface{}
+	expiresAt time.Time
+}
+
+// NewMemorySessionStore creates an in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{
+		sessions:  make(map[string]*storedSession),
+		lastSweep: time.Now(),
+	}
+}
+
+// Load returns the data of a session
+func (s *MemorySessionStore) Load(id string) (map[string]interface{}, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.sessions[id]
+	if !ok {
+		return nil, false, nil
+	}
+	if time.Now().After(stored.expiresAt) {
+		delete(s.sessions, id)
+		return nil, false, nil
+	}
+	return copySessionData(stored.data), true, nil
+}
+
+// Save stores the data of a session
+func (s *MemorySessionStore) Save(id string, data map[string]interface{}, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sessions[id] = &storedSession{
+		data:      copySessionData(data),
+		expiresAt: now.Add(ttl),
+	}
+
+	// Drop expired sessions at most once a minute
+	if now.Sub(s.lastSweep) >= time.Minute {
+		for key, stored := range s.sessions {
+			if now.After(stored.expiresAt) {
+				delete(s.sessions, key)
+			}
+		}
+		s.lastSweep = now
+	}
+	return nil
+}
+
+// Delete removes a session
+func (s *MemorySessionStore) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.sessions, id)
+	return nil
+}
+
+// Len returns the number of stored sessions, including expired sessions
+// that have not been swept yet
+func (s *MemorySessionStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sessions)
+}
+
+// SessionOptions configures SessionMiddleware
+type SessionOptions struct {
+	CookieName string        // defaults to "gots_session"
+	TTL        time.Duration // defaults to 24 hours
+	Cookie     CookieOptions // HttpOnly is always set and MaxAge follows TTL
+}
+
+// Session is the per-request view of a session
+type Session struct {
+	id          string
+	previousID  string
+	data        map[string]interface{}
+	isNew       bool
+	regenerated bool
+	destroyed   bool
+	mu          sync.RWMutex
+}
+
+// ID returns the session ID
+func (s *Session) ID() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.id
+}
+
+// Get returns a session value
+func (s *Session) Get(key string) (interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	value, ok := s.data[key]
+	return value, ok
+}
+
+// Set sets a session value
+func (s *Session) Set(key string, value interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[key] = value
+}
+
+// Delete removes a session value
+func (s *Session) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.data, key)
+}
+
+// Values returns a copy of the session data
+func (s *Session) Values() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copySessionData(s.data)
+}
+
+// Regenerate moves the session data to a new session ID. Call it when the
+// privilege level changes, such as on login, to prevent session fixation.
+func (s *Session) Regenerate() error {
+	id, err := newSessionID()
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.isNew && !s.regenerated {
+		s.previousID = s.id
+	}
+	s.id = id
+	s.regenerated = true
+	return nil
+}
+
+// Destroy clears the session and removes it from the store at the end of
+// the request
+func (s *Session) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = make(map[string]interface{})
+	s.destroyed = true
+}
+
+// Session returns the request's session, or nil if SessionMiddleware is
+// not in use
+func (c *Context) Session() *Session {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.session
+}
+
+// setSession sets the request's session
+func (c *Context) setSession(session *Session) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.session = session
+}
+
+// SessionMiddleware loads the session named by the session cookie before
+// the request and saves it afterwards. Unknown or expired session IDs are
+// replaced by a new ID, and new sessions are only stored and sent to the
+// client once they hold data.
+func SessionMiddleware(store SessionStore, opts SessionOptions) Middleware {
+	if opts.CookieName == "" {
+		opts.CookieName = "gots_session"
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = 24 * time.Hour
+	}
+
+	return func(ctx *Context, next Next) error {
+		session, err := loadSession(ctx, store, opts.CookieName)
+		if err != nil {
+			return err
+		}
+		ctx.setSession(session)
+
+		if err := next(); err != nil {
+			return err
+		}
+
+		return saveSession(ctx, store, session, opts)
+	}
+}
+
+// loadSession loads the session of the request or starts a new one
+func loadSession(ctx *Context, store SessionStore, cookieName string) (*Session, error) {
+	if id, ok := ctx.Cookie(cookieName); ok && id != "" {
+		data, found, err := store.Load(id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load session: %w", err)
+		}
+		if found {
+			return &Session{id: id, data: data}, nil
+		}
+	}
+
+	id, err := newSessionID()
+	if err != nil {
+		return nil, err
+	}
+	return &Session{id: id, data: make(map[string]interface{}), isNew: true}, nil
+}
+
+// saveSession persists the session and updates the session cookie
+func saveSession(ctx *Context, store SessionStore, session *Session, opts SessionOptions) error {
+	session.mu.RLock()
+	defer session.mu.RUnlock()
+
+	cookie := opts.Cookie
+	cookie.HttpOnly = true
+
+	if session.previousID != "" {
+		if err := store.Delete(session.previousID); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+	}
+
+	if session.destroyed {
+		if !session.isNew || session.regenerated {
+			if err := store.Delete(session.id); err != nil {
+				return fmt.Errorf("failed to delete session: %w", err)
+			}
+		}
+		if _, ok := ctx.Cookie(opts.CookieName); ok {
+			ctx.DeleteCookie(opts.CookieName, cookie)
+		}
+		return nil
+	}
+
+	if session.isNew && len(session.data) == 0 {
+		return nil
+	}
+
+	// Saving on every request keeps active sessions from expiring
+	if err := store.Save(session.id, session.data, opts.TTL); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	cookie.MaxAge = int(opts.TTL / time.Second)
+	ctx.SetCookie(opts.CookieName, session.id, cookie)
+	return nil
+}
+
+// newSessionID generates a random session ID
+func newSessionID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// copySessionData returns a shallow copy of session data
+func copySessionData(data map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		result[k] = v
+	}
+	return result
+}
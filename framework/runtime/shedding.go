@@ -0,0 +1,130 @@
+package runtime
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// Priority is the class of a route's requests for load shedding. The
+// values match the priority classes of the runtime's LoadShedder.
+type Priority int
+
+// Route priorities. Under load, low priority routes are shed first and
+// critical routes, such as health checks, are never shed.
+const (
+	PriorityLow      Priority = -1
+	PriorityNormal   Priority = 0
+	PriorityHigh     Priority = 1
+	PriorityCritical Priority = 2
+)
+
+// ParsePriority returns the priority named "low", "normal", "high" or
+// "critical"
+func ParsePriority(name string) (Priority, error) {
+	switch name {
+	case "low":
+		return PriorityLow, nil
+	case "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	case "critical":
+		return PriorityCritical, nil
+	}
+	return 0, fmt.Errorf("unknown route priority: %s", name)
+}
+
+// LoadShedder decides which requests Admit admits under load; the
+// runtime's LoadShedder implements it. The load reported to it is the
+// number of admitted requests that have not been handled yet.
+type LoadShedder interface {
+	UpdateLoad(load int)
+	ShouldRejectWithPriority(priority int) bool
+	RecordRequest(rejected bool, responseTime time.Duration)
+}
+
+// shedRetryAfter is the Retry-After header of shed requests, in seconds
+const shedRetryAfter = "1"
+
+// SetLoadShedder sets the load shedder that Admit consults
+func (a *App) SetLoadShedder(shedder LoadShedder) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.shedder = shedder
+}
+
+// SetRoutePriority sets the priority of the route registered for method
+// and path, static or dynamic
+func (a *App) SetRoutePriority(method, path string, priority Priority) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%s", method, path)
+	if route, ok := a.routes[key]; ok {
+		route.Priority = priority
+		a.routes[key] = route
+		return nil
+	}
+	for _, dynRoute := range a.dynamicRoutes {
+		if dynRoute.Method == method && dynRoute.Path == path {
+			dynRoute.Priority = priority
+			return nil
+		}
+	}
+	return fmt.Errorf("no route for %s %s", method, path)
+}
+
+// routePriority returns the priority of the route matching a request;
+// requests that match no route have normal priority
+func (a *App) routePriority(method, path string) Priority {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	if route, ok := a.routes[fmt.Sprintf("%s:%s", method, path)]; ok {
+		return route.Priority
+	}
+	for _, dynRoute := range a.dynamicRoutes {
+		if dynRoute.Method == method && dynRoute.Pattern.MatchString(path) {
+			return dynRoute.Priority
+		}
+	}
+	return PriorityNormal
+}
+
+// Admit counts a request as in flight and asks the load shedder, if any,
+// whether to handle it. Servers call it before queueing a request, so that
+// the requests waiting for a handler count as load. When the request is
+// admitted, done must be called once it has been handled; otherwise
+// ctx.Response holds the 503 response rendered by the error handler.
+func (a *App) Admit(ctx *Context) (done func(), ok bool) {
+	a.mu.RLock()
+	shedder := a.shedder
+	errorHandler := a.errorHandler
+	a.mu.RUnlock()
+	if shedder == nil {
+		return func() {}, true
+	}
+
+	start := time.Now()
+	shedder.UpdateLoad(int(atomic.AddInt64(&a.inFlight, 1)))
+	release := func() {
+		shedder.UpdateLoad(int(atomic.AddInt64(&a.inFlight, -1)))
+	}
+
+	priority := a.routePriority(ctx.Request.Method, ctx.Request.Path)
+	if shedder.ShouldRejectWithPriority(int(priority)) {
+		release()
+		shedder.RecordRequest(true, time.Since(start))
+		if ctx.Response.Headers == nil {
+			ctx.Response.Headers = make(map[string]string)
+		}
+		ctx.Response.Headers["Retry-After"] = shedRetryAfter
+		_ = errorHandler(ctx, &HTTPError{Status: 503, Code: "overloaded", Message: "The server is overloaded, retry later"})
+		return nil, false
+	}
+	return func() {
+		release()
+		shedder.RecordRequest(false, time.Since(start))
+	}, true
+}
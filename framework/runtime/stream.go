@@ -0,0 +1,29 @@
+package runtime
+
+import (
+	"gots-runtime/internal/api"
+)
+
+// Stream switches the response to a streamed body and returns the stream.
+// Chunks written to it are sent as they arrive with chunked transfer
+// encoding, and may be written after the handler returns; the response
+// finishes when the stream is ended or the client disconnects. Calling
+// Stream again returns the same stream.
+func (c *Context) Stream() *api.Stream {
+	if c.Response.Stream == nil {
+		c.Response.Stream = api.NewStream()
+	}
+	return c.Response.Stream
+}
+
+// SSE starts a server-sent events response and returns its stream; send
+// events with WriteEvent
+func (c *Context) SSE() *api.Stream {
+	if c.Response.Headers == nil {
+		c.Response.Headers = make(map[string]string)
+	}
+	c.Response.Headers["Content-Type"] = "text/event-stream"
+	c.Response.Headers["Cache-Control"] = "no-cache"
+	c.Response.Headers["X-Accel-Buffering"] = "no"
+	return c.Stream()
+}
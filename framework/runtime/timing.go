@@ -0,0 +1,204 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gots-runtime/internal/observability"
+)
+
+// TimingKey is the key of the request's *Timing in Context.Data
+const TimingKey = "timing"
+
+// TimingPhase is a named duration of a request's timing breakdown
+type TimingPhase struct {
+	Name     string
+	Duration time.Duration
+}
+
+// Timing is the timing breakdown of a request, recorded by
+// TimingMiddleware: the time spent in middleware, in the route handler
+// (with its route middleware) and in total, and any phases measured by
+// handlers.
+type Timing struct {
+	TraceID      string // the trace of the request's span, if traced
+	start        time.Time
+	handlerStart time.Time
+	handlerEnd   time.Time
+	end          time.Time
+	custom       []TimingPhase
+	mu           sync.Mutex
+}
+
+// NewTiming starts the timing breakdown of a request
+func NewTiming() *Timing {
+	return &Timing{start: time.Now()}
+}
+
+// Timing returns the timing breakdown of the request, or nil when
+// TimingMiddleware is not in use
+func (c *Context) Timing() *Timing {
+	timing, _ := c.Data[TimingKey].(*Timing)
+	return timing
+}
+
+// Add records a phase measured by the caller, e.g. a database query
+func (t *Timing) Add(name string, duration time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.custom = append(t.custom, TimingPhase{Name: name, Duration: duration})
+}
+
+// Start starts measuring a phase and returns the function that ends it
+func (t *Timing) Start(name string) func() {
+	start := time.Now()
+	var once sync.Once
+	return func() {
+		once.Do(func() { t.Add(name, time.Since(start)) })
+	}
+}
+
+// beginHandler records that the route handler started
+func (t *Timing) beginHandler() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlerStart = time.Now()
+}
+
+// endHandler records that the route handler returned
+func (t *Timing) endHandler() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.handlerEnd = time.Now()
+}
+
+// finish records that the request has been handled
+func (t *Timing) finish() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.end = time.Now()
+}
+
+// Phases returns the breakdown so far: "middleware", "handler" once the
+// handler has started, the phases added by handlers and "total". While
+// the request is being handled, the running phases end now.
+func (t *Timing) Phases() []TimingPhase {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	end := t.end
+	if end.IsZero() {
+		end = now
+	}
+	total := end.Sub(t.start)
+
+	var handler time.Duration
+	if !t.handlerStart.IsZero() {
+		handlerEnd := t.handlerEnd
+		if handlerEnd.IsZero() {
+			handlerEnd = now
+		}
+		handler = handlerEnd.Sub(t.handlerStart)
+	}
+
+	phases := []TimingPhase{{Name: "middleware", Duration: total - handler}}
+	if !t.handlerStart.IsZero() {
+		phases = append(phases, TimingPhase{Name: "handler", Duration: handler})
+	}
+	phases = append(phases, t.custom...)
+	return append(phases, TimingPhase{Name: "total", Duration: total})
+}
+
+// Header formats the breakdown as a Server-Timing header value, e.g.
+// "middleware;dur=0.41, handler;dur=12.3, total;dur=12.71"
+func (t *Timing) Header() string {
+	phases := t.Phases()
+	metrics := make([]string, len(phases))
+	for i, phase := range phases {
+		metrics[i] = serverTimingName(phase.Name) + ";dur=" + strconv.FormatFloat(durationMillis(phase.Duration), 'f', -1, 64)
+	}
+	return strings.Join(metrics, ", ")
+}
+
+// durationMillis converts d to milliseconds, rounded to microseconds
+func durationMillis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// serverTimingName makes name a valid Server-Timing metric name, which is
+// an HTTP token
+func serverTimingName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("!#$%&'*+-.^_`|~", r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// TimingMiddleware records the timing breakdown of each request in
+// ctx.Data[TimingKey] and sends it in a Server-Timing response header.
+// When tracer is not nil, each request is also recorded as a span, tagged
+// with the method, path, status and phase durations, whose trace ID is
+// added to the fields of ctx.Logger. Register it first, in PhasePre, so
+// that it measures the other middleware.
+func TimingMiddleware(tracer *observability.Tracer) Middleware {
+	return func(ctx *Context, next Next) error {
+		timing := NewTiming()
+
+		var span *observability.Span
+		if tracer != nil {
+			var spanCtx context.Context
+			spanCtx, span = tracer.StartSpan(ctx.Context(), ctx.Request.Method+" "+ctx.Request.Path)
+			ctx.SetContext(spanCtx)
+			timing.TraceID = span.TraceID
+			ctx.AddLogFields(map[string]interface{}{"traceId": span.TraceID})
+		}
+
+		if ctx.Data == nil {
+			ctx.Data = make(map[string]interface{})
+		}
+		ctx.Data[TimingKey] = timing
+
+		err := next()
+		timing.finish()
+
+		if ctx.Response.Headers == nil {
+			ctx.Response.Headers = make(map[string]string)
+		}
+		ctx.Response.Headers["Server-Timing"] = timing.Header()
+
+		if span != nil {
+			status := ctx.Response.Status
+			if err != nil {
+				status = http.StatusInternalServerError
+				var httpErr *HTTPError
+				if errors.As(err, &httpErr) {
+					status = httpErr.Status
+				}
+			}
+			tracer.AddTag(span.SpanID, "http.method", ctx.Request.Method)
+			tracer.AddTag(span.SpanID, "http.path", ctx.Request.Path)
+			tracer.AddTag(span.SpanID, "http.status", strconv.Itoa(status))
+			for _, phase := range timing.Phases() {
+				tracer.AddTag(span.SpanID, "timing."+phase.Name, fmt.Sprintf("%.3fms", durationMillis(phase.Duration)))
+			}
+			tracer.FinishSpan(span.SpanID)
+		}
+		return err
+	}
+}
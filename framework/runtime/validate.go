@@ -0,0 +1,91 @@
+package runtime
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// FieldError describes a request field that failed validation
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// Validator checks a decoded part of a request. It returns the value to
+// hand to the handler, which may be converted to the expected types (e.g.
+// a numeric query parameter), and an error for each invalid field.
+type Validator interface {
+	Validate(value interface{}) (interface{}, []FieldError)
+}
+
+// Schema describes the parts of a request checked by ValidateMiddleware;
+// nil parts are not checked
+type Schema struct {
+	Body   Validator // the JSON-decoded request body
+	Query  Validator // the query parameters as a map[string]interface{}
+	Params Validator // the route parameters as a map[string]interface{}
+}
+
+// ValidateMiddleware validates requests against schema. Requests that fail
+// get a 400 HTTPError listing the invalid fields, with field names prefixed
+// by "body.", "query." or "params."; otherwise the validated values are
+// stored in ctx.Data["validated"] as a map with "body", "query" and
+// "params" keys for the parts the schema checks.
+func ValidateMiddleware(schema Schema) Middleware {
+	return func(ctx *Context, next Next) error {
+		validated := make(map[string]interface{})
+		var fieldErrors []FieldError
+
+		check := func(part string, validator Validator, value interface{}) {
+			if validator == nil {
+				return
+			}
+			result, errs := validator.Validate(value)
+			for _, fe := range errs {
+				field := part
+				if fe.Field != "" {
+					field += "." + fe.Field
+				}
+				fieldErrors = append(fieldErrors, FieldError{Field: field, Message: fe.Message})
+			}
+			validated[part] = result
+		}
+
+		if schema.Body != nil {
+			var body interface{}
+			if len(ctx.Request.Body) > 0 {
+				if err := json.Unmarshal(ctx.Request.Body, &body); err != nil {
+					return &HTTPError{Status: http.StatusBadRequest, Code: "invalid_json", Message: "request body is not valid JSON", Err: err}
+				}
+			}
+			check("body", schema.Body, body)
+		}
+		check("query", schema.Query, stringMap(ctx.Request.Query))
+		check("params", schema.Params, stringMap(ctx.Request.Params))
+
+		if len(fieldErrors) > 0 {
+			return &HTTPError{
+				Status:  http.StatusBadRequest,
+				Code:    "validation_failed",
+				Message: "request validation failed",
+				Details: fieldErrors,
+			}
+		}
+
+		if ctx.Data == nil {
+			ctx.Data = make(map[string]interface{})
+		}
+		ctx.Data["validated"] = validated
+
+		return next()
+	}
+}
+
+// stringMap converts request query or route parameters for validation
+func stringMap(values map[string]string) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for key, val := range values {
+		result[key] = val
+	}
+	return result
+}
@@ -3,8 +3,11 @@ package api
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"io"
+	"net"
 	"net/http"
+	"sync"
 	"time"
 
 	"gots-runtime/internal/eventloop"
@@ -13,7 +16,6 @@ import (
 // HTTP provides HTTP server functionality
 type HTTP struct {
 	eventLoop *eventloop.Loop
-	server    *http.Server
 }
 
 // NewHTTP creates a new HTTP API
@@ -25,12 +27,17 @@ func NewHTTP(eventLoop *eventloop.Loop) *HTTP {
 
 // Request represents an HTTP request
 type Request struct {
-	Method  string
-	URL     string
-	Headers map[string]string
-	Body    []byte
-	Params  map[string]string
-	Query   map[string]string
+	Method     string
+	URL        string
+	Headers    map[string]string
+	Body       []byte
+	Params     map[string]string
+	Query      map[string]string
+	Context    context.Context // cancelled when the client disconnects or the request ends
+	RawQuery   string          // the query string, without the "?"
+	Host       string          // the Host header
+	RemoteAddr string          // the client's address, e.g. "10.0.0.1:52344"
+	TLS        bool            // whether the request arrived over TLS
 }
 
 // Response represents an HTTP response
@@ -38,6 +45,12 @@ type Response struct {
 	Status  int
 	Headers map[string]string
 	Body    []byte
+	Cookies []*http.Cookie
+	Stream  *Stream // when set, Body is ignored and the stream is sent chunked
+	// Deferred, when set, is called off the event loop after the handler
+	// returns and produces the response instead, e.g. by waiting for an
+	// upstream server
+	Deferred func() (*Response, error)
 }
 
 // Handler is a function that handles HTTP requests
@@ -49,13 +62,44 @@ type Middleware func(Handler) Handler
 // Server represents an HTTP server
 type Server struct {
 	http      *HTTP
+	server    *http.Server
 	mux       *http.ServeMux
 	handlers  map[string]Handler
 	middleware []Middleware
+	admission Admission
+	options   ServerOptions
+	listener  net.Listener
+	mu        sync.RWMutex
+}
+
+// Admission decides whether to handle a request before it is queued on the
+// event loop. It returns the function to call once an admitted request has
+// been handled, or the response for a rejected one.
+type Admission func(req *Request) (done func(), reject *Response)
+
+// SetAdmission sets the admission check of the server's requests
+func (s *Server) SetAdmission(admit Admission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.admission = admit
+}
+
+// ServerOptions configures how a server binds its address
+type ServerOptions struct {
+	// ReusePort sets SO_REUSEPORT on the listening socket, so that several
+	// processes can listen on the same address; a restarted server can then
+	// bind before the old one stops accepting connections
+	ReusePort bool
 }
 
 // NewServer creates a new HTTP server
 func (h *HTTP) NewServer(addr string) *Server {
+	return h.NewServerWithOptions(addr, ServerOptions{})
+}
+
+// NewServerWithOptions creates a new HTTP server that binds its address
+// with opts
+func (h *HTTP) NewServerWithOptions(addr string, opts ServerOptions) *Server {
 	mux := http.NewServeMux()
 	server := &http.Server{
 		Addr:    addr,
@@ -64,14 +108,13 @@ func (h *HTTP) NewServer(addr string) *Server {
 
 	s := &Server{
 		http:     h,
+		server:   server,
 		mux:      mux,
 		handlers: make(map[string]Handler),
 		middleware: make([]Middleware, 0),
+		options:  opts,
 	}
 
-	// Set the server
-	h.server = server
-
 	return s
 }
 
@@ -89,46 +132,177 @@ func (s *Server) Handle(path string, handler Handler) {
 		// Convert http.Request to our Request type
 		req := s.convertRequest(r)
 		
-		// Execute handler in event loop
-		s.http.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
-			resp, err := wrappedHandler(req)
-			if err != nil {
-				http.Error(w, err.Error(), http.StatusInternalServerError)
-				return nil
-			}
-
-			// Write response
-			for k, v := range resp.Headers {
-				w.Header().Set(k, v)
+		s.mu.RLock()
+		admit := s.admission
+		s.mu.RUnlock()
+		if admit != nil {
+			done, reject := admit(req)
+			if reject != nil {
+				writeResponse(w, r, reject)
+				return
 			}
-			w.WriteHeader(resp.Status)
-			_, _ = w.Write(resp.Body)
+			defer done()
+		}
+		
+		// Execute handler in event loop and wait for its response, which is
+		// written from this goroutine so that it is complete before the
+		// request ends
+		type result struct {
+			resp *Response
+			err  error
+		}
+		done := make(chan result, 1)
+		err := s.http.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+			resp, err := wrappedHandler(req)
+			done <- result{resp: resp, err: err}
 			return nil
 		}, 0))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		
+		var res result
+		select {
+		case res = <-done:
+		case <-r.Context().Done():
+			// The client went away; abandon any stream the handler starts
+			go func() {
+				if res := <-done; res.resp != nil && res.resp.Stream != nil {
+					res.resp.Stream.Close()
+				}
+			}()
+			return
+		}
+		if res.err == nil && res.resp != nil && res.resp.Deferred != nil {
+			res.resp, res.err = res.resp.Deferred()
+		}
+		if res.err != nil {
+			http.Error(w, res.err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeResponse(w, r, res.resp)
 	})
 }
 
+// writeResponse writes resp to w. A streamed response is sent with chunked
+// transfer encoding until the stream ends or the client disconnects.
+func writeResponse(w http.ResponseWriter, r *http.Request, resp *Response) {
+	for k, v := range resp.Headers {
+		w.Header().Set(k, v)
+	}
+	for _, cookie := range resp.Cookies {
+		http.SetCookie(w, cookie)
+	}
+	
+	if resp.Stream != nil {
+		w.Header().Del("Content-Length")
+		w.WriteHeader(resp.Status)
+		resp.Stream.pipe(r.Context(), w)
+		return
+	}
+	
+	w.WriteHeader(resp.Status)
+	_, _ = w.Write(resp.Body)
+}
+
 // Use adds middleware
 func (s *Server) Use(middleware Middleware) {
 	s.middleware = append(s.middleware, middleware)
 }
 
-// ListenAndServe starts the HTTP server
-func (s *Server) ListenAndServe(callback func(error)) {
-	s.http.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
-		err := s.http.server.ListenAndServe()
-		callback(err)
+// Listen binds the server's address and serves from its own goroutine, so
+// that the event loop stays free to run request handlers. It returns the
+// bound address, which carries the actual port when the address asked for
+// port 0. The server keeps the loop alive until it stops; stopped is then
+// run on the loop with the error that stopped it, http.ErrServerClosed after
+// Shutdown.
+func (s *Server) Listen(stopped func(error)) (net.Addr, error) {
+	s.mu.Lock()
+	if s.listener != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("server is already listening on %s", s.listener.Addr())
+	}
+	
+	addr := s.server.Addr
+	if addr == "" {
+		addr = ":http"
+	}
+	var lc net.ListenConfig
+	if s.options.ReusePort {
+		lc.Control = reusePortControl
+	}
+	listener, err := lc.Listen(context.Background(), "tcp", addr)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	s.listener = listener
+	s.mu.Unlock()
+	
+	s.http.eventLoop.Ref()
+	go func() {
+		err := s.server.Serve(listener)
+		s.http.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+			if stopped != nil {
+				stopped(err)
+			}
+			return nil
+		}, 0))
+		s.http.eventLoop.Unref()
+	}()
+	
+	return listener.Addr(), nil
+}
+
+// Addr returns the address the server is bound to, or nil before Listen
+func (s *Server) Addr() net.Addr {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.listener == nil {
 		return nil
-	}, 0))
+	}
+	return s.listener.Addr()
+}
+
+// Port returns the TCP port the server is bound to, or 0 before Listen
+func (s *Server) Port() int {
+	if addr, ok := s.Addr().(*net.TCPAddr); ok {
+		return addr.Port
+	}
+	return 0
 }
 
-// Shutdown gracefully shuts down the server
+// ListenAndServe starts the HTTP server; callback is run on the event loop
+// with the error that stopped it, including a failure to bind
+func (s *Server) ListenAndServe(callback func(error)) {
+	if _, err := s.Listen(callback); err != nil {
+		s.http.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+			callback(err)
+			return nil
+		}, 0))
+	}
+}
+
+// Shutdown gracefully shuts down the server. In-flight requests need the
+// event loop to finish, so the shutdown waits for them from its own
+// goroutine and callback is then run on the loop.
 func (s *Server) Shutdown(ctx context.Context, callback func(error)) {
-	s.http.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
-		err := s.http.server.Shutdown(ctx)
-		callback(err)
-		return nil
-	}, 0))
+	go func() {
+		err := s.server.Shutdown(ctx)
+		// Serve may not have started tracking the listener yet, in which
+		// case Shutdown leaves it to Serve to close; close it here so the
+		// port is free once callback runs
+		s.mu.RLock()
+		if s.listener != nil {
+			s.listener.Close()
+		}
+		s.mu.RUnlock()
+		s.http.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+			callback(err)
+			return nil
+		}, 0))
+	}()
 }
 
 // convertRequest converts http.Request to our Request type
@@ -137,12 +311,7 @@ func (s *Server) convertRequest(r *http.Request) *Request {
 	body, _ := io.ReadAll(r.Body)
 	
 	// Parse query parameters
-	query := make(map[string]string)
-	for k, v := range r.URL.Query() {
-		if len(v) > 0 {
-			query[k] = v[0]
-		}
-	}
+	query := FirstQueryValues(r.URL.Query())
 
 	// Parse headers
 	headers := make(map[string]string)
@@ -153,12 +322,17 @@ func (s *Server) convertRequest(r *http.Request) *Request {
 	}
 
 	return &Request{
-		Method:  r.Method,
-		URL:     r.URL.Path,
-		Headers: headers,
-		Body:    body,
-		Query:   query,
-		Params:  make(map[string]string), // Would be populated by router
+		Method:     r.Method,
+		URL:        r.URL.Path,
+		Headers:    headers,
+		Body:       body,
+		Query:      query,
+		Params:     make(map[string]string), // Would be populated by router
+		Context:    r.Context(),
+		RawQuery:   r.URL.RawQuery,
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		TLS:        r.TLS != nil,
 	}
 }
 
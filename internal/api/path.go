@@ -0,0 +1,94 @@
+package api
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// PathSeparator is the separator used by the path API. Paths are always
+// returned with forward slashes so scripts behave the same on every platform.
+const PathSeparator = "/"
+
+// JoinPath joins path elements and normalizes the result
+func JoinPath(elem ...string) string {
+	joined := filepath.Join(elem...)
+	if joined == "" {
+		return "."
+	}
+	return filepath.ToSlash(joined)
+}
+
+// ResolvePath resolves path elements to an absolute path. Elements are
+// processed left to right; an absolute element discards the ones before it,
+// and relative results are resolved against the working directory.
+func ResolvePath(elem ...string) (string, error) {
+	resolved := ""
+	for _, e := range elem {
+		if e == "" {
+			continue
+		}
+		e = filepath.FromSlash(e)
+		if filepath.IsAbs(e) {
+			resolved = e
+		} else {
+			resolved = filepath.Join(resolved, e)
+		}
+	}
+
+	abs, err := filepath.Abs(resolved)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(abs), nil
+}
+
+// DirnamePath returns all but the last element of a path
+func DirnamePath(path string) string {
+	return filepath.ToSlash(filepath.Dir(filepath.FromSlash(path)))
+}
+
+// BasenamePath returns the last element of a path, without ext if it
+// matches the end of the name
+func BasenamePath(path, ext string) string {
+	if path == "" {
+		return ""
+	}
+	base := filepath.ToSlash(filepath.Base(filepath.FromSlash(path)))
+	if ext != "" && ext != base && strings.HasSuffix(base, ext) {
+		base = strings.TrimSuffix(base, ext)
+	}
+	return base
+}
+
+// ExtnamePath returns the extension of the last element of a path. Dotfiles
+// such as ".bashrc" have no extension.
+func ExtnamePath(path string) string {
+	base := BasenamePath(path, "")
+	ext := filepath.Ext(base)
+	if ext == base {
+		return ""
+	}
+	return ext
+}
+
+// RelativePath returns the relative path from one path to another, resolving
+// both against the working directory first. Identical paths yield "".
+func RelativePath(from, to string) (string, error) {
+	fromAbs, err := ResolvePath(from)
+	if err != nil {
+		return "", err
+	}
+	toAbs, err := ResolvePath(to)
+	if err != nil {
+		return "", err
+	}
+
+	rel, err := filepath.Rel(filepath.FromSlash(fromAbs), filepath.FromSlash(toAbs))
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "", nil
+	}
+	return filepath.ToSlash(rel), nil
+}
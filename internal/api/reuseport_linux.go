@@ -0,0 +1,7 @@
+//go:build linux && !(mips || mipsle || mips64 || mips64le)
+
+package api
+
+// soReusePort is SO_REUSEPORT, which package syscall does not define on
+// Linux
+const soReusePort = 0xf
@@ -61,3 +61,13 @@ func (se *SecureEnv) LookupEnv(key string) (string, bool, error) {
 	return value, ok, nil
 }
 
+// GetAll gets all environment variables with permission check
+func (se *SecureEnv) GetAll() (map[string]string, error) {
+	// Check permission
+	if err := se.permManager.CheckPermission(se.moduleID, security.PermissionEnvRead); err != nil {
+		return nil, err
+	}
+	
+	return se.env.GetAll(), nil
+}
+
@@ -27,7 +27,7 @@ func NewSecureFS(eventLoop *eventloop.Loop, permManager *security.PermissionMana
 // ReadFile reads a file asynchronously with permission check
 func (sfs *SecureFS) ReadFile(path string, callback func([]byte, error)) {
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, security.PermissionFSRead); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, security.PermissionFSRead, path); err != nil {
 		callback(nil, err)
 		return
 	}
@@ -38,7 +38,7 @@ func (sfs *SecureFS) ReadFile(path string, callback func([]byte, error)) {
 // WriteFile writes data to a file asynchronously with permission check
 func (sfs *SecureFS) WriteFile(path string, data []byte, perm os.FileMode, callback func(error)) {
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, security.PermissionFSWrite); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, security.PermissionFSWrite, path); err != nil {
 		callback(err)
 		return
 	}
@@ -49,7 +49,7 @@ func (sfs *SecureFS) WriteFile(path string, data []byte, perm os.FileMode, callb
 // ReadDir reads a directory asynchronously with permission check
 func (sfs *SecureFS) ReadDir(path string, callback func([]fs.DirEntry, error)) {
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, security.PermissionFSRead); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, security.PermissionFSRead, path); err != nil {
 		callback(nil, err)
 		return
 	}
@@ -60,7 +60,7 @@ func (sfs *SecureFS) ReadDir(path string, callback func([]fs.DirEntry, error)) {
 // Stat gets file information asynchronously with permission check
 func (sfs *SecureFS) Stat(path string, callback func(os.FileInfo, error)) {
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, security.PermissionFSRead); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, security.PermissionFSRead, path); err != nil {
 		callback(nil, err)
 		return
 	}
@@ -71,7 +71,7 @@ func (sfs *SecureFS) Stat(path string, callback func(os.FileInfo, error)) {
 // Mkdir creates a directory asynchronously with permission check
 func (sfs *SecureFS) Mkdir(path string, perm os.FileMode, callback func(error)) {
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, security.PermissionFSWrite); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, security.PermissionFSWrite, path); err != nil {
 		callback(err)
 		return
 	}
@@ -82,7 +82,7 @@ func (sfs *SecureFS) Mkdir(path string, perm os.FileMode, callback func(error))
 // Remove removes a file or directory asynchronously with permission check
 func (sfs *SecureFS) Remove(path string, callback func(error)) {
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, security.PermissionFSWrite); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, security.PermissionFSWrite, path); err != nil {
 		callback(err)
 		return
 	}
@@ -101,7 +101,7 @@ func (sfs *SecureFS) Open(path string, flag int, perm os.FileMode, callback func
 	}
 	
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, permType); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, permType, path); err != nil {
 		callback(nil, err)
 		return
 	}
@@ -112,7 +112,7 @@ func (sfs *SecureFS) Open(path string, flag int, perm os.FileMode, callback func
 // ReadFileSync reads a file synchronously with permission check
 func (sfs *SecureFS) ReadFileSync(path string) ([]byte, error) {
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, security.PermissionFSRead); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, security.PermissionFSRead, path); err != nil {
 		return nil, err
 	}
 	
@@ -122,7 +122,7 @@ func (sfs *SecureFS) ReadFileSync(path string) ([]byte, error) {
 // WriteFileSync writes a file synchronously with permission check
 func (sfs *SecureFS) WriteFileSync(path string, data []byte, perm os.FileMode) error {
 	// Check permission
-	if err := sfs.permManager.CheckPermission(sfs.moduleID, security.PermissionFSWrite); err != nil {
+	if err := sfs.permManager.CheckPathPermission(sfs.moduleID, security.PermissionFSWrite, path); err != nil {
 		return err
 	}
 	
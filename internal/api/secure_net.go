@@ -27,7 +27,7 @@ func NewSecureNet(eventLoop *eventloop.Loop, permManager *security.PermissionMan
 // Dial connects to a network address with permission check
 func (sn *SecureNet) Dial(network, address string, callback func(net.Conn, error)) {
 	// Check permission
-	if err := sn.permManager.CheckPermission(sn.moduleID, security.PermissionNetDial); err != nil {
+	if err := sn.permManager.CheckNetPermission(sn.moduleID, security.PermissionNetDial, address); err != nil {
 		callback(nil, err)
 		return
 	}
@@ -38,7 +38,7 @@ func (sn *SecureNet) Dial(network, address string, callback func(net.Conn, error
 // DialTimeout connects to a network address with timeout and permission check
 func (sn *SecureNet) DialTimeout(network, address string, timeout time.Duration, callback func(net.Conn, error)) {
 	// Check permission
-	if err := sn.permManager.CheckPermission(sn.moduleID, security.PermissionNetDial); err != nil {
+	if err := sn.permManager.CheckNetPermission(sn.moduleID, security.PermissionNetDial, address); err != nil {
 		callback(nil, err)
 		return
 	}
@@ -49,7 +49,7 @@ func (sn *SecureNet) DialTimeout(network, address string, timeout time.Duration,
 // Listen creates a listener on a network address with permission check
 func (sn *SecureNet) Listen(network, address string, callback func(net.Listener, error)) {
 	// Check permission
-	if err := sn.permManager.CheckPermission(sn.moduleID, security.PermissionNetListen); err != nil {
+	if err := sn.permManager.CheckNetPermission(sn.moduleID, security.PermissionNetListen, address); err != nil {
 		callback(nil, err)
 		return
 	}
@@ -60,7 +60,7 @@ func (sn *SecureNet) Listen(network, address string, callback func(net.Listener,
 // LookupIP looks up IP addresses for a hostname with permission check
 func (sn *SecureNet) LookupIP(host string, callback func([]net.IP, error)) {
 	// Check permission (DNS lookup requires net permission)
-	if err := sn.permManager.CheckPermission(sn.moduleID, security.PermissionNetDial); err != nil {
+	if err := sn.permManager.CheckNetPermission(sn.moduleID, security.PermissionNetDial, host); err != nil {
 		callback(nil, err)
 		return
 	}
@@ -71,7 +71,7 @@ func (sn *SecureNet) LookupIP(host string, callback func([]net.IP, error)) {
 // LookupHost looks up host addresses for a hostname with permission check
 func (sn *SecureNet) LookupHost(host string, callback func([]string, error)) {
 	// Check permission
-	if err := sn.permManager.CheckPermission(sn.moduleID, security.PermissionNetDial); err != nil {
+	if err := sn.permManager.CheckNetPermission(sn.moduleID, security.PermissionNetDial, host); err != nil {
 		callback(nil, err)
 		return
 	}
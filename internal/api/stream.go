@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ErrStreamClosed is returned when writing to a stream that has ended or
+// whose client has disconnected
+var ErrStreamClosed = errors.New("stream closed")
+
+// Stream is a response body written in chunks over time. A handler returns
+// a Response with a Stream and may keep writing to it after returning; the
+// server sends each chunk as it arrives using chunked transfer encoding
+// until End is called or the client disconnects.
+type Stream struct {
+	chunks [][]byte
+	ended  bool
+	closed bool
+	ready  chan struct{}
+	done   chan struct{}
+	mu     sync.Mutex
+}
+
+// NewStream creates a new response stream
+func NewStream() *Stream {
+	return &Stream{
+		ready: make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+}
+
+// Write queues a chunk to be sent. It never blocks, so it is safe to call
+// from the event loop.
+func (s *Stream) Write(p []byte) error {
+	s.mu.Lock()
+	if s.ended || s.closed {
+		s.mu.Unlock()
+		return ErrStreamClosed
+	}
+	s.chunks = append(s.chunks, append([]byte(nil), p...))
+	s.mu.Unlock()
+
+	s.signal()
+	return nil
+}
+
+// WriteEvent writes a server-sent event. Each line of data is sent as its
+// own data field; an empty event name omits the event field.
+func (s *Stream) WriteEvent(event, data string) error {
+	var b strings.Builder
+	if event != "" {
+		b.WriteString("event: " + event + "\n")
+	}
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return s.Write([]byte(b.String()))
+}
+
+// End ends the stream once the queued chunks have been sent
+func (s *Stream) End() {
+	s.mu.Lock()
+	s.ended = true
+	s.mu.Unlock()
+	s.signal()
+}
+
+// Close abandons the stream, dropping chunks that have not been sent
+func (s *Stream) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.done)
+	}
+}
+
+// Done returns a channel that is closed when the stream is finished, either
+// because it was sent to the end or because the client disconnected
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// signal wakes up the writer goroutine
+func (s *Stream) signal() {
+	select {
+	case s.ready <- struct{}{}:
+	default:
+	}
+}
+
+// pipe sends queued chunks to w, flushing after each batch, until the
+// stream ends or ctx is done
+func (s *Stream) pipe(ctx context.Context, w http.ResponseWriter) {
+	defer s.Close()
+
+	flusher, _ := w.(http.Flusher)
+	flush := func() {
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	// Send the headers right away so clients see the stream open
+	flush()
+
+	for {
+		s.mu.Lock()
+		chunks, ended, closed := s.chunks, s.ended, s.closed
+		s.chunks = nil
+		s.mu.Unlock()
+
+		if closed {
+			return
+		}
+		for _, chunk := range chunks {
+			if _, err := w.Write(chunk); err != nil {
+				return
+			}
+		}
+		if len(chunks) > 0 {
+			flush()
+		}
+		if ended {
+			return
+		}
+
+		select {
+		case <-s.ready:
+		case <-s.done:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// URLInfo represents the components of a parsed URL
+type URLInfo struct {
+	Href     string
+	Protocol string // scheme with trailing colon, e.g. "https:"
+	Username string
+	Password string
+	Host     string // hostname and port
+	Hostname string
+	Port     string
+	Pathname string
+	Search   string // query string with leading "?"
+	Query    map[string]interface{}
+	Hash     string // fragment with leading "#"
+}
+
+// ParseURL parses a URL into its components
+func ParseURL(raw string) (*URLInfo, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+
+	info := &URLInfo{
+		Href:     u.String(),
+		Host:     u.Host,
+		Hostname: u.Hostname(),
+		Port:     u.Port(),
+		Pathname: u.EscapedPath(),
+		Query:    QueryToMap(u.Query()),
+	}
+	if u.Scheme != "" {
+		info.Protocol = u.Scheme + ":"
+	}
+	if u.User != nil {
+		info.Username = u.User.Username()
+		info.Password, _ = u.User.Password()
+	}
+	if u.RawQuery != "" {
+		info.Search = "?" + u.RawQuery
+	}
+	if u.Fragment != "" {
+		info.Hash = "#" + u.EscapedFragment()
+	}
+	return info, nil
+}
+
+// FormatURL builds a URL string from its components. Host takes precedence
+// over Hostname and Port, and Search over Query.
+func FormatURL(info *URLInfo) string {
+	u := &url.URL{
+		Scheme: strings.TrimSuffix(info.Protocol, ":"),
+		Host:   info.Host,
+	}
+	setEscaped(&u.Path, &u.RawPath, info.Pathname)
+
+	if u.Host == "" && info.Hostname != "" {
+		u.Host = info.Hostname
+		if info.Port != "" {
+			u.Host = net.JoinHostPort(info.Hostname, info.Port)
+		}
+	}
+	if info.Username != "" {
+		if info.Password != "" {
+			u.User = url.UserPassword(info.Username, info.Password)
+		} else {
+			u.User = url.User(info.Username)
+		}
+	}
+	if info.Search != "" {
+		u.RawQuery = strings.TrimPrefix(info.Search, "?")
+	} else if len(info.Query) > 0 {
+		u.RawQuery = StringifyQuery(info.Query)
+	}
+	if info.Hash != "" {
+		setEscaped(&u.Fragment, &u.RawFragment, strings.TrimPrefix(info.Hash, "#"))
+	}
+	if u.Host != "" && u.Path != "" && !strings.HasPrefix(u.Path, "/") {
+		u.Path = "/" + u.Path
+		u.RawPath = ""
+	}
+	return u.String()
+}
+
+// setEscaped sets a URL component from a possibly percent-encoded value,
+// keeping the original encoding
+func setEscaped(decoded, raw *string, value string) {
+	unescaped, err := url.PathUnescape(value)
+	if err != nil {
+		*decoded = value
+		return
+	}
+	*decoded = unescaped
+	*raw = value
+}
+
+// ParseQuery parses a query string. Keys that appear once map to a string
+// and repeated keys map to a []string.
+func ParseQuery(qs string) (map[string]interface{}, error) {
+	values, err := url.ParseQuery(strings.TrimPrefix(qs, "?"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid query string: %w", err)
+	}
+	return QueryToMap(values), nil
+}
+
+// QueryToMap converts query values to a map of strings, using []string for
+// repeated keys
+func QueryToMap(values url.Values) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for key, vals := range values {
+		if len(vals) == 1 {
+			result[key] = vals[0]
+		} else {
+			result[key] = append([]string(nil), vals...)
+		}
+	}
+	return result
+}
+
+// FirstQueryValues returns the first value of each query parameter
+func FirstQueryValues(values url.Values) map[string]string {
+	result := make(map[string]string, len(values))
+	for key, vals := range values {
+		if len(vals) > 0 {
+			result[key] = vals[0]
+		}
+	}
+	return result
+}
+
+// StringifyQuery encodes a map as a query string with keys in sorted order.
+// Slice values produce repeated keys.
+func StringifyQuery(query map[string]interface{}) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range queryValues(query[key]) {
+			parts = append(parts, url.QueryEscape(key)+"="+url.QueryEscape(value))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// queryValues converts a query map value to its string values
+func queryValues(value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return []string{""}
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprintf("%v", item))
+		}
+		return values
+	default:
+		return []string{fmt.Sprintf("%v", v)}
+	}
+}
@@ -0,0 +1,101 @@
+package concurrency
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+)
+
+// Semaphore limits how many holders may proceed at once. Acquire does not
+// block: it calls back once a permit is granted, so that callers such as
+// the event loop never wait on it. Permits are granted in the order they
+// were requested.
+type Semaphore struct {
+	permits   int
+	available int
+	waiters   *list.List // grant callbacks of pending acquires, oldest first
+	mu        sync.Mutex
+}
+
+// NewSemaphore creates a semaphore with n permits
+func NewSemaphore(n int) (*Semaphore, error) {
+	if n < 1 {
+		return nil, fmt.Errorf("semaphore needs at least 1 permit, got %d", n)
+	}
+	return &Semaphore{
+		permits:   n,
+		available: n,
+		waiters:   list.New(),
+	}, nil
+}
+
+// Acquire requests a permit and calls grant once it is held, immediately
+// if one is free. The returned function withdraws a request that has not
+// been granted yet and reports whether it did.
+func (s *Semaphore) Acquire(grant func()) (cancel func() bool) {
+	s.mu.Lock()
+	if s.available > 0 && s.waiters.Len() == 0 {
+		s.available--
+		s.mu.Unlock()
+		grant()
+		return func() bool { return false }
+	}
+	elem := s.waiters.PushBack(grant)
+	s.mu.Unlock()
+
+	return func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for e := s.waiters.Front(); e != nil; e = e.Next() {
+			if e == elem {
+				s.waiters.Remove(e)
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// TryAcquire takes a permit if one is free without waiting
+func (s *Semaphore) TryAcquire() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.available > 0 && s.waiters.Len() == 0 {
+		s.available--
+		return true
+	}
+	return false
+}
+
+// Release returns a permit, handing it to the oldest pending acquire if
+// there is one. Releasing a permit that is not held is an error.
+func (s *Semaphore) Release() error {
+	s.mu.Lock()
+	if front := s.waiters.Front(); front != nil {
+		s.waiters.Remove(front)
+		s.mu.Unlock()
+		front.Value.(func())()
+		return nil
+	}
+	if s.available == s.permits {
+		s.mu.Unlock()
+		return fmt.Errorf("semaphore released more times than acquired")
+	}
+	s.available++
+	s.mu.Unlock()
+	return nil
+}
+
+// Available returns the number of free permits
+func (s *Semaphore) Available() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.available
+}
+
+// Waiting returns the number of pending acquires
+func (s *Semaphore) Waiting() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.waiters.Len()
+}
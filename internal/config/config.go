@@ -2,9 +2,12 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"time"
 
 	"gots-runtime/internal/security"
 )
@@ -15,15 +18,30 @@ type ProjectConfig struct {
 	Version     string                 `json:"version"`
 	Main        string                 `json:"main,omitempty"`
 	Permissions []PermissionConfig     `json:"permissions,omitempty"`
+	Quotas      []QuotaConfig          `json:"quotas,omitempty"`
 	Observability *ObservabilityConfig `json:"observability,omitempty"`
 	Runtime     *RuntimeConfig         `json:"runtime,omitempty"`
 	Modules     []ModuleConfig         `json:"modules,omitempty"`
+	Domains     []DomainConfig         `json:"domains,omitempty"`
+	CompilerOptions *CompilerOptions   `json:"compilerOptions,omitempty"`
+
+	warnings []FieldError // problems found by LoadConfig that do not prevent use
 }
 
 // PermissionConfig represents module permissions
 type PermissionConfig struct {
 	Module      string   `json:"module"`
 	Permissions []string `json:"permissions"`
+	Hosts       []string `json:"hosts,omitempty"` // host[:port] allowlist for net permissions
+}
+
+// QuotaConfig caps the operations of a category a module may make per
+// window
+type QuotaConfig struct {
+	Module   string `json:"module"`
+	Category string `json:"category"`         // fs, net, env or rpc
+	Limit    int    `json:"limit"`            // operations per window
+	Window   string `json:"window,omitempty"` // duration such as "1s" or "1m"; defaults to "1s"
 }
 
 // ObservabilityConfig represents observability settings
@@ -37,11 +55,14 @@ type ObservabilityConfig struct {
 
 // RuntimeConfig represents runtime settings
 type RuntimeConfig struct {
-	SandboxMode      string `json:"sandboxMode,omitempty"`
-	MaxWorkers       int    `json:"maxWorkers,omitempty"`
-	EventQueueSize   int    `json:"eventQueueSize,omitempty"`
-	EnableHotReload  bool   `json:"enableHotReload,omitempty"`
-	TypeEnforcement  bool   `json:"typeEnforcement,omitempty"`
+	SandboxMode        string `json:"sandboxMode,omitempty"`
+	MaxWorkers         int    `json:"maxWorkers,omitempty"`
+	EventQueueSize     int    `json:"eventQueueSize,omitempty"`
+	QueuePolicy        string `json:"queuePolicy,omitempty"` // block, drop-oldest or reject
+	EnableHotReload    bool   `json:"enableHotReload,omitempty"`
+	TypeEnforcement    bool   `json:"typeEnforcement,omitempty"`
+	ReusePort          bool   `json:"reusePort,omitempty"`          // SO_REUSEPORT on HTTP listeners
+	TranspileCacheSize int    `json:"transpileCacheSize,omitempty"` // transpiled files kept in memory
 }
 
 // ModuleConfig represents module configuration
@@ -52,22 +73,53 @@ type ModuleConfig struct {
 	Sandbox     bool     `json:"sandbox,omitempty"`
 }
 
-// LoadConfig loads configuration from a file
+// DomainConfig represents a DDD domain and the domains it may call
+type DomainConfig struct {
+	Name    string           `json:"name"`
+	Modules []string         `json:"modules"` // files or directories relative to the project root
+	Allow   []BoundaryConfig `json:"allow,omitempty"`
+}
+
+// BoundaryConfig permits calls from a domain to another domain
+type BoundaryConfig struct {
+	Domain   string `json:"domain"`
+	Protocol string `json:"protocol,omitempty"` // comma-separated, e.g. "rpc,http"; empty allows any
+}
+
+// LoadConfig loads configuration from a file. The file is checked against
+// the configuration schema and every problem is reported at once, in a
+// *ValidationError; unknown keys only produce warnings, returned by the
+// configuration's Warnings method.
 func LoadConfig(configPath string) (*ProjectConfig, error) {
 	data, err := os.ReadFile(configPath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 	
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	v := &validator{}
+	checkSchema(raw, reflect.TypeOf(ProjectConfig{}), "", v)
+	
+	// Values of the wrong type are left unset and have been reported by
+	// checkSchema; the rest is still validated
 	var config ProjectConfig
 	if err := json.Unmarshal(data, &config); err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %w", err)
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) || len(v.errors) == 0 {
+			return nil, fmt.Errorf("failed to parse config file: %w", err)
+		}
 	}
 	
 	// Validate config
-	if err := config.Validate(); err != nil {
+	config.validate(v)
+	config.validateFiles(filepath.Dir(configPath), v)
+	if err := v.err(); err != nil {
 		return nil, fmt.Errorf("invalid config: %w", err)
 	}
+	config.warnings = v.warnings
 	
 	return &config, nil
 }
@@ -105,35 +157,12 @@ func SaveConfig(config *ProjectConfig, configPath string) error {
 	return nil
 }
 
-// Validate validates the configuration
+// Validate validates the configuration. It returns a *ValidationError
+// listing every problem, or nil.
 func (c *ProjectConfig) Validate() error {
-	if c.Name == "" {
-		return fmt.Errorf("project name is required")
-	}
-	
-	// Validate permissions
-	for _, perm := range c.Permissions {
-		if perm.Module == "" {
-			return fmt.Errorf("permission module name is required")
-		}
-		for _, p := range perm.Permissions {
-			if !isValidPermission(p) {
-				return fmt.Errorf("invalid permission: %s", p)
-			}
-		}
-	}
-	
-	// Validate modules
-	for _, mod := range c.Modules {
-		if mod.ID == "" {
-			return fmt.Errorf("module ID is required")
-		}
-		if mod.Path == "" {
-			return fmt.Errorf("module path is required")
-		}
-	}
-	
-	return nil
+	v := &validator{}
+	c.validate(v)
+	return v.err()
 }
 
 // GetDefaultConfig returns a default configuration
@@ -153,6 +182,7 @@ func GetDefaultConfig() *ProjectConfig {
 			SandboxMode:     "none",
 			MaxWorkers:      10,
 			EventQueueSize:  1000,
+			QueuePolicy:     "block",
 			EnableHotReload: false,
 			TypeEnforcement: true,
 		},
@@ -168,9 +198,64 @@ func (pc *PermissionConfig) ToSecurityPermissions() []security.Permission {
 	return perms
 }
 
-// isValidPermission checks if a permission string is valid
-func isValidPermission(perm string) bool {
-	validPerms := []string{
+// Policy builds a security policy for the module. Net permissions are
+// limited to Hosts when a host list is configured.
+func (pc *PermissionConfig) Policy() *security.Policy {
+	policy := security.NewPolicy(pc.Module)
+	pc.ApplyTo(policy)
+	return policy
+}
+
+// ApplyTo grants the configured permissions on an existing policy
+func (pc *PermissionConfig) ApplyTo(policy *security.Policy) {
+	for _, perm := range pc.ToSecurityPermissions() {
+		isNet := perm == security.PermissionNetDial || perm == security.PermissionNetListen
+		if isNet && len(pc.Hosts) > 0 {
+			policy.AllowScoped(perm, pc.Hosts...)
+		} else {
+			policy.Allow(perm)
+		}
+	}
+}
+
+// Quota returns the configured quota
+func (qc *QuotaConfig) Quota() (security.Quota, error) {
+	valid := false
+	for _, category := range security.QuotaCategories {
+		if qc.Category == string(category) {
+			valid = true
+		}
+	}
+	if !valid {
+		return security.Quota{}, fmt.Errorf("invalid quota category for module %s: %q", qc.Module, qc.Category)
+	}
+	if qc.Limit <= 0 {
+		return security.Quota{}, fmt.Errorf("quota limit for module %s must be positive", qc.Module)
+	}
+	
+	window := time.Second
+	if qc.Window != "" {
+		parsed, err := time.ParseDuration(qc.Window)
+		if err != nil || parsed <= 0 {
+			return security.Quota{}, fmt.Errorf("invalid quota window for module %s: %q", qc.Module, qc.Window)
+		}
+		window = parsed
+	}
+	return security.Quota{Limit: qc.Limit, Window: window}, nil
+}
+
+// ApplyTo sets the configured quota on a quota manager
+func (qc *QuotaConfig) ApplyTo(qm *security.QuotaManager) error {
+	quota, err := qc.Quota()
+	if err != nil {
+		return err
+	}
+	return qm.SetQuota(qc.Module, security.QuotaCategory(qc.Category), quota)
+}
+
+// validPermissions returns the permissions a configuration may grant
+func validPermissions() []string {
+	return []string{
 		string(security.PermissionFSRead),
 		string(security.PermissionFSWrite),
 		string(security.PermissionNetDial),
@@ -179,12 +264,14 @@ func isValidPermission(perm string) bool {
 		string(security.PermissionEnvWrite),
 		string(security.PermissionAll),
 	}
-	
-	for _, vp := range validPerms {
+}
+
+// isValidPermission checks if a permission string is valid
+func isValidPermission(perm string) bool {
+	for _, vp := range validPermissions() {
 		if perm == vp {
 			return true
 		}
 	}
 	return false
 }
-
@@ -0,0 +1,389 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gots-runtime/internal/security"
+)
+
+// Allowed values of the enumerated settings
+var (
+	queuePolicies = []string{"block", "drop-oldest", "reject"}
+	sandboxModes  = []string{"none", "strict", "deterministic"}
+	logLevels     = []string{"debug", "info", "warn", "error"}
+)
+
+// FieldError is a problem with one value of a configuration, located by
+// its JSON path, e.g. "runtime.queuePolicy" or "permissions[0].module"
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+// Error returns the path and the problem
+func (e FieldError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationError lists every problem found in a configuration
+type ValidationError struct {
+	Errors []FieldError
+}
+
+// Error lists the problems, one per line when there are several
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+	lines := make([]string, len(e.Errors))
+	for i, fieldErr := range e.Errors {
+		lines[i] = "  " + fieldErr.Error()
+	}
+	return fmt.Sprintf("%d problems:\n%s", len(e.Errors), strings.Join(lines, "\n"))
+}
+
+// validator accumulates the problems of a configuration
+type validator struct {
+	errors   []FieldError
+	warnings []FieldError
+}
+
+// fail records an error at path, unless one has been recorded there
+// already, such as a value of the wrong type
+func (v *validator) fail(path, format string, args ...interface{}) {
+	for _, existing := range v.errors {
+		if existing.Path == path {
+			return
+		}
+	}
+	v.errors = append(v.errors, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// warn records a warning at path
+func (v *validator) warn(path, format string, args ...interface{}) {
+	v.warnings = append(v.warnings, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns the recorded errors as a *ValidationError, or nil
+func (v *validator) err() error {
+	if len(v.errors) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: v.errors}
+}
+
+// Warnings returns the problems found when the configuration was loaded
+// that do not prevent its use, such as unknown keys
+func (c *ProjectConfig) Warnings() []FieldError {
+	return c.warnings
+}
+
+// checkSchema checks decoded JSON against the Go type it is decoded into:
+// every value must have the JSON type of its field, and keys that match no
+// field are reported as warnings
+func checkSchema(value interface{}, t reflect.Type, path string, v *validator) {
+	if value == nil {
+		return // null leaves the field unset
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			v.fail(path, "expected object, got %s", jsonType(value))
+			return
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			child := obj[key]
+			field, ok := fields[key]
+			if !ok {
+				if suggestion := suggestKey(key, fields); suggestion != "" {
+					v.warn(joinPath(path, key), "unknown key (did you mean %q?)", suggestion)
+				} else {
+					v.warn(joinPath(path, key), "unknown key")
+				}
+				continue
+			}
+			checkSchema(child, field.Type, joinPath(path, key), v)
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			v.fail(path, "expected array, got %s", jsonType(value))
+			return
+		}
+		for i, item := range items {
+			checkSchema(item, t.Elem(), path+"["+strconv.Itoa(i)+"]", v)
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			v.fail(path, "expected string, got %s", jsonType(value))
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			v.fail(path, "expected boolean, got %s", jsonType(value))
+		}
+	case reflect.Int, reflect.Int64:
+		n, ok := value.(float64)
+		if !ok {
+			v.fail(path, "expected number, got %s", jsonType(value))
+		} else if n != math.Trunc(n) {
+			v.fail(path, "expected an integer, got %v", n)
+		}
+	}
+}
+
+// jsonFields maps the JSON names of the fields of a struct type to the
+// fields
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" || field.PkgPath != "" {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// suggestKey returns the known key that key is probably a misspelling of:
+// one that differs only in case, or by a single edit
+func suggestKey(key string, fields map[string]reflect.StructField) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if strings.EqualFold(name, key) {
+			return name
+		}
+	}
+	for _, name := range names {
+		if editDistanceOne(strings.ToLower(name), strings.ToLower(key)) {
+			return name
+		}
+	}
+	return ""
+}
+
+// editDistanceOne reports whether a and b differ by exactly one inserted,
+// deleted or replaced byte
+func editDistanceOne(a, b string) bool {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	if len(a)-len(b) > 1 || a == b {
+		return false
+	}
+	i := 0
+	for i < len(b) && a[i] == b[i] {
+		i++
+	}
+	if len(a) == len(b) {
+		return a[i+1:] == b[i+1:]
+	}
+	return a[i+1:] == b[i:]
+}
+
+// jsonType names the JSON type of a decoded value
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+// joinPath appends a key to a JSON path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// indexPath appends an array index and a key to a JSON path
+func indexPath(path string, i int, key string) string {
+	return joinPath(path+"["+strconv.Itoa(i)+"]", key)
+}
+
+// validate checks the values of the configuration
+func (c *ProjectConfig) validate(v *validator) {
+	if c.Name == "" {
+		v.fail("name", "project name is required")
+	}
+
+	for i, perm := range c.Permissions {
+		if perm.Module == "" {
+			v.fail(indexPath("permissions", i, "module"), "permission module name is required")
+		}
+		for j, p := range perm.Permissions {
+			if !isValidPermission(p) {
+				v.fail(indexPath("permissions", i, "permissions")+"["+strconv.Itoa(j)+"]",
+					"invalid permission %q (expected one of %s)", p, strings.Join(validPermissions(), ", "))
+			}
+		}
+		for j, host := range perm.Hosts {
+			if host == "" {
+				v.fail(indexPath("permissions", i, "hosts")+"["+strconv.Itoa(j)+"]", "host must not be empty")
+			}
+		}
+	}
+
+	for i, quota := range c.Quotas {
+		if quota.Module == "" {
+			v.fail(indexPath("quotas", i, "module"), "quota module name is required")
+		}
+		categories := make([]string, len(security.QuotaCategories))
+		for k, category := range security.QuotaCategories {
+			categories[k] = string(category)
+		}
+		checkEnum(v, indexPath("quotas", i, "category"), quota.Category, categories, true)
+		if quota.Limit <= 0 {
+			v.fail(indexPath("quotas", i, "limit"), "quota limit must be positive")
+		}
+		if quota.Window != "" {
+			if window, err := time.ParseDuration(quota.Window); err != nil || window <= 0 {
+				v.fail(indexPath("quotas", i, "window"), "invalid duration %q (e.g. \"1s\" or \"1m\")", quota.Window)
+			}
+		}
+	}
+
+	if c.Runtime != nil {
+		if c.Runtime.EventQueueSize < 0 {
+			v.fail("runtime.eventQueueSize", "eventQueueSize must not be negative")
+		}
+		if c.Runtime.MaxWorkers < 0 {
+			v.fail("runtime.maxWorkers", "maxWorkers must not be negative")
+		}
+		if c.Runtime.TranspileCacheSize < 0 {
+			v.fail("runtime.transpileCacheSize", "transpileCacheSize must not be negative")
+		}
+		checkEnum(v, "runtime.queuePolicy", c.Runtime.QueuePolicy, queuePolicies, false)
+		checkEnum(v, "runtime.sandboxMode", c.Runtime.SandboxMode, sandboxModes, false)
+	}
+
+	if obs := c.Observability; obs != nil {
+		checkPort(v, "observability.healthPort", obs.HealthPort)
+		checkPort(v, "observability.metricsPort", obs.MetricsPort)
+		if obs.HealthPort != 0 && obs.HealthPort == obs.MetricsPort {
+			v.fail("observability.metricsPort", "port %d is already the healthPort", obs.MetricsPort)
+		}
+		checkEnum(v, "observability.logLevel", obs.LogLevel, logLevels, false)
+	}
+
+	moduleIDs := make(map[string]int)
+	for i, mod := range c.Modules {
+		if mod.ID == "" {
+			v.fail(indexPath("modules", i, "id"), "module ID is required")
+		} else if first, ok := moduleIDs[mod.ID]; ok {
+			v.fail(indexPath("modules", i, "id"), "duplicate module ID %q, also used by modules[%d]", mod.ID, first)
+		} else {
+			moduleIDs[mod.ID] = i
+		}
+		if mod.Path == "" {
+			v.fail(indexPath("modules", i, "path"), "module path is required")
+		}
+	}
+
+	domains := make(map[string]int)
+	for i, domain := range c.Domains {
+		if domain.Name == "" {
+			v.fail(indexPath("domains", i, "name"), "domain name is required")
+		} else if first, ok := domains[domain.Name]; ok {
+			v.fail(indexPath("domains", i, "name"), "duplicate domain %q, also declared by domains[%d]", domain.Name, first)
+		} else {
+			domains[domain.Name] = i
+		}
+	}
+	for i, domain := range c.Domains {
+		for j, boundary := range domain.Allow {
+			path := indexPath(indexPath("domains", i, "allow"), j, "domain")
+			if boundary.Domain == "" {
+				v.fail(path, "boundary target domain is required")
+			} else if _, ok := domains[boundary.Domain]; !ok {
+				v.fail(path, "unknown domain %q", boundary.Domain)
+			}
+		}
+	}
+}
+
+// validateFiles checks that the files the configuration refers to exist,
+// relative to the project root dir
+func (c *ProjectConfig) validateFiles(dir string, v *validator) {
+	exists := func(path, file string) {
+		if file == "" {
+			return
+		}
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(dir, file)
+		}
+		if _, err := os.Stat(file); err != nil {
+			v.fail(path, "%s does not exist", file)
+		}
+	}
+
+	exists("main", c.Main)
+	for i, mod := range c.Modules {
+		exists(indexPath("modules", i, "path"), mod.Path)
+	}
+	for i, domain := range c.Domains {
+		for j, module := range domain.Modules {
+			exists(indexPath("domains", i, "modules")+"["+strconv.Itoa(j)+"]", module)
+		}
+	}
+}
+
+// checkEnum checks that value is one of allowed; an empty value is only
+// accepted when the setting is optional
+func checkEnum(v *validator, path, value string, allowed []string, required bool) {
+	if value == "" && !required {
+		return
+	}
+	for _, a := range allowed {
+		if value == a {
+			return
+		}
+	}
+	v.fail(path, "invalid value %q (expected one of %s)", value, strings.Join(allowed, ", "))
+}
+
+// checkPort checks that a configured port is a TCP port; 0 leaves it unset
+func checkPort(v *validator, path string, port int) {
+	if port < 0 || port > 65535 {
+		v.fail(path, "port %d is out of range (1-65535)", port)
+	}
+}
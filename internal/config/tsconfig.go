@@ -0,0 +1,132 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// CompilerOptions are the tsconfig.json compiler options the transpiler
+// understands
+type CompilerOptions struct {
+	Target string `json:"target,omitempty"` // e.g. "ES2020" or "ESNext"
+	Module string `json:"module,omitempty"` // e.g. "CommonJS" or "ESNext"
+	JSX    string `json:"jsx,omitempty"`    // "react", "react-jsx", "react-jsxdev" or "preserve"
+
+	JSXFactory         string `json:"jsxFactory,omitempty"`         // e.g. "h"; defaults to React.createElement
+	JSXFragmentFactory string `json:"jsxFragmentFactory,omitempty"` // e.g. "Fragment"; defaults to React.Fragment
+}
+
+// tsConfig is the part of a tsconfig.json file read by the runtime
+type tsConfig struct {
+	CompilerOptions *CompilerOptions `json:"compilerOptions"`
+}
+
+// FindCompilerOptions searches startDir and its parents for compiler
+// options. In each directory a tsconfig.json takes precedence over the
+// compilerOptions block of a gots.json. It returns nil options and an empty
+// path when neither is found.
+func FindCompilerOptions(startDir string) (*CompilerOptions, string, error) {
+	dir := startDir
+	for {
+		tsconfigPath := filepath.Join(dir, "tsconfig.json")
+		if _, err := os.Stat(tsconfigPath); err == nil {
+			opts, err := LoadTSConfig(tsconfigPath)
+			return opts, tsconfigPath, err
+		}
+
+		configPath := filepath.Join(dir, "gots.json")
+		if _, err := os.Stat(configPath); err == nil {
+			cfg, err := LoadConfig(configPath)
+			if err != nil {
+				return nil, configPath, err
+			}
+			if cfg.CompilerOptions != nil {
+				return cfg.CompilerOptions, configPath, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break // Reached root
+		}
+		dir = parent
+	}
+
+	return nil, "", nil
+}
+
+// LoadTSConfig loads the compiler options of a tsconfig.json file. Comments
+// and trailing commas are allowed, as in TypeScript.
+func LoadTSConfig(path string) (*CompilerOptions, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tsconfig: %w", err)
+	}
+
+	var cfg tsConfig
+	if err := json.Unmarshal(stripJSONC(data), &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse tsconfig %s: %w", path, err)
+	}
+	if cfg.CompilerOptions == nil {
+		return &CompilerOptions{}, nil
+	}
+	return cfg.CompilerOptions, nil
+}
+
+// stripJSONC removes comments and trailing commas from JSON with comments
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	inString := false
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		if inString {
+			out = append(out, c)
+			if c == '\\' && i+1 < len(data) {
+				i++
+				out = append(out, data[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+
+		switch {
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(data) && data[i+1] == '/':
+			for i < len(data) && data[i] != '\n' {
+				i++
+			}
+			if i < len(data) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(data) && data[i+1] == '*':
+			i += 2
+			for i+1 < len(data) && !(data[i] == '*' && data[i+1] == '/') {
+				i++
+			}
+			i++
+		case c == '}' || c == ']':
+			// Drop a trailing comma before the closing bracket
+			j := len(out) - 1
+			for j >= 0 && isJSONSpace(out[j]) {
+				j--
+			}
+			if j >= 0 && out[j] == ',' {
+				out = append(out[:j], out[j+1:]...)
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// isJSONSpace reports whether c is JSON whitespace
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
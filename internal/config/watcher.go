@@ -0,0 +1,194 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"gots-runtime/internal/hotreload"
+)
+
+// watchDebounce is how long the watcher waits for writes to settle before
+// reloading
+const watchDebounce = 100 * time.Millisecond
+
+// ConfigChange is a configuration that replaced the previous one
+type ConfigChange struct {
+	Config   *ProjectConfig
+	Previous *ProjectConfig
+}
+
+// Watcher keeps a gots.json loaded and reloads it when it changes on disk,
+// together with the tsconfig.json next to it, which it references for
+// compiler options. A changed configuration that fails to load or validate
+// is rejected and the previous one stays current.
+type Watcher struct {
+	path        string
+	files       []string
+	current     *ProjectConfig
+	subscribers map[int]func(ConfigChange)
+	nextID      int
+	onError     func(error)
+	reloader    *hotreload.HotReloader
+	mu          sync.RWMutex
+}
+
+// NewWatcher loads the configuration at path for watching. It fails if
+// the configuration is invalid.
+func NewWatcher(path string) (*Watcher, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	w := &Watcher{
+		path:        abs,
+		files:       []string{abs},
+		subscribers: make(map[int]func(ConfigChange)),
+	}
+
+	tsconfig := filepath.Join(filepath.Dir(abs), "tsconfig.json")
+	if _, err := os.Stat(tsconfig); err == nil {
+		w.files = append(w.files, tsconfig)
+	}
+
+	cfg, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	w.current = cfg
+	return w, nil
+}
+
+// Path returns the path of the watched gots.json
+func (w *Watcher) Path() string {
+	return w.path
+}
+
+// Current returns the configuration in effect
+func (w *Watcher) Current() *ProjectConfig {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.current
+}
+
+// Subscribe calls fn with every accepted change until the returned
+// function is called
+func (w *Watcher) Subscribe(fn func(ConfigChange)) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.nextID
+	w.nextID++
+	w.subscribers[id] = fn
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subscribers, id)
+	}
+}
+
+// OnError sets a function called with the reason a changed configuration
+// was rejected
+func (w *Watcher) OnError(fn func(error)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onError = fn
+}
+
+// load reads and validates the configuration. Compiler options of the
+// tsconfig.json take precedence over those of the gots.json, as in
+// FindCompilerOptions.
+func (w *Watcher) load() (*ProjectConfig, error) {
+	cfg, err := LoadConfig(w.path)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range w.files[1:] {
+		opts, err := LoadTSConfig(file)
+		if err != nil {
+			return nil, err
+		}
+		cfg.CompilerOptions = opts
+	}
+	return cfg, nil
+}
+
+// Reload loads the configuration again and, if it is valid and differs
+// from the current one, makes it current and notifies the subscribers. An
+// invalid configuration is reported to the error handler and returned.
+func (w *Watcher) Reload() error {
+	cfg, err := w.load()
+	if err != nil {
+		err = fmt.Errorf("rejected configuration change, keeping the previous one: %w", err)
+		w.mu.RLock()
+		onError := w.onError
+		w.mu.RUnlock()
+		if onError != nil {
+			onError(err)
+		}
+		return err
+	}
+
+	w.mu.Lock()
+	previous := w.current
+	if sameConfig(previous, cfg) {
+		w.mu.Unlock()
+		return nil
+	}
+	w.current = cfg
+	subscribers := make([]func(ConfigChange), 0, len(w.subscribers))
+	for _, fn := range w.subscribers {
+		subscribers = append(subscribers, fn)
+	}
+	w.mu.Unlock()
+
+	change := ConfigChange{Config: cfg, Previous: previous}
+	for _, fn := range subscribers {
+		fn(change)
+	}
+	return nil
+}
+
+// Start starts watching the configuration files
+func (w *Watcher) Start() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.reloader != nil {
+		return fmt.Errorf("config watcher already running")
+	}
+
+	reloader, err := hotreload.NewHotReloader(&hotreload.HotReloadConfig{
+		Watch:    w.files,
+		Debounce: watchDebounce,
+		OnReload: w.Reload,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.path, err)
+	}
+	if err := reloader.Start(); err != nil {
+		return fmt.Errorf("failed to watch %s: %w", w.path, err)
+	}
+	w.reloader = reloader
+	return nil
+}
+
+// Stop stops watching the configuration files
+func (w *Watcher) Stop() error {
+	w.mu.Lock()
+	reloader := w.reloader
+	w.reloader = nil
+	w.mu.Unlock()
+	if reloader == nil {
+		return nil
+	}
+	return reloader.Stop()
+}
+
+// sameConfig reports whether two configurations are equal
+func sameConfig(a, b *ProjectConfig) bool {
+	dataA, errA := json.Marshal(a)
+	dataB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(dataA) == string(dataB)
+}
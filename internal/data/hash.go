@@ -0,0 +1,192 @@
+package data
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+)
+
+// Hashable is implemented by values with their own notion of equality,
+// such as the immutable structures
+type Hashable interface {
+	Hash() uint64
+	Equals(other interface{}) bool
+}
+
+// Type tags keep values of different types that encode alike apart
+const (
+	tagNil byte = iota
+	tagBool
+	tagNumber
+	tagString
+	tagArray
+	tagObject
+	tagMap
+	tagList
+	tagSet
+	tagOther
+)
+
+// Hash returns a stable hash of a value exported from JavaScript. Values
+// that are Equal hash alike: arrays and objects by their contents, and
+// integral floats like the integers they equal.
+func Hash(value interface{}) uint64 {
+	h := fnv.New64a()
+	writeHash(h, value)
+	return h.Sum64()
+}
+
+// hashWriter is what values are hashed into
+type hashWriter interface {
+	Write(p []byte) (int, error)
+}
+
+// writeHash writes the canonical encoding of value to h
+func writeHash(h hashWriter, value interface{}) {
+	var buf [8]byte
+	writeUint := func(tag byte, n uint64) {
+		binary.LittleEndian.PutUint64(buf[:], n)
+		h.Write([]byte{tag})
+		h.Write(buf[:])
+	}
+	writeString := func(tag byte, s string) {
+		writeUint(tag, uint64(len(s)))
+		h.Write([]byte(s))
+	}
+
+	if n, ok := number(value); ok {
+		if math.IsNaN(n) {
+			writeUint(tagNumber, math.Float64bits(math.NaN()))
+		} else if n == math.Trunc(n) && !math.IsInf(n, 0) {
+			writeUint(tagNumber, uint64(int64(n)))
+		} else {
+			writeUint(tagNumber, math.Float64bits(n))
+		}
+		return
+	}
+
+	switch v := value.(type) {
+	case nil:
+		h.Write([]byte{tagNil})
+	case bool:
+		if v {
+			writeUint(tagBool, 1)
+		} else {
+			writeUint(tagBool, 0)
+		}
+	case string:
+		writeString(tagString, v)
+	case Hashable:
+		writeUint(tagOther, v.Hash())
+	case []interface{}:
+		writeUint(tagArray, uint64(len(v)))
+		for _, item := range v {
+			writeHash(h, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		writeUint(tagObject, uint64(len(keys)))
+		for _, k := range keys {
+			writeString(tagString, k)
+			writeHash(h, v[k])
+		}
+	default:
+		// Functions, host objects and the like hash by identity
+		writeString(tagOther, fmt.Sprintf("%T:%v", value, identity(value)))
+	}
+}
+
+// combineHash hashes a tag and a sequence of hashes
+func combineHash(tag byte, hashes ...uint64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte{tag})
+	var buf [8]byte
+	for _, n := range hashes {
+		binary.LittleEndian.PutUint64(buf[:], n)
+		h.Write(buf[:])
+	}
+	return h.Sum64()
+}
+
+// Equal reports whether two values exported from JavaScript are equal by
+// value: arrays and objects by their contents, immutable structures by
+// Equals and numbers regardless of whether they were exported as integers
+// or floats
+func Equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		// NaN equals itself, as in JavaScript sets
+		return ok && (x == y || math.IsNaN(x) && math.IsNaN(y))
+	}
+
+	switch x := a.(type) {
+	case nil:
+		return b == nil
+	case bool:
+		y, ok := b.(bool)
+		return ok && x == y
+	case string:
+		y, ok := b.(string)
+		return ok && x == y
+	case Hashable:
+		return x.Equals(b)
+	case []interface{}:
+		y, ok := b.([]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for i := range x {
+			if !Equal(x[i], y[i]) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		y, ok := b.(map[string]interface{})
+		if !ok || len(x) != len(y) {
+			return false
+		}
+		for k, v := range x {
+			w, ok := y[k]
+			if !ok || !Equal(v, w) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.TypeOf(a) == reflect.TypeOf(b) && identity(a) == identity(b)
+}
+
+// number returns value as a float64 if it is a number
+func number(value interface{}) (float64, bool) {
+	switch n := value.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// identity returns what a value without a notion of equality is compared
+// by: the pointer of reference types and the value itself otherwise
+func identity(value interface{}) interface{} {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Func, reflect.Map, reflect.Slice, reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return v.Pointer()
+	}
+	if v.IsValid() && v.Type().Comparable() {
+		return value
+	}
+	return fmt.Sprintf("%#v", value)
+}
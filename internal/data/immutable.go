@@ -82,6 +82,81 @@ func (im *ImmutableMap) Keys() []string {
 	return keys
 }
 
+// Merge returns a new map with the entries of both maps; values of other
+// win for keys in both
+func (im *ImmutableMap) Merge(other *ImmutableMap) *ImmutableMap {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	
+	newData := make(map[string]interface{}, len(im.data)+len(other.data))
+	for k, v := range im.data {
+		newData[k] = v
+	}
+	for k, v := range other.data {
+		newData[k] = v
+	}
+	
+	return &ImmutableMap{
+		data: newData,
+	}
+}
+
+// Filter returns a new map with the entries pred returns true for
+func (im *ImmutableMap) Filter(pred func(key string, value interface{}) bool) *ImmutableMap {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	
+	newData := make(map[string]interface{})
+	for k, v := range im.data {
+		if pred(k, v) {
+			newData[k] = v
+		}
+	}
+	
+	return &ImmutableMap{
+		data: newData,
+	}
+}
+
+// Map returns a new map with the same keys and the values transform
+// returns for them
+func (im *ImmutableMap) Map(transform func(key string, value interface{}) interface{}) *ImmutableMap {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	
+	newData := make(map[string]interface{}, len(im.data))
+	for k, v := range im.data {
+		newData[k] = transform(k, v)
+	}
+	
+	return &ImmutableMap{
+		data: newData,
+	}
+}
+
+// Equals reports whether other is a map with equal values for the same
+// keys
+func (im *ImmutableMap) Equals(other interface{}) bool {
+	om, ok := other.(*ImmutableMap)
+	if !ok {
+		return false
+	}
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	om.mu.RLock()
+	defer om.mu.RUnlock()
+	return Equal(im.data, om.data)
+}
+
+// Hash returns a hash of the map's entries
+func (im *ImmutableMap) Hash() uint64 {
+	im.mu.RLock()
+	defer im.mu.RUnlock()
+	return combineHash(tagMap, Hash(im.data))
+}
+
 // ImmutableList is an immutable list
 type ImmutableList struct {
 	data []interface{}
@@ -144,24 +219,171 @@ func (il *ImmutableList) Size() int {
 	return len(il.data)
 }
 
-// ImmutableSet is an immutable set
+// Slice returns the values from start up to, but not including, end as a
+// new list sharing this list's storage. Negative indexes count from the
+// end and both are clamped to the list, as with Array.prototype.slice.
+func (il *ImmutableList) Slice(start, end int) *ImmutableList {
+	il.mu.RLock()
+	defer il.mu.RUnlock()
+	
+	start = clampIndex(start, len(il.data))
+	end = clampIndex(end, len(il.data))
+	if end < start {
+		end = start
+	}
+	
+	// The full slice expression keeps appends from writing to the shared
+	// storage; Append copies anyway
+	return &ImmutableList{
+		data: il.data[start:end:end],
+	}
+}
+
+// clampIndex resolves a negative index from the end of a list of length n
+// and clamps the result to [0, n]
+func clampIndex(index, n int) int {
+	if index < 0 {
+		index += n
+	}
+	if index < 0 {
+		return 0
+	}
+	if index > n {
+		return n
+	}
+	return index
+}
+
+// Concat returns a new list with the values of this list followed by those
+// of others
+func (il *ImmutableList) Concat(others ...*ImmutableList) *ImmutableList {
+	il.mu.RLock()
+	defer il.mu.RUnlock()
+	
+	size := len(il.data)
+	for _, other := range others {
+		size += other.Size()
+	}
+	newData := make([]interface{}, 0, size)
+	newData = append(newData, il.data...)
+	for _, other := range others {
+		other.mu.RLock()
+		newData = append(newData, other.data...)
+		other.mu.RUnlock()
+	}
+	
+	return &ImmutableList{
+		data: newData,
+	}
+}
+
+// Reduce folds the values into an accumulator, starting from initial
+func (il *ImmutableList) Reduce(fn func(acc interface{}, value interface{}, index int) interface{}, initial interface{}) interface{} {
+	il.mu.RLock()
+	defer il.mu.RUnlock()
+	
+	acc := initial
+	for i, v := range il.data {
+		acc = fn(acc, v, i)
+	}
+	return acc
+}
+
+// FindIndex returns the index of the first value pred returns true for, or
+// -1 if there is none
+func (il *ImmutableList) FindIndex(pred func(value interface{}, index int) bool) int {
+	il.mu.RLock()
+	defer il.mu.RUnlock()
+	
+	for i, v := range il.data {
+		if pred(v, i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// Find returns the first value pred returns true for
+func (il *ImmutableList) Find(pred func(value interface{}, index int) bool) (interface{}, bool) {
+	index := il.FindIndex(pred)
+	if index < 0 {
+		return nil, false
+	}
+	value, err := il.Get(index)
+	return value, err == nil
+}
+
+// IndexOf returns the index of the first value Equal to value, so that
+// exported arrays and objects match by value, or -1 if there is none
+func (il *ImmutableList) IndexOf(value interface{}) int {
+	return il.FindIndex(func(v interface{}, _ int) bool {
+		return Equal(v, value)
+	})
+}
+
+// Equals reports whether other is a list with equal values in the same
+// order
+func (il *ImmutableList) Equals(other interface{}) bool {
+	ol, ok := other.(*ImmutableList)
+	if !ok {
+		return false
+	}
+	il.mu.RLock()
+	defer il.mu.RUnlock()
+	ol.mu.RLock()
+	defer ol.mu.RUnlock()
+	return Equal(il.data, ol.data)
+}
+
+// Hash returns a hash of the list's values
+func (il *ImmutableList) Hash() uint64 {
+	il.mu.RLock()
+	defer il.mu.RUnlock()
+	return combineHash(tagList, Hash(il.data))
+}
+
+// ImmutableSet is an immutable set. Values are keyed on their Hash and
+// compared with Equal, so arrays, objects and immutable structures can be
+// elements.
 type ImmutableSet struct {
-	data map[interface{}]bool
+	data map[uint64][]interface{}
+	size int
 	mu   sync.RWMutex
 }
 
 // NewImmutableSet creates a new immutable set
 func NewImmutableSet() *ImmutableSet {
 	return &ImmutableSet{
-		data: make(map[interface{}]bool),
+		data: make(map[uint64][]interface{}),
+	}
+}
+
+// contains reports whether value, whose hash is h, is in the set; the
+// caller holds the read lock
+func (is *ImmutableSet) contains(h uint64, value interface{}) bool {
+	for _, v := range is.data[h] {
+		if Equal(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// copyData returns a copy of the buckets that can be appended to without
+// changing this set
+func (is *ImmutableSet) copyData() map[uint64][]interface{} {
+	newData := make(map[uint64][]interface{}, len(is.data))
+	for h, bucket := range is.data {
+		newData[h] = bucket[:len(bucket):len(bucket)]
 	}
+	return newData
 }
 
 // Contains checks if a value is in the set
 func (is *ImmutableSet) Contains(value interface{}) bool {
 	is.mu.RLock()
 	defer is.mu.RUnlock()
-	return is.data[value]
+	return is.contains(Hash(value), value)
 }
 
 // Add adds a value (returns a new set)
@@ -169,17 +391,21 @@ func (is *ImmutableSet) Add(value interface{}) *ImmutableSet {
 	is.mu.RLock()
 	defer is.mu.RUnlock()
 	
-	// Create new set with all existing data
-	newData := make(map[interface{}]bool)
-	for k := range is.data {
-		newData[k] = true
+	h := Hash(value)
+	if is.contains(h, value) {
+		return &ImmutableSet{
+			data: is.copyData(),
+			size: is.size,
+		}
 	}
 	
-	// Add new value
-	newData[value] = true
+	// Create new set with all existing data and the new value
+	newData := is.copyData()
+	newData[h] = append(newData[h], value)
 	
 	return &ImmutableSet{
 		data: newData,
+		size: is.size + 1,
 	}
 }
 
@@ -189,15 +415,26 @@ func (is *ImmutableSet) Remove(value interface{}) *ImmutableSet {
 	defer is.mu.RUnlock()
 	
 	// Create new set without the value
-	newData := make(map[interface{}]bool)
-	for k := range is.data {
-		if k != value {
-			newData[k] = true
+	h := Hash(value)
+	newData := is.copyData()
+	size := is.size
+	var bucket []interface{}
+	for _, v := range is.data[h] {
+		if Equal(v, value) {
+			size--
+			continue
 		}
+		bucket = append(bucket, v)
+	}
+	if len(bucket) == 0 {
+		delete(newData, h)
+	} else {
+		newData[h] = bucket
 	}
 	
 	return &ImmutableSet{
 		data: newData,
+		size: size,
 	}
 }
 
@@ -205,6 +442,117 @@ func (is *ImmutableSet) Remove(value interface{}) *ImmutableSet {
 func (is *ImmutableSet) Size() int {
 	is.mu.RLock()
 	defer is.mu.RUnlock()
-	return len(is.data)
+	return is.size
+}
+
+// each calls fn with each value of the set and its hash; the caller holds
+// the read lock
+func (is *ImmutableSet) each(fn func(h uint64, value interface{})) {
+	for h, bucket := range is.data {
+		for _, v := range bucket {
+			fn(h, v)
+		}
+	}
+}
+
+// filter returns a new set with the values of this set keep returns true
+// for; the caller holds the read lock
+func (is *ImmutableSet) filter(keep func(h uint64, value interface{}) bool) *ImmutableSet {
+	newSet := NewImmutableSet()
+	is.each(func(h uint64, v interface{}) {
+		if keep(h, v) {
+			newSet.data[h] = append(newSet.data[h], v)
+			newSet.size++
+		}
+	})
+	return newSet
+}
+
+// Union returns a new set with the values of both sets
+func (is *ImmutableSet) Union(other *ImmutableSet) *ImmutableSet {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	
+	newSet := &ImmutableSet{
+		data: is.copyData(),
+		size: is.size,
+	}
+	other.each(func(h uint64, v interface{}) {
+		if !is.contains(h, v) {
+			newSet.data[h] = append(newSet.data[h], v)
+			newSet.size++
+		}
+	})
+	return newSet
+}
+
+// Intersection returns a new set with the values in both sets
+func (is *ImmutableSet) Intersection(other *ImmutableSet) *ImmutableSet {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	
+	// Walk the smaller set
+	small, large := is, other
+	if small.size > large.size {
+		small, large = large, small
+	}
+	return small.filter(func(h uint64, v interface{}) bool {
+		return large.contains(h, v)
+	})
 }
 
+// Difference returns a new set with the values of this set that are not in
+// other
+func (is *ImmutableSet) Difference(other *ImmutableSet) *ImmutableSet {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	other.mu.RLock()
+	defer other.mu.RUnlock()
+	
+	return is.filter(func(h uint64, v interface{}) bool {
+		return !other.contains(h, v)
+	})
+}
+
+// Equals reports whether other is a set with the same values
+func (is *ImmutableSet) Equals(other interface{}) bool {
+	os, ok := other.(*ImmutableSet)
+	if !ok {
+		return false
+	}
+	if os == is {
+		return true
+	}
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	os.mu.RLock()
+	defer os.mu.RUnlock()
+	
+	if is.size != os.size {
+		return false
+	}
+	equal := true
+	is.each(func(h uint64, v interface{}) {
+		if equal && !os.contains(h, v) {
+			equal = false
+		}
+	})
+	return equal
+}
+
+// Hash returns a hash of the set's values that does not depend on their
+// order
+func (is *ImmutableSet) Hash() uint64 {
+	is.mu.RLock()
+	defer is.mu.RUnlock()
+	
+	var sum uint64
+	is.each(func(h uint64, _ interface{}) {
+		sum += h
+	})
+	return combineHash(tagSet, uint64(is.size), sum)
+}
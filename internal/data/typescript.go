@@ -1,13 +1,77 @@
 package data
 
 import (
+	"errors"
+	"fmt"
+	"runtime"
+
 	"github.com/dop251/goja"
 )
 
+// entrySize is the approximate size in bytes accounted per structure entry
+const entrySize = 64
+
+// AllocationTracker accounts an approximate allocation size and returns a
+// function that releases it
+type AllocationTracker func(size uint64) (release func())
+
+// trackObject accounts a structure of entries to track until the JavaScript
+// object wrapping it is garbage collected
+func trackObject(obj *goja.Object, entries int, track AllocationTracker) {
+	if track == nil {
+		return
+	}
+	release := track(uint64(entries+1) * entrySize)
+	runtime.SetFinalizer(obj, func(*goja.Object) {
+		release()
+	})
+}
+
+// wrappedKey is the hidden symbol through which an object created by
+// ToJSObject refers to the structure it wraps
+var wrappedKey = goja.NewSymbol("gots.data.wrapped")
+
+// setWrapped links obj to the structure it wraps
+func setWrapped(engine *goja.Runtime, obj *goja.Object, structure interface{}) {
+	obj.DefineDataPropertySymbol(wrappedKey, engine.ToValue(structure), goja.FLAG_FALSE, goja.FLAG_FALSE, goja.FLAG_FALSE)
+}
+
+// wrappedBy returns the structure a JavaScript object created by ToJSObject
+// wraps, or nil for any other value
+func wrappedBy(value goja.Value) interface{} {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil
+	}
+	structure := obj.GetSymbol(wrappedKey)
+	if structure == nil {
+		return nil
+	}
+	return structure.Export()
+}
+
+// ExportValue exports a JavaScript value to compare or hash it: the
+// structure an immutable map, list or set object wraps, so that such
+// objects compare by value, or the exported value otherwise
+func ExportValue(value goja.Value) interface{} {
+	if structure := wrappedBy(value); structure != nil {
+		return structure
+	}
+	return value.Export()
+}
+
+// equals returns the equals method of a wrapper of structure
+func equals(structure Hashable) func(goja.Value) bool {
+	return func(other goja.Value) bool {
+		return structure.Equals(wrappedBy(other))
+	}
+}
+
 // TypeScriptImmutableMap wraps ImmutableMap for TypeScript
 type TypeScriptImmutableMap struct {
 	im     *ImmutableMap
 	engine *goja.Runtime
+	track  AllocationTracker
 }
 
 // NewTypeScriptImmutableMap creates a new TypeScript-wrapped immutable map
@@ -18,6 +82,45 @@ func NewTypeScriptImmutableMap(engine *goja.Runtime, im *ImmutableMap) *TypeScri
 	}
 }
 
+// WithTracker sets the tracker the map and the maps derived from it
+// report their approximate size to
+func (tsim *TypeScriptImmutableMap) WithTracker(track AllocationTracker) *TypeScriptImmutableMap {
+	tsim.track = track
+	return tsim
+}
+
+// derive wraps a map derived from this one, sharing its tracker
+func (tsim *TypeScriptImmutableMap) derive(im *ImmutableMap) *TypeScriptImmutableMap {
+	return NewTypeScriptImmutableMap(tsim.engine, im).WithTracker(tsim.track)
+}
+
+// toMap returns the map a JavaScript value stands for: the map wrapped by
+// an immutable map object or the own enumerable properties of an object
+func (tsim *TypeScriptImmutableMap) toMap(value goja.Value) (*ImmutableMap, error) {
+	if im, ok := wrappedBy(value).(*ImmutableMap); ok {
+		return im, nil
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, errors.New("expected an immutable map or an object")
+	}
+	im := NewImmutableMap()
+	for _, key := range obj.Keys() {
+		im = im.Set(key, obj.Get(key).Export())
+	}
+	return im, nil
+}
+
+// call calls a map callback with value and key, raising an exception it
+// throws in the caller
+func (tsim *TypeScriptImmutableMap) call(callback goja.Callable, key string, value interface{}) goja.Value {
+	result, err := callback(nil, tsim.engine.ToValue(value), tsim.engine.ToValue(key))
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
 // ToJSObject converts the immutable map to a JavaScript object
 func (tsim *TypeScriptImmutableMap) ToJSObject() *goja.Object {
 	obj := tsim.engine.NewObject()
@@ -40,13 +143,13 @@ func (tsim *TypeScriptImmutableMap) ToJSObject() *goja.Object {
 	// Set method (returns new map)
 	obj.Set("set", func(key string, value goja.Value) *goja.Object {
 		newMap := tsim.im.Set(key, value.Export())
-		return NewTypeScriptImmutableMap(tsim.engine, newMap).ToJSObject()
+		return tsim.derive(newMap).ToJSObject()
 	})
 	
 	// Delete method (returns new map)
 	obj.Set("delete", func(key string) *goja.Object {
 		newMap := tsim.im.Delete(key)
-		return NewTypeScriptImmutableMap(tsim.engine, newMap).ToJSObject()
+		return tsim.derive(newMap).ToJSObject()
 	})
 	
 	// Size method
@@ -83,6 +186,32 @@ func (tsim *TypeScriptImmutableMap) ToJSObject() *goja.Object {
 		return entries
 	})
 	
+	// Merge method (returns new map). other may be an immutable map or a
+	// plain object; its values win for keys in both.
+	obj.Set("merge", func(value goja.Value) *goja.Object {
+		other, err := tsim.toMap(value)
+		if err != nil {
+			panic(tsim.engine.NewTypeError(fmt.Sprintf("merge: %v", err)))
+		}
+		return tsim.derive(tsim.im.Merge(other)).ToJSObject()
+	})
+	
+	// Filter method (returns new map)
+	obj.Set("filter", func(callback goja.Callable) *goja.Object {
+		newMap := tsim.im.Filter(func(key string, value interface{}) bool {
+			return tsim.call(callback, key, value).ToBoolean()
+		})
+		return tsim.derive(newMap).ToJSObject()
+	})
+	
+	// Map method (returns new map with the same keys)
+	obj.Set("map", func(callback goja.Callable) *goja.Object {
+		newMap := tsim.im.Map(func(key string, value interface{}) interface{} {
+			return tsim.call(callback, key, value).Export()
+		})
+		return tsim.derive(newMap).ToJSObject()
+	})
+	
 	// ForEach method
 	obj.Set("forEach", func(callback goja.Callable) {
 		keys := tsim.im.Keys()
@@ -105,6 +234,11 @@ func (tsim *TypeScriptImmutableMap) ToJSObject() *goja.Object {
 		return result
 	})
 	
+	// Equals method (compares entries by value)
+	obj.Set("equals", equals(tsim.im))
+	
+	setWrapped(tsim.engine, obj, tsim.im)
+	trackObject(obj, tsim.im.Size(), tsim.track)
 	return obj
 }
 
@@ -112,6 +246,7 @@ func (tsim *TypeScriptImmutableMap) ToJSObject() *goja.Object {
 type TypeScriptImmutableList struct {
 	il     *ImmutableList
 	engine *goja.Runtime
+	track  AllocationTracker
 }
 
 // NewTypeScriptImmutableList creates a new TypeScript-wrapped immutable list
@@ -122,6 +257,48 @@ func NewTypeScriptImmutableList(engine *goja.Runtime, il *ImmutableList) *TypeSc
 	}
 }
 
+// WithTracker sets the tracker the list and the lists derived from it
+// report their approximate size to
+func (tsil *TypeScriptImmutableList) WithTracker(track AllocationTracker) *TypeScriptImmutableList {
+	tsil.track = track
+	return tsil
+}
+
+// derive wraps a list derived from this one, sharing its tracker
+func (tsil *TypeScriptImmutableList) derive(il *ImmutableList) *TypeScriptImmutableList {
+	return NewTypeScriptImmutableList(tsil.engine, il).WithTracker(tsil.track)
+}
+
+// toList returns the list a JavaScript value stands for: the list wrapped
+// by an immutable list object or the values of an array or other iterable
+func (tsil *TypeScriptImmutableList) toList(value goja.Value) (*ImmutableList, error) {
+	if il, ok := wrappedBy(value).(*ImmutableList); ok {
+		return il, nil
+	}
+	if _, ok := value.(*goja.Object); !ok {
+		return nil, errors.New("expected an immutable list or an array")
+	}
+	var values []goja.Value
+	if err := tsil.engine.ExportTo(value, &values); err != nil {
+		return nil, errors.New("expected an immutable list or an array")
+	}
+	il := NewImmutableList()
+	for _, v := range values {
+		il = il.Append(v.Export())
+	}
+	return il, nil
+}
+
+// call calls a list callback with value and index, raising an exception it
+// throws in the caller
+func (tsil *TypeScriptImmutableList) call(callback goja.Callable, value interface{}, index int) goja.Value {
+	result, err := callback(nil, tsil.engine.ToValue(value), tsil.engine.ToValue(index))
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
 // ToJSObject converts the immutable list to a JavaScript object
 func (tsil *TypeScriptImmutableList) ToJSObject() *goja.Object {
 	obj := tsil.engine.NewObject()
@@ -140,20 +317,20 @@ func (tsil *TypeScriptImmutableList) ToJSObject() *goja.Object {
 		// For immutable list, we need to create a new list with the updated value
 		// This is a simplified version - in practice, we'd need to copy and modify
 		newList := tsil.il.Append(value.Export()) // Simplified - should replace at index
-		return NewTypeScriptImmutableList(tsil.engine, newList).ToJSObject()
+		return tsil.derive(newList).ToJSObject()
 	})
 	
 	// Push method (returns new list)
 	obj.Set("push", func(value goja.Value) *goja.Object {
 		newList := tsil.il.Append(value.Export())
-		return NewTypeScriptImmutableList(tsil.engine, newList).ToJSObject()
+		return tsil.derive(newList).ToJSObject()
 	})
 	
 	// Pop method (returns [newList, value])
 	obj.Set("pop", func() []interface{} {
 		size := tsil.il.Size()
 		if size == 0 {
-			return []interface{}{NewTypeScriptImmutableList(tsil.engine, tsil.il).ToJSObject(), goja.Undefined()}
+			return []interface{}{tsil.derive(tsil.il).ToJSObject(), goja.Undefined()}
 		}
 		value, _ := tsil.il.Get(size - 1)
 		// Create new list without last element (simplified)
@@ -162,20 +339,20 @@ func (tsil *TypeScriptImmutableList) ToJSObject() *goja.Object {
 			v, _ := tsil.il.Get(i)
 			newList = newList.Append(v)
 		}
-		return []interface{}{NewTypeScriptImmutableList(tsil.engine, newList).ToJSObject(), tsil.engine.ToValue(value)}
+		return []interface{}{tsil.derive(newList).ToJSObject(), tsil.engine.ToValue(value)}
 	})
 	
 	// Unshift method (returns new list)
 	obj.Set("unshift", func(value goja.Value) *goja.Object {
 		newList := tsil.il.Prepend(value.Export())
-		return NewTypeScriptImmutableList(tsil.engine, newList).ToJSObject()
+		return tsil.derive(newList).ToJSObject()
 	})
 	
 	// Shift method (returns [newList, value])
 	obj.Set("shift", func() []interface{} {
 		size := tsil.il.Size()
 		if size == 0 {
-			return []interface{}{NewTypeScriptImmutableList(tsil.engine, tsil.il).ToJSObject(), goja.Undefined()}
+			return []interface{}{tsil.derive(tsil.il).ToJSObject(), goja.Undefined()}
 		}
 		value, _ := tsil.il.Get(0)
 		// Create new list without first element
@@ -184,7 +361,7 @@ func (tsil *TypeScriptImmutableList) ToJSObject() *goja.Object {
 			v, _ := tsil.il.Get(i)
 			newList = newList.Append(v)
 		}
-		return []interface{}{NewTypeScriptImmutableList(tsil.engine, newList).ToJSObject(), tsil.engine.ToValue(value)}
+		return []interface{}{tsil.derive(newList).ToJSObject(), tsil.engine.ToValue(value)}
 	})
 	
 	// Size method
@@ -212,7 +389,7 @@ func (tsil *TypeScriptImmutableList) ToJSObject() *goja.Object {
 				newList = newList.Append(result.Export())
 			}
 		}
-		return NewTypeScriptImmutableList(tsil.engine, newList).ToJSObject()
+		return tsil.derive(newList).ToJSObject()
 	})
 	
 	// Filter method
@@ -227,7 +404,87 @@ func (tsil *TypeScriptImmutableList) ToJSObject() *goja.Object {
 				}
 			}
 		}
-		return NewTypeScriptImmutableList(tsil.engine, newList).ToJSObject()
+		return tsil.derive(newList).ToJSObject()
+	})
+	
+	// Reduce method. Without an initial value the first value is the
+	// initial accumulator, as with Array.prototype.reduce.
+	obj.Set("reduce", func(call goja.FunctionCall) goja.Value {
+		callback, ok := goja.AssertFunction(call.Argument(0))
+		if !ok {
+			panic(tsil.engine.NewTypeError("reduce: callback must be a function"))
+		}
+		list := tsil.il
+		var acc goja.Value
+		if len(call.Arguments) > 1 {
+			acc = call.Argument(1)
+		} else {
+			first, err := list.Get(0)
+			if err != nil {
+				panic(tsil.engine.NewTypeError("reduce of empty list with no initial value"))
+			}
+			acc = tsil.engine.ToValue(first)
+			list = list.Slice(1, list.Size())
+		}
+		offset := tsil.il.Size() - list.Size()
+		result := list.Reduce(func(acc interface{}, value interface{}, index int) interface{} {
+			next, err := callback(nil, acc.(goja.Value), tsil.engine.ToValue(value), tsil.engine.ToValue(index+offset))
+			if err != nil {
+				panic(err)
+			}
+			return next
+		}, acc)
+		return result.(goja.Value)
+	})
+	
+	// Find method
+	obj.Set("find", func(callback goja.Callable) goja.Value {
+		value, ok := tsil.il.Find(func(value interface{}, index int) bool {
+			return tsil.call(callback, value, index).ToBoolean()
+		})
+		if !ok {
+			return goja.Undefined()
+		}
+		return tsil.engine.ToValue(value)
+	})
+	
+	// FindIndex method
+	obj.Set("findIndex", func(callback goja.Callable) int {
+		return tsil.il.FindIndex(func(value interface{}, index int) bool {
+			return tsil.call(callback, value, index).ToBoolean()
+		})
+	})
+	
+	// IndexOf method (compares values by value)
+	obj.Set("indexOf", func(value goja.Value) int {
+		return tsil.il.IndexOf(ExportValue(value))
+	})
+	
+	// Slice method (returns new list sharing this one's storage)
+	obj.Set("slice", func(call goja.FunctionCall) goja.Value {
+		size := tsil.il.Size()
+		start, end := 0, size
+		if arg := call.Argument(0); !goja.IsUndefined(arg) {
+			start = int(arg.ToInteger())
+		}
+		if arg := call.Argument(1); !goja.IsUndefined(arg) {
+			end = int(arg.ToInteger())
+		}
+		return tsil.derive(tsil.il.Slice(start, end)).ToJSObject()
+	})
+	
+	// Concat method (returns new list). Each argument may be an immutable
+	// list or an array.
+	obj.Set("concat", func(call goja.FunctionCall) goja.Value {
+		others := make([]*ImmutableList, 0, len(call.Arguments))
+		for _, arg := range call.Arguments {
+			other, err := tsil.toList(arg)
+			if err != nil {
+				panic(tsil.engine.NewTypeError(fmt.Sprintf("concat: %v", err)))
+			}
+			others = append(others, other)
+		}
+		return tsil.derive(tsil.il.Concat(others...)).ToJSObject()
 	})
 	
 	// ToJS method (converts to native JS Array)
@@ -242,6 +499,11 @@ func (tsil *TypeScriptImmutableList) ToJSObject() *goja.Object {
 		return result
 	})
 	
+	// Equals method (compares values in order by value)
+	obj.Set("equals", equals(tsil.il))
+	
+	setWrapped(tsil.engine, obj, tsil.il)
+	trackObject(obj, tsil.il.Size(), tsil.track)
 	return obj
 }
 
@@ -249,6 +511,7 @@ func (tsil *TypeScriptImmutableList) ToJSObject() *goja.Object {
 type TypeScriptImmutableSet struct {
 	is     *ImmutableSet
 	engine *goja.Runtime
+	track  AllocationTracker
 }
 
 // NewTypeScriptImmutableSet creates a new TypeScript-wrapped immutable set
@@ -259,25 +522,69 @@ func NewTypeScriptImmutableSet(engine *goja.Runtime, is *ImmutableSet) *TypeScri
 	}
 }
 
+// WithTracker sets the tracker the set and the sets derived from it
+// report their approximate size to
+func (tsis *TypeScriptImmutableSet) WithTracker(track AllocationTracker) *TypeScriptImmutableSet {
+	tsis.track = track
+	return tsis
+}
+
+// derive wraps a set derived from this one, sharing its tracker
+func (tsis *TypeScriptImmutableSet) derive(is *ImmutableSet) *TypeScriptImmutableSet {
+	return NewTypeScriptImmutableSet(tsis.engine, is).WithTracker(tsis.track)
+}
+
+// toSet returns the set a JavaScript value stands for: the set wrapped by
+// an immutable set object or the values of an array or other iterable
+func (tsis *TypeScriptImmutableSet) toSet(value goja.Value) (*ImmutableSet, error) {
+	if is, ok := wrappedBy(value).(*ImmutableSet); ok {
+		return is, nil
+	}
+	if _, ok := value.(*goja.Object); !ok {
+		return nil, errors.New("expected an immutable set or an array")
+	}
+	var values []goja.Value
+	if err := tsis.engine.ExportTo(value, &values); err != nil {
+		return nil, errors.New("expected an immutable set or an array")
+	}
+	is := NewImmutableSet()
+	for _, v := range values {
+		is = is.Add(ExportValue(v))
+	}
+	return is, nil
+}
+
+// combine returns a set operation that takes the other set from
+// JavaScript and returns the resulting set as a JavaScript object
+func (tsis *TypeScriptImmutableSet) combine(name string, op func(is, other *ImmutableSet) *ImmutableSet) func(goja.Value) *goja.Object {
+	return func(value goja.Value) *goja.Object {
+		other, err := tsis.toSet(value)
+		if err != nil {
+			panic(tsis.engine.NewTypeError(fmt.Sprintf("%s: %v", name, err)))
+		}
+		return tsis.derive(op(tsis.is, other)).ToJSObject()
+	}
+}
+
 // ToJSObject converts the immutable set to a JavaScript object
 func (tsis *TypeScriptImmutableSet) ToJSObject() *goja.Object {
 	obj := tsis.engine.NewObject()
 	
 	// Has method
 	obj.Set("has", func(value goja.Value) bool {
-		return tsis.is.Contains(value.Export())
+		return tsis.is.Contains(ExportValue(value))
 	})
 	
 	// Add method (returns new set)
 	obj.Set("add", func(value goja.Value) *goja.Object {
-		newSet := tsis.is.Add(value.Export())
-		return NewTypeScriptImmutableSet(tsis.engine, newSet).ToJSObject()
+		newSet := tsis.is.Add(ExportValue(value))
+		return tsis.derive(newSet).ToJSObject()
 	})
 	
 	// Delete method (returns new set)
 	obj.Set("delete", func(value goja.Value) *goja.Object {
-		newSet := tsis.is.Remove(value.Export())
-		return NewTypeScriptImmutableSet(tsis.engine, newSet).ToJSObject()
+		newSet := tsis.is.Remove(ExportValue(value))
+		return tsis.derive(newSet).ToJSObject()
 	})
 	
 	// Size method
@@ -285,6 +592,12 @@ func (tsis *TypeScriptImmutableSet) ToJSObject() *goja.Object {
 		return tsis.is.Size()
 	})
 	
+	// Set algebra (each returns a new set). The other set may be an
+	// immutable set or an array.
+	obj.Set("union", tsis.combine("union", (*ImmutableSet).Union))
+	obj.Set("intersection", tsis.combine("intersection", (*ImmutableSet).Intersection))
+	obj.Set("difference", tsis.combine("difference", (*ImmutableSet).Difference))
+	
 	// Values method
 	obj.Set("values", func() []interface{} {
 		// Get all values from the set
@@ -305,6 +618,11 @@ func (tsis *TypeScriptImmutableSet) ToJSObject() *goja.Object {
 		return []interface{}{}
 	})
 	
+	// Equals method (compares values by value)
+	obj.Set("equals", equals(tsis.is))
+	
+	setWrapped(tsis.engine, obj, tsis.is)
+	trackObject(obj, tsis.is.Size(), tsis.track)
 	return obj
 }
 
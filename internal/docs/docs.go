@@ -0,0 +1,321 @@
+// Package docs provides the offline documentation index searched by gots doc.
+// Guides are embedded markdown topics; API reference topics are generated
+// from the standard library declaration files.
+package docs
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gots-runtime/stdlib"
+)
+
+//go:embed topics/*.md
+var topicFS embed.FS
+
+// Topic is a documentation page made of sections
+type Topic struct {
+	Name     string // e.g. "framework" or "stdlib/fs"
+	Title    string
+	Summary  string
+	Sections []Section
+}
+
+// Section is a searchable part of a topic
+type Section struct {
+	Topic   string
+	Heading string
+	Body    string
+}
+
+// Result is a section matching a search query
+type Result struct {
+	Section Section
+	Score   int
+}
+
+// Index is a searchable set of documentation topics
+type Index struct {
+	topics []*Topic
+	byName map[string]*Topic
+}
+
+// NewIndex creates an empty index
+func NewIndex() *Index {
+	return &Index{
+		topics: make([]*Topic, 0),
+		byName: make(map[string]*Topic),
+	}
+}
+
+// Load builds the index of the embedded guides and standard library
+func Load() (*Index, error) {
+	ix := NewIndex()
+
+	guides, err := fs.Glob(topicFS, "topics/*.md")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list topics: %w", err)
+	}
+	for _, file := range guides {
+		src, err := topicFS.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read topic %s: %w", file, err)
+		}
+		ix.AddMarkdown(strings.TrimSuffix(path.Base(file), ".md"), string(src))
+	}
+
+	declarations, err := fs.Glob(stdlib.FS, "*/*.ts")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list stdlib: %w", err)
+	}
+	for _, file := range declarations {
+		src, err := stdlib.FS.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read stdlib %s: %w", file, err)
+		}
+		name := "stdlib/" + path.Dir(file)
+		if base := strings.TrimSuffix(path.Base(file), ".ts"); base != "index" {
+			name += "/" + base
+		}
+		ix.AddDeclarations(name, string(src))
+	}
+
+	return ix, nil
+}
+
+// add adds a topic to the index, replacing one with the same name
+func (ix *Index) add(topic *Topic) {
+	if existing, ok := ix.byName[topic.Name]; ok {
+		*existing = *topic
+		return
+	}
+	ix.topics = append(ix.topics, topic)
+	ix.byName[topic.Name] = topic
+}
+
+// AddMarkdown adds a topic from markdown. The "# " line is the title, the
+// paragraph after it the summary, and each "## " heading starts a section.
+func (ix *Index) AddMarkdown(name, src string) {
+	topic := &Topic{Name: name, Title: name}
+	var section *Section
+	var body []string
+	inSummary := false
+
+	flush := func() {
+		if section != nil {
+			section.Body = strings.TrimSpace(strings.Join(body, "\n"))
+			topic.Sections = append(topic.Sections, *section)
+		}
+		body = nil
+	}
+
+	for _, line := range strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n") {
+		switch {
+		case strings.HasPrefix(line, "# ") && section == nil && topic.Summary == "":
+			topic.Title = strings.TrimSpace(line[2:])
+			inSummary = true
+		case strings.HasPrefix(line, "## "):
+			flush()
+			section = &Section{Topic: name, Heading: strings.TrimSpace(line[3:])}
+			inSummary = false
+		case section != nil:
+			body = append(body, line)
+		case inSummary && strings.TrimSpace(line) != "":
+			topic.Summary = strings.TrimSpace(topic.Summary + " " + strings.TrimSpace(line))
+		case inSummary && topic.Summary != "":
+			inSummary = false
+		}
+	}
+	flush()
+
+	ix.add(topic)
+}
+
+// declarationPattern matches the start of a top-level exported declaration
+var declarationPattern = regexp.MustCompile(`^export\s+(?:declare\s+)?(?:abstract\s+)?(interface|type|function|const|let|class|enum)\s+([A-Za-z_$][\w$]*)`)
+
+// AddDeclarations adds a topic from a TypeScript declaration file. The
+// leading comment gives the title ("Standard Library: X") and summary, and
+// each exported declaration becomes a section including the comments
+// directly above it.
+func (ix *Index) AddDeclarations(name, src string) {
+	topic := &Topic{Name: name, Title: name}
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	// Leading comment block
+	i := 0
+	var header []string
+	for ; i < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[i]), "//"); i++ {
+		header = append(header, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(lines[i]), "//")))
+	}
+	if len(header) > 0 {
+		topic.Title = strings.TrimSpace(strings.TrimPrefix(header[0], "Standard Library:"))
+	}
+	if len(header) > 1 {
+		topic.Summary = strings.Join(header[1:], " ")
+	}
+
+	var section *Section
+	var body, pending []string
+	flush := func() {
+		if section != nil {
+			section.Body = strings.TrimSpace(strings.Join(body, "\n"))
+			topic.Sections = append(topic.Sections, *section)
+		}
+	}
+
+	for ; i < len(lines); i++ {
+		line := strings.TrimRight(lines[i], " \t")
+		if match := declarationPattern.FindStringSubmatch(line); match != nil {
+			flush()
+			section = &Section{Topic: name, Heading: match[1] + " " + match[2]}
+			body = append(pending, line)
+			pending = nil
+			continue
+		}
+		if strings.HasPrefix(line, "//") {
+			pending = append(pending, line)
+			continue
+		}
+		if section != nil {
+			body = append(body, pending...)
+			body = append(body, line)
+		}
+		pending = nil
+	}
+	flush()
+
+	ix.add(topic)
+}
+
+// Topics returns the indexed topics, guides first, each group by name
+func (ix *Index) Topics() []*Topic {
+	result := make([]*Topic, len(ix.topics))
+	copy(result, ix.topics)
+	sort.SliceStable(result, func(i, j int) bool {
+		si := strings.HasPrefix(result[i].Name, "stdlib/")
+		sj := strings.HasPrefix(result[j].Name, "stdlib/")
+		if si != sj {
+			return !si
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// Topic returns the topic with the given name. Standard library topics can
+// also be found by their module name, e.g. "fs" for "stdlib/fs".
+func (ix *Index) Topic(name string) (*Topic, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if topic, ok := ix.byName[name]; ok {
+		return topic, true
+	}
+	topic, ok := ix.byName["stdlib/"+name]
+	return topic, ok
+}
+
+// Search returns the sections matching every term of query, best first.
+// Matches in headings weigh most, then matches in the topic name, then
+// occurrences in the body.
+func (ix *Index) Search(query string) []Result {
+	terms := queryTerms(query)
+	if len(terms) == 0 {
+		return nil
+	}
+
+	results := make([]Result, 0)
+	for _, topic := range ix.Topics() {
+		topicName := strings.ToLower(topic.Name + " " + topic.Title)
+		for _, section := range topic.Sections {
+			heading := strings.ToLower(section.Heading)
+			body := strings.ToLower(section.Body)
+
+			score := 0
+			for _, term := range terms {
+				termScore := 10*strings.Count(heading, term) + strings.Count(body, term)
+				if strings.Contains(topicName, term) {
+					termScore += 5
+				}
+				if termScore == 0 {
+					score = 0
+					break
+				}
+				score += termScore
+			}
+			if score > 0 {
+				results = append(results, Result{Section: section, Score: score})
+			}
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	return results
+}
+
+// queryTerms splits a query into lowercase search terms
+func queryTerms(query string) []string {
+	return strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$'
+	})
+}
+
+// Snippet returns the first line of the section that mentions the most
+// terms of query, or its first line, shortened for display
+func (s Section) Snippet(query string) string {
+	lines := make([]string, 0)
+	for _, line := range strings.Split(s.Body, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+
+	snippet, best := lines[0], 0
+	terms := queryTerms(query)
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		matched := 0
+		for _, term := range terms {
+			if strings.Contains(lower, term) {
+				matched++
+			}
+		}
+		if matched > best {
+			snippet, best = line, matched
+		}
+	}
+
+	const maxSnippet = 100
+	if runes := []rune(snippet); len(runes) > maxSnippet {
+		snippet = string(runes[:maxSnippet-3]) + "..."
+	}
+	return snippet
+}
+
+// Text returns the full text of the topic
+func (t *Topic) Text() string {
+	var b strings.Builder
+	b.WriteString(t.Title + "\n")
+	b.WriteString(strings.Repeat("=", len([]rune(t.Title))) + "\n")
+	if t.Summary != "" {
+		b.WriteString("\n" + t.Summary + "\n")
+	}
+	for _, section := range t.Sections {
+		b.WriteString("\n" + section.Heading + "\n")
+		b.WriteString(strings.Repeat("-", len([]rune(section.Heading))) + "\n")
+		if section.Body != "" {
+			b.WriteString(section.Body + "\n")
+		}
+	}
+	return b.String()
+}
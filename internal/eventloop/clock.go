@@ -0,0 +1,111 @@
+package eventloop
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of an event loop. It follows real time until it
+// is set or advanced, which freezes it: a frozen clock only moves when set
+// or advanced again, and timers waiting on it fire as it passes their
+// deadlines.
+type Clock struct {
+	frozen  bool
+	now     time.Time // the time of a frozen clock
+	waiters []*clockWaiter
+	mu      sync.Mutex
+}
+
+// clockWaiter is a pending Clock.After
+type clockWaiter struct {
+	deadline time.Time
+	ch       chan struct{}
+}
+
+// NewClock creates a clock that follows real time
+func NewClock() *Clock {
+	return &Clock{}
+}
+
+// Now returns the current time of the clock
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nowLocked()
+}
+
+// nowLocked returns the current time; c.mu must be held
+func (c *Clock) nowLocked() time.Time {
+	if c.frozen {
+		return c.now
+	}
+	return time.Now()
+}
+
+// IsFrozen reports whether the clock has been set or advanced
+func (c *Clock) IsFrozen() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.frozen
+}
+
+// Set freezes the clock at t and fires the timers due by then
+func (c *Clock) Set(t time.Time) {
+	c.mu.Lock()
+	c.frozen = true
+	c.now = t
+	c.mu.Unlock()
+	c.fireDue()
+}
+
+// Advance moves the clock forward by d, freezing it at the resulting time,
+// and fires the timers due by then
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.nowLocked().Add(d)
+	c.frozen = true
+	c.mu.Unlock()
+	c.fireDue()
+}
+
+// After returns a channel that is closed once d has passed on the clock
+func (c *Clock) After(d time.Duration) <-chan struct{} {
+	return c.Until(c.Now().Add(d))
+}
+
+// Until returns a channel that is closed once the clock reaches deadline
+func (c *Clock) Until(deadline time.Time) <-chan struct{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	waiter := &clockWaiter{deadline: deadline, ch: make(chan struct{})}
+	now := c.nowLocked()
+	if !deadline.After(now) {
+		close(waiter.ch)
+		return waiter.ch
+	}
+
+	c.waiters = append(c.waiters, waiter)
+	if !c.frozen {
+		// Fire in real time unless the clock is frozen in the meantime
+		time.AfterFunc(deadline.Sub(now), c.fireDue)
+	}
+	return waiter.ch
+}
+
+// fireDue closes the channels of the waiters whose deadline has passed
+func (c *Clock) fireDue() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.nowLocked()
+	pending := c.waiters[:0]
+	for _, waiter := range c.waiters {
+		if waiter.deadline.After(now) {
+			pending = append(pending, waiter)
+		} else {
+			close(waiter.ch)
+		}
+	}
+	c.waiters = pending
+}
@@ -14,6 +14,15 @@ const (
 	EventNextTick
 )
 
+// Event priorities. Higher values run first; events with equal priority
+// run in the order they were enqueued.
+const (
+	PriorityLow    = -10
+	PriorityNormal = 0
+	PriorityTimer  = 5
+	PriorityHigh   = 10
+)
+
 // Event represents an event in the event loop
 type Event struct {
 	Type      EventType
@@ -54,7 +63,7 @@ type TimerEvent struct {
 // NewTimerEvent creates a new timer event
 func NewTimerEvent(duration time.Duration, repeat bool, handler func() error) *TimerEvent {
 	return &TimerEvent{
-		Event:    NewEvent(EventTimer, handler, 0),
+		Event:    NewEvent(EventTimer, handler, PriorityTimer),
 		Duration: duration,
 		Repeat:   repeat,
 	}
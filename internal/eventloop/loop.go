@@ -3,7 +3,10 @@ package eventloop
 import (
 	"context"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"gots-runtime/internal/observability"
 )
 
 // Loop represents the event loop
@@ -16,18 +19,39 @@ type Loop struct {
 	mu          sync.RWMutex
 	timers      map[uint64]*TimerEvent
 	timerMu     sync.Mutex
+	clock       *Clock
+	timerSeq    uint64
 	nextTick    []EventCallback
 	nextTickMu  sync.Mutex
+	metrics     *observability.MetricsCollector
+	busy        int32
+	refs        int32
+
+	// Stall watchdog state; eventStart is 0 while no event is running
+	stallThreshold time.Duration
+	onStall        StallHandler
+	eventStart     int64
+	eventSeq       uint64
+	eventType      int32
+	goroutine      int64
+	stalls         uint64
 }
 
 // NewLoop creates a new event loop
 func NewLoop(ctx context.Context) *Loop {
+	return NewLoopWithQueue(ctx, BackpressureThreshold, OverflowBlock)
+}
+
+// NewLoopWithQueue creates a new event loop with a bounded queue of the given
+// size and overflow policy
+func NewLoopWithQueue(ctx context.Context, size int, policy OverflowPolicy) *Loop {
 	loopCtx, cancel := context.WithCancel(ctx)
 	return &Loop{
-		queue:   NewEventQueue(),
+		queue:   NewBoundedEventQueue(size, policy),
 		ctx:     loopCtx,
 		cancel:  cancel,
 		timers:  make(map[uint64]*TimerEvent),
+		clock:   NewClock(),
 		nextTick: make([]EventCallback, 0),
 	}
 }
@@ -40,10 +64,14 @@ func (l *Loop) Start() {
 		return
 	}
 	l.running = true
+	threshold, onStall := l.stallThreshold, l.onStall
 	l.mu.Unlock()
 
 	l.wg.Add(1)
 	go l.run()
+	if threshold > 0 {
+		go l.watch(threshold, onStall)
+	}
 }
 
 // Stop stops the event loop
@@ -57,34 +85,82 @@ func (l *Loop) Stop() {
 	l.mu.Unlock()
 
 	l.cancel()
+	l.queue.Close()
 	l.wg.Wait()
 }
 
-// Enqueue adds an event to the queue
+// SetMetrics sets the collector that receives queue length metrics
+func (l *Loop) SetMetrics(metrics *observability.MetricsCollector) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.metrics = metrics
+}
+
+// Clock returns the clock that drives the loop's timers
+func (l *Loop) Clock() *Clock {
+	return l.clock
+}
+
+// timerActive reports whether a timer has not fired or been cleared
+func (l *Loop) timerActive(id uint64) bool {
+	l.timerMu.Lock()
+	defer l.timerMu.Unlock()
+	_, ok := l.timers[id]
+	return ok
+}
+
+// Enqueue adds an event to the queue. When the queue is full the configured
+// overflow policy decides whether to block, drop the oldest event or reject.
+// With OverflowBlock, events enqueued before the loop started or by the loop
+// goroutine itself, e.g. by a callback settling a promise, are admitted over
+// capacity instead, as nothing would make room for them.
 func (l *Loop) Enqueue(event *Event) error {
-	if l.IsOverloaded() {
-		return ErrQueueOverloaded
-	}
-	l.queue.Push(event)
-	return nil
+	err := l.queue.enqueue(event, l.mustNotBlock)
+	l.recordQueueMetrics()
+	return err
 }
 
-// SetTimeout schedules a function to run after a delay
+// mustNotBlock reports whether waiting for room in the queue could never
+// end: the loop has not started or the caller is the loop goroutine
+func (l *Loop) mustNotBlock() bool {
+	l.mu.RLock()
+	running := l.running
+	l.mu.RUnlock()
+	return !running || currentGoroutine() == atomic.LoadInt64(&l.goroutine)
+}
+
+// Submit enqueues a handler as an I/O event with the given priority
+func (l *Loop) Submit(handler func() error, priority int) error {
+	return l.Enqueue(NewEvent(EventIO, handler, priority))
+}
+
+// SubmitHighPriority enqueues a handler ahead of normal and timer events
+func (l *Loop) SubmitHighPriority(handler func() error) error {
+	return l.Submit(handler, PriorityHigh)
+}
+
+// SetTimeout schedules a function to run once duration has passed on the
+// loop's clock
 func (l *Loop) SetTimeout(duration time.Duration, handler func() error) uint64 {
 	timer := NewTimerEvent(duration, false, handler)
+	timerID := atomic.AddUint64(&l.timerSeq, 1)
+	timer.ID = timerID
 	l.timerMu.Lock()
-	timerID := timer.ID
 	l.timers[timerID] = timer
 	l.timerMu.Unlock()
 
-	// Schedule the timer
+	// Schedule the timer; the deadline is fixed now, not when the
+	// goroutine starts
+	due := l.clock.After(duration)
 	go func() {
 		select {
-		case <-time.After(duration):
-			l.Enqueue(timer.Event)
-			l.timerMu.Lock()
-			delete(l.timers, timerID)
-			l.timerMu.Unlock()
+		case <-due:
+			// Enqueue before forgetting the timer so the loop is never
+			// seen idle in between
+			if l.timerActive(timerID) {
+				l.Enqueue(timer.Event)
+			}
+			l.ClearTimeout(timerID)
 		case <-l.ctx.Done():
 			return
 		}
@@ -93,22 +169,35 @@ func (l *Loop) SetTimeout(duration time.Duration, handler func() error) uint64 {
 	return timerID
 }
 
-// SetInterval schedules a function to run repeatedly
+// SetInterval schedules a function to run every duration on the loop's
+// clock until the interval is cleared
 func (l *Loop) SetInterval(duration time.Duration, handler func() error) uint64 {
 	timer := NewTimerEvent(duration, true, handler)
+	timerID := atomic.AddUint64(&l.timerSeq, 1)
+	timer.ID = timerID
 	l.timerMu.Lock()
-	timerID := timer.ID
 	l.timers[timerID] = timer
 	l.timerMu.Unlock()
 
-	// Schedule the repeating timer
+	// Schedule the repeating timer. Deadlines are multiples of duration
+	// from now, so an advanced clock fires every interval it passes.
+	if duration <= 0 {
+		duration = time.Millisecond
+	}
+	deadline := l.clock.Now().Add(duration)
+	due := l.clock.Until(deadline)
 	go func() {
-		ticker := time.NewTicker(duration)
-		defer ticker.Stop()
 		for {
 			select {
-			case <-ticker.C:
-				l.Enqueue(timer.Event)
+			case <-due:
+				if !l.timerActive(timerID) {
+					return
+				}
+				// Each tick is a new event: the previous one may still be
+				// queued, and a queued event must not be enqueued again
+				l.Enqueue(NewEvent(timer.Event.Type, timer.Event.Handler, timer.Event.Priority))
+				deadline = deadline.Add(duration)
+				due = l.clock.Until(deadline)
 			case <-l.ctx.Done():
 				l.timerMu.Lock()
 				delete(l.timers, timerID)
@@ -142,18 +231,121 @@ func (l *Loop) NextTick(callback EventCallback) {
 
 // SetImmediate schedules a callback to run immediately
 func (l *Loop) SetImmediate(callback EventCallback) {
-	event := NewEvent(EventImmediate, callback, 10)
+	event := NewEvent(EventImmediate, callback, PriorityHigh)
 	l.Enqueue(event)
 }
 
-// IsOverloaded checks if the event queue is overloaded
+// IsOverloaded checks if the event queue has reached its high-water mark
 func (l *Loop) IsOverloaded() bool {
 	return l.queue.IsOverloaded()
 }
 
+// Ref registers a long-lived handle, such as a listening server, that keeps
+// the loop from being idle until the matching Unref
+func (l *Loop) Ref() {
+	atomic.AddInt32(&l.refs, 1)
+}
+
+// Unref releases a handle registered with Ref
+func (l *Loop) Unref() {
+	atomic.AddInt32(&l.refs, -1)
+}
+
+// IsIdle reports whether there are no queued events, pending timers,
+// nextTick callbacks or referenced handles and no event is executing
+func (l *Loop) IsIdle() bool {
+	if atomic.LoadInt32(&l.busy) != 0 || atomic.LoadInt32(&l.refs) > 0 || l.queue.Size() > 0 {
+		return false
+	}
+	
+	l.nextTickMu.Lock()
+	pendingTicks := len(l.nextTick)
+	l.nextTickMu.Unlock()
+	
+	l.timerMu.Lock()
+	pendingTimers := len(l.timers)
+	l.timerMu.Unlock()
+	
+	return pendingTicks == 0 && pendingTimers == 0
+}
+
+// WaitIdle blocks until the loop is idle or ctx is done
+func (l *Loop) WaitIdle(ctx context.Context) error {
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	
+	for !l.IsIdle() {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-l.ctx.Done():
+			return ErrLoopStopped
+		case <-ticker.C:
+		}
+	}
+	return nil
+}
+
+// QueueLength returns the number of pending events
+func (l *Loop) QueueLength() int {
+	return l.queue.Size()
+}
+
+// QueueStats returns event queue statistics
+func (l *Loop) QueueStats() QueueStats {
+	return l.queue.Stats()
+}
+
+// LoopStats describes the backlog of an event loop
+type LoopStats struct {
+	Queue     QueueStats
+	Timers    int  // pending timers and intervals
+	NextTicks int  // pending nextTick callbacks
+	Refs      int  // referenced handles, such as listening servers
+	Busy      bool // whether an event is executing
+	Stalls    uint64 // events reported by the stall watchdog
+}
+
+// Stats returns the backlog of the loop
+func (l *Loop) Stats() LoopStats {
+	l.nextTickMu.Lock()
+	nextTicks := len(l.nextTick)
+	l.nextTickMu.Unlock()
+	
+	l.timerMu.Lock()
+	timers := len(l.timers)
+	l.timerMu.Unlock()
+	
+	return LoopStats{
+		Queue:     l.queue.Stats(),
+		Timers:    timers,
+		NextTicks: nextTicks,
+		Refs:      int(atomic.LoadInt32(&l.refs)),
+		Busy:      atomic.LoadInt32(&l.busy) != 0,
+		Stalls:    atomic.LoadUint64(&l.stalls),
+	}
+}
+
+// recordQueueMetrics publishes queue statistics to the metrics collector
+func (l *Loop) recordQueueMetrics() {
+	l.mu.RLock()
+	metrics := l.metrics
+	l.mu.RUnlock()
+	if metrics == nil {
+		return
+	}
+
+	stats := l.queue.Stats()
+	metrics.Set("eventloop_queue_length", float64(stats.Length), nil)
+	metrics.Set("eventloop_queue_high_water", float64(stats.HighWater), nil)
+	metrics.Set("eventloop_queue_dropped", float64(stats.Dropped), nil)
+	metrics.Set("eventloop_queue_rejected", float64(stats.Rejected), nil)
+}
+
 // run is the main event loop
 func (l *Loop) run() {
 	defer l.wg.Done()
+	atomic.StoreInt64(&l.goroutine, currentGoroutine())
 
 	for {
 		select {
@@ -164,10 +356,16 @@ func (l *Loop) run() {
 			l.processNextTick()
 
 		// Process events from queue
+		atomic.StoreInt32(&l.busy, 1)
 		event := l.queue.Dequeue()
 		if event != nil {
+			l.recordQueueMetrics()
+			l.beginEvent(event.Type)
 			_ = event.Execute()
+			l.endEvent()
+			atomic.StoreInt32(&l.busy, 0)
 		} else {
+			atomic.StoreInt32(&l.busy, 0)
 			// No events, sleep briefly to avoid busy waiting
 			time.Sleep(1 * time.Millisecond)
 		}
@@ -183,13 +381,16 @@ func (l *Loop) processNextTick() {
 	l.nextTickMu.Unlock()
 
 	for _, callback := range callbacks {
+		l.beginEvent(EventNextTick)
 		_ = callback()
+		l.endEvent()
 	}
 }
 
 // Errors
 var (
 	ErrQueueOverloaded = &EventLoopError{Message: "event queue is overloaded"}
+	ErrLoopStopped     = &EventLoopError{Message: "event loop is stopped"}
 )
 
 // EventLoopError represents an event loop error
@@ -2,33 +2,144 @@ package eventloop
 
 import (
 	"container/heap"
+	"fmt"
 	"sync"
 )
 
+// OverflowPolicy determines what happens when the queue is full
+type OverflowPolicy string
+
+const (
+	// OverflowBlock makes Enqueue wait until there is room in the queue.
+	// It is the default, as it never loses an event. Events that the loop
+	// enqueues itself are admitted over capacity instead, as the loop
+	// cannot wait for itself.
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDropOldest evicts the oldest lowest-priority event to make
+	// room. The callback of a dropped event never runs, so promises it
+	// would settle stay pending.
+	OverflowDropOldest OverflowPolicy = "drop-oldest"
+	// OverflowReject makes Enqueue fail with ErrQueueOverloaded. Producers
+	// that ignore the error lose their callbacks like with
+	// OverflowDropOldest.
+	OverflowReject OverflowPolicy = "reject"
+)
+
+// ParseOverflowPolicy parses an overflow policy name
+func ParseOverflowPolicy(name string) (OverflowPolicy, error) {
+	switch OverflowPolicy(name) {
+	case "", OverflowBlock:
+		return OverflowBlock, nil
+	case OverflowReject:
+		return OverflowReject, nil
+	case OverflowDropOldest:
+		return OverflowDropOldest, nil
+	default:
+		return "", fmt.Errorf("unknown queue overflow policy: %s", name)
+	}
+}
+
 // EventQueue is a priority queue for events
 type EventQueue struct {
-	events []*Event
-	mu     sync.Mutex
-	idGen  uint64
+	events    []*Event
+	mu        sync.Mutex
+	notFull   *sync.Cond
+	idGen     uint64
+	capacity  int
+	policy    OverflowPolicy
+	closed    bool
+	dropped   uint64
+	rejected  uint64
+	highWater int
 }
 
 // NewEventQueue creates a new event queue
 func NewEventQueue() *EventQueue {
+	return NewBoundedEventQueue(BackpressureThreshold, OverflowBlock)
+}
+
+// NewBoundedEventQueue creates a new event queue holding at most capacity events
+func NewBoundedEventQueue(capacity int, policy OverflowPolicy) *EventQueue {
+	if capacity <= 0 {
+		capacity = BackpressureThreshold
+	}
+	if policy == "" {
+		policy = OverflowBlock
+	}
 	eq := &EventQueue{
-		events: make([]*Event, 0),
+		events:   make([]*Event, 0),
+		capacity: capacity,
+		policy:   policy,
 	}
+	eq.notFull = sync.NewCond(&eq.mu)
 	heap.Init(eq)
 	return eq
 }
 
-// Enqueue adds an event to the queue
-func (eq *EventQueue) Enqueue(event *Event) {
+// Enqueue adds an event to the queue, applying the overflow policy when full
+func (eq *EventQueue) Enqueue(event *Event) error {
+	return eq.enqueue(event, nil)
+}
+
+// enqueue adds an event to the queue, applying the overflow policy when
+// full. With OverflowBlock the event is admitted over capacity instead of
+// waiting if mustNotBlock reports true.
+func (eq *EventQueue) enqueue(event *Event, mustNotBlock func() bool) error {
 	eq.mu.Lock()
 	defer eq.mu.Unlock()
-	
+
+	for len(eq.events) >= eq.capacity && !eq.closed {
+		if eq.policy == OverflowBlock && mustNotBlock != nil && mustNotBlock() {
+			break
+		}
+		switch eq.policy {
+		case OverflowBlock:
+			eq.notFull.Wait()
+			continue
+		case OverflowDropOldest:
+			eq.dropOldest()
+			continue
+		default:
+			eq.rejected++
+			return ErrQueueOverloaded
+		}
+	}
+	if eq.closed {
+		return ErrLoopStopped
+	}
+
 	event.ID = eq.idGen
 	eq.idGen++
 	heap.Push(eq, event)
+	if len(eq.events) > eq.highWater {
+		eq.highWater = len(eq.events)
+	}
+	return nil
+}
+
+// dropOldest removes the oldest event among those with the lowest priority.
+// Must be called with eq.mu held.
+func (eq *EventQueue) dropOldest() {
+	if len(eq.events) == 0 {
+		return
+	}
+	victim := 0
+	for i, e := range eq.events {
+		v := eq.events[victim]
+		if e.Priority < v.Priority || (e.Priority == v.Priority && e.ID < v.ID) {
+			victim = i
+		}
+	}
+	heap.Remove(eq, victim)
+	eq.dropped++
+}
+
+// Close wakes up any blocked producers; subsequent enqueues fail
+func (eq *EventQueue) Close() {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	eq.closed = true
+	eq.notFull.Broadcast()
 }
 
 // Dequeue removes and returns the highest priority event
@@ -40,7 +151,9 @@ func (eq *EventQueue) Dequeue() *Event {
 		return nil
 	}
 	
-	return heap.Pop(eq).(*Event)
+	event := heap.Pop(eq).(*Event)
+	eq.notFull.Signal()
+	return event
 }
 
 // Peek returns the highest priority event without removing it
@@ -73,6 +186,7 @@ func (eq *EventQueue) Clear() {
 	defer eq.mu.Unlock()
 	eq.events = make([]*Event, 0)
 	heap.Init(eq)
+	eq.notFull.Broadcast()
 }
 
 // Heap interface implementation
@@ -81,8 +195,8 @@ func (eq *EventQueue) Less(i, j int) bool {
 	if eq.events[i].Priority != eq.events[j].Priority {
 		return eq.events[i].Priority > eq.events[j].Priority
 	}
-	// Earlier events come first if same priority
-	return eq.events[i].Timestamp.Before(eq.events[j].Timestamp)
+	// Earlier events come first if same priority (IDs are assigned in enqueue order)
+	return eq.events[i].ID < eq.events[j].ID
 }
 
 func (eq *EventQueue) Swap(i, j int) {
@@ -101,14 +215,48 @@ func (eq *EventQueue) Pop() interface{} {
 	return event
 }
 
-// BackpressureThreshold is the maximum number of events before backpressure kicks in
+// BackpressureThreshold is the default maximum number of queued events
 const BackpressureThreshold = 10000
 
-// IsOverloaded checks if the queue is overloaded
+// IsOverloaded checks if the queue has reached its high-water mark
 func (eq *EventQueue) IsOverloaded() bool {
 	eq.mu.Lock()
 	defer eq.mu.Unlock()
-	return len(eq.events) > BackpressureThreshold
+	return len(eq.events) >= eq.capacity
+}
+
+// Capacity returns the maximum number of queued events
+func (eq *EventQueue) Capacity() int {
+	return eq.capacity
+}
+
+// Policy returns the overflow policy
+func (eq *EventQueue) Policy() OverflowPolicy {
+	return eq.policy
+}
+
+// QueueStats represents event queue statistics
+type QueueStats struct {
+	Length    int
+	Capacity  int
+	HighWater int
+	Dropped   uint64
+	Rejected  uint64
+	Policy    OverflowPolicy
+}
+
+// Stats returns queue statistics
+func (eq *EventQueue) Stats() QueueStats {
+	eq.mu.Lock()
+	defer eq.mu.Unlock()
+	return QueueStats{
+		Length:    len(eq.events),
+		Capacity:  eq.capacity,
+		HighWater: eq.highWater,
+		Dropped:   eq.dropped,
+		Rejected:  eq.rejected,
+		Policy:    eq.policy,
+	}
 }
 
 // Size returns the current queue size
@@ -0,0 +1,153 @@
+package eventloop
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// minStallCheck is the shortest interval between two stall checks
+const minStallCheck = 10 * time.Millisecond
+
+// Stall describes an event that has kept the loop from making progress for
+// longer than the stall threshold
+type Stall struct {
+	Event    EventType     // type of the stalled event
+	Duration time.Duration // how long the event had been running when reported
+	Stack    []byte        // stack of the loop goroutine when the stall was detected
+}
+
+// StallHandler is called, on the watchdog goroutine, once per stalled event
+type StallHandler func(Stall)
+
+// String names an event type
+func (t EventType) String() string {
+	switch t {
+	case EventIO:
+		return "I/O"
+	case EventTimer:
+		return "timer"
+	case EventImmediate:
+		return "immediate"
+	case EventNextTick:
+		return "nextTick"
+	default:
+		return "event " + strconv.Itoa(int(t))
+	}
+}
+
+// SetStallWatchdog reports events that run for longer than threshold to
+// handler and to the eventloop_stalls_total metric. A threshold of 0
+// disables the watchdog. It must be called before Start.
+func (l *Loop) SetStallWatchdog(threshold time.Duration, handler StallHandler) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.stallThreshold = threshold
+	l.onStall = handler
+}
+
+// beginEvent records that an event of type t started running on the loop
+func (l *Loop) beginEvent(t EventType) {
+	atomic.StoreInt32(&l.eventType, int32(t))
+	atomic.AddUint64(&l.eventSeq, 1)
+	atomic.StoreInt64(&l.eventStart, time.Now().UnixNano())
+}
+
+// endEvent records that the running event returned, which is progress
+func (l *Loop) endEvent() {
+	atomic.StoreInt64(&l.eventStart, 0)
+}
+
+// watch checks the running event against threshold until the loop stops
+func (l *Loop) watch(threshold time.Duration, handler StallHandler) {
+	interval := threshold / 4
+	if interval < minStallCheck {
+		interval = minStallCheck
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var reported uint64
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		start := atomic.LoadInt64(&l.eventStart)
+		seq := atomic.LoadUint64(&l.eventSeq)
+		if start == 0 || seq == reported {
+			continue
+		}
+		running := time.Since(time.Unix(0, start))
+		if running < threshold {
+			continue
+		}
+
+		// Report each event once, however long it keeps the loop
+		reported = seq
+		atomic.AddUint64(&l.stalls, 1)
+		l.recordStallMetrics(running)
+		if handler != nil {
+			handler(Stall{
+				Event:    EventType(atomic.LoadInt32(&l.eventType)),
+				Duration: running,
+				Stack:    goroutineStack(atomic.LoadInt64(&l.goroutine)),
+			})
+		}
+	}
+}
+
+// recordStallMetrics publishes a detected stall to the metrics collector
+func (l *Loop) recordStallMetrics(running time.Duration) {
+	l.mu.RLock()
+	metrics := l.metrics
+	l.mu.RUnlock()
+	if metrics == nil {
+		return
+	}
+	metrics.Increment("eventloop_stalls_total", nil)
+	metrics.Set("eventloop_last_stall_seconds", running.Seconds(), nil)
+}
+
+// currentGoroutine returns the ID of the calling goroutine, parsed from the
+// header of its stack trace ("goroutine 42 [running]:")
+func currentGoroutine() int64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i > 0 {
+		if id, err := strconv.ParseInt(string(buf[:i]), 10, 64); err == nil {
+			return id
+		}
+	}
+	return 0
+}
+
+// goroutineStack returns the stack trace of the goroutine with the given ID,
+// or nil if it cannot be found
+func goroutineStack(id int64) []byte {
+	if id == 0 {
+		return nil
+	}
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	header := []byte("goroutine " + strconv.FormatInt(id, 10) + " [")
+	for _, trace := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(trace, header) {
+			return trace
+		}
+	}
+	return nil
+}
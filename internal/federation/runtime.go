@@ -3,8 +3,11 @@ package federation
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net"
+	"sort"
 	"sync"
 	"time"
 )
@@ -43,17 +46,32 @@ type FederationMessage struct {
 	To        string
 	Payload   json.RawMessage
 	Timestamp time.Time
+	Error     string `json:",omitempty"` // set on replies whose handler failed
 }
 
+// Default limits of federation connections
+const (
+	DefaultReadTimeout    = 10 * time.Second
+	DefaultWriteTimeout   = 10 * time.Second
+	DefaultMaxMessageSize = 1 << 20 // 1 MiB
+)
+
+// ErrMessageTooLarge is returned when a peer sends a message larger than the
+// maximum message size
+var ErrMessageTooLarge = errors.New("federation message too large")
+
 // Federation provides multi-runtime federation
 type Federation struct {
-	localID  string
-	nodes    map[string]*RuntimeNode
-	listener net.Listener
-	handlers map[string]MessageHandler
-	mu       sync.RWMutex
-	ctx      context.Context
-	cancel   context.CancelFunc
+	localID        string
+	nodes          map[string]*RuntimeNode
+	listener       net.Listener
+	handlers       map[string]MessageHandler
+	readTimeout    time.Duration
+	writeTimeout   time.Duration
+	maxMessageSize int64
+	mu             sync.RWMutex
+	ctx            context.Context
+	cancel         context.CancelFunc
 }
 
 // MessageHandler handles federation messages
@@ -63,12 +81,76 @@ type MessageHandler func(ctx context.Context, msg *FederationMessage) (*Federati
 func NewFederation(localID string, ctx context.Context) *Federation {
 	fedCtx, cancel := context.WithCancel(ctx)
 	return &Federation{
-		localID:  localID,
-		nodes:    make(map[string]*RuntimeNode),
-		handlers: make(map[string]MessageHandler),
-		ctx:      fedCtx,
-		cancel:   cancel,
+		localID:        localID,
+		nodes:          make(map[string]*RuntimeNode),
+		handlers:       make(map[string]MessageHandler),
+		readTimeout:    DefaultReadTimeout,
+		writeTimeout:   DefaultWriteTimeout,
+		maxMessageSize: DefaultMaxMessageSize,
+		ctx:            fedCtx,
+		cancel:         cancel,
+	}
+}
+
+// SetTimeouts sets how long a peer may take to send a whole message and to
+// receive one. Connections exceeding them are dropped.
+func (f *Federation) SetTimeouts(read, write time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.readTimeout = read
+	f.writeTimeout = write
+}
+
+// SetMaxMessageSize sets the largest encoded message accepted from a peer.
+// Connections sending more are dropped.
+func (f *Federation) SetMaxMessageSize(size int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.maxMessageSize = size
+}
+
+// limits returns the connection timeouts and maximum message size
+func (f *Federation) limits() (read, write time.Duration, maxSize int64) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.readTimeout, f.writeTimeout, f.maxMessageSize
+}
+
+// limitedReader reads at most n bytes and then fails with
+// ErrMessageTooLarge
+type limitedReader struct {
+	r io.Reader
+	n int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.n <= 0 {
+		return 0, ErrMessageTooLarge
 	}
+	if int64(len(p)) > l.n {
+		p = p[:l.n]
+	}
+	n, err := l.r.Read(p)
+	l.n -= int64(n)
+	return n, err
+}
+
+// decodeMessage reads one message from conn, failing if it exceeds the
+// maximum message size
+func (f *Federation) decodeMessage(conn net.Conn, msg *FederationMessage) error {
+	_, _, maxSize := f.limits()
+	return json.NewDecoder(&limitedReader{r: conn, n: maxSize}).Decode(msg)
+}
+
+// encodeMessage writes one message to conn within the write timeout
+func (f *Federation) encodeMessage(conn net.Conn, msg *FederationMessage) error {
+	_, write, _ := f.limits()
+	if write > 0 {
+		if err := conn.SetWriteDeadline(time.Now().Add(write)); err != nil {
+			return err
+		}
+	}
+	return json.NewEncoder(conn).Encode(msg)
 }
 
 // RegisterNode registers a node
@@ -85,6 +167,19 @@ func (f *Federation) UnregisterNode(nodeID string) {
 	delete(f.nodes, nodeID)
 }
 
+// NodeIDs returns the IDs of the registered nodes in sorted order
+func (f *Federation) NodeIDs() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	ids := make([]string, 0, len(f.nodes))
+	for id := range f.nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
 // RegisterHandler registers a message handler
 func (f *Federation) RegisterHandler(msgType string, handler MessageHandler) {
 	f.mu.Lock()
@@ -92,19 +187,77 @@ func (f *Federation) RegisterHandler(msgType string, handler MessageHandler) {
 	f.handlers[msgType] = handler
 }
 
+// ID returns the ID of the local node
+func (f *Federation) ID() string {
+	return f.localID
+}
+
 // Send sends a message to a node
 func (f *Federation) Send(nodeID string, msgType string, payload interface{}) error {
+	address, msg, err := f.newMessage(nodeID, msgType, payload)
+	if err != nil {
+		return err
+	}
+
+	return f.sendMessage(address, msg)
+}
+
+// Request sends a message to a node and waits for the reply of its
+// handler. The reply is nil if the handler sent none; a handler failure is
+// returned as an error.
+func (f *Federation) Request(ctx context.Context, nodeID string, msgType string, payload interface{}) (*FederationMessage, error) {
+	address, msg, err := f.newMessage(nodeID, msgType, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock the exchange when ctx is done
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if err := f.encodeMessage(conn, msg); err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	var reply FederationMessage
+	if err := f.decodeMessage(conn, &reply); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read reply: %w", err)
+	}
+	if reply.Error != "" {
+		return nil, fmt.Errorf("node %s: %s", nodeID, reply.Error)
+	}
+	return &reply, nil
+}
+
+// newMessage builds a message to a registered node and returns the node's
+// address with it
+func (f *Federation) newMessage(nodeID string, msgType string, payload interface{}) (string, *FederationMessage, error) {
 	f.mu.RLock()
 	node, ok := f.nodes[nodeID]
 	f.mu.RUnlock()
 
 	if !ok {
-		return fmt.Errorf("node not found: %s", nodeID)
+		return "", nil, fmt.Errorf("node not found: %s", nodeID)
 	}
 
 	payloadJSON, err := json.Marshal(payload)
 	if err != nil {
-		return fmt.Errorf("failed to marshal payload: %w", err)
+		return "", nil, fmt.Errorf("failed to marshal payload: %w", err)
 	}
 
 	msg := &FederationMessage{
@@ -114,8 +267,7 @@ func (f *Federation) Send(nodeID string, msgType string, payload interface{}) er
 		Payload:   payloadJSON,
 		Timestamp: time.Now(),
 	}
-
-	return f.sendMessage(node.Address, msg)
+	return node.Address, msg, nil
 }
 
 // Broadcast broadcasts a message to all nodes
@@ -151,14 +303,14 @@ func (f *Federation) Broadcast(msgType string, payload interface{}) error {
 
 // sendMessage sends a message to an address
 func (f *Federation) sendMessage(address string, msg *FederationMessage) error {
-	conn, err := net.Dial("tcp", address)
+	_, write, _ := f.limits()
+	conn, err := net.DialTimeout("tcp", address, write)
 	if err != nil {
 		return fmt.Errorf("failed to connect: %w", err)
 	}
 	defer conn.Close()
 
-	encoder := json.NewEncoder(conn)
-	return encoder.Encode(msg)
+	return f.encodeMessage(conn, msg)
 }
 
 // Listen starts listening for federation messages
@@ -176,6 +328,16 @@ func (f *Federation) Listen(address string) error {
 	return nil
 }
 
+// Addr returns the address the federation listens on, or nil before Listen
+func (f *Federation) Addr() net.Addr {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.listener == nil {
+		return nil
+	}
+	return f.listener.Addr()
+}
+
 // accept accepts connections
 func (f *Federation) accept() {
 	for {
@@ -201,16 +363,25 @@ func (f *Federation) accept() {
 	}
 }
 
-// handleConnection handles a connection
+// handleConnection handles a connection. A peer that does not send a
+// complete message within the read timeout, or sends one larger than the
+// maximum message size, is dropped.
 func (f *Federation) handleConnection(conn net.Conn) {
 	defer conn.Close()
 
-	decoder := json.NewDecoder(conn)
+	read, _, _ := f.limits()
+	if read > 0 {
+		if err := conn.SetReadDeadline(time.Now().Add(read)); err != nil {
+			return
+		}
+	}
 
 	var msg FederationMessage
-	if err := decoder.Decode(&msg); err != nil {
+	if err := f.decodeMessage(conn, &msg); err != nil {
 		return
 	}
+	// The handler may take longer than a peer may take to send
+	_ = conn.SetReadDeadline(time.Time{})
 
 	// Handle message
 	f.mu.RLock()
@@ -223,12 +394,18 @@ func (f *Federation) handleConnection(conn net.Conn) {
 
 	response, err := handler(f.ctx, &msg)
 	if err != nil {
-		return
+		// Tell a waiting requester why there is no reply
+		response = &FederationMessage{
+			Type:      msg.Type,
+			From:      f.localID,
+			To:        msg.From,
+			Timestamp: time.Now(),
+			Error:     err.Error(),
+		}
 	}
 
 	if response != nil {
-		encoder := json.NewEncoder(conn)
-		_ = encoder.Encode(response)
+		_ = f.encodeMessage(conn, response)
 	}
 }
 
@@ -0,0 +1,286 @@
+package framework
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/framework/runtime"
+	"gots-runtime/internal/eventloop"
+)
+
+// loopResult is how a call made on the event loop finished
+type loopResult struct {
+	err      error
+	panicked interface{}
+}
+
+// loopCall is a call made on the event loop by callOnLoop
+type loopCall struct {
+	finish func(error)
+	// abandoned is set on the loop once the caller stopped waiting for the
+	// call; the response is then no longer updated from JavaScript
+	abandoned bool
+}
+
+// callOnLoop runs start on the event loop and waits until it calls
+// call.finish, which may happen in a later event, e.g. once a promise
+// settles. The app handles requests off the loop, so that it can wait for
+// asynchronous handlers while the loop runs. A panic in start is raised
+// again in the caller, where the app's panic handler recovers it. The wait
+// ends early when the request is done; the call is then abandoned and
+// finished from the loop.
+func (tsa *TypeScriptApp) callOnLoop(ctx *runtime.Context, start func(call *loopCall)) error {
+	done := make(chan loopResult, 1)
+	var once sync.Once
+	call := &loopCall{}
+	call.finish = func(err error) {
+		once.Do(func() { done <- loopResult{err: err} })
+	}
+
+	goCtx := ctx.Context()
+	err := tsa.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+		defer func() {
+			if r := recover(); r != nil {
+				once.Do(func() { done <- loopResult{panicked: r} })
+			}
+		}()
+		start(call)
+		return nil
+	}, 0))
+	if err != nil {
+		return fmt.Errorf("failed to schedule handler: %w", err)
+	}
+
+	var res loopResult
+	select {
+	case res = <-done:
+	case <-goCtx.Done():
+		err := tsa.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+			call.abandoned = true
+			call.finish(goCtx.Err())
+			return nil
+		}, 0))
+		if err != nil {
+			return goCtx.Err()
+		}
+		res = <-done
+	}
+	if res.panicked != nil {
+		panic(res.panicked)
+	}
+	return res.err
+}
+
+// isThenable reports whether value is a promise or another object with a
+// then method
+func isThenable(value goja.Value) bool {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return false
+	}
+	_, ok = goja.AssertFunction(obj.Get("then"))
+	return ok
+}
+
+// whenSettled calls onSettled with value, or, when value is a promise,
+// with its result once it settles; a rejection is passed as the error
+func (tsa *TypeScriptApp) whenSettled(value goja.Value, onSettled func(result goja.Value, err error)) {
+	if !isThenable(value) {
+		onSettled(value, nil)
+		return
+	}
+	obj := value.(*goja.Object)
+	then, _ := goja.AssertFunction(obj.Get("then"))
+	onFulfilled := func(result goja.Value) {
+		onSettled(result, nil)
+	}
+	onRejected := func(reason goja.Value) {
+		onSettled(nil, rejectionError(reason))
+	}
+	if _, err := then(obj, tsa.engine.ToValue(onFulfilled), tsa.engine.ToValue(onRejected)); err != nil {
+		onSettled(nil, toHTTPError(err))
+	}
+}
+
+// rejectionError converts the reason a promise was rejected with to an
+// error: an HTTPError thrown by throwHTTPError, the Go error of a rejection
+// made by the runtime or the reason's string form
+func rejectionError(reason goja.Value) error {
+	if httpErr, ok := httpErrorOf(reason); ok {
+		return httpErr
+	}
+	if reason != nil {
+		if err, ok := reason.Export().(error); ok {
+			return err
+		}
+	}
+	if reason == nil || goja.IsUndefined(reason) {
+		return errors.New("promise rejected")
+	}
+	return errors.New(reason.String())
+}
+
+// errorValue converts an error of the Go middleware chain to the value a
+// JavaScript promise is rejected with
+func (tsa *TypeScriptApp) errorValue(err error) goja.Value {
+	var httpErr *runtime.HTTPError
+	if errors.As(err, &httpErr) {
+		return newHTTPErrorObject(tsa.engine, httpErr)
+	}
+	return tsa.engine.NewGoError(err)
+}
+
+// responseObject is ctx.response. JavaScript changes its status, headers
+// and body; sync copies them to the Go response and refresh copies the Go
+// response back, e.g. after next() ran the rest of the chain. Neither
+// happens once its call is abandoned.
+type responseObject struct {
+	obj   *goja.Object
+	body  goja.Value // the body last copied from the Go response
+	tsa   *TypeScriptApp
+	call  *loopCall
+	goCtx context.Context // the Go context the call was made with
+}
+
+// createResponseObject creates ctx.response from the response of ctx for
+// call
+func (tsa *TypeScriptApp) createResponseObject(ctx *runtime.Context, call *loopCall) *responseObject {
+	res := &responseObject{obj: tsa.engine.NewObject(), tsa: tsa, call: call, goCtx: ctx.Context()}
+	res.set(ctx.Response)
+
+	obj := res.obj
+	setHeader := func(name, value string) {
+		if headers, ok := obj.Get("headers").(*goja.Object); ok {
+			headers.Set(name, value)
+		}
+	}
+	obj.Set("setStatus", func(code int) *goja.Object {
+		obj.Set("status", code)
+		return obj
+	})
+	obj.Set("setHeader", func(name, value string) *goja.Object {
+		setHeader(name, value)
+		return obj
+	})
+	obj.Set("text", func(text string) {
+		setHeader("Content-Type", "text/plain; charset=utf-8")
+		obj.Set("body", text)
+	})
+	obj.Set("html", func(html string) {
+		setHeader("Content-Type", "text/html; charset=utf-8")
+		obj.Set("body", html)
+	})
+	obj.Set("json", func(data goja.Value) {
+		encoded, err := json.Marshal(data.Export())
+		if err != nil {
+			panic(tsa.engine.ToValue(fmt.Sprintf("failed to encode JSON response: %v", err)))
+		}
+		setHeader("Content-Type", "application/json")
+		obj.Set("body", string(encoded))
+	})
+	return res
+}
+
+// abandoned reports whether the request stopped waiting for the call,
+// because the request is done or a TimeoutMiddleware deadline passed
+func (res *responseObject) abandoned() bool {
+	return res.call.abandoned || runtime.Abandoned(res.goCtx)
+}
+
+// refresh sets the status, headers and body of ctx.response from the
+// response of ctx, unless the call was abandoned
+func (res *responseObject) refresh(ctx *runtime.Context) {
+	if res.abandoned() {
+		return
+	}
+	res.set(ctx.Response)
+}
+
+// set sets the status, headers and body of ctx.response from resp
+func (res *responseObject) set(resp *runtime.Response) {
+	headers := res.tsa.engine.NewObject()
+	for key, value := range resp.Headers {
+		headers.Set(key, value)
+	}
+	res.body = res.tsa.engine.ToValue(string(resp.Body))
+	res.obj.Set("status", resp.Status)
+	res.obj.Set("headers", headers)
+	res.obj.Set("body", res.body)
+}
+
+// sync copies the status, headers and body of ctx.response to the response
+// of ctx, unless the call was abandoned. The body is only copied once
+// JavaScript replaced it, so that binary bodies set in Go are kept as they
+// are.
+func (res *responseObject) sync(ctx *runtime.Context) {
+	if res.abandoned() {
+		return
+	}
+	resp := ctx.Response
+	resp.Status = int(res.obj.Get("status").ToInteger())
+
+	resp.Headers = make(map[string]string)
+	if headers, ok := res.obj.Get("headers").(*goja.Object); ok {
+		for _, key := range headers.Keys() {
+			resp.Headers[key] = headers.Get(key).String()
+		}
+	}
+
+	body := res.obj.Get("body")
+	if body != nil && res.body != nil && body.SameAs(res.body) {
+		return
+	}
+	if body == nil || goja.IsUndefined(body) || goja.IsNull(body) {
+		resp.Body = nil
+	} else {
+		resp.Body = res.tsa.chunkBytes(body)
+	}
+	res.body = body
+}
+
+// send makes a value returned by a handler the response body, unless the
+// handler set a body or started a stream or the call was abandoned: strings
+// and buffers as they are and other values as JSON
+func (res *responseObject) send(ctx *runtime.Context, value goja.Value) error {
+	if value == nil || goja.IsUndefined(value) || res.abandoned() || ctx.Response.Stream != nil {
+		return nil
+	}
+	if body := res.obj.Get("body"); body != nil && !goja.IsUndefined(body) && !goja.IsNull(body) && body.String() != "" {
+		return nil
+	}
+
+	contentType := ""
+	switch value.Export().(type) {
+	case string:
+		contentType = "text/plain; charset=utf-8"
+		res.obj.Set("body", value)
+	case []byte, goja.ArrayBuffer:
+		contentType = "application/octet-stream"
+		res.obj.Set("body", value)
+	default:
+		encoded, err := json.Marshal(value.Export())
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON response: %w", err)
+		}
+		contentType = "application/json"
+		res.obj.Set("body", string(encoded))
+	}
+
+	headers, ok := res.obj.Get("headers").(*goja.Object)
+	if !ok {
+		return nil
+	}
+	for _, key := range headers.Keys() {
+		if http.CanonicalHeaderKey(key) == "Content-Type" {
+			return nil
+		}
+	}
+	headers.Set("Content-Type", contentType)
+	return nil
+}
@@ -0,0 +1,86 @@
+package framework
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/dop251/goja"
+	"gots-runtime/framework/runtime"
+)
+
+// httpErrorName is the name of errors thrown by throwHTTPError
+const httpErrorName = "HTTPError"
+
+// ThrowHTTPError returns the TypeScript throwHTTPError(status, message?,
+// code?) function. It throws an Error named HTTPError that route handlers
+// and middleware turn into an error response with that status.
+func ThrowHTTPError(vm *goja.Runtime) func(status int, message, code goja.Value) {
+	return func(status int, message, code goja.Value) {
+		httpErr := &runtime.HTTPError{Status: status}
+		if message != nil && !goja.IsUndefined(message) && !goja.IsNull(message) {
+			httpErr.Message = message.String()
+		}
+		if code != nil && !goja.IsUndefined(code) && !goja.IsNull(code) {
+			httpErr.Code = code.String()
+		}
+		panic(newHTTPErrorObject(vm, httpErr))
+	}
+}
+
+// newHTTPErrorObject creates the Error named HTTPError that represents
+// httpErr in TypeScript
+func newHTTPErrorObject(vm *goja.Runtime, httpErr *runtime.HTTPError) *goja.Object {
+	errObj, err := vm.New(vm.Get("Error"), vm.ToValue(httpErr.Error()))
+	if err != nil {
+		panic(err)
+	}
+	errObj.Set("name", httpErrorName)
+	errObj.Set("status", httpErr.Status)
+	errObj.Set("code", httpErr.Code)
+	errObj.Set("message", httpErr.Message)
+	return errObj
+}
+
+// toHTTPError converts an error thrown by throwHTTPError to a
+// runtime.HTTPError; other errors are returned unchanged
+func toHTTPError(err error) error {
+	var exception *goja.Exception
+	if !errors.As(err, &exception) {
+		return err
+	}
+	if httpErr, ok := httpErrorOf(exception.Value()); ok {
+		return httpErr
+	}
+	return err
+}
+
+// httpErrorOf converts a value thrown by throwHTTPError to a
+// runtime.HTTPError
+func httpErrorOf(value goja.Value) (*runtime.HTTPError, bool) {
+	errObj, ok := value.(*goja.Object)
+	if !ok || errObj.Get("name") == nil || errObj.Get("name").String() != httpErrorName {
+		return nil, false
+	}
+
+	httpErr := &runtime.HTTPError{Status: int(errObj.Get("status").ToInteger())}
+	if v := errObj.Get("code"); v != nil && !goja.IsUndefined(v) {
+		httpErr.Code = v.String()
+	}
+	if v := errObj.Get("message"); v != nil && !goja.IsUndefined(v) {
+		httpErr.Message = v.String()
+	}
+	return httpErr, true
+}
+
+// middlewareError wraps an error of a TypeScript middleware; HTTP errors
+// are returned as they are, so that they keep their status
+func middlewareError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var httpErr *runtime.HTTPError
+	if errors.As(err, &httpErr) {
+		return err
+	}
+	return fmt.Errorf("middleware error: %w", err)
+}
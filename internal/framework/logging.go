@@ -0,0 +1,74 @@
+package framework
+
+import (
+	"github.com/dop251/goja"
+	"gots-runtime/framework/runtime"
+	"gots-runtime/internal/observability"
+)
+
+// createLogObject creates ctx.log, which logs through the request's logger
+// so that every entry carries the request ID. fields are added to every
+// entry of this log object; with(fields) derives one with more.
+func (tsa *TypeScriptApp) createLogObject(ctx *runtime.Context, fields map[string]interface{}) *goja.Object {
+	logObj := tsa.engine.NewObject()
+
+	// logger is resolved on every call, so that fields added by middleware
+	// that ran since the object was created are included
+	logger := func(extra goja.Value) *observability.Logger {
+		l := ctx.Logger()
+		if len(fields) > 0 {
+			l = l.With(fields)
+		}
+		if extraFields := tsa.logFields(extra); len(extraFields) > 0 {
+			l = l.With(extraFields)
+		}
+		return l
+	}
+
+	// debug, info, warn and error(message, fields?) log at their level
+	logObj.Set("debug", func(message, extra goja.Value) {
+		logger(extra).Debug("%s", message.String())
+	})
+	logObj.Set("info", func(message, extra goja.Value) {
+		logger(extra).Info("%s", message.String())
+	})
+	logObj.Set("warn", func(message, extra goja.Value) {
+		logger(extra).Warn("%s", message.String())
+	})
+	logObj.Set("error", func(message, extra goja.Value) {
+		logger(extra).Error("%s", message.String())
+	})
+
+	// with(fields) returns a log object that adds fields to its entries
+	logObj.Set("with", func(extra goja.Value) *goja.Object {
+		merged := make(map[string]interface{}, len(fields))
+		for key, value := range fields {
+			merged[key] = value
+		}
+		for key, value := range tsa.logFields(extra) {
+			merged[key] = value
+		}
+		return tsa.createLogObject(ctx, merged)
+	})
+
+	// set(fields) adds fields to every entry logged for the rest of the
+	// request, including by later middleware and the handler
+	logObj.Set("set", func(extra goja.Value) {
+		ctx.AddLogFields(tsa.logFields(extra))
+	})
+
+	return logObj
+}
+
+// logFields reads log fields from a TypeScript object
+func (tsa *TypeScriptApp) logFields(value goja.Value) map[string]interface{} {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil
+	}
+	fields := make(map[string]interface{})
+	for _, key := range obj.Keys() {
+		fields[key] = obj.Get(key).Export()
+	}
+	return fields
+}
@@ -0,0 +1,67 @@
+package framework
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"gots-runtime/framework/runtime"
+	"gots-runtime/internal/plugin"
+)
+
+// SetPluginManager makes the app dispatch the request hooks of the plugins
+// registered with manager: beforeRequest, afterRequest and onError. An
+// error returned by a beforeRequest handler fails the request, which then
+// passes the onError and afterRequest hooks like any failed request.
+func (tsa *TypeScriptApp) SetPluginManager(manager *plugin.PluginManager) {
+	tsa.app.UsePriority(pluginHooksMiddleware(tsa.app, manager), runtime.PhasePre)
+}
+
+// pluginHooksMiddleware dispatches the request hooks of manager around the
+// rest of the chain
+func pluginHooksMiddleware(app *runtime.App, manager *plugin.PluginManager) runtime.Middleware {
+	return func(ctx *runtime.Context, next runtime.Next) error {
+		request := map[string]interface{}{
+			"method": ctx.Request.Method,
+			"path":   ctx.Request.Path,
+		}
+		start := time.Now()
+		err := manager.Dispatch(plugin.HookBeforeRequest, request)
+		if err == nil {
+			err = next()
+		}
+
+		if err != nil && manager.HasHook(plugin.HookError) {
+			failed := map[string]interface{}{
+				"method": ctx.Request.Method,
+				"path":   ctx.Request.Path,
+				"error":  err.Error(),
+			}
+			if hookErr := manager.Dispatch(plugin.HookError, failed); hookErr != nil {
+				app.Logger().Error("%v", hookErr)
+			}
+		}
+		if manager.HasHook(plugin.HookAfterRequest) {
+			response := map[string]interface{}{
+				"method":     ctx.Request.Method,
+				"path":       ctx.Request.Path,
+				"status":     ctx.Response.Status,
+				"durationMs": time.Since(start).Milliseconds(),
+			}
+			if err != nil {
+				// The error handler renders the response later; report the
+				// status it will use
+				response["status"] = http.StatusInternalServerError
+				var httpErr *runtime.HTTPError
+				if errors.As(err, &httpErr) {
+					response["status"] = httpErr.Status
+				}
+				response["error"] = err.Error()
+			}
+			if hookErr := manager.Dispatch(plugin.HookAfterRequest, response); hookErr != nil {
+				app.Logger().Error("%v", hookErr)
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,100 @@
+package framework
+
+import (
+	"context"
+	"errors"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/eventloop"
+)
+
+// createSignalObject creates an AbortSignal-like object that is aborted
+// when ctx is done. Listeners are called on the event loop with the abort
+// reason, a TimeoutError when ctx's deadline passed and an AbortError
+// otherwise.
+func (tsa *TypeScriptApp) createSignalObject(ctx context.Context) *goja.Object {
+	signalObj := tsa.engine.NewObject()
+	signalObj.Set("aborted", false)
+	signalObj.Set("reason", goja.Undefined())
+	signalObj.Set("onabort", goja.Null())
+
+	// Listeners are only accessed on the event loop
+	var listeners []goja.Value
+
+	signalObj.Set("addEventListener", func(eventType string, listener goja.Value) {
+		if eventType != "abort" {
+			return
+		}
+		if _, ok := goja.AssertFunction(listener); !ok {
+			panic(tsa.engine.ToValue("listener must be a function"))
+		}
+		listeners = append(listeners, listener)
+	})
+
+	signalObj.Set("removeEventListener", func(eventType string, listener goja.Value) {
+		for i, l := range listeners {
+			if l.StrictEquals(listener) {
+				listeners = append(listeners[:i], listeners[i+1:]...)
+				return
+			}
+		}
+	})
+
+	signalObj.Set("throwIfAborted", func() {
+		if signalObj.Get("aborted").ToBoolean() {
+			panic(signalObj.Get("reason"))
+		}
+	})
+
+	abort := func() {
+		if signalObj.Get("aborted").ToBoolean() {
+			return
+		}
+		reason := tsa.abortReason(ctx.Err())
+		signalObj.Set("aborted", true)
+		signalObj.Set("reason", reason)
+
+		if onabort, ok := goja.AssertFunction(signalObj.Get("onabort")); ok {
+			_, _ = onabort(signalObj, reason)
+		}
+		for _, l := range listeners {
+			if fn, ok := goja.AssertFunction(l); ok {
+				_, _ = fn(signalObj, reason)
+			}
+		}
+		listeners = nil
+	}
+
+	if ctx.Err() != nil {
+		abort()
+		return signalObj
+	}
+
+	if done := ctx.Done(); done != nil {
+		go func() {
+			<-done
+			tsa.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				abort()
+				return nil
+			}, eventloop.PriorityNormal))
+		}()
+	}
+
+	return signalObj
+}
+
+// abortReason creates the JavaScript error for a context error
+func (tsa *TypeScriptApp) abortReason(err error) goja.Value {
+	name, message := "AbortError", "The request was aborted"
+	if errors.Is(err, context.DeadlineExceeded) {
+		name, message = "TimeoutError", "The request timed out"
+	}
+
+	errObj, newErr := tsa.engine.New(tsa.engine.Get("Error"), tsa.engine.ToValue(message))
+	if newErr != nil {
+		return tsa.engine.ToValue(message)
+	}
+	errObj.Set("name", name)
+	return errObj
+}
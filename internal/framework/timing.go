@@ -0,0 +1,77 @@
+package framework
+
+import (
+	"sort"
+	"time"
+
+	"github.com/dop251/goja"
+	"gots-runtime/framework/runtime"
+	"gots-runtime/internal/observability"
+)
+
+// createTimingObject creates ctx.timing for a request timed by
+// app.useTiming(), or returns undefined when it is not in use
+func (tsa *TypeScriptApp) createTimingObject(ctx *runtime.Context) goja.Value {
+	timing := ctx.Timing()
+	if timing == nil {
+		return goja.Undefined()
+	}
+	timingObj := tsa.engine.NewObject()
+
+	if timing.TraceID != "" {
+		timingObj.Set("traceId", timing.TraceID)
+	}
+
+	// phases() returns the breakdown so far in milliseconds
+	timingObj.Set("phases", func() map[string]interface{} {
+		phases := make(map[string]interface{})
+		for _, phase := range timing.Phases() {
+			phases[phase.Name] = float64(phase.Duration.Microseconds()) / 1000
+		}
+		return phases
+	})
+
+	// add(name, ms) records a phase measured by the caller
+	timingObj.Set("add", func(name string, ms float64) {
+		timing.Add(name, time.Duration(ms*float64(time.Millisecond)))
+	})
+
+	// start(name) starts measuring a phase and returns the function that
+	// ends it
+	timingObj.Set("start", func(name string) func() {
+		return timing.Start(name)
+	})
+
+	return timingObj
+}
+
+// spans returns the spans of a trace recorded by app.useTiming(), oldest
+// first, as plain objects
+func (tsa *TypeScriptApp) spans(traceID string) []map[string]interface{} {
+	spans := tsa.tracer.GetSpansByTraceID(traceID)
+	sortSpans(spans)
+	result := make([]map[string]interface{}, len(spans))
+	for i, span := range spans {
+		tags := make(map[string]interface{}, len(span.Tags))
+		for key, value := range span.Tags {
+			tags[key] = value
+		}
+		result[i] = map[string]interface{}{
+			"traceId":  span.TraceID,
+			"spanId":   span.SpanID,
+			"parentId": span.ParentID,
+			"name":     span.Name,
+			"start":    span.StartTime.UnixMilli(),
+			"duration": float64(span.Duration.Microseconds()) / 1000,
+			"tags":     tags,
+		}
+	}
+	return result
+}
+
+// sortSpans orders spans by start time
+func sortSpans(spans []*observability.Span) {
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].StartTime.Before(spans[j].StartTime)
+	})
+}
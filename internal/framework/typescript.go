@@ -1,35 +1,57 @@
 package framework
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"net"
+	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/dop251/goja"
 	"gots-runtime/framework/runtime"
 	"gots-runtime/internal/api"
 	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/loadbalancer"
+	"gots-runtime/internal/observability"
 )
 
 // TypeScriptApp wraps the Go App for TypeScript
 type TypeScriptApp struct {
-	app      *runtime.App
-	engine   *goja.Runtime
-	eventLoop *eventloop.Loop
-	httpAPI  *api.HTTP
-	server   *api.Server
-	mu       sync.RWMutex
+	app            *runtime.App
+	engine         *goja.Runtime
+	eventLoop      *eventloop.Loop
+	httpAPI        *api.HTTP
+	server         *api.Server
+	schemaCompiler SchemaCompiler
+	shedderFactory ShedderFactory
+	serverOptions  api.ServerOptions
+	tracer         *observability.Tracer
+	mu             sync.RWMutex
 }
 
+// SchemaCompiler converts a route schema object, such as { body: {...} },
+// into a framework schema
+type SchemaCompiler func(schema goja.Value) (runtime.Schema, error)
+
+// ShedderFactory creates the load shedder of app.shedLoad, which sheds
+// requests once threshold are in flight, according to policy ("" for the
+// default one)
+type ShedderFactory func(threshold int, policy string) (runtime.LoadShedder, error)
+
 // NewTypeScriptApp creates a new TypeScript-wrapped app
 func NewTypeScriptApp(engine *goja.Runtime, eventLoop *eventloop.Loop, name string) *TypeScriptApp {
 	app := runtime.NewApp(name)
 	httpAPI := api.NewHTTP(eventLoop)
 	
 	return &TypeScriptApp{
-		app:      app,
-		engine:   engine,
+		app:       app,
+		engine:    engine,
 		eventLoop: eventLoop,
-		httpAPI:  httpAPI,
+		httpAPI:   httpAPI,
+		tracer:    observability.NewTracer(),
 	}
 }
 
@@ -37,98 +59,130 @@ func NewTypeScriptApp(engine *goja.Runtime, eventLoop *eventloop.Loop, name stri
 func (tsa *TypeScriptApp) ToJSObject() *goja.Object {
 	obj := tsa.engine.NewObject()
 	
-	// Use method - add middleware
-	obj.Set("use", func(middleware goja.Value) {
-		mwFunc, ok := goja.AssertFunction(middleware)
-		if !ok {
-			panic(tsa.engine.ToValue("middleware must be a function"))
-		}
-		
-		tsa.app.Use(func(ctx *runtime.Context, next runtime.Next) error {
-			// Create TypeScript context
-			tsCtx := tsa.createContextObject(ctx)
-			
-			// Call TypeScript middleware
-			nextFunc := tsa.engine.NewObject()
-			nextFunc.Set("call", func() *goja.Promise {
-				promise, resolve, reject := tsa.engine.NewPromise()
-				go func() {
-					if err := next(); err != nil {
-						reject(tsa.engine.ToValue(err.Error()))
-					} else {
-						resolve(tsa.engine.ToValue(true))
-					}
-				}()
-				return promise
-			})
-			
-			result, err := mwFunc(nil, tsCtx, nextFunc)
-			if err != nil {
-				return fmt.Errorf("middleware error: %w", err)
+	// Use method - add middleware, optionally with a phase ("pre", "main"
+	// or "post") or a numeric priority; lower priorities run first
+	obj.Set("use", func(middleware goja.Value, order goja.Value) {
+		priority := runtime.PhaseMain
+		if order != nil && !goja.IsUndefined(order) && !goja.IsNull(order) {
+			if phase, ok := order.Export().(string); ok {
+				p, err := runtime.ParsePhase(phase)
+				if err != nil {
+					panic(tsa.engine.ToValue(err.Error()))
+				}
+				priority = p
+			} else {
+				priority = int(order.ToInteger())
 			}
-			
-			// If middleware returns a promise, wait for it
-			// For now, we'll execute synchronously
-			_ = result
-			
-			return nil
-		})
+		}
+		tsa.app.UsePriority(tsa.wrapMiddleware(middleware), priority)
 	})
 	
-	// Get method
-	obj.Set("get", func(path string, handler goja.Value) {
-		handlerFunc, ok := goja.AssertFunction(handler)
-		if !ok {
-			panic(tsa.engine.ToValue("handler must be a function"))
+	// UseSession method - add session middleware backed by an in-memory store
+	obj.Set("useSession", func(options goja.Value) {
+		opts := runtime.SessionOptions{}
+		if options != nil && !goja.IsUndefined(options) && !goja.IsNull(options) {
+			optsObj := options.ToObject(tsa.engine)
+			if v := optsObj.Get("cookieName"); v != nil && !goja.IsUndefined(v) {
+				opts.CookieName = v.String()
+			}
+			if v := optsObj.Get("ttl"); v != nil && !goja.IsUndefined(v) {
+				opts.TTL = time.Duration(v.ToInteger()) * time.Millisecond
+			}
+			opts.Cookie = tsa.cookieOptions(optsObj.Get("cookie"))
 		}
-		
-		tsa.app.Get(path, func(ctx *runtime.Context) error {
-			tsCtx := tsa.createContextObject(ctx)
-			_, err := handlerFunc(nil, tsCtx)
-			return err
-		})
+		tsa.app.Use(runtime.SessionMiddleware(runtime.NewMemorySessionStore(), opts))
 	})
 	
-	// Post method
-	obj.Set("post", func(path string, handler goja.Value) {
-		handlerFunc, ok := goja.AssertFunction(handler)
-		if !ok {
-			panic(tsa.engine.ToValue("handler must be a function"))
+	// UseRequestId method - give every request an ID, taken from its
+	// X-Request-ID header or generated, which ctx.log adds to its entries
+	obj.Set("useRequestId", func() {
+		tsa.app.UsePriority(runtime.RequestIDMiddleware(runtime.NewRequestID), runtime.PhasePre)
+	})
+	
+	// UseMetrics method - count requests and their errors and latency
+	// per route for app.metrics()
+	obj.Set("useMetrics", func() {
+		tsa.app.UsePriority(runtime.MetricsMiddleware, runtime.PhasePre)
+	})
+	
+	// UseTiming method - send a Server-Timing header with the time spent
+	// in middleware and in the handler, and trace each request
+	obj.Set("useTiming", func() {
+		tsa.app.UsePriority(runtime.TimingMiddleware(tsa.tracer), runtime.PhasePre)
+	})
+	
+	// Spans method - the spans recorded for a trace by useTiming
+	obj.Set("spans", func(traceID string) []map[string]interface{} {
+		return tsa.spans(traceID)
+	})
+	
+	// Route methods take optional route-specific middleware between the
+	// path and the handler: app.get(path, ...middleware, handler)
+	obj.Set("get", tsa.routeMethod(http.MethodGet, tsa.app.Get))
+	obj.Set("post", tsa.routeMethod(http.MethodPost, tsa.app.Post))
+	obj.Set("put", tsa.routeMethod(http.MethodPut, tsa.app.Put))
+	obj.Set("delete", tsa.routeMethod(http.MethodDelete, tsa.app.Delete))
+	obj.Set("patch", tsa.routeMethod(http.MethodPatch, tsa.app.Patch))
+	obj.Set("options", tsa.routeMethod(http.MethodOptions, tsa.app.Options))
+	obj.Set("head", tsa.routeMethod(http.MethodHead, tsa.app.Head))
+	
+	// Dynamic method - a route for any method: app.dynamic(method, path,
+	// ...middleware, handler)
+	obj.Set("dynamic", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 3 {
+			panic(tsa.engine.ToValue("method, path and handler are required"))
 		}
-		
-		tsa.app.Post(path, func(ctx *runtime.Context) error {
-			tsCtx := tsa.createContextObject(ctx)
-			_, err := handlerFunc(nil, tsCtx)
-			return err
-		})
+		method := strings.ToUpper(call.Argument(0).String())
+		register := func(path string, handler runtime.Handler, middleware ...runtime.Middleware) {
+			tsa.app.Dynamic(method, path, handler, middleware...)
+		}
+		return tsa.routeMethod(method, register)(goja.FunctionCall{This: call.This, Arguments: call.Arguments[1:]})
 	})
 	
-	// Put method
-	obj.Set("put", func(path string, handler goja.Value) {
-		handlerFunc, ok := goja.AssertFunction(handler)
-		if !ok {
-			panic(tsa.engine.ToValue("handler must be a function"))
+	// Proxy method - forward the requests under a path prefix to a set of
+	// backends: app.proxy(prefix, ...middleware, { backends, strategy })
+	obj.Set("proxy", func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 {
+			panic(tsa.engine.ToValue("path prefix and proxy options are required"))
 		}
-		
-		tsa.app.Put(path, func(ctx *runtime.Context) error {
-			tsCtx := tsa.createContextObject(ctx)
-			_, err := handlerFunc(nil, tsCtx)
-			return err
-		})
+		var middleware []runtime.Middleware
+		for _, mw := range call.Arguments[1 : len(call.Arguments)-1] {
+			middleware = append(middleware, tsa.wrapMiddleware(mw))
+		}
+		lb := tsa.loadBalancer(call.Arguments[len(call.Arguments)-1])
+		tsa.app.Proxy(call.Argument(0).String(), lb, middleware...)
+		return goja.Undefined()
 	})
 	
-	// Delete method
-	obj.Set("delete", func(path string, handler goja.Value) {
-		handlerFunc, ok := goja.AssertFunction(handler)
-		if !ok {
-			panic(tsa.engine.ToValue("handler must be a function"))
+	// ShedLoad method - reject requests with 503 once too many are in
+	// flight, lower priority routes first
+	obj.Set("shedLoad", func(options goja.Value) {
+		threshold := 0
+		policy := ""
+		if options != nil && !goja.IsUndefined(options) && !goja.IsNull(options) {
+			optsObj := options.ToObject(tsa.engine)
+			if v := optsObj.Get("threshold"); v != nil && !goja.IsUndefined(v) {
+				threshold = int(v.ToInteger())
+			}
+			if v := optsObj.Get("policy"); v != nil && !goja.IsUndefined(v) {
+				policy = v.String()
+			}
+		}
+		if threshold <= 0 {
+			panic(tsa.engine.ToValue("shedLoad requires a positive threshold"))
 		}
 		
-		tsa.app.Delete(path, func(ctx *runtime.Context) error {
-			tsCtx := tsa.createContextObject(ctx)
-			_, err := handlerFunc(nil, tsCtx)
-			return err
-		})
+		tsa.mu.RLock()
+		factory := tsa.shedderFactory
+		tsa.mu.RUnlock()
+		if factory == nil {
+			panic(tsa.engine.ToValue("load shedding is not supported"))
+		}
+		shedder, err := factory(threshold, policy)
+		if err != nil {
+			panic(tsa.engine.ToValue(err.Error()))
+		}
+		tsa.app.SetLoadShedder(shedder)
 	})
 	
 	// OnStart method
@@ -189,69 +243,464 @@ func (tsa *TypeScriptApp) ToJSObject() *goja.Object {
 		return promise
 	})
 	
-	// Listen method
-	obj.Set("listen", func(port int, callback goja.Value) {
+	// Close method - stop accepting connections, wait for in-flight
+	// requests to finish and then run the stop hooks
+	obj.Set("close", func() *goja.Promise {
+		promise, resolve, reject := tsa.engine.NewPromise()
+		settle := func(err error) {
+			if err != nil {
+				reject(tsa.engine.NewGoError(err))
+			} else {
+				resolve(goja.Undefined())
+			}
+		}
+		
 		tsa.mu.Lock()
-		if tsa.server == nil {
-			addr := fmt.Sprintf(":%d", port)
-			tsa.server = tsa.httpAPI.NewServer(addr)
-			
-			// Register app handler
-			tsa.server.Handle("/", func(req *api.Request) (*api.Response, error) {
-				// Convert API request to framework request
-				fwReq := &runtime.Request{
-					Method:  req.Method,
-					Path:    req.URL,
-					Headers: req.Headers,
-					Body:    req.Body,
-					Query:   req.Query,
-					Params:  req.Params,
+		server := tsa.server
+		tsa.server = nil
+		tsa.mu.Unlock()
+		
+		if server == nil {
+			settle(tsa.app.Stop())
+			return promise
+		}
+		server.Shutdown(context.Background(), func(err error) {
+			if err != nil {
+				settle(fmt.Errorf("failed to shut down server: %w", err))
+				return
+			}
+			settle(tsa.app.Stop())
+		})
+		return promise
+	})
+	
+	// SetDevMode method - include internal error details in error responses
+	obj.Set("setDevMode", func(dev bool) {
+		tsa.app.SetDevMode(dev)
+	})
+	
+	// Metrics method
+	obj.Set("metrics", func() map[string]interface{} {
+		metrics := tsa.app.Metrics()
+		return map[string]interface{}{
+			"totalRequests":  metrics.TotalRequests,
+			"totalErrors":    metrics.TotalErrors,
+			"averageLatency": metrics.AverageLatency,
+			"requestCounts":  metrics.RequestCounts,
+			"errorCounts":    metrics.ErrorCounts,
+		}
+	})
+	
+	// Listen method; the returned promise resolves with the bound port,
+	// the assigned one when port is 0, once the server accepts connections
+	// and rejects if binding fails. callback(undefined, port) is called
+	// once the server is bound, and callback(err) if binding or serving
+	// fails.
+	obj.Set("listen", func(port int, callback goja.Value) *goja.Promise {
+		promise, resolve, reject := tsa.engine.NewPromise()
+		callbackFunc, _ := goja.AssertFunction(callback)
+		report := func(args ...goja.Value) {
+			if callbackFunc != nil {
+				_, _ = callbackFunc(nil, args...)
+			}
+		}
+		fail := func(err error) *goja.Promise {
+			report(tsa.engine.ToValue(err.Error()))
+			reject(tsa.engine.NewGoError(err))
+			return promise
+		}
+		
+		tsa.mu.Lock()
+		if tsa.server != nil {
+			tsa.mu.Unlock()
+			return fail(fmt.Errorf("app is already listening"))
+		}
+		server := tsa.httpAPI.NewServerWithOptions(fmt.Sprintf(":%d", port), tsa.serverOptions)
+		
+		// Register app handler
+		server.SetAdmission(tsa.admit)
+		server.Handle("/", tsa.serveHTTP)
+		
+		addr, err := server.Listen(func(err error) {
+			if err != nil && err != http.ErrServerClosed {
+				report(tsa.engine.ToValue(err.Error()))
+			}
+		})
+		if err != nil {
+			tsa.mu.Unlock()
+			return fail(err)
+		}
+		tsa.server = server
+		tsa.mu.Unlock()
+		
+		boundPort := tsa.engine.ToValue(addr.(*net.TCPAddr).Port)
+		report(goja.Undefined(), boundPort)
+		resolve(boundPort)
+		return promise
+	})
+	
+	return obj
+}
+
+// SetSchemaCompiler sets the compiler for route schemas; routes given a
+// schema are rejected until one is set
+func (tsa *TypeScriptApp) SetSchemaCompiler(compiler SchemaCompiler) {
+	tsa.mu.Lock()
+	defer tsa.mu.Unlock()
+	tsa.schemaCompiler = compiler
+}
+
+// SetShedderFactory sets the factory of the load shedders of
+// app.shedLoad, which throws until one is set
+func (tsa *TypeScriptApp) SetShedderFactory(factory ShedderFactory) {
+	tsa.mu.Lock()
+	defer tsa.mu.Unlock()
+	tsa.shedderFactory = factory
+}
+
+// SetServerOptions sets how the server started by app.listen binds its
+// port
+func (tsa *TypeScriptApp) SetServerOptions(opts api.ServerOptions) {
+	tsa.mu.Lock()
+	defer tsa.mu.Unlock()
+	tsa.serverOptions = opts
+}
+
+// admit asks the app's load shedder whether to handle a request received
+// by its HTTP server, before the request waits for the event loop
+func (tsa *TypeScriptApp) admit(req *api.Request) (func(), *api.Response) {
+	fwResp := &runtime.Response{
+		Status:  200,
+		Headers: make(map[string]string),
+	}
+	fwCtx := &runtime.Context{
+		Request:  &runtime.Request{Method: req.Method, Path: req.URL, Headers: req.Headers},
+		Response: fwResp,
+		App:      tsa.app,
+		Data:     make(map[string]interface{}),
+	}
+	done, ok := tsa.app.Admit(fwCtx)
+	if ok {
+		return done, nil
+	}
+	return nil, &api.Response{
+		Status:  fwResp.Status,
+		Headers: fwResp.Headers,
+		Body:    fwResp.Body,
+	}
+}
+
+// serveHTTP runs the app for a request received by its HTTP server. The
+// app handles the request off the event loop, in the server's goroutine,
+// so that it can wait for asynchronous handlers; TypeScript middleware and
+// handlers are called on the loop.
+func (tsa *TypeScriptApp) serveHTTP(req *api.Request) (*api.Response, error) {
+	return &api.Response{Deferred: func() (*api.Response, error) {
+		return tsa.handle(req), nil
+	}}, nil
+}
+
+// handle runs the app for a request
+func (tsa *TypeScriptApp) handle(req *api.Request) *api.Response {
+	// Convert API request to framework request
+	fwReq := &runtime.Request{
+		Method:     req.Method,
+		Path:       req.URL,
+		Headers:    req.Headers,
+		Body:       req.Body,
+		Query:      req.Query,
+		Params:     req.Params,
+		RawQuery:   req.RawQuery,
+		Host:       req.Host,
+		RemoteAddr: req.RemoteAddr,
+		TLS:        req.TLS,
+	}
+	
+	fwResp := &runtime.Response{
+		Status:  200,
+		Headers: make(map[string]string),
+		Body:    []byte{},
+	}
+	
+	fwCtx := &runtime.Context{
+		Request:  fwReq,
+		Response: fwResp,
+		App:      tsa.app,
+		Data:     make(map[string]interface{}),
+	}
+	if req.Context != nil {
+		fwCtx.SetContext(req.Context)
+	}
+	
+	// Errors are rendered into the response by the app's error handler
+	_ = tsa.app.Handle(fwCtx)
+	
+	// Complete the response, e.g. of proxied requests
+	if fwResp.Deferred != nil {
+		_ = fwResp.Deferred()
+	}
+	return &api.Response{
+		Status:  fwResp.Status,
+		Headers: fwResp.Headers,
+		Body:    fwResp.Body,
+		Cookies: fwResp.Cookies,
+		Stream:  fwResp.Stream,
+	}
+}
+
+// loadBalancer creates the load balancer of app.proxy from its options:
+// { backends, strategy, healthCheckInterval }
+func (tsa *TypeScriptApp) loadBalancer(options goja.Value) *loadbalancer.LoadBalancer {
+	if options == nil || goja.IsUndefined(options) || goja.IsNull(options) {
+		panic(tsa.engine.ToValue("proxy options with backends are required"))
+	}
+	optsObj := options.ToObject(tsa.engine)
+	
+	strategy := loadbalancer.StrategyRoundRobin
+	if v := optsObj.Get("strategy"); v != nil && !goja.IsUndefined(v) {
+		s, err := loadbalancer.ParseStrategy(v.String())
+		if err != nil {
+			panic(tsa.engine.ToValue(err.Error()))
+		}
+		strategy = s
+	}
+	lb := loadbalancer.NewLoadBalancer(strategy)
+	
+	backends, ok := optsObj.Get("backends").(*goja.Object)
+	if !ok || backends.ClassName() != "Array" || backends.Get("length").ToInteger() == 0 {
+		panic(tsa.engine.ToValue("proxy backends must be a non-empty array"))
+	}
+	for i := int64(0); i < backends.Get("length").ToInteger(); i++ {
+		backend := backends.Get(fmt.Sprint(i))
+		url, weight := backend.String(), 1
+		if backendObj, isObj := backend.(*goja.Object); isObj {
+			url = backendObj.Get("url").String()
+			if w := backendObj.Get("weight"); w != nil && !goja.IsUndefined(w) {
+				weight = int(w.ToInteger())
+			}
+		}
+		lb.AddBackend(loadbalancer.NewBackend(url, weight))
+	}
+	
+	if v := optsObj.Get("healthCheckInterval"); v != nil && !goja.IsUndefined(v) {
+		interval := time.Duration(v.ToInteger()) * time.Millisecond
+		if interval <= 0 {
+			panic(tsa.engine.ToValue("healthCheckInterval must be positive"))
+		}
+		lb.StartHealthChecks(interval)
+		tsa.app.OnStop(func() error {
+			lb.StopHealthChecks()
+			return nil
+		})
+	}
+	return lb
+}
+
+// wrapMiddleware converts a TypeScript middleware function to Go middleware.
+// The middleware is called on the event loop; when it returns a promise,
+// the chain waits for it to settle and a rejection is the middleware's
+// error. next runs the rest of the chain off the loop and returns a
+// promise of its completion. Without a returned promise to await it, the
+// middleware's call ends once next does, with next's error.
+func (tsa *TypeScriptApp) wrapMiddleware(middleware goja.Value) runtime.Middleware {
+	mwFunc, ok := goja.AssertFunction(middleware)
+	if !ok {
+		panic(tsa.engine.ToValue("middleware must be a function"))
+	}
+	
+	return func(ctx *runtime.Context, next runtime.Next) error {
+		// Accessed on the event loop only
+		var (
+			res        *responseObject
+			thenable   bool
+			settled    bool
+			mwErr      error
+			nextCalled bool
+			nextDone   bool
+			nextErr    error
+			completed  bool
+		)
+		
+		return tsa.callOnLoop(ctx, func(call *loopCall) {
+			// complete finishes the call once the middleware has settled
+			// and next, if it was called, has returned. Settling next's
+			// promise may settle the middleware's, so it can be reached
+			// twice.
+			complete := func() {
+				if completed || !settled || (nextCalled && !nextDone) {
+					return
 				}
-				
-				fwResp := &runtime.Response{
-					Status:  200,
-					Headers: make(map[string]string),
-					Body:    []byte{},
+				completed = true
+				err := mwErr
+				if err == nil && !thenable {
+					err = nextErr
 				}
-				
-				fwCtx := &runtime.Context{
-					Request:  fwReq,
-					Response: fwResp,
-					App:      tsa.app,
-					Data:     make(map[string]interface{}),
+				if err == nil {
+					res.sync(ctx)
 				}
-				
-				if err := tsa.app.Handle(fwCtx); err != nil {
-					return nil, err
+				call.finish(err)
+			}
+			
+			tsCtx, r := tsa.createContextObject(ctx, call)
+			res = r
+			
+			// next() is a function, as in other frameworks; it returns a
+			// promise of the rest of the chain
+			nextFunc := func() *goja.Promise {
+				promise, resolve, reject := tsa.engine.NewPromise()
+				if nextCalled {
+					reject(tsa.engine.NewGoError(fmt.Errorf("next() called more than once")))
+					return promise
 				}
+				nextCalled = true
 				
-				return &api.Response{
-					Status:  fwResp.Status,
-					Headers: fwResp.Headers,
-					Body:    fwResp.Body,
-				}, nil
+				// The rest of the chain sees the response so far and the
+				// middleware sees the response it produced
+				res.sync(ctx)
+				go func() {
+					err := next()
+					_ = tsa.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+						nextDone, nextErr = true, err
+						res.refresh(ctx)
+						if err != nil {
+							reject(tsa.errorValue(err))
+						} else {
+							resolve(goja.Undefined())
+						}
+						complete()
+						return nil
+					}, 0))
+				}()
+				return promise
+			}
+			
+			result, err := mwFunc(nil, tsCtx, tsa.engine.ToValue(nextFunc))
+			if err != nil {
+				settled, mwErr = true, middlewareError(toHTTPError(err))
+				complete()
+				return
+			}
+			thenable = isThenable(result)
+			tsa.whenSettled(result, func(_ goja.Value, err error) {
+				settled, mwErr = true, middlewareError(err)
+				complete()
 			})
+		})
+	}
+}
+
+// routeMethod creates a TypeScript route registration function for
+// register, which registers routes for method. The last argument is the
+// handler and any arguments between the path and the handler are
+// route-specific middleware, or an object of route options: a schema that
+// validates the request and the route's load shedding priority, as in
+// app.post(path, { body: schema, priority: 'high' }, handler).
+func (tsa *TypeScriptApp) routeMethod(method string, register func(string, runtime.Handler, ...runtime.Middleware)) func(goja.FunctionCall) goja.Value {
+	return func(call goja.FunctionCall) goja.Value {
+		if len(call.Arguments) < 2 {
+			panic(tsa.engine.ToValue("path and handler are required"))
+		}
+		
+		path := call.Argument(0).String()
+		handler := call.Arguments[len(call.Arguments)-1]
+		handlerFunc, ok := goja.AssertFunction(handler)
+		if !ok {
+			panic(tsa.engine.ToValue("handler must be a function"))
 		}
-		tsa.mu.Unlock()
 		
-		tsa.server.ListenAndServe(func(err error) {
-			if callback != nil {
-				if callable, ok := goja.AssertFunction(callback); ok {
-					if err != nil {
-						_, _ = callable(nil, tsa.engine.ToValue(err.Error()))
-					} else {
-						_, _ = callable(nil, goja.Undefined())
+		var middleware []runtime.Middleware
+		priority := runtime.PriorityNormal
+		for _, mw := range call.Arguments[1 : len(call.Arguments)-1] {
+			if _, isFunc := goja.AssertFunction(mw); !isFunc {
+				if optsObj, isObj := mw.(*goja.Object); isObj {
+					var schema goja.Value
+					priority, schema = tsa.routeOptions(optsObj, priority)
+					if schema != nil {
+						middleware = append(middleware, tsa.validateMiddleware(schema))
 					}
+					continue
 				}
 			}
+			middleware = append(middleware, tsa.wrapMiddleware(mw))
+		}
+		
+		register(path, tsa.wrapHandler(handlerFunc), middleware...)
+		if priority != runtime.PriorityNormal {
+			if err := tsa.app.SetRoutePriority(method, path, priority); err != nil {
+				panic(tsa.engine.ToValue(err.Error()))
+			}
+		}
+		return goja.Undefined()
+	}
+}
+
+// wrapHandler converts a TypeScript route handler to a Go handler. The
+// handler is called on the event loop; when it returns a promise, the
+// request waits for it to settle and a rejection is the handler's error. A
+// value it returns or resolves to is the response body, unless it set one.
+func (tsa *TypeScriptApp) wrapHandler(handlerFunc goja.Callable) runtime.Handler {
+	return func(ctx *runtime.Context) error {
+		return tsa.callOnLoop(ctx, func(call *loopCall) {
+			tsCtx, res := tsa.createContextObject(ctx, call)
+			result, err := handlerFunc(nil, tsCtx)
+			if err != nil {
+				call.finish(toHTTPError(err))
+				return
+			}
+			tsa.whenSettled(result, func(value goja.Value, err error) {
+				if err == nil {
+					if err = res.send(ctx, value); err == nil {
+						res.sync(ctx)
+					}
+				}
+				call.finish(err)
+			})
 		})
-	})
+	}
+}
+
+// routeOptions splits route options into the route's priority, defaulting
+// to priority, and its schema, which is nil when only a priority is given
+func (tsa *TypeScriptApp) routeOptions(options *goja.Object, priority runtime.Priority) (runtime.Priority, goja.Value) {
+	schema := tsa.engine.NewObject()
+	hasSchema := false
+	for _, key := range options.Keys() {
+		if key == "priority" {
+			p, err := runtime.ParsePriority(options.Get(key).String())
+			if err != nil {
+				panic(tsa.engine.ToValue(err.Error()))
+			}
+			priority = p
+			continue
+		}
+		schema.Set(key, options.Get(key))
+		hasSchema = true
+	}
+	if !hasSchema {
+		return priority, nil
+	}
+	return priority, schema
+}
+
+// validateMiddleware compiles a route schema into validation middleware
+func (tsa *TypeScriptApp) validateMiddleware(schema goja.Value) runtime.Middleware {
+	tsa.mu.RLock()
+	compiler := tsa.schemaCompiler
+	tsa.mu.RUnlock()
+	if compiler == nil {
+		panic(tsa.engine.ToValue("route schemas are not supported"))
+	}
 	
-	return obj
+	compiled, err := compiler(schema)
+	if err != nil {
+		panic(tsa.engine.ToValue(err.Error()))
+	}
+	return runtime.ValidateMiddleware(compiled)
 }
 
 // createContextObject creates a TypeScript context object from Go context
-func (tsa *TypeScriptApp) createContextObject(ctx *runtime.Context) *goja.Object {
+// for call, and its response object
+func (tsa *TypeScriptApp) createContextObject(ctx *runtime.Context, call *loopCall) (*goja.Object, *responseObject) {
 	ctxObj := tsa.engine.NewObject()
 	
 	// Request object
@@ -264,12 +713,34 @@ func (tsa *TypeScriptApp) createContextObject(ctx *runtime.Context) *goja.Object
 	reqObj.Set("params", tsa.engine.ToValue(ctx.Request.Params))
 	ctxObj.Set("request", reqObj)
 	
-	// Response object
-	respObj := tsa.engine.NewObject()
-	respObj.Set("status", ctx.Response.Status)
-	respObj.Set("headers", tsa.engine.ToValue(ctx.Response.Headers))
-	respObj.Set("body", tsa.engine.ToValue(string(ctx.Response.Body)))
-	ctxObj.Set("response", respObj)
+	// Response object, copied to the Go response when the call settles
+	res := tsa.createResponseObject(ctx, call)
+	ctxObj.Set("response", res.obj)
+	
+	// Cookies object
+	ctxObj.Set("cookies", tsa.createCookiesObject(ctx))
+	
+	// Session object, when session middleware is in use
+	if session := ctx.Session(); session != nil {
+		ctxObj.Set("session", tsa.createSessionObject(session))
+	}
+	
+	// Abort signal for the request's Go context
+	ctxObj.Set("signal", tsa.createSignalObject(ctx.Context()))
+	
+	// Logger whose entries carry the request ID
+	ctxObj.Set("log", tsa.createLogObject(ctx, nil))
+	
+	// Timing breakdown, when app.useTiming() is in use
+	ctxObj.Set("timing", tsa.createTimingObject(ctx))
+	
+	// Streaming responses
+	ctxObj.Set("stream", func(callback goja.Value) {
+		tsa.startStream(ctx, callback)
+	})
+	ctxObj.Set("sse", func() *goja.Object {
+		return tsa.createSSEObject(ctx.SSE())
+	})
 	
 	// Data object
 	ctxObj.Set("data", tsa.engine.ToValue(ctx.Data))
@@ -289,6 +760,199 @@ func (tsa *TypeScriptApp) createContextObject(ctx *runtime.Context) *goja.Object
 		return tsa.engine.ToValue(value)
 	})
 	
-	return ctxObj
+	return ctxObj, res
+}
+
+// startStream switches the response to a stream and calls
+// callback(write, end). write returns false once the client has gone away;
+// end optionally writes a last chunk and finishes the response.
+func (tsa *TypeScriptApp) startStream(ctx *runtime.Context, callback goja.Value) {
+	callbackFunc, ok := goja.AssertFunction(callback)
+	if !ok {
+		panic(tsa.engine.ToValue("stream callback must be a function"))
+	}
+	
+	stream := ctx.Stream()
+	write := func(chunk goja.Value) bool {
+		return stream.Write(tsa.chunkBytes(chunk)) == nil
+	}
+	end := func(chunk goja.Value) {
+		if chunk != nil && !goja.IsUndefined(chunk) && !goja.IsNull(chunk) {
+			_ = stream.Write(tsa.chunkBytes(chunk))
+		}
+		stream.End()
+	}
+	
+	if _, err := callbackFunc(goja.Undefined(), tsa.engine.ToValue(write), tsa.engine.ToValue(end)); err != nil {
+		stream.End()
+		panic(err)
+	}
+}
+
+// createSSEObject creates the object returned by ctx.sse() for sending
+// server-sent events
+func (tsa *TypeScriptApp) createSSEObject(stream *api.Stream) *goja.Object {
+	sseObj := tsa.engine.NewObject()
+	
+	// send(data, event?) sends an event; data that is not a string is sent
+	// as JSON. Returns false once the client has gone away.
+	sseObj.Set("send", func(data goja.Value, event goja.Value) bool {
+		eventName := ""
+		if event != nil && !goja.IsUndefined(event) && !goja.IsNull(event) {
+			eventName = event.String()
+		}
+		
+		var payload string
+		if s, ok := data.Export().(string); ok {
+			payload = s
+		} else {
+			encoded, err := json.Marshal(data.Export())
+			if err != nil {
+				panic(tsa.engine.ToValue(fmt.Sprintf("failed to encode event data: %v", err)))
+			}
+			payload = string(encoded)
+		}
+		return stream.WriteEvent(eventName, payload) == nil
+	})
+	
+	sseObj.Set("close", func() {
+		stream.End()
+	})
+	
+	sseObj.Set("closed", func() bool {
+		select {
+		case <-stream.Done():
+			return true
+		default:
+			return false
+		}
+	})
+	
+	return sseObj
+}
+
+// chunkBytes converts a stream chunk (string, ArrayBuffer or typed array)
+// to bytes
+func (tsa *TypeScriptApp) chunkBytes(chunk goja.Value) []byte {
+	switch v := chunk.Export().(type) {
+	case []byte:
+		return v
+	case goja.ArrayBuffer:
+		return v.Bytes()
+	default:
+		return []byte(chunk.String())
+	}
+}
+
+// createCookiesObject creates the ctx.cookies object for reading request
+// cookies and setting response cookies
+func (tsa *TypeScriptApp) createCookiesObject(ctx *runtime.Context) *goja.Object {
+	cookiesObj := tsa.engine.NewObject()
+	
+	cookiesObj.Set("get", func(name string) goja.Value {
+		value, ok := ctx.Cookie(name)
+		if !ok {
+			return goja.Undefined()
+		}
+		return tsa.engine.ToValue(value)
+	})
+	
+	cookiesObj.Set("all", func() map[string]string {
+		return ctx.Cookies()
+	})
+	
+	cookiesObj.Set("set", func(name, value string, options goja.Value) {
+		ctx.SetCookie(name, value, tsa.cookieOptions(options))
+	})
+	
+	cookiesObj.Set("delete", func(name string, options goja.Value) {
+		ctx.DeleteCookie(name, tsa.cookieOptions(options))
+	})
+	
+	return cookiesObj
 }
 
+// cookieOptions reads cookie options from a TypeScript object
+func (tsa *TypeScriptApp) cookieOptions(options goja.Value) runtime.CookieOptions {
+	var opts runtime.CookieOptions
+	if options == nil || goja.IsUndefined(options) || goja.IsNull(options) {
+		return opts
+	}
+	
+	obj := options.ToObject(tsa.engine)
+	get := func(key string) goja.Value {
+		value := obj.Get(key)
+		if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+			return nil
+		}
+		return value
+	}
+	
+	if v := get("path"); v != nil {
+		opts.Path = v.String()
+	}
+	if v := get("domain"); v != nil {
+		opts.Domain = v.String()
+	}
+	if v := get("maxAge"); v != nil {
+		opts.MaxAge = int(v.ToInteger())
+	}
+	if v := get("expires"); v != nil {
+		if t, ok := v.Export().(time.Time); ok {
+			opts.Expires = t
+		} else {
+			opts.Expires = time.UnixMilli(v.ToInteger())
+		}
+	}
+	if v := get("httpOnly"); v != nil {
+		opts.HttpOnly = v.ToBoolean()
+	}
+	if v := get("secure"); v != nil {
+		opts.Secure = v.ToBoolean()
+	}
+	if v := get("sameSite"); v != nil {
+		opts.SameSite = v.String()
+	}
+	return opts
+}
+
+// createSessionObject creates the ctx.session object
+func (tsa *TypeScriptApp) createSessionObject(session *runtime.Session) *goja.Object {
+	sessionObj := tsa.engine.NewObject()
+	
+	sessionObj.Set("id", func() string {
+		return session.ID()
+	})
+	
+	sessionObj.Set("get", func(key string) goja.Value {
+		value, ok := session.Get(key)
+		if !ok {
+			return goja.Undefined()
+		}
+		return tsa.engine.ToValue(value)
+	})
+	
+	sessionObj.Set("set", func(key string, value goja.Value) {
+		session.Set(key, value.Export())
+	})
+	
+	sessionObj.Set("delete", func(key string) {
+		session.Delete(key)
+	})
+	
+	sessionObj.Set("all", func() map[string]interface{} {
+		return session.Values()
+	})
+	
+	sessionObj.Set("regenerate", func() {
+		if err := session.Regenerate(); err != nil {
+			panic(tsa.engine.ToValue(err.Error()))
+		}
+	})
+	
+	sessionObj.Set("destroy", func() {
+		session.Destroy()
+	})
+	
+	return sessionObj
+}
@@ -0,0 +1,66 @@
+// Package graph provides algorithms on dependency graphs
+package graph
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CycleError reports a circular dependency
+type CycleError struct {
+	Cycle []string // the nodes of the cycle, starting and ending with the same node
+}
+
+// Error returns the cycle as "a -> b -> a"
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("circular dependency: %s", strings.Join(e.Cycle, " -> "))
+}
+
+// TopologicalSort orders nodes so that each node comes after the nodes it
+// depends on; deps maps a node to its dependencies, which must all be in
+// nodes. Nodes that are not ordered by their dependencies keep their order
+// in nodes. A circular dependency is reported as a *CycleError.
+func TopologicalSort(nodes []string, deps map[string][]string) ([]string, error) {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int, len(nodes))
+	result := make([]string, 0, len(nodes))
+	var path []string
+
+	var visit func(node string) error
+	visit = func(node string) error {
+		switch state[node] {
+		case done:
+			return nil
+		case visiting:
+			for i, n := range path {
+				if n == node {
+					cycle := append([]string(nil), path[i:]...)
+					return &CycleError{Cycle: append(cycle, node)}
+				}
+			}
+		}
+
+		state[node] = visiting
+		path = append(path, node)
+		for _, dep := range deps[node] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[node] = done
+		result = append(result, node)
+		return nil
+	}
+
+	for _, node := range nodes {
+		if err := visit(node); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
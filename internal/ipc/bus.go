@@ -0,0 +1,180 @@
+package ipc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/federation"
+)
+
+// BusHandler handles a message published on a topic
+type BusHandler func(topic string, payload interface{})
+
+// busSubscription represents a topic subscription
+type busSubscription struct {
+	id      string
+	pattern []string
+	handler BusHandler
+}
+
+// federationBusMessage is the federation message type used to bridge buses
+const federationBusMessage = "bus.publish"
+
+// busEnvelope is the payload of a bridged bus message
+type busEnvelope struct {
+	Topic   string      `json:"topic"`
+	Payload interface{} `json:"payload"`
+}
+
+// EventBus provides in-process publish/subscribe messaging.
+// Topics are dot-separated; in subscription patterns "*" matches exactly one
+// segment and "**" matches any number of segments (e.g. "orders.*").
+type EventBus struct {
+	eventLoop     *eventloop.Loop
+	subscriptions map[string]*busSubscription
+	federation    *federation.Federation
+	mu            sync.RWMutex
+	idGen         uint64
+}
+
+// NewEventBus creates a new event bus delivering messages on the event loop
+func NewEventBus(eventLoop *eventloop.Loop) *EventBus {
+	return &EventBus{
+		eventLoop:     eventLoop,
+		subscriptions: make(map[string]*busSubscription),
+	}
+}
+
+// Subscribe subscribes a handler to a topic pattern and returns its handle
+func (b *EventBus) Subscribe(pattern string, handler BusHandler) (string, error) {
+	if pattern == "" {
+		return "", fmt.Errorf("topic pattern is required")
+	}
+	if handler == nil {
+		return "", fmt.Errorf("handler is required")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.idGen++
+	id := fmt.Sprintf("sub-%d", b.idGen)
+	b.subscriptions[id] = &busSubscription{
+		id:      id,
+		pattern: strings.Split(pattern, "."),
+		handler: handler,
+	}
+	return id, nil
+}
+
+// Unsubscribe removes a subscription by handle
+func (b *EventBus) Unsubscribe(handle string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscriptions[handle]; !ok {
+		return false
+	}
+	delete(b.subscriptions, handle)
+	return true
+}
+
+// Publish delivers a message to local subscribers and, when bridged, to
+// federated nodes. It returns the number of local subscribers notified.
+func (b *EventBus) Publish(topic string, payload interface{}) (int, error) {
+	delivered, err := b.PublishLocal(topic, payload)
+	if err != nil {
+		return delivered, err
+	}
+
+	b.mu.RLock()
+	fed := b.federation
+	b.mu.RUnlock()
+
+	if fed != nil {
+		if err := fed.Broadcast(federationBusMessage, &busEnvelope{Topic: topic, Payload: payload}); err != nil {
+			return delivered, fmt.Errorf("failed to bridge message: %w", err)
+		}
+	}
+
+	return delivered, nil
+}
+
+// PublishLocal delivers a message to local subscribers only
+func (b *EventBus) PublishLocal(topic string, payload interface{}) (int, error) {
+	if topic == "" {
+		return 0, fmt.Errorf("topic is required")
+	}
+	segments := strings.Split(topic, ".")
+
+	b.mu.RLock()
+	matched := make([]*busSubscription, 0)
+	for _, sub := range b.subscriptions {
+		if matchTopic(sub.pattern, segments) {
+			matched = append(matched, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range matched {
+		handler := sub.handler
+		err := b.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+			handler(topic, payload)
+			return nil
+		}, eventloop.PriorityNormal))
+		if err != nil {
+			return 0, fmt.Errorf("failed to deliver message: %w", err)
+		}
+	}
+
+	return len(matched), nil
+}
+
+// BridgeFederation fans published messages out to federated nodes and
+// delivers messages published on other nodes to local subscribers
+func (b *EventBus) BridgeFederation(fed *federation.Federation) {
+	b.mu.Lock()
+	b.federation = fed
+	b.mu.Unlock()
+
+	fed.RegisterHandler(federationBusMessage, func(ctx context.Context, msg *federation.FederationMessage) (*federation.FederationMessage, error) {
+		var envelope busEnvelope
+		if err := json.Unmarshal(msg.Payload, &envelope); err != nil {
+			return nil, fmt.Errorf("invalid bus message: %w", err)
+		}
+		_, err := b.PublishLocal(envelope.Topic, envelope.Payload)
+		return nil, err
+	})
+}
+
+// SubscriptionCount returns the number of active subscriptions
+func (b *EventBus) SubscriptionCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscriptions)
+}
+
+// matchTopic reports whether topic segments match a subscription pattern
+func matchTopic(pattern, topic []string) bool {
+	if len(pattern) == 0 {
+		return len(topic) == 0
+	}
+
+	switch pattern[0] {
+	case "**":
+		for i := 0; i <= len(topic); i++ {
+			if matchTopic(pattern[1:], topic[i:]) {
+				return true
+			}
+		}
+		return false
+	case "*":
+		return len(topic) > 0 && matchTopic(pattern[1:], topic[1:])
+	default:
+		return len(topic) > 0 && pattern[0] == topic[0] && matchTopic(pattern[1:], topic[1:])
+	}
+}
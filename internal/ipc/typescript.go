@@ -0,0 +1,55 @@
+package ipc
+
+import (
+	"github.com/dop251/goja"
+)
+
+// TypeScriptEventBus wraps EventBus for TypeScript
+type TypeScriptEventBus struct {
+	bus    *EventBus
+	engine *goja.Runtime
+}
+
+// NewTypeScriptEventBus creates a new TypeScript-wrapped event bus
+func NewTypeScriptEventBus(engine *goja.Runtime, bus *EventBus) *TypeScriptEventBus {
+	return &TypeScriptEventBus{
+		bus:    bus,
+		engine: engine,
+	}
+}
+
+// ToJSObject converts the event bus to a JavaScript object
+func (tb *TypeScriptEventBus) ToJSObject() *goja.Object {
+	obj := tb.engine.NewObject()
+
+	// Subscribe method returns a handle for unsubscribe
+	obj.Set("subscribe", func(topic string, handler goja.Callable) string {
+		handle, err := tb.bus.Subscribe(topic, func(topic string, payload interface{}) {
+			_, _ = handler(nil, tb.engine.ToValue(payload), tb.engine.ToValue(topic))
+		})
+		if err != nil {
+			panic(tb.engine.NewGoError(err))
+		}
+		return handle
+	})
+
+	// Publish method returns the number of local subscribers notified
+	obj.Set("publish", func(topic string, payload goja.Value) int {
+		var data interface{}
+		if payload != nil && !goja.IsUndefined(payload) {
+			data = payload.Export()
+		}
+		delivered, err := tb.bus.Publish(topic, data)
+		if err != nil {
+			panic(tb.engine.NewGoError(err))
+		}
+		return delivered
+	})
+
+	// Unsubscribe method
+	obj.Set("unsubscribe", func(handle string) bool {
+		return tb.bus.Unsubscribe(handle)
+	})
+
+	return obj
+}
@@ -1,9 +1,11 @@
 package loadbalancer
 
 import (
-	"context"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"sync"
 	"time"
 )
@@ -71,6 +73,22 @@ const (
 	StrategyIPHash
 )
 
+// ParseStrategy returns the strategy named "round-robin",
+// "least-connections", "weighted-round-robin" or "ip-hash"
+func ParseStrategy(name string) (Strategy, error) {
+	switch name {
+	case "round-robin":
+		return StrategyRoundRobin, nil
+	case "least-connections":
+		return StrategyLeastConnections, nil
+	case "weighted-round-robin":
+		return StrategyWeightedRoundRobin, nil
+	case "ip-hash":
+		return StrategyIPHash, nil
+	}
+	return 0, fmt.Errorf("unknown load balancing strategy: %s", name)
+}
+
 // NewLoadBalancer creates a new load balancer
 func NewLoadBalancer(strategy Strategy) *LoadBalancer {
 	return &LoadBalancer{
@@ -209,24 +227,77 @@ func (lb *LoadBalancer) ipHash(backends []*Backend, req *http.Request) (*Backend
 	return backends[hash%len(backends)], nil
 }
 
-// Proxy proxies a request to a backend
+// proxyClient forwards proxied requests. It has no overall timeout, so
+// that long responses can be streamed; requests end with their context.
+var proxyClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		ResponseHeaderTimeout: 30 * time.Second,
+		IdleConnTimeout:       90 * time.Second,
+	},
+	// Redirects are passed on to the client
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Proxy proxies a request to a backend. The backend counts as having an
+// active connection until the body of the response is closed.
 func (lb *LoadBalancer) Proxy(req *http.Request) (*http.Response, error) {
 	backend, err := lb.SelectBackend(req)
 	if err != nil {
 		return nil, err
 	}
+	target, err := backendURL(backend.URL)
+	if err != nil {
+		return nil, err
+	}
 	
 	backend.IncrementConn()
-	defer backend.DecrementConn()
 	
 	// Create new request to backend
-	backendReq := req.Clone(context.Background())
-	backendReq.URL.Scheme = "http"
-	backendReq.URL.Host = backend.URL
+	backendReq := req.Clone(req.Context())
+	backendReq.RequestURI = ""
+	backendReq.URL.Scheme = target.Scheme
+	backendReq.URL.Host = target.Host
+	backendReq.URL.Path = strings.TrimSuffix(target.Path, "/") + req.URL.Path
+	backendReq.URL.RawPath = ""
 	
 	// Forward request
-	client := &http.Client{Timeout: 30 * time.Second}
-	return client.Do(backendReq)
+	resp, err := proxyClient.Do(backendReq)
+	if err != nil {
+		backend.DecrementConn()
+		return nil, fmt.Errorf("backend %s: %w", backend.URL, err)
+	}
+	resp.Body = &connBody{ReadCloser: resp.Body, backend: backend}
+	return resp, nil
+}
+
+// backendURL parses the URL of a backend, which is either a URL such as
+// "http://10.0.0.1:8080" or a bare host and port
+func backendURL(raw string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = "http://" + raw
+	}
+	target, err := url.Parse(raw)
+	if err != nil || target.Host == "" {
+		return nil, fmt.Errorf("invalid backend URL %q", raw)
+	}
+	return target, nil
+}
+
+// connBody is the body of a proxied response, which releases the
+// backend's connection when closed
+type connBody struct {
+	io.ReadCloser
+	backend *Backend
+	once    sync.Once
+}
+
+// Close closes the body and releases the connection
+func (b *connBody) Close() error {
+	b.once.Do(b.backend.DecrementConn)
+	return b.ReadCloser.Close()
 }
 
 // StartHealthChecks starts health checking
@@ -3,6 +3,7 @@ package loadbalancer
 import (
 	"context"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 )
@@ -85,7 +86,12 @@ func (hc *HealthChecker) checkBackend(backend *Backend) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 	
-	req, err := http.NewRequestWithContext(ctx, "GET", backend.URL+"/health", nil)
+	target, err := backendURL(backend.URL)
+	if err != nil {
+		backend.SetHealthy(false)
+		return
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.TrimSuffix(target.String(), "/")+"/health", nil)
 	if err != nil {
 		backend.SetHealthy(false)
 		return
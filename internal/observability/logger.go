@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -21,6 +24,7 @@ const (
 type Logger struct {
 	level  LogLevel
 	logger *log.Logger
+	fields string // " key=value" pairs appended to the first line of every entry
 }
 
 // NewLogger creates a new logger
@@ -31,31 +35,72 @@ func NewLogger(level LogLevel) *Logger {
 	}
 }
 
+// With returns a logger writing to the same output at the same level that
+// adds fields, sorted by key, to every entry after those of l
+func (l *Logger) With(fields map[string]interface{}) *Logger {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(l.fields)
+	for _, key := range keys {
+		b.WriteString(" " + key + "=" + formatField(fields[key]))
+	}
+	return &Logger{level: l.level, logger: l.logger, fields: b.String()}
+}
+
+// formatField formats a field value, quoting strings that would be
+// ambiguous unquoted
+func formatField(value interface{}) string {
+	s := fmt.Sprint(value)
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// output writes an entry at level, with the logger's fields at the end of
+// its first line
+func (l *Logger) output(level string, format string, args []interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if l.fields != "" {
+		if i := strings.IndexByte(msg, '\n'); i >= 0 {
+			msg = msg[:i] + l.fields + msg[i:]
+		} else {
+			msg += l.fields
+		}
+	}
+	l.logger.Print("[" + level + "] " + msg)
+}
+
 // Debug logs a debug message
 func (l *Logger) Debug(format string, args ...interface{}) {
 	if l.level <= LogLevelDebug {
-		l.logger.Printf("[DEBUG] "+format, args...)
+		l.output("DEBUG", format, args)
 	}
 }
 
 // Info logs an info message
 func (l *Logger) Info(format string, args ...interface{}) {
 	if l.level <= LogLevelInfo {
-		l.logger.Printf("[INFO] "+format, args...)
+		l.output("INFO", format, args)
 	}
 }
 
 // Warn logs a warning message
 func (l *Logger) Warn(format string, args ...interface{}) {
 	if l.level <= LogLevelWarn {
-		l.logger.Printf("[WARN] "+format, args...)
+		l.output("WARN", format, args)
 	}
 }
 
 // Error logs an error message
 func (l *Logger) Error(format string, args ...interface{}) {
 	if l.level <= LogLevelError {
-		l.logger.Printf("[ERROR] "+format, args...)
+		l.output("ERROR", format, args)
 	}
 }
 
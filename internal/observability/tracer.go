@@ -26,17 +26,46 @@ type LogEntry struct {
 	Fields    map[string]interface{}
 }
 
+// DefaultMaxSpans is how many spans a tracer keeps by default; older spans
+// are dropped
+const DefaultMaxSpans = 10000
+
 // Tracer represents a distributed tracer
 type Tracer struct {
-	spans map[string]*Span
-	mu    sync.RWMutex
+	spans    map[string]*Span
+	order    []string // span IDs, oldest first
+	maxSpans int
+	mu       sync.RWMutex
 }
 
 // NewTracer creates a new tracer
 func NewTracer() *Tracer {
 	return &Tracer{
-		spans: make(map[string]*Span),
+		spans:    make(map[string]*Span),
+		maxSpans: DefaultMaxSpans,
+	}
+}
+
+// SetMaxSpans sets how many spans the tracer keeps; the oldest are dropped
+// once there are more
+func (t *Tracer) SetMaxSpans(max int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxSpans = max
+	t.trim()
+}
+
+// trim drops the oldest spans beyond maxSpans
+func (t *Tracer) trim() {
+	excess := len(t.order) - t.maxSpans
+	if excess <= 0 {
+		return
+	}
+	for _, spanID := range t.order[:excess] {
+		delete(t.spans, spanID)
 	}
+	// The dropped IDs are released when append next grows the slice
+	t.order = t.order[excess:]
 }
 
 // StartSpan starts a new span
@@ -62,6 +91,8 @@ func (t *Tracer) StartSpan(ctx context.Context, name string) (context.Context, *
 
 	t.mu.Lock()
 	t.spans[span.SpanID] = span
+	t.order = append(t.order, span.SpanID)
+	t.trim()
 	t.mu.Unlock()
 
 	// Add span to context
@@ -0,0 +1,102 @@
+package plugin
+
+import (
+	"fmt"
+	"sort"
+
+	"gots-runtime/internal/graph"
+)
+
+// DependentPlugin is implemented by plugins that depend on other plugins,
+// which are initialized before them
+type DependentPlugin interface {
+	Plugin
+	// Dependencies returns the names of the plugins the plugin depends on
+	Dependencies() []string
+}
+
+// pluginDependencies returns the dependencies of plugin, if it has any
+func pluginDependencies(plugin Plugin) []string {
+	if dp, ok := plugin.(DependentPlugin); ok {
+		return dp.Dependencies()
+	}
+	return nil
+}
+
+// InitializationOrder returns the names of the registered plugins in the
+// order they are initialized: each plugin after the plugins it depends on,
+// and otherwise in registration order. It fails if a dependency is not
+// registered or the dependencies are circular.
+func (pm *PluginManager) InitializationOrder() ([]string, error) {
+	return pm.initializationOrderWith(nil)
+}
+
+// initializationOrderWith returns the initialization order the plugins
+// would have if replacement, when not nil, replaced the registered plugin
+// of the same name
+func (pm *PluginManager) initializationOrderWith(replacement Plugin) ([]string, error) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	deps := make(map[string][]string, len(pm.order))
+	for _, name := range pm.order {
+		plugin := pm.plugins[name]
+		if replacement != nil && replacement.Name() == name {
+			plugin = replacement
+		}
+		deps[name] = pluginDependencies(plugin)
+	}
+	return dependencyOrder(pm.order, deps)
+}
+
+// LoadOrder returns the loaded plugins ordered by the dependencies listed
+// in their manifests, each plugin after the plugins it depends on
+func (pl *PluginLoader) LoadOrder() ([]*LoadedPlugin, error) {
+	pl.mu.RLock()
+	defer pl.mu.RUnlock()
+
+	names := make([]string, 0, len(pl.plugins))
+	deps := make(map[string][]string, len(pl.plugins))
+	for name, loaded := range pl.plugins {
+		if !loaded.Loaded {
+			continue
+		}
+		names = append(names, name)
+		deps[name] = loaded.Manifest.Dependencies
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := pl.plugins[names[i]], pl.plugins[names[j]]
+		if !a.LoadedAt.Equal(b.LoadedAt) {
+			return a.LoadedAt.Before(b.LoadedAt)
+		}
+		return a.Name < b.Name
+	})
+
+	order, err := dependencyOrder(names, deps)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*LoadedPlugin, len(order))
+	for i, name := range order {
+		result[i] = pl.plugins[name]
+	}
+	return result, nil
+}
+
+// dependencyOrder orders names by deps, failing clearly on dependencies
+// that are not in names and on cycles
+func dependencyOrder(names []string, deps map[string][]string) ([]string, error) {
+	for _, name := range names {
+		for _, dep := range deps[name] {
+			if _, ok := deps[dep]; !ok {
+				return nil, fmt.Errorf("plugin %s depends on %s, which is not registered", name, dep)
+			}
+		}
+	}
+
+	order, err := graph.TopologicalSort(names, deps)
+	if err != nil {
+		return nil, fmt.Errorf("invalid plugin dependencies: %w", err)
+	}
+	return order, nil
+}
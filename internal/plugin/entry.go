@@ -0,0 +1,170 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gots-runtime/internal/hotreload"
+
+	"github.com/dop251/goja"
+)
+
+// EntryLoader runs the entry point file of a plugin and returns its
+// exports. It runs the file again on every call, so that reloaded plugins
+// run its current code.
+type EntryLoader func(path string) (goja.Value, error)
+
+// SetEntryLoader sets how the entry points of plugins loaded with load()
+// are run
+func (tpm *TypeScriptPluginManager) SetEntryLoader(loader EntryLoader) {
+	tpm.mu.Lock()
+	defer tpm.mu.Unlock()
+	tpm.loadEntry = loader
+}
+
+// SetScheduler sets how work is run on the JavaScript goroutine from other
+// goroutines; it is needed to reload watched plugins
+func (tpm *TypeScriptPluginManager) SetScheduler(schedule func(task func()) error) {
+	tpm.mu.Lock()
+	defer tpm.mu.Unlock()
+	tpm.schedule = schedule
+}
+
+// load loads and registers the plugin at path, an entry point file or a
+// directory with a plugin.json manifest. If watch is set, the plugin is
+// reloaded whenever its files change.
+func (tpm *TypeScriptPluginManager) load(path string, watch bool) (Plugin, error) {
+	tpm.mu.RLock()
+	loadEntry := tpm.loadEntry
+	tpm.mu.RUnlock()
+	if loadEntry == nil {
+		return nil, fmt.Errorf("plugins cannot be loaded from files in this runtime")
+	}
+
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", path, err)
+	}
+	entry, manifest, err := resolvePluginEntry(absPath)
+	if err != nil {
+		return nil, err
+	}
+
+	factory := func() (Plugin, error) {
+		exports, err := loadEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		tsPlugin, err := tpm.newPlugin(pluginExport(exports))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry, err)
+		}
+		if manifest != nil && len(tsPlugin.Dependencies()) == 0 {
+			tsPlugin.dependencies = manifest.Dependencies
+		}
+		return tsPlugin, nil
+	}
+
+	plugin, err := factory()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load plugin %s: %w", path, err)
+	}
+	if err := tpm.manager.RegisterReloadable(plugin, factory); err != nil {
+		return nil, err
+	}
+
+	if watch {
+		if err := tpm.watch(plugin.Name(), absPath); err != nil {
+			return nil, err
+		}
+	}
+	return plugin, nil
+}
+
+// resolvePluginEntry returns the entry point file of the plugin at path and
+// its manifest, if path is a plugin directory
+func resolvePluginEntry(path string) (string, *PluginManifest, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("plugin not found: %s", path)
+	}
+	if !info.IsDir() {
+		return path, nil, nil
+	}
+
+	loader := NewPluginLoader(filepath.Dir(path))
+	manifest, err := loader.LoadManifest(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to load plugin %s: %w", path, err)
+	}
+	if err := loader.ValidatePlugin(manifest); err != nil {
+		return "", nil, fmt.Errorf("invalid plugin %s: %w", path, err)
+	}
+	return filepath.Join(path, manifest.EntryPoint), manifest, nil
+}
+
+// pluginExport returns the plugin object exported by an entry point, either
+// as module.exports or as its default export
+func pluginExport(exports goja.Value) goja.Value {
+	obj, ok := exports.(*goja.Object)
+	if !ok {
+		return exports
+	}
+	if _, ok := goja.AssertFunction(obj.Get("execute")); ok {
+		return obj
+	}
+	if def, ok := obj.Get("default").(*goja.Object); ok {
+		return def
+	}
+	return obj
+}
+
+// watch reloads the plugin name whenever a file under path changes
+func (tpm *TypeScriptPluginManager) watch(name, path string) error {
+	tpm.mu.Lock()
+	defer tpm.mu.Unlock()
+	if tpm.schedule == nil {
+		return fmt.Errorf("plugins cannot be watched in this runtime")
+	}
+	if _, ok := tpm.watchers[name]; ok {
+		return nil
+	}
+
+	schedule := tpm.schedule
+	reloader, err := hotreload.NewHotReloader(&hotreload.HotReloadConfig{
+		Watch: []string{path},
+		OnReload: func() error {
+			// Plugins run JavaScript, so they are reloaded on its goroutine
+			done := make(chan error, 1)
+			if err := schedule(func() {
+				done <- tpm.manager.Reload(name)
+			}); err != nil {
+				return err
+			}
+			return <-done
+		},
+		OnError: func(err error) {
+			fmt.Fprintf(os.Stderr, "[HotReload] plugin %s: %v\n", name, err)
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to watch plugin %s: %w", name, err)
+	}
+	if err := reloader.Start(); err != nil {
+		return fmt.Errorf("failed to watch plugin %s: %w", name, err)
+	}
+	tpm.watchers[name] = reloader
+	return nil
+}
+
+// unwatch stops reloading the plugin name when its files change
+func (tpm *TypeScriptPluginManager) unwatch(name string) {
+	tpm.mu.Lock()
+	reloader, ok := tpm.watchers[name]
+	delete(tpm.watchers, name)
+	tpm.mu.Unlock()
+	if ok {
+		_ = reloader.Stop()
+	}
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Hook is a point in the runtime at which plugins are called
+type Hook string
+
+// Runtime hook points
+const (
+	HookModuleLoad    Hook = "onModuleLoad"  // a module was required
+	HookBeforeRequest Hook = "beforeRequest" // a framework app received a request
+	HookAfterRequest  Hook = "afterRequest"  // a framework app sent a response
+	HookError         Hook = "onError"       // a framework app failed to handle a request
+	HookShutdown      Hook = "onShutdown"    // the runtime is shutting down
+)
+
+// Hooks lists the hook points in the order a request passes them
+var Hooks = []Hook{HookModuleLoad, HookBeforeRequest, HookAfterRequest, HookError, HookShutdown}
+
+// ParseHook parses a hook name as listed in a plugin manifest
+func ParseHook(name string) (Hook, error) {
+	for _, hook := range Hooks {
+		if string(hook) == name {
+			return hook, nil
+		}
+	}
+	return "", fmt.Errorf("unknown hook %q", name)
+}
+
+// HookPlugin is implemented by plugins that handle hooks
+type HookPlugin interface {
+	Plugin
+	// Hooks returns the hooks the plugin handles
+	Hooks() []Hook
+	// HandleHook handles hook; data describes the event, such as the
+	// module path or the request method and path
+	HandleHook(hook Hook, data map[string]interface{}) error
+}
+
+// Dispatch calls the handlers of hook of the registered plugins, in
+// registration order. Every handler is called; the errors they return are
+// joined.
+func (pm *PluginManager) Dispatch(hook Hook, data map[string]interface{}) error {
+	pm.mu.RLock()
+	var handlers []HookPlugin
+	for _, name := range pm.order {
+		if hp, ok := pm.plugins[name].(HookPlugin); ok && handlesHook(hp, hook) {
+			handlers = append(handlers, hp)
+		}
+	}
+	pm.mu.RUnlock()
+
+	var errs []error
+	for _, hp := range handlers {
+		if err := hp.HandleHook(hook, data); err != nil {
+			errs = append(errs, fmt.Errorf("plugin %s: %s: %w", hp.Name(), hook, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// HasHook reports whether a registered plugin handles hook
+func (pm *PluginManager) HasHook(hook Hook) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	for _, plugin := range pm.plugins {
+		if hp, ok := plugin.(HookPlugin); ok && handlesHook(hp, hook) {
+			return true
+		}
+	}
+	return false
+}
+
+// handlesHook reports whether hp handles hook
+func handlesHook(hp HookPlugin, hook Hook) bool {
+	for _, h := range hp.Hooks() {
+		if h == hook {
+			return true
+		}
+	}
+	return false
+}
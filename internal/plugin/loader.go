@@ -20,6 +20,7 @@ type PluginManifest struct {
 	Config       map[string]interface{} `json:"config"`
 	Hooks        []string               `json:"hooks"`
 	Capabilities []string               `json:"capabilities"`
+	Dependencies []string               `json:"dependencies"`
 }
 
 // LoadedPlugin represents a loaded plugin with metadata
@@ -200,5 +201,20 @@ func (pl *PluginLoader) ValidatePlugin(manifest *PluginManifest) error {
 		return fmt.Errorf("plugin entry point is required")
 	}
 
+	for _, hook := range manifest.Hooks {
+		if _, err := ParseHook(hook); err != nil {
+			return fmt.Errorf("invalid plugin hooks: %w", err)
+		}
+	}
+
+	for _, dep := range manifest.Dependencies {
+		if dep == "" {
+			return fmt.Errorf("plugin dependency names must not be empty")
+		}
+		if dep == manifest.Name {
+			return fmt.Errorf("plugin %s cannot depend on itself", manifest.Name)
+		}
+	}
+
 	return nil
 }
@@ -29,14 +29,19 @@ type Logger interface {
 
 // PluginManager manages plugins
 type PluginManager struct {
-	plugins map[string]Plugin
-	mu      sync.RWMutex
+	plugins   map[string]Plugin
+	order     []string           // plugin names in registration order
+	factories map[string]Factory // how reloadable plugins are created again
+	initCtx   *PluginContext     // the context of InitializeAll, once called
+	mu        sync.RWMutex
+	reloadMu  sync.Mutex
 }
 
 // NewPluginManager creates a new plugin manager
 func NewPluginManager() *PluginManager {
 	return &PluginManager{
-		plugins: make(map[string]Plugin),
+		plugins:   make(map[string]Plugin),
+		factories: make(map[string]Factory),
 	}
 }
 
@@ -51,6 +56,7 @@ func (pm *PluginManager) Register(plugin Plugin) error {
 	}
 	
 	pm.plugins[name] = plugin
+	pm.order = append(pm.order, name)
 	return nil
 }
 
@@ -69,6 +75,13 @@ func (pm *PluginManager) Unregister(name string) error {
 	}
 	
 	delete(pm.plugins, name)
+	delete(pm.factories, name)
+	for i, n := range pm.order {
+		if n == name {
+			pm.order = append(pm.order[:i], pm.order[i+1:]...)
+			break
+		}
+	}
 	return nil
 }
 
@@ -85,11 +98,7 @@ func (pm *PluginManager) ListPlugins() []string {
 	pm.mu.RLock()
 	defer pm.mu.RUnlock()
 	
-	names := make([]string, 0, len(pm.plugins))
-	for name := range pm.plugins {
-		names = append(names, name)
-	}
-	return names
+	return append([]string(nil), pm.order...)
 }
 
 // Execute executes a plugin
@@ -102,16 +111,20 @@ func (pm *PluginManager) Execute(name string, ctx *PluginContext, args map[strin
 	return plugin.Execute(ctx, args)
 }
 
-// InitializeAll initializes all plugins
+// InitializeAll initializes all plugins, each after the plugins it depends
+// on; it fails before initializing any plugin if a dependency is missing
+// or the dependencies are circular
 func (pm *PluginManager) InitializeAll(ctx *PluginContext) error {
-	pm.mu.RLock()
-	plugins := make([]Plugin, 0, len(pm.plugins))
-	for _, plugin := range pm.plugins {
-		plugins = append(plugins, plugin)
+	order, err := pm.InitializationOrder()
+	if err != nil {
+		return err
 	}
-	pm.mu.RUnlock()
 	
-	for _, plugin := range plugins {
+	pm.mu.Lock()
+	pm.initCtx = ctx
+	pm.mu.Unlock()
+	
+	for _, plugin := range pm.pluginsByName(order) {
 		if err := plugin.Initialize(ctx); err != nil {
 			return fmt.Errorf("failed to initialize plugin %s: %w", plugin.Name(), err)
 		}
@@ -120,18 +133,19 @@ func (pm *PluginManager) InitializeAll(ctx *PluginContext) error {
 	return nil
 }
 
-// ShutdownAll shuts down all plugins
+// ShutdownAll shuts down all plugins in the reverse of their
+// initialization order, so that plugins shut down before their dependencies
 func (pm *PluginManager) ShutdownAll() error {
-	pm.mu.RLock()
-	plugins := make([]Plugin, 0, len(pm.plugins))
-	for _, plugin := range pm.plugins {
-		plugins = append(plugins, plugin)
+	order, err := pm.InitializationOrder()
+	if err != nil {
+		// Shut down in reverse registration order instead
+		order = pm.ListPlugins()
 	}
-	pm.mu.RUnlock()
+	plugins := pm.pluginsByName(order)
 	
 	var firstErr error
-	for _, plugin := range plugins {
-		if err := plugin.Shutdown(); err != nil {
+	for i := len(plugins) - 1; i >= 0; i-- {
+		if err := plugins[i].Shutdown(); err != nil {
 			if firstErr == nil {
 				firstErr = err
 			}
@@ -141,3 +155,17 @@ func (pm *PluginManager) ShutdownAll() error {
 	return firstErr
 }
 
+// pluginsByName returns the registered plugins with the given names, in
+// that order, skipping names that are no longer registered
+func (pm *PluginManager) pluginsByName(names []string) []Plugin {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	
+	plugins := make([]Plugin, 0, len(names))
+	for _, name := range names {
+		if plugin, ok := pm.plugins[name]; ok {
+			plugins = append(plugins, plugin)
+		}
+	}
+	return plugins
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import "fmt"
+
+// Factory creates a new instance of a plugin, for example by running its
+// entry point again
+type Factory func() (Plugin, error)
+
+// RegisterReloadable registers a plugin that Reload can replace with a new
+// instance created by factory
+func (pm *PluginManager) RegisterReloadable(plugin Plugin, factory Factory) error {
+	if err := pm.Register(plugin); err != nil {
+		return err
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.factories[plugin.Name()] = factory
+	return nil
+}
+
+// IsReloadable reports whether the plugin name can be reloaded
+func (pm *PluginManager) IsReloadable(name string) bool {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	_, ok := pm.factories[name]
+	return ok
+}
+
+// Reload replaces a plugin registered with RegisterReloadable by a new
+// instance of it. The old instance is shut down and, if the plugins have
+// been initialized, the new one is initialized in its place; it is then
+// swapped in atomically, so that its hooks handle all later events. If the
+// new instance cannot be created or initialized, the old instance is
+// initialized again and stays registered.
+func (pm *PluginManager) Reload(name string) error {
+	pm.reloadMu.Lock()
+	defer pm.reloadMu.Unlock()
+
+	pm.mu.RLock()
+	old, ok := pm.plugins[name]
+	factory := pm.factories[name]
+	ctx := pm.initCtx
+	pm.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("plugin not found: %s", name)
+	}
+	if factory == nil {
+		return fmt.Errorf("plugin %s cannot be reloaded: it was not loaded from an entry point", name)
+	}
+
+	fresh, err := factory()
+	if err != nil {
+		return fmt.Errorf("failed to reload plugin %s: %w", name, err)
+	}
+	if fresh.Name() != name {
+		return fmt.Errorf("failed to reload plugin %s: the reloaded plugin is named %s", name, fresh.Name())
+	}
+	if _, err := pm.initializationOrderWith(fresh); err != nil {
+		return fmt.Errorf("failed to reload plugin %s: %w", name, err)
+	}
+
+	if err := old.Shutdown(); err != nil {
+		return fmt.Errorf("failed to reload plugin %s: failed to shut down the old instance: %w", name, err)
+	}
+	if ctx != nil {
+		if err := fresh.Initialize(ctx); err != nil {
+			// Roll back to the old instance
+			if restoreErr := old.Initialize(ctx); restoreErr != nil {
+				return fmt.Errorf("failed to reload plugin %s: %w (restoring the old instance failed: %v)", name, err, restoreErr)
+			}
+			return fmt.Errorf("failed to reload plugin %s: %w", name, err)
+		}
+	}
+
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	if _, ok := pm.plugins[name]; !ok {
+		return fmt.Errorf("failed to reload plugin %s: it was unregistered", name)
+	}
+	pm.plugins[name] = fresh
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sync"
 
+	"gots-runtime/internal/hotreload"
+
 	"github.com/dop251/goja"
 )
 
@@ -14,6 +16,8 @@ type TypeScriptPlugin struct {
 	initFunc  goja.Callable
 	execFunc  goja.Callable
 	shutdownFunc goja.Callable
+	hooks     map[Hook]goja.Callable
+	dependencies []string
 	engine    *goja.Runtime
 	mu        sync.RWMutex
 }
@@ -71,6 +75,90 @@ func (tp *TypeScriptPlugin) Execute(ctx *PluginContext, args map[string]interfac
 	return result.Export(), nil
 }
 
+// SetHooks sets the hook handlers of the plugin from the hooks object of a
+// plugin, such as { beforeRequest(data) {} }
+func (tp *TypeScriptPlugin) SetHooks(hooks goja.Value) error {
+	handlers := make(map[Hook]goja.Callable)
+	if hooks != nil && !goja.IsUndefined(hooks) && !goja.IsNull(hooks) {
+		hooksObj := hooks.ToObject(tp.engine)
+		for _, name := range hooksObj.Keys() {
+			hook, err := ParseHook(name)
+			if err != nil {
+				return err
+			}
+			handler, ok := goja.AssertFunction(hooksObj.Get(name))
+			if !ok {
+				return fmt.Errorf("hook %s must be a function", name)
+			}
+			handlers[hook] = handler
+		}
+	}
+	
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.hooks = handlers
+	return nil
+}
+
+// SetDependencies sets the plugins the plugin depends on from the
+// dependencies array of a plugin, such as ["auth", "db"]
+func (tp *TypeScriptPlugin) SetDependencies(deps goja.Value) error {
+	var names []string
+	if deps != nil && !goja.IsUndefined(deps) && !goja.IsNull(deps) {
+		if err := tp.engine.ExportTo(deps, &names); err != nil {
+			return fmt.Errorf("plugin dependencies must be an array of plugin names")
+		}
+		for _, name := range names {
+			if name == "" {
+				return fmt.Errorf("plugin dependency names must not be empty")
+			}
+			if name == tp.name {
+				return fmt.Errorf("plugin %s cannot depend on itself", tp.name)
+			}
+		}
+	}
+	
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+	tp.dependencies = names
+	return nil
+}
+
+// Dependencies returns the names of the plugins the plugin depends on
+func (tp *TypeScriptPlugin) Dependencies() []string {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	return append([]string(nil), tp.dependencies...)
+}
+
+// Hooks returns the hooks the plugin handles
+func (tp *TypeScriptPlugin) Hooks() []Hook {
+	tp.mu.RLock()
+	defer tp.mu.RUnlock()
+	hooks := make([]Hook, 0, len(tp.hooks))
+	for _, hook := range Hooks {
+		if _, ok := tp.hooks[hook]; ok {
+			hooks = append(hooks, hook)
+		}
+	}
+	return hooks
+}
+
+// HandleHook calls the handler of hook with data
+func (tp *TypeScriptPlugin) HandleHook(hook Hook, data map[string]interface{}) error {
+	tp.mu.RLock()
+	handler, ok := tp.hooks[hook]
+	tp.mu.RUnlock()
+	if !ok {
+		return nil
+	}
+	
+	if _, err := handler(nil, tp.engine.ToValue(data)); err != nil {
+		return err
+	}
+	return nil
+}
+
 // Shutdown shuts down the plugin
 func (tp *TypeScriptPlugin) Shutdown() error {
 	if tp.shutdownFunc == nil {
@@ -115,53 +203,81 @@ func (tp *TypeScriptPlugin) createContextObject(ctx *PluginContext) *goja.Object
 
 // TypeScriptPluginManager wraps PluginManager for TypeScript
 type TypeScriptPluginManager struct {
-	manager *PluginManager
-	engine  *goja.Runtime
-	mu      sync.RWMutex
+	manager   *PluginManager
+	engine    *goja.Runtime
+	loadEntry EntryLoader
+	schedule  func(task func()) error
+	watchers  map[string]*hotreload.HotReloader
+	mu        sync.RWMutex
 }
 
 // NewTypeScriptPluginManager creates a new TypeScript-wrapped plugin manager
 func NewTypeScriptPluginManager(engine *goja.Runtime, manager *PluginManager) *TypeScriptPluginManager {
 	return &TypeScriptPluginManager{
-		manager: manager,
-		engine:  engine,
+		manager:  manager,
+		engine:   engine,
+		watchers: make(map[string]*hotreload.HotReloader),
 	}
 }
 
+// newPlugin creates a plugin from a plugin object, such as
+// { name, version, initialize, execute, shutdown, hooks, dependencies }
+func (tpm *TypeScriptPluginManager) newPlugin(pluginObj goja.Value) (*TypeScriptPlugin, error) {
+	plugin, ok := pluginObj.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("plugin must be an object")
+	}
+	
+	name := plugin.Get("name").String()
+	version := plugin.Get("version").String()
+	
+	initFunc, _ := goja.AssertFunction(plugin.Get("initialize"))
+	execFunc, ok := goja.AssertFunction(plugin.Get("execute"))
+	if !ok {
+		return nil, fmt.Errorf("plugin must have an execute function")
+	}
+	
+	shutdownFunc, _ := goja.AssertFunction(plugin.Get("shutdown"))
+	
+	tsPlugin := NewTypeScriptPlugin(tpm.engine, name, version, initFunc, execFunc, shutdownFunc)
+	if err := tsPlugin.SetHooks(plugin.Get("hooks")); err != nil {
+		return nil, err
+	}
+	if err := tsPlugin.SetDependencies(plugin.Get("dependencies")); err != nil {
+		return nil, err
+	}
+	return tsPlugin, nil
+}
+
+// pluginInfo returns the name and version of plugin as an object
+func (tpm *TypeScriptPluginManager) pluginInfo(plugin Plugin) *goja.Object {
+	pluginObj := tpm.engine.NewObject()
+	pluginObj.Set("name", plugin.Name())
+	pluginObj.Set("version", plugin.Version())
+	return pluginObj
+}
+
 // ToJSObject converts the plugin manager to a JavaScript object
 func (tpm *TypeScriptPluginManager) ToJSObject() *goja.Object {
 	obj := tpm.engine.NewObject()
 	
-	// Register method
+	// Register method. Plugins are JavaScript objects, so the methods work
+	// on the calling goroutine and return settled promises.
 	obj.Set("register", func(pluginObj goja.Value) *goja.Promise {
 		promise, resolve, reject := tpm.engine.NewPromise()
 		
-		go func() {
+		func() {
 			if pluginObj == nil || goja.IsUndefined(pluginObj) {
 				reject(tpm.engine.ToValue("plugin object is required"))
 				return
 			}
 			
-			plugin, ok := pluginObj.(*goja.Object)
-			if !ok {
-				reject(tpm.engine.ToValue("plugin must be an object"))
-				return
-			}
-			
-			name := plugin.Get("name").String()
-			version := plugin.Get("version").String()
-			
-			initFunc, _ := goja.AssertFunction(plugin.Get("initialize"))
-			execFunc, ok := goja.AssertFunction(plugin.Get("execute"))
-			if !ok {
-				reject(tpm.engine.ToValue("plugin must have an execute function"))
+			tsPlugin, err := tpm.newPlugin(pluginObj)
+			if err != nil {
+				reject(tpm.engine.ToValue(err.Error()))
 				return
 			}
 			
-			shutdownFunc, _ := goja.AssertFunction(plugin.Get("shutdown"))
-			
-			tsPlugin := NewTypeScriptPlugin(tpm.engine, name, version, initFunc, execFunc, shutdownFunc)
-			
 			if err := tpm.manager.Register(tsPlugin); err != nil {
 				reject(tpm.engine.ToValue(err.Error()))
 			} else {
@@ -176,7 +292,8 @@ func (tpm *TypeScriptPluginManager) ToJSObject() *goja.Object {
 	obj.Set("unregister", func(name string) *goja.Promise {
 		promise, resolve, reject := tpm.engine.NewPromise()
 		
-		go func() {
+		func() {
+			tpm.unwatch(name)
 			if err := tpm.manager.Unregister(name); err != nil {
 				reject(tpm.engine.ToValue(err.Error()))
 			} else {
@@ -191,7 +308,7 @@ func (tpm *TypeScriptPluginManager) ToJSObject() *goja.Object {
 	obj.Set("execute", func(name string, args goja.Value) *goja.Promise {
 		promise, resolve, reject := tpm.engine.NewPromise()
 		
-		go func() {
+		func() {
 			var argsMap map[string]interface{}
 			if args != nil && !goja.IsUndefined(args) {
 				argsMap = args.Export().(map[string]interface{})
@@ -217,6 +334,88 @@ func (tpm *TypeScriptPluginManager) ToJSObject() *goja.Object {
 		return promise
 	})
 	
+	// Load method: loads a plugin from its entry point file, or from a
+	// directory with a plugin.json manifest, so that it can be reloaded
+	obj.Set("load", func(path string, options goja.Value) *goja.Promise {
+		promise, resolve, reject := tpm.engine.NewPromise()
+		
+		func() {
+			watch := false
+			if options != nil && !goja.IsUndefined(options) && !goja.IsNull(options) {
+				if w := options.ToObject(tpm.engine).Get("watch"); w != nil {
+					watch = w.ToBoolean()
+				}
+			}
+			
+			plugin, err := tpm.load(path, watch)
+			if err != nil {
+				reject(tpm.engine.ToValue(err.Error()))
+			} else {
+				resolve(tpm.pluginInfo(plugin))
+			}
+		}()
+		
+		return promise
+	})
+	
+	// Reload method: replaces a loaded plugin with a new instance running
+	// the current code of its entry point
+	obj.Set("reload", func(name string) *goja.Promise {
+		promise, resolve, reject := tpm.engine.NewPromise()
+		
+		func() {
+			if err := tpm.manager.Reload(name); err != nil {
+				reject(tpm.engine.ToValue(err.Error()))
+			} else {
+				resolve(goja.Undefined())
+			}
+		}()
+		
+		return promise
+	})
+	
+	// Initialize method: initializes the registered plugins, each after
+	// the plugins it depends on
+	obj.Set("initialize", func() *goja.Promise {
+		promise, resolve, reject := tpm.engine.NewPromise()
+		
+		func() {
+			ctx := &PluginContext{
+				RuntimeID: "ts-runtime",
+				Config:    make(map[string]interface{}),
+				Logger:    &TypeScriptLogger{engine: tpm.engine},
+			}
+			
+			if err := tpm.manager.InitializeAll(ctx); err != nil {
+				reject(tpm.engine.ToValue(err.Error()))
+			} else {
+				resolve(goja.Undefined())
+			}
+		}()
+		
+		return promise
+	})
+	
+	// Order method: the order plugins are initialized in
+	obj.Set("order", func() ([]string, error) {
+		return tpm.manager.InitializationOrder()
+	})
+	
+	// Shutdown method: shuts the plugins down in reverse initialization order
+	obj.Set("shutdown", func() *goja.Promise {
+		promise, resolve, reject := tpm.engine.NewPromise()
+		
+		func() {
+			if err := tpm.manager.ShutdownAll(); err != nil {
+				reject(tpm.engine.ToValue(err.Error()))
+			} else {
+				resolve(goja.Undefined())
+			}
+		}()
+		
+		return promise
+	})
+	
 	// List method
 	obj.Set("list", func() []string {
 		return tpm.manager.ListPlugins()
@@ -230,10 +429,7 @@ func (tpm *TypeScriptPluginManager) ToJSObject() *goja.Object {
 		}
 		
 		// Return plugin info
-		pluginObj := tpm.engine.NewObject()
-		pluginObj.Set("name", plugin.Name())
-		pluginObj.Set("version", plugin.Version())
-		return pluginObj
+		return tpm.pluginInfo(plugin)
 	})
 	
 	return obj
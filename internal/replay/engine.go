@@ -1,9 +1,12 @@
 package replay
 
 import (
+	"crypto/rand"
 	"encoding/json"
 	"fmt"
 	"os"
+	"reflect"
+	"sort"
 	"sync"
 	"time"
 )
@@ -17,12 +20,16 @@ type Event struct {
 	Result    json.RawMessage
 }
 
+// Redactor rewrites event data or results before they are written to disk
+type Redactor func(eventType string, data json.RawMessage) json.RawMessage
+
 // ReplayEngine provides deterministic replay functionality
 type ReplayEngine struct {
 	events    []*Event
 	current   int
 	recording bool
 	replaying bool
+	redactor  Redactor
 	mu        sync.RWMutex
 }
 
@@ -136,12 +143,35 @@ func (re *ReplayEngine) NextEvent() (*Event, error) {
 	return event, nil
 }
 
+// SetRedactor sets the redactor applied to event data and results on Save
+func (re *ReplayEngine) SetRedactor(redactor Redactor) {
+	re.mu.Lock()
+	defer re.mu.Unlock()
+	re.redactor = redactor
+}
+
 // Save saves events to a file
 func (re *ReplayEngine) Save(filename string) error {
 	re.mu.RLock()
 	defer re.mu.RUnlock()
 
-	data, err := json.MarshalIndent(re.events, "", "  ")
+	events := re.events
+	if re.redactor != nil {
+		// Redact copies so the in-memory recording stays intact
+		events = make([]*Event, len(re.events))
+		for i, event := range re.events {
+			redacted := *event
+			if len(event.Data) > 0 {
+				redacted.Data = re.redactor(event.Type, event.Data)
+			}
+			if len(event.Result) > 0 {
+				redacted.Result = re.redactor(event.Type, event.Result)
+			}
+			events[i] = &redacted
+		}
+	}
+
+	data, err := json.MarshalIndent(events, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal events: %w", err)
 	}
@@ -251,6 +281,19 @@ type ReplayStats struct {
 	AverageDuration time.Duration
 	StartTime       time.Time
 	EndTime         time.Time
+	ComparedEvents  int
+	DivergentEvents int
+	Divergences     []*Divergence
+}
+
+// Divergence describes a live result that differs from the recorded one
+type Divergence struct {
+	EventID    string
+	EventType  string
+	Expected   json.RawMessage
+	Actual     json.RawMessage
+	Detail     string
+	DetectedAt time.Time
 }
 
 // ExecutionRecord tracks a single replay execution
@@ -267,10 +310,11 @@ type ExecutionRecord struct {
 // ReplaySession manages a replay session
 type ReplaySession struct {
 	*ReplayEngine
-	records   map[string]*ExecutionRecord
-	stats     *ReplayStats
-	sessionID string
-	mu        sync.RWMutex
+	records     map[string]*ExecutionRecord
+	divergences []*Divergence
+	stats       *ReplayStats
+	sessionID   string
+	mu          sync.RWMutex
 }
 
 // NewReplaySession creates a new replay session
@@ -317,10 +361,143 @@ func (rs *ReplaySession) GetSessionStats() *ReplayStats {
 	if stats.ExecutedEvents > 0 {
 		stats.AverageDuration = stats.TotalDuration / time.Duration(stats.ExecutedEvents)
 	}
-	stats.TotalEvents = len(rs.ReplayEngine.events)
+	stats.TotalEvents = rs.ReplayEngine.GetEventCount()
+	stats.Divergences = make([]*Divergence, len(rs.divergences))
+	copy(stats.Divergences, rs.divergences)
 	return &stats
 }
 
+// CompareResult compares the live result of re-executing an event with the
+// recorded result. It returns true if they match; otherwise it records a
+// divergence and returns a description of the first difference.
+func (rs *ReplaySession) CompareResult(eventID string, liveResult interface{}) (bool, string) {
+	event, ok := rs.GetEventByID(eventID)
+	if !ok {
+		return false, fmt.Sprintf("event not found: %s", eventID)
+	}
+
+	actual, err := json.Marshal(liveResult)
+	if err != nil {
+		return false, fmt.Sprintf("failed to marshal live result: %v", err)
+	}
+
+	var expectedValue, actualValue interface{}
+	if len(event.Result) > 0 {
+		if err := json.Unmarshal(event.Result, &expectedValue); err != nil {
+			return false, fmt.Sprintf("failed to parse recorded result: %v", err)
+		}
+	}
+	if err := json.Unmarshal(actual, &actualValue); err != nil {
+		return false, fmt.Sprintf("failed to parse live result: %v", err)
+	}
+
+	detail := diffValues("$", expectedValue, actualValue)
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	rs.stats.ComparedEvents++
+	if detail == "" {
+		return true, ""
+	}
+
+	rs.stats.DivergentEvents++
+	rs.divergences = append(rs.divergences, &Divergence{
+		EventID:    eventID,
+		EventType:  event.Type,
+		Expected:   event.Result,
+		Actual:     actual,
+		Detail:     detail,
+		DetectedAt: time.Now(),
+	})
+	return false, detail
+}
+
+// GetDivergences returns all divergences detected in this session
+func (rs *ReplaySession) GetDivergences() []*Divergence {
+	rs.mu.RLock()
+	defer rs.mu.RUnlock()
+
+	result := make([]*Divergence, len(rs.divergences))
+	copy(result, rs.divergences)
+	return result
+}
+
+// diffValues returns a description of the first difference between two
+// decoded JSON values, or an empty string if they are equal
+func diffValues(path string, expected, actual interface{}) string {
+	switch e := expected.(type) {
+	case map[string]interface{}:
+		a, ok := actual.(map[string]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected object, got %s", path, jsonType(actual))
+		}
+		keys := make([]string, 0, len(e)+len(a))
+		for k := range e {
+			keys = append(keys, k)
+		}
+		for k := range a {
+			if _, ok := e[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			ev, inExpected := e[k]
+			av, inActual := a[k]
+			switch {
+			case !inActual:
+				return fmt.Sprintf("%s.%s: missing in live result", path, k)
+			case !inExpected:
+				return fmt.Sprintf("%s.%s: unexpected in live result", path, k)
+			}
+			if detail := diffValues(path+"."+k, ev, av); detail != "" {
+				return detail
+			}
+		}
+		return ""
+	case []interface{}:
+		a, ok := actual.([]interface{})
+		if !ok {
+			return fmt.Sprintf("%s: expected array, got %s", path, jsonType(actual))
+		}
+		if len(e) != len(a) {
+			return fmt.Sprintf("%s: expected %d elements, got %d", path, len(e), len(a))
+		}
+		for i := range e {
+			if detail := diffValues(fmt.Sprintf("%s[%d]", path, i), e[i], a[i]); detail != "" {
+				return detail
+			}
+		}
+		return ""
+	default:
+		if !reflect.DeepEqual(expected, actual) {
+			return fmt.Sprintf("%s: expected %v, got %v", path, expected, actual)
+		}
+		return ""
+	}
+}
+
+// jsonType returns the JSON type name of a decoded value
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
 // GetExecutionRecord gets the execution record for an event
 func (rs *ReplaySession) GetExecutionRecord(eventID string) (*ExecutionRecord, bool) {
 	rs.mu.RLock()
@@ -332,9 +509,18 @@ func (rs *ReplaySession) GetExecutionRecord(eventID string) (*ExecutionRecord, b
 var eventIDCounter uint64
 var eventIDMu sync.Mutex
 
+// generateEventID generates an event ID that is unique across processes.
+// IDs have the form event-<unix nanos>-<sequence>-<random>, with fixed-width
+// hex fields so that sorting IDs recovers recording order.
 func generateEventID() string {
 	eventIDMu.Lock()
-	defer eventIDMu.Unlock()
 	eventIDCounter++
-	return fmt.Sprintf("event-%d", eventIDCounter)
+	seq := eventIDCounter
+	eventIDMu.Unlock()
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return fmt.Sprintf("event-%016x-%08x", time.Now().UnixNano(), seq)
+	}
+	return fmt.Sprintf("event-%016x-%08x-%x", time.Now().UnixNano(), seq, suffix)
 }
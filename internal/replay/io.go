@@ -0,0 +1,141 @@
+package replay
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ioOutcome is the recorded result of an I/O operation
+type ioOutcome struct {
+	Value json.RawMessage `json:"value,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// IOLog records the outcomes of I/O operations while a program runs and
+// serves the recorded outcomes back when the program is replayed, so that
+// the replayed run sees the same file contents and environment without
+// touching either. Operations must be begun in the same order in both runs.
+type IOLog struct {
+	engine    *ReplayEngine
+	replaying bool
+	err       error // first divergence found while replaying
+	mu        sync.Mutex
+}
+
+// NewIORecorder creates an I/O log that records into engine
+func NewIORecorder(engine *ReplayEngine) *IOLog {
+	engine.StartRecording()
+	return &IOLog{engine: engine}
+}
+
+// NewIOReplayer creates an I/O log that replays the events loaded into
+// engine. Events already consumed with engine.NextEvent are skipped.
+func NewIOReplayer(engine *ReplayEngine) (*IOLog, error) {
+	if !engine.IsReplaying() {
+		if err := engine.StartReplay(); err != nil {
+			return nil, err
+		}
+	}
+	return &IOLog{engine: engine, replaying: true}, nil
+}
+
+// Replaying reports whether the log serves recorded outcomes
+func (l *IOLog) Replaying() bool {
+	return l.replaying
+}
+
+// Begin starts an I/O operation. When recording it records the operation;
+// when replaying it returns the next recorded operation, which must have
+// the same type and data.
+func (l *IOLog) Begin(eventType string, data interface{}) (*Event, error) {
+	if !l.replaying {
+		return l.engine.RecordEvent(eventType, data)
+	}
+
+	event, err := l.engine.NextEvent()
+	if err != nil {
+		return nil, l.diverge(fmt.Errorf("replay diverged: unexpected %s: %w", eventType, err))
+	}
+
+	if event.Type != eventType {
+		return nil, l.diverge(fmt.Errorf("replay diverged at %s: expected %s, got %s", event.ID, event.Type, eventType))
+	}
+
+	actual, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal data: %w", err)
+	}
+	var expectedValue, actualValue interface{}
+	if err := json.Unmarshal(event.Data, &expectedValue); err != nil {
+		return nil, fmt.Errorf("failed to parse recorded data: %w", err)
+	}
+	if err := json.Unmarshal(actual, &actualValue); err != nil {
+		return nil, fmt.Errorf("failed to parse data: %w", err)
+	}
+	if detail := diffValues("$", expectedValue, actualValue); detail != "" {
+		return nil, l.diverge(fmt.Errorf("replay diverged at %s (%s): %s", event.ID, eventType, detail))
+	}
+
+	return event, nil
+}
+
+// Finish records the outcome of an operation begun while recording: its
+// value if err is nil, and err otherwise
+func (l *IOLog) Finish(event *Event, value interface{}, err error) error {
+	outcome := ioOutcome{}
+	if err != nil {
+		outcome.Error = err.Error()
+	} else {
+		valueJSON, marshalErr := json.Marshal(value)
+		if marshalErr != nil {
+			return fmt.Errorf("failed to marshal value: %w", marshalErr)
+		}
+		outcome.Value = valueJSON
+	}
+	return l.engine.RecordResult(event.ID, outcome)
+}
+
+// Outcome decodes the recorded value of an operation begun while
+// replaying into value, and returns the error the operation failed with
+func (l *IOLog) Outcome(event *Event, value interface{}) error {
+	var outcome ioOutcome
+	if len(event.Result) > 0 {
+		if err := json.Unmarshal(event.Result, &outcome); err != nil {
+			return fmt.Errorf("failed to parse recorded result of %s: %w", event.ID, err)
+		}
+	}
+
+	if outcome.Error != "" {
+		return errors.New(outcome.Error)
+	}
+	if len(outcome.Value) > 0 && value != nil {
+		if err := json.Unmarshal(outcome.Value, value); err != nil {
+			return fmt.Errorf("failed to decode recorded result of %s: %w", event.ID, err)
+		}
+	}
+	return nil
+}
+
+// Err returns the first divergence found while replaying
+func (l *IOLog) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
+
+// Remaining returns the number of recorded events not yet replayed
+func (l *IOLog) Remaining() int {
+	return l.engine.GetEventCount() - l.engine.GetCurrentEventIndex()
+}
+
+// diverge remembers the first divergence and returns err
+func (l *IOLog) diverge(err error) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = err
+	}
+	return err
+}
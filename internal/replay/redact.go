@@ -0,0 +1,80 @@
+package replay
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// RedactedValue replaces values masked by a path redactor
+const RedactedValue = "[REDACTED]"
+
+// NewPathRedactor creates a redactor that masks the values at the given JSON
+// paths. Paths are dot-separated keys (an optional "$." prefix is ignored);
+// "*" matches any object key or array element, e.g. "headers.authorization"
+// or "users.*.password". Data that is not valid JSON is returned unchanged.
+func NewPathRedactor(paths ...string) Redactor {
+	parsed := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		path = strings.TrimPrefix(path, "$.")
+		if path == "" {
+			continue
+		}
+		parsed = append(parsed, strings.Split(path, "."))
+	}
+
+	return func(eventType string, data json.RawMessage) json.RawMessage {
+		var value interface{}
+		if err := json.Unmarshal(data, &value); err != nil {
+			return data
+		}
+
+		for _, path := range parsed {
+			value = redactPath(value, path)
+		}
+
+		redacted, err := json.Marshal(value)
+		if err != nil {
+			return data
+		}
+		return redacted
+	}
+}
+
+// ChainRedactors applies redactors in order
+func ChainRedactors(redactors ...Redactor) Redactor {
+	return func(eventType string, data json.RawMessage) json.RawMessage {
+		for _, redactor := range redactors {
+			data = redactor(eventType, data)
+		}
+		return data
+	}
+}
+
+// redactPath masks the value at path within value
+func redactPath(value interface{}, path []string) interface{} {
+	if len(path) == 0 {
+		return RedactedValue
+	}
+
+	key, rest := path[0], path[1:]
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if key == "*" {
+			for k, child := range v {
+				v[k] = redactPath(child, rest)
+			}
+		} else if child, ok := v[key]; ok {
+			v[key] = redactPath(child, rest)
+		}
+	case []interface{}:
+		if key == "*" {
+			for i, child := range v {
+				v[i] = redactPath(child, rest)
+			}
+		} else if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(v) {
+			v[i] = redactPath(v[i], rest)
+		}
+	}
+	return value
+}
@@ -2,32 +2,107 @@ package rpc
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"sync"
+	"time"
 )
 
+// ErrNotConnected is returned when a call is made while the client is disconnected
+// and queuing is disabled, or after reconnection has been given up
+var ErrNotConnected = errors.New("rpc client is not connected")
+
+// ErrClientClosed is returned when a call is made on a closed client
+var ErrClientClosed = errors.New("rpc client is closed")
+
+// ClientOptions configures RPC client connection handling
+type ClientOptions struct {
+	// Reconnect enables automatic reconnection when the connection drops
+	Reconnect bool
+	// InitialBackoff is the delay before the first reconnection attempt
+	InitialBackoff time.Duration
+	// MaxBackoff caps the exponential backoff between attempts
+	MaxBackoff time.Duration
+	// MaxRetries limits reconnection attempts (0 means unlimited)
+	MaxRetries int
+	// QueueWhileDisconnected makes calls wait for reconnection instead of failing fast
+	QueueWhileDisconnected bool
+	// DialTimeout bounds each connection attempt
+	DialTimeout time.Duration
+}
+
+// DefaultClientOptions returns the default client options
+func DefaultClientOptions() ClientOptions {
+	return ClientOptions{
+		Reconnect:              true,
+		InitialBackoff:         100 * time.Millisecond,
+		MaxBackoff:             10 * time.Second,
+		QueueWhileDisconnected: true,
+		DialTimeout:            5 * time.Second,
+	}
+}
+
 // RPCClient provides RPC client functionality
 type RPCClient struct {
-	conn   net.Conn
-	encoder *json.Encoder
-	decoder *json.Decoder
-	mu     sync.Mutex
-	idGen  uint64
+	address      string
+	opts         ClientOptions
+	conn         net.Conn
+	encoder      *json.Encoder
+	decoder      *json.Decoder
+	mu           sync.Mutex
+	callMu       sync.Mutex
+	stateChanged *sync.Cond
+	idGen        uint64
+	connected    bool
+	reconnecting bool
+	gaveUp       bool
+	closed       bool
+	onConnect    []func()
+	onDisconnect []func(error)
 }
 
 // NewRPCClient creates a new RPC client
 func NewRPCClient(address string) (*RPCClient, error) {
-	conn, err := net.Dial("tcp", address)
+	return NewRPCClientWithOptions(address, DefaultClientOptions())
+}
+
+// NewRPCClientWithOptions creates a new RPC client with connection options
+func NewRPCClientWithOptions(address string, opts ClientOptions) (*RPCClient, error) {
+	rc := &RPCClient{
+		address: address,
+		opts:    opts,
+	}
+	rc.stateChanged = sync.NewCond(&rc.mu)
+
+	conn, err := rc.dial()
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect: %w", err)
 	}
-	
-	return &RPCClient{
-		conn:    conn,
-		encoder: json.NewEncoder(conn),
-		decoder: json.NewDecoder(conn),
-	}, nil
+	rc.setConn(conn)
+
+	return rc, nil
+}
+
+// OnConnect registers a callback invoked after the client (re)connects
+func (rc *RPCClient) OnConnect(callback func()) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.onConnect = append(rc.onConnect, callback)
+}
+
+// OnDisconnect registers a callback invoked when the connection drops
+func (rc *RPCClient) OnDisconnect(callback func(error)) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.onDisconnect = append(rc.onDisconnect, callback)
+}
+
+// IsConnected reports whether the client currently has a live connection
+func (rc *RPCClient) IsConnected() bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return rc.connected
 }
 
 // Call makes an RPC call
@@ -36,12 +111,12 @@ func (rc *RPCClient) Call(method string, params interface{}) (interface{}, error
 	id := fmt.Sprintf("req-%d", rc.idGen)
 	rc.idGen++
 	rc.mu.Unlock()
-	
+
 	req := &RPCRequest{
 		ID:     id,
 		Method: method,
 	}
-	
+
 	if params != nil {
 		paramsData, err := json.Marshal(params)
 		if err != nil {
@@ -49,25 +124,160 @@ func (rc *RPCClient) Call(method string, params interface{}) (interface{}, error
 		}
 		req.Params = paramsData
 	}
-	
-	if err := rc.encoder.Encode(req); err != nil {
+
+	// Requests and responses share one connection, so calls are serialized
+	rc.callMu.Lock()
+	defer rc.callMu.Unlock()
+
+	encoder, decoder, err := rc.waitForConnection()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := encoder.Encode(req); err != nil {
+		rc.handleDisconnect(err)
 		return nil, fmt.Errorf("failed to send request: %w", err)
 	}
-	
+
 	var response RPCResponse
-	if err := rc.decoder.Decode(&response); err != nil {
+	if err := decoder.Decode(&response); err != nil {
+		rc.handleDisconnect(err)
 		return nil, fmt.Errorf("failed to receive response: %w", err)
 	}
-	
+
 	if response.Error != nil {
 		return nil, fmt.Errorf("RPC error: %s", response.Error.Message)
 	}
-	
+
 	return response.Result, nil
 }
 
 // Close closes the client connection
 func (rc *RPCClient) Close() error {
-	return rc.conn.Close()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.closed {
+		return nil
+	}
+	rc.closed = true
+	rc.connected = false
+	rc.stateChanged.Broadcast()
+
+	if rc.conn != nil {
+		return rc.conn.Close()
+	}
+	return nil
+}
+
+// dial opens a new connection to the server
+func (rc *RPCClient) dial() (net.Conn, error) {
+	if rc.opts.DialTimeout > 0 {
+		return net.DialTimeout("tcp", rc.address, rc.opts.DialTimeout)
+	}
+	return net.Dial("tcp", rc.address)
+}
+
+// setConn installs a connection and marks the client as connected
+func (rc *RPCClient) setConn(conn net.Conn) bool {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if rc.closed {
+		_ = conn.Close()
+		return false
+	}
+	rc.conn = conn
+	rc.encoder = json.NewEncoder(conn)
+	rc.decoder = json.NewDecoder(conn)
+	rc.connected = true
+	rc.reconnecting = false
+	rc.gaveUp = false
+	rc.stateChanged.Broadcast()
+	return true
 }
 
+// waitForConnection returns the current codec, waiting for reconnection if configured
+func (rc *RPCClient) waitForConnection() (*json.Encoder, *json.Decoder, error) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	for !rc.connected {
+		if rc.closed {
+			return nil, nil, ErrClientClosed
+		}
+		if !rc.opts.QueueWhileDisconnected || !rc.reconnecting || rc.gaveUp {
+			return nil, nil, ErrNotConnected
+		}
+		rc.stateChanged.Wait()
+	}
+
+	return rc.encoder, rc.decoder, nil
+}
+
+// handleDisconnect marks the connection as lost and starts reconnecting
+func (rc *RPCClient) handleDisconnect(cause error) {
+	rc.mu.Lock()
+	if !rc.connected || rc.closed {
+		rc.mu.Unlock()
+		return
+	}
+	rc.connected = false
+	_ = rc.conn.Close()
+	if rc.opts.Reconnect {
+		rc.reconnecting = true
+		go rc.reconnect()
+	}
+	callbacks := append([]func(error){}, rc.onDisconnect...)
+	rc.stateChanged.Broadcast()
+	rc.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(cause)
+	}
+}
+
+// reconnect redials the server with exponential backoff
+func (rc *RPCClient) reconnect() {
+	backoff := rc.opts.InitialBackoff
+	if backoff <= 0 {
+		backoff = 100 * time.Millisecond
+	}
+
+	for attempt := 1; rc.opts.MaxRetries == 0 || attempt <= rc.opts.MaxRetries; attempt++ {
+		time.Sleep(backoff)
+
+		rc.mu.Lock()
+		closed := rc.closed
+		rc.mu.Unlock()
+		if closed {
+			return
+		}
+
+		conn, err := rc.dial()
+		if err == nil {
+			if !rc.setConn(conn) {
+				return
+			}
+
+			rc.mu.Lock()
+			callbacks := append([]func(){}, rc.onConnect...)
+			rc.mu.Unlock()
+			for _, callback := range callbacks {
+				callback()
+			}
+			return
+		}
+
+		backoff *= 2
+		if rc.opts.MaxBackoff > 0 && backoff > rc.opts.MaxBackoff {
+			backoff = rc.opts.MaxBackoff
+		}
+	}
+
+	rc.mu.Lock()
+	rc.reconnecting = false
+	rc.gaveUp = true
+	rc.stateChanged.Broadcast()
+	rc.mu.Unlock()
+}
@@ -14,13 +14,24 @@ type RPCRequest struct {
 	Method  string
 	Params  json.RawMessage
 	Module  string
+	Stream  bool
 }
 
+// Stream frame types. A streaming call produces zero or more FrameData
+// responses followed by exactly one FrameEnd response, or a FrameError
+// response carrying Error if the handler fails.
+const (
+	FrameData  = "data"
+	FrameEnd   = "end"
+	FrameError = "error"
+)
+
 // RPCResponse represents an RPC response
 type RPCResponse struct {
 	ID     string
 	Result interface{}
 	Error  *RPCError
+	Frame  string `json:",omitempty"`
 }
 
 // RPCError represents an RPC error
@@ -33,9 +44,14 @@ type RPCError struct {
 // RPCHandler handles RPC calls
 type RPCHandler func(ctx context.Context, params json.RawMessage) (interface{}, error)
 
+// StreamHandler handles server-streaming RPC calls. It calls send for every
+// message; the stream ends when the handler returns.
+type StreamHandler func(ctx context.Context, params json.RawMessage, send func(interface{}) error) error
+
 // RPCServer provides native RPC functionality
 type RPCServer struct {
 	handlers map[string]RPCHandler
+	streams  map[string]StreamHandler
 	listener net.Listener
 	mu       sync.RWMutex
 	ctx      context.Context
@@ -47,6 +63,7 @@ func NewRPCServer(ctx context.Context) *RPCServer {
 	rpcCtx, cancel := context.WithCancel(ctx)
 	return &RPCServer{
 		handlers: make(map[string]RPCHandler),
+		streams:  make(map[string]StreamHandler),
 		ctx:      rpcCtx,
 		cancel:   cancel,
 	}
@@ -59,6 +76,13 @@ func (rs *RPCServer) RegisterHandler(method string, handler RPCHandler) {
 	rs.handlers[method] = handler
 }
 
+// RegisterStreamHandler registers a server-streaming RPC handler
+func (rs *RPCServer) RegisterStreamHandler(method string, handler StreamHandler) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.streams[method] = handler
+}
+
 // Listen starts listening on an address
 func (rs *RPCServer) Listen(address string) error {
 	listener, err := net.Listen("tcp", address)
@@ -104,6 +128,13 @@ func (rs *RPCServer) handleConnection(conn net.Conn) {
 			return
 		}
 		
+		if req.Stream {
+			if err := rs.handleStream(&req, encoder); err != nil {
+				return
+			}
+			continue
+		}
+		
 		response := rs.handleRequest(&req)
 		if err := encoder.Encode(response); err != nil {
 			return
@@ -144,6 +175,68 @@ func (rs *RPCServer) handleRequest(req *RPCRequest) *RPCResponse {
 	}
 }
 
+// handleStream runs a streaming handler, writing data frames followed by an
+// end or error frame. It returns an error only if the connection is broken.
+func (rs *RPCServer) handleStream(req *RPCRequest, encoder *json.Encoder) error {
+	rs.mu.RLock()
+	handler, ok := rs.streams[req.Method]
+	rs.mu.RUnlock()
+	
+	if !ok {
+		return encoder.Encode(&RPCResponse{
+			ID:    req.ID,
+			Frame: FrameError,
+			Error: &RPCError{
+				Code:    -32601,
+				Message: "Method not found",
+			},
+		})
+	}
+	
+	ctx, cancel := context.WithCancel(rs.ctx)
+	defer cancel()
+	
+	var sendMu sync.Mutex
+	var sendErr error
+	send := func(msg interface{}) error {
+		sendMu.Lock()
+		defer sendMu.Unlock()
+		if sendErr != nil {
+			return sendErr
+		}
+		if err := encoder.Encode(&RPCResponse{ID: req.ID, Result: msg, Frame: FrameData}); err != nil {
+			// The client went away; stop the handler
+			sendErr = err
+			cancel()
+			return err
+		}
+		return nil
+	}
+	
+	err := handler(ctx, req.Params, send)
+	
+	sendMu.Lock()
+	defer sendMu.Unlock()
+	if sendErr != nil {
+		return sendErr
+	}
+	// Reject sends made after the handler returned
+	sendErr = fmt.Errorf("stream closed")
+	
+	if err != nil {
+		return encoder.Encode(&RPCResponse{
+			ID:    req.ID,
+			Frame: FrameError,
+			Error: &RPCError{
+				Code:    -32000,
+				Message: err.Error(),
+			},
+		})
+	}
+	
+	return encoder.Encode(&RPCResponse{ID: req.ID, Frame: FrameEnd})
+}
+
 // Stop stops the RPC server
 func (rs *RPCServer) Stop() error {
 	rs.cancel()
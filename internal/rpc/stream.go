@@ -0,0 +1,123 @@
+package rpc
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+)
+
+// RPCStream reads messages from a server-streaming RPC call
+type RPCStream struct {
+	id      string
+	conn    net.Conn
+	decoder *json.Decoder
+	done    bool
+	err     error
+	closing atomic.Bool
+	mu      sync.Mutex
+}
+
+// CallStream starts a server-streaming RPC call. Each stream uses its own
+// connection so that long-lived streams do not block unary calls.
+func (rc *RPCClient) CallStream(method string, params interface{}) (*RPCStream, error) {
+	rc.mu.Lock()
+	if rc.closed {
+		rc.mu.Unlock()
+		return nil, ErrClientClosed
+	}
+	id := fmt.Sprintf("stream-%d", rc.idGen)
+	rc.idGen++
+	rc.mu.Unlock()
+
+	req := &RPCRequest{
+		ID:     id,
+		Method: method,
+		Stream: true,
+	}
+
+	if params != nil {
+		paramsData, err := json.Marshal(params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal params: %w", err)
+		}
+		req.Params = paramsData
+	}
+
+	conn, err := rc.dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect: %w", err)
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	return &RPCStream{
+		id:      id,
+		conn:    conn,
+		decoder: json.NewDecoder(conn),
+	}, nil
+}
+
+// Recv returns the next message. It returns io.EOF once the server ends the
+// stream, or the server's error if the handler failed.
+func (s *RPCStream) Recv() (interface{}, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.done {
+		return nil, s.err
+	}
+
+	var response RPCResponse
+	if err := s.decoder.Decode(&response); err != nil {
+		if s.closing.Load() {
+			return nil, s.finish(io.EOF)
+		}
+		return nil, s.finish(fmt.Errorf("failed to receive stream frame: %w", err))
+	}
+
+	switch response.Frame {
+	case FrameData:
+		return response.Result, nil
+	case FrameEnd:
+		return nil, s.finish(io.EOF)
+	case FrameError:
+		message := "stream failed"
+		if response.Error != nil {
+			message = response.Error.Message
+		}
+		return nil, s.finish(fmt.Errorf("RPC error: %s", message))
+	default:
+		return nil, s.finish(fmt.Errorf("unexpected stream frame: %q", response.Frame))
+	}
+}
+
+// Close cancels the stream and releases its connection. A Recv blocked on
+// the connection returns io.EOF.
+func (s *RPCStream) Close() error {
+	if s.closing.Swap(true) {
+		return nil
+	}
+	err := s.conn.Close()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.done = true
+		s.err = io.EOF
+	}
+	return err
+}
+
+// finish marks the stream as done with err. Must be called with s.mu held.
+func (s *RPCStream) finish(err error) error {
+	s.done = true
+	s.err = err
+	_ = s.conn.Close()
+	return err
+}
@@ -4,25 +4,32 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"sync"
+	"time"
 
 	"github.com/dop251/goja"
+
+	"gots-runtime/internal/eventloop"
 )
 
 // TypeScriptRPCServer wraps RPC server for TypeScript
 type TypeScriptRPCServer struct {
-	server  *RPCServer
-	engine  *goja.Runtime
-	ctx     context.Context
-	mu      sync.RWMutex
+	server    *RPCServer
+	engine    *goja.Runtime
+	eventLoop *eventloop.Loop
+	ctx       context.Context
+	mu        sync.RWMutex
 }
 
-// NewTypeScriptRPCServer creates a new TypeScript-wrapped RPC server
-func NewTypeScriptRPCServer(engine *goja.Runtime, ctx context.Context) *TypeScriptRPCServer {
+// NewTypeScriptRPCServer creates a new TypeScript-wrapped RPC server whose
+// handlers run on eventLoop
+func NewTypeScriptRPCServer(engine *goja.Runtime, eventLoop *eventloop.Loop, ctx context.Context) *TypeScriptRPCServer {
 	return &TypeScriptRPCServer{
-		server: NewRPCServer(ctx),
-		engine: engine,
-		ctx:    ctx,
+		server:    NewRPCServer(ctx),
+		engine:    engine,
+		eventLoop: eventLoop,
+		ctx:       ctx,
 	}
 }
 
@@ -47,13 +54,40 @@ func (tsr *TypeScriptRPCServer) ToJSObject() *goja.Object {
 			}
 			
 			// Call TypeScript handler
-			result, err := handlerFunc(nil, tsr.engine.ToValue(paramsData))
-			if err != nil {
-				return nil, fmt.Errorf("handler error: %w", err)
+			return tsr.callHandler(ctx, func() (goja.Value, error) {
+				return handlerFunc(nil, tsr.engine.ToValue(paramsData))
+			})
+		})
+	})
+	
+	// RegisterStream registers a server-streaming handler. The handler receives
+	// the params and a stream object; it calls stream.send(value) for each
+	// message and the stream ends when the handler (or its promise) completes.
+	obj.Set("registerStream", func(method string, handler goja.Value) {
+		handlerFunc, ok := goja.AssertFunction(handler)
+		if !ok {
+			panic(tsr.engine.ToValue("handler must be a function"))
+		}
+		
+		tsr.server.RegisterStreamHandler(method, func(ctx context.Context, params json.RawMessage, send func(interface{}) error) error {
+			var paramsData interface{}
+			if len(params) > 0 {
+				if err := json.Unmarshal(params, &paramsData); err != nil {
+					return fmt.Errorf("failed to parse params: %w", err)
+				}
 			}
 			
-			// Export the result
-			return result.Export(), nil
+			_, err := tsr.callHandler(ctx, func() (goja.Value, error) {
+				streamObj := tsr.engine.NewObject()
+				streamObj.Set("send", func(value goja.Value) bool {
+					return send(value.Export()) == nil
+				})
+				streamObj.Set("isCancelled", func() bool {
+					return ctx.Err() != nil
+				})
+				return handlerFunc(nil, tsr.engine.ToValue(paramsData), streamObj)
+			})
+			return err
 		})
 	})
 	
@@ -101,21 +135,29 @@ func (tsr *TypeScriptRPCServer) ToJSObject() *goja.Object {
 
 // TypeScriptRPCClient wraps RPC client for TypeScript
 type TypeScriptRPCClient struct {
-	client *RPCClient
-	engine *goja.Runtime
-	mu     sync.RWMutex
+	client    *RPCClient
+	engine    *goja.Runtime
+	eventLoop *eventloop.Loop
+	mu        sync.RWMutex
+}
+
+// NewTypeScriptRPCClient creates a new TypeScript-wrapped RPC client whose
+// connection callbacks run on eventLoop
+func NewTypeScriptRPCClient(engine *goja.Runtime, eventLoop *eventloop.Loop, address string) (*TypeScriptRPCClient, error) {
+	return NewTypeScriptRPCClientWithOptions(engine, eventLoop, address, DefaultClientOptions())
 }
 
-// NewTypeScriptRPCClient creates a new TypeScript-wrapped RPC client
-func NewTypeScriptRPCClient(engine *goja.Runtime, address string) (*TypeScriptRPCClient, error) {
-	client, err := NewRPCClient(address)
+// NewTypeScriptRPCClientWithOptions creates a new TypeScript-wrapped RPC client with connection options
+func NewTypeScriptRPCClientWithOptions(engine *goja.Runtime, eventLoop *eventloop.Loop, address string, opts ClientOptions) (*TypeScriptRPCClient, error) {
+	client, err := NewRPCClientWithOptions(address, opts)
 	if err != nil {
 		return nil, err
 	}
 	
 	return &TypeScriptRPCClient{
-		client: client,
-		engine: engine,
+		client:    client,
+		engine:    engine,
+		eventLoop: eventLoop,
 	}, nil
 }
 
@@ -123,37 +165,145 @@ func NewTypeScriptRPCClient(engine *goja.Runtime, address string) (*TypeScriptRP
 func (tsc *TypeScriptRPCClient) ToJSObject() *goja.Object {
 	obj := tsc.engine.NewObject()
 	
-	// Call method
+	// Call method. The call is made in a goroutine and its promise settled
+	// on the event loop, which owns the VM.
 	obj.Set("call", func(method string, params goja.Value) *goja.Promise {
 		promise, resolve, reject := tsc.engine.NewPromise()
 		
+		var paramsData interface{}
+		if params != nil && !goja.IsUndefined(params) {
+			paramsData = params.Export()
+		}
+		
+		settle := tsc.settler()
 		go func() {
-			var paramsData interface{}
-			if params != nil && !goja.IsUndefined(params) {
-				paramsData = params.Export()
-			}
-			
 			result, err := tsc.client.Call(method, paramsData)
-			if err != nil {
-				reject(tsc.engine.ToValue(err.Error()))
-			} else {
-				resolve(tsc.engine.ToValue(result))
-			}
+			settle(func() {
+				if err != nil {
+					reject(tsc.engine.ToValue(err.Error()))
+				} else {
+					resolve(tsc.engine.ToValue(result))
+				}
+			})
 		}()
 		
 		return promise
 	})
 	
+	// CallStream method returns an async iterator over streamed messages
+	obj.Set("callStream", func(method string, params goja.Value) *goja.Object {
+		var paramsData interface{}
+		if params != nil && !goja.IsUndefined(params) {
+			paramsData = params.Export()
+		}
+		
+		var stream *RPCStream
+		var startErr error
+		var startOnce sync.Once
+		start := func() {
+			stream, startErr = tsc.client.CallStream(method, paramsData)
+		}
+		
+		// iterResult creates the { value, done } result of the iterator
+		iterResult := func(value goja.Value, done bool) *goja.Object {
+			result := tsc.engine.NewObject()
+			result.Set("value", value)
+			result.Set("done", done)
+			return result
+		}
+		
+		iter := tsc.engine.NewObject()
+		iter.Set("next", func() *goja.Promise {
+			promise, resolve, reject := tsc.engine.NewPromise()
+			
+			settle := tsc.settler()
+			go func() {
+				startOnce.Do(start)
+				if startErr != nil {
+					settle(func() { reject(tsc.engine.ToValue(startErr.Error())) })
+					return
+				}
+				
+				value, err := stream.Recv()
+				settle(func() {
+					if err == io.EOF {
+						resolve(iterResult(goja.Undefined(), true))
+					} else if err != nil {
+						reject(tsc.engine.ToValue(err.Error()))
+					} else {
+						resolve(iterResult(tsc.engine.ToValue(value), false))
+					}
+				})
+			}()
+			
+			return promise
+		})
+		
+		iter.Set("return", func() *goja.Promise {
+			promise, resolve, _ := tsc.engine.NewPromise()
+			
+			settle := tsc.settler()
+			go func() {
+				startOnce.Do(func() {})
+				if stream != nil {
+					_ = stream.Close()
+				}
+				settle(func() { resolve(iterResult(goja.Undefined(), true)) })
+			}()
+			
+			return promise
+		})
+		
+		// Make the iterator usable with for await when Symbol.asyncIterator exists
+		if symbolCtor := tsc.engine.Get("Symbol"); symbolCtor != nil && !goja.IsUndefined(symbolCtor) {
+			if sym, ok := symbolCtor.ToObject(tsc.engine).Get("asyncIterator").(*goja.Symbol); ok {
+				_ = iter.SetSymbol(sym, func() *goja.Object { return iter })
+			}
+		}
+		
+		return iter
+	})
+	
+	// Connection state events. The client reports them from its reconnect
+	// goroutine, so the callbacks are run on the event loop, which owns the
+	// VM.
+	obj.Set("onConnect", func(callback goja.Callable) {
+		tsc.client.OnConnect(func() {
+			_ = tsc.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				_, _ = callback(nil)
+				return nil
+			}, eventloop.PriorityNormal))
+		})
+	})
+	
+	obj.Set("onDisconnect", func(callback goja.Callable) {
+		tsc.client.OnDisconnect(func(err error) {
+			message := err.Error()
+			_ = tsc.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				_, _ = callback(nil, tsc.engine.ToValue(message))
+				return nil
+			}, eventloop.PriorityNormal))
+		})
+	})
+	
+	obj.Set("isConnected", func() bool {
+		return tsc.client.IsConnected()
+	})
+	
 	// Close method
 	obj.Set("close", func() *goja.Promise {
 		promise, resolve, reject := tsc.engine.NewPromise()
 		
+		settle := tsc.settler()
 		go func() {
-			if err := tsc.client.Close(); err != nil {
-				reject(tsc.engine.ToValue(err.Error()))
-			} else {
-				resolve(tsc.engine.ToValue(true))
-			}
+			err := tsc.client.Close()
+			settle(func() {
+				if err != nil {
+					reject(tsc.engine.ToValue(err.Error()))
+				} else {
+					resolve(tsc.engine.ToValue(true))
+				}
+			})
 		}()
 		
 		return promise
@@ -162,3 +312,122 @@ func (tsc *TypeScriptRPCClient) ToJSObject() *goja.Object {
 	return obj
 }
 
+// settler references the event loop until the returned function is called
+// with a function that settles a promise, which is then run on the loop.
+// The goroutines making calls pass only Go values to it, as the VM must
+// only be used on the loop; the reference keeps the loop from going idle
+// while a call is in flight.
+func (tsc *TypeScriptRPCClient) settler() func(settle func()) {
+	tsc.eventLoop.Ref()
+	var once sync.Once
+	return func(settle func()) {
+		once.Do(func() {
+			err := tsc.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				defer tsc.eventLoop.Unref()
+				settle()
+				return nil
+			}, eventloop.PriorityNormal))
+			if err != nil {
+				// The loop stopped; nothing can observe the promise anymore
+				tsc.eventLoop.Unref()
+			}
+		})
+	}
+}
+
+
+// handlerResult is how a JavaScript handler finished
+type handlerResult struct {
+	value interface{}
+	err   error
+}
+
+// callHandler calls a JavaScript handler on the event loop, which owns the
+// VM, and waits until the value it returns settles if it is a promise. The
+// settled value is exported on the loop.
+func (tsr *TypeScriptRPCServer) callHandler(ctx context.Context, call func() (goja.Value, error)) (interface{}, error) {
+	done := make(chan handlerResult, 1)
+	err := tsr.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+		result, err := call()
+		if err != nil {
+			done <- handlerResult{err: fmt.Errorf("handler error: %w", err)}
+			return nil
+		}
+		
+		obj, ok := result.(*goja.Object)
+		if _, isPromise := result.Export().(*goja.Promise); !ok || !isPromise {
+			done <- handlerResult{value: result.Export()}
+			return nil
+		}
+		then, _ := goja.AssertFunction(obj.Get("then"))
+		onFulfilled := func(value goja.Value) {
+			done <- handlerResult{value: value.Export()}
+		}
+		onRejected := func(reason goja.Value) {
+			done <- handlerResult{err: fmt.Errorf("handler error: %v", reason)}
+		}
+		if _, err := then(obj, tsr.engine.ToValue(onFulfilled), tsr.engine.ToValue(onRejected)); err != nil {
+			done <- handlerResult{err: fmt.Errorf("handler error: %w", err)}
+		}
+		return nil
+	}, eventloop.PriorityNormal))
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule handler: %w", err)
+	}
+	
+	select {
+	case res := <-done:
+		return res.value, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// ParseClientOptions builds client options from a JavaScript options object.
+// Supported fields: reconnect, onDisconnected ("queue" or "fail"), maxRetries,
+// initialBackoffMs, maxBackoffMs and dialTimeoutMs.
+func ParseClientOptions(value goja.Value) ClientOptions {
+	opts := DefaultClientOptions()
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return opts
+	}
+	
+	raw, ok := value.Export().(map[string]interface{})
+	if !ok {
+		return opts
+	}
+	
+	if v, ok := raw["reconnect"].(bool); ok {
+		opts.Reconnect = v
+	}
+	if v, ok := raw["onDisconnected"].(string); ok {
+		opts.QueueWhileDisconnected = v != "fail"
+	}
+	if v, ok := toInt64(raw["maxRetries"]); ok {
+		opts.MaxRetries = int(v)
+	}
+	if v, ok := toInt64(raw["initialBackoffMs"]); ok {
+		opts.InitialBackoff = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := toInt64(raw["maxBackoffMs"]); ok {
+		opts.MaxBackoff = time.Duration(v) * time.Millisecond
+	}
+	if v, ok := toInt64(raw["dialTimeoutMs"]); ok {
+		opts.DialTimeout = time.Duration(v) * time.Millisecond
+	}
+	
+	return opts
+}
+
+// toInt64 converts an exported JavaScript number to int64
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case float64:
+		return int64(n), true
+	case int:
+		return int64(n), true
+	}
+	return 0, false
+}
@@ -6,6 +6,7 @@ import (
 	"sync"
 
 	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/observability"
 	"gots-runtime/internal/worker"
 )
 
@@ -21,10 +22,16 @@ type AdvancedScheduler struct {
 
 // NewAdvancedScheduler creates a new advanced scheduler
 func NewAdvancedScheduler(ctx context.Context, eventLoop *eventloop.Loop) *AdvancedScheduler {
+	return NewAdvancedSchedulerWithMetrics(ctx, eventLoop, nil)
+}
+
+// NewAdvancedSchedulerWithMetrics creates a new advanced scheduler whose
+// worker pool reports into metrics
+func NewAdvancedSchedulerWithMetrics(ctx context.Context, eventLoop *eventloop.Loop, metrics *observability.MetricsCollector) *AdvancedScheduler {
 	schedCtx, cancel := context.WithCancel(ctx)
 	
 	// Create worker pool with min 2, max 10 workers
-	pool := worker.NewPool(schedCtx, 2, 10)
+	pool := worker.NewPoolWithMetrics(schedCtx, 2, 10, metrics)
 	
 	return &AdvancedScheduler{
 		workerPool: pool,
@@ -2,8 +2,13 @@ package runtime
 
 import (
 	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
 	"sync"
 	"time"
+
+	"gots-runtime/internal/config"
 )
 
 // Domain represents a domain in DDD
@@ -19,7 +24,28 @@ type DomainBoundary struct {
 	FromDomain string
 	ToDomain   string
 	Allowed    bool
-	Protocol   string // RPC, HTTP, etc.
+	Protocol   string // permitted protocols, comma-separated; empty allows any
+}
+
+// Protocols a module can use to reach another module
+const (
+	ProtocolDirect = "direct" // in-process require/import
+	ProtocolRPC    = "rpc"
+	ProtocolHTTP   = "http"
+	ProtocolEvent  = "event"
+)
+
+// AllowsProtocol reports whether the boundary permits calls made via protocol
+func (b DomainBoundary) AllowsProtocol(protocol string) bool {
+	if strings.TrimSpace(b.Protocol) == "" {
+		return true
+	}
+	for _, p := range strings.Split(b.Protocol, ",") {
+		if strings.EqualFold(strings.TrimSpace(p), protocol) {
+			return true
+		}
+	}
+	return false
 }
 
 // DDDEnforcer enforces Domain-Driven Design principles
@@ -49,7 +75,8 @@ func (de *DDDEnforcer) RegisterDomain(name string, modules []string) {
 	}
 }
 
-// AddBoundary adds a boundary between domains
+// AddBoundary adds a boundary between domains. protocol lists the protocols
+// cross-domain calls must use, e.g. "rpc" or "rpc,http".
 func (de *DDDEnforcer) AddBoundary(fromDomain, toDomain, protocol string, allowed bool) {
 	de.mu.Lock()
 	defer de.mu.Unlock()
@@ -69,8 +96,10 @@ func (de *DDDEnforcer) AddBoundary(fromDomain, toDomain, protocol string, allowe
 	from.mu.Unlock()
 }
 
-// CheckAccess checks if a module can access another module
-func (de *DDDEnforcer) CheckAccess(fromModule, toModule string) error {
+// CheckAccess checks if a module can access another module via the given
+// protocol. Calls within a domain are always allowed; cross-domain calls
+// must use a protocol permitted by the boundary.
+func (de *DDDEnforcer) CheckAccess(fromModule, toModule, protocol string) error {
 	de.mu.RLock()
 	defer de.mu.RUnlock()
 
@@ -102,6 +131,10 @@ func (de *DDDEnforcer) CheckAccess(fromModule, toModule string) error {
 				return fmt.Errorf("access denied: boundary between %s and %s is not allowed",
 					fromDomain, toDomain)
 			}
+			if !boundary.AllowsProtocol(protocol) {
+				return fmt.Errorf("access denied: %s -> %s must use %s, not %s",
+					fromDomain, toDomain, boundary.Protocol, protocol)
+			}
 			return nil
 		}
 	}
@@ -111,12 +144,13 @@ func (de *DDDEnforcer) CheckAccess(fromModule, toModule string) error {
 		fromDomain, toDomain)
 }
 
-// findDomainForModule finds the domain for a module
+// findDomainForModule finds the domain for a module. A domain module ending
+// in a slash is a directory and contains the modules under it.
 func (de *DDDEnforcer) findDomainForModule(moduleID string) string {
 	for domainName, domain := range de.domains {
 		domain.mu.RLock()
 		for _, mod := range domain.Modules {
-			if mod == moduleID {
+			if mod == moduleID || (strings.HasSuffix(mod, "/") && strings.HasPrefix(moduleID, mod)) {
 				domain.mu.RUnlock()
 				return domainName
 			}
@@ -126,6 +160,24 @@ func (de *DDDEnforcer) findDomainForModule(moduleID string) string {
 	return ""
 }
 
+// dependencyProtocol returns the protocol used to reach a graph node: the
+// service type for promoted services, otherwise a direct require
+func dependencyProtocol(nodes map[string]*ServiceNode, nodeID string) string {
+	node, ok := nodes[nodeID]
+	if !ok {
+		return ProtocolDirect
+	}
+
+	node.mu.RLock()
+	defer node.mu.RUnlock()
+	switch node.ServiceType {
+	case ProtocolRPC, ProtocolHTTP, ProtocolEvent:
+		return node.ServiceType
+	default:
+		return ProtocolDirect
+	}
+}
+
 // EnforceModuleBoundaries enforces module boundaries
 func (de *DDDEnforcer) EnforceModuleBoundaries() error {
 	// Get all nodes from service graph
@@ -137,7 +189,7 @@ func (de *DDDEnforcer) EnforceModuleBoundaries() error {
 		node.mu.RUnlock()
 
 		for _, depID := range dependencies {
-			if err := de.CheckAccess(nodeID, depID); err != nil {
+			if err := de.CheckAccess(nodeID, depID, dependencyProtocol(nodes, depID)); err != nil {
 				return fmt.Errorf("boundary violation: %s -> %s: %w", nodeID, depID, err)
 			}
 		}
@@ -146,6 +198,69 @@ func (de *DDDEnforcer) EnforceModuleBoundaries() error {
 	return nil
 }
 
+// ModuleAccess checks the calls between the modules of a running program
+// against the domains of the project configuration
+type ModuleAccess struct {
+	enforcer *DDDEnforcer
+	root     string
+}
+
+// NewModuleAccess creates a ModuleAccess for the domains of a project whose
+// module paths are relative to root
+func NewModuleAccess(root string, domains []config.DomainConfig) *ModuleAccess {
+	if abs, err := filepath.Abs(root); err == nil {
+		root = abs
+	}
+	enforcer := NewDDDEnforcer(NewServiceGraph())
+	for _, domain := range domains {
+		// Entries are files or directories; directories contain every
+		// module under them
+		modules := make([]string, 0, len(domain.Modules)*2)
+		for _, entry := range domain.Modules {
+			entry = path.Clean(filepath.ToSlash(entry))
+			modules = append(modules, entry, entry+"/")
+		}
+		enforcer.RegisterDomain(domain.Name, modules)
+	}
+	for _, domain := range domains {
+		for _, boundary := range domain.Allow {
+			enforcer.AddBoundary(domain.Name, boundary.Domain, boundary.Protocol, true)
+		}
+	}
+	return &ModuleAccess{enforcer: enforcer, root: root}
+}
+
+// Check returns an error if the module in file from may not reach the
+// module in file to via protocol. Files outside every domain, such as those
+// of the stdlib, are not subject to boundaries.
+func (ma *ModuleAccess) Check(from, to, protocol string) error {
+	fromID, toID := ma.moduleID(from), ma.moduleID(to)
+	if _, err := ma.enforcer.GetDomainForModule(fromID); err != nil {
+		return nil
+	}
+	if _, err := ma.enforcer.GetDomainForModule(toID); err != nil {
+		return nil
+	}
+	if err := ma.enforcer.CheckAccess(fromID, toID, protocol); err != nil {
+		return fmt.Errorf("%s cannot reach %s: %w", fromID, toID, err)
+	}
+	return nil
+}
+
+// moduleID returns the ID of the module in file: its slash separated path
+// relative to the project root
+func (ma *ModuleAccess) moduleID(file string) string {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(ma.root, abs)
+	if err != nil {
+		return filepath.ToSlash(abs)
+	}
+	return filepath.ToSlash(rel)
+}
+
 // GetDomainForModule gets the domain for a module
 func (de *DDDEnforcer) GetDomainForModule(moduleID string) (string, error) {
 	de.mu.RLock()
@@ -173,6 +288,7 @@ type BoundaryViolation struct {
 	ToModule   string
 	FromDomain string
 	ToDomain   string
+	Protocol   string
 	Timestamp  time.Time
 	Severity   string // "warning", "critical"
 }
@@ -188,12 +304,12 @@ func NewDDDValidator(enforcer *DDDEnforcer) *DDDValidator {
 
 // ValidateModule validates a module
 func (dv *DDDValidator) ValidateModule(moduleID string) error {
-	return dv.enforcer.CheckAccess(moduleID, moduleID)
+	return dv.enforcer.CheckAccess(moduleID, moduleID, ProtocolDirect)
 }
 
-// ValidateDependency validates a dependency
-func (dv *DDDValidator) ValidateDependency(fromModule, toModule string) error {
-	err := dv.enforcer.CheckAccess(fromModule, toModule)
+// ValidateDependency validates a dependency made via the given protocol
+func (dv *DDDValidator) ValidateDependency(fromModule, toModule, protocol string) error {
+	err := dv.enforcer.CheckAccess(fromModule, toModule, protocol)
 
 	if err != nil {
 		dv.mu.Lock()
@@ -207,6 +323,7 @@ func (dv *DDDValidator) ValidateDependency(fromModule, toModule string) error {
 			ToModule:   toModule,
 			FromDomain: fromDomain,
 			ToDomain:   toDomain,
+			Protocol:   protocol,
 			Timestamp:  time.Now(),
 			Severity:   "critical",
 		}
@@ -231,7 +348,8 @@ func (dv *DDDValidator) ValidateAllDependencies() []BoundaryViolation {
 		node.mu.RUnlock()
 
 		for _, depID := range dependencies {
-			if err := dv.ValidateDependency(nodeID, depID); err != nil {
+			protocol := dependencyProtocol(nodes, depID)
+			if err := dv.ValidateDependency(nodeID, depID, protocol); err != nil {
 				fromDomain := dv.enforcer.findDomainForModule(nodeID)
 				toDomain := dv.enforcer.findDomainForModule(depID)
 
@@ -240,6 +358,7 @@ func (dv *DDDValidator) ValidateAllDependencies() []BoundaryViolation {
 					ToModule:   depID,
 					FromDomain: fromDomain,
 					ToDomain:   toDomain,
+					Protocol:   protocol,
 					Timestamp:  time.Now(),
 					Severity:   "critical",
 				})
@@ -0,0 +1,146 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"gots-runtime/internal/config"
+	"gots-runtime/internal/security"
+)
+
+// newDomainProject writes the files of a project whose orders domain may
+// reach its billing domain only via protocol, and returns a runtime for it
+// running in the project directory
+func newDomainProject(t *testing.T, protocol string, files map[string]string) *Runtime {
+	t.Helper()
+	root := t.TempDir()
+	files["gots.json"] = fmt.Sprintf(`{
+		"name": "shop",
+		"version": "0.1.0",
+		"domains": [
+			{ "name": "orders", "modules": ["orders"], "allow": [{ "domain": "billing", "protocol": %q }] },
+			{ "name": "billing", "modules": ["billing"] }
+		]
+	}`, protocol)
+	for name, content := range files {
+		file := filepath.Join(root, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Relative requires resolve against the working directory
+	t.Chdir(root)
+
+	watcher, err := config.NewWatcher(filepath.Join(root, "gots.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt.SetConfigWatcher(watcher)
+	if err := rt.EnableSecureAPIs(security.NewPermissionManager(), "main"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rt.Shutdown)
+	return rt
+}
+
+// runEntry runs the entry file and the callbacks it schedules and returns
+// the value of result
+func runEntry(t *testing.T, rt *Runtime, entry string) string {
+	t.Helper()
+	if _, err := rt.ExecuteFile(entry); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rt.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	result, err := rt.ExecuteString(`result`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.String()
+}
+
+func TestRequireAcrossDomainsNeedsDirectProtocol(t *testing.T) {
+	files := map[string]string{
+		"billing/index.js": `module.exports = { charge: (n) => n * 2 };`,
+		"orders/main.js": `
+			var result;
+			try {
+				result = 'charged ' + require('./billing/index.js').charge(21);
+			} catch (e) {
+				result = String(e);
+			}
+		`,
+	}
+
+	rt := newDomainProject(t, "rpc", files)
+	if got := runEntry(t, rt, "orders/main.js"); !strings.Contains(got, "orders -> billing must use rpc, not direct") {
+		t.Errorf("require via a disallowed protocol gave %q", got)
+	}
+
+	rt = newDomainProject(t, "rpc,direct", files)
+	if got := runEntry(t, rt, "orders/main.js"); got != "charged 42" {
+		t.Errorf("require via an allowed protocol gave %q", got)
+	}
+}
+
+func TestRPCAcrossDomainsNeedsRPCProtocol(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	files := map[string]string{
+		"billing/server.js": fmt.Sprintf(`
+			const server = rpc.createServer();
+			server.register('charge', (params) => params.n * 2);
+			server.listen(%q);
+			module.exports = server;
+		`, address),
+		"orders/client.js": fmt.Sprintf(`
+			module.exports = async () => {
+				const client = await rpc.createClient(%q);
+				try {
+					return 'charged ' + await client.call('charge', { n: 21 });
+				} finally {
+					await client.close();
+				}
+			};
+		`, address),
+		// The entry is in no domain, so it may require both
+		"main.js": `
+			var result;
+			const server = require('./billing/server.js');
+			require('./orders/client.js')()
+				.then((value) => { result = value; }, (e) => { result = String(e); })
+				.finally(() => server.close());
+		`,
+	}
+
+	rt := newDomainProject(t, "direct", files)
+	if got := runEntry(t, rt, "main.js"); !strings.Contains(got, "orders -> billing must use direct, not rpc") {
+		t.Errorf("RPC via a disallowed protocol gave %q", got)
+	}
+
+	rt = newDomainProject(t, "rpc", files)
+	if got := runEntry(t, rt, "main.js"); got != "charged 42" {
+		t.Errorf("RPC via an allowed protocol gave %q", got)
+	}
+}
@@ -2,25 +2,39 @@ package runtime
 
 import (
 	"context"
+	crand "crypto/rand"
 	"fmt"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 )
 
-// DeterministicScheduler provides deterministic scheduling for debug/prod parity
+// DeterministicScheduler provides deterministic scheduling for debug/prod
+// parity. In deterministic mode tasks run one at a time, waiting tasks in
+// order of priority and then of scheduling, and task IDs and the per-task
+// random sources returned by TaskRand are derived from the seed, so the same
+// seed and the same scheduled tasks reproduce the same execution order and
+// IDs. Task IDs are prefixed with the run ID, which keeps them unique across
+// runs; the rest of an ID, returned by ReproducibleID, is what runs with the
+// same seed share.
 type DeterministicScheduler struct {
 	tasks              []TaskExecution
+	queue              []TaskExecution // deterministic tasks waiting to run
+	draining           bool
 	execOrder          []string
 	taskIDGen          uint64
 	mu                 sync.RWMutex
 	deterministic      bool
 	seed               int64
+	runID              string // unique to this scheduler run
+	idTag              string // derived from the seed, shared by runs with it
 	rng                *rand.Rand
 	executionLog       []ExecutionRecord
 	taskCompletionChan map[string]chan TaskResult
 	waitGroup          sync.WaitGroup
 	stopped            bool
+	failure            error // set when a cancelled task could not be stopped
 }
 
 // TaskExecution represents a task with metadata
@@ -36,13 +50,27 @@ type TaskExecution struct {
 // ExecutionRecord represents a recorded execution
 type ExecutionRecord struct {
 	TaskID    string
-	Status    string // "pending", "running", "completed", "failed"
+	Status    string // "pending", "running", "completed", "failed", "cancelled"
 	StartTime time.Time
 	EndTime   time.Time
 	Duration  time.Duration
 	Error     error
 }
 
+// taskRandKey is the context key of a task's random source
+type taskRandKey struct{}
+
+// TaskRand returns the random source of a task scheduled on a
+// DeterministicScheduler. It is seeded from the scheduler seed, so tasks that
+// draw random numbers only from it produce reproducible results. Outside a
+// scheduled task it returns a time-seeded source.
+func TaskRand(ctx context.Context) *rand.Rand {
+	if rng, ok := ctx.Value(taskRandKey{}).(*rand.Rand); ok {
+		return rng
+	}
+	return rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
 // TaskResult represents the result of task execution
 type TaskResult struct {
 	TaskID string
@@ -52,12 +80,16 @@ type TaskResult struct {
 
 // NewDeterministicScheduler creates a new deterministic scheduler
 func NewDeterministicScheduler(seed int64) *DeterministicScheduler {
+	rng := rand.New(rand.NewSource(seed))
 	return &DeterministicScheduler{
 		tasks:              make([]TaskExecution, 0),
+		queue:              make([]TaskExecution, 0),
 		execOrder:          make([]string, 0),
 		deterministic:      true,
 		seed:               seed,
-		rng:                rand.New(rand.NewSource(seed)),
+		runID:              generateRunID(),
+		idTag:              generateIDTag(rng),
+		rng:                rng,
 		executionLog:       make([]ExecutionRecord, 0),
 		taskCompletionChan: make(map[string]chan TaskResult),
 		stopped:            false,
@@ -74,12 +106,18 @@ func (ds *DeterministicScheduler) ScheduleWithPriority(task Task, priority int)
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
 
+	if ds.failure != nil {
+		return fmt.Errorf("scheduler failed: %w", ds.failure)
+	}
 	if ds.stopped {
 		return fmt.Errorf("scheduler is stopped")
 	}
 
 	taskID := ds.generateTaskID()
-	ctx, cancel := context.WithCancel(context.Background())
+	// Each task gets its own source so its draws do not depend on how tasks
+	// interleave
+	taskRng := rand.New(rand.NewSource(ds.rng.Int63()))
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), taskRandKey{}, taskRng))
 
 	execution := TaskExecution{
 		ID:        taskID,
@@ -91,20 +129,22 @@ func (ds *DeterministicScheduler) ScheduleWithPriority(task Task, priority int)
 	}
 
 	ds.tasks = append(ds.tasks, execution)
-	ds.execOrder = append(ds.execOrder, taskID)
 	ds.taskCompletionChan[taskID] = make(chan TaskResult, 1)
 
-	// Record execution start
+	// Record the task as pending until it starts
 	ds.logExecution(ExecutionRecord{
-		TaskID:    taskID,
-		Status:    "pending",
-		StartTime: time.Now(),
+		TaskID: taskID,
+		Status: "pending",
 	})
 
 	// In deterministic mode, execute tasks in strict order
 	if ds.deterministic {
 		ds.waitGroup.Add(1)
-		go ds.executeTaskDeterministic(execution)
+		ds.enqueue(execution)
+		if !ds.draining {
+			ds.draining = true
+			go ds.drainQueue()
+		}
 	} else {
 		ds.waitGroup.Add(1)
 		go ds.executeTaskConcurrent(execution)
@@ -113,74 +153,153 @@ func (ds *DeterministicScheduler) ScheduleWithPriority(task Task, priority int)
 	return nil
 }
 
-// executeTaskDeterministic executes a task in deterministic order
-func (ds *DeterministicScheduler) executeTaskDeterministic(execution TaskExecution) {
-	defer ds.waitGroup.Done()
-
-	ds.updateExecutionStatus(execution.ID, "running")
-	startTime := time.Now()
-
-	err := execution.Task.Execute(execution.Context)
-
-	duration := time.Since(startTime)
+// enqueue inserts a deterministic task into the queue after the tasks with
+// the same or a higher priority, so that the queue runs in order of priority
+// and tasks with equal priority in scheduling order. The caller must hold
+// ds.mu.
+func (ds *DeterministicScheduler) enqueue(execution TaskExecution) {
+	i := len(ds.queue)
+	for i > 0 && ds.queue[i-1].Priority < execution.Priority {
+		i--
+	}
+	ds.queue = append(ds.queue, TaskExecution{})
+	copy(ds.queue[i+1:], ds.queue[i:])
+	ds.queue[i] = execution
+}
 
-	// Record result
-	ds.mu.Lock()
-	ds.executionLog = append(ds.executionLog, ExecutionRecord{
-		TaskID:    execution.ID,
-		Status:    "completed",
-		StartTime: startTime,
-		EndTime:   time.Now(),
-		Duration:  duration,
-		Error:     err,
-	})
-	ds.mu.Unlock()
+// drainQueue runs queued deterministic tasks one at a time in queue order
+// until the queue is empty. A running task is not preempted by one with a
+// higher priority scheduled after it started.
+func (ds *DeterministicScheduler) drainQueue() {
+	for {
+		ds.mu.Lock()
+		if len(ds.queue) == 0 {
+			ds.draining = false
+			ds.mu.Unlock()
+			return
+		}
+		execution := ds.queue[0]
+		ds.queue = ds.queue[1:]
+		ds.mu.Unlock()
 
-	// Send result to channel
-	ds.taskCompletionChan[execution.ID] <- TaskResult{
-		TaskID: execution.ID,
-		Error:  err,
+		ds.executeTaskDeterministic(execution)
 	}
 }
 
+// executeTaskDeterministic executes a task in deterministic order
+func (ds *DeterministicScheduler) executeTaskDeterministic(execution TaskExecution) {
+	defer ds.waitGroup.Done()
+	ds.runTask(execution)
+}
+
 // executeTaskConcurrent executes a task concurrently
 func (ds *DeterministicScheduler) executeTaskConcurrent(execution TaskExecution) {
 	defer ds.waitGroup.Done()
+	ds.runTask(execution)
+}
 
-	ds.updateExecutionStatus(execution.ID, "running")
-	startTime := time.Now()
+// cancelGracePeriod is how long a cancelled task may keep running before the
+// scheduler gives up on stopping it and fails
+const cancelGracePeriod = time.Second
 
-	err := execution.Task.Execute(execution.Context)
+// runTask executes a task, records it in the execution order and log, and
+// delivers its result. Tasks cancelled before they start are skipped, and
+// once the scheduler failed no task runs; they fail with its error.
+func (ds *DeterministicScheduler) runTask(execution TaskExecution) {
+	defer execution.Cancel()
+
+	ctx := execution.Context
+	startTime := time.Now()
+	ds.mu.Lock()
+	failure := ds.failure
+	if ctx.Err() == nil && failure == nil {
+		ds.execOrder = append(ds.execOrder, execution.ID)
+		ds.updateExecutionRecord(execution.ID, func(record *ExecutionRecord) {
+			record.Status = "running"
+			record.StartTime = startTime
+		})
+	}
+	ds.mu.Unlock()
 
-	duration := time.Since(startTime)
+	var err error
+	switch {
+	case failure != nil:
+		err = fmt.Errorf("scheduler failed: %w", failure)
+	case ctx.Err() == nil:
+		err = ds.executeCancellable(execution)
+	}
+	endTime := time.Now()
 
 	// Record result
 	ds.mu.Lock()
-	ds.executionLog = append(ds.executionLog, ExecutionRecord{
-		TaskID:    execution.ID,
-		Status:    "completed",
-		StartTime: startTime,
-		EndTime:   time.Now(),
-		Duration:  duration,
-		Error:     err,
+	if ctx.Err() != nil && failure == nil {
+		err = ctx.Err()
+	}
+	ds.updateExecutionRecord(execution.ID, func(record *ExecutionRecord) {
+		switch {
+		case failure != nil:
+			record.Status = "failed"
+		case ctx.Err() != nil:
+			record.Status = "cancelled"
+		case err != nil:
+			record.Status = "failed"
+		default:
+			record.Status = "completed"
+		}
+		record.EndTime = endTime
+		record.Duration = endTime.Sub(startTime)
+		record.Error = err
 	})
+	ch := ds.taskCompletionChan[execution.ID]
 	ds.mu.Unlock()
 
 	// Send result to channel
-	ds.taskCompletionChan[execution.ID] <- TaskResult{
+	ch <- TaskResult{
 		TaskID: execution.ID,
 		Error:  err,
 	}
 }
 
-// updateExecutionStatus updates the execution status in the log
-func (ds *DeterministicScheduler) updateExecutionStatus(taskID, status string) {
+// executeCancellable runs a task until it returns. A cancelled task is
+// interrupted if it is Interruptible, e.g. a ScriptTask through
+// goja.Runtime.Interrupt, and waited for, as the next task must not start
+// while it runs. One that is still running after cancelGracePeriod fails
+// the scheduler, so that no further task can overlap with it.
+func (ds *DeterministicScheduler) executeCancellable(execution TaskExecution) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- execution.Task.Execute(execution.Context)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-execution.Context.Done():
+	}
+
+	if task, ok := execution.Task.(Interruptible); ok {
+		task.Interrupt(execution.Context.Err())
+	}
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(cancelGracePeriod):
+	}
+
 	ds.mu.Lock()
-	defer ds.mu.Unlock()
+	if ds.failure == nil {
+		ds.failure = fmt.Errorf("task %s did not stop within %v of being cancelled", execution.ID, cancelGracePeriod)
+	}
+	ds.mu.Unlock()
+	return execution.Context.Err()
+}
 
+// updateExecutionRecord updates the log record of a task. The caller must
+// hold ds.mu.
+func (ds *DeterministicScheduler) updateExecutionRecord(taskID string, update func(*ExecutionRecord)) {
 	for i := range ds.executionLog {
 		if ds.executionLog[i].TaskID == taskID {
-			ds.executionLog[i].Status = status
+			update(&ds.executionLog[i])
 			break
 		}
 	}
@@ -191,7 +310,8 @@ func (ds *DeterministicScheduler) logExecution(record ExecutionRecord) {
 	ds.executionLog = append(ds.executionLog, record)
 }
 
-// Shutdown shuts down the scheduler and waits for all tasks
+// Shutdown shuts down the scheduler and waits for all tasks. It returns the
+// error the scheduler failed with, if any.
 func (ds *DeterministicScheduler) Shutdown() error {
 	ds.mu.Lock()
 	ds.stopped = true
@@ -200,6 +320,11 @@ func (ds *DeterministicScheduler) Shutdown() error {
 	// Wait for all tasks to complete
 	ds.waitGroup.Wait()
 
+	ds.mu.RLock()
+	defer ds.mu.RUnlock()
+	if ds.failure != nil {
+		return fmt.Errorf("scheduler failed: %w", ds.failure)
+	}
 	return nil
 }
 
@@ -210,7 +335,7 @@ func (ds *DeterministicScheduler) SetDeterministic(deterministic bool) {
 	ds.deterministic = deterministic
 }
 
-// GetExecutionOrder returns the execution order
+// GetExecutionOrder returns the IDs of the tasks in the order they started
 func (ds *DeterministicScheduler) GetExecutionOrder() []string {
 	ds.mu.RLock()
 	defer ds.mu.RUnlock()
@@ -248,7 +373,10 @@ func (ds *DeterministicScheduler) GetTaskResult(taskID string, timeout time.Dura
 	}
 }
 
-// CancelTask cancels a scheduled task
+// CancelTask cancels a scheduled task. A pending task is marked cancelled
+// and never runs; a running task has its context cancelled and is
+// interrupted if it is Interruptible. Either way its result is a
+// context.Canceled error and its status becomes "cancelled".
 func (ds *DeterministicScheduler) CancelTask(taskID string) error {
 	ds.mu.Lock()
 	defer ds.mu.Unlock()
@@ -256,6 +384,11 @@ func (ds *DeterministicScheduler) CancelTask(taskID string) error {
 	for i := range ds.tasks {
 		if ds.tasks[i].ID == taskID {
 			ds.tasks[i].Cancel()
+			ds.updateExecutionRecord(taskID, func(record *ExecutionRecord) {
+				if record.Status == "pending" {
+					record.Status = "cancelled"
+				}
+			})
 			return nil
 		}
 	}
@@ -263,10 +396,41 @@ func (ds *DeterministicScheduler) CancelTask(taskID string) error {
 	return fmt.Errorf("task not found: %s", taskID)
 }
 
-// generateTaskID generates a deterministic task ID
+// RunID returns the identifier of the scheduler run, which prefixes its
+// task IDs
+func (ds *DeterministicScheduler) RunID() string {
+	return ds.runID
+}
+
+// ReproducibleID returns a task ID without its run ID prefix. Runs with the
+// same seed and the same scheduled tasks produce the same reproducible IDs.
+func (ds *DeterministicScheduler) ReproducibleID(taskID string) string {
+	return strings.TrimPrefix(taskID, ds.runID+"-")
+}
+
+// generateTaskID generates a task ID of the form
+// <run>-task-<seed>-<sequence>-<tag>. The run ID keeps IDs unique across
+// runs, the sequence preserves scheduling order within a run and the tag,
+// drawn from the seeded random source, keeps IDs from runs with different
+// seeds apart.
 func (ds *DeterministicScheduler) generateTaskID() string {
 	ds.taskIDGen++
-	return fmt.Sprintf("task-%d-%d", ds.seed, ds.taskIDGen)
+	return fmt.Sprintf("%s-task-%d-%08d-%s", ds.runID, ds.seed, ds.taskIDGen, ds.idTag)
+}
+
+// generateRunID generates a random identifier for a scheduler run
+func generateRunID() string {
+	bytes := make([]byte, 6)
+	if _, err := crand.Read(bytes); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return fmt.Sprintf("%x", bytes)
+}
+
+// generateIDTag generates the tag of the task IDs of a run from its seeded
+// random source
+func generateIDTag(rng *rand.Rand) string {
+	return fmt.Sprintf("%012x", rng.Int63()&0xffffffffffff)
 }
 
 // GetStats returns scheduler statistics
@@ -276,26 +440,33 @@ func (ds *DeterministicScheduler) GetStats() map[string]interface{} {
 
 	completed := 0
 	failed := 0
+	cancelled := 0
 	totalDuration := time.Duration(0)
 
 	for _, record := range ds.executionLog {
-		if record.Status == "completed" {
+		switch record.Status {
+		case "completed":
+			completed++
+			totalDuration += record.Duration
+		case "failed":
 			completed++
-			if record.Error != nil {
-				failed++
-			}
+			failed++
 			totalDuration += record.Duration
+		case "cancelled":
+			cancelled++
 		}
 	}
 
 	return map[string]interface{}{
-		"total_tasks":     len(ds.execOrder),
+		"total_tasks":     len(ds.tasks),
 		"completed_tasks": completed,
 		"failed_tasks":    failed,
-		"pending_tasks":   len(ds.tasks) - completed,
+		"cancelled_tasks": cancelled,
+		"pending_tasks":   len(ds.tasks) - completed - cancelled,
 		"deterministic":   ds.deterministic,
 		"total_duration":  totalDuration,
 		"avg_task_time":   totalDuration / time.Duration(completed+1),
 		"seed":            ds.seed,
+		"run_id":          ds.runID,
 	}
 }
@@ -0,0 +1,133 @@
+package runtime
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// randTask draws numbers from its task's random source, sleeping for a
+// random time in between so that a scheduler that let tasks interleave would
+// record them in varying order
+type randTask struct {
+	index int
+	mu    *sync.Mutex
+	draws map[int][]int64
+}
+
+func (t randTask) Execute(ctx context.Context) error {
+	rng := TaskRand(ctx)
+	var draws []int64
+	for i := 0; i < 3; i++ {
+		draws = append(draws, rng.Int63())
+		time.Sleep(time.Duration(rng.Intn(3)) * time.Millisecond)
+	}
+	t.mu.Lock()
+	t.draws[t.index] = draws
+	t.mu.Unlock()
+	if draws[0]%3 == 0 {
+		return errors.New("draw divisible by three")
+	}
+	return nil
+}
+
+func (t randTask) IsCPUIntensive() bool {
+	return false
+}
+
+// gateTask blocks until its channel is closed
+type gateTask chan struct{}
+
+func (t gateTask) Execute(ctx context.Context) error {
+	<-t
+	return nil
+}
+
+func (t gateTask) IsCPUIntensive() bool {
+	return false
+}
+
+// schedulerRun is what a run of the workload records
+type schedulerRun struct {
+	order      []string
+	priorities []int
+	log        []string
+	draws      map[int][]int64
+	runID      interface{}
+}
+
+// runWorkload schedules the same tasks on a scheduler seeded with seed and
+// waits for them. A gate task holds the queue until all are scheduled, so
+// that they are ordered by priority.
+func runWorkload(t *testing.T, seed int64) schedulerRun {
+	t.Helper()
+	ds := NewDeterministicScheduler(seed)
+	run := schedulerRun{draws: make(map[int][]int64)}
+	gate := make(gateTask)
+	if err := ds.ScheduleWithPriority(gate, 0); err != nil {
+		t.Fatalf("Schedule: %v", err)
+	}
+	var mu sync.Mutex
+	for i := 0; i < 20; i++ {
+		if err := ds.ScheduleWithPriority(randTask{index: i, mu: &mu, draws: run.draws}, i%3); err != nil {
+			t.Fatalf("Schedule: %v", err)
+		}
+	}
+	close(gate)
+	if err := ds.Shutdown(); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	// The log holds the tasks in scheduling order, the gate first
+	priorities := make(map[string]int)
+	for i, record := range ds.GetExecutionLog()[1:] {
+		priorities[record.TaskID] = i % 3
+	}
+	for _, id := range ds.GetExecutionOrder() {
+		run.order = append(run.order, ds.ReproducibleID(id))
+		run.priorities = append(run.priorities, priorities[id])
+	}
+	for _, record := range ds.GetExecutionLog() {
+		// Times and run IDs differ between runs; the rest of the IDs,
+		// statuses and errors must not
+		run.log = append(run.log, fmt.Sprintf("%s %s %v", ds.ReproducibleID(record.TaskID), record.Status, record.Error))
+	}
+	run.runID = ds.GetStats()["run_id"]
+	return run
+}
+
+func TestDeterministicSchedulerReproducesRuns(t *testing.T) {
+	first := runWorkload(t, 42)
+	second := runWorkload(t, 42)
+
+	if len(first.order) != 21 {
+		t.Fatalf("ran %d tasks, want 21", len(first.order))
+	}
+	// After the gate, higher priorities run first
+	for i := 2; i < len(first.priorities); i++ {
+		if first.priorities[i] > first.priorities[i-1] {
+			t.Fatalf("tasks ran in priority order %v", first.priorities[1:])
+		}
+	}
+	if !reflect.DeepEqual(first.order, second.order) {
+		t.Errorf("execution orders differ:\n%v\n%v", first.order, second.order)
+	}
+	if !reflect.DeepEqual(first.log, second.log) {
+		t.Errorf("execution logs differ:\n%v\n%v", first.log, second.log)
+	}
+	if !reflect.DeepEqual(first.draws, second.draws) {
+		t.Errorf("task random draws differ:\n%v\n%v", first.draws, second.draws)
+	}
+	if first.runID == second.runID {
+		t.Errorf("runs share run ID %v", first.runID)
+	}
+
+	other := runWorkload(t, 43)
+	if reflect.DeepEqual(first.log, other.log) {
+		t.Errorf("runs with different seeds have the same log")
+	}
+}
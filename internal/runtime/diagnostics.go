@@ -0,0 +1,90 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+	"github.com/dop251/goja/parser"
+)
+
+// SyntaxError is a syntax error in a program, with its location
+type SyntaxError struct {
+	File    string
+	Line    int // starting at 1
+	Column  int // starting at 1, in characters
+	Message string
+	Source  string // the source line of the error
+	Err     error
+}
+
+// Error returns the error with its location
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: SyntaxError: %s", e.File, e.Line, e.Column, e.Message)
+}
+
+// Unwrap returns the error reported by the engine
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostic formats the error like a compiler diagnostic: its location and
+// message, followed by the source line with a caret under the column
+func (e *SyntaxError) Diagnostic() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s:%d:%d: error: %s\n", e.File, e.Line, e.Column, e.Message)
+	if e.Source == "" {
+		return b.String()
+	}
+
+	gutter := fmt.Sprintf("%d", e.Line)
+	fmt.Fprintf(&b, " %s | %s\n", gutter, e.Source)
+
+	// Keep tabs before the caret so that it lines up with the source
+	var pad strings.Builder
+	for i, r := range []rune(e.Source) {
+		if i >= e.Column-1 {
+			break
+		}
+		if r == '\t' {
+			pad.WriteRune('\t')
+		} else {
+			pad.WriteRune(' ')
+		}
+	}
+	fmt.Fprintf(&b, " %s | %s^\n", strings.Repeat(" ", len(gutter)), pad.String())
+	return b.String()
+}
+
+// syntaxError converts a syntax error reported by the engine for code,
+// compiled as name, to a SyntaxError with its location; other errors are
+// returned unchanged
+func syntaxError(name, code string, err error) error {
+	var compileErr *goja.CompilerSyntaxError
+	if !errors.As(err, &compileErr) {
+		return err
+	}
+
+	synErr := &SyntaxError{File: name, Message: compileErr.Message, Err: err}
+	if compileErr.File != nil {
+		pos := compileErr.File.Position(compileErr.Offset)
+		synErr.Line, synErr.Column = pos.Line, pos.Column
+	} else {
+		// Parse errors only carry their location in the message; parse
+		// again to get it
+		_, parseErr := parser.ParseFile(nil, name, code, 0)
+		var list parser.ErrorList
+		if !errors.As(parseErr, &list) || len(list) == 0 {
+			return err
+		}
+		synErr.Line, synErr.Column = list[0].Position.Line, list[0].Position.Column
+		synErr.Message = list[0].Message
+	}
+
+	lines := strings.Split(code, "\n")
+	if synErr.Line >= 1 && synErr.Line <= len(lines) {
+		synErr.Source = strings.TrimRight(lines[synErr.Line-1], "\r")
+	}
+	return synErr
+}
@@ -0,0 +1,222 @@
+package runtime
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dotEscaper escapes strings for use inside quoted DOT identifiers
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// dotQuote quotes s as a DOT identifier
+func dotQuote(s string) string {
+	return `"` + dotEscaper.Replace(s) + `"`
+}
+
+// dotEdge is an edge in a DOT export
+type dotEdge struct {
+	from, to  string
+	cycle     bool
+	violation bool
+}
+
+// ToDOT renders the service graph in Graphviz DOT format. Edges that are
+// part of a dependency cycle are drawn orange and edges with a boundary
+// violation are drawn red.
+func (sg *ServiceGraph) ToDOT(violations ...BoundaryViolation) string {
+	sg.mu.RLock()
+	ids := make([]string, 0, len(sg.nodes))
+	labels := make(map[string]string, len(sg.nodes))
+	adjacency := make(map[string][]string, len(sg.nodes))
+	for id, node := range sg.nodes {
+		ids = append(ids, id)
+		node.mu.RLock()
+		labels[id] = id
+		if node.ServiceType != "" {
+			labels[id] = fmt.Sprintf("%s\n(%s)", id, node.ServiceType)
+		}
+		adjacency[id] = append([]string(nil), node.Dependencies...)
+		node.mu.RUnlock()
+	}
+	sg.mu.RUnlock()
+	sort.Strings(ids)
+
+	violated := make(map[[2]string]bool, len(violations))
+	for _, v := range violations {
+		violated[[2]string{v.FromModule, v.ToModule}] = true
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph services {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", dotQuote(id), dotQuote(labels[id]))
+	}
+	for _, edge := range dotEdges(ids, adjacency, violated) {
+		writeDOTEdge(&b, edge)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// ToDOT renders the domain graph in Graphviz DOT format. Edges that are
+// part of a cycle are drawn orange, and edges not permitted by an allowed
+// boundary of the source domain are drawn red as violations.
+func (dg *DomainGraph) ToDOT() string {
+	dg.mu.RLock()
+	names := make([]string, 0, len(dg.domains))
+	labels := make(map[string]string, len(dg.domains))
+	adjacency := make(map[string][]string, len(dg.edges))
+	violated := make(map[[2]string]bool)
+	for name, domain := range dg.domains {
+		names = append(names, name)
+		domain.mu.RLock()
+		labels[name] = fmt.Sprintf("%s\n(%d modules)", name, len(domain.Modules))
+		for _, to := range dg.edges[name] {
+			if !domain.allows(to) {
+				violated[[2]string{name, to}] = true
+			}
+		}
+		domain.mu.RUnlock()
+		adjacency[name] = append([]string(nil), dg.edges[name]...)
+	}
+	dg.mu.RUnlock()
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("digraph domains {\n\trankdir=LR;\n\tnode [shape=box, style=rounded];\n")
+	for _, name := range names {
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", dotQuote(name), dotQuote(labels[name]))
+	}
+	for _, edge := range dotEdges(names, adjacency, violated) {
+		writeDOTEdge(&b, edge)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// allows reports whether the domain has an allowed boundary to toDomain.
+// The caller must hold d.mu.
+func (d *Domain) allows(toDomain string) bool {
+	for _, boundary := range d.Boundaries {
+		if boundary.ToDomain == toDomain {
+			return boundary.Allowed
+		}
+	}
+	return false
+}
+
+// dotEdges returns the distinct edges of a graph in a stable order, marking
+// those on a cycle or in violated
+func dotEdges(nodes []string, adjacency map[string][]string, violated map[[2]string]bool) []dotEdge {
+	cyclic := cyclicEdges(adjacency)
+
+	edges := make([]dotEdge, 0)
+	for _, from := range nodes {
+		targets := append([]string(nil), adjacency[from]...)
+		sort.Strings(targets)
+		for i, to := range targets {
+			if i > 0 && targets[i-1] == to {
+				continue
+			}
+			key := [2]string{from, to}
+			edges = append(edges, dotEdge{
+				from:      from,
+				to:        to,
+				cycle:     cyclic[key],
+				violation: violated[key],
+			})
+		}
+	}
+	return edges
+}
+
+// writeDOTEdge writes an edge statement with highlighting attributes
+func writeDOTEdge(b *strings.Builder, edge dotEdge) {
+	var attrs []string
+	switch {
+	case edge.violation && edge.cycle:
+		attrs = []string{"color=red", "penwidth=2", `label="violation, cycle"`}
+	case edge.violation:
+		attrs = []string{"color=red", "penwidth=2", `label="violation"`}
+	case edge.cycle:
+		attrs = []string{"color=orange", "penwidth=2", `label="cycle"`}
+	}
+
+	fmt.Fprintf(b, "\t%s -> %s", dotQuote(edge.from), dotQuote(edge.to))
+	if len(attrs) > 0 {
+		fmt.Fprintf(b, " [%s]", strings.Join(attrs, ", "))
+	}
+	b.WriteString(";\n")
+}
+
+// cyclicEdges returns the edges that lie on a cycle, i.e. self-loops and
+// edges within a strongly connected component (Tarjan's algorithm)
+func cyclicEdges(adjacency map[string][]string) map[[2]string]bool {
+	index := make(map[string]int)
+	lowlink := make(map[string]int)
+	onStack := make(map[string]bool)
+	component := make(map[string]int)
+	stack := make([]string, 0)
+	next, components := 0, 0
+
+	var connect func(node string)
+	connect = func(node string) {
+		index[node] = next
+		lowlink[node] = next
+		next++
+		stack = append(stack, node)
+		onStack[node] = true
+
+		for _, neighbor := range adjacency[node] {
+			if _, seen := index[neighbor]; !seen {
+				connect(neighbor)
+				if lowlink[neighbor] < lowlink[node] {
+					lowlink[node] = lowlink[neighbor]
+				}
+			} else if onStack[neighbor] && index[neighbor] < lowlink[node] {
+				lowlink[node] = index[neighbor]
+			}
+		}
+
+		if lowlink[node] == index[node] {
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component[top] = components
+				if top == node {
+					break
+				}
+			}
+			components++
+		}
+	}
+
+	nodes := make([]string, 0, len(adjacency))
+	for node := range adjacency {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	for _, node := range nodes {
+		if _, seen := index[node]; !seen {
+			connect(node)
+		}
+	}
+
+	// Count component sizes so single-node components only count self-loops
+	sizes := make(map[int]int)
+	for _, c := range component {
+		sizes[c]++
+	}
+
+	cyclic := make(map[[2]string]bool)
+	for from, targets := range adjacency {
+		for _, to := range targets {
+			if from == to || (component[from] == component[to] && sizes[component[from]] > 1) {
+				cyclic[[2]string{from, to}] = true
+			}
+		}
+	}
+	return cyclic
+}
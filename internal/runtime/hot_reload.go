@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"gots-runtime/internal/transpiler"
 )
 
 // HotReloader provides hot reload functionality with state preservation
@@ -148,7 +150,7 @@ func (hr *HotReloader) WatchDirectory(dir string) error {
 			return err
 		}
 		
-		if !info.IsDir() && filepath.Ext(path) == ".ts" {
+		if !info.IsDir() && transpiler.NeedsTranspile(path) {
 			hr.Watch(path)
 		}
 		
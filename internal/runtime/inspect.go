@@ -0,0 +1,223 @@
+package runtime
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+const (
+	// inspectDepth is how deep nested objects are printed; deeper ones are
+	// abbreviated to [Object] or [Array]
+	inspectDepth = 2
+	// inspectLineWidth is the longest object or array printed on one line
+	inspectLineWidth = 72
+)
+
+// identifierPattern matches property names that need no quotes
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// inspector formats JavaScript values for the console, like Node's
+// util.inspect: objects and arrays are printed with their contents, nested
+// up to inspectDepth levels, and circular references are marked.
+type inspector struct {
+	vm   *goja.Runtime
+	seen []*goja.Object // objects being printed, outermost first
+}
+
+// formatLogArgs formats console arguments: strings are printed as they are
+// and other values are inspected
+func formatLogArgs(vm *goja.Runtime, args []goja.Value) string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		if _, isObject := arg.(*goja.Object); !isObject && arg != nil {
+			if s, ok := arg.Export().(string); ok {
+				parts[i] = s
+				continue
+			}
+		}
+		parts[i] = inspectValue(vm, arg)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Inspect formats value the way console.log prints it
+func (r *Runtime) Inspect(value goja.Value) string {
+	return inspectValue(r.vm, value)
+}
+
+// inspectValue formats value for the console
+func inspectValue(vm *goja.Runtime, value goja.Value) string {
+	in := &inspector{vm: vm}
+	return in.format(value, 0, 0)
+}
+
+// format formats value nested depth levels deep, indented by indent spaces
+func (in *inspector) format(value goja.Value, depth, indent int) string {
+	if value == nil || goja.IsUndefined(value) {
+		return "undefined"
+	}
+	if goja.IsNull(value) {
+		return "null"
+	}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return in.formatPrimitive(value)
+	}
+
+	for _, seen := range in.seen {
+		if seen.SameAs(obj) {
+			return "[Circular]"
+		}
+	}
+
+	switch obj.ClassName() {
+	case "Function":
+		name := obj.Get("name")
+		if name == nil || name.String() == "" {
+			return "[Function (anonymous)]"
+		}
+		if strings.ContainsAny(name.String(), "/()") {
+			// Go functions are named after their Go symbol
+			return "[Function (native)]"
+		}
+		return "[Function: " + name.String() + "]"
+	case "Error":
+		if stack := obj.Get("stack"); stack != nil && !goja.IsUndefined(stack) {
+			return strings.TrimRight(stack.String(), "\n")
+		}
+		return obj.String()
+	case "Date":
+		return in.callString(obj, "toISOString")
+	case "RegExp":
+		return obj.String()
+	}
+
+	in.seen = append(in.seen, obj)
+	defer func() { in.seen = in.seen[:len(in.seen)-1] }()
+
+	if obj.ClassName() == "Array" {
+		if depth > inspectDepth {
+			return "[Array]"
+		}
+		length := int(obj.Get("length").ToInteger())
+		entries := make([]string, 0, length)
+		for i := 0; i < length; i++ {
+			entries = append(entries, in.format(obj.Get(strconv.Itoa(i)), depth+1, indent+2))
+		}
+		return in.wrap("", "[", "]", entries, indent)
+	}
+
+	prefix := in.constructorName(obj)
+	if prefix == "Map" || prefix == "Set" {
+		return in.formatCollection(obj, prefix, depth, indent)
+	}
+	if depth > inspectDepth {
+		if prefix == "" {
+			return "[Object]"
+		}
+		return "[" + prefix + "]"
+	}
+	keys := obj.Keys()
+	entries := make([]string, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, formatKey(key)+": "+in.format(obj.Get(key), depth+1, indent+2))
+	}
+	return in.wrap(prefix, "{", "}", entries, indent)
+}
+
+// formatPrimitive formats a value that is not an object
+func (in *inspector) formatPrimitive(value goja.Value) string {
+	if s, ok := value.Export().(string); ok {
+		return quoteString(s)
+	}
+	return value.String()
+}
+
+// formatCollection formats a Map as Map(n) { k => v } and a Set as
+// Set(n) { v }
+func (in *inspector) formatCollection(obj *goja.Object, kind string, depth, indent int) string {
+	size := obj.Get("size").ToInteger()
+	prefix := kind + "(" + strconv.FormatInt(size, 10) + ")"
+	if depth > inspectDepth {
+		return "[" + kind + "]"
+	}
+
+	var entries []string
+	forEach, ok := goja.AssertFunction(obj.Get("forEach"))
+	if ok {
+		_, _ = forEach(obj, in.vm.ToValue(func(value, key goja.Value) {
+			entry := in.format(value, depth+1, indent+2)
+			if kind == "Map" {
+				entry = in.format(key, depth+1, indent+2) + " => " + entry
+			}
+			entries = append(entries, entry)
+		}))
+	}
+	return in.wrap(prefix, "{", "}", entries, indent)
+}
+
+// wrap joins entries between open and close, on one line if they fit and
+// one per line otherwise
+func (in *inspector) wrap(prefix, open, close string, entries []string, indent int) string {
+	if prefix != "" {
+		prefix += " "
+	}
+	if len(entries) == 0 {
+		return prefix + open + close
+	}
+
+	oneLine := prefix + open + " " + strings.Join(entries, ", ") + " " + close
+	if indent+len(oneLine) <= inspectLineWidth && !strings.Contains(oneLine, "\n") {
+		return oneLine
+	}
+
+	pad := strings.Repeat(" ", indent+2)
+	return prefix + open + "\n" + pad + strings.Join(entries, ",\n"+pad) + "\n" + strings.Repeat(" ", indent) + close
+}
+
+// constructorName returns the name of the class of obj, or "" for plain
+// objects
+func (in *inspector) constructorName(obj *goja.Object) string {
+	constructor, ok := obj.Get("constructor").(*goja.Object)
+	if !ok {
+		return ""
+	}
+	name := constructor.Get("name")
+	if name == nil || name.String() == "Object" {
+		return ""
+	}
+	return name.String()
+}
+
+// callString calls the method name of obj and returns its result as a
+// string
+func (in *inspector) callString(obj *goja.Object, name string) string {
+	method, ok := goja.AssertFunction(obj.Get(name))
+	if !ok {
+		return obj.String()
+	}
+	result, err := method(obj)
+	if err != nil {
+		return obj.String()
+	}
+	return result.String()
+}
+
+// formatKey formats a property name, quoting it unless it is an identifier
+func formatKey(key string) string {
+	if identifierPattern.MatchString(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+// quoteString quotes a string nested in an inspected value
+func quoteString(s string) string {
+	quoted := strconv.Quote(s)
+	quoted = strings.ReplaceAll(quoted[1:len(quoted)-1], `\"`, `"`)
+	return "'" + strings.ReplaceAll(quoted, "'", `\'`) + "'"
+}
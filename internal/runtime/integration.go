@@ -3,18 +3,23 @@ package runtime
 import (
 	"context"
 	"fmt"
+	"os"
 	"sync"
+	"time"
 
 	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/ipc"
 	"gots-runtime/internal/observability"
 	"gots-runtime/internal/security"
 	"gots-runtime/internal/tsengine"
+	"gots-runtime/internal/worker"
 )
 
 // RuntimeIntegration provides the main integration layer
 type RuntimeIntegration struct {
 	orchestrator    *Orchestrator
 	eventLoop       *eventloop.Loop
+	eventBus        *ipc.EventBus
 	tsEngine        *tsengine.Engine
 	permManager     *security.PermissionManager
 	sandboxManager  *security.SandboxManager
@@ -22,19 +27,31 @@ type RuntimeIntegration struct {
 	logger          *observability.Logger
 	metrics         *observability.MetricsCollector
 	tracer          *observability.Tracer
+	memory          *MemoryIsolation
 	mu              sync.RWMutex
 	initialized     bool
 }
 
+// RuntimeOptions configures a runtime integration
+type RuntimeOptions struct {
+	EventQueueSize int
+	QueuePolicy    eventloop.OverflowPolicy
+}
+
 // NewRuntimeIntegration creates a new runtime integration
 func NewRuntimeIntegration() *RuntimeIntegration {
+	return NewRuntimeIntegrationWithOptions(RuntimeOptions{})
+}
+
+// NewRuntimeIntegrationWithOptions creates a new runtime integration with options
+func NewRuntimeIntegrationWithOptions(opts RuntimeOptions) *RuntimeIntegration {
 	ctx := context.Background()
 	
 	// Create orchestrator
 	orch := NewOrchestrator()
 	
 	// Create event loop
-	eventLoop := eventloop.NewLoop(ctx)
+	eventLoop := eventloop.NewLoopWithQueue(ctx, opts.EventQueueSize, opts.QueuePolicy)
 	
 	// Create TypeScript engine
 	tsEngine := tsengine.NewEngine()
@@ -48,10 +65,15 @@ func NewRuntimeIntegration() *RuntimeIntegration {
 	metrics := observability.NewMetricsCollector()
 	tracer := observability.NewTracer()
 	healthEndpoint := observability.NewHealthEndpoint()
+	eventLoop.SetMetrics(metrics)
+	eventLoop.SetStallWatchdog(DefaultStallThreshold, func(stall eventloop.Stall) {
+		logger.Warn("event loop blocked for %s by a %s callback\n%s", stall.Duration.Round(time.Millisecond), stall.Event, stall.Stack)
+	})
 	
 	return &RuntimeIntegration{
 		orchestrator:   orch,
 		eventLoop:      eventLoop,
+		eventBus:       ipc.NewEventBus(eventLoop),
 		tsEngine:       tsEngine,
 		permManager:    permManager,
 		sandboxManager: sandboxManager,
@@ -59,6 +81,7 @@ func NewRuntimeIntegration() *RuntimeIntegration {
 		logger:         logger,
 		metrics:        metrics,
 		tracer:         tracer,
+		memory:         NewMemoryIsolation(),
 	}
 }
 
@@ -80,7 +103,7 @@ func (ri *RuntimeIntegration) Initialize() error {
 	ri.eventLoop.Start()
 	
 	// Create and start scheduler
-	scheduler := NewAdvancedScheduler(ri.orchestrator.Context(), ri.eventLoop)
+	scheduler := NewAdvancedSchedulerWithMetrics(ri.orchestrator.Context(), ri.eventLoop, ri.metrics)
 	scheduler.Start()
 	ri.orchestrator.SetScheduler(scheduler)
 	
@@ -114,12 +137,24 @@ func (ri *RuntimeIntegration) setupHealthChecks() {
 	
 	ri.healthEndpoint.RegisterCheck("eventloop", func() (observability.HealthStatus, string) {
 		if ri.eventLoop.IsOverloaded() {
-			return observability.HealthStatusDegraded, "event loop is overloaded"
+			stats := ri.eventLoop.QueueStats()
+			return observability.HealthStatusDegraded, fmt.Sprintf("event loop is overloaded (%d/%d queued)", stats.Length, stats.Capacity)
 		}
 		return observability.HealthStatusHealthy, "event loop is healthy"
 	})
 }
 
+// orchestratorStats reports the orchestrator state and the queue of its
+// scheduler's worker pool to runtime.stats()
+func (ri *RuntimeIntegration) orchestratorStats() tsengine.OrchestratorStats {
+	stats := tsengine.OrchestratorStats{State: ri.orchestrator.State().String()}
+	if sched, ok := ri.orchestrator.GetScheduler().(interface{ WorkerPool() *worker.Pool }); ok {
+		poolStats := sched.WorkerPool().GetStats()
+		stats.Scheduler = &poolStats
+	}
+	return stats
+}
+
 // GetOrchestrator returns the orchestrator
 func (ri *RuntimeIntegration) GetOrchestrator() *Orchestrator {
 	return ri.orchestrator
@@ -130,6 +165,11 @@ func (ri *RuntimeIntegration) GetEventLoop() *eventloop.Loop {
 	return ri.eventLoop
 }
 
+// GetEventBus returns the event bus shared between modules
+func (ri *RuntimeIntegration) GetEventBus() *ipc.EventBus {
+	return ri.eventBus
+}
+
 // GetTSEngine returns the TypeScript engine
 func (ri *RuntimeIntegration) GetTSEngine() *tsengine.Engine {
 	return ri.tsEngine
@@ -165,6 +205,11 @@ func (ri *RuntimeIntegration) GetTracer() *observability.Tracer {
 	return ri.tracer
 }
 
+// GetMemory returns the per-module memory tracker
+func (ri *RuntimeIntegration) GetMemory() *MemoryIsolation {
+	return ri.memory
+}
+
 // RegisterModule registers a module with security policy
 func (ri *RuntimeIntegration) RegisterModule(moduleID string, permissions ...security.Permission) error {
 	policy := security.NewPolicy(moduleID)
@@ -173,11 +218,19 @@ func (ri *RuntimeIntegration) RegisterModule(moduleID string, permissions ...sec
 	}
 	
 	ri.permManager.RegisterPolicy(moduleID, policy)
+	ri.memory.RegisterModule(moduleID)
 	ri.logger.Info("Module registered: %s", moduleID)
 	
 	return nil
 }
 
+// RegisterPolicy registers a prebuilt security policy for its module
+func (ri *RuntimeIntegration) RegisterPolicy(policy *security.Policy) {
+	ri.permManager.RegisterPolicy(policy.ModuleID(), policy)
+	ri.memory.RegisterModule(policy.ModuleID())
+	ri.logger.Info("Module registered: %s", policy.ModuleID())
+}
+
 // ExecuteModule executes a TypeScript module
 func (ri *RuntimeIntegration) ExecuteModule(moduleID, filePath string) error {
 	// Register APIs for this module
@@ -187,6 +240,11 @@ func (ri *RuntimeIntegration) ExecuteModule(moduleID, filePath string) error {
 		ri.permManager,
 		moduleID,
 	)
+	bindings.SetEventBus(ri.eventBus)
+	ri.memory.RegisterModule(moduleID)
+	bindings.SetMemoryReporter(ri.memory.Reporter())
+	bindings.SetMemoryTracker(ri.memory)
+	bindings.SetOrchestratorReporter(ri.orchestratorStats)
 	
 	if err := bindings.RegisterAPIs(); err != nil {
 		return fmt.Errorf("failed to register APIs: %w", err)
@@ -198,6 +256,11 @@ func (ri *RuntimeIntegration) ExecuteModule(moduleID, filePath string) error {
 		return fmt.Errorf("failed to execute module: %w", err)
 	}
 	
+	// Account the module source; it stays referenced by the VM
+	if info, err := os.Stat(filePath); err == nil {
+		ri.memory.Track(moduleID, uint64(info.Size()))
+	}
+	
 	ri.metrics.Increment("modules.executed", map[string]string{"module": moduleID})
 	ri.logger.Info("Module executed: %s", moduleID)
 	
@@ -24,6 +24,22 @@ const (
 	StateStopped
 )
 
+// String returns the name of the state
+func (s State) String() string {
+	switch s {
+	case StateInitialized:
+		return "initialized"
+	case StateRunning:
+		return "running"
+	case StateShuttingDown:
+		return "shutting-down"
+	case StateStopped:
+		return "stopped"
+	default:
+		return "unknown"
+	}
+}
+
 // NewLifecycle creates a new lifecycle manager
 func NewLifecycle() *Lifecycle {
 	ctx, cancel := context.WithCancel(context.Background())
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"sync"
 	"time"
+
+	fwruntime "gots-runtime/framework/runtime"
 )
 
 // LoadShedder provides adaptive load shedding
@@ -49,6 +51,21 @@ func NewLoadShedder(threshold int) *LoadShedder {
 	}
 }
 
+// NewRequestShedder creates a load shedder for the requests of a framework
+// app, shedding them once threshold are in flight according to policy; an
+// empty policy is linear
+func NewRequestShedder(threshold int, policy string) (fwruntime.LoadShedder, error) {
+	shedder := NewLoadShedder(threshold)
+	switch RejectionPolicy(policy) {
+	case "":
+	case RejectionPolicyLinear, RejectionPolicyCubic, RejectionPolicyExponential:
+		shedder.SetRejectionPolicy(RejectionPolicy(policy))
+	default:
+		return nil, fmt.Errorf("unknown rejection policy: %s", policy)
+	}
+	return shedder, nil
+}
+
 // SetRejectionPolicy sets the rejection policy
 func (ls *LoadShedder) SetRejectionPolicy(policy RejectionPolicy) {
 	ls.mu.Lock()
@@ -56,17 +73,59 @@ func (ls *LoadShedder) SetRejectionPolicy(policy RejectionPolicy) {
 	ls.rejectionPolicy = policy
 }
 
+// Priority classes of requests for ShouldRejectWithPriority. Lower
+// classes are shed first; critical requests, such as health checks, are
+// never shed.
+const (
+	PriorityLow      = -1
+	PriorityNormal   = 0
+	PriorityHigh     = 1
+	PriorityCritical = 2
+)
+
+// priorityThresholds scales the threshold for each priority class below
+// critical: low priority requests are shed at half the load of normal ones
+// and high priority ones at twice the load
+var priorityThresholds = map[int]float64{
+	PriorityLow:    0.5,
+	PriorityNormal: 1,
+	PriorityHigh:   2,
+}
+
 // ShouldReject determines if a request should be rejected
 func (ls *LoadShedder) ShouldReject() bool {
-	ls.mu.RLock()
-	defer ls.mu.RUnlock()
+	return ls.ShouldRejectWithPriority(PriorityNormal)
+}
+
+// ShouldRejectWithPriority determines if a request of a priority class
+// should be rejected. The threshold is scaled by the class, so that under
+// load low priority requests are shed before normal and high priority
+// ones; critical requests are always admitted. Priorities outside the
+// classes are clamped to the nearest one.
+func (ls *LoadShedder) ShouldRejectWithPriority(priority int) bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if priority < PriorityLow {
+		priority = PriorityLow
+	}
+	if priority >= PriorityCritical {
+		if ls.currentLoad >= ls.threshold {
+			ls.metrics.BypassCount++
+		}
+		return false
+	}
 
-	if ls.currentLoad < ls.threshold {
+	threshold := float64(ls.threshold) * priorityThresholds[priority]
+	if threshold < 1 {
+		threshold = 1
+	}
+	if float64(ls.currentLoad) < threshold {
 		return false
 	}
 
 	// Calculate rejection rate based on overload and policy
-	overloadRatio := float64(ls.currentLoad-ls.threshold) / float64(ls.threshold)
+	overloadRatio := (float64(ls.currentLoad) - threshold) / threshold
 
 	var rejectionRate float64
 	switch ls.rejectionPolicy {
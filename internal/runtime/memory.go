@@ -2,15 +2,23 @@ package runtime
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
 	"runtime"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"gots-runtime/internal/observability"
+	"gots-runtime/internal/tsengine"
 )
 
 // MemoryIsolation provides per-module memory isolation
 type MemoryIsolation struct {
-	modules map[string]*ModuleMemory
-	mu      sync.RWMutex
+	modules    map[string]*ModuleMemory
+	nextHandle atomic.Uint64
+	mu         sync.RWMutex
 }
 
 // ModuleMemory represents memory usage for a module
@@ -81,6 +89,22 @@ func (mi *MemoryIsolation) TrackDeallocation(moduleID string, ptr uintptr) {
 	}
 }
 
+// Track records an allocation of size bytes for a module under a generated
+// handle, for callers that account approximate sizes rather than pointers.
+// The returned function releases the allocation and may be called more than
+// once.
+func (mi *MemoryIsolation) Track(moduleID string, size uint64) func() {
+	handle := uintptr(mi.nextHandle.Add(1))
+	mi.TrackAllocation(moduleID, handle, size)
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			mi.TrackDeallocation(moduleID, handle)
+		})
+	}
+}
+
 // GetModuleMemory gets memory usage for a module
 func (mi *MemoryIsolation) GetModuleMemory(moduleID string) (*ModuleMemory, error) {
 	mi.mu.RLock()
@@ -106,6 +130,24 @@ func (mi *MemoryIsolation) GetAllModulesMemory() map[string]*ModuleMemory {
 	return result
 }
 
+// Reporter returns a MemoryReporter that exposes the usage of every
+// registered module to TypeScript
+func (mi *MemoryIsolation) Reporter() tsengine.MemoryReporter {
+	return func() map[string]tsengine.ModuleMemoryUsage {
+		modules := mi.GetAllModulesMemory()
+		usage := make(map[string]tsengine.ModuleMemoryUsage, len(modules))
+		for id, module := range modules {
+			module.mu.RLock()
+			usage[id] = tsengine.ModuleMemoryUsage{
+				Allocated: module.Allocated,
+				Max:       module.MaxAllocated,
+			}
+			module.mu.RUnlock()
+		}
+		return usage
+	}
+}
+
 // MemoryLeakDetector detects memory leaks
 type MemoryLeakDetector struct {
 	isolation  *MemoryIsolation
@@ -113,18 +155,22 @@ type MemoryLeakDetector struct {
 	interval   time.Duration
 	stop       chan struct{}
 	leaks      map[string]LeakReport
+	profileDir string
 	mu         sync.RWMutex
 	autoRepair bool
 }
 
 // LeakReport contains information about a detected leak
 type LeakReport struct {
-	ModuleID        string
-	AllocatedBytes  uint64
-	AllocationCount int
-	Timestamp       time.Time
-	StackTrace      string
-	Severity        string // "warning", "critical"
+	ModuleID          string
+	AllocatedBytes    uint64 // after the detector's GC
+	AllocatedBeforeGC uint64
+	GCDelta           int64 // AllocatedBytes - AllocatedBeforeGC; negative when GC released memory
+	AllocationCount   int
+	Timestamp         time.Time
+	StackTrace        string // dump of all goroutines at detection time
+	HeapProfile       string // path of the heap profile written at detection time, if any
+	Severity          string // "warning", "critical"
 }
 
 // NewMemoryLeakDetector creates a new memory leak detector
@@ -135,10 +181,19 @@ func NewMemoryLeakDetector(isolation *MemoryIsolation, threshold uint64, interva
 		interval:   interval,
 		stop:       make(chan struct{}),
 		leaks:      make(map[string]LeakReport),
+		profileDir: os.TempDir(),
 		autoRepair: true,
 	}
 }
 
+// SetProfileDir sets the directory heap profiles of detected leaks are
+// written to. An empty dir disables heap profiles.
+func (mld *MemoryLeakDetector) SetProfileDir(dir string) {
+	mld.mu.Lock()
+	defer mld.mu.Unlock()
+	mld.profileDir = dir
+}
+
 // SetAutoRepair enables or disables automatic repair
 func (mld *MemoryLeakDetector) SetAutoRepair(enabled bool) {
 	mld.mu.Lock()
@@ -200,12 +255,16 @@ func (mld *MemoryLeakDetector) checkLeaks() {
 				}
 
 				report := LeakReport{
-					ModuleID:        moduleID,
-					AllocatedBytes:  afterGC,
-					AllocationCount: allocationCount,
-					Timestamp:       time.Now(),
-					Severity:        severity,
+					ModuleID:          moduleID,
+					AllocatedBytes:    afterGC,
+					AllocatedBeforeGC: allocated,
+					GCDelta:           int64(afterGC) - int64(allocated),
+					AllocationCount:   allocationCount,
+					Timestamp:         time.Now(),
+					StackTrace:        getStackTrace(true),
+					Severity:          severity,
 				}
+				report.HeapProfile = mld.writeHeapProfile(moduleID, report.Timestamp)
 
 				mld.mu.Lock()
 				mld.leaks[moduleID] = report
@@ -220,6 +279,29 @@ func (mld *MemoryLeakDetector) checkLeaks() {
 	}
 }
 
+// unsafeFileChars matches characters not allowed in profile file names
+var unsafeFileChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// writeHeapProfile writes a heap profile for a leak detected in moduleID and
+// returns its path, or "" if profiles are disabled or writing failed
+func (mld *MemoryLeakDetector) writeHeapProfile(moduleID string, at time.Time) string {
+	mld.mu.RLock()
+	dir := mld.profileDir
+	mld.mu.RUnlock()
+
+	if dir == "" {
+		return ""
+	}
+
+	name := fmt.Sprintf("gots-leak-%s-%d.pprof", unsafeFileChars.ReplaceAllString(moduleID, "_"), at.UnixNano())
+	path := filepath.Join(dir, name)
+	if err := observability.NewProfiler().WriteHeapProfile(path); err != nil {
+		fmt.Printf("LEAK: failed to write heap profile for module %s: %v\n", moduleID, err)
+		return ""
+	}
+	return path
+}
+
 // attemptRepair attempts to repair a memory leak
 func (mld *MemoryLeakDetector) attemptRepair(moduleID string) {
 	// Force garbage collection
@@ -351,8 +433,6 @@ func (cc *CrashContainer) Execute(moduleID string, fn func() error) error {
 	defer func() {
 		if r := recover(); r != nil {
 			container.mu.Lock()
-			container.CrashCount++
-			container.LastCrash = time.Now()
 			container.IsRecovering = true
 			container.mu.Unlock()
 
@@ -364,18 +444,7 @@ func (cc *CrashContainer) Execute(moduleID string, fn func() error) error {
 			}
 
 			// Record crash event
-			container.mu.Lock()
-			container.Crashes = append(container.Crashes, CrashEvent{
-				Timestamp:  time.Now(),
-				Error:      err,
-				StackTrace: getStackTrace(),
-			})
-
-			// Keep only the last MaxCrashes
-			if len(container.Crashes) > container.MaxCrashes {
-				container.Crashes = container.Crashes[len(container.Crashes)-container.MaxCrashes:]
-			}
-			container.mu.Unlock()
+			container.record(err, getStackTrace(false))
 
 			// Delay recovery
 			time.Sleep(cc.recoveryDelay)
@@ -394,6 +463,41 @@ func (cc *CrashContainer) Execute(moduleID string, fn func() error) error {
 	return fn()
 }
 
+// RecordCrash records a crash of moduleID that was recovered elsewhere, such
+// as a panicking worker task, and calls the module's recovery function.
+// Crashes of unregistered modules are ignored.
+func (cc *CrashContainer) RecordCrash(moduleID string, err error, stackTrace string) {
+	cc.mu.RLock()
+	container, ok := cc.modules[moduleID]
+	cc.mu.RUnlock()
+	if !ok {
+		return
+	}
+
+	container.record(err, stackTrace)
+	if container.RecoveryFunc != nil {
+		container.RecoveryFunc(err)
+	}
+}
+
+// record adds a crash event, keeping only the last MaxCrashes
+func (mc *ModuleContainer) record(err error, stackTrace string) {
+	mc.mu.Lock()
+	defer mc.mu.Unlock()
+
+	now := time.Now()
+	mc.CrashCount++
+	mc.LastCrash = now
+	mc.Crashes = append(mc.Crashes, CrashEvent{
+		Timestamp:  now,
+		Error:      err,
+		StackTrace: stackTrace,
+	})
+	if len(mc.Crashes) > mc.MaxCrashes {
+		mc.Crashes = mc.Crashes[len(mc.Crashes)-mc.MaxCrashes:]
+	}
+}
+
 // GetModuleStatus gets the crash status for a module
 func (cc *CrashContainer) GetModuleStatus(moduleID string) (*ModuleContainer, bool) {
 	cc.mu.RLock()
@@ -455,9 +559,15 @@ func (cc *CrashContainer) ClearCrashes(moduleID string) {
 	}
 }
 
-// Helper function to get stack trace
-func getStackTrace() string {
+// getStackTrace returns the stack trace of the calling goroutine, or of all
+// goroutines if all is set
+func getStackTrace(all bool) string {
 	buf := make([]byte, 4096)
-	n := runtime.Stack(buf, false)
-	return string(buf[:n])
+	for {
+		n := runtime.Stack(buf, all)
+		if n < len(buf) {
+			return string(buf[:n])
+		}
+		buf = make([]byte, 2*len(buf))
+	}
 }
@@ -19,12 +19,19 @@ type Scheduler interface {
 	Shutdown() error
 }
 
-// Task represents a unit of work
+// Task represents a unit of work. Execute must return promptly once ctx is
+// done; schedulers cancel tasks through ctx.
 type Task interface {
 	Execute(ctx context.Context) error
 	IsCPUIntensive() bool
 }
 
+// Interruptible is implemented by tasks that can be stopped while they are
+// busy and not checking ctx, such as scripts running in the VM
+type Interruptible interface {
+	Interrupt(reason interface{})
+}
+
 // NewOrchestrator creates a new runtime orchestrator
 func NewOrchestrator() *Orchestrator {
 	return &Orchestrator{
@@ -39,6 +46,13 @@ func (o *Orchestrator) SetScheduler(scheduler Scheduler) {
 	o.scheduler = scheduler
 }
 
+// GetScheduler returns the task scheduler, or nil if none is set
+func (o *Orchestrator) GetScheduler() Scheduler {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.scheduler
+}
+
 // Start initializes and starts the runtime
 func (o *Orchestrator) Start() error {
 	if err := o.lifecycle.Start(); err != nil {
@@ -1,24 +1,59 @@
 package runtime
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
 	"os"
 	"path/filepath"
 	"strings"
-
+	"sync"
+	"time"
+
+	"gots-runtime/internal/api"
+	"gots-runtime/internal/config"
+	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/plugin"
+	"gots-runtime/internal/replay"
+	"gots-runtime/internal/security"
 	"gots-runtime/internal/transpiler"
+	"gots-runtime/internal/tsengine"
+	"gots-runtime/stdlib"
 
 	"github.com/dop251/goja"
 )
 
 // Runtime manages the JavaScript/TypeScript execution environment
 type Runtime struct {
-	vm         *goja.Runtime
-	transpiler *transpiler.Transpiler
-	stdlibPath string
-	modules    map[string]interface{}
+	vm          *goja.Runtime
+	transpiler  *transpiler.Transpiler
+	stdlibPath  string
+	stdlibFS    fs.FS
+	stdlibErr   error
+	modules     map[string]interface{}
+	loading     map[string]*goja.Object // module objects of modules being loaded
+	modulesMu   sync.Mutex
+	eventLoop   *eventloop.Loop
+	loopStarted bool // whether eventLoop runs and so owns the VM
+	plugins     *plugin.PluginManager
+	argv        []string
+	ioLog       *replay.IOLog
+	exit        func(code int)
+	clockCtl    bool
+	seed        *int64
+	memory      *MemoryIsolation
+	crashes     *CrashContainer
+	config      *config.Watcher
+	access      *ModuleAccess // domain boundaries of the project, if any
+	stall       time.Duration
+	moduleID    string
 }
 
+// DefaultStallThreshold is how long an event may keep the event loop busy
+// before a warning with the loop's stack is printed
+const DefaultStallThreshold = 5 * time.Second
+
 // New creates a new Runtime instance
 func New(stdlibPath string) (*Runtime, error) {
 	r := &Runtime{
@@ -26,6 +61,10 @@ func New(stdlibPath string) (*Runtime, error) {
 		transpiler: transpiler.New(),
 		stdlibPath: stdlibPath,
 		modules:    make(map[string]interface{}),
+		loading:    make(map[string]*goja.Object),
+		memory:     NewMemoryIsolation(),
+		crashes:    NewCrashContainer(),
+		stall:      DefaultStallThreshold,
 	}
 
 	// Initialize built-in objects
@@ -33,12 +72,9 @@ func New(stdlibPath string) (*Runtime, error) {
 		return nil, fmt.Errorf("failed to initialize builtins: %w", err)
 	}
 
-	// Load stdlib if path is provided
-	if stdlibPath != "" {
-		if err := r.loadStdlib(); err != nil {
-			return nil, fmt.Errorf("failed to load stdlib: %w", err)
-		}
-	}
+	// Record a missing stdlib directory so that stdlib imports can
+	// explain it
+	r.stdlibErr = r.loadStdlib()
 
 	return r, nil
 }
@@ -46,17 +82,7 @@ func New(stdlibPath string) (*Runtime, error) {
 // initializeBuiltins sets up built-in objects and functions
 func (r *Runtime) initializeBuiltins() error {
 	// Add console object
-	console := r.vm.NewObject()
-	console.Set("log", func(args ...interface{}) {
-		fmt.Println(args...)
-	})
-	console.Set("error", func(args ...interface{}) {
-		fmt.Fprintln(os.Stderr, args...)
-	})
-	console.Set("warn", func(args ...interface{}) {
-		fmt.Fprintln(os.Stderr, "Warning:")
-	})
-	r.vm.Set("console", console)
+	setConsole(r.vm)
 
 	// Add require function
 	r.vm.Set("require", r.requireFunction())
@@ -67,26 +93,84 @@ func (r *Runtime) initializeBuiltins() error {
 	return nil
 }
 
+// setConsole defines the console object of vm
+func setConsole(vm *goja.Runtime) {
+	console := vm.NewObject()
+	console.Set("log", func(args ...goja.Value) {
+		fmt.Println(formatLogArgs(vm, args))
+	})
+	console.Set("error", func(args ...goja.Value) {
+		fmt.Fprintln(os.Stderr, formatLogArgs(vm, args))
+	})
+	console.Set("warn", func(args ...goja.Value) {
+		fmt.Fprintln(os.Stderr, formatLogArgs(vm, args))
+	})
+	vm.Set("console", console)
+}
+
 // requireFunction creates a CommonJS-style require function
 func (r *Runtime) requireFunction() func(string) interface{} {
 	return func(modulePath string) interface{} {
-		// Check if already loaded
+		// A require is a direct call, which domain boundaries may forbid
+		if err := r.checkRequire(modulePath); err != nil {
+			panic(r.vm.ToValue(fmt.Sprintf("Cannot load module '%s': %v", modulePath, err)))
+		}
+
+		// Check if already loaded. A module that is still loading is
+		// required in a cycle and gets the exports assigned so far.
+		r.modulesMu.Lock()
 		if mod, ok := r.modules[modulePath]; ok {
+			r.modulesMu.Unlock()
 			return mod
 		}
+		if moduleObj, ok := r.loading[modulePath]; ok {
+			r.modulesMu.Unlock()
+			return moduleObj.Get("exports")
+		}
+		r.modulesMu.Unlock()
 
 		// Try to load the module
-		mod, err := r.loadModule(modulePath)
+		var mod interface{}
+		var err error
+		if strings.HasPrefix(modulePath, stdlibPrefix) {
+			mod, err = r.requireStdlib(modulePath)
+		} else {
+			mod, err = r.loadModule(modulePath)
+		}
 		if err != nil {
 			panic(r.vm.ToValue(fmt.Sprintf("Cannot find module '%s': %v", modulePath, err)))
 		}
+		if r.plugins != nil {
+			if err := r.plugins.Dispatch(plugin.HookModuleLoad, map[string]interface{}{"specifier": modulePath}); err != nil {
+				panic(r.vm.ToValue(fmt.Sprintf("Cannot load module '%s': %v", modulePath, err)))
+			}
+		}
 
-		// Cache the module
+		// Cache the module; if it was loaded meanwhile, keep the first
+		r.modulesMu.Lock()
+		defer r.modulesMu.Unlock()
+		if cached, ok := r.modules[modulePath]; ok {
+			return cached
+		}
 		r.modules[modulePath] = mod
 		return mod
 	}
 }
 
+// checkRequire returns an error if the domain boundaries of the project
+// forbid the calling module to require the module at modulePath
+func (r *Runtime) checkRequire(modulePath string) error {
+	if r.access == nil || strings.HasPrefix(modulePath, stdlibPrefix) {
+		return nil
+	}
+	resolvedPath, err := r.resolveModulePath(modulePath)
+	if err != nil {
+		// Loading the module reports it
+		return nil
+	}
+	return r.access.Check(tsengine.CallerFile(r.vm), resolvedPath, ProtocolDirect)
+}
+
 // loadModule loads a module by path
 func (r *Runtime) loadModule(modulePath string) (interface{}, error) {
 	// Resolve module path
@@ -97,7 +181,7 @@ func (r *Runtime) loadModule(modulePath string) (interface{}, error) {
 
 	// Check if it's a TypeScript or JavaScript file
 	var code string
-	if strings.HasSuffix(resolvedPath, ".ts") {
+	if transpiler.NeedsTranspile(resolvedPath) {
 		// Transpile TypeScript to JavaScript
 		code, err = r.transpiler.TranspileFile(resolvedPath)
 		if err != nil {
@@ -112,17 +196,43 @@ func (r *Runtime) loadModule(modulePath string) (interface{}, error) {
 		code = string(content)
 	}
 
-	// Create module context
+	// Create module context; the module object is registered while the
+	// module loads so that cyclic requires get its partial exports
 	moduleObj := r.vm.NewObject()
 	exportsObj := r.vm.NewObject()
 	moduleObj.Set("exports", exportsObj)
 
-	// Set module and exports in scope
-	r.vm.Set("module", moduleObj)
-	r.vm.Set("exports", exportsObj)
-
-	// Execute the module code
-	_, err = r.vm.RunString(code)
+	r.modulesMu.Lock()
+	r.loading[modulePath] = moduleObj
+	r.modulesMu.Unlock()
+	defer func() {
+		r.modulesMu.Lock()
+		delete(r.loading, modulePath)
+		r.modulesMu.Unlock()
+	}()
+
+	// Run the module in its own function scope, CommonJS-style, so that
+	// its module and exports are not shared with other modules
+	absPath, err := filepath.Abs(resolvedPath)
+	if err != nil {
+		absPath = resolvedPath
+	}
+	r.trackCode(code)
+	wrapped, err := r.vm.RunScript(absPath, moduleWrapperStart+code+moduleWrapperEnd)
+	if err != nil {
+		return nil, fmt.Errorf("module execution failed: %w", err)
+	}
+	moduleFunc, ok := goja.AssertFunction(wrapped)
+	if !ok {
+		return nil, fmt.Errorf("module execution failed: %s is not a module", resolvedPath)
+	}
+	_, err = moduleFunc(goja.Undefined(),
+		exportsObj,
+		r.vm.Get("require"),
+		moduleObj,
+		r.vm.ToValue(absPath),
+		r.vm.ToValue(filepath.Dir(absPath)),
+	)
 	if err != nil {
 		return nil, fmt.Errorf("module execution failed: %w", err)
 	}
@@ -133,26 +243,43 @@ func (r *Runtime) loadModule(modulePath string) (interface{}, error) {
 	return moduleExports, nil
 }
 
+// loadPluginEntry runs the entry point of a plugin. It bypasses the module
+// cache, so that a reloaded plugin runs the current code of the file.
+func (r *Runtime) loadPluginEntry(path string) (goja.Value, error) {
+	exports, err := r.loadModule(path)
+	if err != nil {
+		return nil, err
+	}
+	return r.vm.ToValue(exports), nil
+}
+
+// The module wrapper gives each module its own exports, require, module,
+// __filename and __dirname. The code starts on the wrapper's first line so
+// that error positions match the source.
+const (
+	moduleWrapperStart = "(function (exports, require, module, __filename, __dirname) {"
+	moduleWrapperEnd   = "\n})"
+)
+
+// moduleExtensions are the extensions tried, in order, when resolving a
+// relative module path without one
+var moduleExtensions = []string{".ts", ".tsx", ".js", ".jsx"}
+
 // resolveModulePath resolves a module path to an actual file path
 func (r *Runtime) resolveModulePath(modulePath string) (string, error) {
 	// If it's a relative path, resolve it
-	if strings.HasPrefix(modulePath, "./") || strings.HasPrefix(modulePath, "../") {
+	if strings.HasPrefix(modulePath, "./") || strings.HasPrefix(modulePath, "../") || filepath.IsAbs(modulePath) {
 		// This would need the current module's directory context
 		// For now, just check if file exists
 		if _, err := os.Stat(modulePath); err == nil {
 			return modulePath, nil
 		}
 
-		// Try with .ts extension
-		tsPath := modulePath + ".ts"
-		if _, err := os.Stat(tsPath); err == nil {
-			return tsPath, nil
-		}
-
-		// Try with .js extension
-		jsPath := modulePath + ".js"
-		if _, err := os.Stat(jsPath); err == nil {
-			return jsPath, nil
+		// Try with each source extension
+		for _, ext := range moduleExtensions {
+			if _, err := os.Stat(modulePath + ext); err == nil {
+				return modulePath + ext, nil
+			}
 		}
 	}
 
@@ -191,16 +318,20 @@ func (r *Runtime) resolveModulePath(modulePath string) (string, error) {
 	return "", fmt.Errorf("module not found: %s", modulePath)
 }
 
-// loadStdlib loads all stdlib modules
+// loadStdlib selects the stdlib tree: the stdlib directory if one is
+// given, otherwise the stdlib embedded in the binary. Modules are loaded on
+// demand by require().
 func (r *Runtime) loadStdlib() error {
+	r.stdlibFS = stdlib.FS
 	if r.stdlibPath == "" {
 		return nil
 	}
 
 	// Check if stdlib directory exists
-	if _, err := os.Stat(r.stdlibPath); os.IsNotExist(err) {
-		return fmt.Errorf("stdlib directory not found: %s", r.stdlibPath)
+	if info, err := os.Stat(r.stdlibPath); err != nil || !info.IsDir() {
+		return fmt.Errorf("%w: %s", ErrStdlibNotFound, r.stdlibPath)
 	}
+	r.stdlibFS = os.DirFS(r.stdlibPath)
 
 	// Don't preload stdlib modules - load them on demand via require()
 	// This avoids errors with incomplete stdlib files during development
@@ -212,7 +343,7 @@ func (r *Runtime) ExecuteFile(filePath string) (goja.Value, error) {
 	var code string
 	var err error
 
-	if strings.HasSuffix(filePath, ".ts") {
+	if transpiler.NeedsTranspile(filePath) {
 		// Transpile TypeScript
 		code, err = r.transpiler.TranspileFile(filePath)
 		if err != nil {
@@ -227,22 +358,381 @@ func (r *Runtime) ExecuteFile(filePath string) (goja.Value, error) {
 		code = string(content)
 	}
 
-	// Execute code
-	return r.vm.RunString(code)
+	// Execute code; a module using top-level await runs as an async
+	// function whose promise is awaited
+	program, async, err := compileEntry(filePath, code)
+	if err != nil {
+		return nil, err
+	}
+	r.trackCode(code)
+	result, err := r.runProgram(program)
+	if err != nil || !async {
+		return result, err
+	}
+	return r.awaitTopLevel(result.Export().(*goja.Promise))
+}
+
+// CheckFile transpiles and compiles a file as ExecuteFile does, without
+// running it. Syntax errors are returned as a *SyntaxError.
+func (r *Runtime) CheckFile(filePath string) error {
+	var code string
+	if transpiler.NeedsTranspile(filePath) {
+		js, err := r.transpiler.TranspileFile(filePath)
+		if err != nil {
+			return fmt.Errorf("transpilation failed: %w", err)
+		}
+		code = js
+	} else {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+		code = string(content)
+	}
+	_, _, err := compileEntry(filePath, code)
+	return err
+}
+
+// topLevelAwaitWrapper runs an entry module as the body of an async
+// function. The code starts on the wrapper's first line so that error
+// positions match the source.
+const (
+	topLevelAwaitStart = "(async function () {"
+	topLevelAwaitEnd   = "\n})()"
+)
+
+// compileEntry compiles the code of an entry module. Code that only
+// compiles inside an async function uses top-level await and is compiled
+// wrapped in one; async reports whether it was.
+func compileEntry(name, code string) (program *goja.Program, async bool, err error) {
+	program, err = goja.Compile(name, code, false)
+	if err == nil {
+		return program, false, nil
+	}
+	if !strings.Contains(code, "await") {
+		return nil, false, syntaxError(name, code, err)
+	}
+	wrapped, wrappedErr := goja.Compile(name, topLevelAwaitStart+code+topLevelAwaitEnd, false)
+	if wrappedErr != nil {
+		// Report the error found in the async function, whose body is the
+		// code, as the first error may be the top-level await itself
+		wrappedErr = syntaxError(name, topLevelAwaitStart+code+topLevelAwaitEnd, wrappedErr)
+		var synErr *SyntaxError
+		if errors.As(wrappedErr, &synErr) && synErr.Line == 1 {
+			synErr.Column -= len([]rune(topLevelAwaitStart))
+			synErr.Source = strings.TrimPrefix(synErr.Source, topLevelAwaitStart)
+		}
+		return nil, false, wrappedErr
+	}
+	return wrapped, true, nil
+}
+
+// topLevelResult is how the promise of an entry module settled
+type topLevelResult struct {
+	value goja.Value
+	err   error
+}
+
+// awaitTopLevel waits for the promise of an entry module using top-level
+// await to settle while the event loop runs, and returns its value or
+// rejection. The promise is observed with a then callback attached on the
+// loop, which owns the VM. It fails if the loop runs out of work first, as
+// the promise can then never settle; bindings that finish work in
+// goroutines keep the loop referenced until they settle on it.
+func (r *Runtime) awaitTopLevel(promise *goja.Promise) (goja.Value, error) {
+	if r.eventLoop == nil {
+		switch promise.State() {
+		case goja.PromiseStateFulfilled:
+			return promise.Result(), nil
+		case goja.PromiseStateRejected:
+			reason := promise.Result()
+			return nil, r.vm.Try(func() { panic(reason) })
+		}
+		return nil, fmt.Errorf("top-level await never resolved: the program has no pending work left")
+	}
+
+	done := make(chan topLevelResult, 1)
+	err := r.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+		obj := r.vm.ToValue(promise).ToObject(r.vm)
+		then, _ := goja.AssertFunction(obj.Get("then"))
+		onFulfilled := func(value goja.Value) {
+			done <- topLevelResult{value: value}
+		}
+		onRejected := func(reason goja.Value) {
+			done <- topLevelResult{err: r.vm.Try(func() { panic(reason) })}
+		}
+		_, err := then(obj, r.vm.ToValue(onFulfilled), r.vm.ToValue(onRejected))
+		return err
+	}, eventloop.PriorityNormal))
+	if err != nil {
+		return nil, fmt.Errorf("failed to await top-level promise: %w", err)
+	}
+
+	// The loop is not idle before the event above ran, and the callbacks
+	// run in loop events, so an idle loop with nothing sent means the
+	// promise can no longer settle
+	ticker := time.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case res := <-done:
+			return res.value, res.err
+		case <-ticker.C:
+		}
+		if !r.eventLoop.IsIdle() {
+			continue
+		}
+		select {
+		case res := <-done:
+			return res.value, res.err
+		default:
+			return nil, fmt.Errorf("top-level await never resolved: the program has no pending work left")
+		}
+	}
 }
 
 // ExecuteString executes TypeScript or JavaScript code from a string
 func (r *Runtime) ExecuteString(code string, isTypeScript bool) (goja.Value, error) {
+	return r.ExecuteNamedString(code, "<string>", isTypeScript)
+}
+
+// ExecuteNamedString executes code from a string, reporting errors and stack
+// traces against a synthetic filename such as "<stdin>"
+func (r *Runtime) ExecuteNamedString(code, name string, isTypeScript bool) (goja.Value, error) {
 	if isTypeScript {
 		// Transpile first
-		js, err := r.transpiler.Transpile(code, "<string>")
+		js, err := r.transpiler.Transpile(code, name)
 		if err != nil {
 			return nil, fmt.Errorf("transpilation failed: %w", err)
 		}
 		code = js
 	}
 
-	return r.vm.RunString(code)
+	program, err := goja.Compile(name, code, false)
+	if err != nil {
+		return nil, syntaxError(name, code, err)
+	}
+	r.trackCode(code)
+	return r.runProgram(program)
+}
+
+// runProgram runs program on the VM. The event loop owns the VM once it
+// runs, so the first program, the entry module, runs before the loop is
+// started and the callbacks it schedules run after it returns; later
+// programs run as loop events.
+func (r *Runtime) runProgram(program *goja.Program) (goja.Value, error) {
+	if r.eventLoop == nil || !r.loopStarted {
+		result, err := r.vm.RunProgram(program)
+		r.startLoop()
+		return result, err
+	}
+
+	done := make(chan topLevelResult, 1)
+	err := r.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				done <- topLevelResult{err: fmt.Errorf("program panicked: %v", recovered)}
+			}
+		}()
+		value, err := r.vm.RunProgram(program)
+		done <- topLevelResult{value: value, err: err}
+		return nil
+	}, eventloop.PriorityNormal))
+	if err != nil {
+		return nil, fmt.Errorf("failed to schedule program: %w", err)
+	}
+	res := <-done
+	return res.value, res.err
+}
+
+// startLoop starts the event loop, if the secure APIs enabled one
+func (r *Runtime) startLoop() {
+	if r.eventLoop != nil && !r.loopStarted {
+		r.eventLoop.Start()
+		r.loopStarted = true
+	}
+}
+
+// trackCode accounts executed code to the module the secure APIs were
+// enabled for. Compiled code stays referenced by the VM, so it is never
+// released.
+func (r *Runtime) trackCode(code string) {
+	if r.moduleID != "" {
+		r.memory.Track(r.moduleID, uint64(len(code)))
+	}
+}
+
+// EnableSecureAPIs registers the permission-checked fs, net and env APIs
+// for moduleID and creates the event loop that drives their callbacks. The
+// loop starts once the entry program returns, or on Wait.
+func (r *Runtime) EnableSecureAPIs(permManager *security.PermissionManager, moduleID string) error {
+	if r.eventLoop != nil {
+		return fmt.Errorf("secure APIs already enabled")
+	}
+
+	eventLoop := eventloop.NewLoop(context.Background())
+	eventLoop.SetStallWatchdog(r.stall, reportStall)
+	bindings := tsengine.NewRuntimeBindings(tsengine.NewEngineWithVM(r.vm), eventLoop, permManager, moduleID)
+	bindings.SetArgv(r.argv)
+	if r.ioLog != nil {
+		bindings.SetIOLog(r.ioLog)
+	}
+	if r.exit != nil {
+		bindings.SetExitHandler(r.exit)
+	}
+	bindings.SetClockControl(r.clockCtl)
+	if r.access != nil {
+		bindings.SetModuleAccess(r.access.Check)
+	}
+	bindings.SetPluginEntryLoader(r.loadPluginEntry)
+	bindings.SetShedderFactory(NewRequestShedder)
+	if r.config != nil {
+		bindings.SetConfigWatcher(r.config)
+		if cfg := r.config.Current(); cfg != nil && cfg.Runtime != nil {
+			bindings.SetServerOptions(api.ServerOptions{ReusePort: cfg.Runtime.ReusePort})
+		}
+	}
+	if r.seed != nil {
+		bindings.SetRandomSeed(*r.seed)
+	}
+	r.memory.RegisterModule(moduleID)
+	bindings.SetMemoryReporter(r.memory.Reporter())
+	bindings.SetMemoryTracker(r.memory)
+	r.crashes.RegisterModule(moduleID, nil)
+	bindings.SetCrashRecorder(r.crashes)
+	// Worker tasks run in runtimes of their own, which get a console too
+	bindings.SetWorkerSetup(setConsole)
+	if err := bindings.RegisterAPIs(); err != nil {
+		return fmt.Errorf("failed to register APIs: %w", err)
+	}
+
+	r.eventLoop = eventLoop
+	r.plugins = bindings.PluginManager()
+	r.moduleID = moduleID
+	return nil
+}
+
+// SetCompilerOptions sets the options used to transpile TypeScript
+func (r *Runtime) SetCompilerOptions(opts transpiler.Options) error {
+	return r.transpiler.SetOptions(opts)
+}
+
+// SetTranspileCacheDir keeps transpiled files in dir across runs, so that
+// unchanged files are not transpiled again
+func (r *Runtime) SetTranspileCacheDir(dir string) {
+	r.transpiler.SetDiskCache(transpiler.NewDiskCache(dir))
+}
+
+// SetArgv sets the script arguments exposed as process.argv. It must be
+// called before EnableSecureAPIs.
+func (r *Runtime) SetArgv(argv []string) {
+	r.argv = argv
+}
+
+// SetIOLog records fs and env operations into log, or replays them from
+// it. It must be called before EnableSecureAPIs.
+func (r *Runtime) SetIOLog(log *replay.IOLog) {
+	r.ioLog = log
+}
+
+// SetExitHandler sets the function called by process.exit instead of
+// os.Exit. It must be called before EnableSecureAPIs.
+func (r *Runtime) SetExitHandler(handler func(code int)) {
+	r.exit = handler
+}
+
+// SetClockControl exposes runtime.clock.set and runtime.clock.advance to
+// scripts. It must be called before EnableSecureAPIs.
+func (r *Runtime) SetClockControl(enabled bool) {
+	r.clockCtl = enabled
+}
+
+// SetRandomSeed seeds Math.random so that runs are reproducible. It must
+// be called before EnableSecureAPIs.
+func (r *Runtime) SetRandomSeed(seed int64) {
+	r.seed = &seed
+}
+
+// SetConfigWatcher sets the watcher of the project configuration, which
+// the runtime stops on shutdown. The domains it declares restrict how
+// modules may reach each other, and its transpile cache size bounds the
+// transpiler's cache. It must be called before EnableSecureAPIs.
+func (r *Runtime) SetConfigWatcher(watcher *config.Watcher) {
+	r.config = watcher
+	cfg := watcher.Current()
+	if cfg == nil {
+		return
+	}
+	if len(cfg.Domains) > 0 {
+		r.access = NewModuleAccess(filepath.Dir(watcher.Path()), cfg.Domains)
+	}
+	if cfg.Runtime != nil && cfg.Runtime.TranspileCacheSize > 0 {
+		r.SetTranspileCacheSize(cfg.Runtime.TranspileCacheSize)
+	}
+}
+
+// SetTranspileCacheSize sets how many transpiled files the transpiler keeps
+// in memory. The cache is emptied; the compiler options and the on-disk
+// cache are kept.
+func (r *Runtime) SetTranspileCacheSize(size int) {
+	t := transpiler.NewWithCacheSize(size)
+	// The options were validated when they were set
+	_ = t.SetOptions(r.transpiler.Options())
+	t.SetDiskCache(r.transpiler.DiskCache())
+	r.transpiler = t
+}
+
+// SetStallThreshold sets how long an event may keep the event loop busy
+// before a warning is printed; 0 disables the warning. It must be called
+// before EnableSecureAPIs.
+func (r *Runtime) SetStallThreshold(threshold time.Duration) {
+	r.stall = threshold
+}
+
+// reportStall warns on stderr that an event is blocking the event loop
+func reportStall(stall eventloop.Stall) {
+	fmt.Fprintf(os.Stderr, "Warning: the event loop has been blocked for %s by a %s callback\n",
+		stall.Duration.Round(time.Millisecond), stall.Event)
+	if len(stall.Stack) > 0 {
+		fmt.Fprintf(os.Stderr, "%s\n", stall.Stack)
+	}
+}
+
+// Wait blocks until the event loop has no pending work
+func (r *Runtime) Wait(ctx context.Context) error {
+	if r.eventLoop == nil {
+		return nil
+	}
+	r.startLoop()
+	return r.eventLoop.WaitIdle(ctx)
+}
+
+// Shutdown runs the onShutdown hooks of the program's plugins, once, and
+// stops the event loop
+func (r *Runtime) Shutdown() {
+	if plugins := r.plugins; plugins != nil {
+		r.plugins = nil
+		if err := plugins.Dispatch(plugin.HookShutdown, map[string]interface{}{}); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+	}
+	if r.config != nil {
+		_ = r.config.Stop()
+	}
+	if r.eventLoop != nil {
+		r.eventLoop.Stop()
+	}
+}
+
+// GetMemory returns the per-module memory tracker
+func (r *Runtime) GetMemory() *MemoryIsolation {
+	return r.memory
+}
+
+// GetCrashes returns the record of crashes contained by the runtime, such
+// as panicking worker tasks
+func (r *Runtime) GetCrashes() *CrashContainer {
+	return r.crashes
 }
 
 // GetVM returns the underlying Goja VM
@@ -0,0 +1,92 @@
+package runtime
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"gots-runtime/internal/config"
+	"gots-runtime/internal/security"
+)
+
+// newTestRuntime creates a runtime with the runtime APIs enabled
+func newTestRuntime(t *testing.T) *Runtime {
+	t.Helper()
+	rt, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rt.EnableSecureAPIs(security.NewPermissionManager(), "main"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rt.Shutdown)
+	return rt
+}
+
+// The entry module keeps running after its timer is due; the timer must not
+// run on the loop while it does
+func TestEntryRunsBeforeItsCallbacks(t *testing.T) {
+	rt := newTestRuntime(t)
+	entry := filepath.Join(t.TempDir(), "main.js")
+	script := `
+		var order = [];
+		setTimeout(() => order.push('timer'), 0);
+		Promise.resolve().then(() => order.push('microtask'));
+		const end = Date.now() + 100;
+		while (Date.now() < end) {}
+		order.push('entry');
+	`
+	if err := os.WriteFile(entry, []byte(script), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rt.ExecuteFile(entry); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := rt.Wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	order, err := rt.ExecuteString(`order.join(',')`, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := order.String(); got != "entry,microtask,timer" {
+		t.Errorf("ran %s, want entry,microtask,timer", got)
+	}
+}
+
+func TestConfigBoundsTranspileCache(t *testing.T) {
+	root := t.TempDir()
+	project := `{ "name": "app", "version": "0.1.0", "runtime": { "transpileCacheSize": 2 } }`
+	if err := os.WriteFile(filepath.Join(root, "gots.json"), []byte(project), 0644); err != nil {
+		t.Fatal(err)
+	}
+	watcher, err := config.NewWatcher(filepath.Join(root, "gots.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt, err := New("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(rt.Shutdown)
+	rt.SetConfigWatcher(watcher)
+
+	for i := 0; i < 3; i++ {
+		file := filepath.Join(root, fmt.Sprintf("m%d.ts", i))
+		if err := os.WriteFile(file, []byte(fmt.Sprintf("export const n: number = %d;", i)), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := rt.transpiler.TranspileFile(file); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := rt.transpiler.CacheLen(); n != 2 {
+		t.Errorf("transpiler caches %d files, want 2", n)
+	}
+}
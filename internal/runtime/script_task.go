@@ -0,0 +1,73 @@
+package runtime
+
+import (
+	"context"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// ScriptTask is a task that runs JavaScript in a goja VM. Cancelling its
+// context interrupts the script. The VM must not be used by anything else
+// while the task runs.
+type ScriptTask struct {
+	VM      *goja.Runtime
+	Name    string
+	Code    string
+	Result  goja.Value
+	running bool
+	mu      sync.Mutex
+}
+
+// NewScriptTask creates a task that runs code in vm
+func NewScriptTask(vm *goja.Runtime, name, code string) *ScriptTask {
+	return &ScriptTask{
+		VM:   vm,
+		Name: name,
+		Code: code,
+	}
+}
+
+// Execute runs the script, stopping it when ctx is done
+func (t *ScriptTask) Execute(ctx context.Context) error {
+	t.mu.Lock()
+	t.running = true
+	t.mu.Unlock()
+
+	stop := context.AfterFunc(ctx, func() {
+		t.Interrupt(ctx.Err())
+	})
+
+	result, err := t.VM.RunScript(t.Name, t.Code)
+
+	stop()
+	t.mu.Lock()
+	t.running = false
+	// Drop an interrupt that arrived after the script finished so it does
+	// not stop the next use of the VM
+	t.VM.ClearInterrupt()
+	t.mu.Unlock()
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	if err != nil {
+		return err
+	}
+	t.Result = result
+	return nil
+}
+
+// IsCPUIntensive reports that scripts run on a CPU-bound VM
+func (t *ScriptTask) IsCPUIntensive() bool {
+	return true
+}
+
+// Interrupt stops the script if it is running
+func (t *ScriptTask) Interrupt(reason interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.running {
+		t.VM.Interrupt(reason)
+	}
+}
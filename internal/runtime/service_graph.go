@@ -2,7 +2,10 @@ package runtime
 
 import (
 	"fmt"
+	"sort"
 	"sync"
+
+	"gots-runtime/internal/graph"
 )
 
 // ServiceNode represents a node in the service graph
@@ -112,54 +115,17 @@ func (sg *ServiceGraph) TopologicalSort() ([]string, error) {
 	sg.mu.RLock()
 	defer sg.mu.RUnlock()
 	
-	// Kahn's algorithm
-	inDegree := make(map[string]int)
-	for id := range sg.nodes {
-		inDegree[id] = 0
-	}
-	
-	// Calculate in-degrees
-	for _, node := range sg.nodes {
-		node.mu.RLock()
-		for range node.Dependencies {
-			inDegree[node.ID]++
-		}
-		node.mu.RUnlock()
-	}
-	
-	// Find nodes with no dependencies
-	queue := make([]string, 0)
-	for id, degree := range inDegree {
-		if degree == 0 {
-			queue = append(queue, id)
-		}
-	}
-	
-	result := make([]string, 0)
-	for len(queue) > 0 {
-		nodeID := queue[0]
-		queue = queue[1:]
-		result = append(result, nodeID)
-		
-		node, ok := sg.nodes[nodeID]
-		if !ok {
-			continue
-		}
+	ids := make([]string, 0, len(sg.nodes))
+	deps := make(map[string][]string, len(sg.nodes))
+	for id, node := range sg.nodes {
+		ids = append(ids, id)
 		node.mu.RLock()
-		for _, dependent := range node.Dependents {
-			inDegree[dependent]--
-			if inDegree[dependent] == 0 {
-				queue = append(queue, dependent)
-			}
-		}
+		deps[id] = append([]string(nil), node.Dependencies...)
 		node.mu.RUnlock()
 	}
+	sort.Strings(ids)
 	
-	if len(result) != len(sg.nodes) {
-		return nil, fmt.Errorf("circular dependency detected")
-	}
-	
-	return result, nil
+	return graph.TopologicalSort(ids, deps)
 }
 
 // GetAllNodes returns all nodes
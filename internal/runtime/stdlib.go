@@ -0,0 +1,72 @@
+package runtime
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// stdlibPrefix is the import prefix of standard library modules
+const stdlibPrefix = "gots/stdlib/"
+
+// ErrStdlibNotFound is recorded when the stdlib directory given to the
+// runtime does not exist
+var ErrStdlibNotFound = errors.New("stdlib directory not found")
+
+// stdlibGlobals maps stdlib modules to the global the runtime APIs are
+// registered under, where the names differ
+var stdlibGlobals = map[string]string{
+	"buffer": "Buffer",
+}
+
+// StdlibError returns why the stdlib directory given to New is
+// unavailable, or nil. Without it, stdlib imports fall back to the stdlib
+// embedded in the binary.
+func (r *Runtime) StdlibError() error {
+	return r.stdlibErr
+}
+
+// hasStdlibModule reports whether name is a standard library module
+func (r *Runtime) hasStdlibModule(name string) bool {
+	if matches, _ := fs.Glob(r.stdlibFS, path.Join(name, "*.ts")); len(matches) > 0 {
+		return true
+	}
+	_, err := fs.Stat(r.stdlibFS, name+".ts")
+	return err == nil
+}
+
+// requireStdlib loads a gots/stdlib/* module. Stdlib modules are declared
+// in TypeScript but implemented by the runtime, so the exports are the
+// members of the module's runtime API, plus the API itself under its
+// global name.
+func (r *Runtime) requireStdlib(modulePath string) (goja.Value, error) {
+	name := strings.TrimPrefix(modulePath, stdlibPrefix)
+	if !r.hasStdlibModule(name) {
+		if r.stdlibErr != nil {
+			return nil, fmt.Errorf("cannot load %s: %v; set GOTS_STDLIB_PATH to the stdlib directory of your gots installation", modulePath, r.stdlibErr)
+		}
+		return nil, fmt.Errorf("stdlib module not found: %s", modulePath)
+	}
+
+	global, ok := stdlibGlobals[name]
+	if !ok {
+		global = name
+	}
+	api := r.vm.GlobalObject().Get(global)
+	if api == nil || goja.IsUndefined(api) {
+		return nil, fmt.Errorf("%s is not provided by this runtime", modulePath)
+	}
+
+	exports := r.vm.NewObject()
+	if obj, ok := api.(*goja.Object); ok {
+		for _, key := range obj.Keys() {
+			exports.Set(key, obj.Get(key))
+		}
+	}
+	exports.Set(global, api)
+	return exports, nil
+}
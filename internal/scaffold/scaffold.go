@@ -0,0 +1,110 @@
+// Package scaffold provides the project templates written by gots init.
+// Templates are embedded directories of text/template files whose names
+// end in .tmpl, which is dropped when they are written.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// DefaultTemplate is the template used when none is named
+const DefaultTemplate = "basic"
+
+// templateSuffix ends the names of embedded template files
+const templateSuffix = ".tmpl"
+
+//go:embed templates
+var templateFS embed.FS
+
+// Data is the data templates are rendered with
+type Data struct {
+	Name string // project name
+}
+
+// funcs are the functions available to templates
+var funcs = template.FuncMap{
+	// json quotes a value for a JSON file
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// Templates returns the names of the available templates, sorted
+func Templates() []string {
+	entries, err := fs.ReadDir(templateFS, "templates")
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Write renders the template name into dir, creating dir if needed, and
+// returns the paths of the files written, relative to dir
+func Write(name, dir string, data Data) ([]string, error) {
+	root := path.Join("templates", name)
+	if _, err := fs.Stat(templateFS, root); err != nil {
+		return nil, fmt.Errorf("unknown template %q (available: %s)", name, strings.Join(Templates(), ", "))
+	}
+
+	// Render everything before writing, so that a broken template leaves
+	// no partial project behind
+	files := make(map[string][]byte)
+	err := fs.WalkDir(templateFS, root, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		src, err := templateFS.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		tmpl, err := template.New(file).Funcs(funcs).Parse(string(src))
+		if err != nil {
+			return fmt.Errorf("invalid template %s: %w", file, err)
+		}
+		var out bytes.Buffer
+		if err := tmpl.Execute(&out, data); err != nil {
+			return fmt.Errorf("failed to render %s: %w", file, err)
+		}
+		rel := strings.TrimSuffix(strings.TrimPrefix(file, root+"/"), templateSuffix)
+		files[rel] = out.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	written := make([]string, 0, len(files))
+	for rel := range files {
+		written = append(written, rel)
+	}
+	sort.Strings(written)
+
+	for _, rel := range written {
+		target := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create directory for %s: %w", rel, err)
+		}
+		if err := os.WriteFile(target, files[rel], 0644); err != nil {
+			return nil, fmt.Errorf("failed to create %s: %w", rel, err)
+		}
+	}
+	return written, nil
+}
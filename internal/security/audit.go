@@ -0,0 +1,73 @@
+package security
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditEvent records the outcome of a permission check
+type AuditEvent struct {
+	Time       time.Time  `json:"time"`
+	ModuleID   string     `json:"module"`
+	Permission Permission `json:"permission"`
+	Resource   string     `json:"resource,omitempty"` // path or address checked, if any
+	Allowed    bool       `json:"allowed"`
+	Reason     string     `json:"reason,omitempty"` // why the check was denied
+}
+
+// AuditSink receives an event for every permission check. Sinks are called
+// synchronously by the checking goroutine and must be safe for concurrent
+// use.
+type AuditSink func(event AuditEvent)
+
+// AddAuditSink registers sink to receive an event for every permission
+// check
+func (pm *PermissionManager) AddAuditSink(sink AuditSink) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.auditSinks = append(pm.auditSinks, sink)
+}
+
+// audit sends the outcome of a permission check to the audit sinks
+func (pm *PermissionManager) audit(moduleID string, permission Permission, resource string, err error) {
+	pm.mu.RLock()
+	sinks := pm.auditSinks
+	pm.mu.RUnlock()
+	if len(sinks) == 0 {
+		return
+	}
+
+	event := AuditEvent{
+		Time:       time.Now().UTC(),
+		ModuleID:   moduleID,
+		Permission: permission,
+		Resource:   resource,
+		Allowed:    err == nil,
+	}
+	var permErr *PermissionError
+	if errors.As(err, &permErr) {
+		event.Reason = permErr.Message
+	} else if err != nil {
+		event.Reason = err.Error()
+	}
+
+	for _, sink := range sinks {
+		sink(event)
+	}
+}
+
+// NewJSONLinesAuditSink returns a sink that writes each event to w as a
+// line of JSON. Write errors are dropped, so that auditing never fails the
+// checked operation.
+func NewJSONLinesAuditSink(w io.Writer) AuditSink {
+	var mu sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(event AuditEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = encoder.Encode(event)
+	}
+}
@@ -2,6 +2,10 @@ package security
 
 import (
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
 	"sync"
 )
 
@@ -79,6 +83,7 @@ type Policy struct {
 	moduleID    string
 	permissions *PermissionSet
 	restrictions map[string]interface{}
+	scopes      map[Permission][]string
 	mu          sync.RWMutex
 }
 
@@ -88,14 +93,79 @@ func NewPolicy(moduleID string) *Policy {
 		moduleID:    moduleID,
 		permissions: NewPermissionSet(),
 		restrictions: make(map[string]interface{}),
+		scopes:      make(map[Permission][]string),
 	}
 }
 
-// Allow grants a permission
+// ModuleID returns the module the policy applies to
+func (p *Policy) ModuleID() string {
+	return p.moduleID
+}
+
+// Allow grants a permission without scope restrictions
 func (p *Policy) Allow(permission Permission) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.permissions.Add(permission)
+	delete(p.scopes, permission)
+}
+
+// AllowScoped grants a permission limited to the given scopes (paths for
+// fs permissions). Scopes accumulate across calls unless the permission is
+// already granted without restrictions.
+func (p *Policy) AllowScoped(permission Permission, scopes ...string) {
+	if len(scopes) == 0 {
+		p.Allow(permission)
+		return
+	}
+	
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	
+	if p.permissions.Has(permission) {
+		if _, scoped := p.scopes[permission]; !scoped {
+			return
+		}
+	}
+	p.permissions.Add(permission)
+	p.scopes[permission] = append(p.scopes[permission], scopes...)
+}
+
+// Scopes returns the scopes a permission is limited to; nil means unrestricted
+func (p *Policy) Scopes(permission Permission) []string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	
+	scopes, ok := p.scopes[permission]
+	if !ok {
+		return nil
+	}
+	result := make([]string, len(scopes))
+	copy(result, scopes)
+	return result
+}
+
+// CheckScoped checks if a permission is allowed for a target, using match to
+// compare the target against each granted scope
+func (p *Policy) CheckScoped(permission Permission, target string, match func(scope, target string) bool) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	
+	if !p.permissions.Has(permission) {
+		return false
+	}
+	
+	scopes, scoped := p.scopes[permission]
+	if !scoped || p.permissions.Has(PermissionAll) {
+		return true
+	}
+	
+	for _, scope := range scopes {
+		if match(scope, target) {
+			return true
+		}
+	}
+	return false
 }
 
 // Deny denies a permission
@@ -103,6 +173,7 @@ func (p *Policy) Deny(permission Permission) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
 	p.permissions.Remove(permission)
+	delete(p.scopes, permission)
 }
 
 // Check checks if a permission is allowed
@@ -127,10 +198,18 @@ func (p *Policy) GetRestriction(key string) (interface{}, bool) {
 	return value, ok
 }
 
+// Prompter asks whether a denied permission should be granted. target is
+// the path or address being accessed, or empty for unscoped checks.
+type Prompter func(moduleID string, permission Permission, target string) bool
+
 // PermissionManager manages permissions for modules
 type PermissionManager struct {
-	policies map[string]*Policy
-	mu       sync.RWMutex
+	policies   map[string]*Policy
+	prompter   Prompter
+	auditSinks []AuditSink
+	quotas     *QuotaManager
+	promptMu   sync.Mutex
+	mu         sync.RWMutex
 }
 
 // NewPermissionManager creates a new permission manager
@@ -155,13 +234,34 @@ func (pm *PermissionManager) GetPolicy(moduleID string) (*Policy, bool) {
 	return policy, ok
 }
 
-// CheckPermission checks if a module has a permission
+// SetPrompter sets the function consulted when a permission is denied
+func (pm *PermissionManager) SetPrompter(prompter Prompter) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.prompter = prompter
+}
+
+// CheckPermission checks if a module has a permission and, if it has, that
+// its quota allows another operation
 func (pm *PermissionManager) CheckPermission(moduleID string, permission Permission) error {
+	err := pm.checkPermission(moduleID, permission)
+	if err == nil {
+		err = pm.TakeQuota(moduleID, QuotaCategoryOf(permission))
+	}
+	pm.audit(moduleID, permission, "", err)
+	return err
+}
+
+// checkPermission checks an unscoped permission without auditing it
+func (pm *PermissionManager) checkPermission(moduleID string, permission Permission) error {
 	pm.mu.RLock()
 	policy, ok := pm.policies[moduleID]
 	pm.mu.RUnlock()
 	
 	if !ok {
+		if pm.prompt(moduleID, permission, "") {
+			return nil
+		}
 		// Default: deny all if no policy
 		return &PermissionError{
 			ModuleID:   moduleID,
@@ -171,6 +271,9 @@ func (pm *PermissionManager) CheckPermission(moduleID string, permission Permiss
 	}
 	
 	if !policy.Check(permission) {
+		if pm.prompt(moduleID, permission, "") {
+			return nil
+		}
 		return &PermissionError{
 			ModuleID:   moduleID,
 			Permission: permission,
@@ -181,10 +284,219 @@ func (pm *PermissionManager) CheckPermission(moduleID string, permission Permiss
 	return nil
 }
 
+// CheckPathPermission checks if a module has a permission for a file system
+// path and, if it has, that its quota allows another operation
+func (pm *PermissionManager) CheckPathPermission(moduleID string, permission Permission, path string) error {
+	err := pm.checkPathPermission(moduleID, permission, path)
+	if err == nil {
+		err = pm.TakeQuota(moduleID, QuotaCategoryOf(permission))
+	}
+	pm.audit(moduleID, permission, path, err)
+	return err
+}
+
+// checkPathPermission checks a path permission without auditing it
+func (pm *PermissionManager) checkPathPermission(moduleID string, permission Permission, path string) error {
+	pm.mu.RLock()
+	policy, ok := pm.policies[moduleID]
+	pm.mu.RUnlock()
+	
+	if ok && policy.CheckScoped(permission, path, MatchPath) {
+		return nil
+	}
+	
+	if pm.prompt(moduleID, permission, path) {
+		return nil
+	}
+	
+	message := "permission denied"
+	if !ok {
+		message = "no policy found for module"
+	} else if policy.Check(permission) {
+		message = fmt.Sprintf("path %s is outside the allowed scope", path)
+	}
+	return &PermissionError{
+		ModuleID:   moduleID,
+		Permission: permission,
+		Target:     path,
+		Message:    message,
+	}
+}
+
+// CheckNetPermission checks if a module has a network permission for an
+// address and, if it has, that its quota allows another operation. The
+// address is a host, a host:port pair or, for listeners, :port.
+func (pm *PermissionManager) CheckNetPermission(moduleID string, permission Permission, address string) error {
+	err := pm.checkNetPermission(moduleID, permission, address)
+	if err == nil {
+		err = pm.TakeQuota(moduleID, QuotaCategoryOf(permission))
+	}
+	pm.audit(moduleID, permission, address, err)
+	return err
+}
+
+// checkNetPermission checks a network permission without auditing it
+func (pm *PermissionManager) checkNetPermission(moduleID string, permission Permission, address string) error {
+	pm.mu.RLock()
+	policy, ok := pm.policies[moduleID]
+	pm.mu.RUnlock()
+	
+	if ok && policy.CheckScoped(permission, address, MatchHost) {
+		return nil
+	}
+	
+	if pm.prompt(moduleID, permission, address) {
+		return nil
+	}
+	
+	message := "permission denied"
+	if !ok {
+		message = "no policy found for module"
+	} else if policy.Check(permission) {
+		message = fmt.Sprintf("address %s is not in the allowed host list", address)
+	}
+	return &PermissionError{
+		ModuleID:   moduleID,
+		Permission: permission,
+		Target:     address,
+		Message:    message,
+	}
+}
+
+// prompt asks the prompter to grant a denied permission and records the grant
+func (pm *PermissionManager) prompt(moduleID string, permission Permission, target string) bool {
+	pm.mu.RLock()
+	prompter := pm.prompter
+	pm.mu.RUnlock()
+	
+	if prompter == nil {
+		return false
+	}
+	
+	// Serialize prompts so concurrent denials don't interleave
+	pm.promptMu.Lock()
+	defer pm.promptMu.Unlock()
+	
+	if !prompter(moduleID, permission, target) {
+		return false
+	}
+	
+	pm.mu.Lock()
+	policy, ok := pm.policies[moduleID]
+	if !ok {
+		policy = NewPolicy(moduleID)
+		pm.policies[moduleID] = policy
+	}
+	pm.mu.Unlock()
+	
+	if target == "" {
+		policy.Allow(permission)
+	} else {
+		policy.AllowScoped(permission, target)
+	}
+	return true
+}
+
+// MatchPath reports whether path is the scope path or lies beneath it.
+// Symbolic links are resolved in both, so that a link inside the scope
+// does not grant access to what it points to outside of it.
+func MatchPath(scope, path string) bool {
+	scopeAbs, err := resolvePath(scope)
+	if err != nil {
+		return false
+	}
+	pathAbs, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	
+	rel, err := filepath.Rel(scopeAbs, pathAbs)
+	if err != nil {
+		return false
+	}
+	return rel == "." || (rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)))
+}
+
+// resolvePath returns the absolute path of path with symbolic links
+// resolved. Of a path that does not exist, such as a file about to be
+// created, the longest existing parent is resolved. Dangling links fail.
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	
+	rest := ""
+	for dir := abs; ; dir = filepath.Dir(dir) {
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err == nil {
+			return filepath.Join(resolved, rest), nil
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+		// A dangling link cannot be resolved, and writing to it would
+		// create its target
+		if _, lerr := os.Lstat(dir); lerr == nil {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return abs, nil
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// MatchHost reports whether a network address is allowed by a host scope.
+// Scopes are "host" (any port), "host:port" or "host:*"; a host of "*"
+// matches any host and "*.example.com" matches subdomains of example.com.
+// An empty host, as in ":8080", stands for all interfaces.
+func MatchHost(scope, address string) bool {
+	scopeHost, scopePort := splitHostPort(scope)
+	host, port := splitHostPort(address)
+	
+	// Host-only addresses (DNS lookups) match on the host alone
+	if port != "" && scopePort != "" && scopePort != "*" && scopePort != port {
+		return false
+	}
+	return matchHostname(scopeHost, host)
+}
+
+// splitHostPort splits an address into a normalized host and port. The port
+// is empty when the address has none.
+func splitHostPort(address string) (string, string) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		host, port = strings.Trim(address, "[]"), ""
+	}
+	
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" {
+		host = "0.0.0.0"
+	}
+	return host, port
+}
+
+// matchHostname compares normalized hosts, treating IP addresses by value
+func matchHostname(scope, host string) bool {
+	if scope == "*" || scope == host {
+		return true
+	}
+	if strings.HasPrefix(scope, "*.") {
+		return strings.HasSuffix(host, scope[1:])
+	}
+	
+	scopeIP := net.ParseIP(scope)
+	hostIP := net.ParseIP(host)
+	return scopeIP != nil && hostIP != nil && scopeIP.Equal(hostIP)
+}
+
 // PermissionError represents a permission error
 type PermissionError struct {
 	ModuleID   string
 	Permission Permission
+	Target     string // Path or address being accessed, if any
 	Message    string
 }
 
@@ -0,0 +1,52 @@
+package security
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchPathResolvesSymlinks(t *testing.T) {
+	root := t.TempDir()
+	scope := filepath.Join(root, "scope")
+	outside := filepath.Join(root, "outside")
+	for _, dir := range []string{scope, outside} {
+		if err := os.Mkdir(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(scope, "file"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(outside, "secret"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	links := map[string]string{
+		filepath.Join(scope, "escape"):   outside,
+		filepath.Join(scope, "dangling"): filepath.Join(outside, "missing"),
+		filepath.Join(root, "alias"):     scope,
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks unavailable: %v", err)
+		}
+	}
+
+	for _, test := range []struct {
+		scope, path string
+		want        bool
+	}{
+		{scope, filepath.Join(scope, "file"), true},
+		{scope, filepath.Join(scope, "new", "file"), true},
+		{scope, filepath.Join(scope, "escape", "secret"), false},
+		{scope, filepath.Join(scope, "escape", "new"), false},
+		{scope, filepath.Join(scope, "dangling"), false},
+		{scope, filepath.Join(root, "alias", "file"), true},
+		{filepath.Join(root, "alias"), filepath.Join(scope, "file"), true},
+		{filepath.Join(root, "alias"), filepath.Join(outside, "secret"), false},
+	} {
+		if got := MatchPath(test.scope, test.path); got != test.want {
+			t.Errorf("MatchPath(%s, %s) = %v, want %v", test.scope, test.path, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package security
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// QuotaCategory groups the operations that count against the same quota
+type QuotaCategory string
+
+const (
+	QuotaFS  QuotaCategory = "fs"
+	QuotaNet QuotaCategory = "net"
+	QuotaEnv QuotaCategory = "env"
+	QuotaRPC QuotaCategory = "rpc"
+)
+
+// QuotaCategories lists the quota categories
+var QuotaCategories = []QuotaCategory{QuotaFS, QuotaNet, QuotaEnv, QuotaRPC}
+
+// QuotaCategoryOf returns the quota category of the operations guarded by
+// permission, or "" if they have none
+func QuotaCategoryOf(permission Permission) QuotaCategory {
+	switch permission {
+	case PermissionFSRead, PermissionFSWrite:
+		return QuotaFS
+	case PermissionNetDial, PermissionNetListen:
+		return QuotaNet
+	case PermissionEnvRead, PermissionEnvWrite:
+		return QuotaEnv
+	default:
+		return ""
+	}
+}
+
+// Quota allows Limit operations per Window. Unused capacity accumulates up
+// to Limit, so a module may burst Limit operations at once.
+type Quota struct {
+	Limit  int
+	Window time.Duration
+}
+
+// tokenBucket enforces a quota: it holds up to Limit tokens, refilled at
+// Limit per Window, and every operation takes one
+type tokenBucket struct {
+	quota  Quota
+	tokens float64
+	last   time.Time
+}
+
+// take takes a token at now; if none is left, it returns how long until
+// the next one is available
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	rate := float64(b.quota.Limit) / b.quota.Window.Seconds()
+	b.tokens += now.Sub(b.last).Seconds() * rate
+	if b.tokens > float64(b.quota.Limit) {
+		b.tokens = float64(b.quota.Limit)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rate * float64(time.Second))
+}
+
+// QuotaManager caps how many operations of each category modules may make
+// per window, to contain runaway modules
+type QuotaManager struct {
+	buckets map[string]map[QuotaCategory]*tokenBucket
+	mu      sync.Mutex
+}
+
+// NewQuotaManager creates a quota manager without quotas
+func NewQuotaManager() *QuotaManager {
+	return &QuotaManager{
+		buckets: make(map[string]map[QuotaCategory]*tokenBucket),
+	}
+}
+
+// SetQuota limits the operations of category made by moduleID
+func (qm *QuotaManager) SetQuota(moduleID string, category QuotaCategory, quota Quota) error {
+	if quota.Limit <= 0 || quota.Window <= 0 {
+		return fmt.Errorf("invalid %s quota for module %s: limit and window must be positive", category, moduleID)
+	}
+
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+	if qm.buckets[moduleID] == nil {
+		qm.buckets[moduleID] = make(map[QuotaCategory]*tokenBucket)
+	}
+	qm.buckets[moduleID][category] = &tokenBucket{
+		quota:  quota,
+		tokens: float64(quota.Limit),
+		last:   time.Now(),
+	}
+	return nil
+}
+
+// Take counts an operation of category made by moduleID against its quota.
+// It returns a *QuotaError if the quota is used up.
+func (qm *QuotaManager) Take(moduleID string, category QuotaCategory) error {
+	qm.mu.Lock()
+	defer qm.mu.Unlock()
+
+	bucket, ok := qm.buckets[moduleID][category]
+	if !ok {
+		return nil
+	}
+	allowed, retryAfter := bucket.take(time.Now())
+	if allowed {
+		return nil
+	}
+	return &QuotaError{
+		ModuleID:   moduleID,
+		Category:   category,
+		Quota:      bucket.quota,
+		RetryAfter: retryAfter,
+	}
+}
+
+// QuotaError reports an operation rejected because its module used up its
+// quota
+type QuotaError struct {
+	ModuleID   string
+	Category   QuotaCategory
+	Quota      Quota
+	RetryAfter time.Duration // until the quota allows another operation
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota exceeded: module %s may make %d %s operations per %s",
+		e.ModuleID, e.Quota.Limit, e.Category, e.Quota.Window)
+}
+
+// SetQuotaManager enforces the quotas of qm on permission checks: an
+// allowed check counts as an operation of the permission's category
+func (pm *PermissionManager) SetQuotaManager(qm *QuotaManager) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.quotas = qm
+}
+
+// TakeQuota counts an operation of category made by moduleID against its
+// quota, for operations that are not guarded by a permission check
+func (pm *PermissionManager) TakeQuota(moduleID string, category QuotaCategory) error {
+	pm.mu.RLock()
+	quotas := pm.quotas
+	pm.mu.RUnlock()
+	if quotas == nil || category == "" {
+		return nil
+	}
+	return quotas.Take(moduleID, category)
+}
@@ -0,0 +1,105 @@
+package transpiler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CacheDir is the directory, relative to the project root, that holds the
+// on-disk transpile cache
+const CacheDir = ".gots/cache"
+
+// cacheFormat is the version of the cache entries; changing how entries are
+// produced or stored must change it, so that older entries are not used
+const cacheFormat = 1
+
+// DiskCache stores transpiled JavaScript on disk so that later runs skip
+// transpiling unchanged files. Entries are keyed by a hash of the source,
+// its syntax, the compiler options, the esbuild version and the cache
+// format, so edited sources, changed options and upgrades miss the cache
+// rather than invalidating it.
+type DiskCache struct {
+	dir string
+}
+
+// NewDiskCache creates a disk cache in dir. The directory is created when
+// the first entry is stored.
+func NewDiskCache(dir string) *DiskCache {
+	return &DiskCache{dir: dir}
+}
+
+// Dir returns the cache directory
+func (c *DiskCache) Dir() string {
+	return c.dir
+}
+
+// Get returns the cached JavaScript for key
+func (c *DiskCache) Get(key string) (string, bool) {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return "", false
+	}
+	return string(data), true
+}
+
+// Put stores the JavaScript for key. The entry is written to a temporary
+// file and renamed into place, so concurrent runs never read partial
+// entries.
+func (c *DiskCache) Put(key, js string) error {
+	path := c.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if _, err := tmp.WriteString(js); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+	return nil
+}
+
+// Clean removes every cache entry
+func (c *DiskCache) Clean() error {
+	if err := os.RemoveAll(c.dir); err != nil {
+		return fmt.Errorf("failed to clean cache: %w", err)
+	}
+	return nil
+}
+
+// path returns the file of an entry. Entries are spread over directories
+// named after the first two characters of the key.
+func (c *DiskCache) path(key string) string {
+	return filepath.Join(c.dir, key[:2], key+".js")
+}
+
+// esbuildVersion is the version of the installed esbuild, looked up once;
+// it is empty if esbuild is not installed
+var esbuildVersion = sync.OnceValue(func() string {
+	version, _ := ESBuildVersion()
+	return version
+})
+
+// diskCacheKey returns the cache key of a transpilation
+func diskCacheKey(code, filename string, opts Options) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\x00%s\x00%s\x00%+v\x00", cacheFormat, esbuildVersion(), sourceExt(filename), opts)
+	h.Write([]byte(code))
+	return hex.EncodeToString(h.Sum(nil))
+}
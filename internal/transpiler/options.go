@@ -0,0 +1,91 @@
+package transpiler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Options configures how esbuild transpiles TypeScript. Values follow
+// tsconfig.json compiler options and are case-insensitive.
+type Options struct {
+	Target string // ECMAScript level, e.g. "es2020" or "esnext"
+	Module string // "commonjs", or an ES module kind such as "esnext"
+	JSX    string // "react", "react-jsx", "react-jsxdev" or "preserve"
+
+	// JSXFactory and JSXFragment name the functions that JSX elements and
+	// fragments compile to in "react" mode, e.g. "h" and "Fragment"
+	JSXFactory  string
+	JSXFragment string
+}
+
+// DefaultOptions returns the options used when no compiler options are
+// configured
+func DefaultOptions() Options {
+	return Options{
+		Target: "es2020",
+		Module: "commonjs",
+	}
+}
+
+// targetPattern matches the ECMAScript levels esbuild accepts
+var targetPattern = regexp.MustCompile(`^es(5|6|20[0-9]{2}|next)$`)
+
+// esbuildArgs returns the esbuild flags for the options. Empty fields take
+// their default value.
+func (o Options) esbuildArgs() ([]string, error) {
+	defaults := DefaultOptions()
+
+	target := strings.ToLower(o.Target)
+	if target == "" {
+		target = defaults.Target
+	}
+	if !targetPattern.MatchString(target) {
+		return nil, fmt.Errorf("unsupported target: %s", o.Target)
+	}
+	if target == "es6" {
+		target = "es2015"
+	}
+
+	module := strings.ToLower(o.Module)
+	if module == "" {
+		module = defaults.Module
+	}
+	var format string
+	switch {
+	case module == "commonjs":
+		format = "cjs"
+	case module == "es6" || targetPattern.MatchString(module):
+		format = "esm"
+	default:
+		return nil, fmt.Errorf("unsupported module kind: %s", o.Module)
+	}
+
+	args := []string{
+		"--format=" + format,
+		"--target=" + target,
+		"--platform=node",
+	}
+
+	switch strings.ToLower(o.JSX) {
+	case "":
+	case "react":
+		args = append(args, "--jsx=transform")
+	case "react-jsx":
+		args = append(args, "--jsx=automatic")
+	case "react-jsxdev":
+		args = append(args, "--jsx=automatic", "--jsx-dev")
+	case "preserve", "react-native":
+		args = append(args, "--jsx=preserve")
+	default:
+		return nil, fmt.Errorf("unsupported jsx mode: %s", o.JSX)
+	}
+	if o.JSXFactory != "" {
+		args = append(args, "--jsx-factory="+o.JSXFactory)
+	}
+	if o.JSXFragment != "" {
+		args = append(args, "--jsx-fragment="+o.JSXFragment)
+	}
+
+	return args, nil
+}
@@ -1,30 +1,108 @@
 package transpiler
 
 import (
+	"container/list"
+	"crypto/sha256"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 )
 
+// DefaultCacheSize is the default number of transpiled files kept in memory
+const DefaultCacheSize = 512
+
 // Transpiler handles TypeScript to JavaScript conversion
 type Transpiler struct {
-	// Cache for transpiled code
-	cache map[string]string
+	// Cache for transpiled code, keyed by file path. The most recently used
+	// entry is at the front of order.
+	cache     map[string]*list.Element
+	order     *list.List
+	cacheSize int
+	options   Options
+	disk      *DiskCache
+	mu        sync.Mutex
+}
+
+// cacheEntry is a transpiled file
+type cacheEntry struct {
+	path    string
+	hash    [sha256.Size]byte // hash of the TypeScript source
+	modTime time.Time
+	size    int64
+	js      string
 }
 
 // New creates a new Transpiler instance
 func New() *Transpiler {
+	return NewWithCacheSize(DefaultCacheSize)
+}
+
+// NewWithCacheSize creates a new Transpiler that keeps at most size
+// transpiled files in its cache
+func NewWithCacheSize(size int) *Transpiler {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
 	return &Transpiler{
-		cache: make(map[string]string),
+		cache:     make(map[string]*list.Element),
+		order:     list.New(),
+		cacheSize: size,
+		options:   DefaultOptions(),
 	}
 }
 
-// TranspileFile transpiles a TypeScript file to JavaScript
+// SetOptions sets the compiler options used for subsequent transpilations.
+// Files transpiled with the previous options are dropped from the cache.
+func (t *Transpiler) SetOptions(opts Options) error {
+	if _, err := opts.esbuildArgs(); err != nil {
+		return fmt.Errorf("invalid compiler options: %w", err)
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.options = opts
+	t.cache = make(map[string]*list.Element)
+	t.order.Init()
+	return nil
+}
+
+// Options returns the compiler options
+func (t *Transpiler) Options() Options {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.options
+}
+
+// SetDiskCache sets the on-disk cache that transpiled code is kept in
+// across runs, in addition to the in-memory cache. A nil cache disables it.
+func (t *Transpiler) SetDiskCache(cache *DiskCache) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.disk = cache
+}
+
+// DiskCache returns the on-disk cache, or nil if there is none
+func (t *Transpiler) DiskCache() *DiskCache {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.disk
+}
+
+// TranspileFile transpiles a TypeScript file to JavaScript. Results are
+// cached by path; a file is re-read when its modification time or size
+// changes and re-transpiled when its content changes.
 func (t *Transpiler) TranspileFile(tsFilePath string) (string, error) {
+	info, err := os.Stat(tsFilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+
 	// Check cache first
-	if js, ok := t.cache[tsFilePath]; ok {
+	if js, ok := t.cachedByStat(tsFilePath, info); ok {
 		return js, nil
 	}
 
@@ -33,6 +111,21 @@ func (t *Transpiler) TranspileFile(tsFilePath string) (string, error) {
 	if err != nil {
 		return "", fmt.Errorf("failed to read file: %w", err)
 	}
+	hash := sha256.Sum256(tsCode)
+
+	entry := &cacheEntry{
+		path:    tsFilePath,
+		hash:    hash,
+		modTime: info.ModTime(),
+		size:    info.Size(),
+	}
+
+	// The file may have been touched without changing
+	if js, ok := t.cachedByHash(tsFilePath, hash); ok {
+		entry.js = js
+		t.store(entry)
+		return js, nil
+	}
 
 	// Transpile
 	jsCode, err := t.Transpile(string(tsCode), tsFilePath)
@@ -41,49 +134,168 @@ func (t *Transpiler) TranspileFile(tsFilePath string) (string, error) {
 	}
 
 	// Cache result
-	t.cache[tsFilePath] = jsCode
+	entry.js = jsCode
+	t.store(entry)
 
 	return jsCode, nil
 }
 
-// Transpile converts TypeScript code to JavaScript
+// cachedByStat returns the cached transpilation of path if the file's
+// modification time and size are unchanged
+func (t *Transpiler) cachedByStat(path string, info os.FileInfo) (string, bool) {
+	return t.lookup(path, func(entry *cacheEntry) bool {
+		return entry.modTime.Equal(info.ModTime()) && entry.size == info.Size()
+	})
+}
+
+// cachedByHash returns the cached transpilation of path if it was made from
+// source with the given hash
+func (t *Transpiler) cachedByHash(path string, hash [sha256.Size]byte) (string, bool) {
+	return t.lookup(path, func(entry *cacheEntry) bool {
+		return entry.hash == hash
+	})
+}
+
+// lookup returns the cached transpilation of path if valid accepts it
+func (t *Transpiler) lookup(path string, valid func(*cacheEntry) bool) (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elem, ok := t.cache[path]
+	if !ok {
+		return "", false
+	}
+	entry := elem.Value.(*cacheEntry)
+	if !valid(entry) {
+		return "", false
+	}
+	t.order.MoveToFront(elem)
+	return entry.js, true
+}
+
+// store adds an entry to the cache, evicting the least recently used
+// entries beyond the cache size
+func (t *Transpiler) store(entry *cacheEntry) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if elem, ok := t.cache[entry.path]; ok {
+		elem.Value = entry
+		t.order.MoveToFront(elem)
+		return
+	}
+
+	t.cache[entry.path] = t.order.PushFront(entry)
+	for t.order.Len() > t.cacheSize {
+		oldest := t.order.Back()
+		t.order.Remove(oldest)
+		delete(t.cache, oldest.Value.(*cacheEntry).path)
+	}
+}
+
+// CacheLen returns the number of cached files
+func (t *Transpiler) CacheLen() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.order.Len()
+}
+
+// NeedsTranspile reports whether a file must be transpiled before it is
+// executed: TypeScript, TSX and JSX sources
+func NeedsTranspile(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ts", ".tsx", ".jsx":
+		return true
+	}
+	return false
+}
+
+// IsJSX reports whether a file is a TSX or JSX source
+func IsJSX(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".tsx", ".jsx":
+		return true
+	}
+	return false
+}
+
+// sourceExt returns the extension that tells esbuild how to parse a file.
+// Names without a source extension, such as "<stdin>", are TypeScript.
+func sourceExt(filename string) string {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".ts", ".tsx", ".jsx", ".js", ".mts", ".cts":
+		return ext
+	}
+	return ".ts"
+}
+
+// Transpile converts TypeScript code to JavaScript. The filename's
+// extension selects the syntax, so JSX is only accepted in .tsx and .jsx
+// files.
 func (t *Transpiler) Transpile(tsCode, filename string) (string, error) {
+	opts := t.Options()
+
+	// Reuse the output of an earlier run
+	disk := t.DiskCache()
+	var key string
+	if disk != nil {
+		key = diskCacheKey(tsCode, filename, opts)
+		if js, ok := disk.Get(key); ok {
+			return js, nil
+		}
+	}
+
 	// Try using esbuild first (fastest option)
-	if js, err := t.transpileWithESBuild(tsCode, filename); err == nil {
+	js, err := t.transpileWithESBuild(tsCode, filename, opts)
+	if err == nil {
+		// Only esbuild output is stored, so installing esbuild takes
+		// effect; a failed write just costs a transpile next run
+		if disk != nil {
+			_ = disk.Put(key, js)
+		}
 		return js, nil
 	}
 
+	// The fallback cannot compile JSX
+	if IsJSX(filename) {
+		return "", fmt.Errorf("transpiling %s requires esbuild: %w", filename, err)
+	}
+
 	// Fallback to basic TypeScript stripping
 	return t.basicTypeScriptStrip(tsCode), nil
 }
 
 // transpileWithESBuild uses esbuild for fast TypeScript transpilation
-func (t *Transpiler) transpileWithESBuild(tsCode, filename string) (string, error) {
+func (t *Transpiler) transpileWithESBuild(tsCode, filename string, opts Options) (string, error) {
 	// Check if esbuild is available
 	esbuildPath, err := exec.LookPath("esbuild")
 	if err != nil {
 		return "", fmt.Errorf("esbuild not found: %w", err)
 	}
 
-	// Create temp file for input
-	tmpDir := os.TempDir()
-	inputFile := filepath.Join(tmpDir, "input.ts")
+	optionArgs, err := opts.esbuildArgs()
+	if err != nil {
+		return "", err
+	}
+
+	// Create temp files in a private directory so concurrent transpilations
+	// don't overwrite each other
+	tmpDir, err := os.MkdirTemp("", "gots-transpile-")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(tmpDir)
+	inputFile := filepath.Join(tmpDir, "input"+sourceExt(filename))
 	outputFile := filepath.Join(tmpDir, "output.js")
 
 	// Write TypeScript code to temp file
 	if err := os.WriteFile(inputFile, []byte(tsCode), 0644); err != nil {
 		return "", err
 	}
-	defer os.Remove(inputFile)
 
 	// Run esbuild
-	cmd := exec.Command(esbuildPath,
-		inputFile,
-		"--outfile="+outputFile,
-		"--format=cjs",
-		"--target=es2020",
-		"--platform=node",
-	)
+	args := append([]string{inputFile, "--outfile=" + outputFile}, optionArgs...)
+	cmd := exec.Command(esbuildPath, args...)
 
 	if output, err := cmd.CombinedOutput(); err != nil {
 		return "", fmt.Errorf("esbuild failed: %s", string(output))
@@ -94,7 +306,6 @@ func (t *Transpiler) transpileWithESBuild(tsCode, filename string) (string, erro
 	if err != nil {
 		return "", err
 	}
-	defer os.Remove(outputFile)
 
 	return string(jsCode), nil
 }
@@ -104,6 +315,7 @@ func (t *Transpiler) transpileWithESBuild(tsCode, filename string) (string, erro
 func (t *Transpiler) basicTypeScriptStrip(tsCode string) string {
 	lines := strings.Split(tsCode, "\n")
 	result := make([]string, 0, len(lines))
+	marked := false
 
 	for _, line := range lines {
 		// Skip type-only imports
@@ -124,11 +336,21 @@ func (t *Transpiler) basicTypeScriptStrip(tsCode string) string {
 			continue
 		}
 
-		// Remove 'as' type assertions
-		line = removeTypeAssertions(line)
+		// Remove 'as' type assertions; in imports 'as' renames
+		if !strings.HasPrefix(strings.TrimSpace(line), "import ") {
+			line = removeTypeAssertions(line)
+		}
 
-		// Convert 'export' to module.exports or exports
-		line = convertExports(line)
+		// Convert 'export' to exports, marking the module as converted from
+		// an ES module on its first export so that default imports of it
+		// read exports.default
+		if converted := convertExports(line); converted != line {
+			line = converted
+			if !marked {
+				line = esModuleMarker + line
+				marked = true
+			}
+		}
 
 		// Convert 'import' to require
 		line = convertImports(line)
@@ -184,13 +406,23 @@ func removeTypeAssertions(line string) string {
 	return line
 }
 
+// esModuleMarker marks the exports of a module converted from an ES module,
+// following the __esModule convention of other CommonJS transpilers
+const esModuleMarker = "Object.defineProperty(exports, '__esModule', { value: true }); "
+
+// importDefault evaluates to the default export of the module required by
+// the expression it is formatted with: exports.default for a converted ES
+// module, and the whole exports of a CommonJS module
+const importDefault = "(m => m && m.__esModule ? m.default : m)(%s)"
+
 // convertExports converts ES6 exports to CommonJS
 func convertExports(line string) string {
 	trimmed := strings.TrimSpace(line)
 
-	// export default X -> module.exports = X
+	// export default X -> exports.default = X, so that it does not replace
+	// the named exports
 	if strings.HasPrefix(trimmed, "export default ") {
-		return strings.Replace(line, "export default ", "module.exports = ", 1)
+		return strings.Replace(line, "export default ", "exports.default = ", 1)
 	}
 
 	// export const X = Y -> exports.X = Y
@@ -245,17 +477,29 @@ func convertImports(line string) string {
 
 			modulePath := strings.TrimSpace(parts[1])
 			modulePath = strings.Trim(modulePath, "';\"")
+			require := fmt.Sprintf("require('%s')", modulePath)
 
 			// Handle different import styles
 			if strings.HasPrefix(importPart, "{") {
-				// import { X, Y } from 'module' -> const { X, Y } = require('module')
-				return fmt.Sprintf("const %s = require('%s')", importPart, modulePath)
-			} else if strings.Contains(importPart, ",") {
-				// import X, { Y } from 'module' -> handle mixed imports
-				return fmt.Sprintf("const %s = require('%s')", importPart, modulePath)
+				// import { X, Y as Z } from 'module' -> const { X, Y: Z } = require('module')
+				return fmt.Sprintf("const %s = %s", strings.ReplaceAll(importPart, " as ", ": "), require)
+			} else if strings.HasPrefix(importPart, "* as ") {
+				// import * as X from 'module' -> const X = require('module')
+				return fmt.Sprintf("const %s = %s", strings.TrimSpace(strings.TrimPrefix(importPart, "* as ")), require)
+			} else if comma := strings.Index(importPart, ","); comma != -1 {
+				// import X, { Y } from 'module' -> the default export and
+				// the named ones
+				defaultName := strings.TrimSpace(importPart[:comma])
+				named := strings.TrimSpace(importPart[comma+1:])
+				if strings.HasPrefix(named, "* as ") {
+					named = strings.TrimSpace(strings.TrimPrefix(named, "* as "))
+				} else {
+					named = strings.ReplaceAll(named, " as ", ": ")
+				}
+				return fmt.Sprintf("const %s = "+importDefault+", %s = %s", defaultName, require, named, require)
 			} else {
-				// import X from 'module' -> const X = require('module')
-				return fmt.Sprintf("const %s = require('%s')", importPart, modulePath)
+				// import X from 'module' -> the default export
+				return fmt.Sprintf("const %s = "+importDefault, importPart, require)
 			}
 		}
 	}
@@ -265,5 +509,22 @@ func convertImports(line string) string {
 
 // ClearCache clears the transpilation cache
 func (t *Transpiler) ClearCache() {
-	t.cache = make(map[string]string)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cache = make(map[string]*list.Element)
+	t.order.Init()
+}
+
+// ESBuildVersion returns the version of the esbuild on the PATH that
+// transpiles TypeScript, or an error if there is none
+func ESBuildVersion() (string, error) {
+	esbuildPath, err := exec.LookPath("esbuild")
+	if err != nil {
+		return "", fmt.Errorf("esbuild not found: %w", err)
+	}
+	output, err := exec.Command(esbuildPath, "--version").Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run esbuild: %w", err)
+	}
+	return strings.TrimSpace(string(output)), nil
 }
@@ -0,0 +1,81 @@
+package transpiler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/dop251/goja"
+)
+
+// runConverted evaluates main, converted by the fallback transpiler, with a require
+// that returns the exports of the converted modules
+func runConverted(t *testing.T, modules map[string]string, main string) *goja.Runtime {
+	t.Helper()
+	tr := New()
+	vm := goja.New()
+	cache := make(map[string]goja.Value)
+	vm.Set("require", func(name string) goja.Value {
+		if exports, ok := cache[name]; ok {
+			return exports
+		}
+		source, ok := modules[name]
+		if !ok {
+			panic(vm.NewGoError(fmt.Errorf("no module %s", name)))
+		}
+		exports := vm.NewObject()
+		cache[name] = exports
+		wrapper := "(function (exports) {\n" + tr.basicTypeScriptStrip(source) + "\n})"
+		fn, err := vm.RunString(wrapper)
+		if err != nil {
+			t.Fatalf("compiling %s: %v", name, err)
+		}
+		call, _ := goja.AssertFunction(fn)
+		if _, err := call(goja.Undefined(), exports); err != nil {
+			t.Fatalf("running %s: %v", name, err)
+		}
+		return exports
+	})
+	if _, err := vm.RunString(tr.basicTypeScriptStrip(main)); err != nil {
+		t.Fatalf("running main: %v", err)
+	}
+	return vm
+}
+
+func TestDefaultAndNamedExports(t *testing.T) {
+	modules := map[string]string{
+		"./greet": `export const greeting: string = 'hello'
+export function shout(s) { return s.toUpperCase() }
+export default function greet(name) { return greeting + ' ' + name }`,
+		"./plain": `exports.value = 42`,
+	}
+	vm := runConverted(t, modules, `import greet, { greeting, shout as loud } from './greet'
+import greetAgain from './greet'
+import * as all from './greet'
+import plain from './plain'
+var results = [greet('ts'), greeting, loud('hi'), greetAgain === greet, all.greeting, typeof all.default, plain.value]`)
+
+	var results []interface{}
+	if err := vm.ExportTo(vm.Get("results"), &results); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{"hello ts", "hello", "HI", true, "hello", "function", int64(42)}
+	if fmt.Sprint(results) != fmt.Sprint(want) {
+		t.Errorf("results = %v, want %v", results, want)
+	}
+}
+
+func TestConvertExportsKeepsNamedExports(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"export default app", "exports.default = app"},
+		{"export const x = 1", "const x = 1\nexports.x = x"},
+		{"export { a, b }", "exports.a = a; exports.b = b"},
+	}
+	for _, tt := range tests {
+		if got := convertExports(tt.line); got != tt.want {
+			t.Errorf("convertExports(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package tsengine
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/eventloop"
+)
+
+// registerAbortSignal registers the AbortSignal global with its static
+// methods timeout(ms) and abort(reason)
+func (rb *RuntimeBindings) registerAbortSignal() error {
+	vm := rb.engine.VM()
+	signalCtor := vm.NewObject()
+
+	// timeout(ms) returns a signal that aborts with a TimeoutError once ms
+	// pass on the event loop's clock. Like in browsers, the pending timeout
+	// does not keep the program running.
+	signalCtor.Set("timeout", func(ms int64) *goja.Object {
+		if ms < 0 {
+			panic(vm.NewTypeError("timeout must not be negative"))
+		}
+		signal, abort := rb.newAbortSignal()
+		elapsed := rb.eventLoop.Clock().After(time.Duration(ms) * time.Millisecond)
+		go func() {
+			<-elapsed
+			rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventTimer, func() error {
+				reason := rb.namedError("TimeoutError", fmt.Sprintf("signal timed out after %d ms", ms))
+				reason.Set("code", ErrCodeTimeout)
+				abort(reason)
+				return nil
+			}, eventloop.PriorityNormal))
+		}()
+		return signal
+	})
+
+	// abort(reason) returns a signal that is already aborted
+	signalCtor.Set("abort", func(reason goja.Value) *goja.Object {
+		if reason == nil || goja.IsUndefined(reason) {
+			reason = rb.namedError("AbortError", "This operation was aborted")
+		}
+		signal, abort := rb.newAbortSignal()
+		abort(reason)
+		return signal
+	})
+
+	rb.engine.Set("AbortSignal", signalCtor)
+	return nil
+}
+
+// newAbortSignal creates an AbortSignal-like object and the function that
+// aborts it. Aborting sets aborted and reason and calls onabort and the
+// abort listeners with the reason; later aborts have no effect. The signal
+// must only be used on the event loop.
+func (rb *RuntimeBindings) newAbortSignal() (*goja.Object, func(reason goja.Value)) {
+	vm := rb.engine.VM()
+	signalObj := vm.NewObject()
+	signalObj.Set("aborted", false)
+	signalObj.Set("reason", goja.Undefined())
+	signalObj.Set("onabort", goja.Null())
+
+	var listeners []goja.Value
+
+	signalObj.Set("addEventListener", func(eventType string, listener goja.Value) {
+		if eventType != "abort" {
+			return
+		}
+		if _, ok := goja.AssertFunction(listener); !ok {
+			panic(vm.NewTypeError("listener must be a function"))
+		}
+		listeners = append(listeners, listener)
+	})
+
+	signalObj.Set("removeEventListener", func(eventType string, listener goja.Value) {
+		for i, l := range listeners {
+			if l.StrictEquals(listener) {
+				listeners = append(listeners[:i], listeners[i+1:]...)
+				return
+			}
+		}
+	})
+
+	signalObj.Set("throwIfAborted", func() {
+		if signalObj.Get("aborted").ToBoolean() {
+			panic(signalObj.Get("reason"))
+		}
+	})
+
+	abort := func(reason goja.Value) {
+		if signalObj.Get("aborted").ToBoolean() {
+			return
+		}
+		signalObj.Set("aborted", true)
+		signalObj.Set("reason", reason)
+
+		if onabort, ok := goja.AssertFunction(signalObj.Get("onabort")); ok {
+			_, _ = onabort(signalObj, reason)
+		}
+		for _, l := range listeners {
+			if fn, ok := goja.AssertFunction(l); ok {
+				_, _ = fn(signalObj, reason)
+			}
+		}
+		listeners = nil
+	}
+
+	return signalObj, abort
+}
+
+// namedError creates an Error with the given name and message, such as a
+// TimeoutError
+func (rb *RuntimeBindings) namedError(name, message string) *goja.Object {
+	vm := rb.engine.VM()
+	errObj, err := vm.New(vm.Get("Error"), vm.ToValue(message))
+	if err != nil {
+		errObj = vm.NewObject()
+		errObj.Set("message", message)
+	}
+	errObj.Set("name", name)
+	return errObj
+}
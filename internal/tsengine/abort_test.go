@@ -0,0 +1,122 @@
+package tsengine
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/security"
+)
+
+// newTestBindings registers the runtime APIs on a new runtime and starts
+// its event loop
+func newTestBindings(t *testing.T) (*RuntimeBindings, *eventloop.Loop) {
+	t.Helper()
+	loop := eventloop.NewLoop(context.Background())
+	rb := NewRuntimeBindings(NewEngineWithVM(goja.New()), loop, security.NewPermissionManager(), "test")
+	if err := rb.RegisterAPIs(); err != nil {
+		t.Fatal(err)
+	}
+	loop.Start()
+	t.Cleanup(loop.Stop)
+	return rb, loop
+}
+
+// settleScript runs script on the event loop and returns what the promise
+// it evaluates to settles with, and whether it was fulfilled
+func settleScript(t *testing.T, rb *RuntimeBindings, loop *eventloop.Loop, script string) (interface{}, bool) {
+	t.Helper()
+	type settlement struct {
+		value     interface{}
+		fulfilled bool
+	}
+	settled := make(chan settlement, 1)
+	vm := rb.engine.VM()
+	err := loop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+		value, err := vm.RunString(script)
+		if err != nil {
+			settled <- settlement{value: err.Error()}
+			return nil
+		}
+		promise := value.ToObject(vm)
+		then, _ := goja.AssertFunction(promise.Get("then"))
+		onFulfilled := func(result goja.Value) {
+			settled <- settlement{value: result.Export(), fulfilled: true}
+		}
+		onRejected := func(reason goja.Value) {
+			settled <- settlement{value: reason.Export()}
+		}
+		_, err = then(promise, vm.ToValue(onFulfilled), vm.ToValue(onRejected))
+		return err
+	}, eventloop.PriorityNormal))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case s := <-settled:
+		return s.value, s.fulfilled
+	case <-time.After(5 * time.Second):
+		t.Fatal("promise did not settle")
+		return nil, false
+	}
+}
+
+// withTimeout settles an operation that takes opMs with a resolver, unless
+// AbortSignal.timeout(timeoutMs) aborts it first
+const withTimeout = `
+	(function (opMs, timeoutMs) {
+		const signal = AbortSignal.timeout(timeoutMs);
+		const { promise, resolve, reject } = Promise.withResolvers();
+		const timer = setTimeout(() => resolve('done'), opMs);
+		signal.addEventListener('abort', () => {
+			clearTimeout(timer);
+			reject(signal.reason);
+		});
+		return promise.then(
+			(value) => ({ value, aborted: signal.aborted }),
+			(reason) => { throw { name: reason.name, code: reason.code, aborted: signal.aborted }; });
+	})`
+
+func TestAbortSignalTimeoutRejectsSlowOperation(t *testing.T) {
+	rb, loop := newTestBindings(t)
+
+	start := time.Now()
+	value, fulfilled := settleScript(t, rb, loop, withTimeout+`(5000, 20)`)
+	if fulfilled {
+		t.Fatalf("operation fulfilled with %v, want a timeout", value)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("timed out after %v, want about 20ms", elapsed)
+	}
+	reason, _ := value.(map[string]interface{})
+	if reason["name"] != "TimeoutError" || reason["code"] != ErrCodeTimeout || reason["aborted"] != true {
+		t.Errorf("rejected with %v, want an aborted TimeoutError", value)
+	}
+}
+
+func TestAbortSignalTimeoutLetsFastOperationFinish(t *testing.T) {
+	rb, loop := newTestBindings(t)
+
+	value, fulfilled := settleScript(t, rb, loop, withTimeout+`(5, 5000)`)
+	if !fulfilled {
+		t.Fatalf("operation rejected with %v", value)
+	}
+	result, _ := value.(map[string]interface{})
+	if result["value"] != "done" || result["aborted"] != false {
+		t.Errorf("fulfilled with %v, want done before the signal aborted", value)
+	}
+}
+
+func TestAbortSignalTimeoutRejectsNegativeTimeout(t *testing.T) {
+	rb, loop := newTestBindings(t)
+
+	value, fulfilled := settleScript(t, rb, loop, `
+		new Promise((resolve) => resolve(AbortSignal.timeout(-1)))`)
+	if fulfilled {
+		t.Fatalf("AbortSignal.timeout(-1) returned %v", value)
+	}
+}
@@ -0,0 +1,229 @@
+package tsengine
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// Defaults of async.retry
+const (
+	defaultRetryAttempts   = 3
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 10 * time.Second
+)
+
+// retryOptions configures async.retry
+type retryOptions struct {
+	attempts   int           // calls made before giving up, including the first
+	backoff    time.Duration // delay before the first retry; doubled for each later one
+	maxBackoff time.Duration // cap on the delay
+	jitter     bool          // randomize each delay between half and all of it
+}
+
+// parseRetryOptions reads { attempts, backoffMs, maxBackoffMs, jitter }
+func parseRetryOptions(vm *goja.Runtime, value goja.Value) (retryOptions, error) {
+	opts := retryOptions{
+		attempts:   defaultRetryAttempts,
+		backoff:    defaultRetryBackoff,
+		maxBackoff: defaultRetryMaxBackoff,
+		jitter:     true,
+	}
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return opts, nil
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return opts, fmt.Errorf("retry options must be an object")
+	}
+
+	number := func(name string, min int64) (int64, bool, error) {
+		v := obj.Get(name)
+		if v == nil || goja.IsUndefined(v) {
+			return 0, false, nil
+		}
+		n := v.ToFloat()
+		if math.IsNaN(n) || n < float64(min) {
+			return 0, false, fmt.Errorf("%s must be a number of at least %d", name, min)
+		}
+		return int64(n), true, nil
+	}
+
+	if n, ok, err := number("attempts", 1); err != nil {
+		return opts, err
+	} else if ok {
+		opts.attempts = int(n)
+	}
+	if n, ok, err := number("backoffMs", 0); err != nil {
+		return opts, err
+	} else if ok {
+		opts.backoff = time.Duration(n) * time.Millisecond
+	}
+	if n, ok, err := number("maxBackoffMs", 0); err != nil {
+		return opts, err
+	} else if ok {
+		opts.maxBackoff = time.Duration(n) * time.Millisecond
+	}
+	if v := obj.Get("jitter"); v != nil && !goja.IsUndefined(v) {
+		opts.jitter = v.ToBoolean()
+	}
+	return opts, nil
+}
+
+// delay returns how long to wait before retry number retry (1 for the
+// first retry)
+func (opts retryOptions) delay(retry int, random func() float64) time.Duration {
+	d := opts.backoff
+	for i := 1; i < retry && d < opts.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > opts.maxBackoff {
+		d = opts.maxBackoff
+	}
+	if opts.jitter && random != nil {
+		d = d/2 + time.Duration(random()*float64(d/2))
+	}
+	return d
+}
+
+// whenSettled calls onFulfilled or onRejected once value, a promise or a
+// plain value, settles
+func (rb *RuntimeBindings) whenSettled(value goja.Value, onFulfilled, onRejected func(goja.Value)) {
+	vm := rb.engine.VM()
+	promiseCtor := vm.Get("Promise").ToObject(vm)
+	resolve, _ := goja.AssertFunction(promiseCtor.Get("resolve"))
+	promise, err := resolve(promiseCtor, value)
+	if err != nil {
+		onRejected(rb.exceptionValue(err))
+		return
+	}
+	then, _ := goja.AssertFunction(promise.ToObject(vm).Get("then"))
+	if _, err := then(promise, vm.ToValue(onFulfilled), vm.ToValue(onRejected)); err != nil {
+		onRejected(rb.exceptionValue(err))
+	}
+}
+
+// exceptionValue returns the thrown value of a JavaScript exception, or a
+// structured error for other errors
+func (rb *RuntimeBindings) exceptionValue(err error) goja.Value {
+	if exception, ok := err.(*goja.Exception); ok {
+		return exception.Value()
+	}
+	return rb.jsError(err)
+}
+
+// registerAsync registers the async global with retry and timeout, and
+// Promise.withResolvers
+func (rb *RuntimeBindings) registerAsync() error {
+	vm := rb.engine.VM()
+
+	// Promise.withResolvers() returns a new promise with the functions that
+	// settle it
+	if promiseCtor, ok := vm.Get("Promise").(*goja.Object); ok {
+		promiseCtor.Set("withResolvers", func() *goja.Object {
+			promise, resolve, reject := vm.NewPromise()
+			result := vm.NewObject()
+			result.Set("promise", promise)
+			result.Set("resolve", func(value goja.Value) { resolve(value) })
+			result.Set("reject", func(reason goja.Value) { reject(reason) })
+			return result
+		})
+	}
+
+	asyncObj := vm.NewObject()
+
+	// retry(fn, options) calls fn(attempt) until the promise it returns
+	// fulfills, waiting with exponential backoff between attempts, and
+	// rejects with the last error once the attempts are used up
+	asyncObj.Set("retry", func(fn goja.Value, options goja.Value) *goja.Promise {
+		promise, resolve, reject := vm.NewPromise()
+		call, ok := goja.AssertFunction(fn)
+		if !ok {
+			reject(vm.NewTypeError("retry needs a function"))
+			return promise
+		}
+		opts, err := parseRetryOptions(vm, options)
+		if err != nil {
+			reject(rb.jsError(err))
+			return promise
+		}
+
+		var attempt func(n int)
+		attempt = func(n int) {
+			fail := func(reason goja.Value) {
+				if n >= opts.attempts {
+					reject(reason)
+					return
+				}
+				rb.eventLoop.SetTimeout(opts.delay(n, rb.random), func() error {
+					attempt(n + 1)
+					return nil
+				})
+			}
+
+			result, err := call(goja.Undefined(), vm.ToValue(n))
+			if err != nil {
+				fail(rb.exceptionValue(err))
+				return
+			}
+			rb.whenSettled(result, func(value goja.Value) { resolve(value) }, fail)
+		}
+		attempt(1)
+		return promise
+	})
+
+	// timeout(target, ms) settles like target, a promise or a function
+	// called with an AbortSignal, unless ms pass first: then it rejects
+	// with a TimeoutError and aborts the signal
+	asyncObj.Set("timeout", func(target goja.Value, ms int64) *goja.Promise {
+		promise, resolve, reject := vm.NewPromise()
+		if ms < 0 {
+			reject(rb.jsError(fmt.Errorf("timeout must not be negative")))
+			return promise
+		}
+
+		settled := false
+		signal, abort := rb.newAbortSignal()
+		timer := rb.eventLoop.SetTimeout(time.Duration(ms)*time.Millisecond, func() error {
+			if settled {
+				return nil
+			}
+			settled = true
+			reason := rb.namedError("TimeoutError", fmt.Sprintf("operation timed out after %d ms", ms))
+			reason.Set("code", ErrCodeTimeout)
+			abort(reason)
+			reject(reason)
+			return nil
+		})
+		settle := func(fulfilled bool) func(goja.Value) {
+			return func(value goja.Value) {
+				if settled {
+					return
+				}
+				settled = true
+				rb.eventLoop.ClearTimeout(timer)
+				if fulfilled {
+					resolve(value)
+				} else {
+					reject(value)
+				}
+			}
+		}
+
+		if call, ok := goja.AssertFunction(target); ok {
+			result, err := call(goja.Undefined(), signal)
+			if err != nil {
+				settle(false)(rb.exceptionValue(err))
+				return promise
+			}
+			target = result
+		}
+		rb.whenSettled(target, settle(true), settle(false))
+		return promise
+	})
+
+	rb.engine.Set("async", asyncObj)
+	return nil
+}
@@ -5,16 +5,20 @@ import (
 	"fmt"
 	"io/fs"
 	"net"
+	"os"
 	"sync"
 
 	"github.com/dop251/goja"
 
 	"gots-runtime/internal/api"
+	"gots-runtime/internal/config"
 	"gots-runtime/internal/data"
 	"gots-runtime/internal/eventloop"
 	"gots-runtime/internal/framework"
+	"gots-runtime/internal/ipc"
 	"gots-runtime/internal/observability"
 	"gots-runtime/internal/plugin"
+	"gots-runtime/internal/replay"
 	"gots-runtime/internal/rpc"
 	"gots-runtime/internal/security"
 	"gots-runtime/internal/worker"
@@ -22,11 +26,32 @@ import (
 
 // RuntimeBindings provides TypeScript bindings for runtime APIs
 type RuntimeBindings struct {
-	engine      *Engine
-	eventLoop   *eventloop.Loop
-	permManager *security.PermissionManager
-	moduleID    string
-	mu          sync.RWMutex
+	engine         *Engine
+	eventLoop      *eventloop.Loop
+	permManager    *security.PermissionManager
+	moduleID       string
+	eventBus       *ipc.EventBus
+	bufferProto    *goja.Object
+	argv           []string
+	exitHandler    func(code int)
+	memoryReporter MemoryReporter
+	memoryTracker  MemoryTracker
+	ioLog          *replay.IOLog
+	clockControl   bool
+	randomSeed     *int64
+	random         func() float64
+	plugins        *plugin.PluginManager
+	pluginEntry    plugin.EntryLoader
+	crashRecorder  CrashRecorder
+	workerSetup    func(vm *goja.Runtime)
+	workerPools    []*worker.TypeScriptWorker
+	orchestrator   OrchestratorReporter
+	configWatcher  *config.Watcher
+	shedders       framework.ShedderFactory
+	serverOptions  api.ServerOptions
+	moduleAccess   ModuleAccessCheck
+	rpcServers     map[string]string // RPC address -> file of the listening module
+	mu             sync.RWMutex
 }
 
 // NewRuntimeBindings creates new runtime bindings
@@ -36,11 +61,104 @@ func NewRuntimeBindings(engine *Engine, eventLoop *eventloop.Loop, permManager *
 		eventLoop:   eventLoop,
 		permManager: permManager,
 		moduleID:    moduleID,
+		exitHandler: os.Exit,
+		plugins:     plugin.NewPluginManager(),
 	}
 }
 
+// PluginManager returns the manager of the plugins registered by the
+// program, whose hooks the runtime dispatches
+func (rb *RuntimeBindings) PluginManager() *plugin.PluginManager {
+	return rb.plugins
+}
+
+// SetPluginEntryLoader sets how plugin.getPluginManager().load() runs the
+// entry points of plugins. It must be called before RegisterAPIs.
+func (rb *RuntimeBindings) SetPluginEntryLoader(loader plugin.EntryLoader) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.pluginEntry = loader
+}
+
+// SetArgv sets the arguments exposed as process.argv. It must be called
+// before RegisterAPIs.
+func (rb *RuntimeBindings) SetArgv(argv []string) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.argv = append([]string(nil), argv...)
+}
+
+// SetExitHandler sets the function called by process.exit. It defaults to
+// os.Exit.
+func (rb *RuntimeBindings) SetExitHandler(handler func(code int)) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.exitHandler = handler
+}
+
+// SetMemoryReporter sets the source of the per-module memory usage returned
+// by runtime.memoryUsage()
+func (rb *RuntimeBindings) SetMemoryReporter(reporter MemoryReporter) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.memoryReporter = reporter
+}
+
+// SetShedderFactory sets the factory of the load shedders created by
+// app.shedLoad. It must be called before RegisterAPIs.
+func (rb *RuntimeBindings) SetShedderFactory(factory framework.ShedderFactory) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.shedders = factory
+}
+
+// SetServerOptions sets how the HTTP servers created by the program bind
+// their address. It must be called before RegisterAPIs.
+func (rb *RuntimeBindings) SetServerOptions(opts api.ServerOptions) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.serverOptions = opts
+}
+
+// SetEventBus sets the event bus shared between modules
+func (rb *RuntimeBindings) SetEventBus(bus *ipc.EventBus) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.eventBus = bus
+}
+
 // RegisterAPIs registers all runtime APIs to the TypeScript engine
 func (rb *RuntimeBindings) RegisterAPIs() error {
+	// Register Buffer first; the I/O APIs return Buffers
+	if err := rb.registerBuffer(); err != nil {
+		return fmt.Errorf("failed to register Buffer API: %w", err)
+	}
+	
+	// Register timers and the clock behind Date
+	if err := rb.registerTimers(); err != nil {
+		return fmt.Errorf("failed to register timers: %w", err)
+	}
+	
+	// Register the source behind Math.random
+	if err := rb.registerRandom(); err != nil {
+		return fmt.Errorf("failed to register random source: %w", err)
+	}
+	
+	// Register async helpers
+	if err := rb.registerAsync(); err != nil {
+		return fmt.Errorf("failed to register async API: %w", err)
+	}
+	
+	// Register AbortSignal
+	if err := rb.registerAbortSignal(); err != nil {
+		return fmt.Errorf("failed to register AbortSignal: %w", err)
+	}
+	
+	// Register semaphores and mutexes
+	if err := rb.registerSync(); err != nil {
+		return fmt.Errorf("failed to register sync API: %w", err)
+	}
+	
 	// Register FS API
 	if err := rb.registerFS(); err != nil {
 		return fmt.Errorf("failed to register FS API: %w", err)
@@ -96,6 +214,36 @@ func (rb *RuntimeBindings) RegisterAPIs() error {
 		return fmt.Errorf("failed to register Profiler API: %w", err)
 	}
 	
+	// Register Federation API
+	if err := rb.registerFederation(); err != nil {
+		return fmt.Errorf("failed to register Federation API: %w", err)
+	}
+	
+	// Register Event Bus API
+	if err := rb.registerBus(); err != nil {
+		return fmt.Errorf("failed to register Event Bus API: %w", err)
+	}
+	
+	// Register URL and query string API
+	if err := rb.registerURL(); err != nil {
+		return fmt.Errorf("failed to register URL API: %w", err)
+	}
+	
+	// Register Path API
+	if err := rb.registerPath(); err != nil {
+		return fmt.Errorf("failed to register Path API: %w", err)
+	}
+	
+	// Register process global
+	if err := rb.registerProcess(); err != nil {
+		return fmt.Errorf("failed to register process API: %w", err)
+	}
+	
+	// Register runtime global
+	if err := rb.registerRuntime(); err != nil {
+		return fmt.Errorf("failed to register runtime API: %w", err)
+	}
+	
 	return nil
 }
 
@@ -107,61 +255,125 @@ func (rb *RuntimeBindings) registerFS() error {
 	fsObj := rb.engine.VM().NewObject()
 	
 	// Register async methods with promise-like callbacks
-	fsObj.Set("readFile", func(path string, callback goja.Callable) {
-		secureFS.ReadFile(path, func(data []byte, err error) {
-			if callback != nil {
-				if err != nil {
-					_, _ = callback(nil, rb.engine.VM().ToValue(err.Error()))
-				} else {
-					_, _ = callback(rb.engine.VM().ToValue(string(data)), nil)
-				}
+	// readFile(path, [encoding], callback) passes a Buffer, or a string when
+	// an encoding is given
+	fsObj.Set("readFile", func(call goja.FunctionCall) goja.Value {
+		path := call.Argument(0).String()
+		encoding, callback := optionalArg(call, 1)
+		
+		var data []byte
+		rb.ioAsync("fs.readFile", map[string]string{"path": path}, &data, func(done func(error)) {
+			secureFS.ReadFile(path, func(d []byte, err error) {
+				data = d
+				done(err)
+			})
+		}, func(err error) {
+			if callback == nil {
+				return
+			}
+			if err != nil {
+				_, _ = callback(goja.Undefined(), goja.Undefined(), rb.jsError(err))
+				return
 			}
+			value, err := rb.decodeBytes(data, encoding)
+			if err != nil {
+				_, _ = callback(goja.Undefined(), goja.Undefined(), rb.jsError(err))
+				return
+			}
+			_, _ = callback(goja.Undefined(), value)
 		})
+		return goja.Undefined()
 	})
 	
-	fsObj.Set("writeFile", func(path string, data string, callback goja.Callable) {
-		secureFS.WriteFile(path, []byte(data), 0644, func(err error) {
+	// writeFile(path, data, [perm], callback) accepts a string or Buffer
+	fsObj.Set("writeFile", func(call goja.FunctionCall) goja.Value {
+		path := call.Argument(0).String()
+		permArg, callback := optionalArg(call, 2)
+		perm := os.FileMode(0644)
+		if permArg != nil {
+			perm = os.FileMode(permArg.ToInteger())
+		}
+		
+		data, err := bytesOf(call.Argument(1), "")
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		
+		writeData := map[string]interface{}{"path": path, "size": len(data)}
+		rb.ioAsync("fs.writeFile", writeData, nil, func(done func(error)) {
+			secureFS.WriteFile(path, data, perm, done)
+		}, func(err error) {
 			if callback != nil {
 				if err != nil {
-					_, _ = callback(nil, rb.engine.VM().ToValue(err.Error()))
+					_, _ = callback(goja.Undefined(), rb.jsError(err))
 				} else {
-					_, _ = callback(nil, nil)
+					_, _ = callback(goja.Undefined())
 				}
 			}
 		})
+		return goja.Undefined()
 	})
 	
 	fsObj.Set("readDir", func(path string, callback goja.Callable) {
-		secureFS.ReadDir(path, func(entries []fs.DirEntry, err error) {
+		var entries []dirEntry
+		rb.ioAsync("fs.readDir", map[string]string{"path": path}, &entries, func(done func(error)) {
+			secureFS.ReadDir(path, func(dirEntries []fs.DirEntry, err error) {
+				for _, entry := range dirEntries {
+					entries = append(entries, dirEntry{Name: entry.Name(), IsDir: entry.IsDir()})
+				}
+				done(err)
+			})
+		}, func(err error) {
 			if callback != nil {
 				if err != nil {
-					_, _ = callback(nil, rb.engine.VM().ToValue(err.Error()))
+					_, _ = callback(goja.Undefined(), goja.Undefined(), rb.jsError(err))
 				} else {
 					entriesArray := rb.engine.VM().NewArray()
 					for i, entry := range entries {
 						entryObj := rb.engine.VM().NewObject()
-						entryObj.Set("name", entry.Name())
-						entryObj.Set("isDir", entry.IsDir())
+						entryObj.Set("name", entry.Name)
+						entryObj.Set("isDir", entry.IsDir)
 						entriesArray.Set(fmt.Sprintf("%d", i), entryObj)
 					}
-					_, _ = callback(entriesArray, nil)
+					_, _ = callback(goja.Undefined(), entriesArray)
 				}
 			}
 		})
 	})
 	
 	// Register sync methods
-	fsObj.Set("readFileSync", func(path string) string {
-		data, err := secureFS.ReadFileSync(path)
+	fsObj.Set("readFileSync", func(path string, encoding goja.Value) goja.Value {
+		var data []byte
+		err := rb.ioSync("fs.readFile", map[string]string{"path": path}, &data, func() error {
+			var err error
+			data, err = secureFS.ReadFileSync(path)
+			return err
+		})
 		if err != nil {
-			panic(rb.engine.VM().ToValue(err.Error()))
+			panic(rb.jsError(err))
 		}
-		return string(data)
+		value, err := rb.decodeBytes(data, encoding)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return value
 	})
 	
-	fsObj.Set("writeFileSync", func(path, data string) {
-		if err := secureFS.WriteFileSync(path, []byte(data), 0644); err != nil {
-			panic(rb.engine.VM().ToValue(err.Error()))
+	fsObj.Set("writeFileSync", func(path string, data goja.Value, perm goja.Value) {
+		bytes, err := bytesOf(data, "")
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		mode := os.FileMode(0644)
+		if perm != nil && !goja.IsUndefined(perm) {
+			mode = os.FileMode(perm.ToInteger())
+		}
+		writeData := map[string]interface{}{"path": path, "size": len(bytes)}
+		err = rb.ioSync("fs.writeFile", writeData, nil, func() error {
+			return secureFS.WriteFileSync(path, bytes, mode)
+		})
+		if err != nil {
+			panic(rb.jsError(err))
 		}
 	})
 	
@@ -169,6 +381,39 @@ func (rb *RuntimeBindings) registerFS() error {
 	return nil
 }
 
+// dirEntry is a directory entry returned by fs.readDir
+type dirEntry struct {
+	Name  string `json:"name"`
+	IsDir bool   `json:"isDir"`
+}
+
+// optionalArg returns the optional argument at index and the callback that
+// follows it. If the argument at index is itself a function it is the
+// callback and the optional argument is nil.
+func optionalArg(call goja.FunctionCall, index int) (goja.Value, goja.Callable) {
+	arg := call.Argument(index)
+	if callback, ok := goja.AssertFunction(arg); ok {
+		return nil, callback
+	}
+	callback, _ := goja.AssertFunction(call.Argument(index + 1))
+	if goja.IsUndefined(arg) || goja.IsNull(arg) {
+		return nil, callback
+	}
+	return arg, callback
+}
+
+// decodeBytes returns data as a Buffer, or as a string if an encoding is given
+func (rb *RuntimeBindings) decodeBytes(data []byte, encoding goja.Value) (goja.Value, error) {
+	if encoding == nil || goja.IsUndefined(encoding) || goja.IsNull(encoding) {
+		return rb.buffer(data), nil
+	}
+	str, err := EncodeBytes(data, encoding.String())
+	if err != nil {
+		return nil, err
+	}
+	return rb.engine.VM().ToValue(str), nil
+}
+
 // registerNet registers network API
 func (rb *RuntimeBindings) registerNet() error {
 	secureNet := api.NewSecureNet(rb.eventLoop, rb.permManager, rb.moduleID)
@@ -179,10 +424,10 @@ func (rb *RuntimeBindings) registerNet() error {
 		secureNet.Dial(network, address, func(conn net.Conn, err error) {
 			if callback != nil {
 				if err != nil {
-					_, _ = callback(nil, rb.engine.VM().ToValue(err.Error()))
+					_, _ = callback(goja.Undefined(), goja.Undefined(), rb.jsError(err))
 				} else {
 					connObj := rb.createConnObject(conn)
-					_, _ = callback(connObj, nil)
+					_, _ = callback(goja.Undefined(), connObj)
 				}
 			}
 		})
@@ -192,10 +437,10 @@ func (rb *RuntimeBindings) registerNet() error {
 		secureNet.Listen(network, address, func(listener net.Listener, err error) {
 			if callback != nil {
 				if err != nil {
-					_, _ = callback(nil, rb.engine.VM().ToValue(err.Error()))
+					_, _ = callback(goja.Undefined(), goja.Undefined(), rb.jsError(err))
 				} else {
 					listenerObj := rb.createListenerObject(listener)
-					_, _ = callback(listenerObj, nil)
+					_, _ = callback(goja.Undefined(), listenerObj)
 				}
 			}
 		})
@@ -211,24 +456,10 @@ func (rb *RuntimeBindings) registerHTTP() error {
 	
 	httpObj := rb.engine.VM().NewObject()
 	
-	// HTTP server
-	httpObj.Set("createServer", func(callback goja.Callable) interface{} {
-		server := httpAPI.NewServer(":0") // Dynamic port
-		
-		serverObj := rb.engine.VM().NewObject()
-		serverObj.Set("listen", func(port int, callback goja.Callable) {
-			server.ListenAndServe(func(err error) {
-				if callback != nil {
-					if err != nil {
-						_, _ = callback(rb.engine.VM().ToValue(err.Error()))
-					} else {
-						_, _ = callback(nil)
-					}
-				}
-			})
-		})
-		
-		return serverObj
+	// HTTP server; the handler is called as handler(req, res) for every
+	// request and the server binds when listen is called
+	httpObj.Set("createServer", func(handler goja.Value) *goja.Object {
+		return rb.createHTTPServer(httpAPI, handler)
 	})
 	
 	rb.engine.Set("http", httpObj)
@@ -241,23 +472,44 @@ func (rb *RuntimeBindings) registerEnv() error {
 	
 	envObj := rb.engine.VM().NewObject()
 	
-	envObj.Set("get", func(key string) (string, error) {
-		return secureEnv.Get(key)
+	envObj.Set("get", func(key string) string {
+		var value string
+		err := rb.ioSync("env.get", map[string]string{"key": key}, &value, func() error {
+			var err error
+			value, err = secureEnv.Get(key)
+			return err
+		})
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return value
 	})
 	
-	envObj.Set("set", func(key, value string) error {
-		return secureEnv.Set(key, value)
+	envObj.Set("set", func(key, value string) {
+		err := rb.ioSync("env.set", map[string]string{"key": key}, nil, func() error {
+			return secureEnv.Set(key, value)
+		})
+		if err != nil {
+			panic(rb.jsError(err))
+		}
 	})
 	
-	envObj.Set("lookup", func(key string) (interface{}, error) {
-		value, ok, err := secureEnv.LookupEnv(key)
+	envObj.Set("lookup", func(key string) interface{} {
+		var value *string
+		err := rb.ioSync("env.lookup", map[string]string{"key": key}, &value, func() error {
+			v, ok, err := secureEnv.LookupEnv(key)
+			if ok {
+				value = &v
+			}
+			return err
+		})
 		if err != nil {
-			return nil, err
+			panic(rb.jsError(err))
 		}
-		if !ok {
-			return nil, nil
+		if value == nil {
+			return nil
 		}
-		return value, nil
+		return *value
 	})
 	
 	rb.engine.Set("env", envObj)
@@ -270,26 +522,46 @@ func (rb *RuntimeBindings) registerCrypto() error {
 	
 	cryptoObj := rb.engine.VM().NewObject()
 	
-	cryptoObj.Set("md5", func(data string) string {
-		return cryptoAPI.MD5([]byte(data))
+	cryptoObj.Set("md5", func(data goja.Value) string {
+		bytes, err := bytesOf(data, "")
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return cryptoAPI.MD5(bytes)
 	})
 	
-	cryptoObj.Set("sha256", func(data string) string {
-		return cryptoAPI.SHA256([]byte(data))
+	cryptoObj.Set("sha256", func(data goja.Value) string {
+		bytes, err := bytesOf(data, "")
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return cryptoAPI.SHA256(bytes)
 	})
 	
-	cryptoObj.Set("randomBytes", func(n int) string {
-		bytes, err := cryptoAPI.RandomBytes(n)
+	// Random values are recorded through the I/O log, so replays see the
+	// recorded values
+	cryptoObj.Set("randomBytes", func(n int) goja.Value {
+		var bytes []byte
+		err := rb.ioSync("crypto.randomBytes", map[string]int{"n": n}, &bytes, func() error {
+			var err error
+			bytes, err = cryptoAPI.RandomBytes(n)
+			return err
+		})
 		if err != nil {
-			panic(rb.engine.VM().ToValue(err.Error()))
+			panic(rb.jsError(err))
 		}
-		return string(bytes)
+		return rb.buffer(bytes)
 	})
 	
 	cryptoObj.Set("randomUUID", func() string {
-		uuid, err := cryptoAPI.RandomUUID()
+		var uuid string
+		err := rb.ioSync("crypto.randomUUID", nil, &uuid, func() error {
+			var err error
+			uuid, err = cryptoAPI.RandomUUID()
+			return err
+		})
 		if err != nil {
-			panic(rb.engine.VM().ToValue(err.Error()))
+			panic(rb.jsError(err))
 		}
 		return uuid
 	})
@@ -298,17 +570,155 @@ func (rb *RuntimeBindings) registerCrypto() error {
 	return nil
 }
 
-// createConnObject creates a connection object for TypeScript
+// createConnObject creates a connection object for TypeScript. Reads fill
+// the caller's Uint8Array in place and writes accept strings or Buffers.
 func (rb *RuntimeBindings) createConnObject(conn net.Conn) *goja.Object {
-	connObj := rb.engine.VM().NewObject()
-	// Connection methods would be implemented here
+	vm := rb.engine.VM()
+	apiConn := api.NewConn(conn, rb.eventLoop)
+	connObj := vm.NewObject()
+	
+	connObj.Set("read", func(buffer goja.Value, callback goja.Callable) {
+		bytes, err := bytesOf(buffer, "")
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		apiConn.Read(bytes, func(n int, err error) {
+			if callback == nil {
+				return
+			}
+			if err != nil {
+				_, _ = callback(goja.Undefined(), vm.ToValue(n), rb.jsError(err))
+			} else {
+				_, _ = callback(goja.Undefined(), vm.ToValue(n))
+			}
+		})
+	})
+	
+	connObj.Set("readSync", func(buffer goja.Value) int {
+		bytes, err := bytesOf(buffer, "")
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		n, err := conn.Read(bytes)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return n
+	})
+	
+	connObj.Set("write", func(data goja.Value, callback goja.Callable) {
+		bytes, err := bytesOf(data, "")
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		apiConn.Write(bytes, func(n int, err error) {
+			if callback == nil {
+				return
+			}
+			if err != nil {
+				_, _ = callback(goja.Undefined(), vm.ToValue(n), rb.jsError(err))
+			} else {
+				_, _ = callback(goja.Undefined(), vm.ToValue(n))
+			}
+		})
+	})
+	
+	connObj.Set("writeSync", func(data goja.Value) int {
+		bytes, err := bytesOf(data, "")
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		n, err := conn.Write(bytes)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return n
+	})
+	
+	connObj.Set("close", func(callback goja.Callable) {
+		apiConn.Close(func(err error) {
+			if callback == nil {
+				return
+			}
+			if err != nil {
+				_, _ = callback(goja.Undefined(), rb.jsError(err))
+			} else {
+				_, _ = callback(goja.Undefined())
+			}
+		})
+	})
+	
+	connObj.Set("closeSync", func() {
+		if err := conn.Close(); err != nil {
+			panic(rb.jsError(err))
+		}
+	})
+	
+	connObj.Set("localAddr", func() string {
+		return conn.LocalAddr().String()
+	})
+	
+	connObj.Set("remoteAddr", func() string {
+		return conn.RemoteAddr().String()
+	})
+	
 	return connObj
 }
 
 // createListenerObject creates a listener object for TypeScript
 func (rb *RuntimeBindings) createListenerObject(listener net.Listener) *goja.Object {
 	listenerObj := rb.engine.VM().NewObject()
-	// Listener methods would be implemented here
+	
+	listenerObj.Set("accept", func(callback goja.Callable) {
+		go func() {
+			conn, err := listener.Accept()
+			_ = rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				if callback == nil {
+					if conn != nil {
+						conn.Close()
+					}
+					return nil
+				}
+				if err != nil {
+					_, _ = callback(goja.Undefined(), goja.Undefined(), rb.jsError(err))
+				} else {
+					_, _ = callback(goja.Undefined(), rb.createConnObject(conn))
+				}
+				return nil
+			}, eventloop.PriorityNormal))
+		}()
+	})
+	
+	listenerObj.Set("acceptSync", func() *goja.Object {
+		conn, err := listener.Accept()
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return rb.createConnObject(conn)
+	})
+	
+	listenerObj.Set("close", func(callback goja.Callable) {
+		err := listener.Close()
+		if callback == nil {
+			return
+		}
+		if err != nil {
+			_, _ = callback(goja.Undefined(), rb.jsError(err))
+		} else {
+			_, _ = callback(goja.Undefined())
+		}
+	})
+	
+	listenerObj.Set("closeSync", func() {
+		if err := listener.Close(); err != nil {
+			panic(rb.jsError(err))
+		}
+	})
+	
+	listenerObj.Set("addr", func() string {
+		return listener.Addr().String()
+	})
+	
 	return listenerObj
 }
 
@@ -320,7 +730,7 @@ func (rb *RuntimeBindings) registerWorker() error {
 	ctx := context.Background()
 	
 	// Create default worker pool (min 2, max 10 workers)
-	defaultWorker := worker.NewTypeScriptWorker(ctx, vm, 2, 10)
+	defaultWorker := rb.newWorkerPool(ctx, 2, 10)
 	
 	// Create worker namespace
 	workerObj := vm.NewObject()
@@ -337,11 +747,11 @@ func (rb *RuntimeBindings) registerWorker() error {
 			maxWorkers = minWorkers
 		}
 		
-		pool := worker.NewTypeScriptWorker(ctx, vm, minWorkers, maxWorkers)
+		pool := rb.newWorkerPool(ctx, minWorkers, maxWorkers)
 		
 		poolObj := vm.NewObject()
-		poolObj.Set("spawn", func(taskID string, handler goja.Callable, data goja.Value) *goja.Promise {
-			return pool.Spawn(taskID, handler, data)
+		poolObj.Set("spawn", func(taskID string, handler goja.Value, data goja.Value, transfer goja.Value) *goja.Promise {
+			return rb.trackPromise(pool.Spawn(taskID, handler, data, arrayValues(vm, transfer)...), estimateSize(data.Export()))
 		})
 		poolObj.Set("spawnBatch", func(tasks goja.Value) *goja.Promise {
 			if tasksArray, ok := tasks.(*goja.Object); ok {
@@ -350,23 +760,34 @@ func (rb *RuntimeBindings) registerWorker() error {
 				for i := int64(0); i < length; i++ {
 					taskSlice[i] = tasksArray.Get(fmt.Sprintf("%d", i))
 				}
-				return pool.SpawnBatch(taskSlice)
+				return rb.trackPromise(pool.SpawnBatch(taskSlice), estimateSize(tasksArray.Export()))
 			}
 			promise, _, reject := vm.NewPromise()
 			reject(vm.ToValue("tasks must be an array"))
 			return promise
 		})
+		poolObj.Set("map", func(items goja.Value, fn goja.Value, options goja.Value) *goja.Promise {
+			return rb.workerMap(pool, items, fn, options)
+		})
 		poolObj.Set("getStats", func() map[string]interface{} {
 			return pool.GetStats()
 		})
 		poolObj.Set("close", func() *goja.Promise {
 			promise, resolve, reject := vm.NewPromise()
+			rb.eventLoop.Ref()
 			go func() {
-				if err := pool.Close(); err != nil {
-					reject(vm.ToValue(err.Error()))
-				} else {
-					resolve(vm.ToValue(true))
-				}
+				defer rb.eventLoop.Unref()
+				rb.removeWorkerPool(pool)
+				err := pool.Close()
+				// Settle on the event loop, which owns the VM
+				_ = rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+					if err != nil {
+						reject(rb.jsError(err))
+					} else {
+						resolve(vm.ToValue(true))
+					}
+					return nil
+				}, eventloop.PriorityNormal))
 			}()
 			return promise
 		})
@@ -375,16 +796,92 @@ func (rb *RuntimeBindings) registerWorker() error {
 	})
 	
 	// Create spawnWorker convenience function
-	workerObj.Set("spawn", func(taskID string, handler goja.Callable, data goja.Value) *goja.Promise {
-		return defaultWorker.Spawn(taskID, handler, data)
+	workerObj.Set("spawn", func(taskID string, handler goja.Value, data goja.Value, transfer goja.Value) *goja.Promise {
+		return rb.trackPromise(defaultWorker.Spawn(taskID, handler, data, arrayValues(vm, transfer)...), estimateSize(data.Export()))
+	})
+	
+	// worker.sharedBuffer(size) creates memory shared with workers, used
+	// through the atomics functions
+	workerObj.Set("sharedBuffer", func(size int) *goja.Object {
+		buf, err := worker.NewSharedBuffer(size)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return worker.NewSharedBufferObject(vm, buf)
+	})
+	// atomics is also a global, like Atomics in browsers
+	atomics := worker.NewAtomicsObject(vm)
+	workerObj.Set("atomics", atomics)
+	rb.engine.Set("atomics", atomics)
+	
+	// worker.map(items, fn, { concurrency, errors }) maps items on the
+	// default pool
+	workerObj.Set("map", func(items goja.Value, fn goja.Value, options goja.Value) *goja.Promise {
+		return rb.workerMap(defaultWorker, items, fn, options)
 	})
 	
 	// Expose worker API
 	rb.engine.Set("worker", workerObj)
 	
+	// structuredClone(value, { transfer }) copies a value the same way data
+	// is passed to workers
+	rb.engine.Set("structuredClone", func(value goja.Value, options goja.Value) goja.Value {
+		var transfer []goja.Value
+		if options != nil && !goja.IsUndefined(options) && !goja.IsNull(options) {
+			transfer = arrayValues(vm, options.ToObject(vm).Get("transfer"))
+		}
+		
+		cloned, err := worker.StructuredClone(vm, value, transfer...)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		result, err := cloned.Value(vm)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return result
+	})
+	
 	return nil
 }
 
+// workerMap runs fn over the items of an array on pool
+func (rb *RuntimeBindings) workerMap(pool *worker.TypeScriptWorker, items goja.Value, fn goja.Value, options goja.Value) *goja.Promise {
+	vm := rb.engine.VM()
+	obj, ok := items.(*goja.Object)
+	if !ok || obj.ClassName() != "Array" {
+		promise, _, reject := vm.NewPromise()
+		reject(rb.jsError(fmt.Errorf("items must be an array")))
+		return promise
+	}
+	opts, err := worker.ParseMapOptions(options)
+	if err != nil {
+		promise, _, reject := vm.NewPromise()
+		reject(rb.jsError(err))
+		return promise
+	}
+	return rb.trackPromise(pool.Map(arrayValues(vm, items), fn, opts), estimateSize(items.Export()))
+}
+
+// arrayValues returns the elements of a JavaScript array, or nil if value is
+// not an array
+func arrayValues(vm *goja.Runtime, value goja.Value) []goja.Value {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	obj, ok := value.(*goja.Object)
+	if !ok || obj.ClassName() != "Array" {
+		return nil
+	}
+	
+	length := obj.Get("length").ToInteger()
+	values := make([]goja.Value, 0, length)
+	for i := int64(0); i < length; i++ {
+		values = append(values, obj.Get(fmt.Sprintf("%d", i)))
+	}
+	return values
+}
+
 // registerImmutableData registers immutable data structures API
 func (rb *RuntimeBindings) registerImmutableData() error {
 	vm := rb.engine.VM()
@@ -392,62 +889,57 @@ func (rb *RuntimeBindings) registerImmutableData() error {
 	// Create data namespace
 	dataObj := vm.NewObject()
 	
-	// Create Map factory
+	// Create Map factory. entries may be an array or other iterable of
+	// [key, value] pairs, a Map or a plain object; keys are converted to
+	// strings as the map's get and set do.
 	dataObj.Set("createMap", func(entries goja.Value) *goja.Object {
 		im := data.NewImmutableMap()
 		
-		// If entries provided, add them
-		if entries != nil && !goja.IsUndefined(entries) {
-			if entriesArray, ok := entries.(*goja.Object); ok {
-				length := entriesArray.Get("length").ToInteger()
-				for i := int64(0); i < length; i++ {
-					entry := entriesArray.Get(fmt.Sprintf("%d", i))
-					if entryArray, ok := entry.(*goja.Object); ok {
-						key := entryArray.Get("0").Export()
-						value := entryArray.Get("1").Export()
-						im = im.Set(fmt.Sprintf("%v", key), value)
-					}
-				}
+		if !isNullish(entries) {
+			pairs, err := mapEntries(vm, entries)
+			if err != nil {
+				panic(rb.jsError(fmt.Errorf("createMap: %w", err)))
+			}
+			for _, pair := range pairs {
+				im = im.Set(pair[0].String(), pair[1].Export())
 			}
 		}
 		
-		return data.NewTypeScriptImmutableMap(vm, im).ToJSObject()
+		return data.NewTypeScriptImmutableMap(vm, im).WithTracker(rb.trackAllocation).ToJSObject()
 	})
 	
-	// Create List factory
+	// Create List factory. items may be an array, Set or other iterable.
 	dataObj.Set("createList", func(items goja.Value) *goja.Object {
 		il := data.NewImmutableList()
 		
-		// If items provided, add them
-		if items != nil && !goja.IsUndefined(items) {
-			if itemsArray, ok := items.(*goja.Object); ok {
-				length := itemsArray.Get("length").ToInteger()
-				for i := int64(0); i < length; i++ {
-					item := itemsArray.Get(fmt.Sprintf("%d", i)).Export()
-					il = il.Append(item)
-				}
+		if !isNullish(items) {
+			values, err := iterableValues(vm, items)
+			if err != nil {
+				panic(rb.jsError(fmt.Errorf("createList: %w", err)))
+			}
+			for _, value := range values {
+				il = il.Append(value.Export())
 			}
 		}
 		
-		return data.NewTypeScriptImmutableList(vm, il).ToJSObject()
+		return data.NewTypeScriptImmutableList(vm, il).WithTracker(rb.trackAllocation).ToJSObject()
 	})
 	
-	// Create Set factory
+	// Create Set factory. items may be an array, Set or other iterable.
 	dataObj.Set("createSet", func(items goja.Value) *goja.Object {
 		is := data.NewImmutableSet()
 		
-		// If items provided, add them
-		if items != nil && !goja.IsUndefined(items) {
-			if itemsArray, ok := items.(*goja.Object); ok {
-				length := itemsArray.Get("length").ToInteger()
-				for i := int64(0); i < length; i++ {
-					item := itemsArray.Get(fmt.Sprintf("%d", i)).Export()
-					is = is.Add(item)
-				}
+		if !isNullish(items) {
+			values, err := iterableValues(vm, items)
+			if err != nil {
+				panic(rb.jsError(fmt.Errorf("createSet: %w", err)))
+			}
+			for _, value := range values {
+				is = is.Add(data.ExportValue(value))
 			}
 		}
 		
-		return data.NewTypeScriptImmutableSet(vm, is).ToJSObject()
+		return data.NewTypeScriptImmutableSet(vm, is).WithTracker(rb.trackAllocation).ToJSObject()
 	})
 	
 	// Expose data API
@@ -471,9 +963,17 @@ func (rb *RuntimeBindings) registerFramework() error {
 		}
 		
 		tsApp := framework.NewTypeScriptApp(vm, rb.eventLoop, appName)
+		tsApp.SetSchemaCompiler(CompileRouteSchema)
+		tsApp.SetPluginManager(rb.plugins)
+		if rb.shedders != nil {
+			tsApp.SetShedderFactory(rb.shedders)
+		}
+		tsApp.SetServerOptions(rb.serverOptions)
 		return tsApp.ToJSObject()
 	})
 	
+	frameworkObj.Set("throwHTTPError", framework.ThrowHTTPError(vm))
+	
 	// Expose framework API
 	rb.engine.Set("framework", frameworkObj)
 	
@@ -490,21 +990,36 @@ func (rb *RuntimeBindings) registerRPC() error {
 	
 	// Create server factory
 	rpcObj.Set("createServer", func() *goja.Object {
-		server := rpc.NewTypeScriptRPCServer(vm, ctx)
-		return server.ToJSObject()
+		server := rpc.NewTypeScriptRPCServer(vm, rb.eventLoop, ctx)
+		serverObj := server.ToJSObject()
+		rb.trackRPCServer(serverObj)
+		return serverObj
 	})
 	
 	// Create client factory
-	rpcObj.Set("createClient", func(address string) *goja.Promise {
+	rpcObj.Set("createClient", func(address string, options goja.Value) *goja.Promise {
 		promise, resolve, reject := vm.NewPromise()
+		opts := rpc.ParseClientOptions(options)
+		if err := rb.checkRPCAccess(address); err != nil {
+			reject(rb.jsError(err))
+			return promise
+		}
 		
+		rb.eventLoop.Ref()
 		go func() {
-			client, err := rpc.NewTypeScriptRPCClient(vm, address)
-			if err != nil {
-				reject(vm.ToValue(err.Error()))
-			} else {
-				resolve(client.ToJSObject())
-			}
+			defer rb.eventLoop.Unref()
+			client, err := rpc.NewTypeScriptRPCClientWithOptions(vm, rb.eventLoop, address, opts)
+			// Settle on the event loop, which owns the VM
+			_ = rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				if err != nil {
+					reject(rb.jsError(err))
+					return nil
+				}
+				clientObj := client.ToJSObject()
+				rb.guardRPCCalls(clientObj)
+				resolve(clientObj)
+				return nil
+			}, eventloop.PriorityNormal))
 		}()
 		
 		return promise
@@ -516,13 +1031,35 @@ func (rb *RuntimeBindings) registerRPC() error {
 	return nil
 }
 
+// registerBus registers the pub/sub event bus API
+func (rb *RuntimeBindings) registerBus() error {
+	rb.mu.Lock()
+	if rb.eventBus == nil {
+		rb.eventBus = ipc.NewEventBus(rb.eventLoop)
+	}
+	bus := rb.eventBus
+	rb.mu.Unlock()
+	
+	tsBus := ipc.NewTypeScriptEventBus(rb.engine.VM(), bus)
+	rb.engine.Set("bus", tsBus.ToJSObject())
+	
+	return nil
+}
+
 // registerPlugin registers the plugin system API
 func (rb *RuntimeBindings) registerPlugin() error {
 	vm := rb.engine.VM()
 	
-	// Create plugin manager
-	manager := plugin.NewPluginManager()
-	tsManager := plugin.NewTypeScriptPluginManager(vm, manager)
+	tsManager := plugin.NewTypeScriptPluginManager(vm, rb.plugins)
+	if rb.pluginEntry != nil {
+		tsManager.SetEntryLoader(rb.pluginEntry)
+	}
+	tsManager.SetScheduler(func(task func()) error {
+		return rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+			task()
+			return nil
+		}, eventloop.PriorityNormal))
+	})
 	
 	// Create plugin namespace
 	pluginObj := vm.NewObject()
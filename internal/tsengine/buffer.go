@@ -0,0 +1,297 @@
+package tsengine
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/dop251/goja"
+)
+
+// Buffers are Uint8Arrays whose prototype adds toString(encoding) and a few
+// Node-style helpers. They wrap Go byte slices without copying, so binary
+// data read from files or sockets is never routed through Go strings.
+
+// Supported buffer encodings
+const (
+	EncodingUTF8   = "utf8"
+	EncodingHex    = "hex"
+	EncodingBase64 = "base64"
+	EncodingLatin1 = "latin1"
+)
+
+// maxBufferLength is the largest size a Buffer can be allocated with
+const maxBufferLength = 1<<31 - 1
+
+// normalizeEncoding maps encoding aliases to a supported encoding
+func normalizeEncoding(encoding string) (string, error) {
+	switch strings.ToLower(encoding) {
+	case "", "utf8", "utf-8":
+		return EncodingUTF8, nil
+	case "hex":
+		return EncodingHex, nil
+	case "base64":
+		return EncodingBase64, nil
+	case "latin1", "binary", "ascii":
+		return EncodingLatin1, nil
+	default:
+		return "", fmt.Errorf("unknown encoding: %s", encoding)
+	}
+}
+
+// EncodeBytes converts bytes to a string in the given encoding
+func EncodeBytes(data []byte, encoding string) (string, error) {
+	encoding, err := normalizeEncoding(encoding)
+	if err != nil {
+		return "", err
+	}
+
+	switch encoding {
+	case EncodingHex:
+		return hex.EncodeToString(data), nil
+	case EncodingBase64:
+		return base64.StdEncoding.EncodeToString(data), nil
+	case EncodingLatin1:
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes), nil
+	default:
+		if utf8.Valid(data) {
+			return string(data), nil
+		}
+		// Replace invalid sequences like TextDecoder does
+		return strings.ToValidUTF8(string(data), "�"), nil
+	}
+}
+
+// DecodeString converts a string in the given encoding to bytes
+func DecodeString(str, encoding string) ([]byte, error) {
+	encoding, err := normalizeEncoding(encoding)
+	if err != nil {
+		return nil, err
+	}
+
+	switch encoding {
+	case EncodingHex:
+		return hex.DecodeString(str)
+	case EncodingBase64:
+		return base64.StdEncoding.DecodeString(str)
+	case EncodingLatin1:
+		data := make([]byte, 0, len(str))
+		for _, r := range str {
+			data = append(data, byte(r))
+		}
+		return data, nil
+	default:
+		return []byte(str), nil
+	}
+}
+
+// newBuffer wraps data in a Buffer without copying it
+func newBuffer(vm *goja.Runtime, proto *goja.Object, data []byte) goja.Value {
+	if data == nil {
+		data = []byte{}
+	}
+	array, err := vm.New(vm.Get("Uint8Array"), vm.ToValue(vm.NewArrayBuffer(data)))
+	if err != nil {
+		panic(err)
+	}
+	array.SetPrototype(proto)
+	return array
+}
+
+// allocSize returns the size of a Buffer to allocate, throwing a TypeError
+// when size is not a number or is negative or too large
+func allocSize(vm *goja.Runtime, size goja.Value) int {
+	switch size.Export().(type) {
+	case int64, float64:
+	default:
+		panic(vm.NewTypeError("size must be a number, got %s", describeValue(size)))
+	}
+	n := size.ToFloat()
+	if math.IsNaN(n) || n < 0 || n > maxBufferLength {
+		panic(vm.NewTypeError("size must be between 0 and %d, got %v", maxBufferLength, size))
+	}
+	return int(n)
+}
+
+// buffer wraps data in a Buffer of the bindings' runtime
+func (rb *RuntimeBindings) buffer(data []byte) goja.Value {
+	return newBuffer(rb.engine.VM(), rb.bufferProto, data)
+}
+
+// bytesOf returns the bytes of a string, ArrayBuffer or ArrayBuffer view.
+// Views share memory with their buffer; strings are decoded with encoding.
+func bytesOf(value goja.Value, encoding string) ([]byte, error) {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil, fmt.Errorf("data must be a string, Buffer or Uint8Array")
+	}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return DecodeString(value.String(), encoding)
+	}
+
+	if buf, ok := obj.Export().(goja.ArrayBuffer); ok {
+		return buf.Bytes(), nil
+	}
+
+	bufferObj, ok := obj.Get("buffer").(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("data must be a string, Buffer or Uint8Array")
+	}
+	buf, ok := bufferObj.Export().(goja.ArrayBuffer)
+	if !ok {
+		return nil, fmt.Errorf("data must be a string, Buffer or Uint8Array")
+	}
+
+	offset := obj.Get("byteOffset").ToInteger()
+	length := obj.Get("byteLength").ToInteger()
+	bytes := buf.Bytes()
+	if offset < 0 || length < 0 || offset+length > int64(len(bytes)) {
+		return nil, fmt.Errorf("view is out of bounds of its buffer")
+	}
+	return bytes[offset : offset+length], nil
+}
+
+// registerBuffer registers the Buffer global
+func (rb *RuntimeBindings) registerBuffer() error {
+	vm := rb.engine.VM()
+	uint8Array := vm.Get("Uint8Array").ToObject(vm)
+	proto := vm.NewObject()
+	proto.SetPrototype(uint8Array.Get("prototype").ToObject(vm))
+
+	thisBytes := func(call goja.FunctionCall) []byte {
+		data, err := bytesOf(call.This, "")
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		return data
+	}
+
+	proto.Set("toString", func(call goja.FunctionCall) goja.Value {
+		encoding := ""
+		if arg := call.Argument(0); !goja.IsUndefined(arg) {
+			encoding = arg.String()
+		}
+		str, err := EncodeBytes(thisBytes(call), encoding)
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		return vm.ToValue(str)
+	})
+
+	proto.Set("equals", func(call goja.FunctionCall) goja.Value {
+		other, err := bytesOf(call.Argument(0), "")
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		return vm.ToValue(string(thisBytes(call)) == string(other))
+	})
+
+	proto.Set("toJSON", func(call goja.FunctionCall) goja.Value {
+		data := thisBytes(call)
+		values := make([]interface{}, len(data))
+		for i, b := range data {
+			values[i] = int64(b)
+		}
+		return vm.ToValue(map[string]interface{}{"type": "Buffer", "data": values})
+	})
+
+	// subarray and slice return Buffers sharing memory with this one, like
+	// Node; the inherited methods would return plain Uint8Arrays and slice
+	// would copy
+	subarray, ok := goja.AssertFunction(uint8Array.Get("prototype").ToObject(vm).Get("subarray"))
+	if !ok {
+		return fmt.Errorf("Uint8Array.prototype.subarray is not a function")
+	}
+	view := func(call goja.FunctionCall) goja.Value {
+		result, err := subarray(call.This, call.Arguments...)
+		if err != nil {
+			panic(err)
+		}
+		result.ToObject(vm).SetPrototype(proto)
+		return result
+	}
+	proto.Set("subarray", view)
+	proto.Set("slice", view)
+
+	// Buffer(size) and Buffer(data, encoding) behave like Buffer.alloc and
+	// Buffer.from; instanceof works through the shared prototype
+	var from func(data goja.Value, encoding goja.Value) goja.Value
+	buffer := vm.ToValue(func(call goja.ConstructorCall) *goja.Object {
+		arg := call.Argument(0)
+		switch arg.Export().(type) {
+		case int64, float64:
+			return newBuffer(vm, proto, make([]byte, allocSize(vm, arg))).ToObject(vm)
+		default:
+			return from(arg, call.Argument(1)).ToObject(vm)
+		}
+	}).ToObject(vm)
+	buffer.Set("prototype", proto)
+	proto.Set("constructor", buffer)
+
+	from = func(data goja.Value, encoding goja.Value) goja.Value {
+		enc := ""
+		if encoding != nil && !goja.IsUndefined(encoding) {
+			enc = encoding.String()
+		}
+		if obj, ok := data.(*goja.Object); ok && obj.ClassName() == "Array" {
+			length := obj.Get("length").ToInteger()
+			bytes := make([]byte, length)
+			for i := int64(0); i < length; i++ {
+				bytes[i] = byte(obj.Get(fmt.Sprintf("%d", i)).ToInteger())
+			}
+			return newBuffer(vm, proto, bytes)
+		}
+		bytes, err := bytesOf(data, enc)
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		// Buffer.from copies, like Node
+		return newBuffer(vm, proto, append([]byte(nil), bytes...))
+	}
+	buffer.Set("from", from)
+
+	buffer.Set("alloc", func(size goja.Value) goja.Value {
+		return newBuffer(vm, proto, make([]byte, allocSize(vm, size)))
+	})
+
+	buffer.Set("concat", func(list []goja.Value) goja.Value {
+		result := make([]byte, 0)
+		for _, item := range list {
+			bytes, err := bytesOf(item, "")
+			if err != nil {
+				panic(vm.NewTypeError(err.Error()))
+			}
+			result = append(result, bytes...)
+		}
+		return newBuffer(vm, proto, result)
+	})
+
+	buffer.Set("isBuffer", func(value goja.Value) bool {
+		obj, ok := value.(*goja.Object)
+		return ok && obj.Prototype() == proto
+	})
+
+	buffer.Set("byteLength", func(value goja.Value, encoding goja.Value) int {
+		enc := ""
+		if encoding != nil && !goja.IsUndefined(encoding) {
+			enc = encoding.String()
+		}
+		bytes, err := bytesOf(value, enc)
+		if err != nil {
+			panic(vm.NewTypeError(err.Error()))
+		}
+		return len(bytes)
+	})
+
+	rb.bufferProto = proto
+	rb.engine.Set("Buffer", buffer)
+	return nil
+}
@@ -0,0 +1,31 @@
+package tsengine
+
+import "testing"
+
+func TestBufferViewsAreBuffers(t *testing.T) {
+	rb, loop := newTestBindings(t)
+
+	for _, method := range []string{"subarray", "slice"} {
+		t.Run(method, func(t *testing.T) {
+			value, fulfilled := settleScript(t, rb, loop, `(async () => {
+				const buf = Buffer.from("hello world");
+				const view = buf.`+method+`(6);
+				view[0] = 87;
+				return [
+					Buffer.isBuffer(view),
+					view.toString("hex"),
+					view.equals(Buffer.from("World")),
+					JSON.stringify(view.`+method+`(0, 2)),
+					buf.toString(),
+				].join(" ");
+			})()`)
+			if !fulfilled {
+				t.Fatalf("script rejected: %v", value)
+			}
+			want := `true 576f726c64 true {"type":"Buffer","data":[87,111]} hello World`
+			if value != want {
+				t.Errorf("got %q, want %q", value, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package tsengine
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/config"
+	"gots-runtime/internal/eventloop"
+)
+
+// SetConfigWatcher sets the watcher of the project configuration behind
+// runtime.config() and runtime.onConfigChange(). It must be called before
+// RegisterAPIs.
+func (rb *RuntimeBindings) SetConfigWatcher(watcher *config.Watcher) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.configWatcher = watcher
+}
+
+// registerConfig adds config() and onConfigChange(cb) to the runtime
+// object. Without a watcher, config() returns null and no changes are
+// reported.
+func (rb *RuntimeBindings) registerConfig(runtimeObj *goja.Object) {
+	vm := rb.engine.VM()
+	rb.mu.RLock()
+	watcher := rb.configWatcher
+	rb.mu.RUnlock()
+
+	// config() returns the project configuration in effect
+	runtimeObj.Set("config", func() goja.Value {
+		if watcher == nil {
+			return goja.Null()
+		}
+		return rb.configValue(watcher.Current())
+	})
+
+	// onConfigChange(cb) calls cb({ config, previous }) on the event loop
+	// whenever a valid change of the configuration is loaded, and returns a
+	// function that stops the notifications
+	runtimeObj.Set("onConfigChange", func(callback goja.Value) func() {
+		fn, ok := goja.AssertFunction(callback)
+		if !ok {
+			panic(vm.NewTypeError("onConfigChange needs a callback function"))
+		}
+		if watcher == nil {
+			return func() {}
+		}
+		return watcher.Subscribe(func(change config.ConfigChange) {
+			rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				event := vm.NewObject()
+				event.Set("config", rb.configValue(change.Config))
+				event.Set("previous", rb.configValue(change.Previous))
+				_, _ = fn(goja.Undefined(), event)
+				return nil
+			}, eventloop.PriorityNormal))
+		})
+	})
+
+	if watcher != nil {
+		watcher.OnError(func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", watcher.Path(), err)
+		})
+	}
+}
+
+// configValue converts a configuration to a plain JavaScript object with
+// the property names of gots.json
+func (rb *RuntimeBindings) configValue(cfg *config.ProjectConfig) goja.Value {
+	vm := rb.engine.VM()
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return goja.Null()
+	}
+	var plain interface{}
+	if err := json.Unmarshal(data, &plain); err != nil {
+		return goja.Null()
+	}
+	return vm.ToValue(plain)
+}
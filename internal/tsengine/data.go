@@ -0,0 +1,101 @@
+package tsengine
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+)
+
+// isNullish reports whether value is missing, undefined or null
+func isNullish(value goja.Value) bool {
+	return value == nil || goja.IsUndefined(value) || goja.IsNull(value)
+}
+
+// iterableValues returns the values of a JavaScript array, Set, Map (as
+// [key, value] entries), generator or other iterable, or of an array-like
+// object. Strings and other primitives are rejected rather than iterated.
+func iterableValues(vm *goja.Runtime, value goja.Value) ([]goja.Value, error) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("expected an array or iterable, got %s", describeValue(value))
+	}
+
+	source := obj
+	if obj.ClassName() != "Array" {
+		_, iterable := goja.AssertFunction(obj.GetSymbol(goja.SymIterator))
+		if !iterable && isNullish(obj.Get("length")) {
+			return nil, fmt.Errorf("expected an array or iterable, got %s", describeValue(value))
+		}
+		arrayFrom, _ := goja.AssertFunction(vm.Get("Array").ToObject(vm).Get("from"))
+		array, err := arrayFrom(goja.Undefined(), obj)
+		if err != nil {
+			return nil, err
+		}
+		source = array.ToObject(vm)
+	}
+
+	var values []goja.Value
+	if err := vm.ExportTo(source, &values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+// mapEntries returns the [key, value] entries of a JavaScript iterable of
+// pairs, such as an array of pairs or a Map, or the own enumerable
+// properties of a plain object
+func mapEntries(vm *goja.Runtime, value goja.Value) ([][2]goja.Value, error) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, fmt.Errorf("expected entries or an object, got %s", describeValue(value))
+	}
+
+	_, iterable := goja.AssertFunction(obj.GetSymbol(goja.SymIterator))
+	if obj.ClassName() != "Array" && !iterable {
+		keys := obj.Keys()
+		entries := make([][2]goja.Value, 0, len(keys))
+		for _, key := range keys {
+			entries = append(entries, [2]goja.Value{vm.ToValue(key), obj.Get(key)})
+		}
+		return entries, nil
+	}
+
+	items, err := iterableValues(vm, obj)
+	if err != nil {
+		return nil, err
+	}
+	entries := make([][2]goja.Value, 0, len(items))
+	for i, item := range items {
+		pair, err := iterableValues(vm, item)
+		if err != nil || len(pair) < 2 {
+			return nil, fmt.Errorf("entry %d is not a [key, value] pair", i)
+		}
+		entries = append(entries, [2]goja.Value{pair[0], pair[1]})
+	}
+	return entries, nil
+}
+
+// describeValue names the type of a JavaScript value for error messages
+func describeValue(value goja.Value) string {
+	switch {
+	case value == nil || goja.IsUndefined(value):
+		return "undefined"
+	case goja.IsNull(value):
+		return "null"
+	}
+	if obj, ok := value.(*goja.Object); ok {
+		if _, isFunc := goja.AssertFunction(obj); isFunc {
+			return "function"
+		}
+		return "object"
+	}
+	switch value.Export().(type) {
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64, float64:
+		return "number"
+	}
+	return "a primitive value"
+}
@@ -0,0 +1,96 @@
+package tsengine
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// ModuleAccessCheck returns an error if the module in file from may not
+// reach the module in file to via protocol, e.g. "direct" or "rpc"
+type ModuleAccessCheck func(from, to, protocol string) error
+
+// protocolRPC is the protocol RPC clients reach other modules with
+const protocolRPC = "rpc"
+
+// SetModuleAccess sets the check that RPC clients must pass to call a server
+// started by another module of the program. It must be called before
+// RegisterAPIs.
+func (rb *RuntimeBindings) SetModuleAccess(check ModuleAccessCheck) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.moduleAccess = check
+}
+
+// CallerFile returns the absolute path of the file whose code made the
+// current call into Go, or "" if no file is on the call stack. It must be
+// called by a Go function called from a running script.
+func CallerFile(vm *goja.Runtime) string {
+	for _, frame := range vm.CaptureCallStack(0, nil) {
+		name := frame.SrcName()
+		// Skip native frames and code without a file, e.g. <stdin>
+		if name == "" || strings.HasPrefix(name, "<") {
+			continue
+		}
+		if abs, err := filepath.Abs(name); err == nil {
+			return abs
+		}
+		return name
+	}
+	return ""
+}
+
+// rpcServerKey normalizes an RPC address, so that a client dialing
+// localhost:9000 finds the server listening on :9000
+func rpcServerKey(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return address
+	}
+	switch host {
+	case "", "localhost", "127.0.0.1", "0.0.0.0", "::", "::1":
+		host = "local"
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// trackRPCServer records which file starts listening through an RPC server
+// object, so that clients of the address can be checked against its module
+func (rb *RuntimeBindings) trackRPCServer(server *goja.Object) {
+	vm := rb.engine.VM()
+	listen, ok := goja.AssertFunction(server.Get("listen"))
+	if !ok {
+		return
+	}
+	server.Set("listen", func(fc goja.FunctionCall) goja.Value {
+		if file := CallerFile(vm); file != "" {
+			rb.mu.Lock()
+			if rb.rpcServers == nil {
+				rb.rpcServers = make(map[string]string)
+			}
+			rb.rpcServers[rpcServerKey(fc.Argument(0).String())] = file
+			rb.mu.Unlock()
+		}
+		result, err := listen(fc.This, fc.Arguments...)
+		if err != nil {
+			panic(err)
+		}
+		return result
+	})
+}
+
+// checkRPCAccess returns an error if the calling module may not reach the
+// module whose server listens at address via RPC. Servers of other
+// processes are not subject to the check.
+func (rb *RuntimeBindings) checkRPCAccess(address string) error {
+	rb.mu.RLock()
+	check := rb.moduleAccess
+	server, ok := rb.rpcServers[rpcServerKey(address)]
+	rb.mu.RUnlock()
+	if check == nil || !ok {
+		return nil
+	}
+	return check(CallerFile(rb.engine.VM()), server, protocolRPC)
+}
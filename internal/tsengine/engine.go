@@ -23,6 +23,14 @@ func NewEngine() *Engine {
 	}
 }
 
+// NewEngineWithVM creates an engine around an existing goja runtime
+func NewEngineWithVM(vm *goja.Runtime) *Engine {
+	return &Engine{
+		vm:       vm,
+		compiler: NewCompiler(),
+	}
+}
+
 // ExecuteFile executes a TypeScript file
 func (e *Engine) ExecuteFile(filePath string) (goja.Value, error) {
 	// Compile TypeScript to JavaScript
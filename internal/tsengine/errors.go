@@ -0,0 +1,189 @@
+package tsengine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/security"
+)
+
+// Error codes surfaced to TypeScript
+const (
+	ErrCodePermission  = "EPERM"
+	ErrCodeAccess      = "EACCES"
+	ErrCodeNotFound    = "ENOENT"
+	ErrCodeExists      = "EEXIST"
+	ErrCodeNotDir      = "ENOTDIR"
+	ErrCodeIsDir       = "EISDIR"
+	ErrCodeTimeout     = "ETIMEDOUT"
+	ErrCodeConnRefused = "ECONNREFUSED"
+	ErrCodeConnReset   = "ECONNRESET"
+	ErrCodeAddrInUse   = "EADDRINUSE"
+	ErrCodeOverloaded  = "EOVERLOADED"
+	ErrCodeQuota       = "EQUOTA"
+	ErrCodeUnknown     = "EUNKNOWN"
+)
+
+// ErrorCode maps a Go error to a TypeScript error code
+func ErrorCode(err error) string {
+	var permErr *security.PermissionError
+	var quotaErr *security.QuotaError
+	var loopErr *eventloop.EventLoopError
+	var netErr net.Error
+
+	switch {
+	case errors.As(err, &permErr):
+		return ErrCodePermission
+	case errors.As(err, &quotaErr):
+		return ErrCodeQuota
+	case errors.Is(err, eventloop.ErrQueueOverloaded), errors.As(err, &loopErr):
+		return ErrCodeOverloaded
+	case errors.Is(err, fs.ErrNotExist):
+		return ErrCodeNotFound
+	case errors.Is(err, fs.ErrExist):
+		return ErrCodeExists
+	case errors.Is(err, fs.ErrPermission):
+		return ErrCodeAccess
+	case errors.Is(err, syscall.ENOTDIR):
+		return ErrCodeNotDir
+	case errors.Is(err, syscall.EISDIR):
+		return ErrCodeIsDir
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ErrCodeConnRefused
+	case errors.Is(err, syscall.ECONNRESET):
+		return ErrCodeConnReset
+	case errors.Is(err, syscall.EADDRINUSE):
+		return ErrCodeAddrInUse
+	case errors.Is(err, os.ErrDeadlineExceeded):
+		return ErrCodeTimeout
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return ErrCodeTimeout
+	default:
+		return ErrCodeUnknown
+	}
+}
+
+// NewJSError creates a JavaScript Error object carrying code, message and
+// cause properties for a Go error
+func NewJSError(vm *goja.Runtime, err error) *goja.Object {
+	errObj, ctorErr := vm.New(vm.Get("Error"), vm.ToValue(err.Error()))
+	if ctorErr != nil {
+		errObj = vm.NewObject()
+		errObj.Set("message", err.Error())
+	}
+	errObj.Set("code", ErrorCode(err))
+
+	// Permission denials carry the denied permission and module so that
+	// callers can show which permission is missing
+	var permErr *security.PermissionError
+	if errors.As(err, &permErr) {
+		errObj.Set("permission", string(permErr.Permission))
+		errObj.Set("module", permErr.ModuleID)
+		if permErr.Target != "" {
+			errObj.Set("target", permErr.Target)
+		}
+	}
+
+	// Quota rejections carry the quota and when to retry
+	var quotaErr *security.QuotaError
+	if errors.As(err, &quotaErr) {
+		errObj.Set("module", quotaErr.ModuleID)
+		errObj.Set("category", string(quotaErr.Category))
+		errObj.Set("limit", quotaErr.Quota.Limit)
+		errObj.Set("windowMs", quotaErr.Quota.Window.Milliseconds())
+		errObj.Set("retryAfterMs", quotaErr.RetryAfter.Milliseconds())
+	}
+
+	if cause := errors.Unwrap(err); cause != nil {
+		errObj.Set("cause", cause.Error())
+	} else {
+		errObj.Set("cause", goja.Undefined())
+	}
+
+	return errObj
+}
+
+// jsError converts a Go error to a structured JavaScript error
+func (rb *RuntimeBindings) jsError(err error) *goja.Object {
+	return NewJSError(rb.engine.VM(), err)
+}
+
+// PermissionDenial extracts permission denial details from an error returned
+// by script execution, including structured errors thrown in TypeScript
+func PermissionDenial(err error) (*security.PermissionError, bool) {
+	var permErr *security.PermissionError
+	if errors.As(err, &permErr) {
+		return permErr, true
+	}
+
+	var exception *goja.Exception
+	if !errors.As(err, &exception) {
+		return nil, false
+	}
+	errObj, ok := exception.Value().(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	if code := errObj.Get("code"); code == nil || code.String() != ErrCodePermission {
+		return nil, false
+	}
+
+	denial := &security.PermissionError{Message: "permission denied"}
+	if permission := errObj.Get("permission"); permission != nil && !goja.IsUndefined(permission) {
+		denial.Permission = security.Permission(permission.String())
+	}
+	if module := errObj.Get("module"); module != nil && !goja.IsUndefined(module) {
+		denial.ModuleID = module.String()
+	}
+	if target := errObj.Get("target"); target != nil && !goja.IsUndefined(target) {
+		denial.Target = target.String()
+	}
+	return denial, true
+}
+
+// PermissionHint returns an actionable message describing how to grant a
+// denied permission in gots.json
+func PermissionHint(denial *security.PermissionError) string {
+	if denial.Permission == "" {
+		return "a permission was denied; check the permissions in gots.json"
+	}
+	module := denial.ModuleID
+	if module == "" {
+		module = "main"
+	}
+	hint := fmt.Sprintf("module %q is missing permission %q; add it to gots.json:\n"+
+		"  \"permissions\": [{ \"module\": %q, \"permissions\": [%q] }]",
+		module, denial.Permission, module, string(denial.Permission))
+	if flag := permissionFlag(denial.Permission); flag != "" {
+		if denial.Target != "" && flag != "--allow-env" {
+			flag += "=" + denial.Target
+		}
+		hint += fmt.Sprintf("\nor run with %s", flag)
+	}
+	return hint
+}
+
+// permissionFlag returns the gots run flag that grants a permission
+func permissionFlag(permission security.Permission) string {
+	switch permission {
+	case security.PermissionFSRead:
+		return "--allow-read"
+	case security.PermissionFSWrite:
+		return "--allow-write"
+	case security.PermissionNetDial, security.PermissionNetListen:
+		return "--allow-net"
+	case security.PermissionEnvRead, security.PermissionEnvWrite:
+		return "--allow-env"
+	case security.PermissionAll:
+		return "--allow-all"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,284 @@
+package tsengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/federation"
+	"gots-runtime/internal/security"
+
+	"github.com/dop251/goja"
+)
+
+// defaultFederationTimeout bounds federation.send when no timeout is given
+const defaultFederationTimeout = 30 * time.Second
+
+// federationReply is the outcome of a TypeScript message handler
+type federationReply struct {
+	value interface{}
+	err   error
+}
+
+// registerFederation registers the federation API. The local node ID is
+// derived from the host name and process ID.
+func (rb *RuntimeBindings) registerFederation() error {
+	vm := rb.engine.VM()
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	fed := federation.NewFederation(fmt.Sprintf("%s-%d", hostname, os.Getpid()), context.Background())
+	listening := false
+
+	fedObj := vm.NewObject()
+	fedObj.Set("id", fed.ID())
+
+	// listen(address) accepts messages from other nodes and returns the
+	// bound address; the federation keeps the runtime alive until stop()
+	fedObj.Set("listen", func(address string) string {
+		if err := rb.permManager.CheckNetPermission(rb.moduleID, security.PermissionNetListen, address); err != nil {
+			panic(rb.jsError(err))
+		}
+		if listening {
+			panic(rb.jsError(fmt.Errorf("federation is already listening on %s", fed.Addr())))
+		}
+		if err := fed.Listen(address); err != nil {
+			panic(rb.jsError(err))
+		}
+		listening = true
+		rb.eventLoop.Ref()
+		return fed.Addr().String()
+	})
+
+	fedObj.Set("stop", func() {
+		if err := fed.Stop(); err != nil {
+			panic(rb.jsError(err))
+		}
+		if listening {
+			listening = false
+			rb.eventLoop.Unref()
+		}
+	})
+
+	fedObj.Set("registerNode", func(id, address string) {
+		fed.RegisterNode(federation.NewRuntimeNode(id, address))
+	})
+
+	fedObj.Set("unregisterNode", func(id string) {
+		fed.UnregisterNode(id)
+	})
+
+	// onMessage(type, handler) handles messages of a type. The handler is
+	// called on the event loop as handler(payload, message); its return
+	// value, or the value of a returned promise, is the reply to send().
+	fedObj.Set("onMessage", func(msgType string, handler goja.Value) {
+		handlerFunc, ok := goja.AssertFunction(handler)
+		if !ok {
+			panic(rb.jsError(fmt.Errorf("handler must be a function")))
+		}
+		fed.RegisterHandler(msgType, func(ctx context.Context, msg *federation.FederationMessage) (*federation.FederationMessage, error) {
+			replies := make(chan federationReply, 1)
+			err := rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				rb.callFederationHandler(handlerFunc, msg, replies)
+				return nil
+			}, eventloop.PriorityNormal))
+			if err != nil {
+				return nil, err
+			}
+
+			var reply federationReply
+			select {
+			case reply = <-replies:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			if reply.err != nil || reply.value == nil {
+				return nil, reply.err
+			}
+
+			payload, err := json.Marshal(reply.value)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal reply: %w", err)
+			}
+			return &federation.FederationMessage{
+				Type:      msg.Type,
+				From:      fed.ID(),
+				To:        msg.From,
+				Payload:   payload,
+				Timestamp: time.Now(),
+			}, nil
+		})
+	})
+
+	// send(nodeId, type, payload, options?) resolves with the reply payload,
+	// or undefined if the handler sent no reply
+	fedObj.Set("send", func(nodeID, msgType string, payload goja.Value, options goja.Value) *goja.Promise {
+		promise, resolve, reject := vm.NewPromise()
+		timeout := defaultFederationTimeout
+		if options != nil && !goja.IsUndefined(options) && !goja.IsNull(options) {
+			if v := options.ToObject(vm).Get("timeout"); v != nil && !goja.IsUndefined(v) {
+				timeout = time.Duration(v.ToInteger()) * time.Millisecond
+			}
+		}
+		data := exportValue(payload)
+
+		// Keep the runtime alive until the promise settles
+		rb.eventLoop.Ref()
+		go func() {
+			defer rb.eventLoop.Unref()
+
+			var reply *federation.FederationMessage
+			err := rb.checkFederationDial(fed, nodeID)
+			if err == nil {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				reply, err = fed.Request(ctx, nodeID, msgType, data)
+				cancel()
+			}
+
+			_ = rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				if err != nil {
+					reject(rb.jsError(err))
+					return nil
+				}
+				if reply == nil || len(reply.Payload) == 0 {
+					resolve(goja.Undefined())
+					return nil
+				}
+				var value interface{}
+				if err := json.Unmarshal(reply.Payload, &value); err != nil {
+					reject(rb.jsError(fmt.Errorf("failed to decode reply: %w", err)))
+					return nil
+				}
+				resolve(vm.ToValue(value))
+				return nil
+			}, eventloop.PriorityNormal))
+		}()
+
+		return promise
+	})
+
+	// broadcast(type, payload) sends to every healthy node without waiting
+	// for replies; the promise resolves once the messages are sent
+	fedObj.Set("broadcast", func(msgType string, payload goja.Value) *goja.Promise {
+		promise, resolve, reject := vm.NewPromise()
+		data := exportValue(payload)
+
+		// Keep the runtime alive until the promise settles
+		rb.eventLoop.Ref()
+		go func() {
+			defer rb.eventLoop.Unref()
+
+			var err error
+			for _, id := range fed.NodeIDs() {
+				if err = rb.checkFederationDial(fed, id); err != nil {
+					break
+				}
+			}
+			if err == nil {
+				err = fed.Broadcast(msgType, data)
+			}
+			_ = rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				if err != nil {
+					reject(rb.jsError(err))
+				} else {
+					resolve(goja.Undefined())
+				}
+				return nil
+			}, eventloop.PriorityNormal))
+		}()
+
+		return promise
+	})
+
+	// nodes() returns the registered nodes
+	fedObj.Set("nodes", func() []map[string]interface{} {
+		result := make([]map[string]interface{}, 0)
+		for _, id := range fed.NodeIDs() {
+			if stats, ok := fed.GetNodeStats(id); ok {
+				result = append(result, map[string]interface{}{
+					"id":       stats.ID,
+					"address":  stats.Address,
+					"healthy":  stats.Healthy,
+					"lastSeen": stats.LastSeen.UnixMilli(),
+				})
+			}
+		}
+		return result
+	})
+
+	rb.engine.Set("federation", fedObj)
+	return nil
+}
+
+// callFederationHandler calls a message handler and delivers its reply,
+// waiting for it if the handler returns a promise
+func (rb *RuntimeBindings) callFederationHandler(handler goja.Callable, msg *federation.FederationMessage, replies chan<- federationReply) {
+	vm := rb.engine.VM()
+
+	var payload interface{}
+	if len(msg.Payload) > 0 {
+		if err := json.Unmarshal(msg.Payload, &payload); err != nil {
+			replies <- federationReply{err: fmt.Errorf("failed to decode payload: %w", err)}
+			return
+		}
+	}
+	msgObj := vm.NewObject()
+	msgObj.Set("type", msg.Type)
+	msgObj.Set("from", msg.From)
+	msgObj.Set("to", msg.To)
+	msgObj.Set("timestamp", msg.Timestamp.UnixMilli())
+
+	result, err := handler(goja.Undefined(), vm.ToValue(payload), msgObj)
+	if err != nil {
+		// Send the thrown value without the local stack trace
+		if exception, ok := err.(*goja.Exception); ok {
+			err = fmt.Errorf("%s", exception.Value().String())
+		}
+		replies <- federationReply{err: err}
+		return
+	}
+
+	promise, ok := result.Export().(*goja.Promise)
+	if !ok {
+		replies <- federationReply{value: exportValue(result)}
+		return
+	}
+	switch promise.State() {
+	case goja.PromiseStateFulfilled:
+		replies <- federationReply{value: exportValue(promise.Result())}
+	case goja.PromiseStateRejected:
+		replies <- federationReply{err: fmt.Errorf("%s", promise.Result().String())}
+	default:
+		then, _ := goja.AssertFunction(result.ToObject(vm).Get("then"))
+		_, _ = then(result,
+			vm.ToValue(func(value goja.Value) {
+				replies <- federationReply{value: exportValue(value)}
+			}),
+			vm.ToValue(func(reason goja.Value) {
+				replies <- federationReply{err: fmt.Errorf("%s", reason.String())}
+			}))
+	}
+}
+
+// checkFederationDial checks that the module may connect to a registered
+// node
+func (rb *RuntimeBindings) checkFederationDial(fed *federation.Federation, nodeID string) error {
+	stats, ok := fed.GetNodeStats(nodeID)
+	if !ok {
+		return fmt.Errorf("node not found: %s", nodeID)
+	}
+	return rb.permManager.CheckNetPermission(rb.moduleID, security.PermissionNetDial, stats.Address)
+}
+
+// exportValue exports a JS value, mapping undefined and null to nil
+func exportValue(value goja.Value) interface{} {
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return nil
+	}
+	return value.Export()
+}
@@ -0,0 +1,199 @@
+package tsengine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+
+	"gots-runtime/internal/api"
+
+	"github.com/dop251/goja"
+)
+
+// createHTTPServer creates the object returned by http.createServer
+func (rb *RuntimeBindings) createHTTPServer(httpAPI *api.HTTP, handler goja.Value) *goja.Object {
+	vm := rb.engine.VM()
+	handlerFunc, _ := goja.AssertFunction(handler)
+	if handler != nil && !goja.IsUndefined(handler) && !goja.IsNull(handler) && handlerFunc == nil {
+		panic(rb.jsError(fmt.Errorf("handler must be a function")))
+	}
+
+	var server *api.Server
+	serverObj := vm.NewObject()
+
+	// listen(port, host?, callback?) binds the server. callback(undefined,
+	// port) is called with the bound port, which is the assigned one when
+	// port is 0, or callback(err) if binding or serving fails.
+	serverObj.Set("listen", func(call goja.FunctionCall) goja.Value {
+		port := call.Argument(0).ToInteger()
+		host := ""
+		callbackArg := call.Argument(1)
+		if _, ok := goja.AssertFunction(callbackArg); !ok && !goja.IsUndefined(callbackArg) {
+			host = callbackArg.String()
+			callbackArg = call.Argument(2)
+		}
+		callback, _ := goja.AssertFunction(callbackArg)
+		report := func(args ...goja.Value) {
+			if callback != nil {
+				_, _ = callback(goja.Undefined(), args...)
+			}
+		}
+
+		if server != nil {
+			report(rb.jsError(fmt.Errorf("server is already listening")))
+			return goja.Undefined()
+		}
+		server = httpAPI.NewServerWithOptions(net.JoinHostPort(host, strconv.FormatInt(port, 10)), rb.serverOptions)
+		server.Handle("/", func(req *api.Request) (*api.Response, error) {
+			return rb.serveHTTP(handlerFunc, req)
+		})
+
+		addr, err := server.Listen(func(err error) {
+			if err != nil && err != http.ErrServerClosed {
+				report(rb.jsError(err))
+			}
+		})
+		if err != nil {
+			server = nil
+			report(rb.jsError(err))
+			return goja.Undefined()
+		}
+		report(goja.Undefined(), vm.ToValue(addr.(*net.TCPAddr).Port))
+		return goja.Undefined()
+	})
+
+	// address() returns { address, port } once listening, otherwise null
+	serverObj.Set("address", func() interface{} {
+		if server == nil {
+			return nil
+		}
+		addr, ok := server.Addr().(*net.TCPAddr)
+		if !ok {
+			return nil
+		}
+		return map[string]interface{}{
+			"address": addr.IP.String(),
+			"port":    addr.Port,
+		}
+	})
+
+	// close(callback?) stops accepting connections and calls callback once
+	// in-flight requests have finished
+	serverObj.Set("close", func(callback goja.Value) {
+		callbackFunc, _ := goja.AssertFunction(callback)
+		done := func(err error) {
+			if callbackFunc == nil {
+				return
+			}
+			if err != nil {
+				_, _ = callbackFunc(goja.Undefined(), rb.jsError(err))
+			} else {
+				_, _ = callbackFunc(goja.Undefined())
+			}
+		}
+
+		if server == nil {
+			done(fmt.Errorf("server is not listening"))
+			return
+		}
+		server.Shutdown(context.Background(), done)
+	})
+
+	return serverObj
+}
+
+// serveHTTP calls a JavaScript handler as handler(req, res) and converts res
+// to the response. Without a handler every request gets a 404.
+func (rb *RuntimeBindings) serveHTTP(handler goja.Callable, req *api.Request) (*api.Response, error) {
+	vm := rb.engine.VM()
+	if handler == nil {
+		return &api.Response{Status: http.StatusNotFound, Body: []byte("Not Found")}, nil
+	}
+
+	reqObj := vm.NewObject()
+	reqObj.Set("method", req.Method)
+	reqObj.Set("url", req.URL)
+	reqObj.Set("path", req.URL)
+	reqObj.Set("headers", req.Headers)
+	reqObj.Set("body", string(req.Body))
+	reqObj.Set("query", req.Query)
+	reqObj.Set("params", req.Params)
+
+	resObj := rb.createHTTPResponse()
+	if _, err := handler(goja.Undefined(), reqObj, resObj); err != nil {
+		return nil, err
+	}
+
+	resp := &api.Response{
+		Status:  int(resObj.Get("status").ToInteger()),
+		Headers: make(map[string]string),
+	}
+	if headers, ok := resObj.Get("headers").(*goja.Object); ok {
+		for _, key := range headers.Keys() {
+			resp.Headers[key] = headers.Get(key).String()
+		}
+	}
+	if body := resObj.Get("body"); body != nil && !goja.IsUndefined(body) && !goja.IsNull(body) {
+		bytes, err := bytesOf(body, "")
+		if err != nil {
+			return nil, err
+		}
+		resp.Body = bytes
+	}
+	return resp, nil
+}
+
+// createHTTPResponse creates the res object passed to server handlers
+func (rb *RuntimeBindings) createHTTPResponse() *goja.Object {
+	vm := rb.engine.VM()
+	res := vm.NewObject()
+	headers := vm.NewObject()
+	res.Set("status", http.StatusOK)
+	res.Set("headers", headers)
+	res.Set("body", "")
+
+	res.Set("setStatus", func(code int) *goja.Object {
+		res.Set("status", code)
+		return res
+	})
+	res.Set("setHeader", func(name, value string) *goja.Object {
+		headers.Set(name, value)
+		return res
+	})
+	res.Set("text", func(text string) *goja.Object {
+		headers.Set("Content-Type", "text/plain; charset=utf-8")
+		res.Set("body", text)
+		return res
+	})
+	res.Set("html", func(html string) *goja.Object {
+		headers.Set("Content-Type", "text/html; charset=utf-8")
+		res.Set("body", html)
+		return res
+	})
+	res.Set("json", func(data goja.Value) *goja.Object {
+		encoded, err := json.Marshal(data.Export())
+		if err != nil {
+			panic(rb.jsError(fmt.Errorf("failed to encode JSON response: %w", err)))
+		}
+		headers.Set("Content-Type", "application/json")
+		res.Set("body", string(encoded))
+		return res
+	})
+	// send sends strings and buffers as they are and other values as JSON
+	res.Set("send", func(data goja.Value) {
+		if _, err := bytesOf(data, ""); err == nil {
+			res.Set("body", data)
+			return
+		}
+		encoded, err := json.Marshal(data.Export())
+		if err != nil {
+			panic(rb.jsError(fmt.Errorf("failed to encode response: %w", err)))
+		}
+		headers.Set("Content-Type", "application/json")
+		res.Set("body", string(encoded))
+	})
+	return res
+}
@@ -0,0 +1,80 @@
+package tsengine
+
+import (
+	"github.com/dop251/goja"
+)
+
+// MemoryTracker accounts approximate allocation sizes to modules
+type MemoryTracker interface {
+	// Track records size bytes for a module and returns a function that
+	// releases them
+	Track(moduleID string, size uint64) (release func())
+}
+
+// SetMemoryTracker sets the tracker that module code, worker tasks and
+// immutable structures report their approximate sizes to
+func (rb *RuntimeBindings) SetMemoryTracker(tracker MemoryTracker) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.memoryTracker = tracker
+}
+
+// trackAllocation accounts size bytes to the bindings' module and returns a
+// function that releases them
+func (rb *RuntimeBindings) trackAllocation(size uint64) func() {
+	rb.mu.RLock()
+	tracker := rb.memoryTracker
+	rb.mu.RUnlock()
+
+	if tracker == nil {
+		return func() {}
+	}
+	return tracker.Track(rb.moduleID, size)
+}
+
+// trackPromise accounts size bytes until promise settles
+func (rb *RuntimeBindings) trackPromise(promise *goja.Promise, size uint64) *goja.Promise {
+	vm := rb.engine.VM()
+	release := rb.trackAllocation(size)
+
+	obj := vm.ToValue(promise).ToObject(vm)
+	finally, ok := goja.AssertFunction(obj.Get("finally"))
+	if !ok {
+		release()
+		return promise
+	}
+	if _, err := finally(obj, vm.ToValue(release)); err != nil {
+		release()
+	}
+	return promise
+}
+
+// estimateSize returns the approximate size in bytes of an exported JS value
+func estimateSize(value interface{}) uint64 {
+	const wordSize = 8
+
+	switch v := value.(type) {
+	case nil:
+		return wordSize
+	case string:
+		return uint64(len(v)) + 2*wordSize
+	case []byte:
+		return uint64(len(v)) + 3*wordSize
+	case goja.ArrayBuffer:
+		return uint64(len(v.Bytes())) + 3*wordSize
+	case []interface{}:
+		size := uint64(3 * wordSize)
+		for _, item := range v {
+			size += estimateSize(item)
+		}
+		return size
+	case map[string]interface{}:
+		size := uint64(6 * wordSize)
+		for key, item := range v {
+			size += uint64(len(key)) + 2*wordSize + estimateSize(item)
+		}
+		return size
+	default:
+		return 2 * wordSize
+	}
+}
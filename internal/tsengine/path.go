@@ -0,0 +1,48 @@
+package tsengine
+
+import (
+	"gots-runtime/internal/api"
+)
+
+// registerPath registers the path API
+func (rb *RuntimeBindings) registerPath() error {
+	vm := rb.engine.VM()
+	pathObj := vm.NewObject()
+
+	pathObj.Set("sep", api.PathSeparator)
+
+	pathObj.Set("join", func(elem ...string) string {
+		return api.JoinPath(elem...)
+	})
+
+	pathObj.Set("resolve", func(elem ...string) string {
+		resolved, err := api.ResolvePath(elem...)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return resolved
+	})
+
+	pathObj.Set("dirname", func(path string) string {
+		return api.DirnamePath(path)
+	})
+
+	pathObj.Set("basename", func(path string, ext string) string {
+		return api.BasenamePath(path, ext)
+	})
+
+	pathObj.Set("extname", func(path string) string {
+		return api.ExtnamePath(path)
+	})
+
+	pathObj.Set("relative", func(from, to string) string {
+		rel, err := api.RelativePath(from, to)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return rel
+	})
+
+	rb.engine.Set("path", pathObj)
+	return nil
+}
@@ -0,0 +1,109 @@
+package tsengine
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/api"
+)
+
+// registerProcess registers the process global
+func (rb *RuntimeBindings) registerProcess() error {
+	vm := rb.engine.VM()
+	processObj := vm.NewObject()
+
+	rb.mu.RLock()
+	argv := make([]interface{}, len(rb.argv))
+	for i, arg := range rb.argv {
+		argv[i] = arg
+	}
+	rb.mu.RUnlock()
+
+	processObj.Set("argv", vm.NewArray(argv...))
+	processObj.Set("env", vm.NewDynamicObject(&processEnv{
+		rb:  rb,
+		env: api.NewSecureEnv(rb.permManager, rb.moduleID),
+	}))
+	processObj.Set("pid", os.Getpid())
+	processObj.Set("platform", runtime.GOOS)
+
+	processObj.Set("cwd", func() string {
+		dir, err := os.Getwd()
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return filepath.ToSlash(dir)
+	})
+
+	processObj.Set("exit", func(code goja.Value) {
+		status := 0
+		if code != nil && !goja.IsUndefined(code) {
+			status = int(code.ToInteger())
+		}
+
+		rb.mu.RLock()
+		exit := rb.exitHandler
+		rb.mu.RUnlock()
+		exit(status)
+	})
+
+	rb.engine.Set("process", processObj)
+	return nil
+}
+
+// processEnv exposes environment variables as process.env. Every access is
+// checked against the module's env permissions.
+type processEnv struct {
+	rb  *RuntimeBindings
+	env *api.SecureEnv
+}
+
+func (pe *processEnv) Get(key string) goja.Value {
+	value, ok, err := pe.env.LookupEnv(key)
+	if err != nil {
+		panic(pe.rb.jsError(err))
+	}
+	if !ok {
+		return goja.Undefined()
+	}
+	return pe.rb.engine.VM().ToValue(value)
+}
+
+func (pe *processEnv) Set(key string, value goja.Value) bool {
+	if err := pe.env.Set(key, value.String()); err != nil {
+		panic(pe.rb.jsError(err))
+	}
+	return true
+}
+
+func (pe *processEnv) Has(key string) bool {
+	_, ok, err := pe.env.LookupEnv(key)
+	if err != nil {
+		panic(pe.rb.jsError(err))
+	}
+	return ok
+}
+
+func (pe *processEnv) Delete(key string) bool {
+	if err := pe.env.Unset(key); err != nil {
+		panic(pe.rb.jsError(err))
+	}
+	return true
+}
+
+func (pe *processEnv) Keys() []string {
+	all, err := pe.env.GetAll()
+	if err != nil {
+		panic(pe.rb.jsError(err))
+	}
+	keys := make([]string, 0, len(all))
+	for key := range all {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,50 @@
+package tsengine
+
+import (
+	"gots-runtime/internal/security"
+
+	"github.com/dop251/goja"
+)
+
+// takeQuota counts an operation of category against the module's quota
+func (rb *RuntimeBindings) takeQuota(category security.QuotaCategory) error {
+	if rb.permManager == nil {
+		return nil
+	}
+	return rb.permManager.TakeQuota(rb.moduleID, category)
+}
+
+// guardRPCCalls counts the calls made through an RPC client object against
+// the module's rpc quota. Calls over quota are rejected, or for streams
+// thrown, with an EQUOTA error.
+func (rb *RuntimeBindings) guardRPCCalls(client *goja.Object) {
+	vm := rb.engine.VM()
+
+	if call, ok := goja.AssertFunction(client.Get("call")); ok {
+		client.Set("call", func(fc goja.FunctionCall) goja.Value {
+			if err := rb.takeQuota(security.QuotaRPC); err != nil {
+				promise, _, reject := vm.NewPromise()
+				reject(rb.jsError(err))
+				return vm.ToValue(promise)
+			}
+			result, err := call(fc.This, fc.Arguments...)
+			if err != nil {
+				panic(err)
+			}
+			return result
+		})
+	}
+
+	if callStream, ok := goja.AssertFunction(client.Get("callStream")); ok {
+		client.Set("callStream", func(fc goja.FunctionCall) goja.Value {
+			if err := rb.takeQuota(security.QuotaRPC); err != nil {
+				panic(rb.jsError(err))
+			}
+			result, err := callStream(fc.This, fc.Arguments...)
+			if err != nil {
+				panic(err)
+			}
+			return result
+		})
+	}
+}
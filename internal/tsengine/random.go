@@ -0,0 +1,47 @@
+package tsengine
+
+import (
+	"math/rand"
+	"time"
+)
+
+// SetRandomSeed seeds Math.random, so that it produces the same sequence
+// on every run. It must be called before RegisterAPIs.
+func (rb *RuntimeBindings) SetRandomSeed(seed int64) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.randomSeed = &seed
+}
+
+// registerRandom routes Math.random through a seeded source. Without a
+// seed set with SetRandomSeed the seed is time-based; it is recorded
+// through the I/O log so that replays produce the recorded sequence.
+func (rb *RuntimeBindings) registerRandom() error {
+	rb.mu.RLock()
+	configured := rb.randomSeed
+	rb.mu.RUnlock()
+
+	var seed int64
+	err := rb.ioSync("random.seed", nil, &seed, func() error {
+		if configured != nil {
+			seed = *configured
+		} else {
+			seed = time.Now().UnixNano()
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	rb.seedRandom(seed)
+	return nil
+}
+
+// seedRandom restarts the sequence of Math.random, which async.retry also
+// draws its jitter from, from seed
+func (rb *RuntimeBindings) seedRandom(seed int64) {
+	rng := rand.New(rand.NewSource(seed))
+	rb.random = rng.Float64
+	rb.engine.VM().SetRandSource(rng.Float64)
+}
@@ -0,0 +1,71 @@
+package tsengine
+
+import (
+	"gots-runtime/internal/eventloop"
+	"gots-runtime/internal/replay"
+)
+
+// SetIOLog records the outcomes of fs and env operations into log, or,
+// when log is replaying, serves the recorded outcomes instead of performing
+// the operations. It must be called before RegisterAPIs.
+func (rb *RuntimeBindings) SetIOLog(log *replay.IOLog) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.ioLog = log
+}
+
+// ioSync runs a synchronous I/O operation through the I/O log. op stores
+// its result in value, a pointer; when replaying, the recorded result is
+// decoded into value and op is not run.
+func (rb *RuntimeBindings) ioSync(eventType string, data interface{}, value interface{}, op func() error) error {
+	if rb.ioLog == nil {
+		return op()
+	}
+
+	event, err := rb.ioLog.Begin(eventType, data)
+	if err != nil {
+		return err
+	}
+	if rb.ioLog.Replaying() {
+		return rb.ioLog.Outcome(event, value)
+	}
+
+	opErr := op()
+	if err := rb.ioLog.Finish(event, value, opErr); err != nil {
+		return err
+	}
+	return opErr
+}
+
+// ioAsync runs an asynchronous I/O operation through the I/O log. op
+// starts the operation and calls done on the event loop once it has stored
+// its result in value; deliver is then called with the operation's error.
+// When replaying, op is not run and deliver is called from the event loop
+// with the recorded result decoded into value.
+func (rb *RuntimeBindings) ioAsync(eventType string, data interface{}, value interface{}, op func(done func(error)), deliver func(error)) {
+	if rb.ioLog == nil {
+		op(deliver)
+		return
+	}
+
+	event, err := rb.ioLog.Begin(eventType, data)
+	if err == nil && !rb.ioLog.Replaying() {
+		op(func(opErr error) {
+			if err := rb.ioLog.Finish(event, value, opErr); err != nil {
+				deliver(err)
+				return
+			}
+			deliver(opErr)
+		})
+		return
+	}
+
+	rb.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+		if err != nil {
+			deliver(err)
+		} else {
+			deliver(rb.ioLog.Outcome(event, value))
+		}
+		return nil
+	}, eventloop.PriorityNormal))
+}
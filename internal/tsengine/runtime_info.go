@@ -0,0 +1,137 @@
+package tsengine
+
+import (
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/observability"
+	"gots-runtime/internal/worker"
+)
+
+// ModuleMemoryUsage is the memory accounted to a module, in bytes
+type ModuleMemoryUsage struct {
+	Allocated uint64
+	Max       uint64
+}
+
+// MemoryReporter returns the memory usage of each tracked module
+type MemoryReporter func() map[string]ModuleMemoryUsage
+
+// OrchestratorStats describes the orchestrator that runs the module and
+// its scheduler
+type OrchestratorStats struct {
+	State     string
+	Scheduler *worker.Stats // the scheduler's worker pool, nil if it has none
+}
+
+// OrchestratorReporter returns the current state of the orchestrator
+type OrchestratorReporter func() OrchestratorStats
+
+// SetOrchestratorReporter sets where runtime.stats() reads orchestrator
+// and scheduler state from; without one they are reported as null
+func (rb *RuntimeBindings) SetOrchestratorReporter(reporter OrchestratorReporter) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.orchestrator = reporter
+}
+
+// runtimeStats creates the result of runtime.stats()
+func (rb *RuntimeBindings) runtimeStats() *goja.Object {
+	vm := rb.engine.VM()
+	stats := vm.NewObject()
+
+	rb.mu.RLock()
+	reporter := rb.orchestrator
+	rb.mu.RUnlock()
+
+	stats.Set("orchestrator", goja.Null())
+	stats.Set("scheduler", goja.Null())
+	if reporter != nil {
+		orch := reporter()
+		stats.Set("orchestrator", orch.State)
+		if sched := orch.Scheduler; sched != nil {
+			scheduler := vm.NewObject()
+			scheduler.Set("workers", sched.CurrentWorkers)
+			scheduler.Set("busyWorkers", sched.BusyWorkers)
+			scheduler.Set("minWorkers", sched.MinWorkers)
+			scheduler.Set("maxWorkers", sched.MaxWorkers)
+			scheduler.Set("queuedTasks", sched.QueueSize)
+			scheduler.Set("queueHighWater", sched.QueueHighWater)
+			stats.Set("scheduler", scheduler)
+		}
+	}
+
+	loopStats := rb.eventLoop.Stats()
+	loop := vm.NewObject()
+	loop.Set("queued", loopStats.Queue.Length)
+	loop.Set("capacity", loopStats.Queue.Capacity)
+	loop.Set("highWater", loopStats.Queue.HighWater)
+	loop.Set("dropped", loopStats.Queue.Dropped)
+	loop.Set("rejected", loopStats.Queue.Rejected)
+	loop.Set("policy", string(loopStats.Queue.Policy))
+	loop.Set("timers", loopStats.Timers)
+	loop.Set("nextTicks", loopStats.NextTicks)
+	loop.Set("refs", loopStats.Refs)
+	loop.Set("busy", loopStats.Busy)
+	loop.Set("stalls", loopStats.Stalls)
+	stats.Set("eventLoop", loop)
+
+	totals := rb.workerStats()
+	workers := vm.NewObject()
+	for _, key := range []string{"pools", "totalWorkers", "busyWorkers", "idleWorkers", "queuedTasks", "crashedTasks"} {
+		workers.Set(key, totals[key])
+	}
+	stats.Set("workers", workers)
+	return stats
+}
+
+// registerRuntime registers the runtime global
+func (rb *RuntimeBindings) registerRuntime() error {
+	vm := rb.engine.VM()
+	runtimeObj := vm.NewObject()
+	profiler := observability.NewProfiler()
+
+	// memoryUsage() returns process memory stats and the per-module
+	// accounting of the memory isolation manager
+	runtimeObj.Set("memoryUsage", func() *goja.Object {
+		snapshot := profiler.TakeSnapshot()
+
+		rb.mu.RLock()
+		reporter := rb.memoryReporter
+		rb.mu.RUnlock()
+
+		modules := vm.NewObject()
+		if reporter != nil {
+			for id, usage := range reporter() {
+				module := vm.NewObject()
+				module.Set("allocated", usage.Allocated)
+				module.Set("max", usage.Max)
+				modules.Set(id, module)
+			}
+		}
+
+		usage := vm.NewObject()
+		usage.Set("heapAlloc", snapshot.HeapAlloc)
+		usage.Set("heapSys", snapshot.HeapSys)
+		usage.Set("goroutines", snapshot.GoroutineCount)
+		usage.Set("modules", modules)
+		return usage
+	})
+
+	// stats() reports orchestrator state, scheduler queue depth, event loop
+	// backlog and worker pool usage
+	runtimeObj.Set("stats", rb.runtimeStats)
+
+	// config() and onConfigChange(cb) expose the project configuration
+	rb.registerConfig(runtimeObj)
+
+	// seedRandom(n) restarts Math.random from seed n
+	runtimeObj.Set("seedRandom", func(seed int64) {
+		rb.seedRandom(seed)
+	})
+
+	// clock controls the time seen by Date and the timers
+	runtimeObj.Set("clock", rb.createClockObject())
+
+	rb.engine.Set("runtime", runtimeObj)
+	return nil
+}
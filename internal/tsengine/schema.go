@@ -0,0 +1,175 @@
+package tsengine
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dop251/goja"
+
+	fwruntime "gots-runtime/framework/runtime"
+)
+
+// schemaKinds maps the type names used in request schemas to type kinds
+var schemaKinds = map[string]TypeKind{
+	"string":  TypeString,
+	"number":  TypeNumber,
+	"boolean": TypeBoolean,
+	"object":  TypeObject,
+	"array":   TypeArray,
+	"any":     TypeAny,
+	"null":    TypeNull,
+}
+
+// ParseSchemaType converts a schema written in JavaScript into type
+// information. A schema is a type name such as "string" or "number",
+// optionally suffixed with "?" to mark an optional property, or an object
+// whose values are the schemas of its properties:
+//
+//	{ name: "string", age: "number?", address: { city: "string" } }
+func ParseSchemaType(schema interface{}) (*TypeInfo, error) {
+	switch s := schema.(type) {
+	case string:
+		name := strings.TrimSuffix(s, "?")
+		kind, ok := schemaKinds[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown schema type: %s", s)
+		}
+		return &TypeInfo{Name: name, Kind: kind, IsOptional: name != s}, nil
+	case map[string]interface{}:
+		info := &TypeInfo{Name: "object", Kind: TypeObject, Properties: make(map[string]*TypeInfo)}
+		for key, propSchema := range s {
+			prop, err := ParseSchemaType(propSchema)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", key, err)
+			}
+			info.Properties[key] = prop
+		}
+		return info, nil
+	default:
+		return nil, fmt.Errorf("invalid schema: expected a type name or object, got %T", schema)
+	}
+}
+
+// SchemaValidator validates request values against type information with a
+// TypeValidator, reporting every invalid field rather than the first
+type SchemaValidator struct {
+	validator *TypeValidator
+	typeInfo  *TypeInfo
+	coerce    bool
+}
+
+// NewSchemaValidator creates a validator for typeInfo. With coerce set,
+// string values are converted to numbers and booleans where the schema
+// expects them, as needed for query and route parameters.
+func NewSchemaValidator(validator *TypeValidator, typeInfo *TypeInfo, coerce bool) *SchemaValidator {
+	return &SchemaValidator{
+		validator: validator,
+		typeInfo:  typeInfo,
+		coerce:    coerce,
+	}
+}
+
+// Validate returns value, with any coerced fields converted, and an error
+// for each invalid field. Field names are dotted property paths.
+func (sv *SchemaValidator) Validate(value interface{}) (interface{}, []fwruntime.FieldError) {
+	var errs []fwruntime.FieldError
+	result := sv.check(value, sv.typeInfo, "", &errs)
+	return result, errs
+}
+
+// check validates value against typeInfo at path, appending to errs
+func (sv *SchemaValidator) check(value interface{}, typeInfo *TypeInfo, path string, errs *[]fwruntime.FieldError) interface{} {
+	if typeInfo.Kind == TypeObject && typeInfo.Properties != nil {
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			*errs = append(*errs, fwruntime.FieldError{Field: path, Message: fmt.Sprintf("expected object, got %T", value)})
+			return value
+		}
+
+		keys := make([]string, 0, len(typeInfo.Properties))
+		for key := range typeInfo.Properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		result := make(map[string]interface{}, len(obj))
+		for key, val := range obj {
+			result[key] = val
+		}
+		for _, key := range keys {
+			propType := typeInfo.Properties[key]
+			propPath := key
+			if path != "" {
+				propPath = path + "." + key
+			}
+
+			val, present := obj[key]
+			if !present {
+				if !propType.IsOptional {
+					*errs = append(*errs, fwruntime.FieldError{Field: propPath, Message: "is required"})
+				}
+				continue
+			}
+			result[key] = sv.check(val, propType, propPath, errs)
+		}
+		return result
+	}
+
+	if str, ok := value.(string); ok && sv.coerce {
+		value = coerceString(str, typeInfo.Kind)
+	}
+	if err := sv.validator.Validate(value, typeInfo); err != nil {
+		*errs = append(*errs, fwruntime.FieldError{Field: path, Message: err.Error()})
+	}
+	return value
+}
+
+// coerceString converts s to the given kind if it is a number or boolean,
+// returning s unchanged if it does not parse
+func coerceString(s string, kind TypeKind) interface{} {
+	switch kind {
+	case TypeNumber:
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			return n
+		}
+	case TypeBoolean:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+// CompileRouteSchema converts a route schema written in JavaScript, an
+// object with optional body, query and params schemas, into a
+// framework schema
+func CompileRouteSchema(schema goja.Value) (fwruntime.Schema, error) {
+	var compiled fwruntime.Schema
+	parts, ok := schema.Export().(map[string]interface{})
+	if !ok {
+		return compiled, fmt.Errorf("route schema must be an object")
+	}
+
+	validator := NewTypeValidator()
+	for part, partSchema := range parts {
+		typeInfo, err := ParseSchemaType(partSchema)
+		if err != nil {
+			return compiled, fmt.Errorf("%s schema: %w", part, err)
+		}
+
+		switch part {
+		case "body":
+			compiled.Body = NewSchemaValidator(validator, typeInfo, false)
+		case "query":
+			compiled.Query = NewSchemaValidator(validator, typeInfo, true)
+		case "params":
+			compiled.Params = NewSchemaValidator(validator, typeInfo, true)
+		default:
+			return compiled, fmt.Errorf("unknown route schema part: %s", part)
+		}
+	}
+
+	return compiled, nil
+}
@@ -2,9 +2,13 @@ package tsengine
 
 import (
 	"fmt"
+	"io/fs"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
+
+	"gots-runtime/stdlib"
 )
 
 // StdlibLoader loads and registers standard library modules
@@ -23,33 +27,30 @@ func NewStdlibLoader(engine *Engine) *StdlibLoader {
 
 // Load loads all standard library modules
 func (sl *StdlibLoader) Load() error {
-	stdlibPath, err := resolveStdlibPath()
-	if err != nil {
-		return err
-	}
+	stdlibFS, _ := resolveStdlibFS()
 
-	// Walk the stdlib directory
-	err = filepath.Walk(stdlibPath, func(path string, info os.FileInfo, err error) error {
+	// Walk the stdlib tree
+	err := fs.WalkDir(stdlibFS, ".", func(path string, entry fs.DirEntry, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if info.IsDir() {
+		if entry.IsDir() {
 			return nil
 		}
 
-		// Only load .ts files
-		if !strings.HasSuffix(path, ".ts") {
+		// Only load TypeScript files
+		if !strings.HasSuffix(path, ".ts") && !strings.HasSuffix(path, ".tsx") {
 			return nil
 		}
 
 		// Read the file
-		data, err := os.ReadFile(path)
+		data, err := fs.ReadFile(stdlibFS, path)
 		if err != nil {
 			return fmt.Errorf("failed to read stdlib file %s: %w", path, err)
 		}
 
-		// Convert path to module name (e.g., stdlib/fs/index.ts -> gots/stdlib/fs)
+		// Convert path to module name (e.g., fs/index.ts -> gots/stdlib/fs)
 		modulePath := sl.pathToModulePath(path)
 		sl.modules[modulePath] = string(data)
 
@@ -57,21 +58,17 @@ func (sl *StdlibLoader) Load() error {
 	})
 
 	if err != nil {
-		return fmt.Errorf("failed to walk stdlib directory: %w", err)
+		return fmt.Errorf("failed to walk stdlib: %w", err)
 	}
 
 	return nil
 }
 
-// pathToModulePath converts a file system path to a module import path
+// pathToModulePath converts a path in the stdlib tree to a module import
+// path
 func (sl *StdlibLoader) pathToModulePath(path string) string {
-	// Normalize path separators
-	path = filepath.ToSlash(path)
-
-	// Remove stdlib/ prefix and .ts extension
-	path = strings.TrimPrefix(path, "stdlib/")
-	path = strings.TrimPrefix(path, "../../stdlib/")
-	path = strings.TrimSuffix(path, ".ts")
+	// Remove .ts or .tsx extension
+	path = strings.TrimSuffix(strings.TrimSuffix(path, ".tsx"), ".ts")
 
 	// Handle index.ts files
 	if strings.HasSuffix(path, "/index") {
@@ -131,6 +128,7 @@ func (sl *StdlibLoader) GetModuleCode(modulePath string) (string, bool) {
 }
 
 // ResolveStdlib resolves a stdlib import path to the actual module path
+// within the stdlib tree
 func ResolveStdlib(importPath string) (string, error) {
 	// Handle gots/stdlib/* imports
 	if !strings.HasPrefix(importPath, "gots/stdlib/") {
@@ -140,88 +138,51 @@ func ResolveStdlib(importPath string) (string, error) {
 	// Extract module name (e.g., gots/stdlib/fs -> fs)
 	moduleName := strings.TrimPrefix(importPath, "gots/stdlib/")
 
-	// Try to find the module file
-	// First try index.ts
-	possiblePaths := []string{
-		fmt.Sprintf("stdlib/%s/index.ts", moduleName),
-		fmt.Sprintf("stdlib/%s.ts", moduleName),
-		filepath.Join("stdlib", moduleName, "index.ts"),
-		filepath.Join("stdlib", moduleName+".ts"),
+	stdlibFS, root := resolveStdlibFS()
+	if modulePath, ok := findStdlibModule(stdlibFS, moduleName); ok {
+		return modulePath, nil
 	}
 
-	for _, path := range possiblePaths {
-		// Check if file exists
-		if _, err := os.Stat(path); err == nil {
-			return path, nil
-		}
-	}
-
-	return "", fmt.Errorf("stdlib module not found: %s", importPath)
+	return "", fmt.Errorf("stdlib module not found: %s (root: %s)", importPath, root)
 }
 
-// GetStdlibPath returns the file system path for a stdlib module
+// GetStdlibPath returns the path of a stdlib module: a file system path
+// when GOTS_STDLIB_PATH is set, otherwise its path in the embedded stdlib
 func GetStdlibPath(modulePath string) (string, error) {
-	// Convert gots/stdlib/fs to ../../stdlib/fs/index.ts or ../../stdlib/fs.ts
-	if !strings.HasPrefix(modulePath, "gots/stdlib/") {
-		return "", fmt.Errorf("not a stdlib import: %s", modulePath)
-	}
-
-	moduleName := strings.TrimPrefix(modulePath, "gots/stdlib/")
-
-	stdlibRoot, err := resolveStdlibPath()
+	resolved, err := ResolveStdlib(modulePath)
 	if err != nil {
 		return "", err
 	}
 
-	// Try index.ts first
-	indexPath := filepath.Join(stdlibRoot, moduleName, "index.ts")
-	if _, err := os.Stat(indexPath); err == nil {
-		return indexPath, nil
+	if _, root := resolveStdlibFS(); root != embeddedStdlibRoot {
+		return filepath.Join(root, filepath.FromSlash(resolved)), nil
 	}
+	return resolved, nil
+}
 
-	// Try direct .ts file
-	tsPath := filepath.Join(stdlibRoot, moduleName+".ts")
-	if _, err := os.Stat(tsPath); err == nil {
-		return tsPath, nil
+// findStdlibModule finds the declaration file of a module in the stdlib
+// tree, trying index.ts first
+func findStdlibModule(stdlibFS fs.FS, moduleName string) (string, bool) {
+	for _, candidate := range []string{path.Join(moduleName, "index.ts"), moduleName + ".ts"} {
+		if _, err := fs.Stat(stdlibFS, candidate); err == nil {
+			return candidate, true
+		}
 	}
-
-	return "", fmt.Errorf("stdlib module not found: %s (root: %s)", modulePath, stdlibRoot)
+	return "", false
 }
 
-// resolveStdlibPath determines the stdlib directory location.
-// Priority:
-// 1) GOTS_STDLIB_PATH environment variable, if set and exists
-// 2) Directory named "stdlib" next to the running executable
-// 3) "stdlib" in current working directory (for development)
-// 4) "../../stdlib" (legacy/dev fallback)
-func resolveStdlibPath() (string, error) {
-	// 1) Environment override
+// embeddedStdlibRoot names the stdlib embedded in the binary in paths and
+// error messages
+const embeddedStdlibRoot = "<embedded>"
+
+// resolveStdlibFS returns the stdlib tree and where it comes from. The
+// stdlib embedded in the binary is used unless GOTS_STDLIB_PATH names a
+// directory, which lets the stdlib be developed without rebuilding.
+func resolveStdlibFS() (fs.FS, string) {
 	if envPath := os.Getenv("GOTS_STDLIB_PATH"); envPath != "" {
 		if info, err := os.Stat(envPath); err == nil && info.IsDir() {
-			return envPath, nil
-		}
-	}
-
-	// 2) Next to the executable
-	if exePath, err := os.Executable(); err == nil {
-		exeDir := filepath.Dir(exePath)
-		exeStdlib := filepath.Join(exeDir, "stdlib")
-		if info, err := os.Stat(exeStdlib); err == nil && info.IsDir() {
-			return exeStdlib, nil
+			return os.DirFS(envPath), envPath
 		}
 	}
-
-	// 3) Current working directory
-	cwdStdlib := "stdlib"
-	if info, err := os.Stat(cwdStdlib); err == nil && info.IsDir() {
-		return cwdStdlib, nil
-	}
-
-	// 4) Legacy relative source path
-	legacyStdlib := "../../stdlib"
-	if info, err := os.Stat(legacyStdlib); err == nil && info.IsDir() {
-		return legacyStdlib, nil
-	}
-
-	return "", fmt.Errorf("stdlib directory not found; tried GOTS_STDLIB_PATH, executable-relative stdlib, ./stdlib, ../../stdlib")
+	return stdlib.FS, embeddedStdlibRoot
 }
@@ -0,0 +1,73 @@
+package tsengine
+
+import (
+	"fmt"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/concurrency"
+)
+
+// registerSync registers the sync global with semaphores and mutexes.
+// Waiting for a permit or lock is a pending promise, settled when another
+// holder releases it, so nothing blocks the event loop.
+func (rb *RuntimeBindings) registerSync() error {
+	vm := rb.engine.VM()
+	syncObj := vm.NewObject()
+
+	// semaphore(n) creates a semaphore with n permits
+	syncObj.Set("semaphore", func(n int) *goja.Object {
+		sem, err := concurrency.NewSemaphore(n)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+
+		semObj := vm.NewObject()
+		semObj.Set("acquire", func() *goja.Promise {
+			return rb.acquire(sem)
+		})
+		semObj.Set("tryAcquire", sem.TryAcquire)
+		semObj.Set("release", func() {
+			if err := sem.Release(); err != nil {
+				panic(rb.jsError(err))
+			}
+		})
+		semObj.Set("available", sem.Available)
+		semObj.Set("waiting", sem.Waiting)
+		return semObj
+	})
+
+	// mutex() creates a lock held by one holder at a time
+	syncObj.Set("mutex", func() *goja.Object {
+		sem, _ := concurrency.NewSemaphore(1)
+
+		mutexObj := vm.NewObject()
+		mutexObj.Set("lock", func() *goja.Promise {
+			return rb.acquire(sem)
+		})
+		mutexObj.Set("tryLock", sem.TryAcquire)
+		mutexObj.Set("unlock", func() {
+			if err := sem.Release(); err != nil {
+				panic(rb.jsError(fmt.Errorf("mutex is not locked")))
+			}
+		})
+		mutexObj.Set("isLocked", func() bool {
+			return sem.Available() == 0
+		})
+		return mutexObj
+	})
+
+	rb.engine.Set("sync", syncObj)
+	return nil
+}
+
+// acquire returns a promise that resolves once a permit of sem is held.
+// Permits are released from JavaScript, on the event loop, so the promise
+// is resolved there too.
+func (rb *RuntimeBindings) acquire(sem *concurrency.Semaphore) *goja.Promise {
+	promise, resolve, _ := rb.engine.VM().NewPromise()
+	sem.Acquire(func() {
+		resolve(goja.Undefined())
+	})
+	return promise
+}
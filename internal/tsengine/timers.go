@@ -0,0 +1,109 @@
+package tsengine
+
+import (
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// SetClockControl exposes runtime.clock.set and runtime.clock.advance, which
+// freeze and move the clock behind Date and the timers, to make tests and
+// replays reproducible. It must be called before RegisterAPIs.
+func (rb *RuntimeBindings) SetClockControl(enabled bool) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.clockControl = enabled
+}
+
+// now returns the time seen by Date. It is the event loop's clock, recorded
+// through the I/O log so that replays see the recorded times.
+func (rb *RuntimeBindings) now() time.Time {
+	clock := rb.eventLoop.Clock()
+	if rb.ioLog == nil {
+		return clock.Now()
+	}
+
+	var ms int64
+	err := rb.ioSync("clock.now", nil, &ms, func() error {
+		ms = clock.Now().UnixMilli()
+		return nil
+	})
+	if err != nil {
+		return clock.Now()
+	}
+	return time.UnixMilli(ms)
+}
+
+// registerTimers registers the setTimeout and setInterval globals, which
+// run their callbacks on the event loop once the delay has passed on its
+// clock, and routes Date through the clock
+func (rb *RuntimeBindings) registerTimers() error {
+	vm := rb.engine.VM()
+	vm.SetTimeSource(rb.now)
+
+	schedule := func(repeat bool) func(goja.FunctionCall) goja.Value {
+		return func(call goja.FunctionCall) goja.Value {
+			callback, ok := goja.AssertFunction(call.Argument(0))
+			if !ok {
+				panic(vm.NewTypeError("callback must be a function"))
+			}
+			delay := time.Duration(call.Argument(1).ToInteger()) * time.Millisecond
+			if delay < 0 {
+				delay = 0
+			}
+			var args []goja.Value
+			if len(call.Arguments) > 2 {
+				args = append(args, call.Arguments[2:]...)
+			}
+
+			handler := func() error {
+				_, _ = callback(goja.Undefined(), args...)
+				return nil
+			}
+			if repeat {
+				return vm.ToValue(rb.eventLoop.SetInterval(delay, handler))
+			}
+			return vm.ToValue(rb.eventLoop.SetTimeout(delay, handler))
+		}
+	}
+	clear := func(id goja.Value) {
+		if id == nil || goja.IsUndefined(id) || goja.IsNull(id) {
+			return
+		}
+		rb.eventLoop.ClearTimeout(uint64(id.ToInteger()))
+	}
+
+	rb.engine.Set("setTimeout", schedule(false))
+	rb.engine.Set("setInterval", schedule(true))
+	rb.engine.Set("clearTimeout", clear)
+	rb.engine.Set("clearInterval", clear)
+	return nil
+}
+
+// createClockObject creates runtime.clock. now() returns the time in
+// milliseconds; with clock control enabled, set(ms) freezes the clock at a
+// time and advance(ms) moves it forward, firing the timers that fall due.
+func (rb *RuntimeBindings) createClockObject() *goja.Object {
+	vm := rb.engine.VM()
+	clock := rb.eventLoop.Clock()
+	clockObj := vm.NewObject()
+
+	clockObj.Set("now", func() int64 {
+		return rb.now().UnixMilli()
+	})
+
+	rb.mu.RLock()
+	control := rb.clockControl
+	rb.mu.RUnlock()
+	if !control {
+		return clockObj
+	}
+
+	clockObj.Set("set", func(ms int64) {
+		clock.Set(time.UnixMilli(ms))
+	})
+	clockObj.Set("advance", func(ms int64) {
+		clock.Advance(time.Duration(ms) * time.Millisecond)
+	})
+	return clockObj
+}
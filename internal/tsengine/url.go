@@ -0,0 +1,98 @@
+package tsengine
+
+import (
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/api"
+)
+
+// registerURL registers the url and querystring APIs
+func (rb *RuntimeBindings) registerURL() error {
+	vm := rb.engine.VM()
+
+	urlObj := vm.NewObject()
+
+	urlObj.Set("parse", func(raw string) *goja.Object {
+		info, err := api.ParseURL(raw)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return rb.urlInfoObject(info)
+	})
+
+	urlObj.Set("format", func(value goja.Value) string {
+		if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+			return ""
+		}
+		if _, ok := value.(*goja.Object); !ok {
+			return value.String()
+		}
+		return api.FormatURL(urlInfoFromObject(value.ToObject(vm)))
+	})
+
+	queryObj := vm.NewObject()
+
+	queryObj.Set("parse", func(qs string) map[string]interface{} {
+		query, err := api.ParseQuery(qs)
+		if err != nil {
+			panic(rb.jsError(err))
+		}
+		return query
+	})
+
+	queryObj.Set("stringify", func(value goja.Value) string {
+		if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+			return ""
+		}
+		query, _ := value.Export().(map[string]interface{})
+		return api.StringifyQuery(query)
+	})
+
+	rb.engine.Set("url", urlObj)
+	rb.engine.Set("querystring", queryObj)
+	return nil
+}
+
+// urlInfoObject converts parsed URL components to a JavaScript object
+func (rb *RuntimeBindings) urlInfoObject(info *api.URLInfo) *goja.Object {
+	obj := rb.engine.VM().NewObject()
+	obj.Set("href", info.Href)
+	obj.Set("protocol", info.Protocol)
+	obj.Set("username", info.Username)
+	obj.Set("password", info.Password)
+	obj.Set("host", info.Host)
+	obj.Set("hostname", info.Hostname)
+	obj.Set("port", info.Port)
+	obj.Set("pathname", info.Pathname)
+	obj.Set("search", info.Search)
+	obj.Set("query", info.Query)
+	obj.Set("hash", info.Hash)
+	return obj
+}
+
+// urlInfoFromObject reads URL components from a JavaScript object
+func urlInfoFromObject(obj *goja.Object) *api.URLInfo {
+	str := func(key string) string {
+		value := obj.Get(key)
+		if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+			return ""
+		}
+		return value.String()
+	}
+
+	info := &api.URLInfo{
+		Protocol: str("protocol"),
+		Username: str("username"),
+		Password: str("password"),
+		Host:     str("host"),
+		Hostname: str("hostname"),
+		Port:     str("port"),
+		Pathname: str("pathname"),
+		Search:   str("search"),
+		Hash:     str("hash"),
+	}
+	if query := obj.Get("query"); query != nil && !goja.IsUndefined(query) && !goja.IsNull(query) {
+		info.Query, _ = query.Export().(map[string]interface{})
+	}
+	return info
+}
@@ -0,0 +1,78 @@
+package tsengine
+
+import (
+	"context"
+
+	"gots-runtime/internal/worker"
+
+	"github.com/dop251/goja"
+)
+
+// CrashRecorder records crashes that the runtime contained
+type CrashRecorder interface {
+	// RecordCrash records a recovered crash of a module
+	RecordCrash(moduleID string, err error, stackTrace string)
+}
+
+// SetCrashRecorder sets the recorder that worker tasks which panic are
+// reported to. It must be called before RegisterAPIs.
+func (rb *RuntimeBindings) SetCrashRecorder(recorder CrashRecorder) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.crashRecorder = recorder
+}
+
+// SetWorkerSetup sets a function that prepares the runtimes worker tasks
+// run in, for example by defining console. It must be called before
+// RegisterAPIs.
+func (rb *RuntimeBindings) SetWorkerSetup(setup func(vm *goja.Runtime)) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	rb.workerSetup = setup
+}
+
+// newWorkerPool creates a worker pool whose runtimes are prepared with the
+// worker setup and whose crashes go to the crash recorder. The pool counts
+// towards runtime.stats() until removed with removeWorkerPool.
+func (rb *RuntimeBindings) newWorkerPool(ctx context.Context, minWorkers, maxWorkers int) *worker.TypeScriptWorker {
+	pool := worker.NewTypeScriptWorker(ctx, rb.engine.VM(), rb.eventLoop, minWorkers, maxWorkers)
+	pool.SetRuntimeSetup(rb.workerSetup)
+	if rb.crashRecorder != nil {
+		pool.SetCrashHandler(func(crash *worker.TaskCrashError) {
+			rb.crashRecorder.RecordCrash(rb.moduleID, crash, crash.Stack)
+		})
+	}
+	rb.mu.Lock()
+	rb.workerPools = append(rb.workerPools, pool)
+	rb.mu.Unlock()
+	return pool
+}
+
+// removeWorkerPool stops counting a closed pool in runtime.stats()
+func (rb *RuntimeBindings) removeWorkerPool(pool *worker.TypeScriptWorker) {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+	for i, p := range rb.workerPools {
+		if p == pool {
+			rb.workerPools = append(rb.workerPools[:i], rb.workerPools[i+1:]...)
+			return
+		}
+	}
+}
+
+// workerStats sums the stats of the open worker pools
+func (rb *RuntimeBindings) workerStats() map[string]interface{} {
+	rb.mu.RLock()
+	pools := append([]*worker.TypeScriptWorker(nil), rb.workerPools...)
+	rb.mu.RUnlock()
+
+	totals := map[string]interface{}{"pools": len(pools)}
+	for _, pool := range pools {
+		for key, value := range pool.GetStats() {
+			count, _ := value.(int)
+			total, _ := totals[key].(int)
+			totals[key] = total + count
+		}
+	}
+	return totals
+}
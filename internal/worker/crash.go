@@ -0,0 +1,38 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// TaskCrashError is the error of a task whose handler panicked. The panic
+// is contained to the task: the worker that ran it goes on to run other
+// tasks.
+type TaskCrashError struct {
+	TaskID string
+	Value  interface{} // the value the handler panicked with
+	Stack  string      // the stack of the panicking goroutine
+}
+
+// Error implements error
+func (e *TaskCrashError) Error() string {
+	return fmt.Sprintf("task %s crashed: %v", e.TaskID, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error
+func (e *TaskCrashError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// runTask executes task, turning a panic of its handler into a
+// TaskCrashError
+func runTask(ctx context.Context, task *Task) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &TaskCrashError{TaskID: task.ID, Value: r, Stack: string(debug.Stack())}
+		}
+	}()
+	return task.Execute(ctx)
+}
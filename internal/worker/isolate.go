@@ -0,0 +1,169 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// Worker runtimes
+//
+// Handlers of TypeScriptWorker tasks do not run on the main VM, which is
+// not safe for concurrent use: each task runs in a goja runtime of its own,
+// where the handler is compiled again from its source. A handler therefore
+// cannot refer to variables of the scope it was defined in; everything it
+// needs is passed to it as data. Runtimes are reused by later tasks, except
+// that a runtime whose task panicked is discarded.
+
+// maxWorkerCallStack is the call stack depth of worker runtimes; deeper
+// recursion fails the task instead of overflowing the goroutine's stack
+const maxWorkerCallStack = 10000
+
+// Handler is a JavaScript function compiled to run in worker runtimes
+type Handler struct {
+	program *goja.Program
+}
+
+// maxSourceRepairs bounds the closing parentheses CompileHandler restores
+const maxSourceRepairs = 16
+
+// CompileHandler compiles the source of the function fn for worker runtimes.
+// The source goja gives for an arrow function whose body is parenthesized,
+// e.g. (d) => ({ v: d.x }), lacks the closing parentheses at its end, so
+// they are restored until the source compiles.
+func CompileHandler(fn goja.Value) (*Handler, error) {
+	obj, ok := fn.(*goja.Object)
+	if _, isFunction := goja.AssertFunction(fn); !ok || !isFunction {
+		return nil, fmt.Errorf("handler is not a function")
+	}
+	source := obj.String()
+	program, err := goja.Compile("worker handler", "("+source+")", false)
+	for repairs := 1; err != nil && repairs <= maxSourceRepairs; repairs++ {
+		repaired, repairErr := goja.Compile("worker handler", "("+source+strings.Repeat(")", repairs)+")", false)
+		if repairErr == nil {
+			program, err = repaired, nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("handler cannot run in a worker, which needs a function or arrow function expression: %w", err)
+	}
+	return &Handler{program: program}, nil
+}
+
+// newWorkerRuntime creates a runtime for worker tasks, with the atomics
+// functions and whatever setup adds
+func newWorkerRuntime(setup func(vm *goja.Runtime)) *goja.Runtime {
+	vm := goja.New()
+	vm.SetMaxCallStackSize(maxWorkerCallStack)
+	vm.Set("atomics", NewAtomicsObject(vm))
+	if setup != nil {
+		setup(vm)
+	}
+	return vm
+}
+
+// SetRuntimeSetup sets a function that prepares each new worker runtime,
+// for example by defining globals such as console
+func (tw *TypeScriptWorker) SetRuntimeSetup(setup func(vm *goja.Runtime)) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.setup = setup
+}
+
+// SetCrashHandler sets a function called with the error of every task
+// whose handler panics
+func (tw *TypeScriptWorker) SetCrashHandler(handler func(*TaskCrashError)) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	tw.onCrash = handler
+}
+
+// recordCrash counts a crashed task and passes it to the crash handler
+func (tw *TypeScriptWorker) recordCrash(crash *TaskCrashError) {
+	tw.mu.Lock()
+	tw.crashed++
+	handler := tw.onCrash
+	tw.mu.Unlock()
+	if handler != nil {
+		handler(crash)
+	}
+}
+
+// acquireRuntime takes an idle worker runtime, creating one if there is
+// none
+func (tw *TypeScriptWorker) acquireRuntime() *goja.Runtime {
+	tw.mu.Lock()
+	if n := len(tw.runtimes); n > 0 {
+		vm := tw.runtimes[n-1]
+		tw.runtimes = tw.runtimes[:n-1]
+		tw.mu.Unlock()
+		return vm
+	}
+	setup := tw.setup
+	tw.mu.Unlock()
+	return newWorkerRuntime(setup)
+}
+
+// releaseRuntime returns a runtime for later tasks to reuse; no more are
+// kept than the pool has workers
+func (tw *TypeScriptWorker) releaseRuntime(vm *goja.Runtime) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+	if len(tw.runtimes) < tw.pool.maxWorkers {
+		tw.runtimes = append(tw.runtimes, vm)
+	}
+}
+
+// call runs handler in a worker runtime with input and args as its
+// arguments and returns a clone of its result. If the handler panics, the
+// panic propagates to the worker, which fails the task with a
+// TaskCrashError, and the runtime is discarded.
+func (tw *TypeScriptWorker) call(handler *Handler, input *ClonedValue, args ...interface{}) (*ClonedValue, error) {
+	vm := tw.acquireRuntime()
+	output, err := callHandler(vm, handler, input, args)
+	tw.releaseRuntime(vm)
+	return output, err
+}
+
+// callHandler runs handler in vm. A handler returning a promise must
+// settle it before returning to the worker, as worker runtimes have no
+// event loop.
+func callHandler(vm *goja.Runtime, handler *Handler, input *ClonedValue, args []interface{}) (*ClonedValue, error) {
+	value, err := vm.RunProgram(handler.program)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := goja.AssertFunction(value)
+	if !ok {
+		return nil, fmt.Errorf("handler is not a function")
+	}
+
+	arg, err := input.Value(vm)
+	if err != nil {
+		return nil, fmt.Errorf("failed to restore data: %w", err)
+	}
+	callArgs := []goja.Value{arg}
+	for _, extra := range args {
+		callArgs = append(callArgs, vm.ToValue(extra))
+	}
+
+	result, err := fn(goja.Undefined(), callArgs...)
+	if _, ok := err.(*goja.StackOverflowError); ok {
+		return nil, fmt.Errorf("maximum call stack size exceeded")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if promise, ok := result.Export().(*goja.Promise); ok {
+		switch promise.State() {
+		case goja.PromiseStateFulfilled:
+			result = promise.Result()
+		case goja.PromiseStateRejected:
+			return nil, fmt.Errorf("%s", promise.Result().String())
+		default:
+			return nil, fmt.Errorf("handler returned a promise that did not settle")
+		}
+	}
+	return StructuredClone(vm, result)
+}
@@ -0,0 +1,192 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// MapOptions configures TypeScriptWorker.Map
+type MapOptions struct {
+	Concurrency   int  // items in flight at once; defaults to the pool's maximum
+	CollectErrors bool // run every item and report all failures instead of stopping at the first
+}
+
+// ParseMapOptions reads map options from a JavaScript object such as
+// { concurrency: 4, errors: "collect" }; errors is "fail-fast" (the
+// default) or "collect"
+func ParseMapOptions(value goja.Value) (MapOptions, error) {
+	var opts MapOptions
+	if value == nil || goja.IsUndefined(value) || goja.IsNull(value) {
+		return opts, nil
+	}
+
+	raw, ok := value.Export().(map[string]interface{})
+	if !ok {
+		return opts, fmt.Errorf("map options must be an object")
+	}
+	if v, ok := toInt(raw["concurrency"]); ok {
+		if v <= 0 {
+			return opts, fmt.Errorf("concurrency must be positive")
+		}
+		opts.Concurrency = v
+	}
+	switch mode := raw["errors"]; mode {
+	case nil, "fail-fast":
+	case "collect":
+		opts.CollectErrors = true
+	default:
+		return opts, fmt.Errorf("errors must be \"fail-fast\" or \"collect\", got %v", mode)
+	}
+	return opts, nil
+}
+
+// toInt converts an exported JavaScript number to int
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	default:
+		return 0, false
+	}
+}
+
+// Map runs fn(item, index) for every item on the pool, each call in a
+// worker runtime as with Spawn, with at most
+// opts.Concurrency calls in flight, and resolves to the results in the
+// order of items. By default the first failure rejects the promise and no
+// more items are started; with opts.CollectErrors every item runs and the
+// promise rejects with an error listing all failures.
+func (tw *TypeScriptWorker) Map(items []goja.Value, fn goja.Value, opts MapOptions) *goja.Promise {
+	promise, resolve, reject := tw.engine.NewPromise()
+
+	handler, err := CompileHandler(fn)
+	if err != nil {
+		reject(tw.engine.ToValue(err.Error()))
+		return promise
+	}
+
+	inputs := make([]*ClonedValue, len(items))
+	for i, item := range items {
+		input, err := StructuredClone(tw.engine, item)
+		if err != nil {
+			reject(tw.engine.ToValue(fmt.Sprintf("item %d: %v", i, err)))
+			return promise
+		}
+		inputs[i] = input
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = tw.pool.maxWorkers
+	}
+
+	settle := tw.settler()
+	go func() {
+		outputs := make([]*ClonedValue, len(items))
+		errs := make([]error, len(items))
+		slots := make(chan struct{}, concurrency)
+		failed := make(chan struct{})
+		var failOnce sync.Once
+		var wg sync.WaitGroup
+
+	submit:
+		for i := range inputs {
+			select {
+			case slots <- struct{}{}:
+			case <-failed:
+				break submit
+			case <-tw.ctx.Done():
+				break submit
+			}
+
+			index := i
+			task := NewTask(
+				fmt.Sprintf("map-%d", index),
+				func(ctx context.Context) error {
+					var err error
+					outputs[index], err = tw.call(handler, inputs[index], index)
+					return err
+				},
+				true,
+				0,
+			)
+			if err := tw.pool.Submit(task); err != nil {
+				errs[index] = err
+				break submit
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				select {
+				case result := <-task.Done():
+					if result.Error != nil {
+						errs[index] = result.Error
+						if !opts.CollectErrors {
+							failOnce.Do(func() { close(failed) })
+						}
+					}
+				case <-tw.ctx.Done():
+					errs[index] = fmt.Errorf("worker pool closed")
+				}
+				<-slots
+			}()
+		}
+		wg.Wait()
+
+		var failures []int
+		for i, err := range errs {
+			if err != nil {
+				failures = append(failures, i)
+			}
+		}
+		settle(func() {
+			if len(failures) == 0 && tw.ctx.Err() != nil {
+				reject(tw.engine.ToValue("worker pool closed"))
+				return
+			}
+			if len(failures) > 0 {
+				reject(tw.mapError(failures, errs, opts.CollectErrors))
+				return
+			}
+
+			results := make([]interface{}, len(outputs))
+			for i, output := range outputs {
+				results[i] = tw.restore(output)
+			}
+			resolve(tw.engine.ToValue(results))
+		})
+	}()
+
+	return promise
+}
+
+// mapError creates the rejection of a failed Map: the first failure, or
+// with collect, an error whose errors property lists every failure as
+// { index, message }
+func (tw *TypeScriptWorker) mapError(failures []int, errs []error, collect bool) goja.Value {
+	first := failures[0]
+	if !collect {
+		return tw.engine.ToValue(fmt.Sprintf("item %d: %v", first, errs[first]))
+	}
+
+	message := fmt.Sprintf("%d of %d items failed; first, item %d: %v", len(failures), len(errs), first, errs[first])
+	errObj, err := tw.engine.New(tw.engine.Get("Error"), tw.engine.ToValue(message))
+	if err != nil {
+		return tw.engine.ToValue(message)
+	}
+	list := make([]interface{}, len(failures))
+	for i, index := range failures {
+		entry := tw.engine.NewObject()
+		entry.Set("index", index)
+		entry.Set("message", errs[index].Error())
+		list[i] = entry
+	}
+	errObj.Set("errors", list)
+	return errObj
+}
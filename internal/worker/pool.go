@@ -4,6 +4,8 @@ import (
 	"context"
 	"sync"
 	"time"
+
+	"gots-runtime/internal/observability"
 )
 
 // Pool represents a pool of workers
@@ -17,11 +19,22 @@ type Pool struct {
 	minWorkers  int
 	maxWorkers  int
 	currentWorkers int
+	idle        chan struct{}
+	metrics     *observability.MetricsCollector
+	highWater   int
+	onCrash     func(*TaskCrashError)
+	crashMu     sync.RWMutex // guards onCrash; Stop holds mu while workers finish their tasks
 	mu          sync.RWMutex
 }
 
 // NewPool creates a new worker pool
 func NewPool(ctx context.Context, minWorkers, maxWorkers int) *Pool {
+	return NewPoolWithMetrics(ctx, minWorkers, maxWorkers, nil)
+}
+
+// NewPoolWithMetrics creates a new worker pool that records task counters,
+// queue depth and worker counts into metrics
+func NewPoolWithMetrics(ctx context.Context, minWorkers, maxWorkers int, metrics *observability.MetricsCollector) *Pool {
 	poolCtx, cancel := context.WithCancel(ctx)
 	return &Pool{
 		workers:        make([]*Worker, 0),
@@ -32,6 +45,8 @@ func NewPool(ctx context.Context, minWorkers, maxWorkers int) *Pool {
 		minWorkers:      minWorkers,
 		maxWorkers:      maxWorkers,
 		currentWorkers:  0,
+		idle:            make(chan struct{}, 1),
+		metrics:         metrics,
 	}
 }
 
@@ -69,13 +84,85 @@ func (p *Pool) Stop() {
 func (p *Pool) Submit(task *Task) error {
 	select {
 	case p.taskQueue <- task:
+		p.recordSubmit()
 		return nil
 	case <-p.ctx.Done():
+		p.increment("worker_pool_tasks_rejected")
 		return p.ctx.Err()
 	}
 }
 
-// ResultChan returns the result channel
+// recordSubmit records a submitted task and the queue depth
+func (p *Pool) recordSubmit() {
+	if p.metrics == nil {
+		return
+	}
+
+	queued := len(p.taskQueue)
+	p.mu.Lock()
+	if queued > p.highWater {
+		p.highWater = queued
+	}
+	highWater := p.highWater
+	p.mu.Unlock()
+
+	p.metrics.Increment("worker_pool_tasks_submitted", nil)
+	p.metrics.Set("worker_pool_queue_length", float64(queued), nil)
+	p.metrics.Set("worker_pool_queue_high_water", float64(highWater), nil)
+}
+
+// recordResult records a finished task
+func (p *Pool) recordResult(result *TaskResult) {
+	if p.metrics == nil {
+		return
+	}
+
+	if result.Error != nil {
+		p.metrics.Increment("worker_pool_tasks_failed", nil)
+	} else {
+		p.metrics.Increment("worker_pool_tasks_completed", nil)
+	}
+	p.metrics.Set("worker_pool_queue_length", float64(len(p.taskQueue)), nil)
+}
+
+// recordWorkers records the current number of workers. Must be called with
+// p.mu held.
+func (p *Pool) recordWorkers() {
+	if p.metrics != nil {
+		p.metrics.Set("worker_pool_workers", float64(p.currentWorkers), nil)
+	}
+}
+
+// increment increments a counter if metrics are enabled
+func (p *Pool) increment(name string) {
+	if p.metrics != nil {
+		p.metrics.Increment(name, nil)
+	}
+}
+
+// SetCrashHandler sets a function called with the error of every task
+// that panics
+func (p *Pool) SetCrashHandler(handler func(*TaskCrashError)) {
+	p.crashMu.Lock()
+	defer p.crashMu.Unlock()
+	p.onCrash = handler
+}
+
+// reportCrash records a task that panicked and passes it to the crash
+// handler
+func (p *Pool) reportCrash(crash *TaskCrashError) {
+	p.increment("worker_pool_tasks_crashed")
+	p.crashMu.RLock()
+	handler := p.onCrash
+	p.crashMu.RUnlock()
+	if handler != nil {
+		handler(crash)
+	}
+}
+
+// ResultChan returns a channel receiving the results of all tasks. Results
+// are dropped while its buffer is full; use Task.Done to wait for a
+// particular task.
 func (p *Pool) ResultChan() <-chan *TaskResult {
 	return p.resultChan
 }
@@ -90,15 +177,25 @@ func (p *Pool) addWorker() {
 	}
 
 	worker := NewWorker(p.currentWorkers, p.ctx)
+	worker.SetIdleCallback(p.notifyIdle)
+	worker.SetCrashCallback(p.reportCrash)
 	worker.Start()
 
 	// Forward results to pool result channel
 	p.wg.Add(1)
 	go func() {
 		defer p.wg.Done()
-		for result := range worker.ResultChan() {
+		for {
 			select {
-			case p.resultChan <- result:
+			case result := <-worker.ResultChan():
+				p.recordResult(result)
+				// Submitters wait on their tasks' Done channels, so results
+				// nobody collects from the pool are dropped rather than
+				// stalling the workers
+				select {
+				case p.resultChan <- result:
+				default:
+				}
 			case <-p.ctx.Done():
 				return
 			}
@@ -107,9 +204,10 @@ func (p *Pool) addWorker() {
 
 	p.workers = append(p.workers, worker)
 	p.currentWorkers++
+	p.recordWorkers()
 }
 
-// removeWorker removes a worker from the pool
+// removeWorker removes an idle worker from the pool
 func (p *Pool) removeWorker() {
 	p.mu.Lock()
 	defer p.mu.Unlock()
@@ -118,15 +216,24 @@ func (p *Pool) removeWorker() {
 		return
 	}
 
-	if len(p.workers) == 0 {
-		return
+	// Remove the last idle worker; busy workers keep their tasks
+	for i := len(p.workers) - 1; i >= 0; i-- {
+		worker := p.workers[i]
+		if worker.retire() {
+			p.workers = append(p.workers[:i], p.workers[i+1:]...)
+			p.currentWorkers--
+			p.recordWorkers()
+			return
+		}
 	}
+}
 
-	// Remove the last worker
-	worker := p.workers[len(p.workers)-1]
-	p.workers = p.workers[:len(p.workers)-1]
-	worker.Stop()
-	p.currentWorkers--
+// notifyIdle wakes the dispatcher when a worker finishes a task
+func (p *Pool) notifyIdle() {
+	select {
+	case p.idle <- struct{}{}:
+	default:
+	}
 }
 
 // dispatch dispatches tasks to workers
@@ -146,34 +253,37 @@ func (p *Pool) dispatch() {
 	}
 }
 
-// assignTask assigns a task to an available worker
+// assignTask assigns a task to an idle worker, adding a worker if below the
+// maximum and otherwise waiting until one becomes idle. Tasks are only
+// dropped when the pool is stopped.
 func (p *Pool) assignTask(task *Task) {
-	p.mu.RLock()
-	workers := make([]*Worker, len(p.workers))
-	copy(workers, p.workers)
-	p.mu.RUnlock()
+	for {
+		p.mu.RLock()
+		workers := make([]*Worker, len(p.workers))
+		copy(workers, p.workers)
+		canGrow := p.currentWorkers < p.maxWorkers
+		p.mu.RUnlock()
 
-	// Try to find an idle worker
-	for _, worker := range workers {
-		if !worker.IsBusy() {
-			_ = worker.AssignTask(task)
-			return
+		// Try to find an idle worker
+		for _, worker := range workers {
+			if worker.TryAssignTask(task) {
+				return
+			}
 		}
-	}
 
-	// All workers are busy, assign to first available or create new worker
-	if p.currentWorkers < p.maxWorkers {
-		p.addWorker()
-		// Try again with new worker
-		p.mu.RLock()
-		if len(p.workers) > 0 {
-			_ = p.workers[len(p.workers)-1].AssignTask(task)
+		// All workers are busy, create a new worker if allowed
+		if canGrow {
+			p.addWorker()
+			continue
 		}
-		p.mu.RUnlock()
-	} else {
-		// Assign to first worker (round-robin could be improved)
-		if len(workers) > 0 {
-			_ = workers[0].AssignTask(task)
+
+		// Wait for a worker to finish. The timeout covers workers that went
+		// idle between the scan and the wait.
+		select {
+		case <-p.idle:
+		case <-time.After(10 * time.Millisecond):
+		case <-p.ctx.Done():
+			return
 		}
 	}
 }
@@ -223,6 +333,7 @@ type Stats struct {
 	CurrentWorkers int
 	BusyWorkers    int
 	QueueSize      int
+	QueueHighWater int
 	MinWorkers     int
 	MaxWorkers     int
 }
@@ -243,6 +354,7 @@ func (p *Pool) GetStats() Stats {
 		CurrentWorkers: p.currentWorkers,
 		BusyWorkers:    busyCount,
 		QueueSize:      len(p.taskQueue),
+		QueueHighWater: p.highWater,
 		MinWorkers:     p.minWorkers,
 		MaxWorkers:     p.maxWorkers,
 	}
@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPoolRunsMoreTasksThanWorkers(t *testing.T) {
+	pool := NewPool(context.Background(), 2, 4)
+	pool.Start()
+	defer pool.Stop()
+
+	const count = 500
+	var ran int64
+	tasks := make([]*Task, count)
+	for i := range tasks {
+		tasks[i] = NewTask(fmt.Sprintf("task-%d", i), func(ctx context.Context) error {
+			time.Sleep(100 * time.Microsecond)
+			atomic.AddInt64(&ran, 1)
+			return nil
+		}, false, 0)
+		if err := pool.Submit(tasks[i]); err != nil {
+			t.Fatalf("Submit(%d): %v", i, err)
+		}
+	}
+
+	timeout := time.After(10 * time.Second)
+	for i, task := range tasks {
+		select {
+		case result := <-task.Done():
+			if result.TaskID != task.ID {
+				t.Errorf("task %d finished as %q", i, result.TaskID)
+			}
+			if result.Error != nil {
+				t.Errorf("task %d failed: %v", i, result.Error)
+			}
+		case <-timeout:
+			t.Fatalf("task %d of %d did not finish; %d ran", i, count, atomic.LoadInt64(&ran))
+		}
+	}
+
+	if got := atomic.LoadInt64(&ran); got != count {
+		t.Errorf("ran %d tasks, want %d", got, count)
+	}
+	if stats := pool.GetStats(); stats.CurrentWorkers > 4 {
+		t.Errorf("pool grew to %d workers, want at most 4", stats.CurrentWorkers)
+	}
+}
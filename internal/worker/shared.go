@@ -0,0 +1,234 @@
+package worker
+
+import (
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/dop251/goja"
+)
+
+// Shared buffers
+//
+// A SharedBuffer is memory that the main thread and workers use at once
+// instead of copying. Cloning a shared buffer, for example when passing it
+// to a worker, shares its memory rather than copying it.
+//
+// The buffer holds 32-bit signed integers. Every access to an element, by
+// index or through the atomics functions, is a single atomic operation, and
+// all atomic operations are sequentially consistent: they appear to run in
+// one order that all threads agree on, consistent with each thread's
+// program order. An element is never observed half-written. Sequences of
+// operations are not atomic as a whole; build them from compareExchange.
+
+// SharedBuffer is fixed-size memory shared between runtimes, accessed with
+// atomic operations
+type SharedBuffer struct {
+	words []int32
+}
+
+// NewSharedBuffer creates a zeroed shared buffer of size bytes, rounded up
+// to whole 32-bit elements
+func NewSharedBuffer(size int) (*SharedBuffer, error) {
+	if size < 0 {
+		return nil, fmt.Errorf("shared buffer size must not be negative")
+	}
+	return &SharedBuffer{words: make([]int32, (size+3)/4)}, nil
+}
+
+// Len returns the number of 32-bit elements
+func (sb *SharedBuffer) Len() int {
+	return len(sb.words)
+}
+
+// ByteLength returns the size of the buffer in bytes
+func (sb *SharedBuffer) ByteLength() int {
+	return len(sb.words) * 4
+}
+
+// Bytes returns the memory of the buffer. Reads and writes through it are
+// not atomic.
+func (sb *SharedBuffer) Bytes() []byte {
+	if len(sb.words) == 0 {
+		return nil
+	}
+	return unsafe.Slice((*byte)(unsafe.Pointer(&sb.words[0])), len(sb.words)*4)
+}
+
+// word returns the element at index
+func (sb *SharedBuffer) word(index int) (*int32, error) {
+	if index < 0 || index >= len(sb.words) {
+		return nil, fmt.Errorf("index %d is out of range for a shared buffer of %d elements", index, len(sb.words))
+	}
+	return &sb.words[index], nil
+}
+
+// Load atomically reads the element at index
+func (sb *SharedBuffer) Load(index int) (int32, error) {
+	w, err := sb.word(index)
+	if err != nil {
+		return 0, err
+	}
+	return atomic.LoadInt32(w), nil
+}
+
+// Store atomically writes value to the element at index
+func (sb *SharedBuffer) Store(index int, value int32) error {
+	w, err := sb.word(index)
+	if err != nil {
+		return err
+	}
+	atomic.StoreInt32(w, value)
+	return nil
+}
+
+// Add atomically adds delta to the element at index and returns its
+// previous value
+func (sb *SharedBuffer) Add(index int, delta int32) (int32, error) {
+	w, err := sb.word(index)
+	if err != nil {
+		return 0, err
+	}
+	return atomic.AddInt32(w, delta) - delta, nil
+}
+
+// Exchange atomically replaces the element at index with value and returns
+// its previous value
+func (sb *SharedBuffer) Exchange(index int, value int32) (int32, error) {
+	w, err := sb.word(index)
+	if err != nil {
+		return 0, err
+	}
+	return atomic.SwapInt32(w, value), nil
+}
+
+// CompareExchange atomically replaces the element at index with
+// replacement if it equals expected, and returns its previous value
+func (sb *SharedBuffer) CompareExchange(index int, expected, replacement int32) (int32, error) {
+	w, err := sb.word(index)
+	if err != nil {
+		return 0, err
+	}
+	for {
+		old := atomic.LoadInt32(w)
+		if old != expected {
+			return old, nil
+		}
+		if atomic.CompareAndSwapInt32(w, expected, replacement) {
+			return old, nil
+		}
+	}
+}
+
+// sharedBufferObject exposes a shared buffer to JavaScript as an object
+// with byteLength, length and indexed elements
+type sharedBufferObject struct {
+	buf *SharedBuffer
+	vm  *goja.Runtime
+}
+
+// NewSharedBufferObject wraps buf for vm
+func NewSharedBufferObject(vm *goja.Runtime, buf *SharedBuffer) *goja.Object {
+	return vm.NewDynamicObject(&sharedBufferObject{buf: buf, vm: vm})
+}
+
+// SharedBufferOf returns the shared buffer wrapped by value, if any
+func SharedBufferOf(value goja.Value) (*SharedBuffer, bool) {
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		return nil, false
+	}
+	wrapper, ok := obj.Export().(*sharedBufferObject)
+	if !ok {
+		return nil, false
+	}
+	return wrapper.buf, true
+}
+
+// Get returns byteLength, length or an element
+func (o *sharedBufferObject) Get(key string) goja.Value {
+	switch key {
+	case "byteLength":
+		return o.vm.ToValue(o.buf.ByteLength())
+	case "length":
+		return o.vm.ToValue(o.buf.Len())
+	}
+	if index, err := strconv.Atoi(key); err == nil {
+		if value, err := o.buf.Load(index); err == nil {
+			return o.vm.ToValue(value)
+		}
+	}
+	return nil
+}
+
+// Set stores an element; other properties cannot be set
+func (o *sharedBufferObject) Set(key string, val goja.Value) bool {
+	index, err := strconv.Atoi(key)
+	if err != nil {
+		return false
+	}
+	return o.buf.Store(index, int32(val.ToInteger())) == nil
+}
+
+// Has reports whether key is byteLength, length or an element index
+func (o *sharedBufferObject) Has(key string) bool {
+	if key == "byteLength" || key == "length" {
+		return true
+	}
+	index, err := strconv.Atoi(key)
+	return err == nil && index >= 0 && index < o.buf.Len()
+}
+
+// Delete fails: the properties of a shared buffer cannot be deleted
+func (o *sharedBufferObject) Delete(key string) bool {
+	return false
+}
+
+// Keys lists byteLength and length; elements are not enumerated
+func (o *sharedBufferObject) Keys() []string {
+	return []string{"byteLength", "length"}
+}
+
+// NewAtomicsObject creates the atomics object, whose functions operate on
+// the elements of shared buffers: add, sub, load, store, exchange and
+// compareExchange
+func NewAtomicsObject(vm *goja.Runtime) *goja.Object {
+	buffer := func(value goja.Value) *SharedBuffer {
+		buf, ok := SharedBufferOf(value)
+		if !ok {
+			panic(vm.NewTypeError("atomics operate on shared buffers created with worker.sharedBuffer"))
+		}
+		return buf
+	}
+	check := func(result int32, err error) int32 {
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return result
+	}
+
+	obj := vm.NewObject()
+	obj.Set("load", func(target goja.Value, index int) int32 {
+		return check(buffer(target).Load(index))
+	})
+	obj.Set("store", func(target goja.Value, index int, value int32) int32 {
+		if err := buffer(target).Store(index, value); err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return value
+	})
+	obj.Set("add", func(target goja.Value, index int, delta int32) int32 {
+		return check(buffer(target).Add(index, delta))
+	})
+	obj.Set("sub", func(target goja.Value, index int, delta int32) int32 {
+		return check(buffer(target).Add(index, -delta))
+	})
+	obj.Set("exchange", func(target goja.Value, index int, value int32) int32 {
+		return check(buffer(target).Exchange(index, value))
+	})
+	obj.Set("compareExchange", func(target goja.Value, index int, expected, replacement int32) int32 {
+		return check(buffer(target).CompareExchange(index, expected, replacement))
+	})
+	return obj
+}
@@ -12,6 +12,7 @@ type Task struct {
 	IsCPUIntensive bool
 	Priority      int
 	CreatedAt     time.Time
+	done          chan *TaskResult
 }
 
 // NewTask creates a new task
@@ -22,6 +23,24 @@ func NewTask(id string, handler func(ctx context.Context) error, isCPUIntensive
 		IsCPUIntensive: isCPUIntensive,
 		Priority:       priority,
 		CreatedAt:      time.Now(),
+		done:           make(chan *TaskResult, 1),
+	}
+}
+
+// Done returns a channel that receives the result of the task once it has
+// run, so that callers can wait for their own tasks
+func (t *Task) Done() <-chan *TaskResult {
+	return t.done
+}
+
+// finish delivers the result of the task to its Done channel
+func (t *Task) finish(result *TaskResult) {
+	if t.done == nil {
+		return
+	}
+	select {
+	case t.done <- result:
+	default:
 	}
 }
 
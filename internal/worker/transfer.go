@@ -0,0 +1,431 @@
+package worker
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/dop251/goja"
+)
+
+// Transferable types
+//
+// Values passed between the main thread and workers are copied with a
+// structured clone instead of a JSON round-trip. The following types are
+// supported:
+//
+//   - undefined, null, booleans, numbers, strings and BigInts
+//   - arrays and plain objects (own enumerable string keys)
+//   - Date, RegExp, Map, Set and Error (name and message)
+//   - ArrayBuffer, DataView and typed arrays (Uint8Array, Float64Array, ...)
+//   - shared buffers, whose memory is shared instead of copied
+//
+// Functions and symbols are not transferable and cloning them fails with a
+// DataCloneError. Class instances are cloned as plain objects. Shared and cyclic
+// references are preserved. ArrayBuffers are copied unless they appear in
+// the transfer list, in which case their memory is handed over without a
+// copy and the source buffer is detached.
+
+// cloneKind identifies the type of a cloned value
+type cloneKind int
+
+const (
+	cloneUndefined cloneKind = iota
+	cloneNull
+	clonePrimitive
+	cloneDate
+	cloneRegExp
+	cloneError
+	cloneArray
+	cloneObject
+	cloneMap
+	cloneSet
+	cloneArrayBuffer
+	cloneView
+	cloneShared
+)
+
+// cloneNode is a cloned value detached from any JavaScript runtime
+type cloneNode struct {
+	kind     cloneKind
+	value    interface{}  // primitive value, time for dates, *SharedBuffer for shared buffers
+	keys     []string     // object keys
+	children []*cloneNode // array elements, object values, map key/value pairs or set items
+	bytes    []byte       // array buffer contents
+	buffer   *cloneNode   // backing buffer of a view
+	typeName string       // view constructor, error name or regexp source
+	flags    string       // regexp flags
+	offset   int64
+	length   int64
+}
+
+// ClonedValue is a value copied out of a JavaScript runtime that can be
+// recreated in another runtime
+type ClonedValue struct {
+	root *cloneNode
+}
+
+// DataCloneError is returned when a value cannot be cloned
+type DataCloneError struct {
+	Message string
+}
+
+func (e *DataCloneError) Error() string {
+	return "DataCloneError: " + e.Message
+}
+
+// viewTypes are the ArrayBuffer views that can be cloned
+var viewTypes = map[string]bool{
+	"DataView":          true,
+	"Int8Array":         true,
+	"Uint8Array":        true,
+	"Uint8ClampedArray": true,
+	"Int16Array":        true,
+	"Uint16Array":       true,
+	"Int32Array":        true,
+	"Uint32Array":       true,
+	"Float32Array":      true,
+	"Float64Array":      true,
+	"BigInt64Array":     true,
+	"BigUint64Array":    true,
+}
+
+// cloner walks a value in its source runtime
+type cloner struct {
+	vm       *goja.Runtime
+	seen     map[*goja.Object]*cloneNode
+	transfer map[*goja.Object]bool
+}
+
+// StructuredClone copies value out of vm. ArrayBuffers in transfer are moved
+// rather than copied and detached in the source runtime.
+func StructuredClone(vm *goja.Runtime, value goja.Value, transfer ...goja.Value) (*ClonedValue, error) {
+	c := &cloner{
+		vm:       vm,
+		seen:     make(map[*goja.Object]*cloneNode),
+		transfer: make(map[*goja.Object]bool),
+	}
+
+	for _, t := range transfer {
+		obj, ok := t.(*goja.Object)
+		if !ok || constructorName(obj) != "ArrayBuffer" {
+			return nil, &DataCloneError{Message: "only ArrayBuffers can be transferred"}
+		}
+		c.transfer[obj] = true
+	}
+
+	root, err := c.clone(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Detach transferred buffers only once the clone has succeeded
+	for obj := range c.transfer {
+		if buf, ok := obj.Export().(goja.ArrayBuffer); ok {
+			buf.Detach()
+		}
+	}
+
+	return &ClonedValue{root: root}, nil
+}
+
+// clone clones a single value
+func (c *cloner) clone(value goja.Value) (*cloneNode, error) {
+	if value == nil || goja.IsUndefined(value) {
+		return &cloneNode{kind: cloneUndefined}, nil
+	}
+	if goja.IsNull(value) {
+		return &cloneNode{kind: cloneNull}, nil
+	}
+
+	obj, ok := value.(*goja.Object)
+	if !ok {
+		switch v := value.Export().(type) {
+		case bool, string, int64, float64, *big.Int:
+			return &cloneNode{kind: clonePrimitive, value: v}, nil
+		default:
+			return nil, &DataCloneError{Message: fmt.Sprintf("%s could not be cloned", value.String())}
+		}
+	}
+
+	if node, ok := c.seen[obj]; ok {
+		return node, nil
+	}
+
+	if _, ok := goja.AssertFunction(obj); ok {
+		return nil, &DataCloneError{Message: "functions could not be cloned"}
+	}
+
+	node := &cloneNode{}
+	c.seen[obj] = node
+
+	if buf, ok := SharedBufferOf(obj); ok {
+		node.kind = cloneShared
+		node.value = buf
+		return node, nil
+	}
+
+	switch obj.ClassName() {
+	case "Date":
+		node.kind = cloneDate
+		node.value = obj.Export()
+		return node, nil
+	case "RegExp":
+		node.kind = cloneRegExp
+		node.typeName = obj.Get("source").String()
+		node.flags = obj.Get("flags").String()
+		return node, nil
+	case "Error":
+		node.kind = cloneError
+		node.typeName = obj.Get("name").String()
+		node.value = obj.Get("message").String()
+		return node, nil
+	case "Array":
+		node.kind = cloneArray
+		length := obj.Get("length").ToInteger()
+		for i := int64(0); i < length; i++ {
+			child, err := c.clone(obj.Get(fmt.Sprintf("%d", i)))
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		}
+		return node, nil
+	}
+
+	name := constructorName(obj)
+	switch {
+	case name == "ArrayBuffer":
+		buf, ok := obj.Export().(goja.ArrayBuffer)
+		if !ok {
+			break
+		}
+		if buf.Detached() {
+			return nil, &DataCloneError{Message: "a detached ArrayBuffer could not be cloned"}
+		}
+		node.kind = cloneArrayBuffer
+		if c.transfer[obj] {
+			node.bytes = buf.Bytes()
+		} else {
+			node.bytes = append([]byte(nil), buf.Bytes()...)
+		}
+		return node, nil
+	case viewTypes[name]:
+		buffer, ok := obj.Get("buffer").(*goja.Object)
+		if !ok {
+			break
+		}
+		bufferNode, err := c.clone(buffer)
+		if err != nil {
+			return nil, err
+		}
+		node.kind = cloneView
+		node.typeName = name
+		node.buffer = bufferNode
+		node.offset = obj.Get("byteOffset").ToInteger()
+		if name == "DataView" {
+			node.length = obj.Get("byteLength").ToInteger()
+		} else {
+			node.length = obj.Get("length").ToInteger()
+		}
+		return node, nil
+	case name == "Map" || name == "Set":
+		items, err := c.iterate(obj)
+		if err != nil {
+			return nil, err
+		}
+		node.kind = cloneMap
+		if name == "Set" {
+			node.kind = cloneSet
+		}
+		for _, item := range items {
+			if name == "Map" {
+				pair := item.(*goja.Object)
+				key, err := c.clone(pair.Get("0"))
+				if err != nil {
+					return nil, err
+				}
+				val, err := c.clone(pair.Get("1"))
+				if err != nil {
+					return nil, err
+				}
+				node.children = append(node.children, key, val)
+				continue
+			}
+			child, err := c.clone(item)
+			if err != nil {
+				return nil, err
+			}
+			node.children = append(node.children, child)
+		}
+		return node, nil
+	}
+
+	// Plain object
+	node.kind = cloneObject
+	for _, key := range obj.Keys() {
+		child, err := c.clone(obj.Get(key))
+		if err != nil {
+			return nil, err
+		}
+		node.keys = append(node.keys, key)
+		node.children = append(node.children, child)
+	}
+	return node, nil
+}
+
+// iterate returns the entries of a Map or the items of a Set
+func (c *cloner) iterate(obj *goja.Object) ([]goja.Value, error) {
+	from, ok := goja.AssertFunction(c.vm.Get("Array").ToObject(c.vm).Get("from"))
+	if !ok {
+		return nil, &DataCloneError{Message: "Array.from is not available"}
+	}
+	result, err := from(goja.Undefined(), obj)
+	if err != nil {
+		return nil, err
+	}
+
+	array := result.ToObject(c.vm)
+	length := array.Get("length").ToInteger()
+	items := make([]goja.Value, 0, length)
+	for i := int64(0); i < length; i++ {
+		items = append(items, array.Get(fmt.Sprintf("%d", i)))
+	}
+	return items, nil
+}
+
+// Value recreates the cloned value in vm. Each call creates new objects;
+// transferred buffers share memory between the values created.
+func (cv *ClonedValue) Value(vm *goja.Runtime) (goja.Value, error) {
+	m := &materializer{vm: vm, built: make(map[*cloneNode]goja.Value)}
+	return m.build(cv.root)
+}
+
+// materializer recreates cloned values in a target runtime
+type materializer struct {
+	vm    *goja.Runtime
+	built map[*cloneNode]goja.Value
+}
+
+// build recreates a single node
+func (m *materializer) build(node *cloneNode) (goja.Value, error) {
+	if value, ok := m.built[node]; ok {
+		return value, nil
+	}
+
+	vm := m.vm
+	switch node.kind {
+	case cloneUndefined:
+		return goja.Undefined(), nil
+	case cloneNull:
+		return goja.Null(), nil
+	case clonePrimitive:
+		return vm.ToValue(node.value), nil
+	case cloneDate:
+		ms := node.value.(time.Time).UnixMilli()
+		return m.construct(node, "Date", vm.ToValue(ms))
+	case cloneRegExp:
+		return m.construct(node, "RegExp", vm.ToValue(node.typeName), vm.ToValue(node.flags))
+	case cloneError:
+		name := node.typeName
+		if ctor := vm.Get(name); ctor == nil || goja.IsUndefined(ctor) {
+			name = "Error"
+		}
+		return m.construct(node, name, vm.ToValue(node.value))
+	case cloneArrayBuffer:
+		value := vm.ToValue(vm.NewArrayBuffer(node.bytes))
+		m.built[node] = value
+		return value, nil
+	case cloneShared:
+		value := NewSharedBufferObject(vm, node.value.(*SharedBuffer))
+		m.built[node] = value
+		return value, nil
+	case cloneView:
+		buffer, err := m.build(node.buffer)
+		if err != nil {
+			return nil, err
+		}
+		return m.construct(node, node.typeName, buffer, vm.ToValue(node.offset), vm.ToValue(node.length))
+	case cloneArray:
+		array := vm.NewArray()
+		m.built[node] = array
+		for i, child := range node.children {
+			value, err := m.build(child)
+			if err != nil {
+				return nil, err
+			}
+			if err := array.Set(fmt.Sprintf("%d", i), value); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	case cloneObject:
+		obj := vm.NewObject()
+		m.built[node] = obj
+		for i, key := range node.keys {
+			value, err := m.build(node.children[i])
+			if err != nil {
+				return nil, err
+			}
+			if err := obj.Set(key, value); err != nil {
+				return nil, err
+			}
+		}
+		return obj, nil
+	case cloneMap, cloneSet:
+		ctorName, method, step := "Map", "set", 2
+		if node.kind == cloneSet {
+			ctorName, method, step = "Set", "add", 1
+		}
+		value, err := m.construct(node, ctorName)
+		if err != nil {
+			return nil, err
+		}
+		collection := value.(*goja.Object)
+		add, ok := goja.AssertFunction(collection.Get(method))
+		if !ok {
+			return nil, fmt.Errorf("%s.%s is not a function", ctorName, method)
+		}
+		for i := 0; i+step <= len(node.children); i += step {
+			args := make([]goja.Value, step)
+			for j := 0; j < step; j++ {
+				if args[j], err = m.build(node.children[i+j]); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := add(collection, args...); err != nil {
+				return nil, err
+			}
+		}
+		return collection, nil
+	default:
+		return nil, fmt.Errorf("unknown clone kind %d", node.kind)
+	}
+}
+
+// construct calls a global constructor and records the result for node
+func (m *materializer) construct(node *cloneNode, name string, args ...goja.Value) (goja.Value, error) {
+	ctor := m.vm.Get(name)
+	if ctor == nil || goja.IsUndefined(ctor) {
+		return nil, fmt.Errorf("%s is not defined", name)
+	}
+	obj, err := m.vm.New(ctor, args...)
+	if err != nil {
+		return nil, err
+	}
+	m.built[node] = obj
+	return obj, nil
+}
+
+// constructorName returns the name of an object's constructor
+func constructorName(obj *goja.Object) string {
+	ctor, ok := obj.Get("constructor").(*goja.Object)
+	if !ok {
+		return ""
+	}
+	name := ctor.Get("name")
+	if name == nil {
+		return ""
+	}
+	return name.String()
+}
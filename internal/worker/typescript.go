@@ -2,54 +2,80 @@ package worker
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
 	"sync"
 	"time"
 
 	"github.com/dop251/goja"
+
+	"gots-runtime/internal/eventloop"
 )
 
 // TypeScriptWorker provides TypeScript bindings for worker pool
 type TypeScriptWorker struct {
-	pool    *Pool
-	engine  *goja.Runtime
-	ctx     context.Context
-	cancel  context.CancelFunc
-	mu      sync.RWMutex
+	pool     *Pool
+	engine   *goja.Runtime
+	eventLoop *eventloop.Loop // owns engine; promises are settled on it
+	ctx      context.Context
+	cancel   context.CancelFunc
+	runtimes []*goja.Runtime // idle worker runtimes
+	setup    func(vm *goja.Runtime)
+	onCrash  func(*TaskCrashError)
+	crashed  int
+	mu       sync.RWMutex
 }
 
-// NewTypeScriptWorker creates a new TypeScript worker wrapper
-func NewTypeScriptWorker(ctx context.Context, engine *goja.Runtime, minWorkers, maxWorkers int) *TypeScriptWorker {
+// NewTypeScriptWorker creates a new TypeScript worker wrapper. Tasks run in
+// the pool's goroutines and their promises are settled on eventLoop, which
+// runs the code using engine.
+func NewTypeScriptWorker(ctx context.Context, engine *goja.Runtime, eventLoop *eventloop.Loop, minWorkers, maxWorkers int) *TypeScriptWorker {
 	workerCtx, cancel := context.WithCancel(ctx)
 	pool := NewPool(workerCtx, minWorkers, maxWorkers)
 	pool.Start()
 	
-	return &TypeScriptWorker{
-		pool:   pool,
-		engine: engine,
-		ctx:    workerCtx,
-		cancel: cancel,
+	tw := &TypeScriptWorker{
+		pool:      pool,
+		engine:    engine,
+		eventLoop: eventLoop,
+		ctx:       workerCtx,
+		cancel:    cancel,
 	}
+	pool.SetCrashHandler(tw.recordCrash)
+	return tw
 }
 
-// Spawn executes a task in a worker and returns a promise
-func (tw *TypeScriptWorker) Spawn(taskID string, handler goja.Callable, data goja.Value) *goja.Promise {
+// Spawn executes a task in a worker and returns a promise. The handler
+// runs in a worker runtime of its own; the data is structured-cloned when
+// the task is spawned, and ArrayBuffers listed in transfer are moved to the
+// task instead of copied.
+func (tw *TypeScriptWorker) Spawn(taskID string, handler goja.Value, data goja.Value, transfer ...goja.Value) *goja.Promise {
 	promise, resolve, reject := tw.engine.NewPromise()
 	
+	compiled, err := CompileHandler(handler)
+	if err != nil {
+		reject(tw.engine.ToValue(err.Error()))
+		return promise
+	}
+	input, err := StructuredClone(tw.engine, data, transfer...)
+	if err != nil {
+		reject(tw.engine.ToValue(err.Error()))
+		return promise
+	}
+	
+	settle := tw.settler()
 	go func() {
+		var output *ClonedValue
+		
 		// Create a task that executes the handler
 		task := NewTask(
 			taskID,
 			func(ctx context.Context) error {
 				// Call the TypeScript handler with the data
-				result, err := handler(nil, data)
+				var err error
+				output, err = tw.call(compiled, input)
 				if err != nil {
 					return fmt.Errorf("handler error: %w", err)
 				}
-				
-				// Store result in task context (we'll need to modify this)
-				_ = result
 				return nil
 			},
 			true, // CPU intensive
@@ -58,96 +84,161 @@ func (tw *TypeScriptWorker) Spawn(taskID string, handler goja.Callable, data goj
 		
 		// Submit task to pool
 		if err := tw.pool.Submit(task); err != nil {
-			reject(tw.engine.ToValue(err.Error()))
+			settle(func() { reject(tw.engine.ToValue(err.Error())) })
 			return
 		}
 		
 		// Wait for result
 		select {
-		case result := <-tw.pool.ResultChan():
-			if result.Error != nil {
-				reject(tw.engine.ToValue(result.Error.Error()))
-			} else {
+		case result := <-task.Done():
+			settle(func() {
+				if result.Error != nil {
+					reject(tw.engine.ToValue(result.Error.Error()))
+					return
+				}
 				// Create result object
 				resultObj := tw.engine.NewObject()
 				resultObj.Set("id", result.TaskID)
-				resultObj.Set("data", nil) // We need to store the actual result
+				resultObj.Set("data", tw.restore(output))
 				resultObj.Set("duration", result.Duration.Milliseconds())
 				resolve(resultObj)
-			}
+			})
 		case <-tw.ctx.Done():
-			reject(tw.engine.ToValue("worker pool closed"))
+			settle(func() { reject(tw.engine.ToValue("worker pool closed")) })
 		case <-time.After(30 * time.Second):
-			reject(tw.engine.ToValue("task timeout"))
+			settle(func() { reject(tw.engine.ToValue("task timeout")) })
 		}
 	}()
 	
 	return promise
 }
 
-// SpawnBatch executes multiple tasks in parallel
+// settler references the event loop until the returned function is called
+// with a function that settles a promise, which is then run on the loop.
+// Goroutines pass only Go values to it, as the engine must only be used on
+// the loop; the reference keeps the loop from going idle while they work.
+func (tw *TypeScriptWorker) settler() func(settle func()) {
+	tw.eventLoop.Ref()
+	var once sync.Once
+	return func(settle func()) {
+		once.Do(func() {
+			err := tw.eventLoop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+				defer tw.eventLoop.Unref()
+				settle()
+				return nil
+			}, eventloop.PriorityNormal))
+			if err != nil {
+				// The loop stopped; nothing can observe the promise anymore
+				tw.eventLoop.Unref()
+			}
+		})
+	}
+}
+
+// restore recreates a task result, or null if the task produced none
+func (tw *TypeScriptWorker) restore(output *ClonedValue) goja.Value {
+	if output == nil {
+		return goja.Null()
+	}
+	value, err := output.Value(tw.engine)
+	if err != nil {
+		return goja.Null()
+	}
+	return value
+}
+
+// SpawnBatch executes multiple tasks in parallel. The promise resolves to
+// the results in the order of tasks, whatever order the tasks finish in.
+// Handlers are compiled and data cloned before SpawnBatch returns; tasks are
+// submitted as the pool's queue has room, so a large batch waits for
+// capacity instead of failing.
 func (tw *TypeScriptWorker) SpawnBatch(tasks []interface{}) *goja.Promise {
 	promise, resolve, reject := tw.engine.NewPromise()
 	
-	go func() {
-		results := make([]interface{}, 0, len(tasks))
+	// batchTask is a submitted task and the output of its handler
+	type batchTask struct {
+		task   *Task
+		output *ClonedValue
+	}
+	
+	batch := make([]*batchTask, len(tasks))
+	for i, taskVal := range tasks {
+		taskObj, ok := taskVal.(*goja.Object)
+		if !ok {
+			reject(tw.engine.ToValue(fmt.Sprintf("task %d is not an object", i)))
+			return promise
+		}
 		
-		for i, taskVal := range tasks {
-			taskObj, ok := taskVal.(*goja.Object)
-			if !ok {
-				reject(tw.engine.ToValue(fmt.Sprintf("task %d is not an object", i)))
+		taskID := taskObj.Get("id").String()
+		handler, err := CompileHandler(taskObj.Get("handler"))
+		if err != nil {
+			reject(tw.engine.ToValue(fmt.Sprintf("task %d: %v", i, err)))
+			return promise
+		}
+		input, err := StructuredClone(tw.engine, taskObj.Get("data"))
+		if err != nil {
+			reject(tw.engine.ToValue(fmt.Sprintf("task %d: %v", i, err)))
+			return promise
+		}
+		
+		// Create task
+		bt := &batchTask{}
+		bt.task = NewTask(
+			taskID,
+			func(ctx context.Context) error {
+				var err error
+				bt.output, err = tw.call(handler, input)
+				return err
+			},
+			true,
+			0,
+		)
+		batch[i] = bt
+	}
+	
+	settle := tw.settler()
+	go func() {
+		for i, bt := range batch {
+			// Submit task; this blocks while the pool's queue is full
+			if err := tw.pool.Submit(bt.task); err != nil {
+				settle(func() {
+					reject(tw.engine.ToValue(fmt.Sprintf("failed to submit task %d: %v", i, err)))
+				})
 				return
 			}
-			
-			taskID := taskObj.Get("id").String()
-			handlerVal := taskObj.Get("handler")
-			handler, ok := goja.AssertFunction(handlerVal)
-			if !ok {
-				reject(tw.engine.ToValue(fmt.Sprintf("task %d handler is not a function", i)))
+		}
+		
+		// Collect results in submission order
+		results := make([]*TaskResult, len(batch))
+		timeout := time.After(30 * time.Second)
+		for i, bt := range batch {
+			select {
+			case results[i] = <-bt.task.Done():
+			case <-tw.ctx.Done():
+				settle(func() { reject(tw.engine.ToValue("worker pool closed")) })
 				return
-			}
-			data := taskObj.Get("data")
-			
-			// Create task
-			task := NewTask(
-				taskID,
-				func(ctx context.Context) error {
-					_, err := handler(nil, data)
-					return err
-				},
-				true,
-				0,
-			)
-			
-			// Submit task
-			if err := tw.pool.Submit(task); err != nil {
-				reject(tw.engine.ToValue(fmt.Sprintf("failed to submit task %d: %v", i, err)))
+			case <-timeout:
+				settle(func() { reject(tw.engine.ToValue("task timeout")) })
 				return
 			}
 		}
 		
-		// Collect results
-		for i := 0; i < len(tasks); i++ {
-			select {
-			case result := <-tw.pool.ResultChan():
+		settle(func() {
+			values := make([]interface{}, len(results))
+			for i, result := range results {
 				resultObj := tw.engine.NewObject()
 				resultObj.Set("id", result.TaskID)
-				resultObj.Set("data", nil)
-				resultObj.Set("duration", result.Duration.Milliseconds())
 				if result.Error != nil {
+					resultObj.Set("data", goja.Null())
 					resultObj.Set("error", tw.engine.ToValue(result.Error.Error()))
+				} else {
+					resultObj.Set("data", tw.restore(batch[i].output))
 				}
-				results = append(results, resultObj)
-			case <-tw.ctx.Done():
-				reject(tw.engine.ToValue("worker pool closed"))
-				return
-			case <-time.After(30 * time.Second):
-				reject(tw.engine.ToValue("task timeout"))
-				return
+				resultObj.Set("duration", result.Duration.Milliseconds())
+				values[i] = resultObj
 			}
-		}
-		
-		resolve(tw.engine.ToValue(results))
+			resolve(tw.engine.ToValue(values))
+		})
 	}()
 	
 	return promise
@@ -155,21 +246,17 @@ func (tw *TypeScriptWorker) SpawnBatch(tasks []interface{}) *goja.Promise {
 
 // GetStats returns worker pool statistics
 func (tw *TypeScriptWorker) GetStats() map[string]interface{} {
+	stats := tw.pool.GetStats()
 	tw.mu.RLock()
-	defer tw.mu.RUnlock()
-	
-	busy := 0
-	for _, w := range tw.pool.workers {
-		if w.IsBusy() {
-			busy++
-		}
-	}
+	crashed := tw.crashed
+	tw.mu.RUnlock()
 	
 	return map[string]interface{}{
-		"totalWorkers": tw.pool.currentWorkers,
-		"busyWorkers":  busy,
-		"idleWorkers":  tw.pool.currentWorkers - busy,
-		"queuedTasks":  len(tw.pool.taskQueue),
+		"totalWorkers": stats.CurrentWorkers,
+		"busyWorkers":  stats.BusyWorkers,
+		"idleWorkers":  stats.CurrentWorkers - stats.BusyWorkers,
+		"queuedTasks":  stats.QueueSize,
+		"crashedTasks": crashed,
 	}
 }
 
@@ -181,25 +268,7 @@ func (tw *TypeScriptWorker) Close() error {
 }
 
 // SpawnWorker is a convenience function to spawn a single worker task
-func SpawnWorker(ctx context.Context, engine *goja.Runtime, taskID string, handler goja.Callable, data goja.Value) *goja.Promise {
-	worker := NewTypeScriptWorker(ctx, engine, 1, 1)
+func SpawnWorker(ctx context.Context, engine *goja.Runtime, eventLoop *eventloop.Loop, taskID string, handler goja.Value, data goja.Value) *goja.Promise {
+	worker := NewTypeScriptWorker(ctx, engine, eventLoop, 1, 1)
 	return worker.Spawn(taskID, handler, data)
 }
-
-// Helper function to serialize/deserialize data for worker tasks
-func serializeData(data goja.Value) (string, error) {
-	jsonData, err := json.Marshal(data.Export())
-	if err != nil {
-		return "", fmt.Errorf("failed to serialize data: %w", err)
-	}
-	return string(jsonData), nil
-}
-
-func deserializeData(jsonStr string) (interface{}, error) {
-	var data interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &data); err != nil {
-		return nil, fmt.Errorf("failed to deserialize data: %w", err)
-	}
-	return data, nil
-}
-
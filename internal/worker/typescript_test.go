@@ -0,0 +1,180 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/dop251/goja"
+
+	"gots-runtime/internal/eventloop"
+)
+
+// newTestWorker creates a worker pool for a runtime whose event loop runs
+func newTestWorker(t *testing.T, minWorkers, maxWorkers int) (*TypeScriptWorker, *goja.Runtime, *eventloop.Loop) {
+	t.Helper()
+	vm := goja.New()
+	loop := eventloop.NewLoop(context.Background())
+	loop.Start()
+	tw := NewTypeScriptWorker(context.Background(), vm, loop, minWorkers, maxWorkers)
+	t.Cleanup(func() {
+		tw.Close()
+		loop.Stop()
+	})
+	return tw, vm, loop
+}
+
+// taskResult is the result of a spawned task, decoded from JSON
+type taskResult struct {
+	ID   string `json:"id"`
+	Data int    `json:"data"`
+}
+
+// awaitOnLoop calls start on the event loop, which owns vm, and waits for
+// the promise it returns to settle there. It decodes the JSON of the value
+// the promise fulfilled with into result, or fails the test.
+func awaitOnLoop(t *testing.T, vm *goja.Runtime, loop *eventloop.Loop, result interface{}, start func() (goja.Value, error)) {
+	t.Helper()
+	fulfilled := make(chan string, 1)
+	rejected := make(chan string, 1)
+	err := loop.Enqueue(eventloop.NewEvent(eventloop.EventIO, func() error {
+		promise, err := start()
+		if err != nil {
+			rejected <- err.Error()
+			return nil
+		}
+		obj := promise.ToObject(vm)
+		then, _ := goja.AssertFunction(obj.Get("then"))
+		stringify, _ := goja.AssertFunction(vm.Get("JSON").ToObject(vm).Get("stringify"))
+		onFulfilled := func(value goja.Value) {
+			encoded, err := stringify(goja.Undefined(), value)
+			if err != nil {
+				rejected <- err.Error()
+				return
+			}
+			fulfilled <- encoded.String()
+		}
+		onRejected := func(reason goja.Value) { rejected <- reason.String() }
+		if _, err := then(obj, vm.ToValue(onFulfilled), vm.ToValue(onRejected)); err != nil {
+			rejected <- err.Error()
+		}
+		return nil
+	}, eventloop.PriorityNormal))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case encoded := <-fulfilled:
+		if err := json.Unmarshal([]byte(encoded), result); err != nil {
+			t.Fatalf("decoding %s: %v", encoded, err)
+		}
+	case reason := <-rejected:
+		t.Fatalf("promise rejected: %s", reason)
+	case <-time.After(10 * time.Second):
+		t.Fatal("promise did not settle")
+	}
+}
+
+func TestSpawnBatchKeepsTaskOrder(t *testing.T) {
+	tw, vm, loop := newTestWorker(t, 4, 4)
+
+	// Earlier tasks run longer, so they finish after later ones
+	const count = 8
+	var results []taskResult
+	awaitOnLoop(t, vm, loop, &results, func() (goja.Value, error) {
+		tasks, err := vm.RunString(fmt.Sprintf(`
+			const handler = (data) => {
+				const end = Date.now() + data.ms;
+				while (Date.now() < end) {}
+				return data.index * 10;
+			};
+			Array.from({ length: %d }, (_, index) => ({
+				id: 'task-' + index,
+				handler,
+				data: { index, ms: (%d - index) * 5 },
+			}));
+		`, count, count))
+		if err != nil {
+			return nil, err
+		}
+		var taskValues []goja.Value
+		if err := vm.ExportTo(tasks, &taskValues); err != nil {
+			return nil, err
+		}
+		batch := make([]interface{}, len(taskValues))
+		for i, task := range taskValues {
+			batch[i] = task
+		}
+		return vm.ToValue(tw.SpawnBatch(batch)), nil
+	})
+
+	if len(results) != count {
+		t.Fatalf("got %d results, want %d", len(results), count)
+	}
+	for i, result := range results {
+		if want := fmt.Sprintf("task-%d", i); result.ID != want {
+			t.Errorf("results[%d] is %s, want %s", i, result.ID, want)
+		}
+		if result.Data != i*10 {
+			t.Errorf("results[%d].data = %d, want %d", i, result.Data, i*10)
+		}
+	}
+}
+
+func TestSpawnRunsMoreTasksThanWorkers(t *testing.T) {
+	tw, vm, loop := newTestWorker(t, 2, 2)
+
+	const count = 50
+	var results []taskResult
+	awaitOnLoop(t, vm, loop, &results, func() (goja.Value, error) {
+		handler, err := vm.RunString(`(n) => n * 2`)
+		if err != nil {
+			return nil, err
+		}
+		promises := make([]interface{}, count)
+		for i := range promises {
+			promises[i] = tw.Spawn(fmt.Sprintf("task-%d", i), handler, vm.ToValue(i))
+		}
+		all, _ := goja.AssertFunction(vm.Get("Promise").ToObject(vm).Get("all"))
+		return all(vm.Get("Promise"), vm.ToValue(promises))
+	})
+
+	if len(results) != count {
+		t.Fatalf("got %d results, want %d", len(results), count)
+	}
+	for i, result := range results {
+		if result.ID != fmt.Sprintf("task-%d", i) || result.Data != i*2 {
+			t.Errorf("results[%d] = %+v, want task-%d with %d", i, result, i, i*2)
+		}
+	}
+}
+
+func TestSpawnArrowHandlerWithObjectLiteralBody(t *testing.T) {
+	tw, vm, loop := newTestWorker(t, 1, 1)
+
+	var result struct {
+		ID   string `json:"id"`
+		Data struct {
+			V    int   `json:"v"`
+			List []int `json:"list"`
+		} `json:"data"`
+	}
+	awaitOnLoop(t, vm, loop, &result, func() (goja.Value, error) {
+		handler, err := vm.RunString(`(d) => ({ v: d.x * 2, list: [(d.x)] })`)
+		if err != nil {
+			return nil, err
+		}
+		data, err := vm.RunString(`({ x: 21 })`)
+		if err != nil {
+			return nil, err
+		}
+		return vm.ToValue(tw.Spawn("task", handler, data)), nil
+	})
+
+	if result.Data.V != 42 || len(result.Data.List) != 1 || result.Data.List[0] != 21 {
+		t.Errorf("data = %+v, want v 42 and list [21]", result.Data)
+	}
+}
@@ -15,6 +15,10 @@ type Worker struct {
 	cancel   context.CancelFunc
 	wg       sync.WaitGroup
 	busy     bool
+	stopped  bool
+	idle     chan struct{}
+	onIdle   func()
+	onCrash  func(*TaskCrashError)
 	mu       sync.RWMutex
 }
 
@@ -28,6 +32,7 @@ func NewWorker(id int, ctx context.Context) *Worker {
 		ctx:        workerCtx,
 		cancel:     cancel,
 		busy:       false,
+		idle:       make(chan struct{}, 1),
 	}
 }
 
@@ -39,19 +44,80 @@ func (w *Worker) Start() {
 
 // Stop stops the worker
 func (w *Worker) Stop() {
+	w.mu.Lock()
+	w.stopLocked()
+	w.mu.Unlock()
+	
+	w.wg.Wait()
+}
+
+// stopLocked cancels the worker and closes its task channel. Must be called
+// with w.mu held.
+func (w *Worker) stopLocked() {
+	if w.stopped {
+		return
+	}
+	w.stopped = true
 	w.cancel()
 	close(w.taskChan)
-	w.wg.Wait()
 }
 
-// AssignTask assigns a task to the worker
+// AssignTask assigns a task to the worker, blocking until the worker is idle
 func (w *Worker) AssignTask(task *Task) error {
-	select {
-	case w.taskChan <- task:
-		return nil
-	case <-w.ctx.Done():
-		return w.ctx.Err()
+	for {
+		if w.TryAssignTask(task) {
+			return nil
+		}
+		
+		select {
+		case <-w.idle:
+		case <-w.ctx.Done():
+			return w.ctx.Err()
+		}
+	}
+}
+
+// TryAssignTask assigns a task if the worker is idle. The worker is marked
+// busy as soon as the task is accepted, so an idle worker never has a task
+// waiting in its queue.
+func (w *Worker) TryAssignTask(task *Task) bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	
+	if w.busy || w.stopped {
+		return false
 	}
+	w.busy = true
+	w.taskChan <- task
+	return true
+}
+
+// SetIdleCallback sets a function called whenever the worker finishes a task
+func (w *Worker) SetIdleCallback(callback func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onIdle = callback
+}
+
+// SetCrashCallback sets a function called whenever a task panics
+func (w *Worker) SetCrashCallback(callback func(*TaskCrashError)) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.onCrash = callback
+}
+
+// retire stops the worker if it is idle and reports whether it was stopped
+func (w *Worker) retire() bool {
+	w.mu.Lock()
+	if w.busy || w.stopped {
+		w.mu.Unlock()
+		return false
+	}
+	w.stopLocked()
+	w.mu.Unlock()
+	
+	w.wg.Wait()
+	return true
 }
 
 // IsBusy returns whether the worker is currently busy
@@ -83,21 +149,28 @@ func (w *Worker) run() {
 	}
 }
 
-// executeTask executes a task
+// executeTask executes a task. A panicking task fails with a
+// TaskCrashError and does not stop the worker.
 func (w *Worker) executeTask(task *Task) {
-	w.mu.Lock()
-	w.busy = true
-	w.mu.Unlock()
-
 	start := time.Now()
-	err := task.Execute(w.ctx)
+	err := runTask(w.ctx, task)
 	duration := time.Since(start)
+	
+	if crash, ok := err.(*TaskCrashError); ok {
+		w.mu.RLock()
+		onCrash := w.onCrash
+		w.mu.RUnlock()
+		if onCrash != nil {
+			onCrash(crash)
+		}
+	}
 
 	result := &TaskResult{
 		TaskID:   task.ID,
 		Error:    err,
 		Duration: duration,
 	}
+	task.finish(result)
 
 	select {
 	case w.resultChan <- result:
@@ -106,6 +179,15 @@ func (w *Worker) executeTask(task *Task) {
 
 	w.mu.Lock()
 	w.busy = false
+	onIdle := w.onIdle
 	w.mu.Unlock()
+
+	select {
+	case w.idle <- struct{}{}:
+	default:
+	}
+	if onIdle != nil {
+		onIdle()
+	}
 }
 
@@ -0,0 +1,356 @@
+package debugger
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"sync"
+)
+
+// DefaultInspectAddr is the address the DAP server listens on by default
+const DefaultInspectAddr = "127.0.0.1:9229"
+
+// dapThreadID is the ID of the only thread reported to clients: the
+// JavaScript event loop
+const dapThreadID = 1
+
+// Evaluator evaluates an expression for a DAP evaluate request and returns
+// the result as text
+type Evaluator func(expression string) (string, error)
+
+// dapMessage is a Debug Adapter Protocol request, response or event
+type dapMessage struct {
+	Seq        int             `json:"seq"`
+	Type       string          `json:"type"`
+	Command    string          `json:"command,omitempty"`
+	Arguments  json.RawMessage `json:"arguments,omitempty"`
+	RequestSeq int             `json:"request_seq,omitempty"`
+	Success    *bool           `json:"success,omitempty"`
+	Message    string          `json:"message,omitempty"`
+	Event      string          `json:"event,omitempty"`
+	Body       interface{}     `json:"body,omitempty"`
+}
+
+// DAPServer serves the Debug Adapter Protocol to one IDE client at a time.
+// The engine has no line-level hooks, so breakpoints set by the client are
+// reported as unverified; the program can be stopped on entry, where the
+// client may evaluate expressions before continuing it.
+type DAPServer struct {
+	listener   net.Listener
+	file       string
+	evaluate   Evaluator
+	conn       net.Conn
+	seq        int
+	configured chan struct{} // closed on configurationDone
+	resume     chan struct{} // closed on continue or disconnect
+	jobs       chan func()   // work to run on the program's goroutine while stopped
+	stopped    bool
+	mu         sync.Mutex
+	writeMu    sync.Mutex
+}
+
+// NewDAPServer starts a DAP server on addr for debugging file; evaluate
+// serves evaluate requests while the program is stopped
+func NewDAPServer(addr, file string, evaluate Evaluator) (*DAPServer, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+
+	s := &DAPServer{
+		listener:   listener,
+		file:       file,
+		evaluate:   evaluate,
+		configured: make(chan struct{}),
+		resume:     make(chan struct{}),
+		jobs:       make(chan func()),
+	}
+	go s.accept()
+	return s, nil
+}
+
+// Addr returns the address the server listens on
+func (s *DAPServer) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+// WaitForClient blocks until a client has connected and finished
+// configuring the session with configurationDone
+func (s *DAPServer) WaitForClient(ctx context.Context) error {
+	select {
+	case <-s.configured:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// StopOnEntry reports the program as stopped before its first statement and
+// blocks until the client continues it or disconnects. Evaluate requests
+// are served meanwhile on the calling goroutine.
+func (s *DAPServer) StopOnEntry(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		s.stopped = false
+		s.mu.Unlock()
+	}()
+
+	s.sendEvent("stopped", map[string]interface{}{
+		"reason":            "entry",
+		"threadId":          dapThreadID,
+		"allThreadsStopped": true,
+	})
+
+	for {
+		select {
+		case job := <-s.jobs:
+			job()
+		case <-s.resume:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Exited tells the client that the program exited with code and ends the
+// session
+func (s *DAPServer) Exited(code int) {
+	s.sendEvent("exited", map[string]interface{}{"exitCode": code})
+	s.sendEvent("terminated", nil)
+}
+
+// Close stops the server and disconnects the client
+func (s *DAPServer) Close() error {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+	if conn != nil {
+		conn.Close()
+	}
+	return s.listener.Close()
+}
+
+// accept serves clients one after another until the server is closed
+func (s *DAPServer) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conn = conn
+		s.mu.Unlock()
+
+		s.serve(conn)
+		conn.Close()
+	}
+}
+
+// serve reads and handles the requests of a client until it disconnects
+func (s *DAPServer) serve(conn net.Conn) {
+	reader := bufio.NewReader(conn)
+	for {
+		msg, err := readDAPMessage(reader)
+		if err != nil {
+			// A client that goes away must not leave the program stopped
+			s.continueProgram()
+			return
+		}
+		if msg.Type != "request" {
+			continue
+		}
+		if !s.handle(msg) {
+			return
+		}
+	}
+}
+
+// handle answers a request; it returns false once the client disconnects
+func (s *DAPServer) handle(req *dapMessage) bool {
+	switch req.Command {
+	case "initialize":
+		s.respond(req, map[string]interface{}{
+			"supportsConfigurationDoneRequest": true,
+		})
+		s.sendEvent("initialized", nil)
+	case "launch", "attach", "setExceptionBreakpoints", "pause":
+		s.respond(req, nil)
+	case "setBreakpoints":
+		var args struct {
+			Breakpoints []struct {
+				Line int `json:"line"`
+			} `json:"breakpoints"`
+		}
+		_ = json.Unmarshal(req.Arguments, &args)
+		breakpoints := make([]map[string]interface{}, len(args.Breakpoints))
+		for i, bp := range args.Breakpoints {
+			breakpoints[i] = map[string]interface{}{
+				"verified": false,
+				"line":     bp.Line,
+				"message":  "line breakpoints are not supported by the runtime",
+			}
+		}
+		s.respond(req, map[string]interface{}{"breakpoints": breakpoints})
+	case "configurationDone":
+		s.respond(req, nil)
+		select {
+		case <-s.configured:
+		default:
+			close(s.configured)
+		}
+	case "threads":
+		s.respond(req, map[string]interface{}{
+			"threads": []map[string]interface{}{{"id": dapThreadID, "name": "main"}},
+		})
+	case "stackTrace":
+		frames := []map[string]interface{}{}
+		if s.isStopped() {
+			frames = append(frames, map[string]interface{}{
+				"id":     1,
+				"name":   "(entry)",
+				"source": map[string]interface{}{"path": s.file},
+				"line":   1,
+				"column": 1,
+			})
+		}
+		s.respond(req, map[string]interface{}{"stackFrames": frames, "totalFrames": len(frames)})
+	case "scopes":
+		s.respond(req, map[string]interface{}{"scopes": []interface{}{}})
+	case "evaluate":
+		s.handleEvaluate(req)
+	case "continue":
+		s.respond(req, map[string]interface{}{"allThreadsContinued": true})
+		s.continueProgram()
+	case "disconnect", "terminate":
+		s.respond(req, nil)
+		s.continueProgram()
+		return false
+	default:
+		s.fail(req, fmt.Sprintf("unsupported request %q", req.Command))
+	}
+	return true
+}
+
+// handleEvaluate evaluates an expression on the program's goroutine, which
+// is only possible while the program is stopped
+func (s *DAPServer) handleEvaluate(req *dapMessage) {
+	var args struct {
+		Expression string `json:"expression"`
+	}
+	if err := json.Unmarshal(req.Arguments, &args); err != nil {
+		s.fail(req, "invalid evaluate arguments")
+		return
+	}
+	if s.evaluate == nil || !s.isStopped() {
+		s.fail(req, "expressions can only be evaluated while the program is stopped")
+		return
+	}
+
+	done := make(chan struct{})
+	var result string
+	var err error
+	select {
+	case s.jobs <- func() {
+		result, err = s.evaluate(args.Expression)
+		close(done)
+	}:
+		<-done
+	case <-s.resume:
+		err = errors.New("the program is running")
+	}
+	if err != nil {
+		s.fail(req, err.Error())
+		return
+	}
+	s.respond(req, map[string]interface{}{"result": result, "variablesReference": 0})
+}
+
+// continueProgram lets a program stopped on entry run
+func (s *DAPServer) continueProgram() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	select {
+	case <-s.resume:
+	default:
+		close(s.resume)
+	}
+	s.stopped = false
+}
+
+// isStopped reports whether the program is stopped on entry
+func (s *DAPServer) isStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+// respond sends a successful response to req
+func (s *DAPServer) respond(req *dapMessage, body interface{}) {
+	success := true
+	s.send(&dapMessage{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: &success, Body: body})
+}
+
+// fail sends an error response to req
+func (s *DAPServer) fail(req *dapMessage, message string) {
+	success := false
+	s.send(&dapMessage{Type: "response", RequestSeq: req.Seq, Command: req.Command, Success: &success, Message: message})
+}
+
+// sendEvent sends an event to the client, if one is connected
+func (s *DAPServer) sendEvent(event string, body interface{}) {
+	s.send(&dapMessage{Type: "event", Event: event, Body: body})
+}
+
+// send writes msg to the connected client; messages are dropped while no
+// client is connected
+func (s *DAPServer) send(msg *dapMessage) {
+	s.mu.Lock()
+	conn := s.conn
+	s.mu.Unlock()
+	if conn == nil {
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	msg.Seq = s.seq
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	_, _ = fmt.Fprintf(conn, "Content-Length: %d\r\n\r\n%s", len(data), data)
+}
+
+// readDAPMessage reads a message framed with a Content-Length header
+func readDAPMessage(reader *bufio.Reader) (*dapMessage, error) {
+	header, err := textproto.NewReader(reader).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid Content-Length header")
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(reader, data); err != nil {
+		return nil, err
+	}
+	var msg dapMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	return &msg, nil
+}
@@ -0,0 +1,116 @@
+package testrunner
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dop251/goja"
+)
+
+// topLevelTest names snapshots taken outside of a test() block
+const topLevelTest = "(top level)"
+
+// testContext provides the test and expect globals of one test file and
+// records failing tests
+type testContext struct {
+	vm        *goja.Runtime
+	snapshots *snapshotFile
+	current   string
+	counts    map[string]int
+	failures  []string
+}
+
+// newTestContext creates the test globals for a file
+func newTestContext(vm *goja.Runtime, snapshots *snapshotFile) *testContext {
+	return &testContext{
+		vm:        vm,
+		snapshots: snapshots,
+		current:   topLevelTest,
+		counts:    make(map[string]int),
+	}
+}
+
+// register sets the test and expect globals
+func (tc *testContext) register() {
+	tc.vm.Set("test", tc.test)
+	tc.vm.Set("expect", tc.expect)
+}
+
+// test runs a named test, recording it as failed if it throws
+func (tc *testContext) test(name string, fn goja.Callable) {
+	previous := tc.current
+	tc.current = name
+	defer func() { tc.current = previous }()
+
+	if _, err := fn(goja.Undefined()); err != nil {
+		message := err.Error()
+		if exception, ok := err.(*goja.Exception); ok {
+			message = exception.Value().String()
+		}
+		tc.failures = append(tc.failures, fmt.Sprintf("%s: %s", name, message))
+	}
+}
+
+// expect creates the assertion object for a value
+func (tc *testContext) expect(value goja.Value) *goja.Object {
+	obj := tc.vm.NewObject()
+
+	obj.Set("toBe", func(expected goja.Value) {
+		if !value.SameAs(expected) {
+			tc.fail("expected %s to be %s", tc.serialize(value), tc.serialize(expected))
+		}
+	})
+
+	obj.Set("toEqual", func(expected goja.Value) {
+		if tc.serialize(value) != tc.serialize(expected) {
+			tc.fail("expected %s to equal %s", tc.serialize(value), tc.serialize(expected))
+		}
+	})
+
+	obj.Set("toMatchSnapshot", func() {
+		tc.counts[tc.current]++
+		key := fmt.Sprintf("%s %d", tc.current, tc.counts[tc.current])
+
+		received := tc.serialize(value)
+		if ok, stored := tc.snapshots.match(key, received); !ok {
+			tc.fail("snapshot %q does not match (run with --update-snapshots to update)\n--- snapshot\n%s\n+++ received\n%s", key, stored, received)
+		}
+	})
+
+	return obj
+}
+
+// fail throws an AssertionError in the test file
+func (tc *testContext) fail(format string, args ...interface{}) {
+	assertionError, err := tc.vm.New(tc.vm.Get("Error"), tc.vm.ToValue(fmt.Sprintf(format, args...)))
+	if err != nil {
+		panic(err)
+	}
+	assertionError.Set("name", "AssertionError")
+	panic(assertionError)
+}
+
+// serialize converts a value to its snapshot form
+func (tc *testContext) serialize(value goja.Value) string {
+	if value == nil || goja.IsUndefined(value) {
+		return "undefined"
+	}
+	if _, ok := goja.AssertFunction(value); ok {
+		return "[Function]"
+	}
+
+	stringify, _ := goja.AssertFunction(tc.vm.Get("JSON").ToObject(tc.vm).Get("stringify"))
+	result, err := stringify(goja.Undefined(), value, goja.Null(), tc.vm.ToValue(2))
+	if err != nil || goja.IsUndefined(result) {
+		return value.String()
+	}
+	return result.String()
+}
+
+// err returns an error describing the failed tests, or nil
+func (tc *testContext) err() error {
+	if len(tc.failures) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d test(s) failed:\n  %s", len(tc.failures), strings.Join(tc.failures, "\n  "))
+}
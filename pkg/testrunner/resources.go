@@ -0,0 +1,106 @@
+package testrunner
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/dop251/goja"
+)
+
+// sharedResources hands out resources that concurrently running test files
+// must not share
+type sharedResources struct {
+	ports map[int]bool
+	mu    sync.Mutex
+}
+
+// newSharedResources creates an empty resource registry
+func newSharedResources() *sharedResources {
+	return &sharedResources{
+		ports: make(map[int]bool),
+	}
+}
+
+// freePort returns a free TCP port that has not been handed to another
+// test file during this run
+func (sr *sharedResources) freePort() (int, error) {
+	sr.mu.Lock()
+	defer sr.mu.Unlock()
+
+	for attempt := 0; attempt < 100; attempt++ {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			return 0, fmt.Errorf("failed to find a free port: %w", err)
+		}
+		port := listener.Addr().(*net.TCPAddr).Port
+		listener.Close()
+
+		if !sr.ports[port] {
+			sr.ports[port] = true
+			return port, nil
+		}
+	}
+	return 0, fmt.Errorf("failed to find a free port")
+}
+
+// testHelpers are the per-file helpers exposed as the testing global
+type testHelpers struct {
+	resources *sharedResources
+	tempDirs  []string
+	mu        sync.Mutex
+}
+
+// newTestHelpers creates helpers for one test file
+func newTestHelpers(resources *sharedResources) *testHelpers {
+	return &testHelpers{resources: resources}
+}
+
+// tempDir creates a temporary directory that is removed when the file's
+// tests finish
+func (th *testHelpers) tempDir() (string, error) {
+	dir, err := os.MkdirTemp("", "gots-test-")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp dir: %w", err)
+	}
+
+	th.mu.Lock()
+	th.tempDirs = append(th.tempDirs, dir)
+	th.mu.Unlock()
+	return dir, nil
+}
+
+// cleanup removes the file's temporary directories
+func (th *testHelpers) cleanup() {
+	th.mu.Lock()
+	defer th.mu.Unlock()
+	for _, dir := range th.tempDirs {
+		os.RemoveAll(dir)
+	}
+	th.tempDirs = nil
+}
+
+// toJSObject creates the testing global for a test file
+func (th *testHelpers) toJSObject(vm *goja.Runtime, testFile string) *goja.Object {
+	obj := vm.NewObject()
+	obj.Set("file", testFile)
+
+	obj.Set("tempDir", func() string {
+		dir, err := th.tempDir()
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return dir
+	})
+
+	obj.Set("freePort", func() int {
+		port, err := th.resources.freePort()
+		if err != nil {
+			panic(vm.NewGoError(err))
+		}
+		return port
+	})
+
+	return obj
+}
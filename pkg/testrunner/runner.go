@@ -5,9 +5,9 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 
-	"github.com/dop251/goja"
 	"gots-runtime/internal/tsengine"
 )
 
@@ -21,18 +21,36 @@ type TestResult struct {
 
 // Runner represents a test runner
 type Runner struct {
-	testDir string
-	engine  *tsengine.Engine
+	testDir         string
+	parallelism     int
+	updateSnapshots bool
+	resources       *sharedResources
 }
 
 // NewRunner creates a new test runner
 func NewRunner(testDir string) *Runner {
 	return &Runner{
-		testDir: testDir,
-		engine:  tsengine.NewEngine(),
+		testDir:     testDir,
+		parallelism: 1,
+		resources:   newSharedResources(),
 	}
 }
 
+// SetParallelism sets how many test files run concurrently. Each file runs
+// in its own runtime, so files cannot see each other's globals.
+func (r *Runner) SetParallelism(n int) {
+	if n < 1 {
+		n = 1
+	}
+	r.parallelism = n
+}
+
+// SetUpdateSnapshots sets whether mismatching snapshots are overwritten
+// instead of failing
+func (r *Runner) SetUpdateSnapshots(update bool) {
+	r.updateSnapshots = update
+}
+
 // DiscoverTests discovers test files
 func (r *Runner) DiscoverTests(pattern string) ([]string, error) {
 	var testFiles []string
@@ -59,35 +77,73 @@ func (r *Runner) DiscoverTests(pattern string) ([]string, error) {
 	return testFiles, err
 }
 
-// RunTests runs all discovered tests
+// RunTests runs all discovered tests. Results are reported in discovery
+// order regardless of the order in which files finish.
 func (r *Runner) RunTests(pattern string) ([]TestResult, error) {
 	testFiles, err := r.DiscoverTests(pattern)
 	if err != nil {
 		return nil, fmt.Errorf("failed to discover tests: %w", err)
 	}
 
-	var results []TestResult
-	for _, file := range testFiles {
-		result, err := r.RunTest(file)
-		if err != nil {
-			result = &TestResult{
-				Name:   file,
-				Passed: false,
-				Error:  err,
+	results := make([]TestResult, len(testFiles))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := r.parallelism
+	if workers > len(testFiles) {
+		workers = len(testFiles)
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result, err := r.RunTest(testFiles[i])
+				if err != nil {
+					result = &TestResult{
+						Name:   testFiles[i],
+						Passed: false,
+						Error:  err,
+					}
+				}
+				results[i] = *result
 			}
-		}
-		results = append(results, *result)
+		}()
+	}
+
+	for i := range testFiles {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
 
 	return results, nil
 }
 
-// RunTest runs a single test file
+// RunTest runs a single test file in a fresh runtime
 func (r *Runner) RunTest(testFile string) (*TestResult, error) {
 	startTime := time.Now()
+
+	snapshots, err := loadSnapshotFile(testFile, r.updateSnapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	engine := tsengine.NewEngine()
+	helpers := newTestHelpers(r.resources)
+	defer helpers.cleanup()
+	engine.Set("testing", helpers.toJSObject(engine.VM(), testFile))
+	tests := newTestContext(engine.VM(), snapshots)
+	tests.register()
 	
 	// Execute the test file
-	_, err := r.engine.ExecuteFile(testFile)
+	_, err = engine.ExecuteFile(testFile)
+	if err == nil {
+		err = tests.err()
+	}
+	if saveErr := snapshots.save(); err == nil {
+		err = saveErr
+	}
 	
 	duration := time.Since(startTime).Milliseconds()
 	
@@ -100,22 +156,6 @@ func (r *Runner) RunTest(testFile string) (*TestResult, error) {
 		}, nil
 	}
 	
-	// Check for test results in the engine
-	// Look for test functions that were executed
-	// For now, we'll assume success if no error occurred
-	// In a full implementation, we'd parse test results from the executed code
-	
-	// Try to get test results from the engine
-	testResults := r.engine.Get("__testResults__")
-	if testResults != nil {
-		// Check if test results exist
-		if !goja.IsUndefined(testResults) {
-			// Parse test results if available
-			// This would require the test file to set __testResults__
-			// For now, we assume success if no error occurred
-		}
-	}
-	
 	return &TestResult{
 		Name:     testFile,
 		Passed:   true,
@@ -0,0 +1,89 @@
+package testrunner
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// snapshotFile holds the stored snapshots of one test file. Snapshots live
+// in __snapshots__/<test file>.snap next to the test file, as a JSON object
+// keyed by "<test name> <n>".
+type snapshotFile struct {
+	path      string
+	snapshots map[string]string
+	update    bool
+	dirty     bool
+	mu        sync.Mutex
+}
+
+// snapshotPath returns the snapshot file path for a test file
+func snapshotPath(testFile string) string {
+	return filepath.Join(filepath.Dir(testFile), "__snapshots__", filepath.Base(testFile)+".snap")
+}
+
+// loadSnapshotFile loads the snapshots of a test file. A missing snapshot
+// file is treated as empty.
+func loadSnapshotFile(testFile string, update bool) (*snapshotFile, error) {
+	sf := &snapshotFile{
+		path:      snapshotPath(testFile),
+		snapshots: make(map[string]string),
+		update:    update,
+	}
+
+	data, err := os.ReadFile(sf.path)
+	if os.IsNotExist(err) {
+		return sf, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read snapshots: %w", err)
+	}
+	if err := json.Unmarshal(data, &sf.snapshots); err != nil {
+		return nil, fmt.Errorf("failed to parse snapshots %s: %w", sf.path, err)
+	}
+	return sf, nil
+}
+
+// match compares a serialized value with the stored snapshot. New snapshots
+// are recorded, and mismatches are overwritten when updating. It returns
+// whether the value matched and the stored snapshot.
+func (sf *snapshotFile) match(key, serialized string) (bool, string) {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	stored, ok := sf.snapshots[key]
+	if ok && stored == serialized {
+		return true, stored
+	}
+	if !ok || sf.update {
+		sf.snapshots[key] = serialized
+		sf.dirty = true
+		return true, serialized
+	}
+	return false, stored
+}
+
+// save writes the snapshots if any were added or updated
+func (sf *snapshotFile) save() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	if !sf.dirty {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(sf.snapshots, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshots: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(sf.path), 0755); err != nil {
+		return fmt.Errorf("failed to create snapshot directory: %w", err)
+	}
+	if err := os.WriteFile(sf.path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write snapshots: %w", err)
+	}
+	sf.dirty = false
+	return nil
+}
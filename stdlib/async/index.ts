@@ -0,0 +1,56 @@
+// Standard Library: Async
+// TypeScript definitions for async helpers
+
+export interface RetryOptions {
+    attempts?: number;      // calls made before giving up, including the first; default 3
+    backoffMs?: number;     // delay before the first retry, doubled for each later one; default 100
+    maxBackoffMs?: number;  // cap on the delay; default 10000
+    jitter?: boolean;       // wait between half and all of each delay; default true
+}
+
+export interface TimeoutError extends Error {
+    name: 'TimeoutError';
+    code: 'ETIMEDOUT';
+}
+
+export interface Async {
+    // Calls fn until its promise fulfills, waiting with exponential backoff
+    // between attempts; rejects with the last error once attempts run out
+    retry<T>(fn: (attempt: number) => T | Promise<T>, options?: RetryOptions): Promise<T>;
+
+    // Settles like target unless ms pass first, then rejects with a
+    // TimeoutError. A function target is called with a signal that is
+    // aborted on timeout, so that it can cancel its work.
+    timeout<T>(target: Promise<T> | ((signal: AbortSignal) => T | Promise<T>), ms: number): Promise<T>;
+}
+
+export declare const async: Async;
+
+export interface PromiseWithResolvers<T> {
+    promise: Promise<T>;
+    resolve(value: T | PromiseLike<T>): void;
+    reject(reason?: any): void;
+}
+
+// Globals provided by the runtime
+declare global {
+    interface PromiseConstructor {
+        withResolvers<T>(): PromiseWithResolvers<T>;
+    }
+
+    interface AbortSignal {
+        readonly aborted: boolean;
+        readonly reason: any;
+        onabort: ((reason: any) => void) | null;
+        addEventListener(type: 'abort', listener: (reason: any) => void): void;
+        removeEventListener(type: 'abort', listener: (reason: any) => void): void;
+        throwIfAborted(): void;
+    }
+
+    var AbortSignal: {
+        // Aborts with a TimeoutError after ms; does not keep the program running
+        timeout(ms: number): AbortSignal;
+        // An already aborted signal; the reason defaults to an AbortError
+        abort(reason?: any): AbortSignal;
+    };
+}
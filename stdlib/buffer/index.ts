@@ -0,0 +1,30 @@
+// Standard Library: Buffer
+// TypeScript definitions for binary data
+
+export type BufferEncoding = 'utf8' | 'utf-8' | 'hex' | 'base64' | 'latin1' | 'binary' | 'ascii';
+
+// Buffer is a Uint8Array with encoding helpers. Buffers returned by fs, net
+// and crypto share memory with the underlying data instead of copying it.
+export interface Buffer extends Uint8Array {
+    toString(encoding?: BufferEncoding): string;
+    equals(other: Uint8Array): boolean;
+    toJSON(): { type: 'Buffer'; data: number[] };
+    // Both return a Buffer sharing memory with this one, like Node
+    subarray(start?: number, end?: number): Buffer;
+    slice(start?: number, end?: number): Buffer;
+}
+
+export interface BufferConstructor {
+    new (size: number): Buffer;
+    new (data: string, encoding?: BufferEncoding): Buffer;
+    new (data: Uint8Array | ArrayBuffer | number[]): Buffer;
+
+    from(data: string, encoding?: BufferEncoding): Buffer;
+    from(data: Uint8Array | ArrayBuffer | number[]): Buffer;
+    alloc(size: number): Buffer;
+    concat(list: Uint8Array[]): Buffer;
+    isBuffer(value: any): value is Buffer;
+    byteLength(data: string | Uint8Array | ArrayBuffer, encoding?: BufferEncoding): number;
+}
+
+export declare const Buffer: BufferConstructor;
@@ -0,0 +1,17 @@
+// Standard Library: Event Bus
+// TypeScript definitions for in-process publish/subscribe messaging
+
+// Topics are dot-separated (e.g. "orders.created"). In subscription patterns
+// "*" matches exactly one segment and "**" matches any number of segments.
+export type BusHandler = (payload: any, topic: string) => void;
+
+export type SubscriptionHandle = string;
+
+export interface EventBus {
+    subscribe(pattern: string, handler: BusHandler): SubscriptionHandle;
+    publish(topic: string, payload?: any): number;   // Number of local subscribers notified
+    unsubscribe(handle: SubscriptionHandle): boolean;
+}
+
+// Global bus instance
+export declare const bus: EventBus;
@@ -1,6 +1,8 @@
 // Standard Library: Crypto
 // TypeScript definitions for cryptographic operations
 
+import { Buffer } from '../buffer';
+
 export interface Crypto {
     // Hashing
     md5(data: Uint8Array | string): string;
@@ -13,7 +15,7 @@ export interface Crypto {
     blake2s(data: Uint8Array | string, size?: number): string;
 
     // Random
-    randomBytes(n: number): Uint8Array;
+    randomBytes(n: number): Buffer;
     randomHex(n: number): string;
     randomUUID(): string;
     randomInt(min: number, max: number): number;
@@ -13,8 +13,9 @@ export interface ImmutableMap<K, V> {
     values(): V[];
     entries(): Array<[K, V]>;
     forEach(callback: (value: V, key: K) => void): void;
-    map<U>(callback: (value: V, key: K) => U): ImmutableList<U>;
+    map<U>(callback: (value: V, key: K) => U): ImmutableMap<K, U>;
     filter(callback: (value: V, key: K) => boolean): ImmutableMap<K, V>;
+    merge(other: ImmutableMap<K, V> | Record<string, V>): ImmutableMap<K, V>;
     toJS(): Map<K, V>;
     toObject(): Record<string | number | symbol, V>;
     equals(other: ImmutableMap<K, V>): boolean;
@@ -35,11 +36,12 @@ export interface ImmutableList<T> {
     first(): T | undefined;
     last(): T | undefined;
     slice(start?: number, end?: number): ImmutableList<T>;
-    concat(...lists: ImmutableList<T>[]): ImmutableList<T>;
+    concat(...lists: Array<ImmutableList<T> | T[]>): ImmutableList<T>;
     forEach(callback: (value: T, index: number) => void): void;
     map<U>(callback: (value: T, index: number) => U): ImmutableList<U>;
     filter(callback: (value: T, index: number) => boolean): ImmutableList<T>;
     reduce<U>(callback: (acc: U, value: T, index: number) => U, initial: U): U;
+    reduce(callback: (acc: T, value: T, index: number) => T): T;
     find(callback: (value: T, index: number) => boolean): T | undefined;
     findIndex(callback: (value: T, index: number) => boolean): number;
     includes(value: T): boolean;
@@ -104,7 +106,7 @@ export interface ImmutableRecord {
 }
 
 // Factory functions
-export function createMap<K, V>(entries?: Array<[K, V]> | Record<string, V>): ImmutableMap<K, V> { throw new Error('Not implemented'); }
+export function createMap<K, V>(entries?: Iterable<[K, V]> | Map<K, V> | Record<string, V>): ImmutableMap<K, V> { throw new Error('Not implemented'); }
 export function createList<T>(items?: T[] | Iterable<T>): ImmutableList<T> { throw new Error('Not implemented'); }
 export function createSet<T>(items?: T[] | Iterable<T>): ImmutableSet<T> { throw new Error('Not implemented'); }
 export function createQueue<T>(items?: T[] | Iterable<T>): ImmutableQueue<T> { throw new Error('Not implemented'); }
@@ -0,0 +1,11 @@
+// Package stdlib embeds the TypeScript declarations of the standard library,
+// so that gots can be distributed as a single binary
+package stdlib
+
+import "embed"
+
+// FS holds the declaration files of the standard library modules, rooted at
+// the module directories (e.g. fs/index.ts)
+//
+//go:embed */*.ts
+var FS embed.FS
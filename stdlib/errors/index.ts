@@ -0,0 +1,33 @@
+// Standard Library: Runtime Errors
+// TypeScript definitions for errors raised by runtime APIs
+
+export type ErrorCode =
+    | 'EPERM'         // Permission denied by the module's security policy
+    | 'EACCES'        // Permission denied by the operating system
+    | 'ENOENT'        // No such file or directory
+    | 'EEXIST'        // File already exists
+    | 'ENOTDIR'       // Not a directory
+    | 'EISDIR'        // Is a directory
+    | 'ETIMEDOUT'     // Operation timed out
+    | 'ECONNREFUSED'  // Connection refused
+    | 'ECONNRESET'    // Connection reset by peer
+    | 'EADDRINUSE'    // Address already in use
+    | 'EOVERLOADED'   // Event loop queue is full
+    | 'EUNKNOWN';
+
+// Errors thrown or passed to callbacks by fs, net, env and other runtime APIs
+export interface RuntimeError extends Error {
+    code: ErrorCode;
+    message: string;
+    cause?: string;   // Message of the underlying error, if any
+}
+
+// Errors with code 'EPERM' also name the denied permission and module
+export interface PermissionDeniedError extends RuntimeError {
+    code: 'EPERM';
+    permission: string;   // e.g. 'fs:write'
+    module: string;
+    target?: string;      // Path or host:port outside the allowed scope
+}
+
+export function isRuntimeError(err: any): err is RuntimeError { throw new Error('Not implemented'); }
@@ -0,0 +1,48 @@
+// Standard Library: Federation
+// TypeScript definitions for messaging between runtimes on different hosts
+
+export interface FederationMessage {
+    type: string;
+    from: string;       // ID of the sending runtime
+    to: string;
+    timestamp: number;  // milliseconds since the epoch
+}
+
+// The return value, or the value of a returned promise, is the reply
+// received by send(); return undefined to send no reply. A thrown error
+// rejects the sender's promise.
+export type MessageHandler = (payload: any, message: FederationMessage) => any;
+
+export interface SendOptions {
+    timeout?: number;   // milliseconds, defaults to 30000
+}
+
+export interface FederationNode {
+    id: string;
+    address: string;
+    healthy: boolean;
+    lastSeen: number;
+}
+
+export interface Federation {
+    readonly id: string;    // ID of this runtime, derived from host name and process ID
+
+    // Accept messages from other runtimes; returns the bound address.
+    // Requires net:listen permission. Keeps the runtime alive until stop().
+    listen(address: string): string;
+    stop(): void;
+
+    registerNode(id: string, address: string): void;
+    unregisterNode(id: string): void;
+    nodes(): FederationNode[];
+
+    onMessage(type: string, handler: MessageHandler): void;
+    // Resolves with the reply payload, or undefined if the handler sent no
+    // reply. Requires net:dial permission for the node's address.
+    send(nodeId: string, type: string, payload?: any, options?: SendOptions): Promise<any>;
+    // Sends to every healthy node without waiting for replies
+    broadcast(type: string, payload?: any): Promise<void>;
+}
+
+// Global federation instance
+export declare const federation: Federation;
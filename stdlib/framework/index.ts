@@ -22,44 +22,276 @@ export interface Response {
     setHeader(name: string, value: string): Response;
 }
 
+export interface CookieOptions {
+    path?: string;          // defaults to '/'
+    domain?: string;
+    maxAge?: number;        // seconds
+    expires?: Date | number;
+    httpOnly?: boolean;
+    secure?: boolean;       // forced on for sameSite 'None'
+    sameSite?: 'Strict' | 'Lax' | 'None';
+}
+
+export interface Cookies {
+    get(name: string): string | undefined;
+    all(): Record<string, string>;
+    set(name: string, value: string, options?: CookieOptions): void;
+    // path and domain must match the ones the cookie was set with
+    delete(name: string, options?: CookieOptions): void;
+}
+
+export interface Session {
+    id(): string;
+    get(key: string): any;
+    set(key: string, value: any): void;
+    delete(key: string): void;
+    all(): Record<string, any>;
+    // Move the data to a new session ID; call on login or privilege change
+    regenerate(): void;
+    destroy(): void;
+}
+
+export interface SessionOptions {
+    cookieName?: string;    // defaults to 'gots_session'
+    ttl?: number;           // milliseconds, defaults to 24 hours
+    cookie?: CookieOptions; // httpOnly is always set
+}
+
+// Writes a chunk of a streamed response; returns false once the client
+// has disconnected
+export type StreamWrite = (chunk: string | Uint8Array | ArrayBuffer) => boolean;
+
+// Ends a streamed response, optionally writing a last chunk
+export type StreamEnd = (chunk?: string | Uint8Array | ArrayBuffer) => void;
+
+export interface ServerSentEvents {
+    // Send an event; data that is not a string is sent as JSON. Returns
+    // false once the client has disconnected.
+    send(data: any, event?: string): boolean;
+    close(): void;
+    closed(): boolean;
+}
+
+// Aborted when the client disconnects, a timeout passes or the request
+// ends; the reason is an Error named "AbortError" or "TimeoutError"
+export interface AbortSignal {
+    readonly aborted: boolean;
+    readonly reason: Error | undefined;
+    onabort: ((reason: Error) => void) | null;
+    addEventListener(type: 'abort', listener: (reason: Error) => void): void;
+    removeEventListener(type: 'abort', listener: (reason: Error) => void): void;
+    throwIfAborted(): void;
+}
+
+// The timing breakdown of a request timed by app.useTiming(); durations are
+// in milliseconds
+export interface RequestTiming {
+    traceId: string;
+    // middleware, handler (once it has started), the added phases and
+    // total, up to now while the request is being handled
+    phases(): Record<string, number>;
+    // Record a phase measured by the caller
+    add(name: string, ms: number): void;
+    // Start measuring a phase; call the returned function to end it
+    start(name: string): () => void;
+}
+
+// A span recorded by app.useTiming(); tags include the method, path,
+// status and phase durations
+export interface Span {
+    traceId: string;
+    spanId: string;
+    parentId: string;
+    name: string;        // e.g. "GET /orders"
+    start: number;       // Unix milliseconds
+    duration: number;    // milliseconds
+    tags: Record<string, string>;
+}
+
+// Logs through the app's logger; every entry carries the request's method,
+// path and ID (with app.useRequestId()) and the fields given to set
+export interface RequestLogger {
+    debug(message: string, fields?: Record<string, any>): void;
+    info(message: string, fields?: Record<string, any>): void;
+    warn(message: string, fields?: Record<string, any>): void;
+    error(message: string, fields?: Record<string, any>): void;
+    // A logger that adds fields to its own entries
+    with(fields: Record<string, any>): RequestLogger;
+    // Add fields to every entry logged for the rest of the request
+    set(fields: Record<string, any>): void;
+}
+
 export interface Context {
     request: Request;
     response: Response;
+    cookies: Cookies;
+    session?: Session;      // set when app.useSession() is in use
     data: Record<string, any>;
+    signal: AbortSignal;
+    log: RequestLogger;
+    timing?: RequestTiming; // set when app.useTiming() is in use
     set(key: string, value: any): void;
     get(key: string): any;
-    param(name: string): string | undefined;
-    query(name: string): string | undefined;
-    header(name: string): string | undefined;
+
+    // Stream the response body with chunked transfer encoding. write and
+    // end may be called after the handler returns.
+    stream(callback: (write: StreamWrite, end: StreamEnd) => void): void;
+    // Start a text/event-stream response
+    sse(): ServerSentEvents;
 }
 
-export type Middleware = (ctx: Context, next: () => Promise<void> | void) => Promise<void> | void;
-export type Handler = (ctx: Context) => Promise<void> | void;
+export type Middleware = (ctx: Context, next: () => Promise<void>) => Promise<void> | void;
+// A value a handler returns or resolves to is the response body, unless it
+// set one: strings as text and other values as JSON
+export type Handler = (ctx: Context) => Promise<unknown> | unknown;
 export type ErrorHandler = (ctx: Context, error: Error) => Promise<void> | void;
 export type NotFoundHandler = (ctx: Context) => Promise<void> | void;
 
+// A type name, with a "?" suffix for optional properties, or an object
+// of property schemas
+export type SchemaType =
+    | 'string' | 'number' | 'boolean' | 'object' | 'array' | 'any' | 'null'
+    | 'string?' | 'number?' | 'boolean?' | 'object?' | 'array?' | 'any?' | 'null?'
+    | { [property: string]: SchemaType };
+
+// Validates the parts of a request; the validated values are available
+// as ctx.get('validated')
+export interface RouteSchema {
+    body?: SchemaType;
+    query?: { [name: string]: SchemaType };
+    params?: { [name: string]: SchemaType };
+}
+
+export interface FieldError {
+    field: string;   // e.g. "body.address.city"
+    message: string;
+}
+
+// Load shedding class of a route: under load, low priority routes are
+// shed first and critical ones, such as health checks, never are
+export type RoutePriority = 'low' | 'normal' | 'high' | 'critical';
+
+// A route's schema and load shedding priority, which defaults to "normal"
+export interface RouteOptions extends RouteSchema {
+    priority?: RoutePriority;
+}
+
+// A backend of app.proxy: a URL such as "http://10.0.0.1:8080", or a
+// host and port, with an optional weight for "weighted-round-robin"
+export type ProxyBackend = string | { url: string; weight?: number };
+
+export interface ProxyOptions {
+    backends: ProxyBackend[];
+    strategy?: 'round-robin' | 'least-connections' | 'weighted-round-robin' | 'ip-hash';
+    // Milliseconds between GET /health checks of each backend; unhealthy
+    // backends get no requests. Backends are not checked by default.
+    healthCheckInterval?: number;
+}
+
+export interface ShedLoadOptions {
+    // Requests in flight at which normal priority requests start being
+    // shed; low priority ones are shed at half of it and high priority
+    // ones at twice it
+    threshold: number;
+    // How the rejection rate grows past the threshold; requests are shed
+    // once it exceeds 50%, at twice the threshold with "cubic" and
+    // "exponential" and six times it with "linear", the default
+    policy?: 'linear' | 'cubic' | 'exponential';
+}
+
+// Route-specific middleware and options followed by the handler; they run
+// after the app's global middleware
+export type RouteHandlers = [...(Middleware | RouteOptions)[], Handler];
+
+// Middleware phases: "pre" middleware wraps "main" middleware, which wraps
+// "post" middleware. The numeric priorities are -100, 0 and 100.
+export type MiddlewarePhase = 'pre' | 'main' | 'post';
+
 export interface App {
-    use(middleware: Middleware): App;
-    get(path: string, handler: Handler): App;
-    post(path: string, handler: Handler): App;
-    put(path: string, handler: Handler): App;
-    delete(path: string, handler: Handler): App;
-    patch(path: string, handler: Handler): App;
-    options(path: string, handler: Handler): App;
-    head(path: string, handler: Handler): App;
-    dynamic(method: string, path: string, handler: Handler): App;
+    // Middleware runs in ascending priority, and in registration order
+    // within a priority; it defaults to the "main" phase
+    use(middleware: Middleware, order?: MiddlewarePhase | number): App;
+    useSession(options?: SessionOptions): App;
+    // Give every request an ID, from its X-Request-ID header or generated,
+    // echoed in the response and added to the entries of ctx.log
+    useRequestId(): App;
+    // Count requests and their errors and latency per route for
+    // app.metrics()
+    useMetrics(): App;
+    // Send a Server-Timing header with the time spent in middleware, in the
+    // handler and in total, and record each request as a span whose trace
+    // ID is added to ctx.log entries. Call it before registering other
+    // middleware so that it measures it.
+    useTiming(): App;
+    // The spans recorded for a trace, oldest first
+    spans(traceId: string): Span[];
+    // Reject requests with 503 and Retry-After before any middleware runs
+    // once too many are in flight, lower priority routes first
+    shedLoad(options: ShedLoadOptions): App;
+    get(path: string, ...handlers: RouteHandlers): App;
+    post(path: string, ...handlers: RouteHandlers): App;
+    put(path: string, ...handlers: RouteHandlers): App;
+    delete(path: string, ...handlers: RouteHandlers): App;
+    patch(path: string, ...handlers: RouteHandlers): App;
+    options(path: string, ...handlers: RouteHandlers): App;
+    head(path: string, ...handlers: RouteHandlers): App;
+    dynamic(method: string, path: string, ...handlers: RouteHandlers): App;
+    // Forward requests with any method whose path is prefix or below it to
+    // the backends, after the given middleware; the response is streamed
+    // back. Routes under prefix take precedence.
+    proxy(prefix: string, ...args: [...Middleware[], ProxyOptions]): App;
 
     onStart(hook: () => Promise<void> | void): App;
     onStop(hook: () => Promise<void> | void): App;
 
     setErrorHandler(handler: ErrorHandler): App;
     setNotFoundHandler(handler: NotFoundHandler): App;
+    // Include the details of internal errors in error responses; never
+    // enable in production
+    setDevMode(dev: boolean): void;
 
     start(): Promise<void>;
     stop(): Promise<void>;
+    // Stops accepting connections, waits for in-flight requests and then
+    // runs the stop hooks
+    close(): Promise<void>;
     handle(ctx: Context): Promise<void>;
-    listen(port: number, callback?: (err?: Error) => void): void;
+    // Resolves with the bound port, which is the assigned one when port is
+    // 0, once the server accepts connections, and rejects if binding fails.
+    // The callback receives the bound port, or an error if binding or
+    // serving fails.
+    listen(port: number, callback?: (err?: Error, port?: number) => void): Promise<number>;
+
+    // Request metrics for this app, collected once app.useMetrics() is
+    // called; keys of the count maps are "METHOD route", e.g.
+    // "GET /users/:id", with "(unmatched)" for requests no route matches
+    metrics(): AppMetrics;
+}
+
+export interface AppMetrics {
+    totalRequests: number;
+    totalErrors: number;
+    averageLatency: number; // milliseconds
+    requestCounts: Record<string, number>;
+    errorCounts: Record<string, number>;
+}
+
+// Error thrown by throwHTTPError
+export interface HTTPError extends Error {
+    name: 'HTTPError';
+    status: number;
+    code: string;
+}
+
+// The JSON body of error responses
+export interface ErrorBody {
+    error: { code: string; message: string; details?: FieldError[] | any };
 }
 
 // Factory function to create a new application
 export function createApp(name?: string): App { throw new Error('Not implemented'); }
+
+// Abort the request with an error response of the given status. The code
+// defaults to the status text in snake case, e.g. "not_found", and the
+// message to the status text.
+export declare function throwHTTPError(status: number, message?: string, code?: string): never;
@@ -1,6 +1,8 @@
 // Standard Library: File System
 // TypeScript definitions for file system operations
 
+import { Buffer, BufferEncoding } from '../buffer';
+
 export const O_RDONLY: number;
 export const O_WRONLY: number;
 export const O_RDWR: number;
@@ -29,9 +31,10 @@ export interface FileHandle {
 
 export interface FS {
     // Async file operations
-    readFile(path: string, callback: (data: Uint8Array, err?: Error) => void): void;
-    readFile(path: string, encoding: 'utf8' | 'ascii' | 'base64', callback: (data: string, err?: Error) => void): void;
+    readFile(path: string, callback: (data: Buffer, err?: Error) => void): void;
+    readFile(path: string, encoding: BufferEncoding, callback: (data: string, err?: Error) => void): void;
 
+    writeFile(path: string, data: Uint8Array | string, callback: (err?: Error) => void): void;
     writeFile(path: string, data: Uint8Array | string, perm: number, callback: (err?: Error) => void): void;
     appendFile(path: string, data: Uint8Array | string, callback: (err?: Error) => void): void;
 
@@ -63,8 +66,9 @@ export interface FS {
     open(path: string, flag: number, perm: number, callback: (handle: FileHandle, err?: Error) => void): void;
 
     // Sync file operations
-    readFileSync(path: string, encoding?: 'utf8' | 'ascii' | 'base64'): Uint8Array | string;
-    writeFileSync(path: string, data: Uint8Array | string, perm: number): void;
+    readFileSync(path: string): Buffer;
+    readFileSync(path: string, encoding: BufferEncoding): string;
+    writeFileSync(path: string, data: Uint8Array | string, perm?: number): void;
     appendFileSync(path: string, data: Uint8Array | string): void;
 
     readDirSync(path: string): DirEntry[];
@@ -59,7 +59,14 @@ export interface Server {
     options(path: string, handler: Handler): Server;
     head(path: string, handler: Handler): Server;
 
-    listen(port: number, host?: string, callback?: (err?: Error) => void): void;
+    // The callback receives the bound port, which is the assigned one when
+    // port is 0, or an error if binding or serving fails
+    listen(port: number, host?: string, callback?: (err?: Error, port?: number) => void): void;
+    listen(port: number, callback?: (err?: Error, port?: number) => void): void;
+    // null until the server is listening
+    address(): { address: string; port: number } | null;
+    // Stop accepting connections; the callback runs once in-flight
+    // requests have finished
     close(callback?: (err?: Error) => void): void;
 
     setErrorHandler(handler: ErrorHandler): Server;
@@ -107,5 +114,5 @@ export interface Client {
 }
 
 // Factory functions
-export function createServer(options?: ServerOptions): Server { throw new Error('Not implemented'); }
+export function createServer(handler?: Handler): Server { throw new Error('Not implemented'); }
 export function createClient(options?: ClientOptions): Client { throw new Error('Not implemented'); }
@@ -10,8 +10,8 @@ export const AF_UNIX: number;
 export interface Conn {
     read(buffer: Uint8Array, callback: (n: number, err?: Error) => void): void;
     readSync(buffer: Uint8Array): number;
-    write(buffer: Uint8Array, callback: (n: number, err?: Error) => void): void;
-    writeSync(buffer: Uint8Array): number;
+    write(data: Uint8Array | string, callback: (n: number, err?: Error) => void): void;
+    writeSync(data: Uint8Array | string): number;
     close(callback: (err?: Error) => void): void;
     closeSync(): void;
     localAddr(): string;
@@ -0,0 +1,16 @@
+// Standard Library: Path
+// TypeScript definitions for path manipulation
+
+// Paths always use forward slashes, regardless of the host platform
+export interface Path {
+    readonly sep: '/';
+
+    join(...paths: string[]): string;
+    resolve(...paths: string[]): string;
+    dirname(path: string): string;
+    basename(path: string, ext?: string): string;
+    extname(path: string): string;
+    relative(from: string, to: string): string;
+}
+
+export declare const path: Path;
@@ -16,11 +16,42 @@ export interface PluginContext {
     getData(key: string): any;
 }
 
+// Data passed to plugin hooks
+export interface ModuleLoadEvent {
+    specifier: string;      // the path given to require
+}
+
+export interface RequestEvent {
+    method: string;
+    path: string;
+}
+
+export interface ResponseEvent extends RequestEvent {
+    status: number;
+    durationMs: number;
+    error?: string;         // set when the request failed
+}
+
+export interface RequestErrorEvent extends RequestEvent {
+    error: string;
+}
+
+// Handlers for runtime hook points. A beforeRequest handler that throws
+// fails the request; onModuleLoad handlers that throw fail the require.
+export interface PluginHooks {
+    onModuleLoad?(event: ModuleLoadEvent): void;
+    beforeRequest?(event: RequestEvent): void;
+    afterRequest?(event: ResponseEvent): void;
+    onError?(event: RequestErrorEvent): void;
+    onShutdown?(): void;
+}
+
 export interface Plugin {
     name: string;
     version: string;
     description?: string;
-    dependencies?: string[];
+    dependencies?: string[];    // plugins initialized before this one
+    hooks?: PluginHooks;
 
     initialize(ctx: PluginContext): Promise<void> | void;
     execute(ctx: PluginContext, args: Record<string, any>): Promise<any> | any;
@@ -42,16 +73,27 @@ export interface PluginLoadOptions {
     path?: string;
     config?: Record<string, any>;
     priority?: number; // 0-100, higher = earlier execution
+    watch?: boolean;   // reload the plugin when its files change
 }
 
 export interface PluginManager {
     register(plugin: Plugin, options?: PluginLoadOptions): Promise<void>;
     unregister(name: string): Promise<void>;
-    load(path: string, config?: Record<string, any>): Promise<Plugin>;
+    // Loads a plugin from its entry point file, or from a directory with a
+    // plugin.json manifest. Loaded plugins can be reloaded: the old instance
+    // is shut down and replaced by one running the current code, and kept if
+    // the new one fails to load or initialize.
+    load(path: string, options?: PluginLoadOptions): Promise<PluginMetadata>;
+    reload(name: string): Promise<void>;
 
     execute(name: string, args: Record<string, any>): Promise<any>;
     executeAll(hookName: string, args?: Record<string, any>): Promise<any[]>;
 
+    // Initializes the registered plugins, each after the plugins it depends
+    // on; rejects if a dependency is not registered or dependencies are circular
+    initialize(): Promise<void>;
+    order(): string[];
+
     list(): PluginMetadata[];
     get(name: string): Plugin | undefined;
     has(name: string): boolean;
@@ -0,0 +1,18 @@
+// Standard Library: Process
+// TypeScript definitions for the current process
+
+export interface Process {
+    // Arguments after the script filename
+    readonly argv: string[];
+
+    // Reading requires env read permission, writing env write permission
+    env: Record<string, string | undefined>;
+
+    readonly pid: number;
+    readonly platform: string;
+
+    cwd(): string;
+    exit(code?: number): never;
+}
+
+export declare const process: Process;
@@ -0,0 +1,15 @@
+// Standard Library: Query String
+// TypeScript definitions for query string parsing and encoding
+
+// Keys that appear once map to a string, repeated keys to an array
+export type ParsedQuery = Record<string, string | string[]>;
+
+export interface QueryString {
+    // A leading '?' is ignored
+    parse(query: string): ParsedQuery;
+
+    // Keys are sorted; array values produce repeated keys
+    stringify(query: Record<string, string | number | boolean | Array<string | number | boolean>>): string;
+}
+
+export declare const querystring: QueryString;
@@ -31,8 +31,18 @@ export interface RPCContext {
     metadata: Record<string, any>;
 }
 
+export interface RPCStreamSink {
+    send(value: any): boolean;   // false once the client has gone away
+    isCancelled(): boolean;
+}
+
+// A stream handler sends any number of messages; the stream ends when it returns
+// (or its promise resolves) and fails if it throws (or its promise rejects).
+export type RPCStreamHandler = (params: any, stream: RPCStreamSink) => Promise<void> | void;
+
 export interface RPCServer {
     register(method: string, handler: RPCHandler): RPCServer;
+    registerStream(method: string, handler: RPCStreamHandler): RPCServer;
     unregister(method: string): RPCServer;
     registerModule(moduleName: string, handlers: Record<string, RPCHandler>): RPCServer;
 
@@ -51,8 +61,13 @@ export interface RPCClient {
     call(method: string, params?: any, timeout?: number): Promise<any>;
     callModule(module: string, method: string, params?: any, timeout?: number): Promise<any>;
     batch(calls: Array<{ method: string, params?: any }>): Promise<any[]>;
+    callStream(method: string, params?: any): AsyncIterableIterator<any>;
     close(): Promise<void>;
     isConnected(): boolean;
+
+    // Connection state events
+    onConnect(callback: () => void): void;
+    onDisconnect(callback: (err: string) => void): void;
 }
 
 export interface RPCServerOptions {
@@ -67,6 +82,12 @@ export interface RPCClientOptions {
     retries?: number;
     retryDelay?: number;
     keepAlive?: boolean;
+    reconnect?: boolean;                      // Reconnect automatically (default: true)
+    onDisconnected?: 'queue' | 'fail';        // Queue calls or fail fast while disconnected (default: 'queue')
+    maxRetries?: number;                      // Reconnection attempts, 0 = unlimited
+    initialBackoffMs?: number;
+    maxBackoffMs?: number;
+    dialTimeoutMs?: number;
 }
 
 // Factory functions
@@ -0,0 +1,89 @@
+// Standard Library: Runtime
+// TypeScript definitions for runtime introspection
+
+export interface ModuleMemoryUsage {
+    allocated: number;  // bytes currently accounted to the module
+    max: number;        // highest allocated value seen
+}
+
+export interface MemoryUsage {
+    heapAlloc: number;  // bytes of allocated heap objects
+    heapSys: number;    // bytes of heap memory obtained from the OS
+    goroutines: number;
+    modules: Record<string, ModuleMemoryUsage>;
+}
+
+// The clock behind Date and the timers. set and advance are only
+// available when running with --fake-time; they freeze the clock, which
+// then only moves when set or advanced, firing the timers that fall due.
+export interface Clock {
+    now(): number;              // milliseconds since the epoch
+    set?(ms: number): void;
+    advance?(ms: number): void;
+}
+
+export interface SchedulerStats {
+    workers: number;
+    busyWorkers: number;
+    minWorkers: number;
+    maxWorkers: number;
+    queuedTasks: number;
+    queueHighWater: number;
+}
+
+export interface EventLoopStats {
+    queued: number;     // events waiting to run
+    capacity: number;
+    highWater: number;  // most events ever queued
+    dropped: number;
+    rejected: number;
+    policy: 'block' | 'drop-oldest' | 'reject';
+    timers: number;     // pending timers and intervals
+    nextTicks: number;
+    refs: number;       // handles keeping the loop alive, such as servers
+    busy: boolean;
+    stalls: number;     // callbacks that blocked the loop past the stall threshold
+}
+
+// Totals over the open worker pools, including the default one
+export interface WorkerStats {
+    pools: number;
+    totalWorkers: number;
+    busyWorkers: number;
+    idleWorkers: number;
+    queuedTasks: number;
+    crashedTasks: number;
+}
+
+// orchestrator and scheduler are null when the program does not run under
+// the runtime orchestrator, as with gots run
+export interface RuntimeStats {
+    orchestrator: 'initialized' | 'running' | 'shutting-down' | 'stopped' | null;
+    scheduler: SchedulerStats | null;
+    eventLoop: EventLoopStats;
+    workers: WorkerStats;
+}
+
+// The parsed gots.json, with the compiler options of tsconfig.json
+export type ProjectConfig = Record<string, any> & {
+    name: string;
+    version: string;
+};
+
+export interface ConfigChange {
+    config: ProjectConfig;
+    previous: ProjectConfig;
+}
+
+export interface Runtime {
+    memoryUsage(): MemoryUsage;
+    stats(): RuntimeStats;
+    config(): ProjectConfig | null;  // null when there is no gots.json
+    // Called when a valid change of the configuration is loaded; returns a
+    // function that unsubscribes
+    onConfigChange(callback: (change: ConfigChange) => void): () => void;
+    clock: Clock;
+    seedRandom(seed: number): void; // restart Math.random from seed
+}
+
+export declare const runtime: Runtime;
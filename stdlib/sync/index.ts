@@ -0,0 +1,27 @@
+// Standard Library: Sync
+// TypeScript definitions for concurrency control
+
+// Limits how many holders proceed at once. Waiting acquires are granted in
+// the order they were made, without blocking the event loop.
+export interface Semaphore {
+    acquire(): Promise<void>;
+    tryAcquire(): boolean;      // takes a permit only if one is free now
+    release(): void;            // throws if no permit is held
+    available(): number;
+    waiting(): number;
+}
+
+// A lock held by one holder at a time
+export interface Mutex {
+    lock(): Promise<void>;
+    tryLock(): boolean;
+    unlock(): void;             // throws if the mutex is not locked
+    isLocked(): boolean;
+}
+
+export interface Sync {
+    semaphore(permits: number): Semaphore;
+    mutex(): Mutex;
+}
+
+export declare const sync: Sync;
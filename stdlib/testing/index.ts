@@ -0,0 +1,33 @@
+// Standard Library: Testing
+// TypeScript definitions for helpers available to test files
+
+// Each test file runs in its own runtime, possibly concurrently with other
+// files when gots test --parallel is used. These helpers hand out resources
+// that must not be shared between files.
+export interface Testing {
+    // Path of the running test file
+    readonly file: string;
+
+    // Create a temporary directory, removed when the file's tests finish
+    tempDir(): string;
+
+    // Reserve a free TCP port not handed to any other test file in this run
+    freePort(): number;
+}
+
+export declare const testing: Testing;
+
+export interface Expectation {
+    // Same value, compared like Object.is
+    toBe(expected: any): void;
+    // Equal JSON serialization
+    toEqual(expected: any): void;
+    // Compare with the stored snapshot in __snapshots__/<file>.snap, keyed
+    // by test name; new snapshots are recorded and gots test
+    // --update-snapshots overwrites mismatches
+    toMatchSnapshot(): void;
+}
+
+// Run a named test; a thrown error fails the test and its file
+export declare function test(name: string, fn: () => void): void;
+export declare function expect(value: any): Expectation;
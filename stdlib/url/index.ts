@@ -0,0 +1,28 @@
+// Standard Library: URL
+// TypeScript definitions for URL parsing and formatting
+
+import { ParsedQuery } from '../querystring';
+
+export interface UrlObject {
+    href?: string;
+    protocol?: string;   // scheme with trailing colon, e.g. 'https:'
+    username?: string;
+    password?: string;
+    host?: string;       // hostname and port
+    hostname?: string;
+    port?: string;
+    pathname?: string;
+    search?: string;     // query string with leading '?'
+    query?: ParsedQuery;
+    hash?: string;       // fragment with leading '#'
+}
+
+export interface Url {
+    // Throws a RuntimeError with code EUNKNOWN for malformed URLs
+    parse(url: string): Required<UrlObject>;
+
+    // host takes precedence over hostname and port, search over query
+    format(url: UrlObject | string): string;
+}
+
+export declare const url: Url;
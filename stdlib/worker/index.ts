@@ -1,14 +1,37 @@
 // Standard Library: Worker Threads
 // TypeScript definitions for zero-cost worker thread abstraction
 
+// Data passed to and returned from workers is copied with a structured clone.
+// Transferable: primitives (including bigint), arrays, plain objects, Date,
+// RegExp, Map, Set, Error, ArrayBuffer, DataView, typed arrays and
+// SharedBuffer, whose memory is shared rather than copied. Shared and
+// cyclic references are preserved. Functions and symbols are rejected with a
+// DataCloneError; class instances are cloned as plain objects.
+export type Cloneable =
+    | undefined | null | boolean | number | string | bigint
+    | Date | RegExp | Error | ArrayBuffer | DataView | ArrayBufferView
+    | Cloneable[]
+    | Map<Cloneable, Cloneable>
+    | Set<Cloneable>
+    | { [key: string]: Cloneable };
+
 export interface WorkerTask<T = any, R = any> {
     id: string;
     data: T;
+    // Runs in the worker's own VM, so it must not use variables of its scope
     handler?: (data: T) => R | Promise<R>;
     priority?: number; // 0-10, higher = more important
     timeout?: number; // milliseconds
+    transfer?: ArrayBuffer[]; // Buffers moved to the worker without copying; detached in the caller
+}
+
+export interface StructuredCloneOptions {
+    transfer?: ArrayBuffer[];
 }
 
+// Copies a value the same way worker data is copied
+export declare function structuredClone<T>(value: T, options?: StructuredCloneOptions): T;
+
 export interface WorkerResult<T = any> {
     id: string;
     data: T;
@@ -26,8 +49,10 @@ export interface Worker {
 
 export interface WorkerPool {
     spawn<T, R>(task: WorkerTask<T, R>): Promise<WorkerResult<R>>;
+    // Resolves with results[i] for tasks[i]
     spawnBatch<T, R>(tasks: WorkerTask<T, R>[]): Promise<WorkerResult<R>[]>;
     spawnWithPriority<T, R>(task: WorkerTask<T, R>, priority: number): Promise<WorkerResult<R>>;
+    map<T, R>(items: T[], fn: (item: T, index: number) => R, options?: MapOptions): Promise<R[]>;
 
     getStats(): {
         totalWorkers: number;
@@ -36,6 +61,7 @@ export interface WorkerPool {
         queuedTasks: number;
         completedTasks: number;
         failedTasks: number;
+        crashedTasks: number;              // tasks whose handler panicked
     };
 
     resize(minWorkers: number, maxWorkers: number): Promise<void>;
@@ -43,6 +69,44 @@ export interface WorkerPool {
     warmUp(count: number): Promise<void>;
 }
 
+export interface MapOptions {
+    concurrency?: number;              // items in flight at once; defaults to the pool size
+    errors?: 'fail-fast' | 'collect';  // default 'fail-fast'
+}
+
+// Rejection of map in 'collect' mode
+export interface MapError extends Error {
+    errors: { index: number; message: string }[];
+}
+
+// Runs fn(item, index) for every item on the default pool and resolves to
+// the results in the order of items. In 'fail-fast' mode the first failure
+// rejects and no more items start; in 'collect' mode every item runs and
+// the promise rejects with a MapError listing all failures.
+export declare function map<T, R>(items: T[], fn: (item: T, index: number) => R, options?: MapOptions): Promise<R[]>;
+
+// Memory shared between the main thread and workers instead of copied.
+// Holds 32-bit integers; indexed access is atomic.
+export interface SharedBuffer {
+    readonly byteLength: number;
+    readonly length: number;
+    [index: number]: number;
+}
+
+// Creates a zeroed shared buffer of size bytes, rounded up to whole integers
+export declare function sharedBuffer(size: number): SharedBuffer;
+
+// Sequentially consistent atomic operations on shared buffer elements;
+// all but store return the previous value
+export declare const atomics: {
+    load(buf: SharedBuffer, index: number): number;
+    store(buf: SharedBuffer, index: number, value: number): number;
+    add(buf: SharedBuffer, index: number, delta: number): number;
+    sub(buf: SharedBuffer, index: number, delta: number): number;
+    exchange(buf: SharedBuffer, index: number, value: number): number;
+    compareExchange(buf: SharedBuffer, index: number, expected: number, replacement: number): number;
+};
+
 // Factory function to create a worker pool
 export function createWorkerPool(minWorkers?: number, maxWorkers?: number): WorkerPool { throw new Error('Not implemented'); }
 